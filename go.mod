@@ -4,11 +4,14 @@ go 1.16
 
 require (
 	cloud.google.com/go/errorreporting v0.1.0 // indirect
+	cloud.google.com/go/firestore v1.5.0
 	cloud.google.com/go/monitoring v0.1.0 // indirect
 	cloud.google.com/go/profiler v0.1.0 // indirect
 	cloud.google.com/go/pubsub v1.16.0 // indirect
 	cloud.google.com/go/trace v0.1.0 // indirect
+	firebase.google.com/go v3.13.0+incompatible
 	github.com/99designs/gqlgen v0.13.0
+	github.com/asaskevich/govalidator v0.0.0-20210307081110-f21760c49a8d
 	github.com/aws/aws-sdk-go v1.40.29 // indirect
 	github.com/casbin/casbin/v2 v2.37.0
 	github.com/googleapis/gax-go/v2 v2.1.0 // indirect
@@ -16,9 +19,12 @@ require (
 	github.com/gorilla/mux v1.8.0
 	github.com/imroc/req v0.3.0
 	github.com/kevinburke/go-types v0.0.0-20210723172823-2deba1f80ba7 // indirect
+	github.com/kevinburke/twilio-go v0.0.0-20210327194925-1623146bcf73
 	github.com/labstack/gommon v0.3.0
-	github.com/savannahghi/converterandformatter v0.0.11 // indirect
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/savannahghi/converterandformatter v0.0.11
 	github.com/savannahghi/engagementcore v0.0.30
+	github.com/savannahghi/enumutils v0.0.3
 	github.com/savannahghi/feedlib v0.0.6
 	github.com/savannahghi/firebasetools v0.0.15
 	github.com/savannahghi/interserviceclient v0.0.16
@@ -28,15 +34,17 @@ require (
 	github.com/segmentio/ksuid v1.0.4
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.0
+	github.com/teambition/rrule-go v1.7.2
 	github.com/vektah/gqlparser/v2 v2.1.0
 	go.opencensus.io v0.23.0
 	go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux v0.22.0 // indirect
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.22.0 // indirect
 	go.opentelemetry.io/otel/exporters/jaeger v1.0.0-RC2 // indirect
-	golang.org/x/net v0.0.0-20210813160813-60bc85c4be6d // indirect
-	golang.org/x/oauth2 v0.0.0-20210819190943-2bc19b11175f // indirect
+	golang.org/x/net v0.0.0-20210813160813-60bc85c4be6d
+	golang.org/x/oauth2 v0.0.0-20210819190943-2bc19b11175f
 	golang.org/x/sys v0.0.0-20210823070655-63515b42dcdf // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/api v0.54.0
 	google.golang.org/genproto v0.0.0-20210824181836-a4879c3d0e89 // indirect
+	google.golang.org/grpc v1.40.0
 )
@@ -1 +1,283 @@
 package dto
+
+import (
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// TemplateVariantInput is used to create or update the language specific
+// content of a notification template
+type TemplateVariantInput struct {
+	Language enumutils.Language `json:"language"`
+	Subject  *string            `json:"subject"`
+	Body     string             `json:"body"`
+}
+
+// NotificationTemplateInput is used to create or update a notification template
+type NotificationTemplateInput struct {
+	Name            string                  `json:"name"`
+	Description     string                  `json:"description"`
+	Channel         feedlib.Channel         `json:"channel"`
+	DefaultLanguage enumutils.Language      `json:"defaultLanguage"`
+	Variables       []string                `json:"variables"`
+	Variants        []*TemplateVariantInput `json:"variants"`
+}
+
+// TemplatedMessageInput is used to render a named template and send the
+// result over the template's channel.
+//
+// The recipients depend on the channel: user IDs for FCM, phone numbers
+// for SMS and WhatsApp or email addresses for email.
+//
+// Messages that set a flavour are subject to that flavour's rate limits.
+type TemplatedMessageInput struct {
+	TemplateName string                 `json:"templateName"`
+	Language     *enumutils.Language    `json:"language"`
+	Variables    map[string]interface{} `json:"variables"`
+	To           []string               `json:"to"`
+	Flavour      *feedlib.Flavour       `json:"flavour"`
+}
+
+// TemplatedEmailInput is used to render an email template to HTML and plain
+// text and send it.
+//
+// Attachments are the IDs of existing uploads.
+type TemplatedEmailInput struct {
+	TemplateName string                 `json:"templateName"`
+	Language     *enumutils.Language    `json:"language"`
+	Variables    map[string]interface{} `json:"variables"`
+	To           []string               `json:"to"`
+	Cc           []string               `json:"cc"`
+	Bcc          []string               `json:"bcc"`
+	Attachments  []string               `json:"attachments"`
+}
+
+// DeviceInput is used to register a device for push notifications
+type DeviceInput struct {
+	DeviceID   string                `json:"deviceID"`
+	Token      string                `json:"token"`
+	Platform   domain.DevicePlatform `json:"platform"`
+	AppVersion string                `json:"appVersion"`
+}
+
+// ScheduledNotificationInput is used to schedule a templated message.
+//
+// `Schedule` holds the cron expression or RRULE of a recurring notification
+// and is ignored for one-off notifications.
+type ScheduledNotificationInput struct {
+	Name         string                    `json:"name"`
+	TemplateName string                    `json:"templateName"`
+	Language     *enumutils.Language       `json:"language"`
+	Variables    map[string]interface{}    `json:"variables"`
+	To           []string                  `json:"to"`
+	Groups       []string                  `json:"groups"`
+	Flavour      *feedlib.Flavour          `json:"flavour"`
+	Recurrence   domain.ScheduleRecurrence `json:"recurrence"`
+	Schedule     *string                   `json:"schedule"`
+	Timezone     *string                   `json:"timezone"`
+	StartAt      time.Time                 `json:"startAt"`
+	EndAt        *time.Time                `json:"endAt"`
+}
+
+// RecipientGroupInput is used to create or replace a recipient group
+type RecipientGroupInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"`
+}
+
+// RateLimitPolicyInput is used to configure the rate limit of a flavour
+// and channel
+type RateLimitPolicyInput struct {
+	Flavour       feedlib.Flavour `json:"flavour"`
+	Channel       feedlib.Channel `json:"channel"`
+	Limit         int             `json:"limit"`
+	WindowSeconds int             `json:"windowSeconds"`
+}
+
+// OTPAppInput is used to register or update the OTP settings of an app.
+// Unset fields use the service's defaults.
+// It is the JSON body that other services post to register an app.
+type OTPAppInput struct {
+	AppID            string              `json:"appId"`
+	SenderID         *enumutils.SenderID `json:"senderId"`
+	MessageTemplate  *string             `json:"messageTemplate"`
+	Length           *int                `json:"length"`
+	ExpirySeconds    *int                `json:"expirySeconds"`
+	Channels         []feedlib.Channel   `json:"channels"`
+	SMSRetrieverHash *string             `json:"smsRetrieverHash"`
+}
+
+// SMSConversationInput is used to text a phone number about a feed item.
+// Replies from the phone number are posted to the item's conversation.
+type SMSConversationInput struct {
+	Phone   string             `json:"phone"`
+	UID     string             `json:"uid"`
+	Flavour feedlib.Flavour    `json:"flavour"`
+	ItemID  string             `json:"itemID"`
+	Message string             `json:"message"`
+	Sender  enumutils.SenderID `json:"sender"`
+}
+
+// SMSRateInput is used to set the price of an SMS segment to the phone
+// numbers that start with a prefix
+type SMSRateInput struct {
+	Prefix         string  `json:"prefix"`
+	Country        string  `json:"country"`
+	CostPerSegment float64 `json:"costPerSegment"`
+}
+
+// SMSBudgetInput is used to set the most that a single send of a flavour may
+// cost
+type SMSBudgetInput struct {
+	Flavour feedlib.Flavour `json:"flavour"`
+	MaxCost float64         `json:"maxCost"`
+}
+
+// EmailConversationInput is used to email an address about a feed item.
+// Replies to the email are posted to the item's conversation.
+type EmailConversationInput struct {
+	Email   string          `json:"email"`
+	UID     string          `json:"uid"`
+	Flavour feedlib.Flavour `json:"flavour"`
+	ItemID  string          `json:"itemID"`
+	Subject string          `json:"subject"`
+	Message string          `json:"message"`
+}
+
+// WhatsAppTemplateInput is used to send an approved WhatsApp template.
+// Parameters fill the template's placeholders in order.
+type WhatsAppTemplateInput struct {
+	To         string   `json:"to"`
+	TemplateID string   `json:"templateID"`
+	Parameters []string `json:"parameters"`
+}
+
+// WhatsAppMediaInput is used to send an image, document, audio or video
+// from a public URL over WhatsApp
+type WhatsAppMediaInput struct {
+	To       string  `json:"to"`
+	MediaURL string  `json:"mediaURL"`
+	Caption  *string `json:"caption"`
+}
+
+// WhatsAppConversationInput is used to message a phone number about a feed
+// item with an approved template. Replies from the phone number are posted to
+// the item's conversation.
+type WhatsAppConversationInput struct {
+	Phone      string          `json:"phone"`
+	UID        string          `json:"uid"`
+	Flavour    feedlib.Flavour `json:"flavour"`
+	ItemID     string          `json:"itemID"`
+	TemplateID string          `json:"templateID"`
+	Parameters []string        `json:"parameters"`
+}
+
+// WhatsAppNudgeInput is used to send a nudge to a phone number with a quick
+// reply template. The template's placeholders are the nudge's title and text,
+// and its buttons are named after the nudge's actions.
+type WhatsAppNudgeInput struct {
+	Phone      string          `json:"phone"`
+	UID        string          `json:"uid"`
+	Flavour    feedlib.Flavour `json:"flavour"`
+	NudgeID    string          `json:"nudgeID"`
+	TemplateID string          `json:"templateID"`
+}
+
+// VideoRoomInput is used to schedule a video room, or change a scheduled
+// room. A room is for either an appointment or a feed item, whose flavour is
+// also required. Participants are the UIDs of the users that can join it.
+type VideoRoomInput struct {
+	AppointmentID  *string          `json:"appointmentID"`
+	ItemID         *string          `json:"itemID"`
+	Flavour        *feedlib.Flavour `json:"flavour"`
+	Participants   []string         `json:"participants"`
+	ScheduledStart time.Time        `json:"scheduledStart"`
+	ScheduledEnd   time.Time        `json:"scheduledEnd"`
+}
+
+// VideoRoomEvent is a video room status callback from the video provider
+// e.g `participant-connected`. Participant fields are only set for
+// participant events.
+type VideoRoomEvent struct {
+	Event               string    `json:"event"`
+	RoomSID             string    `json:"roomSID"`
+	RoomName            string    `json:"roomName"`
+	ParticipantSID      string    `json:"participantSID"`
+	ParticipantIdentity string    `json:"participantIdentity"`
+	ParticipantDuration int       `json:"participantDuration"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// AppointmentInput is used to schedule an appointment, or change a scheduled
+// appointment. Start and end are shown to attendees in the time zone, which
+// is an IANA time zone name e.g `Africa/Nairobi`. Reminders are minutes
+// before the start; the default reminders are used when they are nil.
+type AppointmentInput struct {
+	Summary         string                      `json:"summary"`
+	Description     *string                     `json:"description"`
+	Location        *string                     `json:"location"`
+	Start           time.Time                   `json:"start"`
+	End             time.Time                   `json:"end"`
+	TimeZone        *string                     `json:"timeZone"`
+	Attendees       []*AppointmentAttendeeInput `json:"attendees"`
+	ReminderMinutes []int                       `json:"reminderMinutes"`
+}
+
+// AppointmentAttendeeInput is an attendee of an appointment. Attendees are
+// emailed about the appointment, and sent an SMS when they have a phone
+// number.
+type AppointmentAttendeeInput struct {
+	DisplayName *string `json:"displayName"`
+	Email       string  `json:"email"`
+	Phone       *string `json:"phone"`
+	Optional    bool    `json:"optional"`
+}
+
+// ClinicianAvailabilityInput is used to set when a clinician can be booked at
+// a location. Working hours are `HH:MM` local times in the time zone, which
+// is an IANA time zone name e.g `Africa/Nairobi`.
+type ClinicianAvailabilityInput struct {
+	ClinicianID    string               `json:"clinicianID"`
+	ClinicianEmail string               `json:"clinicianEmail"`
+	LocationID     string               `json:"locationID"`
+	Location       *string              `json:"location"`
+	TimeZone       *string              `json:"timeZone"`
+	WorkingHours   []*WorkingHoursInput `json:"workingHours"`
+	SlotMinutes    int                  `json:"slotMinutes"`
+	BufferMinutes  int                  `json:"bufferMinutes"`
+}
+
+// WorkingHoursInput is a period of a day of the week that a clinician works
+type WorkingHoursInput struct {
+	Day   domain.Weekday `json:"day"`
+	Start string         `json:"start"`
+	End   string         `json:"end"`
+}
+
+// AvailabilityExceptionInput is used to add leave or extra hours to a
+// clinician's availability. Exceptions without a location apply at all of
+// the clinician's locations.
+type AvailabilityExceptionInput struct {
+	ClinicianID string    `json:"clinicianID"`
+	LocationID  *string   `json:"locationID"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Available   bool      `json:"available"`
+	Reason      *string   `json:"reason"`
+}
+
+// BookSlotInput is used to book an appointment in one of a clinician's free
+// slots. The clinician is added to the appointment's attendees.
+type BookSlotInput struct {
+	ClinicianID     string                      `json:"clinicianID"`
+	LocationID      string                      `json:"locationID"`
+	Start           time.Time                   `json:"start"`
+	Summary         string                      `json:"summary"`
+	Description     *string                     `json:"description"`
+	Attendees       []*AppointmentAttendeeInput `json:"attendees"`
+	ReminderMinutes []int                       `json:"reminderMinutes"`
+}
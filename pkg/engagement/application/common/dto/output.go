@@ -1 +1,76 @@
 package dto
+
+import (
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+)
+
+// RenderedTemplate is the result of rendering a notification template with
+// a set of variables
+type RenderedTemplate struct {
+	TemplateName string             `json:"templateName"`
+	Channel      feedlib.Channel    `json:"channel"`
+	Language     enumutils.Language `json:"language"`
+	Subject      *string            `json:"subject"`
+	Body         string             `json:"body"`
+}
+
+// FCMSendResult is the outcome of sending a multicast FCM message
+type FCMSendResult struct {
+	SuccessCount int `json:"successCount"`
+	FailureCount int `json:"failureCount"`
+
+	// MessageIDs maps the registration tokens that were sent to onto
+	// the FCM message ID
+	MessageIDs map[string]string `json:"messageIDs"`
+
+	// Failures maps the registration tokens that failed onto the reason
+	Failures map[string]string `json:"failures"`
+
+	// UnregisteredTokens are tokens that FCM reports are no longer valid
+	UnregisteredTokens []string `json:"unregisteredTokens"`
+}
+
+// InboxConnection is a page of a user's notification inbox
+type InboxConnection struct {
+	Edges       []*InboxEdge            `json:"edges"`
+	PageInfo    *firebasetools.PageInfo `json:"pageInfo"`
+	UnreadCount int                     `json:"unreadCount"`
+}
+
+// InboxEdge is a notification in a page of a user's inbox
+type InboxEdge struct {
+	Cursor string                    `json:"cursor"`
+	Node   *domain.InboxNotification `json:"node"`
+}
+
+// TOTPProvisioning is returned when an authenticator app is enrolled. The
+// secret is only shown once.
+type TOTPProvisioning struct {
+	Secret string `json:"secret"`
+
+	// ProvisioningURI is an otpauth:// URI that is usually shown as a QR code
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+// VideoRoomToken is returned to a participant that is joining a video room.
+// The token only lets the participant's identity join the room.
+type VideoRoomToken struct {
+	JWT       string    `json:"jwt"`
+	RoomID    string    `json:"roomID"`
+	RoomSID   string    `json:"roomSID"`
+	Identity  string    `json:"identity"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// FreeSlot is a period that a clinician can be booked for at a location
+type FreeSlot struct {
+	ClinicianID string    `json:"clinicianID"`
+	LocationID  string    `json:"locationID"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
@@ -1 +1,190 @@
 package exceptions
+
+import "fmt"
+
+// ErrTemplateNotFound is a sentinel error used to indicate that a
+// notification template with the supplied name does not exist
+var ErrTemplateNotFound = fmt.Errorf("notification template not found")
+
+// ErrTemplateExists is a sentinel error used to indicate that a notification
+// template with the supplied name has already been created
+var ErrTemplateExists = fmt.Errorf("notification template already exists")
+
+// ErrDeviceNotFound is a sentinel error used to indicate that a user
+// has not registered a device with the supplied ID
+var ErrDeviceNotFound = fmt.Errorf("device not found")
+
+// ErrInboxNotificationNotFound is a sentinel error used to indicate that a
+// notification is not in the user's inbox
+var ErrInboxNotificationNotFound = fmt.Errorf("inbox notification not found")
+
+// ErrInvalidCursor is a sentinel error used to indicate that a pagination
+// cursor could not be decoded
+var ErrInvalidCursor = fmt.Errorf("invalid pagination cursor")
+
+// ErrScheduledNotificationNotFound is a sentinel error used to indicate that
+// a scheduled notification with the supplied ID does not exist
+var ErrScheduledNotificationNotFound = fmt.Errorf("scheduled notification not found")
+
+// ErrRecipientGroupNotFound is a sentinel error used to indicate that a
+// recipient group with the supplied name does not exist
+var ErrRecipientGroupNotFound = fmt.Errorf("recipient group not found")
+
+// ErrRateLimitPolicyNotFound is a sentinel error used to indicate that a rate
+// limit has not been configured for a flavour and channel
+var ErrRateLimitPolicyNotFound = fmt.Errorf("rate limit policy not found")
+
+// ErrDigestNotFound is a sentinel error used to indicate that a notification
+// digest does not exist e.g because it has already been sent
+var ErrDigestNotFound = fmt.Errorf("notification digest not found")
+
+// ErrOTPNotFound is a sentinel error used to indicate that a one time
+// password does not exist
+var ErrOTPNotFound = fmt.Errorf("OTP not found")
+
+// ErrTooManyOTPAttempts is a sentinel error used to indicate that OTPs are
+// being generated or verified too often and the caller should wait
+var ErrTooManyOTPAttempts = fmt.Errorf("too many OTP attempts")
+
+// ErrOTPAppNotFound is a sentinel error used to indicate that an app has not
+// been registered for OTPs
+var ErrOTPAppNotFound = fmt.Errorf("OTP app not found")
+
+// ErrOTPChannelNotAllowed is a sentinel error used to indicate that an app's
+// codes can't be sent over the requested channel
+var ErrOTPChannelNotAllowed = fmt.Errorf("OTP channel not allowed for app")
+
+// ErrTOTPEnrolmentNotFound is a sentinel error used to indicate that a phone
+// number has not enrolled an authenticator app
+var ErrTOTPEnrolmentNotFound = fmt.Errorf("TOTP enrolment not found")
+
+// ErrEmailVerificationLinkNotFound is a sentinel error used to indicate that
+// an email verification link does not exist
+var ErrEmailVerificationLinkNotFound = fmt.Errorf("email verification link not found")
+
+// ErrInvalidEmailVerificationLink is a sentinel error used to indicate that an
+// email verification link is forged, expired, revoked or has been used
+var ErrInvalidEmailVerificationLink = fmt.Errorf("invalid email verification link")
+
+// ErrBulkSMSJobNotFound is a sentinel error used to indicate that a bulk SMS
+// job does not exist
+var ErrBulkSMSJobNotFound = fmt.Errorf("bulk SMS job not found")
+
+// ErrChannelPreferenceNotFound is a sentinel error used to indicate that an
+// address has not set a preference for a channel
+var ErrChannelPreferenceNotFound = fmt.Errorf("channel preference not found")
+
+// ErrSMSConversationNotFound is a sentinel error used to indicate that a
+// phone number is not in an SMS conversation
+var ErrSMSConversationNotFound = fmt.Errorf("SMS conversation not found")
+
+// ErrSMSDeliveryReportNotFound is a sentinel error used to indicate that no
+// delivery report has been received for a message
+var ErrSMSDeliveryReportNotFound = fmt.Errorf("SMS delivery report not found")
+
+// ErrInvalidSMSCallbackSignature is a sentinel error used to indicate that an
+// SMS provider callback was not signed with the callback signing key
+var ErrInvalidSMSCallbackSignature = fmt.Errorf("invalid SMS callback signature")
+
+// ErrSMSRateNotFound is a sentinel error used to indicate that no SMS rate
+// has been set for a prefix
+var ErrSMSRateNotFound = fmt.Errorf("SMS rate not found")
+
+// ErrSMSBudgetNotFound is a sentinel error used to indicate that no SMS
+// budget has been set for a flavour
+var ErrSMSBudgetNotFound = fmt.Errorf("SMS budget not found")
+
+// ErrSMSBudgetExceeded is a sentinel error used to indicate that an SMS was
+// not sent because its estimated cost is over the budget of its flavour
+var ErrSMSBudgetExceeded = fmt.Errorf("SMS budget exceeded")
+
+// ErrEmailSuppressionNotFound is a sentinel error used to indicate that an
+// email address is not on the suppression list
+var ErrEmailSuppressionNotFound = fmt.Errorf("email suppression not found")
+
+// ErrEmailRecipientsSuppressed is a sentinel error used to indicate that an
+// email was not sent because every recipient is on the suppression list
+var ErrEmailRecipientsSuppressed = fmt.Errorf("every email recipient is suppressed")
+
+// ErrInvalidEmailWebhookSignature is a sentinel error used to indicate that
+// an email provider webhook was not signed with the webhook signing key, or
+// that its signature is too old
+var ErrInvalidEmailWebhookSignature = fmt.Errorf("invalid email webhook signature")
+
+// ErrInvalidEmailUnsubscribeSignature is a sentinel error used to indicate
+// that an unsubscribe link was not signed for its email address
+var ErrInvalidEmailUnsubscribeSignature = fmt.Errorf("invalid email unsubscribe signature")
+
+// ErrEmailConversationNotFound is a sentinel error used to indicate that
+// there is no email conversation with a given ID
+var ErrEmailConversationNotFound = fmt.Errorf("email conversation not found")
+
+// ErrInboundEmailNotFound is a sentinel error used to indicate that an
+// inbound email has not been received
+var ErrInboundEmailNotFound = fmt.Errorf("inbound email not found")
+
+// ErrInvalidWhatsAppWebhookSignature is a sentinel error used to indicate
+// that a WhatsApp webhook was not signed with the provider's auth token
+var ErrInvalidWhatsAppWebhookSignature = fmt.Errorf("invalid WhatsApp webhook signature")
+
+// ErrWhatsAppSessionNotFound is a sentinel error used to indicate that a
+// phone number has never sent us a WhatsApp message
+var ErrWhatsAppSessionNotFound = fmt.Errorf("WhatsApp session not found")
+
+// ErrWhatsAppSessionExpired is a sentinel error used to indicate that a
+// WhatsApp message that is not a template was not sent because the
+// recipient has not messaged us in the last 24 hours
+var ErrWhatsAppSessionExpired = fmt.Errorf("WhatsApp session expired")
+
+// ErrInboundWhatsAppNotFound is a sentinel error used to indicate that a
+// WhatsApp message has not been received
+var ErrInboundWhatsAppNotFound = fmt.Errorf("inbound WhatsApp message not found")
+
+// ErrWhatsAppConversationNotFound is a sentinel error used to indicate that
+// a phone number is not in a WhatsApp conversation
+var ErrWhatsAppConversationNotFound = fmt.Errorf("WhatsApp conversation not found")
+
+// ErrWhatsAppNudgeNotFound is a sentinel error used to indicate that a
+// phone number has no unanswered WhatsApp nudge
+var ErrWhatsAppNudgeNotFound = fmt.Errorf("WhatsApp nudge not found")
+
+// ErrVideoRoomNotFound is a sentinel error used to indicate that a video
+// room has not been scheduled
+var ErrVideoRoomNotFound = fmt.Errorf("video room not found")
+
+// ErrVideoParticipantNotFound is a sentinel error used to indicate that a
+// participant's connection to a video room has not been reported
+var ErrVideoParticipantNotFound = fmt.Errorf("video participant not found")
+
+// ErrNotVideoRoomParticipant is a sentinel error used to indicate that a
+// user asked for a token to a video room that they are not a participant of
+var ErrNotVideoRoomParticipant = fmt.Errorf("not a participant of the video room")
+
+// ErrInvalidVideoCallbackSignature is a sentinel error used to indicate
+// that a video room status callback was not signed by the video provider
+var ErrInvalidVideoCallbackSignature = fmt.Errorf("invalid video callback signature")
+
+// ErrCalendarEventNotFound is a sentinel error used to indicate that an
+// event is not in the service's own calendar
+var ErrCalendarEventNotFound = fmt.Errorf("calendar event not found")
+
+// ErrInvalidAppointmentICSSignature is a sentinel error used to indicate
+// that an appointment's iCalendar link was not signed by the service or has
+// expired
+var ErrInvalidAppointmentICSSignature = fmt.Errorf("invalid appointment calendar link signature")
+
+// ErrClinicianAvailabilityNotFound is a sentinel error used to indicate that
+// a clinician has no availability at a location
+var ErrClinicianAvailabilityNotFound = fmt.Errorf("clinician availability not found")
+
+// ErrAvailabilityExceptionNotFound is a sentinel error used to indicate that
+// an availability exception does not exist
+var ErrAvailabilityExceptionNotFound = fmt.Errorf("availability exception not found")
+
+// ErrSlotBookingNotFound is a sentinel error used to indicate that an
+// appointment was not booked into a slot
+var ErrSlotBookingNotFound = fmt.Errorf("slot booking not found")
+
+// ErrSlotUnavailable is a sentinel error used to indicate that a slot is not
+// free, e.g because it was booked by a concurrent request
+var ErrSlotUnavailable = fmt.Errorf("the slot is not available")
@@ -0,0 +1,51 @@
+package helpers_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		want      string
+	}{
+		{
+			name: "no proxy",
+			want: "192.0.2.1",
+		},
+		{
+			name:      "behind the load balancer",
+			forwarded: []string{"197.248.0.1"},
+			want:      "197.248.0.1",
+		},
+		{
+			name:      "spoofed header",
+			forwarded: []string{"10.0.0.1, 197.248.0.1"},
+			want:      "197.248.0.1",
+		},
+		{
+			name:      "spoofed header sent separately",
+			forwarded: []string{"10.0.0.1", "197.248.0.1"},
+			want:      "197.248.0.1",
+		},
+		{
+			name:      "empty header",
+			forwarded: []string{""},
+			want:      "192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/graphql", nil)
+			for _, forwarded := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+			assert.Equal(t, tt.want, helpers.ClientIP(r))
+		})
+	}
+}
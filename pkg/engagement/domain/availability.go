@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxSlotDuration is the longest slot that a clinician can be booked for
+	MaxSlotDuration = 4 * time.Hour
+
+	// MaxSlotBuffer is the longest break that a clinician can have between
+	// bookings
+	MaxSlotBuffer = 2 * time.Hour
+)
+
+// ClinicianAvailability is when a clinician can be booked at a location.
+//
+// Slots of `SlotMinutes` are laid out from the start of each working period,
+// with `BufferMinutes` between them. The working hours are in the time zone.
+type ClinicianAvailability struct {
+	ID             string          `json:"id" firestore:"id"`
+	ClinicianID    string          `json:"clinicianID" firestore:"clinicianID"`
+	ClinicianEmail string          `json:"clinicianEmail" firestore:"clinicianEmail"`
+	LocationID     string          `json:"locationID" firestore:"locationID"`
+	Location       string          `json:"location" firestore:"location"`
+	TimeZone       string          `json:"timeZone" firestore:"timeZone"`
+	WorkingHours   []*WorkingHours `json:"workingHours" firestore:"workingHours"`
+	SlotMinutes    int             `json:"slotMinutes" firestore:"slotMinutes"`
+	BufferMinutes  int             `json:"bufferMinutes" firestore:"bufferMinutes"`
+	UpdatedBy      string          `json:"updatedBy" firestore:"updatedBy"`
+	UpdatedAt      time.Time       `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// SlotDuration is the length of the clinician's slots
+func (a ClinicianAvailability) SlotDuration() time.Duration {
+	return time.Duration(a.SlotMinutes) * time.Minute
+}
+
+// Buffer is the break that the clinician has between bookings
+func (a ClinicianAvailability) Buffer() time.Duration {
+	return time.Duration(a.BufferMinutes) * time.Minute
+}
+
+// WorkingHours is a period of a day of the week that a clinician works. The
+// start and end are `HH:MM` local times.
+type WorkingHours struct {
+	Day   Weekday `json:"day" firestore:"day"`
+	Start string  `json:"start" firestore:"start"`
+	End   string  `json:"end" firestore:"end"`
+}
+
+// AvailabilityException changes when a clinician can be booked, e.g for
+// leave or extra clinic hours. Exceptions without a location apply at all
+// of the clinician's locations.
+//
+// Unavailable exceptions remove the slots that they overlap, while available
+// exceptions are worked like extra working hours.
+type AvailabilityException struct {
+	ID          string    `json:"id" firestore:"id"`
+	ClinicianID string    `json:"clinicianID" firestore:"clinicianID"`
+	LocationID  string    `json:"locationID" firestore:"locationID"`
+	Start       time.Time `json:"start" firestore:"start"`
+	End         time.Time `json:"end" firestore:"end"`
+	Available   bool      `json:"available" firestore:"available"`
+	Reason      string    `json:"reason" firestore:"reason"`
+	CreatedBy   string    `json:"createdBy" firestore:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// AppliesTo returns true if an exception changes the availability of a
+// clinician at a location
+func (e AvailabilityException) AppliesTo(locationID string) bool {
+	return e.LocationID == "" || e.LocationID == locationID
+}
+
+// SlotBooking reserves a clinician's time for an appointment. A clinician
+// can only have one booking at a time, at any location, with a buffer
+// between bookings.
+type SlotBooking struct {
+	ID          string            `json:"id" firestore:"id"`
+	ClinicianID string            `json:"clinicianID" firestore:"clinicianID"`
+	LocationID  string            `json:"locationID" firestore:"locationID"`
+	Start       time.Time         `json:"start" firestore:"start"`
+	End         time.Time         `json:"end" firestore:"end"`
+	Status      SlotBookingStatus `json:"status" firestore:"status"`
+
+	// EventID is the calendar event of the appointment. It is empty
+	// while the appointment is being created.
+	EventID   string    `json:"eventID" firestore:"eventID"`
+	BookedBy  string    `json:"bookedBy" firestore:"bookedBy"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Conflicts returns true if an active booking is within a buffer of an
+// interval
+func (b SlotBooking) Conflicts(start, end time.Time, buffer time.Duration) bool {
+	return b.Status == SlotBookingStatusBooked &&
+		b.Start.Before(end.Add(buffer)) &&
+		b.End.Add(buffer).After(start)
+}
+
+// Weekday is a day of the week
+type Weekday string
+
+// Weekday values
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var weekdays = map[Weekday]time.Weekday{
+	WeekdayMonday:    time.Monday,
+	WeekdayTuesday:   time.Tuesday,
+	WeekdayWednesday: time.Wednesday,
+	WeekdayThursday:  time.Thursday,
+	WeekdayFriday:    time.Friday,
+	WeekdaySaturday:  time.Saturday,
+	WeekdaySunday:    time.Sunday,
+}
+
+// IsValid returns true if a weekday is valid
+func (e Weekday) IsValid() bool {
+	_, ok := weekdays[e]
+	return ok
+}
+
+// Weekday converts a weekday to the standard library's weekday
+func (e Weekday) Weekday() time.Weekday {
+	return weekdays[e]
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a weekday.
+func (e *Weekday) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the weekday to the supplied writer
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// SlotBookingStatus is the state of a slot booking
+type SlotBookingStatus string
+
+// SlotBookingStatus values
+const (
+	// SlotBookingStatusBooked bookings hold the clinician's time
+	SlotBookingStatusBooked SlotBookingStatus = "BOOKED"
+
+	// SlotBookingStatusCancelled bookings have released the clinician's
+	// time
+	SlotBookingStatusCancelled SlotBookingStatus = "CANCELLED"
+)
+
+// IsValid returns true if a slot booking status is valid
+func (e SlotBookingStatus) IsValid() bool {
+	switch e {
+	case SlotBookingStatusBooked, SlotBookingStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e SlotBookingStatus) String() string {
+	return string(e)
+}
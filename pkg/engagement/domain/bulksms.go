@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/enumutils"
+)
+
+// BulkSMSJob is an SMS to many recipients that is sent in the background,
+// a chunk of recipients at a time.
+//
+// The status of each recipient is stored separately as a BulkSMSRecipient
+// so that a job can be resumed where it stopped.
+type BulkSMSJob struct {
+	ID      string             `json:"id" firestore:"id"`
+	Message string             `json:"message" firestore:"message"`
+	Sender  enumutils.SenderID `json:"sender" firestore:"sender"`
+	Status  BulkSMSJobStatus   `json:"status" firestore:"status"`
+
+	// Total is the number of recipients. Sent, Failed and Unknown count the
+	// recipients that have been processed.
+	Total   int `json:"total" firestore:"total"`
+	Sent    int `json:"sent" firestore:"sent"`
+	Failed  int `json:"failed" firestore:"failed"`
+	Unknown int `json:"unknown" firestore:"unknown"`
+
+	// LeaseExpiresAt is set while an instance of the service is sending the
+	// job so that other instances do not send it too
+	LeaseExpiresAt *time.Time `json:"-" firestore:"leaseExpiresAt"`
+
+	// RetryAt is set when the SMS provider could not be reached. The job is
+	// not sent again before then.
+	RetryAt *time.Time `json:"retryAt" firestore:"retryAt"`
+
+	CreatedBy   string     `json:"createdBy" firestore:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt" firestore:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt" firestore:"completedAt"`
+}
+
+// Progress returns the fraction of the job's recipients that have been
+// processed, between 0 and 1
+func (j BulkSMSJob) Progress() float64 {
+	if j.Total == 0 {
+		return 1
+	}
+	return float64(j.Sent+j.Failed+j.Unknown) / float64(j.Total)
+}
+
+// BulkSMSRecipient is the delivery status of one recipient of a bulk SMS job
+type BulkSMSRecipient struct {
+	JobID  string                 `json:"jobID" firestore:"jobID"`
+	Index  int                    `json:"index" firestore:"index"`
+	Number string                 `json:"number" firestore:"number"`
+	Status BulkSMSRecipientStatus `json:"status" firestore:"status"`
+
+	// Cost and MessageID are reported by the SMS provider for sent messages
+	Cost      string `json:"cost" firestore:"cost"`
+	MessageID string `json:"messageID" firestore:"messageID"`
+
+	// Error is why the message was not sent
+	Error  string     `json:"error" firestore:"error"`
+	SentAt *time.Time `json:"sentAt" firestore:"sentAt"`
+
+	// Attempts is the number of times the recipient's chunk could not be
+	// sent because the SMS provider could not be reached
+	Attempts int `json:"attempts" firestore:"attempts"`
+
+	// DeliveryStatus is the latest status from the provider's delivery
+	// reports e.g `Success` once the message reached the phone
+	DeliveryStatus    string     `json:"deliveryStatus" firestore:"deliveryStatus"`
+	DeliveryUpdatedAt *time.Time `json:"deliveryUpdatedAt" firestore:"deliveryUpdatedAt"`
+}
+
+// BulkSMSJobStatus is the state of a bulk SMS job
+type BulkSMSJobStatus string
+
+// BulkSMSJobStatus values
+const (
+	BulkSMSJobStatusPending   BulkSMSJobStatus = "PENDING"
+	BulkSMSJobStatusRunning   BulkSMSJobStatus = "RUNNING"
+	BulkSMSJobStatusCompleted BulkSMSJobStatus = "COMPLETED"
+	BulkSMSJobStatusCancelled BulkSMSJobStatus = "CANCELLED"
+)
+
+// IsValid returns true if a bulk SMS job status is valid
+func (e BulkSMSJobStatus) IsValid() bool {
+	switch e {
+	case BulkSMSJobStatusPending, BulkSMSJobStatusRunning,
+		BulkSMSJobStatusCompleted, BulkSMSJobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsFinal returns true if a job in this status will not send any more
+// messages
+func (e BulkSMSJobStatus) IsFinal() bool {
+	return e == BulkSMSJobStatusCompleted || e == BulkSMSJobStatusCancelled
+}
+
+func (e BulkSMSJobStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a bulk SMS job status.
+func (e *BulkSMSJobStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BulkSMSJobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BulkSMSJobStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the bulk SMS job status to the supplied writer
+func (e BulkSMSJobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// BulkSMSRecipientStatus is the delivery state of a recipient of a bulk SMS
+type BulkSMSRecipientStatus string
+
+// BulkSMSRecipientStatus values
+const (
+	BulkSMSRecipientStatusPending BulkSMSRecipientStatus = "PENDING"
+
+	// BulkSMSRecipientStatusSending is set while the recipient's chunk is
+	// with the SMS provider
+	BulkSMSRecipientStatusSending BulkSMSRecipientStatus = "SENDING"
+
+	BulkSMSRecipientStatusSent   BulkSMSRecipientStatus = "SENT"
+	BulkSMSRecipientStatusFailed BulkSMSRecipientStatus = "FAILED"
+
+	// BulkSMSRecipientStatusUnknown is set when the service stopped while
+	// the recipient's chunk was with the SMS provider, or when the provider
+	// accepted the chunk but did not report on the recipient. The message
+	// may have been sent so it is not sent again.
+	BulkSMSRecipientStatusUnknown BulkSMSRecipientStatus = "UNKNOWN"
+)
+
+// IsValid returns true if a bulk SMS recipient status is valid
+func (e BulkSMSRecipientStatus) IsValid() bool {
+	switch e {
+	case BulkSMSRecipientStatusPending, BulkSMSRecipientStatusSending,
+		BulkSMSRecipientStatusSent, BulkSMSRecipientStatusFailed,
+		BulkSMSRecipientStatusUnknown:
+		return true
+	}
+	return false
+}
+
+func (e BulkSMSRecipientStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a bulk SMS recipient status.
+func (e *BulkSMSRecipientStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BulkSMSRecipientStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BulkSMSRecipientStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the bulk SMS recipient status to the supplied writer
+func (e BulkSMSRecipientStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
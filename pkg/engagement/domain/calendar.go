@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// MaxCalendarEventDuration is the longest event that the service's own
+// calendar keeps. Events that overlap an interval are found among the events
+// that start up to this long before it.
+const MaxCalendarEventDuration = 24 * time.Hour
+
+// StoredCalendarEvent is an event in the service's own calendar, which is
+// used instead of a Google calendar for local development.
+//
+// The event is kept as Google Calendar API JSON so that it reads back
+// exactly as it was written. Its start, end and status are kept alongside it
+// so that events can be queried.
+type StoredCalendarEvent struct {
+	ID     string    `json:"id" firestore:"id"`
+	Start  time.Time `json:"start" firestore:"start"`
+	End    time.Time `json:"end" firestore:"end"`
+	Status string    `json:"status" firestore:"status"`
+	Event  string    `json:"event" firestore:"event"`
+}
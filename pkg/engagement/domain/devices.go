@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Device is a user's device that has registered to receive push notifications.
+//
+// A registration token belongs to exactly one device. When the same token is
+// registered again e.g after the app is reinstalled, the previous registration
+// is replaced.
+type Device struct {
+	ID         string         `json:"id" firestore:"id"`
+	UID        string         `json:"uid" firestore:"uid"`
+	Token      string         `json:"token" firestore:"token"`
+	Platform   DevicePlatform `json:"platform" firestore:"platform"`
+	AppVersion string         `json:"appVersion" firestore:"appVersion"`
+	CreatedAt  time.Time      `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// DevicePlatform is the operating system a push token was issued for
+type DevicePlatform string
+
+// DevicePlatform values
+const (
+	DevicePlatformAndroid DevicePlatform = "ANDROID"
+	DevicePlatformIOS     DevicePlatform = "IOS"
+	DevicePlatformWeb     DevicePlatform = "WEB"
+)
+
+// IsValid returns true if a device platform is valid
+func (e DevicePlatform) IsValid() bool {
+	switch e {
+	case DevicePlatformAndroid, DevicePlatformIOS, DevicePlatformWeb:
+		return true
+	}
+	return false
+}
+
+func (e DevicePlatform) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a device platform.
+func (e *DevicePlatform) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DevicePlatform(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DevicePlatform", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the device platform to the supplied writer
+func (e DevicePlatform) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// EmailConversation links an email address to a feed item so that replies
+// to the emails sent about the item are posted to its conversation
+type EmailConversation struct {
+	ID        string          `json:"id" firestore:"id"`
+	Email     string          `json:"email" firestore:"email"`
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	ItemID    string          `json:"itemID" firestore:"itemID"`
+	Subject   string          `json:"subject" firestore:"subject"`
+	CreatedBy string          `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time       `json:"createdAt" firestore:"createdAt"`
+}
+
+// InboundEmail is an email that was sent to one of our reply addresses
+type InboundEmail struct {
+	ID        string `json:"id" firestore:"id"`
+	MessageID string `json:"messageID" firestore:"messageID"`
+	From      string `json:"from" firestore:"from"`
+	Recipient string `json:"recipient" firestore:"recipient"`
+	Subject   string `json:"subject" firestore:"subject"`
+
+	// Text is the reply without the quoted history of the conversation
+	Text string `json:"text" firestore:"text"`
+
+	// Attachments are the IDs of the uploads that the email's attachments
+	// were saved as
+	Attachments []string `json:"attachments" firestore:"attachments"`
+
+	// ConversationID is set when the email was posted to a feed item's
+	// conversation
+	ConversationID string `json:"conversationID" firestore:"conversationID"`
+
+	ReceivedAt time.Time `json:"receivedAt" firestore:"receivedAt"`
+}
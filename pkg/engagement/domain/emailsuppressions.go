@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// EmailSuppression is an email address that is not sent email because it
+// bounced, its owner marked our email as spam or unsubscribed
+type EmailSuppression struct {
+	Email  string                 `json:"email" firestore:"email"`
+	Reason EmailSuppressionReason `json:"reason" firestore:"reason"`
+
+	// Description is what the email provider reported e.g the bounce
+	// message of the receiving server
+	Description string `json:"description" firestore:"description"`
+
+	// MessageID is the ID of the email that bounced or was complained
+	// about, when it is known
+	MessageID string    `json:"messageID" firestore:"messageID"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// SuppressesTransactional reports whether transactional emails e.g one time
+// passwords are also not sent to the address. Only notifications stop when
+// an address unsubscribes.
+func (s EmailSuppression) SuppressesTransactional() bool {
+	return s.Reason != EmailSuppressionReasonUnsubscribe
+}
+
+// EmailEvent is an event that the email provider reports about a sent email
+type EmailEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+
+	// Severity tells permanent failures, which are bounces, from temporary
+	// ones that the provider retries
+	Severity    string    `json:"severity"`
+	Recipient   string    `json:"recipient"`
+	MessageID   string    `json:"messageID"`
+	Description string    `json:"description"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// EmailSuppressionReason is why an email address is suppressed
+type EmailSuppressionReason string
+
+// EmailSuppressionReason values
+const (
+	// EmailSuppressionReasonBounce is a permanent delivery failure e.g the
+	// mailbox does not exist
+	EmailSuppressionReasonBounce EmailSuppressionReason = "BOUNCE"
+
+	// EmailSuppressionReasonComplaint is set when the recipient marked an
+	// email as spam
+	EmailSuppressionReasonComplaint EmailSuppressionReason = "COMPLAINT"
+
+	// EmailSuppressionReasonUnsubscribe is set when the recipient
+	// unsubscribed from notification emails
+	EmailSuppressionReasonUnsubscribe EmailSuppressionReason = "UNSUBSCRIBE"
+)
+
+// IsValid returns true if an email suppression reason is valid
+func (e EmailSuppressionReason) IsValid() bool {
+	switch e {
+	case EmailSuppressionReasonBounce,
+		EmailSuppressionReasonComplaint,
+		EmailSuppressionReasonUnsubscribe:
+		return true
+	}
+	return false
+}
+
+func (e EmailSuppressionReason) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to an email suppression reason.
+func (e *EmailSuppressionReason) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = EmailSuppressionReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid EmailSuppressionReason", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the email suppression reason to the supplied writer
+func (e EmailSuppressionReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
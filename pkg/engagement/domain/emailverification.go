@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// EmailVerificationStatus is the state of an email verification link
+type EmailVerificationStatus string
+
+// EmailVerificationStatus values
+const (
+	EmailVerificationStatusPending  EmailVerificationStatus = "PENDING"
+	EmailVerificationStatusVerified EmailVerificationStatus = "VERIFIED"
+	EmailVerificationStatusExpired  EmailVerificationStatus = "EXPIRED"
+	EmailVerificationStatusRevoked  EmailVerificationStatus = "REVOKED"
+)
+
+// IsValid returns true if an email verification status is valid
+func (e EmailVerificationStatus) IsValid() bool {
+	switch e {
+	case EmailVerificationStatusPending,
+		EmailVerificationStatusVerified,
+		EmailVerificationStatusExpired,
+		EmailVerificationStatusRevoked:
+		return true
+	}
+	return false
+}
+
+func (e EmailVerificationStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to an email verification status.
+func (e *EmailVerificationStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = EmailVerificationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid EmailVerificationStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the email verification status to the supplied writer
+func (e EmailVerificationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// EmailVerificationLink is a signed, single use link that verifies an email
+// address when it is opened.
+//
+// Only the link's ID is stored. The token in the link is the ID and its
+// signature.
+type EmailVerificationLink struct {
+	ID         string     `json:"id" firestore:"id"`
+	Email      string     `json:"email" firestore:"email"`
+	CreatedAt  time.Time  `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt" firestore:"expiresAt"`
+	VerifiedAt *time.Time `json:"verifiedAt" firestore:"verifiedAt"`
+	RevokedAt  *time.Time `json:"revokedAt" firestore:"revokedAt"`
+
+	// Status is computed when the link is read
+	Status EmailVerificationStatus `json:"status" firestore:"-"`
+}
+
+// IsValid returns true if the link can still be used to verify its email
+func (l EmailVerificationLink) IsValid(now time.Time) bool {
+	return l.VerifiedAt == nil && l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// StatusAt returns the status of the link at `now`
+func (l EmailVerificationLink) StatusAt(now time.Time) EmailVerificationStatus {
+	switch {
+	case l.VerifiedAt != nil:
+		return EmailVerificationStatusVerified
+	case l.RevokedAt != nil:
+		return EmailVerificationStatusRevoked
+	case !now.Before(l.ExpiresAt):
+		return EmailVerificationStatusExpired
+	}
+	return EmailVerificationStatusPending
+}
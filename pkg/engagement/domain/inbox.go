@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// InboxNotification is a notification that was sent to a user, kept so that
+// the user can review it later in the app's notification center.
+//
+// Unlike a SavedNotification, which is recorded against a single device's
+// registration token, an inbox notification belongs to the user and is shared
+// by all of the user's devices.
+type InboxNotification struct {
+	ID        string                 `json:"id" firestore:"id"`
+	UID       string                 `json:"uid" firestore:"uid"`
+	Channel   feedlib.Channel        `json:"channel" firestore:"channel"`
+	MessageID string                 `json:"messageID" firestore:"messageID"`
+	Title     string                 `json:"title" firestore:"title"`
+	Body      string                 `json:"body" firestore:"body"`
+	Data      map[string]interface{} `json:"data" firestore:"data"`
+	Read      bool                   `json:"read" firestore:"read"`
+	ReadAt    *time.Time             `json:"readAt" firestore:"readAt"`
+	CreatedAt time.Time              `json:"createdAt" firestore:"createdAt"`
+}
+
+// InboxFilter narrows down the notifications in a user's inbox.
+// Nil fields are not filtered on.
+type InboxFilter struct {
+	Channel *feedlib.Channel
+	Read    *bool
+}
+
+// InboxCursor is the position of a notification in a user's inbox.
+//
+// Inbox notifications are ordered newest first; the ID breaks ties between
+// notifications created at the same instant.
+type InboxCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// After returns true if the notification comes after the cursor in
+// inbox order
+func (n InboxNotification) After(c InboxCursor) bool {
+	if n.CreatedAt.Equal(c.CreatedAt) {
+		return n.ID < c.ID
+	}
+	return n.CreatedAt.Before(c.CreatedAt)
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// OTP is a one time password that has been sent to a phone number or
+// email address.
+//
+// Only a salted hash of the code is stored.
+type OTP struct {
+	ID string `json:"id" firestore:"id"`
+
+	// Identifier is the normalized phone number or email address that the
+	// code was sent to
+	Identifier string `json:"identifier" firestore:"identifier"`
+
+	CodeHash  string     `json:"-" firestore:"codeHash"`
+	Salt      string     `json:"-" firestore:"salt"`
+	CreatedAt time.Time  `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt" firestore:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt" firestore:"usedAt"`
+}
+
+// IsValid returns true if the OTP has not been used and has not expired
+func (o OTP) IsValid(now time.Time) bool {
+	return o.UsedAt == nil && now.Before(o.ExpiresAt)
+}
+
+// OTPAttempts counts the recent attempts to generate or verify OTPs for a
+// phone number, email address or client IP
+type OTPAttempts struct {
+	ID            string     `json:"id" firestore:"id"`
+	Count         int        `json:"count" firestore:"count"`
+	LastAttemptAt time.Time  `json:"lastAttemptAt" firestore:"lastAttemptAt"`
+	LockedUntil   *time.Time `json:"lockedUntil" firestore:"lockedUntil"`
+}
+
+// OTPApp holds the OTP settings of an app that requests codes with its appId
+type OTPApp struct {
+	AppID string `json:"appId" firestore:"appId"`
+
+	// SenderID is the sender ID of the SMS that carry the app's codes
+	SenderID enumutils.SenderID `json:"senderId" firestore:"senderId"`
+
+	// MessageTemplate is a text/template that renders the message carrying
+	// a code. It can use {{.code}}, {{.hash}} and {{.expiryMinutes}}.
+	MessageTemplate string `json:"messageTemplate" firestore:"messageTemplate"`
+
+	Length        int `json:"length" firestore:"length"`
+	ExpirySeconds int `json:"expirySeconds" firestore:"expirySeconds"`
+
+	// Channels are the channels that the app's codes can be sent over
+	Channels []feedlib.Channel `json:"channels" firestore:"channels"`
+
+	// SMSRetrieverHash is the Android SMS Retriever hash that lets the app
+	// read a code from the SMS automatically
+	SMSRetrieverHash string `json:"smsRetrieverHash" firestore:"smsRetrieverHash"`
+
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Expiry returns how long the app's codes can be verified
+func (a OTPApp) Expiry() time.Duration {
+	return time.Duration(a.ExpirySeconds) * time.Second
+}
+
+// AllowsChannel returns true if the app's codes can be sent over a channel
+func (a OTPApp) AllowsChannel(channel feedlib.Channel) bool {
+	for _, allowed := range a.Channels {
+		if allowed == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// OTPChannel is a channel that a one time password can be delivered over
+type OTPChannel string
+
+// OTPChannel values
+const (
+	OTPChannelSMS      OTPChannel = "SMS"
+	OTPChannelWhatsApp OTPChannel = "WHATSAPP"
+	OTPChannelEmail    OTPChannel = "EMAIL"
+
+	// OTPChannelVoice reads the code out in a phone call
+	OTPChannelVoice OTPChannel = "VOICE"
+)
+
+// IsValid returns true if an OTP channel is valid
+func (e OTPChannel) IsValid() bool {
+	switch e {
+	case OTPChannelSMS, OTPChannelWhatsApp, OTPChannelEmail, OTPChannelVoice:
+		return true
+	}
+	return false
+}
+
+func (e OTPChannel) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to an OTP channel.
+func (e *OTPChannel) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OTPChannel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OTPChannel", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the OTP channel to the supplied writer
+func (e OTPChannel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// OTPDeliveryStatus is the outcome of handing a code to a provider
+type OTPDeliveryStatus string
+
+// OTPDeliveryStatus values
+const (
+	OTPDeliveryStatusSent   OTPDeliveryStatus = "SENT"
+	OTPDeliveryStatusFailed OTPDeliveryStatus = "FAILED"
+)
+
+// IsValid returns true if an OTP delivery status is valid
+func (e OTPDeliveryStatus) IsValid() bool {
+	switch e {
+	case OTPDeliveryStatusSent, OTPDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e OTPDeliveryStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to an OTP delivery status.
+func (e *OTPDeliveryStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OTPDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OTPDeliveryStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the OTP delivery status to the supplied writer
+func (e OTPDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// OTPDelivery records how a code was sent so that support can see how a user
+// was reached. The code itself is not recorded.
+type OTPDelivery struct {
+	ID string `json:"id" firestore:"id"`
+
+	// Identifier is the normalized phone number or email address that the
+	// code can be verified for
+	Identifier string `json:"identifier" firestore:"identifier"`
+
+	AppID string `json:"appId" firestore:"appId"`
+
+	// RetryStep is 0 for the first delivery of a code and the step of the
+	// retry ladder otherwise
+	RetryStep int        `json:"retryStep" firestore:"retryStep"`
+	Channel   OTPChannel `json:"channel" firestore:"channel"`
+
+	// Destination is the phone number or email address the code was sent to
+	Destination string `json:"destination" firestore:"destination"`
+
+	Provider  string            `json:"provider" firestore:"provider"`
+	Status    OTPDeliveryStatus `json:"status" firestore:"status"`
+	Error     string            `json:"error" firestore:"error"`
+	CreatedAt time.Time         `json:"createdAt" firestore:"createdAt"`
+}
+
+// TOTPEnrolment holds the authenticator app secret of a phone number. Codes
+// generated from it (RFC 6238) are accepted wherever an OTP is verified.
+//
+// The secret has to be stored as is since codes are derived from it.
+type TOTPEnrolment struct {
+	// ID is the normalized phone number that was enrolled
+	ID     string `json:"id" firestore:"id"`
+	Secret string `json:"-" firestore:"secret"`
+
+	// EnrolledBy is the UID of the user who enrolled the authenticator app.
+	// Only they can confirm it.
+	EnrolledBy string `json:"enrolledBy" firestore:"enrolledBy"`
+
+	// Confirmed is false until a code from the authenticator app has been
+	// verified. Unconfirmed enrolments are not accepted as a factor.
+	Confirmed bool `json:"confirmed" firestore:"confirmed"`
+
+	// LastUsedStep is the time step of the last accepted code. Codes from
+	// it or earlier steps are rejected so that a code can't be replayed.
+	LastUsedStep int64 `json:"lastUsedStep" firestore:"lastUsedStep"`
+
+	RecoveryCodes []RecoveryCode `json:"-" firestore:"recoveryCodes"`
+
+	CreatedAt   time.Time  `json:"createdAt" firestore:"createdAt"`
+	ConfirmedAt *time.Time `json:"confirmedAt" firestore:"confirmedAt"`
+}
+
+// RecoveryCode is a single use code that can stand in for a TOTP code when
+// the authenticator app is lost. Only a salted hash is stored.
+type RecoveryCode struct {
+	CodeHash string     `json:"-" firestore:"codeHash"`
+	Salt     string     `json:"-" firestore:"salt"`
+	UsedAt   *time.Time `json:"usedAt" firestore:"usedAt"`
+}
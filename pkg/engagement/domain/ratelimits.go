@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// RateLimitPolicy limits how many notifications of a flavour a recipient
+// receives over a channel within a window.
+//
+// Notifications over the limit are not sent. They are collapsed into a single
+// digest e.g "You have 5 new updates" that is sent when the window ends.
+type RateLimitPolicy struct {
+	Flavour feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	Channel feedlib.Channel `json:"channel" firestore:"channel"`
+
+	// Limit is the number of notifications allowed per window.
+	// A limit of 0 disables rate limiting.
+	Limit         int `json:"limit" firestore:"limit"`
+	WindowSeconds int `json:"windowSeconds" firestore:"windowSeconds"`
+
+	// IsDefault is true when the policy is the built-in default rather than
+	// one that has been configured
+	IsDefault bool `json:"isDefault" firestore:"-"`
+
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Window returns the duration of the policy's window
+func (p RateLimitPolicy) Window() time.Duration {
+	return time.Duration(p.WindowSeconds) * time.Second
+}
+
+// ID identifies the policy of a flavour and channel
+func (p RateLimitPolicy) ID() string {
+	return fmt.Sprintf("%s_%s", p.Flavour, p.Channel)
+}
+
+// RateLimitKey returns the key that a recipient's rate limit counter and
+// digest are stored under
+func (p RateLimitPolicy) RateLimitKey(recipient string) string {
+	return fmt.Sprintf("%s_%s_%s", p.Flavour, p.Channel, recipient)
+}
+
+// RateLimitCounter counts the notifications that have been sent to a
+// recipient in the current window
+type RateLimitCounter struct {
+	ID          string    `json:"id" firestore:"id"`
+	WindowStart time.Time `json:"windowStart" firestore:"windowStart"`
+	WindowEnd   time.Time `json:"windowEnd" firestore:"windowEnd"`
+	Sent        int       `json:"sent" firestore:"sent"`
+}
+
+// NotificationDigest counts the notifications that were held back from a
+// recipient because of a rate limit
+type NotificationDigest struct {
+	ID        string          `json:"id" firestore:"id"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	Channel   feedlib.Channel `json:"channel" firestore:"channel"`
+	Recipient string          `json:"recipient" firestore:"recipient"`
+	Count     int             `json:"count" firestore:"count"`
+	DueAt     time.Time       `json:"dueAt" firestore:"dueAt"`
+	CreatedAt time.Time       `json:"createdAt" firestore:"createdAt"`
+
+	// Attempts is the number of times sending the digest has failed
+	Attempts int `json:"attempts" firestore:"attempts"`
+}
+
+// MergeNotificationDigests combines a digest that is being requeued with the
+// digest of the notifications held back from the same recipient since it was
+// taken. The merged digest falls due when the earlier of the two does.
+func MergeNotificationDigests(current, requeued NotificationDigest) NotificationDigest {
+	merged := current
+	merged.Count += requeued.Count
+	merged.Attempts = requeued.Attempts
+	if requeued.DueAt.Before(merged.DueAt) {
+		merged.DueAt = requeued.DueAt
+	}
+	if requeued.CreatedAt.Before(merged.CreatedAt) {
+		merged.CreatedAt = requeued.CreatedAt
+	}
+	return merged
+}
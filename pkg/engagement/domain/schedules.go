@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// ScheduledNotification is a templated message that is sent at a later time,
+// either once or repeatedly e.g a daily medication reminder.
+//
+// The message is sent over the channel of its template to the union of the
+// listed recipients and the members of the listed recipient groups.
+type ScheduledNotification struct {
+	ID           string                 `json:"id" firestore:"id"`
+	Name         string                 `json:"name" firestore:"name"`
+	TemplateName string                 `json:"templateName" firestore:"templateName"`
+	Language     *enumutils.Language    `json:"language" firestore:"language"`
+	Variables    map[string]interface{} `json:"variables" firestore:"variables"`
+	To           []string               `json:"to" firestore:"to"`
+	Groups       []string               `json:"groups" firestore:"groups"`
+
+	// Flavour selects the rate limits and SMS budget that the notification
+	// is sent under. The default SMS budget applies when it is not set.
+	Flavour *feedlib.Flavour `json:"flavour" firestore:"flavour"`
+
+	Recurrence ScheduleRecurrence `json:"recurrence" firestore:"recurrence"`
+
+	// Schedule is a cron expression or an RRULE, depending on the recurrence.
+	// It is empty for one-off notifications.
+	Schedule string `json:"schedule" firestore:"schedule"`
+
+	// Timezone is the IANA name of the timezone that the schedule is
+	// evaluated in e.g `Africa/Nairobi`
+	Timezone string `json:"timezone" firestore:"timezone"`
+
+	// StartAt is when a one-off notification is sent, or the earliest time
+	// that a recurring notification can be sent
+	StartAt time.Time  `json:"startAt" firestore:"startAt"`
+	EndAt   *time.Time `json:"endAt" firestore:"endAt"`
+
+	Status    ScheduleStatus `json:"status" firestore:"status"`
+	NextRunAt *time.Time     `json:"nextRunAt" firestore:"nextRunAt"`
+	LastRunAt *time.Time     `json:"lastRunAt" firestore:"lastRunAt"`
+	LastError string         `json:"lastError" firestore:"lastError"`
+	RunCount  int            `json:"runCount" firestore:"runCount"`
+
+	// LeaseExpiresAt is set while a scheduler instance is sending the
+	// notification so that other instances do not send it too
+	LeaseExpiresAt *time.Time `json:"-" firestore:"leaseExpiresAt"`
+
+	CreatedBy string    `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// RecipientGroup is a named list of recipients that scheduled notifications
+// can target.
+//
+// Members are user IDs for push notifications, or phone numbers and email
+// addresses for the other channels.
+type RecipientGroup struct {
+	Name        string    `json:"name" firestore:"name"`
+	Description string    `json:"description" firestore:"description"`
+	Members     []string  `json:"members" firestore:"members"`
+	CreatedAt   time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// ScheduleRecurrence is how a scheduled notification repeats
+type ScheduleRecurrence string
+
+// ScheduleRecurrence values
+const (
+	// ScheduleRecurrenceOnce is sent once at its start time
+	ScheduleRecurrenceOnce ScheduleRecurrence = "ONCE"
+
+	// ScheduleRecurrenceCron repeats on a standard five field cron expression
+	ScheduleRecurrenceCron ScheduleRecurrence = "CRON"
+
+	// ScheduleRecurrenceRrule repeats on an iCalendar RRULE e.g
+	// `FREQ=DAILY;BYHOUR=8;BYMINUTE=0`
+	ScheduleRecurrenceRrule ScheduleRecurrence = "RRULE"
+)
+
+// IsValid returns true if a schedule recurrence is valid
+func (e ScheduleRecurrence) IsValid() bool {
+	switch e {
+	case ScheduleRecurrenceOnce, ScheduleRecurrenceCron, ScheduleRecurrenceRrule:
+		return true
+	}
+	return false
+}
+
+func (e ScheduleRecurrence) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a schedule recurrence.
+func (e *ScheduleRecurrence) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScheduleRecurrence(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScheduleRecurrence", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the schedule recurrence to the supplied writer
+func (e ScheduleRecurrence) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// ScheduleStatus is the state of a scheduled notification
+type ScheduleStatus string
+
+// ScheduleStatus values
+const (
+	ScheduleStatusActive    ScheduleStatus = "ACTIVE"
+	ScheduleStatusPaused    ScheduleStatus = "PAUSED"
+	ScheduleStatusCancelled ScheduleStatus = "CANCELLED"
+
+	// ScheduleStatusCompleted is set once a notification has no more runs
+	ScheduleStatusCompleted ScheduleStatus = "COMPLETED"
+
+	// ScheduleStatusFailed is set when a one-off notification could not be sent
+	ScheduleStatusFailed ScheduleStatus = "FAILED"
+)
+
+// IsValid returns true if a schedule status is valid
+func (e ScheduleStatus) IsValid() bool {
+	switch e {
+	case ScheduleStatusActive, ScheduleStatusPaused, ScheduleStatusCancelled,
+		ScheduleStatusCompleted, ScheduleStatusFailed:
+		return true
+	}
+	return false
+}
+
+// IsFinal returns true if a scheduled notification in this status will not
+// be sent again
+func (e ScheduleStatus) IsFinal() bool {
+	switch e {
+	case ScheduleStatusCancelled, ScheduleStatusCompleted, ScheduleStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e ScheduleStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a schedule status.
+func (e *ScheduleStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScheduleStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScheduleStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the schedule status to the supplied writer
+func (e ScheduleStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
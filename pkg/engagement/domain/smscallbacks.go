@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// ChannelPreference records whether an address e.g a phone number has opted
+// out of notifications over a channel
+type ChannelPreference struct {
+	Channel  feedlib.Channel `json:"channel" firestore:"channel"`
+	Address  string          `json:"address" firestore:"address"`
+	OptedOut bool            `json:"optedOut" firestore:"optedOut"`
+
+	// Source is how the preference was set e.g `SMS keyword STOP`
+	Source    string    `json:"source" firestore:"source"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// SMSDeliveryReport is the latest status that the SMS provider reported for
+// a sent message
+type SMSDeliveryReport struct {
+	MessageID     string    `json:"messageID" firestore:"messageID"`
+	Number        string    `json:"number" firestore:"number"`
+	Status        string    `json:"status" firestore:"status"`
+	FailureReason string    `json:"failureReason" firestore:"failureReason"`
+	NetworkCode   string    `json:"networkCode" firestore:"networkCode"`
+	RetryCount    int       `json:"retryCount" firestore:"retryCount"`
+	ReceivedAt    time.Time `json:"receivedAt" firestore:"receivedAt"`
+}
+
+// InboundSMS is a message that was sent to one of our short codes or numbers
+type InboundSMS struct {
+	ID   string `json:"id" firestore:"id"`
+	From string `json:"from" firestore:"from"`
+	To   string `json:"to" firestore:"to"`
+	Text string `json:"text" firestore:"text"`
+
+	// Keyword is set when the message was an opt out or opt in keyword
+	Keyword string `json:"keyword" firestore:"keyword"`
+
+	// ConversationID is set when the message was posted to a feed item's
+	// conversation
+	ConversationID string `json:"conversationID" firestore:"conversationID"`
+
+	ReceivedAt time.Time `json:"receivedAt" firestore:"receivedAt"`
+}
+
+// SMSConversation links a phone number to a feed item so that SMS replies
+// from the phone number are posted to the item's conversation
+type SMSConversation struct {
+	ID        string          `json:"id" firestore:"id"`
+	Phone     string          `json:"phone" firestore:"phone"`
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	ItemID    string          `json:"itemID" firestore:"itemID"`
+	CreatedBy string          `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time       `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt time.Time       `json:"expiresAt" firestore:"expiresAt"`
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// SMSRate is the price of one SMS segment to phone numbers that start with a
+// prefix e.g `+254`. The rate of a number is the rate with the longest
+// matching prefix.
+type SMSRate struct {
+	Prefix         string    `json:"prefix" firestore:"prefix"`
+	Country        string    `json:"country" firestore:"country"`
+	CostPerSegment float64   `json:"costPerSegment" firestore:"costPerSegment"`
+	UpdatedAt      time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// SMSBudget is the most that a single send of a flavour may cost. Sends whose
+// estimated cost is over the budget are refused.
+type SMSBudget struct {
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	MaxCost   float64         `json:"maxCost" firestore:"maxCost"`
+	UpdatedAt time.Time       `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// SMSEstimate is what an SMS to some phone numbers is expected to cost before
+// it is sent
+type SMSEstimate struct {
+	Encoding SMSEncoding `json:"encoding"`
+
+	// Length is the length of the message in the units of its encoding i.e
+	// GSM-7 septets or UCS-2 code units
+	Length   int `json:"length"`
+	Segments int `json:"segments"`
+
+	Recipients []*SMSRecipientEstimate `json:"recipients"`
+	TotalCost  float64                 `json:"totalCost"`
+	Currency   string                  `json:"currency"`
+
+	// Budget is the budget of the flavour that the estimate was made for,
+	// if one has been set
+	Budget       *float64 `json:"budget"`
+	WithinBudget bool     `json:"withinBudget"`
+}
+
+// SMSRecipientEstimate is the estimated cost of an SMS to one phone number.
+// Priced is false when no rate matches the number.
+type SMSRecipientEstimate struct {
+	Number  string  `json:"number"`
+	Prefix  string  `json:"prefix"`
+	Country string  `json:"country"`
+	Cost    float64 `json:"cost"`
+	Priced  bool    `json:"priced"`
+}
+
+// SMSEncoding is the character set that an SMS is sent in. It decides how many
+// characters fit in a segment.
+type SMSEncoding string
+
+// SMSEncoding values
+const (
+	// SMSEncodingGSM7 fits 160 characters in a single segment and 153 in
+	// each segment of a longer message
+	SMSEncodingGSM7 SMSEncoding = "GSM_7"
+
+	// SMSEncodingUCS2 is used when a message has a character that GSM-7
+	// can't encode e.g an emoji. It fits 70 characters in a single segment
+	// and 67 in each segment of a longer message.
+	SMSEncodingUCS2 SMSEncoding = "UCS_2"
+)
+
+// IsValid returns true if an SMS encoding is valid
+func (e SMSEncoding) IsValid() bool {
+	switch e {
+	case SMSEncodingGSM7, SMSEncodingUCS2:
+		return true
+	}
+	return false
+}
+
+func (e SMSEncoding) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to an SMS encoding.
+func (e *SMSEncoding) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SMSEncoding(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SMSEncoding", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the SMS encoding to the supplied writer
+func (e SMSEncoding) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// NotificationTemplate is a named, reusable message body that is rendered
+// with variables before being dispatched over a notification channel.
+//
+// A template has one variant per supported language. When a variant for the
+// requested language does not exist, the template's default language is used.
+type NotificationTemplate struct {
+	ID              string             `json:"id" firestore:"id"`
+	Name            string             `json:"name" firestore:"name"`
+	Description     string             `json:"description" firestore:"description"`
+	Channel         feedlib.Channel    `json:"channel" firestore:"channel"`
+	DefaultLanguage enumutils.Language `json:"defaultLanguage" firestore:"defaultLanguage"`
+	Variables       []string           `json:"variables" firestore:"variables"`
+	Variants        []TemplateVariant  `json:"variants" firestore:"variants"`
+	CreatedAt       time.Time          `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt       time.Time          `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Variant returns the variant for the supplied language, falling back to the
+// template's default language.
+func (t NotificationTemplate) Variant(language enumutils.Language) (*TemplateVariant, bool) {
+	var fallback *TemplateVariant
+	for i, variant := range t.Variants {
+		if variant.Language == language {
+			return &t.Variants[i], true
+		}
+		if variant.Language == t.DefaultLanguage {
+			fallback = &t.Variants[i]
+		}
+	}
+	return fallback, fallback != nil
+}
+
+// TemplateVariant is the language specific content of a notification template.
+//
+// The subject and body use Go template syntax e.g `Hello {{.firstName}}`.
+// The subject is only used by channels that support it e.g email and FCM.
+type TemplateVariant struct {
+	Language enumutils.Language `json:"language" firestore:"language"`
+	Subject  *string            `json:"subject,omitempty" firestore:"subject,omitempty"`
+	Body     string             `json:"body" firestore:"body"`
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// VideoRoom is a video consult that is scheduled for an appointment or a
+// feed item. Only its participants are given tokens to join it.
+//
+// The provider's room is created when the first participant is about to
+// join, since the provider ends rooms that nobody joins within minutes.
+type VideoRoom struct {
+	ID string `json:"id" firestore:"id"`
+
+	// RoomSID is the provider's ID of the room. It is empty until the room
+	// is started, and changes if the room is started again after the
+	// provider ended it early.
+	RoomSID string          `json:"roomSID" firestore:"roomSID"`
+	Status  VideoRoomStatus `json:"status" firestore:"status"`
+
+	// A room is for either an appointment or a feed item. The flavour is
+	// the flavour of the item's feed.
+	AppointmentID string           `json:"appointmentID" firestore:"appointmentID"`
+	ItemID        string           `json:"itemID" firestore:"itemID"`
+	Flavour       *feedlib.Flavour `json:"flavour" firestore:"flavour"`
+
+	// Participants are the UIDs of the users that can join the room
+	Participants []string `json:"participants" firestore:"participants"`
+
+	ScheduledStart time.Time  `json:"scheduledStart" firestore:"scheduledStart"`
+	ScheduledEnd   time.Time  `json:"scheduledEnd" firestore:"scheduledEnd"`
+	StartedAt      *time.Time `json:"startedAt" firestore:"startedAt"`
+	EndedAt        *time.Time `json:"endedAt" firestore:"endedAt"`
+	EndedBy        string     `json:"endedBy" firestore:"endedBy"`
+
+	CreatedBy string    `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// HasParticipant returns true if a user can join the room
+func (r *VideoRoom) HasParticipant(uid string) bool {
+	for _, participant := range r.Participants {
+		if participant == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// VideoParticipant is one connection of a participant to a video room, as
+// reported by the provider's status callbacks. A participant that rejoins
+// has a new connection.
+type VideoParticipant struct {
+	// ID is the provider's ID of the connection
+	ID       string `json:"id" firestore:"id"`
+	RoomID   string `json:"roomID" firestore:"roomID"`
+	RoomSID  string `json:"roomSID" firestore:"roomSID"`
+	Identity string `json:"identity" firestore:"identity"`
+
+	JoinedAt *time.Time `json:"joinedAt" firestore:"joinedAt"`
+	LeftAt   *time.Time `json:"leftAt" firestore:"leftAt"`
+
+	// DurationSeconds is how long the participant was connected, as
+	// reported when they left
+	DurationSeconds int `json:"durationSeconds" firestore:"durationSeconds"`
+}
+
+// VideoRoomStatus is the state of a video room
+type VideoRoomStatus string
+
+// VideoRoomStatus values
+const (
+	// VideoRoomStatusScheduled rooms have no provider room. Rooms that the
+	// provider ended before their scheduled end are scheduled again so
+	// that they can be rejoined.
+	VideoRoomStatusScheduled VideoRoomStatus = "SCHEDULED"
+
+	// VideoRoomStatusInProgress rooms have a provider room
+	VideoRoomStatusInProgress VideoRoomStatus = "IN_PROGRESS"
+
+	// VideoRoomStatusCompleted rooms were ended, or ended by the provider
+	// after their scheduled end
+	VideoRoomStatusCompleted VideoRoomStatus = "COMPLETED"
+
+	// VideoRoomStatusCancelled rooms were ended before they started
+	VideoRoomStatusCancelled VideoRoomStatus = "CANCELLED"
+)
+
+// IsValid returns true if a video room status is valid
+func (e VideoRoomStatus) IsValid() bool {
+	switch e {
+	case VideoRoomStatusScheduled, VideoRoomStatusInProgress,
+		VideoRoomStatusCompleted, VideoRoomStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsEnded returns true if a room with the status can't be joined again
+func (e VideoRoomStatus) IsEnded() bool {
+	return e == VideoRoomStatusCompleted || e == VideoRoomStatusCancelled
+}
+
+func (e VideoRoomStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL converts the supplied value to a video room status.
+func (e *VideoRoomStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = VideoRoomStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid VideoRoomStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the video room status to the supplied writer
+func (e VideoRoomStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// WhatsAppSession is the window in which a phone number can be sent WhatsApp
+// messages that are not templates. It opens, or is extended, each time the
+// phone number messages us.
+type WhatsAppSession struct {
+	Phone         string    `json:"phone" firestore:"phone"`
+	LastMessageAt time.Time `json:"lastMessageAt" firestore:"lastMessageAt"`
+	ExpiresAt     time.Time `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// InboundWhatsApp is a WhatsApp message that was sent to our WhatsApp number
+type InboundWhatsApp struct {
+	ID          string   `json:"id" firestore:"id"`
+	From        string   `json:"from" firestore:"from"`
+	To          string   `json:"to" firestore:"to"`
+	ProfileName string   `json:"profileName" firestore:"profileName"`
+	Text        string   `json:"text" firestore:"text"`
+	MediaURLs   []string `json:"mediaURLs" firestore:"mediaURLs"`
+
+	// ButtonPayload and ButtonText are set when the message is a tap on a
+	// quick reply button
+	ButtonPayload string `json:"buttonPayload" firestore:"buttonPayload"`
+	ButtonText    string `json:"buttonText" firestore:"buttonText"`
+
+	// ConversationID is set when the message was posted to a feed item's
+	// conversation
+	ConversationID string `json:"conversationID" firestore:"conversationID"`
+
+	// NudgeID and EventID are set when the message answered a nudge and was
+	// processed as an event
+	NudgeID string `json:"nudgeID" firestore:"nudgeID"`
+	EventID string `json:"eventID" firestore:"eventID"`
+
+	// Support is set when the message was added to the support queue, and
+	// Resolved when someone has dealt with it
+	Support    bool      `json:"support" firestore:"support"`
+	Resolved   bool      `json:"resolved" firestore:"resolved"`
+	ResolvedBy string    `json:"resolvedBy" firestore:"resolvedBy"`
+	ResolvedAt time.Time `json:"resolvedAt" firestore:"resolvedAt"`
+
+	ReceivedAt time.Time `json:"receivedAt" firestore:"receivedAt"`
+}
+
+// WhatsAppConversation links a phone number to a feed item so that WhatsApp
+// replies from the phone number are posted to the item's conversation
+type WhatsAppConversation struct {
+	ID        string          `json:"id" firestore:"id"`
+	Phone     string          `json:"phone" firestore:"phone"`
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	ItemID    string          `json:"itemID" firestore:"itemID"`
+	CreatedBy string          `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time       `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt time.Time       `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// WhatsAppNudge is a nudge that was sent to a phone number with quick reply
+// buttons. A tap on a button triggers the nudge action with the same name.
+type WhatsAppNudge struct {
+	ID        string          `json:"id" firestore:"id"`
+	Phone     string          `json:"phone" firestore:"phone"`
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	NudgeID   string          `json:"nudgeID" firestore:"nudgeID"`
+	MessageID string          `json:"messageID" firestore:"messageID"`
+	CreatedBy string          `json:"createdBy" firestore:"createdBy"`
+	CreatedAt time.Time       `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt time.Time       `json:"expiresAt" firestore:"expiresAt"`
+
+	// AnsweredWith is the name of the action that the nudge was answered
+	// with. A nudge is only answered once.
+	AnsweredWith string    `json:"answeredWith" firestore:"answeredWith"`
+	AnsweredAt   time.Time `json:"answeredAt" firestore:"answeredAt"`
+}
@@ -0,0 +1,228 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveClinicianAvailability creates or replaces an availability keyed on its
+// ID
+func (fr Repository) SaveClinicianAvailability(ctx context.Context, availability *domain.ClinicianAvailability) error {
+	return fr.setDocument(ctx, clinicianAvailabilityCollectionName, availability.ID, availability)
+}
+
+// GetClinicianAvailability retrieves a clinician's availability at a location
+func (fr Repository) GetClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+	locationID string,
+) (*domain.ClinicianAvailability, error) {
+	query := fr.collection(clinicianAvailabilityCollectionName).
+		Where("clinicianID", "==", clinicianID).
+		Where("locationID", "==", locationID).
+		Limit(1)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, exceptions.ErrClinicianAvailabilityNotFound
+	}
+
+	availability := &domain.ClinicianAvailability{}
+	if err := docs[0].DataTo(availability); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal clinician availability: %w", err)
+	}
+	return availability, nil
+}
+
+// ListClinicianAvailability returns a clinician's availability ordered by
+// location
+func (fr Repository) ListClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+) ([]*domain.ClinicianAvailability, error) {
+	query := fr.collection(clinicianAvailabilityCollectionName).
+		Where("clinicianID", "==", clinicianID).
+		OrderBy("locationID", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := []*domain.ClinicianAvailability{}
+	for _, doc := range docs {
+		a := &domain.ClinicianAvailability{}
+		if err := doc.DataTo(a); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal clinician availability: %w", err)
+		}
+		availability = append(availability, a)
+	}
+	return availability, nil
+}
+
+// DeleteClinicianAvailability deletes an availability
+func (fr Repository) DeleteClinicianAvailability(ctx context.Context, id string) error {
+	return fr.deleteDocument(ctx, clinicianAvailabilityCollectionName, id, exceptions.ErrClinicianAvailabilityNotFound)
+}
+
+// SaveAvailabilityException creates or replaces an exception keyed on its ID
+func (fr Repository) SaveAvailabilityException(ctx context.Context, exception *domain.AvailabilityException) error {
+	return fr.setDocument(ctx, availabilityExceptionsCollectionName, exception.ID, exception)
+}
+
+// DeleteAvailabilityException deletes an exception
+func (fr Repository) DeleteAvailabilityException(ctx context.Context, id string) error {
+	return fr.deleteDocument(ctx, availabilityExceptionsCollectionName, id, exceptions.ErrAvailabilityExceptionNotFound)
+}
+
+// ListAvailabilityExceptions returns a clinician's exceptions that overlap an
+// interval ordered by their start.
+//
+// Exceptions have no longest duration, so all of the clinician's exceptions
+// that start before the interval ends are read and the ones that end before
+// it are dropped.
+func (fr Repository) ListAvailabilityExceptions(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+) ([]*domain.AvailabilityException, error) {
+	query := fr.collection(availabilityExceptionsCollectionName).
+		Where("clinicianID", "==", clinicianID).
+		Where("start", "<", to).
+		OrderBy("start", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions := []*domain.AvailabilityException{}
+	for _, doc := range docs {
+		exception := &domain.AvailabilityException{}
+		if err := doc.DataTo(exception); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal availability exception: %w", err)
+		}
+		if !exception.End.After(from) {
+			continue
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
+
+// BookSlot saves a booking if the clinician has no other active booking
+// within a buffer of it. Saving an existing booking moves it.
+//
+// The check and the write are in a transaction that also writes a lock
+// document per clinician. Concurrent bookings for a clinician write the same
+// document, so Firestore retries all but one of them, and the retries see
+// the booking that was saved.
+func (fr Repository) BookSlot(ctx context.Context, booking *domain.SlotBooking, buffer time.Duration) error {
+	fr.checkPreconditions()
+	lockRef := fr.collection(slotBookingLocksCollectionName).Doc(booking.ClinicianID)
+	bookingRef := fr.collection(slotBookingsCollectionName).Doc(booking.ID)
+	query := fr.slotBookingsQuery(booking.ClinicianID, booking.Start.Add(-buffer), booking.End.Add(buffer))
+
+	return fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lock := map[string]interface{}{}
+		if _, err := transactionGet(tx, lockRef, &lock); err != nil {
+			return err
+		}
+
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return fmt.Errorf("unable to read slot bookings: %w", err)
+		}
+		for _, doc := range docs {
+			existing := &domain.SlotBooking{}
+			if err := doc.DataTo(existing); err != nil {
+				return fmt.Errorf("unable to unmarshal slot booking: %w", err)
+			}
+			if existing.ID != booking.ID && existing.Conflicts(booking.Start, booking.End, buffer) {
+				return exceptions.ErrSlotUnavailable
+			}
+		}
+
+		lock = map[string]interface{}{
+			"clinicianID": booking.ClinicianID,
+			"bookingID":   booking.ID,
+			"updatedAt":   booking.UpdatedAt,
+		}
+		if err := tx.Set(lockRef, lock); err != nil {
+			return err
+		}
+		return tx.Set(bookingRef, booking)
+	})
+}
+
+// SaveSlotBooking replaces a booking keyed on its ID
+func (fr Repository) SaveSlotBooking(ctx context.Context, booking *domain.SlotBooking) error {
+	return fr.setDocument(ctx, slotBookingsCollectionName, booking.ID, booking)
+}
+
+// DeleteSlotBooking deletes a booking
+func (fr Repository) DeleteSlotBooking(ctx context.Context, id string) error {
+	return fr.deleteDocument(ctx, slotBookingsCollectionName, id, exceptions.ErrSlotBookingNotFound)
+}
+
+// GetSlotBookingByEvent retrieves the booking of a calendar event
+func (fr Repository) GetSlotBookingByEvent(ctx context.Context, eventID string) (*domain.SlotBooking, error) {
+	query := fr.collection(slotBookingsCollectionName).
+		Where("eventID", "==", eventID).
+		Limit(1)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, exceptions.ErrSlotBookingNotFound
+	}
+
+	booking := &domain.SlotBooking{}
+	if err := docs[0].DataTo(booking); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal slot booking: %w", err)
+	}
+	return booking, nil
+}
+
+// ListSlotBookings returns a clinician's active bookings that overlap an
+// interval ordered by their start
+func (fr Repository) ListSlotBookings(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+) ([]*domain.SlotBooking, error) {
+	docs, err := fr.queryDocuments(ctx, fr.slotBookingsQuery(clinicianID, from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	bookings := []*domain.SlotBooking{}
+	for _, doc := range docs {
+		booking := &domain.SlotBooking{}
+		if err := doc.DataTo(booking); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal slot booking: %w", err)
+		}
+		if !booking.Conflicts(from, to, 0) {
+			continue
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, nil
+}
+
+// slotBookingsQuery selects a clinician's bookings that start in an
+// interval, or up to the longest slot before it. Cancelled bookings and
+// the ones that end before the interval have to be dropped by the caller.
+func (fr Repository) slotBookingsQuery(clinicianID string, from, to time.Time) firestore.Query {
+	return fr.collection(slotBookingsCollectionName).
+		Where("clinicianID", "==", clinicianID).
+		Where("start", ">", from.Add(-domain.MaxSlotDuration)).
+		Where("start", "<", to).
+		OrderBy("start", firestore.Asc)
+}
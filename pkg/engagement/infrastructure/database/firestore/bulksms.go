@@ -0,0 +1,179 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// firestoreBatchLimit is the maximum number of writes in a Firestore batch
+const firestoreBatchLimit = 500
+
+// CreateBulkSMSJob saves a job and its recipients.
+//
+// The recipients are written in batches before the job so that the worker
+// never picks up a job whose recipients are missing.
+func (fr Repository) CreateBulkSMSJob(
+	ctx context.Context,
+	job *domain.BulkSMSJob,
+	recipients []*domain.BulkSMSRecipient,
+) (*domain.BulkSMSJob, error) {
+	if err := fr.SaveBulkSMSRecipients(ctx, recipients); err != nil {
+		return nil, err
+	}
+	if err := fr.setDocument(ctx, bulkSMSJobsCollectionName, job.ID, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetBulkSMSJob retrieves a job by ID
+func (fr Repository) GetBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	job := &domain.BulkSMSJob{}
+	err := fr.getDocument(ctx, bulkSMSJobsCollectionName, id, job, exceptions.ErrBulkSMSJobNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// UpdateBulkSMSJob applies `update` to a job in a transaction
+func (fr Repository) UpdateBulkSMSJob(
+	ctx context.Context,
+	id string,
+	update func(job *domain.BulkSMSJob) error,
+) (*domain.BulkSMSJob, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(bulkSMSJobsCollectionName).Doc(id)
+
+	job := &domain.BulkSMSJob{}
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		job = &domain.BulkSMSJob{}
+		found, err := transactionGet(tx, ref, job)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrBulkSMSJobNotFound
+		}
+		if err := update(job); err != nil {
+			return err
+		}
+		return tx.Set(ref, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListActiveBulkSMSJobs returns the pending and running jobs, oldest first
+func (fr Repository) ListActiveBulkSMSJobs(ctx context.Context, limit int) ([]*domain.BulkSMSJob, error) {
+	query := fr.collection(bulkSMSJobsCollectionName).
+		Where("status", "in", []string{
+			domain.BulkSMSJobStatusPending.String(),
+			domain.BulkSMSJobStatusRunning.String(),
+		}).
+		OrderBy("createdAt", firestore.Asc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*domain.BulkSMSJob{}
+	for _, doc := range docs {
+		job := &domain.BulkSMSJob{}
+		if err := doc.DataTo(job); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal bulk SMS job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListBulkSMSRecipients returns the recipients of a job in index order
+func (fr Repository) ListBulkSMSRecipients(
+	ctx context.Context,
+	jobID string,
+	status *domain.BulkSMSRecipientStatus,
+	afterIndex int,
+	limit int,
+) ([]*domain.BulkSMSRecipient, error) {
+	query := fr.collection(bulkSMSRecipientsCollectionName).Where("jobID", "==", jobID)
+	if status != nil {
+		query = query.Where("status", "==", status.String())
+	}
+	query = query.
+		Where("index", ">", afterIndex).
+		OrderBy("index", firestore.Asc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []*domain.BulkSMSRecipient{}
+	for _, doc := range docs {
+		recipient := &domain.BulkSMSRecipient{}
+		if err := doc.DataTo(recipient); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal bulk SMS recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// SaveBulkSMSRecipients replaces recipients keyed on their job and index, in
+// batches of up to 500 writes
+func (fr Repository) SaveBulkSMSRecipients(ctx context.Context, recipients []*domain.BulkSMSRecipient) error {
+	fr.checkPreconditions()
+	for start := 0; start < len(recipients); start += firestoreBatchLimit {
+		end := start + firestoreBatchLimit
+		if end > len(recipients) {
+			end = len(recipients)
+		}
+
+		batch := fr.firestoreClient.Batch()
+		for _, recipient := range recipients[start:end] {
+			id := fmt.Sprintf("%s_%08d", recipient.JobID, recipient.Index)
+			batch.Set(fr.collection(bulkSMSRecipientsCollectionName).Doc(id), recipient)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("unable to save bulk SMS recipients: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpdateBulkSMSRecipientDelivery sets the delivery status of the recipient
+// that was sent a message
+func (fr Repository) UpdateBulkSMSRecipientDelivery(
+	ctx context.Context,
+	messageID string,
+	status string,
+	at time.Time,
+) (bool, error) {
+	query := fr.collection(bulkSMSRecipientsCollectionName).
+		Where("messageID", "==", messageID).
+		Limit(1)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	if len(docs) == 0 {
+		return false, nil
+	}
+	_, err = docs[0].Ref.Update(ctx, []firestore.Update{
+		{Path: "deliveryStatus", Value: status},
+		{Path: "deliveryUpdatedAt", Value: at},
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to update bulk SMS recipient: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,56 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveCalendarEvent creates or replaces an event keyed on its ID
+func (fr Repository) SaveCalendarEvent(ctx context.Context, event *domain.StoredCalendarEvent) error {
+	return fr.setDocument(ctx, calendarEventsCollectionName, event.ID, event)
+}
+
+// GetCalendarEvent retrieves an event by its ID
+func (fr Repository) GetCalendarEvent(ctx context.Context, id string) (*domain.StoredCalendarEvent, error) {
+	event := &domain.StoredCalendarEvent{}
+	err := fr.getDocument(ctx, calendarEventsCollectionName, id, event, exceptions.ErrCalendarEventNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListCalendarEvents returns the events that overlap an interval ordered by
+// their start.
+//
+// Firestore can only compare one field in a query, so the events that start
+// in the interval, or up to the longest event before it, are read and the
+// ones that end before the interval are dropped.
+func (fr Repository) ListCalendarEvents(ctx context.Context, from, to time.Time) ([]*domain.StoredCalendarEvent, error) {
+	query := fr.collection(calendarEventsCollectionName).
+		Where("start", ">", from.Add(-domain.MaxCalendarEventDuration)).
+		Where("start", "<", to).
+		OrderBy("start", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*domain.StoredCalendarEvent{}
+	for _, doc := range docs {
+		event := &domain.StoredCalendarEvent{}
+		if err := doc.DataTo(event); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal calendar event: %w", err)
+		}
+		if !event.End.After(from) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
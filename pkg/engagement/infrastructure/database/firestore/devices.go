@@ -0,0 +1,111 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+func deviceDocumentID(uid string, deviceID string) string {
+	return fmt.Sprintf("%s_%s", uid, deviceID)
+}
+
+// SaveDevice creates or replaces a device keyed on the user and device ID
+func (fr Repository) SaveDevice(ctx context.Context, device *domain.Device) (*domain.Device, error) {
+	err := fr.setDocument(
+		ctx,
+		devicesCollectionName,
+		deviceDocumentID(device.UID, device.ID),
+		device,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// ListDevices returns a user's devices, most recently updated first
+func (fr Repository) ListDevices(ctx context.Context, uid string) ([]*domain.Device, error) {
+	query := fr.collection(devicesCollectionName).
+		Where("uid", "==", uid).
+		OrderBy("updatedAt", firestore.Desc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []*domain.Device{}
+	for _, doc := range docs {
+		device := &domain.Device{}
+		if err := doc.DataTo(device); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// ListDevicesByToken returns the devices registered with any of the supplied tokens
+func (fr Repository) ListDevicesByToken(ctx context.Context, tokens []string) ([]*domain.Device, error) {
+	docs, err := fr.devicesByToken(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+	devices := []*domain.Device{}
+	for _, doc := range docs {
+		device := &domain.Device{}
+		if err := doc.DataTo(device); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// DeleteDevice removes a user's device
+func (fr Repository) DeleteDevice(ctx context.Context, uid string, deviceID string) error {
+	return fr.deleteDocument(
+		ctx,
+		devicesCollectionName,
+		deviceDocumentID(uid, deviceID),
+		exceptions.ErrDeviceNotFound,
+	)
+}
+
+// DeleteDevicesByToken removes every device registered with the supplied tokens
+func (fr Repository) DeleteDevicesByToken(ctx context.Context, tokens []string) (int, error) {
+	docs, err := fr.devicesByToken(ctx, tokens)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, doc := range docs {
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return count, fmt.Errorf("unable to delete device %s: %w", doc.Ref.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// devicesByToken reads the devices registered with any of the supplied
+// tokens, with one `in` query per maxInQueryValues tokens
+func (fr Repository) devicesByToken(ctx context.Context, tokens []string) ([]*firestore.DocumentSnapshot, error) {
+	docs := []*firestore.DocumentSnapshot{}
+	for start := 0; start < len(tokens); start += maxInQueryValues {
+		end := start + maxInQueryValues
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		query := fr.collection(devicesCollectionName).Where("token", "in", tokens[start:end])
+		batch, err := fr.queryDocuments(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, batch...)
+	}
+	return docs, nil
+}
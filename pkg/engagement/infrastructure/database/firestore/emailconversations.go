@@ -0,0 +1,44 @@
+package fb
+
+import (
+	"context"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveEmailConversation saves a conversation keyed on its ID
+func (fr Repository) SaveEmailConversation(ctx context.Context, conversation *domain.EmailConversation) error {
+	return fr.setDocument(ctx, emailConversationsCollectionName, conversation.ID, conversation)
+}
+
+// GetEmailConversation retrieves a conversation by its ID
+func (fr Repository) GetEmailConversation(ctx context.Context, id string) (*domain.EmailConversation, error) {
+	conversation := &domain.EmailConversation{}
+	err := fr.getDocument(
+		ctx,
+		emailConversationsCollectionName,
+		id,
+		conversation,
+		exceptions.ErrEmailConversationNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// SaveInboundEmail saves a received email keyed on its ID
+func (fr Repository) SaveInboundEmail(ctx context.Context, email *domain.InboundEmail) error {
+	return fr.setDocument(ctx, inboundEmailsCollectionName, email.ID, email)
+}
+
+// GetInboundEmail retrieves a received email by its ID
+func (fr Repository) GetInboundEmail(ctx context.Context, id string) (*domain.InboundEmail, error) {
+	email := &domain.InboundEmail{}
+	err := fr.getDocument(ctx, inboundEmailsCollectionName, id, email, exceptions.ErrInboundEmailNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return email, nil
+}
@@ -0,0 +1,80 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// emailSuppressionID is the document ID of an email address. Slashes are
+// valid in an address but not in a document ID so they are escaped.
+func emailSuppressionID(email string) string {
+	return url.PathEscape(email)
+}
+
+// SaveEmailSuppression creates or replaces a suppression keyed on its email
+// address
+func (fr Repository) SaveEmailSuppression(ctx context.Context, suppression *domain.EmailSuppression) error {
+	return fr.setDocument(
+		ctx,
+		emailSuppressionsCollectionName,
+		emailSuppressionID(suppression.Email),
+		suppression,
+	)
+}
+
+// GetEmailSuppression retrieves the suppression of an email address
+func (fr Repository) GetEmailSuppression(ctx context.Context, email string) (*domain.EmailSuppression, error) {
+	suppression := &domain.EmailSuppression{}
+	err := fr.getDocument(
+		ctx,
+		emailSuppressionsCollectionName,
+		emailSuppressionID(email),
+		suppression,
+		exceptions.ErrEmailSuppressionNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+// ListEmailSuppressions returns the suppressions, optionally of one reason,
+// newest first
+func (fr Repository) ListEmailSuppressions(
+	ctx context.Context,
+	reason *domain.EmailSuppressionReason,
+) ([]*domain.EmailSuppression, error) {
+	query := fr.collection(emailSuppressionsCollectionName).Query
+	if reason != nil {
+		query = query.Where("reason", "==", reason.String())
+	}
+	docs, err := fr.queryDocuments(ctx, query.OrderBy("createdAt", firestore.Desc))
+	if err != nil {
+		return nil, err
+	}
+
+	suppressions := []*domain.EmailSuppression{}
+	for _, doc := range docs {
+		suppression := &domain.EmailSuppression{}
+		if err := doc.DataTo(suppression); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal email suppression: %w", err)
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	return suppressions, nil
+}
+
+// DeleteEmailSuppression removes the suppression of an email address
+func (fr Repository) DeleteEmailSuppression(ctx context.Context, email string) error {
+	return fr.deleteDocument(
+		ctx,
+		emailSuppressionsCollectionName,
+		emailSuppressionID(email),
+		exceptions.ErrEmailSuppressionNotFound,
+	)
+}
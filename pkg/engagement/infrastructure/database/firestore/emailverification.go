@@ -0,0 +1,120 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveEmailVerificationLink creates or replaces a link keyed on its ID
+func (fr Repository) SaveEmailVerificationLink(
+	ctx context.Context,
+	link *domain.EmailVerificationLink,
+) (*domain.EmailVerificationLink, error) {
+	err := fr.setDocument(ctx, emailVerificationCollectionName, link.ID, link)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// GetEmailVerificationLink retrieves a link by its ID
+func (fr Repository) GetEmailVerificationLink(
+	ctx context.Context,
+	id string,
+) (*domain.EmailVerificationLink, error) {
+	link := &domain.EmailVerificationLink{}
+	err := fr.getDocument(
+		ctx,
+		emailVerificationCollectionName,
+		id,
+		link,
+		exceptions.ErrEmailVerificationLinkNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ConsumeEmailVerificationLink marks a valid link as verified in a
+// transaction so that a link can only be used once
+func (fr Repository) ConsumeEmailVerificationLink(
+	ctx context.Context,
+	id string,
+	now time.Time,
+) (*domain.EmailVerificationLink, bool, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(emailVerificationCollectionName).Doc(id)
+
+	link := &domain.EmailVerificationLink{}
+	consumed := false
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		link = &domain.EmailVerificationLink{}
+		consumed = false
+		found, err := transactionGet(tx, ref, link)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrEmailVerificationLinkNotFound
+		}
+		if !link.IsValid(now) {
+			return nil
+		}
+		consumed = true
+		link.VerifiedAt = &now
+		return tx.Update(ref, []firestore.Update{
+			{Path: "verifiedAt", Value: now},
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return link, consumed, nil
+}
+
+// RevokeEmailVerificationLinks revokes the valid links of an email address.
+//
+// Firestore can't filter on a missing timestamp so used and revoked links
+// are skipped here.
+func (fr Repository) RevokeEmailVerificationLinks(
+	ctx context.Context,
+	email string,
+	now time.Time,
+) (int, error) {
+	query := fr.collection(emailVerificationCollectionName).
+		Where("email", "==", email).
+		Where("expiresAt", ">", now)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := fr.firestoreClient.Batch()
+	revoked := 0
+	for _, doc := range docs {
+		link := &domain.EmailVerificationLink{}
+		if err := doc.DataTo(link); err != nil {
+			return 0, fmt.Errorf("unable to unmarshal email verification link: %w", err)
+		}
+		if !link.IsValid(now) {
+			continue
+		}
+		batch.Update(doc.Ref, []firestore.Update{
+			{Path: "revokedAt", Value: now},
+		})
+		revoked++
+	}
+	if revoked == 0 {
+		return 0, nil
+	}
+	if _, err := batch.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("unable to revoke email verification links: %w", err)
+	}
+	return revoked, nil
+}
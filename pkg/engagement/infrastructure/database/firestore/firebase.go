@@ -0,0 +1,174 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/firebasetools"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	notificationTemplatesCollectionName  = "notification_templates"
+	devicesCollectionName                = "push_devices"
+	inboxCollectionName                  = "inbox_notifications"
+	schedulesCollectionName              = "scheduled_notifications"
+	recipientGroupsCollectionName        = "recipient_groups"
+	rateLimitPoliciesCollectionName      = "rate_limit_policies"
+	rateLimitCountersCollectionName      = "rate_limit_counters"
+	digestsCollectionName                = "notification_digests"
+	otpsCollectionName                   = "otps"
+	otpAttemptsCollectionName            = "otp_attempts"
+	otpAppsCollectionName                = "otp_apps"
+	otpDeliveriesCollectionName          = "otp_deliveries"
+	totpEnrolmentsCollectionName         = "totp_enrolments"
+	emailVerificationCollectionName      = "email_verification_links"
+	bulkSMSJobsCollectionName            = "bulk_sms_jobs"
+	bulkSMSRecipientsCollectionName      = "bulk_sms_recipients"
+	smsDeliveryReportsCollectionName     = "sms_delivery_reports"
+	inboundSMSCollectionName             = "inbound_sms"
+	channelPreferencesCollectionName     = "channel_preferences"
+	smsConversationsCollectionName       = "sms_conversations"
+	smsRatesCollectionName               = "sms_rates"
+	smsBudgetsCollectionName             = "sms_budgets"
+	emailSuppressionsCollectionName      = "email_suppressions"
+	emailConversationsCollectionName     = "email_conversations"
+	inboundEmailsCollectionName          = "inbound_emails"
+	whatsAppSessionsCollectionName       = "whatsapp_sessions"
+	inboundWhatsAppCollectionName        = "inbound_whatsapp"
+	whatsAppConversationsCollectionName  = "whatsapp_conversations"
+	whatsAppNudgesCollectionName         = "whatsapp_nudges"
+	videoRoomsCollectionName             = "video_rooms"
+	videoParticipantsCollectionName      = "video_participants"
+	calendarEventsCollectionName         = "calendar_events"
+	clinicianAvailabilityCollectionName  = "clinician_availability"
+	availabilityExceptionsCollectionName = "availability_exceptions"
+	slotBookingsCollectionName           = "slot_bookings"
+	slotBookingLocksCollectionName       = "slot_booking_locks"
+)
+
+// maxInQueryValues is the most values that Firestore compares a field to in
+// an `in` query
+const maxInQueryValues = 10
+
+// NewFirebaseRepository initializes a Firebase repository
+func NewFirebaseRepository(
+	ctx context.Context,
+) (*Repository, error) {
+	fc := firebasetools.FirebaseClient{}
+	fa, err := fc.InitFirebase()
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Firebase app: %w", err)
+	}
+
+	fsc, err := fa.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Firestore: %w", err)
+	}
+
+	fr := &Repository{
+		firestoreClient: fsc,
+	}
+	fr.checkPreconditions()
+	return fr, nil
+}
+
+// Repository accesses and updates the service state that is stored on Firebase
+type Repository struct {
+	firestoreClient *firestore.Client
+}
+
+func (fr Repository) checkPreconditions() {
+	if fr.firestoreClient == nil {
+		log.Panicf("nil firestore client in firebase repository")
+	}
+}
+
+func (fr Repository) collection(name string) *firestore.CollectionRef {
+	return fr.firestoreClient.Collection(firebasetools.SuffixCollection(name))
+}
+
+// getDocument reads a single document into `target`.
+// It returns `notFound` when the document does not exist.
+func (fr Repository) getDocument(
+	ctx context.Context,
+	collectionName string,
+	id string,
+	target interface{},
+	notFound error,
+) error {
+	fr.checkPreconditions()
+	dsnap, err := fr.collection(collectionName).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return notFound
+		}
+		return fmt.Errorf("unable to read %s/%s: %w", collectionName, id, err)
+	}
+	if err := dsnap.DataTo(target); err != nil {
+		return fmt.Errorf("unable to unmarshal %s/%s: %w", collectionName, id, err)
+	}
+	return nil
+}
+
+// setDocument creates or overwrites a single document
+func (fr Repository) setDocument(
+	ctx context.Context,
+	collectionName string,
+	id string,
+	data interface{},
+) error {
+	fr.checkPreconditions()
+	_, err := fr.collection(collectionName).Doc(id).Set(ctx, data)
+	if err != nil {
+		return fmt.Errorf("unable to save %s/%s: %w", collectionName, id, err)
+	}
+	return nil
+}
+
+// deleteDocument removes a single document.
+// It returns `notFound` when the document does not exist.
+func (fr Repository) deleteDocument(
+	ctx context.Context,
+	collectionName string,
+	id string,
+	notFound error,
+) error {
+	fr.checkPreconditions()
+	ref := fr.collection(collectionName).Doc(id)
+	_, err := ref.Delete(ctx, firestore.Exists)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return notFound
+		}
+		return fmt.Errorf("unable to delete %s/%s: %w", collectionName, id, err)
+	}
+	return nil
+}
+
+// queryDocuments runs a query and returns the matching document snapshots
+func (fr Repository) queryDocuments(
+	ctx context.Context,
+	query firestore.Query,
+) ([]*firestore.DocumentSnapshot, error) {
+	fr.checkPreconditions()
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	docs := []*firestore.DocumentSnapshot{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to run query: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
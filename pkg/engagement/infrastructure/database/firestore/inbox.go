@@ -0,0 +1,166 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveInboxNotification creates or replaces a notification keyed on its ID
+func (fr Repository) SaveInboxNotification(
+	ctx context.Context,
+	notification *domain.InboxNotification,
+) (*domain.InboxNotification, error) {
+	err := fr.setDocument(ctx, inboxCollectionName, notification.ID, notification)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// ListInboxNotifications returns a page of a user's notifications, newest first
+func (fr Repository) ListInboxNotifications(
+	ctx context.Context,
+	uid string,
+	filter domain.InboxFilter,
+	after *domain.InboxCursor,
+	limit int,
+) ([]*domain.InboxNotification, error) {
+	query := fr.collection(inboxCollectionName).Where("uid", "==", uid)
+	if filter.Channel != nil {
+		query = query.Where("channel", "==", *filter.Channel)
+	}
+	if filter.Read != nil {
+		query = query.Where("read", "==", *filter.Read)
+	}
+	query = query.
+		OrderBy("createdAt", firestore.Desc).
+		OrderBy("id", firestore.Desc)
+	if after != nil {
+		query = query.StartAfter(after.CreatedAt, after.ID)
+	}
+	docs, err := fr.queryDocuments(ctx, query.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := []*domain.InboxNotification{}
+	for _, doc := range docs {
+		notification := &domain.InboxNotification{}
+		if err := doc.DataTo(notification); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal inbox notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// CountUnreadInboxNotifications counts a user's unread notifications.
+//
+// The Firestore client that the service is built with predates aggregation
+// queries, so the query only returns the IDs of the notifications rather
+// than their contents.
+func (fr Repository) CountUnreadInboxNotifications(ctx context.Context, uid string) (int, error) {
+	docs, err := fr.queryDocuments(ctx, fr.unreadInboxQuery(uid).Select())
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+// SetInboxNotificationsRead marks the supplied notifications as read or unread
+func (fr Repository) SetInboxNotificationsRead(
+	ctx context.Context,
+	uid string,
+	ids []string,
+	read bool,
+	at time.Time,
+) (int, error) {
+	refs := []*firestore.DocumentRef{}
+	for _, id := range ids {
+		notification := &domain.InboxNotification{}
+		err := fr.getDocument(
+			ctx,
+			inboxCollectionName,
+			id,
+			notification,
+			exceptions.ErrInboxNotificationNotFound,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if notification.UID != uid {
+			return 0, exceptions.ErrInboxNotificationNotFound
+		}
+		if notification.Read == read {
+			continue
+		}
+		refs = append(refs, fr.collection(inboxCollectionName).Doc(id))
+	}
+	return fr.updateReadState(ctx, refs, read, at)
+}
+
+// MarkAllInboxNotificationsRead marks every unread notification in a user's
+// inbox as read
+func (fr Repository) MarkAllInboxNotificationsRead(
+	ctx context.Context,
+	uid string,
+	at time.Time,
+) (int, error) {
+	docs, err := fr.queryDocuments(ctx, fr.unreadInboxQuery(uid))
+	if err != nil {
+		return 0, err
+	}
+	refs := []*firestore.DocumentRef{}
+	for _, doc := range docs {
+		refs = append(refs, doc.Ref)
+	}
+	return fr.updateReadState(ctx, refs, true, at)
+}
+
+func (fr Repository) unreadInboxQuery(uid string) firestore.Query {
+	return fr.collection(inboxCollectionName).
+		Where("uid", "==", uid).
+		Where("read", "==", false)
+}
+
+// updateReadState updates the read state of the referenced notifications in
+// batches that stay within Firestore's write batch limit
+func (fr Repository) updateReadState(
+	ctx context.Context,
+	refs []*firestore.DocumentRef,
+	read bool,
+	at time.Time,
+) (int, error) {
+	const maxBatchSize = 500
+
+	var readAt interface{}
+	if read {
+		readAt = at
+	}
+	updates := []firestore.Update{
+		{Path: "read", Value: read},
+		{Path: "readAt", Value: readAt},
+	}
+
+	count := 0
+	for start := 0; start < len(refs); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+		batch := fr.firestoreClient.Batch()
+		for _, ref := range refs[start:end] {
+			batch.Update(ref, updates)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return count, fmt.Errorf("unable to update inbox notifications: %w", err)
+		}
+		count += end - start
+	}
+	return count, nil
+}
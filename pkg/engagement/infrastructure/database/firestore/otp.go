@@ -0,0 +1,250 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveOTP creates or replaces an OTP keyed on its ID
+func (fr Repository) SaveOTP(ctx context.Context, otp *domain.OTP) (*domain.OTP, error) {
+	err := fr.setDocument(ctx, otpsCollectionName, otp.ID, otp)
+	if err != nil {
+		return nil, err
+	}
+	return otp, nil
+}
+
+// ListValidOTPs returns the valid OTPs of an identifier, newest first.
+//
+// Firestore can't combine the expiry range filter with ordering on the
+// creation time so used OTPs are filtered out here.
+func (fr Repository) ListValidOTPs(
+	ctx context.Context,
+	identifier string,
+	now time.Time,
+) ([]*domain.OTP, error) {
+	query := fr.collection(otpsCollectionName).
+		Where("identifier", "==", identifier).
+		Where("expiresAt", ">", now).
+		OrderBy("expiresAt", firestore.Desc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	otps := []*domain.OTP{}
+	for _, doc := range docs {
+		otp := &domain.OTP{}
+		if err := doc.DataTo(otp); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal OTP: %w", err)
+		}
+		if otp.IsValid(now) {
+			otps = append(otps, otp)
+		}
+	}
+	return otps, nil
+}
+
+// ConsumeOTP marks a valid OTP as used in a transaction so that a code can
+// only be verified once
+func (fr Repository) ConsumeOTP(ctx context.Context, id string, now time.Time) (bool, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(otpsCollectionName).Doc(id)
+
+	consumed := false
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		consumed = false
+		otp := &domain.OTP{}
+		found, err := transactionGet(tx, ref, otp)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrOTPNotFound
+		}
+		if !otp.IsValid(now) {
+			return nil
+		}
+		consumed = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "usedAt", Value: now},
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return consumed, nil
+}
+
+// UpdateOTPAttempts applies `update` to the attempts stored under `id` in a
+// transaction so that concurrent guesses are all counted
+func (fr Repository) UpdateOTPAttempts(
+	ctx context.Context,
+	id string,
+	update func(attempts *domain.OTPAttempts),
+) (*domain.OTPAttempts, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(otpAttemptsCollectionName).Doc(id)
+
+	attempts := &domain.OTPAttempts{}
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		attempts = &domain.OTPAttempts{}
+		found, err := transactionGet(tx, ref, attempts)
+		if err != nil {
+			return err
+		}
+		if !found {
+			attempts = &domain.OTPAttempts{ID: id}
+		}
+		update(attempts)
+		return tx.Set(ref, attempts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to update OTP attempts %s: %w", id, err)
+	}
+	return attempts, nil
+}
+
+// SaveOTPApp creates or replaces the OTP settings of an app keyed on its appId
+func (fr Repository) SaveOTPApp(ctx context.Context, app *domain.OTPApp) (*domain.OTPApp, error) {
+	err := fr.setDocument(ctx, otpAppsCollectionName, app.AppID, app)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// GetOTPApp retrieves the OTP settings of an app
+func (fr Repository) GetOTPApp(ctx context.Context, appID string) (*domain.OTPApp, error) {
+	app := &domain.OTPApp{}
+	err := fr.getDocument(ctx, otpAppsCollectionName, appID, app, exceptions.ErrOTPAppNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// ListOTPApps returns the registered apps ordered by appId
+func (fr Repository) ListOTPApps(ctx context.Context) ([]*domain.OTPApp, error) {
+	query := fr.collection(otpAppsCollectionName).OrderBy("appId", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := []*domain.OTPApp{}
+	for _, doc := range docs {
+		app := &domain.OTPApp{}
+		if err := doc.DataTo(app); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal OTP app: %w", err)
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// DeleteOTPApp removes the OTP settings of an app
+func (fr Repository) DeleteOTPApp(ctx context.Context, appID string) error {
+	return fr.deleteDocument(ctx, otpAppsCollectionName, appID, exceptions.ErrOTPAppNotFound)
+}
+
+// SaveOTPDelivery creates or replaces an OTP delivery keyed on its ID
+func (fr Repository) SaveOTPDelivery(
+	ctx context.Context,
+	delivery *domain.OTPDelivery,
+) (*domain.OTPDelivery, error) {
+	err := fr.setDocument(ctx, otpDeliveriesCollectionName, delivery.ID, delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListOTPDeliveries returns the latest deliveries of an identifier's codes
+func (fr Repository) ListOTPDeliveries(
+	ctx context.Context,
+	identifier string,
+	limit int,
+) ([]*domain.OTPDelivery, error) {
+	query := fr.collection(otpDeliveriesCollectionName).
+		Where("identifier", "==", identifier).
+		OrderBy("createdAt", firestore.Desc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []*domain.OTPDelivery{}
+	for _, doc := range docs {
+		delivery := &domain.OTPDelivery{}
+		if err := doc.DataTo(delivery); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal OTP delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// SaveTOTPEnrolment creates or replaces the enrolment of a phone number
+func (fr Repository) SaveTOTPEnrolment(
+	ctx context.Context,
+	enrolment *domain.TOTPEnrolment,
+) (*domain.TOTPEnrolment, error) {
+	err := fr.setDocument(ctx, totpEnrolmentsCollectionName, enrolment.ID, enrolment)
+	if err != nil {
+		return nil, err
+	}
+	return enrolment, nil
+}
+
+// GetTOTPEnrolment retrieves the enrolment of a phone number
+func (fr Repository) GetTOTPEnrolment(ctx context.Context, id string) (*domain.TOTPEnrolment, error) {
+	enrolment := &domain.TOTPEnrolment{}
+	err := fr.getDocument(ctx, totpEnrolmentsCollectionName, id, enrolment, exceptions.ErrTOTPEnrolmentNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return enrolment, nil
+}
+
+// DeleteTOTPEnrolment removes the enrolment of a phone number
+func (fr Repository) DeleteTOTPEnrolment(ctx context.Context, id string) error {
+	return fr.deleteDocument(ctx, totpEnrolmentsCollectionName, id, exceptions.ErrTOTPEnrolmentNotFound)
+}
+
+// UpdateTOTPEnrolment applies `update` to an enrolment in a transaction so
+// that a code or recovery code is only accepted once
+func (fr Repository) UpdateTOTPEnrolment(
+	ctx context.Context,
+	id string,
+	update func(enrolment *domain.TOTPEnrolment) error,
+) (*domain.TOTPEnrolment, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(totpEnrolmentsCollectionName).Doc(id)
+
+	enrolment := &domain.TOTPEnrolment{}
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		enrolment = &domain.TOTPEnrolment{}
+		found, err := transactionGet(tx, ref, enrolment)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrTOTPEnrolmentNotFound
+		}
+		if err := update(enrolment); err != nil {
+			return err
+		}
+		return tx.Set(ref, enrolment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enrolment, nil
+}
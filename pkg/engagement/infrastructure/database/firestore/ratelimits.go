@@ -0,0 +1,240 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SaveRateLimitPolicy creates or replaces the policy of a flavour and channel
+func (fr Repository) SaveRateLimitPolicy(
+	ctx context.Context,
+	policy *domain.RateLimitPolicy,
+) (*domain.RateLimitPolicy, error) {
+	err := fr.setDocument(ctx, rateLimitPoliciesCollectionName, policy.ID(), policy)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// GetRateLimitPolicy retrieves the policy of a flavour and channel
+func (fr Repository) GetRateLimitPolicy(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) (*domain.RateLimitPolicy, error) {
+	policy := &domain.RateLimitPolicy{}
+	err := fr.getDocument(
+		ctx,
+		rateLimitPoliciesCollectionName,
+		domain.RateLimitPolicy{Flavour: flavour, Channel: channel}.ID(),
+		policy,
+		exceptions.ErrRateLimitPolicyNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ListRateLimitPolicies returns the saved policies ordered by flavour and channel
+func (fr Repository) ListRateLimitPolicies(ctx context.Context) ([]*domain.RateLimitPolicy, error) {
+	query := fr.collection(rateLimitPoliciesCollectionName).
+		OrderBy("flavour", firestore.Asc).
+		OrderBy("channel", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*domain.RateLimitPolicy{}
+	for _, doc := range docs {
+		policy := &domain.RateLimitPolicy{}
+		if err := doc.DataTo(policy); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal rate limit policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// DeleteRateLimitPolicy removes the policy of a flavour and channel
+func (fr Repository) DeleteRateLimitPolicy(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) error {
+	return fr.deleteDocument(
+		ctx,
+		rateLimitPoliciesCollectionName,
+		domain.RateLimitPolicy{Flavour: flavour, Channel: channel}.ID(),
+		exceptions.ErrRateLimitPolicyNotFound,
+	)
+}
+
+// ReserveNotification counts a notification against the recipient's current
+// window, or adds it to the recipient's digest when the limit has been reached.
+//
+// The counter and digest are updated in a transaction so that concurrent
+// publishes to the same recipient cannot exceed the limit.
+func (fr Repository) ReserveNotification(
+	ctx context.Context,
+	policy domain.RateLimitPolicy,
+	recipient string,
+	now time.Time,
+) (bool, error) {
+	fr.checkPreconditions()
+	key := policy.RateLimitKey(recipient)
+	counterRef := fr.collection(rateLimitCountersCollectionName).Doc(key)
+	digestRef := fr.collection(digestsCollectionName).Doc(key)
+
+	allowed := false
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		allowed = false
+		counter := &domain.RateLimitCounter{}
+		found, err := transactionGet(tx, counterRef, counter)
+		if err != nil {
+			return err
+		}
+		if !found || !now.Before(counter.WindowEnd) {
+			counter = &domain.RateLimitCounter{
+				ID:          key,
+				WindowStart: now,
+				WindowEnd:   now.Add(policy.Window()),
+			}
+		}
+
+		if counter.Sent < policy.Limit {
+			allowed = true
+			counter.Sent++
+			return tx.Set(counterRef, counter)
+		}
+
+		digest := &domain.NotificationDigest{}
+		found, err = transactionGet(tx, digestRef, digest)
+		if err != nil {
+			return err
+		}
+		if !found {
+			digest = &domain.NotificationDigest{
+				ID:        key,
+				Flavour:   policy.Flavour,
+				Channel:   policy.Channel,
+				Recipient: recipient,
+				CreatedAt: now,
+			}
+		}
+		digest.Count++
+		digest.DueAt = counter.WindowEnd
+		if err := tx.Set(counterRef, counter); err != nil {
+			return err
+		}
+		return tx.Set(digestRef, digest)
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to reserve notification for %s: %w", key, err)
+	}
+	return allowed, nil
+}
+
+// ListDueDigests returns the digests that are due at `now`, earliest first
+func (fr Repository) ListDueDigests(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*domain.NotificationDigest, error) {
+	query := fr.collection(digestsCollectionName).
+		Where("dueAt", "<=", now).
+		OrderBy("dueAt", firestore.Asc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := []*domain.NotificationDigest{}
+	for _, doc := range docs {
+		digest := &domain.NotificationDigest{}
+		if err := doc.DataTo(digest); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal notification digest: %w", err)
+		}
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
+
+// TakeDigest removes and returns a digest in a transaction so that only one
+// instance of the service sends it
+func (fr Repository) TakeDigest(ctx context.Context, id string) (*domain.NotificationDigest, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(digestsCollectionName).Doc(id)
+
+	var digest *domain.NotificationDigest
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		digest = &domain.NotificationDigest{}
+		found, err := transactionGet(tx, ref, digest)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrDigestNotFound
+		}
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// RequeueDigest puts back a digest that could not be sent in a transaction
+// so that notifications held back since it was taken are not lost
+func (fr Repository) RequeueDigest(ctx context.Context, digest *domain.NotificationDigest) error {
+	fr.checkPreconditions()
+	ref := fr.collection(digestsCollectionName).Doc(digest.ID)
+
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		requeued := *digest
+		current := domain.NotificationDigest{}
+		found, err := transactionGet(tx, ref, &current)
+		if err != nil {
+			return err
+		}
+		if found {
+			requeued = domain.MergeNotificationDigests(current, requeued)
+		}
+		return tx.Set(ref, &requeued)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to requeue digest %s: %w", digest.ID, err)
+	}
+	return nil
+}
+
+// transactionGet reads a document within a transaction into `target`.
+// It returns false when the document does not exist.
+func transactionGet(
+	tx *firestore.Transaction,
+	ref *firestore.DocumentRef,
+	target interface{},
+) (bool, error) {
+	dsnap, err := tx.Get(ref)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to read %s: %w", ref.Path, err)
+	}
+	if err := dsnap.DataTo(target); err != nil {
+		return false, fmt.Errorf("unable to unmarshal %s: %w", ref.Path, err)
+	}
+	return true, nil
+}
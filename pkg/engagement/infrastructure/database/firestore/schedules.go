@@ -0,0 +1,226 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SaveScheduledNotification creates or replaces a scheduled notification
+// keyed on its ID
+func (fr Repository) SaveScheduledNotification(
+	ctx context.Context,
+	notification *domain.ScheduledNotification,
+) (*domain.ScheduledNotification, error) {
+	err := fr.setDocument(ctx, schedulesCollectionName, notification.ID, notification)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// GetScheduledNotification retrieves a scheduled notification by ID
+func (fr Repository) GetScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	notification := &domain.ScheduledNotification{}
+	err := fr.getDocument(
+		ctx,
+		schedulesCollectionName,
+		id,
+		notification,
+		exceptions.ErrScheduledNotificationNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// UpdateScheduledNotification atomically applies `update` to a scheduled
+// notification
+func (fr Repository) UpdateScheduledNotification(
+	ctx context.Context,
+	id string,
+	update func(notification *domain.ScheduledNotification) error,
+) (*domain.ScheduledNotification, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(schedulesCollectionName).Doc(id)
+
+	notification := &domain.ScheduledNotification{}
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		notification = &domain.ScheduledNotification{}
+		found, err := transactionGet(tx, ref, notification)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exceptions.ErrScheduledNotificationNotFound
+		}
+		if err := update(notification); err != nil {
+			return err
+		}
+		return tx.Set(ref, notification)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// ListScheduledNotifications returns the scheduled notifications, oldest first
+func (fr Repository) ListScheduledNotifications(
+	ctx context.Context,
+	status *domain.ScheduleStatus,
+) ([]*domain.ScheduledNotification, error) {
+	query := fr.collection(schedulesCollectionName).Query
+	if status != nil {
+		query = query.Where("status", "==", status.String())
+	}
+	docs, err := fr.queryDocuments(ctx, query.OrderBy("createdAt", firestore.Asc))
+	if err != nil {
+		return nil, err
+	}
+	return scheduledNotificationsFromDocs(docs)
+}
+
+// ListDueScheduledNotifications returns the active scheduled notifications
+// whose next run is not after `now`, earliest first
+func (fr Repository) ListDueScheduledNotifications(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*domain.ScheduledNotification, error) {
+	query := fr.collection(schedulesCollectionName).
+		Where("status", "==", domain.ScheduleStatusActive.String()).
+		Where("nextRunAt", "<=", now).
+		OrderBy("nextRunAt", firestore.Asc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return scheduledNotificationsFromDocs(docs)
+}
+
+// ClaimScheduledNotification leases an active scheduled notification.
+//
+// The lease is taken in a transaction so that only one instance of the
+// service sends a due notification.
+func (fr Repository) ClaimScheduledNotification(
+	ctx context.Context,
+	id string,
+	now time.Time,
+	leaseUntil time.Time,
+) (bool, error) {
+	fr.checkPreconditions()
+	ref := fr.collection(schedulesCollectionName).Doc(id)
+
+	claimed := false
+	err := fr.firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		dsnap, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return exceptions.ErrScheduledNotificationNotFound
+			}
+			return err
+		}
+		notification := &domain.ScheduledNotification{}
+		if err := dsnap.DataTo(notification); err != nil {
+			return fmt.Errorf("unable to unmarshal scheduled notification: %w", err)
+		}
+		if notification.Status != domain.ScheduleStatusActive {
+			return nil
+		}
+		if notification.LeaseExpiresAt != nil && notification.LeaseExpiresAt.After(now) {
+			return nil
+		}
+		claimed = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "leaseExpiresAt", Value: leaseUntil},
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to claim scheduled notification %s: %w", id, err)
+	}
+	return claimed, nil
+}
+
+func scheduledNotificationsFromDocs(
+	docs []*firestore.DocumentSnapshot,
+) ([]*domain.ScheduledNotification, error) {
+	notifications := []*domain.ScheduledNotification{}
+	for _, doc := range docs {
+		notification := &domain.ScheduledNotification{}
+		if err := doc.DataTo(notification); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal scheduled notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// SaveRecipientGroup creates or replaces a group keyed on its name
+func (fr Repository) SaveRecipientGroup(
+	ctx context.Context,
+	group *domain.RecipientGroup,
+) (*domain.RecipientGroup, error) {
+	err := fr.setDocument(ctx, recipientGroupsCollectionName, group.Name, group)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetRecipientGroup retrieves a group by name
+func (fr Repository) GetRecipientGroup(ctx context.Context, name string) (*domain.RecipientGroup, error) {
+	group := &domain.RecipientGroup{}
+	err := fr.getDocument(
+		ctx,
+		recipientGroupsCollectionName,
+		name,
+		group,
+		exceptions.ErrRecipientGroupNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListRecipientGroups returns the groups ordered by name
+func (fr Repository) ListRecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error) {
+	query := fr.collection(recipientGroupsCollectionName).OrderBy("name", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []*domain.RecipientGroup{}
+	for _, doc := range docs {
+		group := &domain.RecipientGroup{}
+		if err := doc.DataTo(group); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal recipient group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// DeleteRecipientGroup removes a group
+func (fr Repository) DeleteRecipientGroup(ctx context.Context, name string) error {
+	return fr.deleteDocument(
+		ctx,
+		recipientGroupsCollectionName,
+		name,
+		exceptions.ErrRecipientGroupNotFound,
+	)
+}
@@ -0,0 +1,143 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveSMSDeliveryReport replaces the delivery report of a message
+func (fr Repository) SaveSMSDeliveryReport(ctx context.Context, report *domain.SMSDeliveryReport) error {
+	return fr.setDocument(ctx, smsDeliveryReportsCollectionName, report.MessageID, report)
+}
+
+// GetSMSDeliveryReport retrieves the delivery report of a message
+func (fr Repository) GetSMSDeliveryReport(ctx context.Context, messageID string) (*domain.SMSDeliveryReport, error) {
+	report := &domain.SMSDeliveryReport{}
+	err := fr.getDocument(
+		ctx,
+		smsDeliveryReportsCollectionName,
+		messageID,
+		report,
+		exceptions.ErrSMSDeliveryReportNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// SaveInboundSMS saves a received message keyed on its ID
+func (fr Repository) SaveInboundSMS(ctx context.Context, sms *domain.InboundSMS) error {
+	return fr.setDocument(ctx, inboundSMSCollectionName, sms.ID, sms)
+}
+
+// SaveChannelPreference creates or replaces a preference keyed on its channel
+// and address
+func (fr Repository) SaveChannelPreference(ctx context.Context, preference *domain.ChannelPreference) error {
+	return fr.setDocument(
+		ctx,
+		channelPreferencesCollectionName,
+		channelPreferenceID(preference.Channel, preference.Address),
+		preference,
+	)
+}
+
+// GetChannelPreference retrieves the preference of an address for a channel
+func (fr Repository) GetChannelPreference(
+	ctx context.Context,
+	channel feedlib.Channel,
+	address string,
+) (*domain.ChannelPreference, error) {
+	preference := &domain.ChannelPreference{}
+	err := fr.getDocument(
+		ctx,
+		channelPreferencesCollectionName,
+		channelPreferenceID(channel, address),
+		preference,
+		exceptions.ErrChannelPreferenceNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+// ListChannelPreferences returns the preferences of the addresses for a
+// channel, with one `in` query per maxInQueryValues addresses
+func (fr Repository) ListChannelPreferences(
+	ctx context.Context,
+	channel feedlib.Channel,
+	addresses []string,
+) ([]*domain.ChannelPreference, error) {
+	preferences := []*domain.ChannelPreference{}
+	for start := 0; start < len(addresses); start += maxInQueryValues {
+		end := start + maxInQueryValues
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		query := fr.collection(channelPreferencesCollectionName).
+			Where("channel", "==", channel).
+			Where("address", "in", addresses[start:end])
+		docs, err := fr.queryDocuments(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			preference := &domain.ChannelPreference{}
+			if err := doc.DataTo(preference); err != nil {
+				return nil, fmt.Errorf("unable to unmarshal channel preference: %w", err)
+			}
+			preferences = append(preferences, preference)
+		}
+	}
+	return preferences, nil
+}
+
+// SaveSMSConversation saves a conversation keyed on its ID
+func (fr Repository) SaveSMSConversation(ctx context.Context, conversation *domain.SMSConversation) error {
+	return fr.setDocument(ctx, smsConversationsCollectionName, conversation.ID, conversation)
+}
+
+// GetActiveSMSConversation returns the latest unexpired conversation of a
+// phone number
+func (fr Repository) GetActiveSMSConversation(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.SMSConversation, error) {
+	query := fr.collection(smsConversationsCollectionName).
+		Where("phone", "==", phone).
+		Where("expiresAt", ">", now).
+		OrderBy("expiresAt", firestore.Desc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *domain.SMSConversation
+	for _, doc := range docs {
+		conversation := &domain.SMSConversation{}
+		if err := doc.DataTo(conversation); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal SMS conversation: %w", err)
+		}
+		if latest == nil || conversation.CreatedAt.After(latest.CreatedAt) {
+			latest = conversation
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrSMSConversationNotFound
+	}
+	return latest, nil
+}
+
+// channelPreferenceID is the document ID of a preference. Phone numbers and
+// email addresses don't contain slashes so they are safe in a document ID.
+func channelPreferenceID(channel feedlib.Channel, address string) string {
+	return channel.String() + ":" + address
+}
@@ -0,0 +1,93 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveSMSRate creates or replaces a rate keyed on its prefix
+func (fr Repository) SaveSMSRate(ctx context.Context, rate *domain.SMSRate) (*domain.SMSRate, error) {
+	err := fr.setDocument(ctx, smsRatesCollectionName, rate.Prefix, rate)
+	if err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// ListSMSRates returns the rates ordered by prefix
+func (fr Repository) ListSMSRates(ctx context.Context) ([]*domain.SMSRate, error) {
+	query := fr.collection(smsRatesCollectionName).OrderBy("prefix", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := []*domain.SMSRate{}
+	for _, doc := range docs {
+		rate := &domain.SMSRate{}
+		if err := doc.DataTo(rate); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal SMS rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// DeleteSMSRate removes the rate of a prefix
+func (fr Repository) DeleteSMSRate(ctx context.Context, prefix string) error {
+	return fr.deleteDocument(ctx, smsRatesCollectionName, prefix, exceptions.ErrSMSRateNotFound)
+}
+
+// SaveSMSBudget creates or replaces the budget of a flavour
+func (fr Repository) SaveSMSBudget(ctx context.Context, budget *domain.SMSBudget) (*domain.SMSBudget, error) {
+	err := fr.setDocument(ctx, smsBudgetsCollectionName, budget.Flavour.String(), budget)
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// GetSMSBudget retrieves the budget of a flavour
+func (fr Repository) GetSMSBudget(ctx context.Context, flavour feedlib.Flavour) (*domain.SMSBudget, error) {
+	budget := &domain.SMSBudget{}
+	err := fr.getDocument(
+		ctx,
+		smsBudgetsCollectionName,
+		flavour.String(),
+		budget,
+		exceptions.ErrSMSBudgetNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// ListSMSBudgets returns the budgets ordered by flavour
+func (fr Repository) ListSMSBudgets(ctx context.Context) ([]*domain.SMSBudget, error) {
+	query := fr.collection(smsBudgetsCollectionName).OrderBy("flavour", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets := []*domain.SMSBudget{}
+	for _, doc := range docs {
+		budget := &domain.SMSBudget{}
+		if err := doc.DataTo(budget); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal SMS budget: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, nil
+}
+
+// DeleteSMSBudget removes the budget of a flavour
+func (fr Repository) DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) error {
+	return fr.deleteDocument(ctx, smsBudgetsCollectionName, flavour.String(), exceptions.ErrSMSBudgetNotFound)
+}
@@ -0,0 +1,80 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveNotificationTemplate creates or replaces a template keyed on its name
+func (fr Repository) SaveNotificationTemplate(
+	ctx context.Context,
+	template *domain.NotificationTemplate,
+) (*domain.NotificationTemplate, error) {
+	err := fr.setDocument(ctx, notificationTemplatesCollectionName, template.Name, template)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// GetNotificationTemplate retrieves a template by name
+func (fr Repository) GetNotificationTemplate(
+	ctx context.Context,
+	name string,
+) (*domain.NotificationTemplate, error) {
+	template := &domain.NotificationTemplate{}
+	err := fr.getDocument(
+		ctx,
+		notificationTemplatesCollectionName,
+		name,
+		template,
+		exceptions.ErrTemplateNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// ListNotificationTemplates returns the templates ordered by name
+func (fr Repository) ListNotificationTemplates(
+	ctx context.Context,
+	channel *feedlib.Channel,
+) ([]*domain.NotificationTemplate, error) {
+	query := fr.collection(notificationTemplatesCollectionName).Query
+	if channel != nil {
+		query = query.Where("channel", "==", channel.String())
+	}
+	docs, err := fr.queryDocuments(ctx, query.OrderBy("name", firestore.Asc))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []*domain.NotificationTemplate{}
+	for _, doc := range docs {
+		template := &domain.NotificationTemplate{}
+		if err := doc.DataTo(template); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal notification template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// DeleteNotificationTemplate removes a template
+func (fr Repository) DeleteNotificationTemplate(
+	ctx context.Context,
+	name string,
+) error {
+	return fr.deleteDocument(
+		ctx,
+		notificationTemplatesCollectionName,
+		name,
+		exceptions.ErrTemplateNotFound,
+	)
+}
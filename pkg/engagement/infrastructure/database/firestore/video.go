@@ -0,0 +1,98 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveVideoRoom creates or replaces a room keyed on its ID
+func (fr Repository) SaveVideoRoom(ctx context.Context, room *domain.VideoRoom) error {
+	return fr.setDocument(ctx, videoRoomsCollectionName, room.ID, room)
+}
+
+// GetVideoRoom retrieves a room by its ID
+func (fr Repository) GetVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error) {
+	room := &domain.VideoRoom{}
+	err := fr.getDocument(ctx, videoRoomsCollectionName, id, room, exceptions.ErrVideoRoomNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// ListVideoRooms returns the rooms with a status ordered by their scheduled
+// start
+func (fr Repository) ListVideoRooms(ctx context.Context, status domain.VideoRoomStatus) ([]*domain.VideoRoom, error) {
+	query := fr.collection(videoRoomsCollectionName).
+		Where("status", "==", status).
+		OrderBy("scheduledStart", firestore.Asc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := []*domain.VideoRoom{}
+	for _, doc := range docs {
+		room := &domain.VideoRoom{}
+		if err := doc.DataTo(room); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal video room: %w", err)
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+// SaveVideoParticipant creates or replaces a participant's connection keyed
+// on its ID
+func (fr Repository) SaveVideoParticipant(ctx context.Context, participant *domain.VideoParticipant) error {
+	return fr.setDocument(ctx, videoParticipantsCollectionName, participant.ID, participant)
+}
+
+// GetVideoParticipant retrieves a participant's connection by its ID
+func (fr Repository) GetVideoParticipant(ctx context.Context, id string) (*domain.VideoParticipant, error) {
+	participant := &domain.VideoParticipant{}
+	err := fr.getDocument(
+		ctx,
+		videoParticipantsCollectionName,
+		id,
+		participant,
+		exceptions.ErrVideoParticipantNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// ListVideoParticipants returns the connections to a room in the order that
+// they joined. They are sorted here since connections whose join was not
+// reported have no join time, and are listed last.
+func (fr Repository) ListVideoParticipants(ctx context.Context, roomID string) ([]*domain.VideoParticipant, error) {
+	query := fr.collection(videoParticipantsCollectionName).Where("roomID", "==", roomID)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []*domain.VideoParticipant{}
+	for _, doc := range docs {
+		participant := &domain.VideoParticipant{}
+		if err := doc.DataTo(participant); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal video participant: %w", err)
+		}
+		participants = append(participants, participant)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		a, b := participants[i], participants[j]
+		if a.JoinedAt == nil || b.JoinedAt == nil {
+			return a.JoinedAt != nil
+		}
+		return a.JoinedAt.Before(*b.JoinedAt)
+	})
+	return participants, nil
+}
@@ -0,0 +1,155 @@
+package fb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveWhatsAppSession creates or replaces the session of a phone number
+func (fr Repository) SaveWhatsAppSession(ctx context.Context, session *domain.WhatsAppSession) error {
+	return fr.setDocument(ctx, whatsAppSessionsCollectionName, session.Phone, session)
+}
+
+// GetWhatsAppSession retrieves the session of a phone number
+func (fr Repository) GetWhatsAppSession(ctx context.Context, phone string) (*domain.WhatsAppSession, error) {
+	session := &domain.WhatsAppSession{}
+	err := fr.getDocument(
+		ctx,
+		whatsAppSessionsCollectionName,
+		phone,
+		session,
+		exceptions.ErrWhatsAppSessionNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SaveInboundWhatsApp saves a received message keyed on its ID
+func (fr Repository) SaveInboundWhatsApp(ctx context.Context, message *domain.InboundWhatsApp) error {
+	return fr.setDocument(ctx, inboundWhatsAppCollectionName, message.ID, message)
+}
+
+// GetInboundWhatsApp retrieves a received message by its ID
+func (fr Repository) GetInboundWhatsApp(ctx context.Context, id string) (*domain.InboundWhatsApp, error) {
+	message := &domain.InboundWhatsApp{}
+	err := fr.getDocument(
+		ctx,
+		inboundWhatsAppCollectionName,
+		id,
+		message,
+		exceptions.ErrInboundWhatsAppNotFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// ListWhatsAppSupportQueue returns the unresolved support messages, oldest
+// first
+func (fr Repository) ListWhatsAppSupportQueue(ctx context.Context, limit int) ([]*domain.InboundWhatsApp, error) {
+	query := fr.collection(inboundWhatsAppCollectionName).
+		Where("support", "==", true).
+		Where("resolved", "==", false).
+		OrderBy("receivedAt", firestore.Asc).
+		Limit(limit)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*domain.InboundWhatsApp{}
+	for _, doc := range docs {
+		message := &domain.InboundWhatsApp{}
+		if err := doc.DataTo(message); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal WhatsApp message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// SaveWhatsAppConversation saves a conversation keyed on its ID
+func (fr Repository) SaveWhatsAppConversation(
+	ctx context.Context,
+	conversation *domain.WhatsAppConversation,
+) error {
+	return fr.setDocument(ctx, whatsAppConversationsCollectionName, conversation.ID, conversation)
+}
+
+// GetActiveWhatsAppConversation returns the latest unexpired conversation of
+// a phone number
+func (fr Repository) GetActiveWhatsAppConversation(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.WhatsAppConversation, error) {
+	query := fr.collection(whatsAppConversationsCollectionName).
+		Where("phone", "==", phone).
+		Where("expiresAt", ">", now).
+		OrderBy("expiresAt", firestore.Desc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *domain.WhatsAppConversation
+	for _, doc := range docs {
+		conversation := &domain.WhatsAppConversation{}
+		if err := doc.DataTo(conversation); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal WhatsApp conversation: %w", err)
+		}
+		if latest == nil || conversation.CreatedAt.After(latest.CreatedAt) {
+			latest = conversation
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrWhatsAppConversationNotFound
+	}
+	return latest, nil
+}
+
+// SaveWhatsAppNudge saves a sent nudge keyed on its ID
+func (fr Repository) SaveWhatsAppNudge(ctx context.Context, nudge *domain.WhatsAppNudge) error {
+	return fr.setDocument(ctx, whatsAppNudgesCollectionName, nudge.ID, nudge)
+}
+
+// GetActiveWhatsAppNudge returns the latest unanswered and unexpired nudge of
+// a phone number
+func (fr Repository) GetActiveWhatsAppNudge(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.WhatsAppNudge, error) {
+	query := fr.collection(whatsAppNudgesCollectionName).
+		Where("phone", "==", phone).
+		Where("answeredWith", "==", "").
+		Where("expiresAt", ">", now).
+		OrderBy("expiresAt", firestore.Desc)
+	docs, err := fr.queryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *domain.WhatsAppNudge
+	for _, doc := range docs {
+		nudge := &domain.WhatsAppNudge{}
+		if err := doc.DataTo(nudge); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal WhatsApp nudge: %w", err)
+		}
+		if latest == nil || nudge.CreatedAt.After(latest.CreatedAt) {
+			latest = nudge
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrWhatsAppNudgeNotFound
+	}
+	return latest, nil
+}
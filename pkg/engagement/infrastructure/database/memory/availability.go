@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveClinicianAvailability creates or replaces an availability keyed on its
+// ID
+func (r *Repository) SaveClinicianAvailability(ctx context.Context, availability *domain.ClinicianAvailability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.availability[availability.ID] = *availability
+	return nil
+}
+
+// GetClinicianAvailability retrieves a clinician's availability at a location
+func (r *Repository) GetClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+	locationID string,
+) (*domain.ClinicianAvailability, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, availability := range r.availability {
+		if availability.ClinicianID == clinicianID && availability.LocationID == locationID {
+			a := availability
+			return &a, nil
+		}
+	}
+	return nil, exceptions.ErrClinicianAvailabilityNotFound
+}
+
+// ListClinicianAvailability returns a clinician's availability ordered by
+// location
+func (r *Repository) ListClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+) ([]*domain.ClinicianAvailability, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	availability := []*domain.ClinicianAvailability{}
+	for _, a := range r.availability {
+		if a.ClinicianID != clinicianID {
+			continue
+		}
+		a := a
+		availability = append(availability, &a)
+	}
+	sort.Slice(availability, func(i, j int) bool {
+		return availability[i].LocationID < availability[j].LocationID
+	})
+	return availability, nil
+}
+
+// DeleteClinicianAvailability deletes an availability
+func (r *Repository) DeleteClinicianAvailability(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.availability[id]; !ok {
+		return exceptions.ErrClinicianAvailabilityNotFound
+	}
+	delete(r.availability, id)
+	return nil
+}
+
+// SaveAvailabilityException creates or replaces an exception keyed on its ID
+func (r *Repository) SaveAvailabilityException(ctx context.Context, exception *domain.AvailabilityException) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.availabilityExceptions[exception.ID] = *exception
+	return nil
+}
+
+// DeleteAvailabilityException deletes an exception
+func (r *Repository) DeleteAvailabilityException(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.availabilityExceptions[id]; !ok {
+		return exceptions.ErrAvailabilityExceptionNotFound
+	}
+	delete(r.availabilityExceptions, id)
+	return nil
+}
+
+// ListAvailabilityExceptions returns a clinician's exceptions that overlap an
+// interval ordered by their start
+func (r *Repository) ListAvailabilityExceptions(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+) ([]*domain.AvailabilityException, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exceptions := []*domain.AvailabilityException{}
+	for _, exception := range r.availabilityExceptions {
+		if exception.ClinicianID != clinicianID ||
+			!exception.Start.Before(to) ||
+			!exception.End.After(from) {
+			continue
+		}
+		e := exception
+		exceptions = append(exceptions, &e)
+	}
+	sort.Slice(exceptions, func(i, j int) bool {
+		return exceptions[i].Start.Before(exceptions[j].Start)
+	})
+	return exceptions, nil
+}
+
+// BookSlot saves a booking while holding the repository's lock, so that
+// concurrent bookings are checked against each other
+func (r *Repository) BookSlot(ctx context.Context, booking *domain.SlotBooking, buffer time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.slotBookings {
+		if existing.ID != booking.ID &&
+			existing.ClinicianID == booking.ClinicianID &&
+			existing.Conflicts(booking.Start, booking.End, buffer) {
+			return exceptions.ErrSlotUnavailable
+		}
+	}
+	r.slotBookings[booking.ID] = *booking
+	return nil
+}
+
+// SaveSlotBooking replaces a booking keyed on its ID
+func (r *Repository) SaveSlotBooking(ctx context.Context, booking *domain.SlotBooking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.slotBookings[booking.ID] = *booking
+	return nil
+}
+
+// DeleteSlotBooking deletes a booking
+func (r *Repository) DeleteSlotBooking(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.slotBookings[id]; !ok {
+		return exceptions.ErrSlotBookingNotFound
+	}
+	delete(r.slotBookings, id)
+	return nil
+}
+
+// GetSlotBookingByEvent retrieves the booking of a calendar event
+func (r *Repository) GetSlotBookingByEvent(ctx context.Context, eventID string) (*domain.SlotBooking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, booking := range r.slotBookings {
+		if booking.EventID == eventID {
+			b := booking
+			return &b, nil
+		}
+	}
+	return nil, exceptions.ErrSlotBookingNotFound
+}
+
+// ListSlotBookings returns a clinician's active bookings that overlap an
+// interval ordered by their start
+func (r *Repository) ListSlotBookings(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+) ([]*domain.SlotBooking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bookings := []*domain.SlotBooking{}
+	for _, booking := range r.slotBookings {
+		if booking.ClinicianID != clinicianID || !booking.Conflicts(from, to, 0) {
+			continue
+		}
+		b := booking
+		bookings = append(bookings, &b)
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].Start.Before(bookings[j].Start)
+	})
+	return bookings, nil
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// CreateBulkSMSJob saves a job and its recipients
+func (r *Repository) CreateBulkSMSJob(
+	ctx context.Context,
+	job *domain.BulkSMSJob,
+	recipients []*domain.BulkSMSRecipient,
+) (*domain.BulkSMSJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bulkSMSJobs[job.ID] = *job
+	for _, recipient := range recipients {
+		r.bulkSMSRecipients[bulkSMSRecipientKey(recipient.JobID, recipient.Index)] = *recipient
+	}
+	saved := *job
+	return &saved, nil
+}
+
+// GetBulkSMSJob retrieves a job by ID
+func (r *Repository) GetBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.bulkSMSJobs[id]
+	if !ok {
+		return nil, exceptions.ErrBulkSMSJobNotFound
+	}
+	return &job, nil
+}
+
+// UpdateBulkSMSJob applies `update` to a job under the lock
+func (r *Repository) UpdateBulkSMSJob(
+	ctx context.Context,
+	id string,
+	update func(job *domain.BulkSMSJob) error,
+) (*domain.BulkSMSJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.bulkSMSJobs[id]
+	if !ok {
+		return nil, exceptions.ErrBulkSMSJobNotFound
+	}
+	if err := update(&job); err != nil {
+		return nil, err
+	}
+	r.bulkSMSJobs[id] = job
+	return &job, nil
+}
+
+// ListActiveBulkSMSJobs returns the pending and running jobs, oldest first
+func (r *Repository) ListActiveBulkSMSJobs(ctx context.Context, limit int) ([]*domain.BulkSMSJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := []*domain.BulkSMSJob{}
+	for _, job := range r.bulkSMSJobs {
+		if job.Status.IsFinal() {
+			continue
+		}
+		j := job
+		jobs = append(jobs, &j)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// ListBulkSMSRecipients returns the recipients of a job in index order
+func (r *Repository) ListBulkSMSRecipients(
+	ctx context.Context,
+	jobID string,
+	status *domain.BulkSMSRecipientStatus,
+	afterIndex int,
+	limit int,
+) ([]*domain.BulkSMSRecipient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recipients := []*domain.BulkSMSRecipient{}
+	for _, recipient := range r.bulkSMSRecipients {
+		if recipient.JobID != jobID || recipient.Index <= afterIndex {
+			continue
+		}
+		if status != nil && recipient.Status != *status {
+			continue
+		}
+		rec := recipient
+		recipients = append(recipients, &rec)
+	}
+	sort.Slice(recipients, func(i, j int) bool {
+		return recipients[i].Index < recipients[j].Index
+	})
+	if len(recipients) > limit {
+		recipients = recipients[:limit]
+	}
+	return recipients, nil
+}
+
+// SaveBulkSMSRecipients replaces recipients keyed on their job and index
+func (r *Repository) SaveBulkSMSRecipients(ctx context.Context, recipients []*domain.BulkSMSRecipient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, recipient := range recipients {
+		r.bulkSMSRecipients[bulkSMSRecipientKey(recipient.JobID, recipient.Index)] = *recipient
+	}
+	return nil
+}
+
+// UpdateBulkSMSRecipientDelivery sets the delivery status of the recipient
+// that was sent a message
+func (r *Repository) UpdateBulkSMSRecipientDelivery(
+	ctx context.Context,
+	messageID string,
+	status string,
+	at time.Time,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, recipient := range r.bulkSMSRecipients {
+		if recipient.MessageID != messageID {
+			continue
+		}
+		recipient.DeliveryStatus = status
+		recipient.DeliveryUpdatedAt = &at
+		r.bulkSMSRecipients[key] = recipient
+		return true, nil
+	}
+	return false, nil
+}
+
+func bulkSMSRecipientKey(jobID string, index int) string {
+	return fmt.Sprintf("%s/%d", jobID, index)
+}
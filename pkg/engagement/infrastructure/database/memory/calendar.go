@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveCalendarEvent creates or replaces an event keyed on its ID
+func (r *Repository) SaveCalendarEvent(ctx context.Context, event *domain.StoredCalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calendarEvents[event.ID] = *event
+	return nil
+}
+
+// GetCalendarEvent retrieves an event by its ID
+func (r *Repository) GetCalendarEvent(ctx context.Context, id string) (*domain.StoredCalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.calendarEvents[id]
+	if !ok {
+		return nil, exceptions.ErrCalendarEventNotFound
+	}
+	return &event, nil
+}
+
+// ListCalendarEvents returns the events that overlap an interval ordered by
+// their start
+func (r *Repository) ListCalendarEvents(ctx context.Context, from, to time.Time) ([]*domain.StoredCalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []*domain.StoredCalendarEvent{}
+	for _, event := range r.calendarEvents {
+		if !event.Start.Before(to) || !event.End.After(from) {
+			continue
+		}
+		e := event
+		events = append(events, &e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+func deviceKey(uid string, deviceID string) string {
+	return uid + "/" + deviceID
+}
+
+// SaveDevice creates or replaces a device keyed on the user and device ID
+func (r *Repository) SaveDevice(ctx context.Context, device *domain.Device) (*domain.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.devices[deviceKey(device.UID, device.ID)] = *device
+	saved := *device
+	return &saved, nil
+}
+
+// ListDevices returns a user's devices, most recently updated first
+func (r *Repository) ListDevices(ctx context.Context, uid string) ([]*domain.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices := []*domain.Device{}
+	for _, device := range r.devices {
+		if device.UID != uid {
+			continue
+		}
+		d := device
+		devices = append(devices, &d)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].UpdatedAt.After(devices[j].UpdatedAt)
+	})
+	return devices, nil
+}
+
+// ListDevicesByToken returns the devices registered with any of the supplied tokens
+func (r *Repository) ListDevicesByToken(ctx context.Context, tokens []string) ([]*domain.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, token := range tokens {
+		wanted[token] = true
+	}
+
+	devices := []*domain.Device{}
+	for _, device := range r.devices {
+		if !wanted[device.Token] {
+			continue
+		}
+		d := device
+		devices = append(devices, &d)
+	}
+	return devices, nil
+}
+
+// DeleteDevice removes a user's device
+func (r *Repository) DeleteDevice(ctx context.Context, uid string, deviceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := deviceKey(uid, deviceID)
+	if _, ok := r.devices[key]; !ok {
+		return exceptions.ErrDeviceNotFound
+	}
+	delete(r.devices, key)
+	return nil
+}
+
+// DeleteDevicesByToken removes every device registered with the supplied tokens
+func (r *Repository) DeleteDevicesByToken(ctx context.Context, tokens []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remove := map[string]bool{}
+	for _, token := range tokens {
+		remove[token] = true
+	}
+
+	count := 0
+	for key, device := range r.devices {
+		if remove[device.Token] {
+			delete(r.devices, key)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveEmailConversation saves a conversation keyed on its ID
+func (r *Repository) SaveEmailConversation(ctx context.Context, conversation *domain.EmailConversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emailConversations[conversation.ID] = *conversation
+	return nil
+}
+
+// GetEmailConversation retrieves a conversation by its ID
+func (r *Repository) GetEmailConversation(ctx context.Context, id string) (*domain.EmailConversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.emailConversations[id]
+	if !ok {
+		return nil, exceptions.ErrEmailConversationNotFound
+	}
+	return &conversation, nil
+}
+
+// SaveInboundEmail saves a received email keyed on its ID
+func (r *Repository) SaveInboundEmail(ctx context.Context, email *domain.InboundEmail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inboundEmails[email.ID] = *email
+	return nil
+}
+
+// GetInboundEmail retrieves a received email by its ID
+func (r *Repository) GetInboundEmail(ctx context.Context, id string) (*domain.InboundEmail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email, ok := r.inboundEmails[id]
+	if !ok {
+		return nil, exceptions.ErrInboundEmailNotFound
+	}
+	return &email, nil
+}
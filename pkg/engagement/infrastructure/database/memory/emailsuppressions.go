@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveEmailSuppression creates or replaces a suppression keyed on its email
+// address
+func (r *Repository) SaveEmailSuppression(ctx context.Context, suppression *domain.EmailSuppression) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emailSuppressions[suppression.Email] = *suppression
+	return nil
+}
+
+// GetEmailSuppression retrieves the suppression of an email address
+func (r *Repository) GetEmailSuppression(ctx context.Context, email string) (*domain.EmailSuppression, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suppression, ok := r.emailSuppressions[email]
+	if !ok {
+		return nil, exceptions.ErrEmailSuppressionNotFound
+	}
+	return &suppression, nil
+}
+
+// ListEmailSuppressions returns the suppressions, optionally of one reason,
+// newest first
+func (r *Repository) ListEmailSuppressions(
+	ctx context.Context,
+	reason *domain.EmailSuppressionReason,
+) ([]*domain.EmailSuppression, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suppressions := []*domain.EmailSuppression{}
+	for _, suppression := range r.emailSuppressions {
+		if reason != nil && suppression.Reason != *reason {
+			continue
+		}
+		s := suppression
+		suppressions = append(suppressions, &s)
+	}
+	sort.Slice(suppressions, func(i, j int) bool {
+		return suppressions[i].CreatedAt.After(suppressions[j].CreatedAt)
+	})
+	return suppressions, nil
+}
+
+// DeleteEmailSuppression removes the suppression of an email address
+func (r *Repository) DeleteEmailSuppression(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.emailSuppressions[email]; !ok {
+		return exceptions.ErrEmailSuppressionNotFound
+	}
+	delete(r.emailSuppressions, email)
+	return nil
+}
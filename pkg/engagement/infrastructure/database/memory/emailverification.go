@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveEmailVerificationLink creates or replaces a link keyed on its ID
+func (r *Repository) SaveEmailVerificationLink(
+	ctx context.Context,
+	link *domain.EmailVerificationLink,
+) (*domain.EmailVerificationLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emailVerificationLinks[link.ID] = *link
+	saved := *link
+	return &saved, nil
+}
+
+// GetEmailVerificationLink retrieves a link by its ID
+func (r *Repository) GetEmailVerificationLink(
+	ctx context.Context,
+	id string,
+) (*domain.EmailVerificationLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.emailVerificationLinks[id]
+	if !ok {
+		return nil, exceptions.ErrEmailVerificationLinkNotFound
+	}
+	return &link, nil
+}
+
+// ConsumeEmailVerificationLink marks a valid link as verified under the lock
+func (r *Repository) ConsumeEmailVerificationLink(
+	ctx context.Context,
+	id string,
+	now time.Time,
+) (*domain.EmailVerificationLink, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.emailVerificationLinks[id]
+	if !ok {
+		return nil, false, exceptions.ErrEmailVerificationLinkNotFound
+	}
+	if !link.IsValid(now) {
+		return &link, false, nil
+	}
+	link.VerifiedAt = &now
+	r.emailVerificationLinks[id] = link
+	return &link, true, nil
+}
+
+// RevokeEmailVerificationLinks revokes the valid links of an email address
+func (r *Repository) RevokeEmailVerificationLinks(
+	ctx context.Context,
+	email string,
+	now time.Time,
+) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked := 0
+	for id, link := range r.emailVerificationLinks {
+		if link.Email != email || !link.IsValid(now) {
+			continue
+		}
+		link.RevokedAt = &now
+		r.emailVerificationLinks[id] = link
+		revoked++
+	}
+	return revoked, nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveInboxNotification creates or replaces a notification keyed on its ID
+func (r *Repository) SaveInboxNotification(
+	ctx context.Context,
+	notification *domain.InboxNotification,
+) (*domain.InboxNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inbox[notification.ID] = *notification
+	saved := *notification
+	return &saved, nil
+}
+
+// ListInboxNotifications returns a page of a user's notifications, newest first
+func (r *Repository) ListInboxNotifications(
+	ctx context.Context,
+	uid string,
+	filter domain.InboxFilter,
+	after *domain.InboxCursor,
+	limit int,
+) ([]*domain.InboxNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := []*domain.InboxNotification{}
+	for _, notification := range r.inbox {
+		if notification.UID != uid {
+			continue
+		}
+		if filter.Channel != nil && notification.Channel != *filter.Channel {
+			continue
+		}
+		if filter.Read != nil && notification.Read != *filter.Read {
+			continue
+		}
+		if after != nil && !notification.After(*after) {
+			continue
+		}
+		n := notification
+		notifications = append(notifications, &n)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[j].After(domain.InboxCursor{
+			CreatedAt: notifications[i].CreatedAt,
+			ID:        notifications[i].ID,
+		})
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+// CountUnreadInboxNotifications counts a user's unread notifications
+func (r *Repository) CountUnreadInboxNotifications(ctx context.Context, uid string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, notification := range r.inbox {
+		if notification.UID == uid && !notification.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SetInboxNotificationsRead marks the supplied notifications as read or unread
+func (r *Repository) SetInboxNotificationsRead(
+	ctx context.Context,
+	uid string,
+	ids []string,
+	read bool,
+	at time.Time,
+) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		notification, ok := r.inbox[id]
+		if !ok || notification.UID != uid {
+			return 0, exceptions.ErrInboxNotificationNotFound
+		}
+	}
+
+	count := 0
+	for _, id := range ids {
+		notification := r.inbox[id]
+		if notification.Read == read {
+			continue
+		}
+		setRead(&notification, read, at)
+		r.inbox[id] = notification
+		count++
+	}
+	return count, nil
+}
+
+// MarkAllInboxNotificationsRead marks every unread notification in a user's
+// inbox as read
+func (r *Repository) MarkAllInboxNotificationsRead(
+	ctx context.Context,
+	uid string,
+	at time.Time,
+) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for id, notification := range r.inbox {
+		if notification.UID != uid || notification.Read {
+			continue
+		}
+		setRead(&notification, true, at)
+		r.inbox[id] = notification
+		count++
+	}
+	return count, nil
+}
+
+func setRead(notification *domain.InboxNotification, read bool, at time.Time) {
+	notification.Read = read
+	notification.ReadAt = nil
+	if read {
+		readAt := at
+		notification.ReadAt = &readAt
+	}
+}
@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveOTP creates or replaces an OTP keyed on its ID
+func (r *Repository) SaveOTP(ctx context.Context, otp *domain.OTP) (*domain.OTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.otps[otp.ID] = *otp
+	saved := *otp
+	return &saved, nil
+}
+
+// ListValidOTPs returns the valid OTPs of an identifier, newest first
+func (r *Repository) ListValidOTPs(
+	ctx context.Context,
+	identifier string,
+	now time.Time,
+) ([]*domain.OTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	otps := []*domain.OTP{}
+	for _, otp := range r.otps {
+		if otp.Identifier != identifier || !otp.IsValid(now) {
+			continue
+		}
+		o := otp
+		otps = append(otps, &o)
+	}
+	sort.Slice(otps, func(i, j int) bool {
+		return otps[i].CreatedAt.After(otps[j].CreatedAt)
+	})
+	return otps, nil
+}
+
+// ConsumeOTP marks a valid OTP as used
+func (r *Repository) ConsumeOTP(ctx context.Context, id string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	otp, ok := r.otps[id]
+	if !ok {
+		return false, exceptions.ErrOTPNotFound
+	}
+	if !otp.IsValid(now) {
+		return false, nil
+	}
+	otp.UsedAt = &now
+	r.otps[id] = otp
+	return true, nil
+}
+
+// UpdateOTPAttempts applies `update` to the attempts stored under `id`
+func (r *Repository) UpdateOTPAttempts(
+	ctx context.Context,
+	id string,
+	update func(attempts *domain.OTPAttempts),
+) (*domain.OTPAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.otpAttempts[id]
+	if !ok {
+		attempts = domain.OTPAttempts{ID: id}
+	}
+	update(&attempts)
+	r.otpAttempts[id] = attempts
+	return &attempts, nil
+}
+
+// SaveOTPApp creates or replaces the OTP settings of an app keyed on its appId
+func (r *Repository) SaveOTPApp(ctx context.Context, app *domain.OTPApp) (*domain.OTPApp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.otpApps[app.AppID] = *app
+	saved := *app
+	return &saved, nil
+}
+
+// GetOTPApp retrieves the OTP settings of an app
+func (r *Repository) GetOTPApp(ctx context.Context, appID string) (*domain.OTPApp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	app, ok := r.otpApps[appID]
+	if !ok {
+		return nil, exceptions.ErrOTPAppNotFound
+	}
+	return &app, nil
+}
+
+// ListOTPApps returns the registered apps ordered by appId
+func (r *Repository) ListOTPApps(ctx context.Context) ([]*domain.OTPApp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	apps := []*domain.OTPApp{}
+	for _, app := range r.otpApps {
+		a := app
+		apps = append(apps, &a)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].AppID < apps[j].AppID
+	})
+	return apps, nil
+}
+
+// DeleteOTPApp removes the OTP settings of an app
+func (r *Repository) DeleteOTPApp(ctx context.Context, appID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.otpApps[appID]; !ok {
+		return exceptions.ErrOTPAppNotFound
+	}
+	delete(r.otpApps, appID)
+	return nil
+}
+
+// SaveOTPDelivery creates or replaces an OTP delivery keyed on its ID
+func (r *Repository) SaveOTPDelivery(
+	ctx context.Context,
+	delivery *domain.OTPDelivery,
+) (*domain.OTPDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.otpDeliveries[delivery.ID] = *delivery
+	saved := *delivery
+	return &saved, nil
+}
+
+// ListOTPDeliveries returns the latest deliveries of an identifier's codes
+func (r *Repository) ListOTPDeliveries(
+	ctx context.Context,
+	identifier string,
+	limit int,
+) ([]*domain.OTPDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := []*domain.OTPDelivery{}
+	for _, delivery := range r.otpDeliveries {
+		if delivery.Identifier != identifier {
+			continue
+		}
+		d := delivery
+		deliveries = append(deliveries, &d)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// SaveTOTPEnrolment creates or replaces the enrolment of a phone number
+func (r *Repository) SaveTOTPEnrolment(
+	ctx context.Context,
+	enrolment *domain.TOTPEnrolment,
+) (*domain.TOTPEnrolment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totpEnrolments[enrolment.ID] = copyTOTPEnrolment(*enrolment)
+	saved := copyTOTPEnrolment(*enrolment)
+	return &saved, nil
+}
+
+// GetTOTPEnrolment retrieves the enrolment of a phone number
+func (r *Repository) GetTOTPEnrolment(ctx context.Context, id string) (*domain.TOTPEnrolment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrolment, ok := r.totpEnrolments[id]
+	if !ok {
+		return nil, exceptions.ErrTOTPEnrolmentNotFound
+	}
+	found := copyTOTPEnrolment(enrolment)
+	return &found, nil
+}
+
+// DeleteTOTPEnrolment removes the enrolment of a phone number
+func (r *Repository) DeleteTOTPEnrolment(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.totpEnrolments[id]; !ok {
+		return exceptions.ErrTOTPEnrolmentNotFound
+	}
+	delete(r.totpEnrolments, id)
+	return nil
+}
+
+// UpdateTOTPEnrolment applies `update` to an enrolment under the lock
+func (r *Repository) UpdateTOTPEnrolment(
+	ctx context.Context,
+	id string,
+	update func(enrolment *domain.TOTPEnrolment) error,
+) (*domain.TOTPEnrolment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.totpEnrolments[id]
+	if !ok {
+		return nil, exceptions.ErrTOTPEnrolmentNotFound
+	}
+	enrolment := copyTOTPEnrolment(stored)
+	if err := update(&enrolment); err != nil {
+		return nil, err
+	}
+	r.totpEnrolments[id] = copyTOTPEnrolment(enrolment)
+	return &enrolment, nil
+}
+
+// copyTOTPEnrolment copies the recovery codes so that callers can't change
+// the stored enrolment
+func copyTOTPEnrolment(enrolment domain.TOTPEnrolment) domain.TOTPEnrolment {
+	codes := make([]domain.RecoveryCode, len(enrolment.RecoveryCodes))
+	copy(codes, enrolment.RecoveryCodes)
+	enrolment.RecoveryCodes = codes
+	return enrolment
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveRateLimitPolicy creates or replaces the policy of a flavour and channel
+func (r *Repository) SaveRateLimitPolicy(
+	ctx context.Context,
+	policy *domain.RateLimitPolicy,
+) (*domain.RateLimitPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rateLimits[policy.ID()] = *policy
+	saved := *policy
+	return &saved, nil
+}
+
+// GetRateLimitPolicy retrieves the policy of a flavour and channel
+func (r *Repository) GetRateLimitPolicy(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) (*domain.RateLimitPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := domain.RateLimitPolicy{Flavour: flavour, Channel: channel}.ID()
+	policy, ok := r.rateLimits[key]
+	if !ok {
+		return nil, exceptions.ErrRateLimitPolicyNotFound
+	}
+	return &policy, nil
+}
+
+// ListRateLimitPolicies returns the saved policies ordered by flavour and channel
+func (r *Repository) ListRateLimitPolicies(ctx context.Context) ([]*domain.RateLimitPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policies := []*domain.RateLimitPolicy{}
+	for _, policy := range r.rateLimits {
+		p := policy
+		policies = append(policies, &p)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].ID() < policies[j].ID()
+	})
+	return policies, nil
+}
+
+// DeleteRateLimitPolicy removes the policy of a flavour and channel
+func (r *Repository) DeleteRateLimitPolicy(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := domain.RateLimitPolicy{Flavour: flavour, Channel: channel}.ID()
+	if _, ok := r.rateLimits[key]; !ok {
+		return exceptions.ErrRateLimitPolicyNotFound
+	}
+	delete(r.rateLimits, key)
+	return nil
+}
+
+// ReserveNotification counts a notification against the recipient's current
+// window, or adds it to the recipient's digest when the limit has been reached
+func (r *Repository) ReserveNotification(
+	ctx context.Context,
+	policy domain.RateLimitPolicy,
+	recipient string,
+	now time.Time,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := policy.RateLimitKey(recipient)
+	counter, ok := r.counters[key]
+	if !ok || !now.Before(counter.WindowEnd) {
+		counter = domain.RateLimitCounter{
+			ID:          key,
+			WindowStart: now,
+			WindowEnd:   now.Add(policy.Window()),
+		}
+	}
+
+	if counter.Sent < policy.Limit {
+		counter.Sent++
+		r.counters[key] = counter
+		return true, nil
+	}
+	r.counters[key] = counter
+
+	digest, ok := r.digests[key]
+	if !ok {
+		digest = domain.NotificationDigest{
+			ID:        key,
+			Flavour:   policy.Flavour,
+			Channel:   policy.Channel,
+			Recipient: recipient,
+			CreatedAt: now,
+		}
+	}
+	digest.Count++
+	digest.DueAt = counter.WindowEnd
+	r.digests[key] = digest
+	return false, nil
+}
+
+// ListDueDigests returns the digests that are due at `now`, earliest first
+func (r *Repository) ListDueDigests(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*domain.NotificationDigest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digests := []*domain.NotificationDigest{}
+	for _, digest := range r.digests {
+		if digest.DueAt.After(now) {
+			continue
+		}
+		d := digest
+		digests = append(digests, &d)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].DueAt.Before(digests[j].DueAt)
+	})
+	if len(digests) > limit {
+		digests = digests[:limit]
+	}
+	return digests, nil
+}
+
+// TakeDigest removes and returns a digest
+func (r *Repository) TakeDigest(ctx context.Context, id string) (*domain.NotificationDigest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digest, ok := r.digests[id]
+	if !ok {
+		return nil, exceptions.ErrDigestNotFound
+	}
+	delete(r.digests, id)
+	return &digest, nil
+}
+
+// RequeueDigest puts back a digest that could not be sent
+func (r *Repository) RequeueDigest(ctx context.Context, digest *domain.NotificationDigest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	requeued := *digest
+	if current, ok := r.digests[digest.ID]; ok {
+		requeued = domain.MergeNotificationDigests(current, requeued)
+	}
+	r.digests[digest.ID] = requeued
+	return nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// Repository is an in-memory implementation of the service repository.
+//
+// It is meant for tests and local development. Nothing is persisted
+// across restarts.
+type Repository struct {
+	mu *sync.Mutex
+
+	templates map[string]domain.NotificationTemplate
+	devices   map[string]domain.Device
+	inbox     map[string]domain.InboxNotification
+	schedules map[string]domain.ScheduledNotification
+	groups    map[string]domain.RecipientGroup
+
+	rateLimits map[string]domain.RateLimitPolicy
+	counters   map[string]domain.RateLimitCounter
+	digests    map[string]domain.NotificationDigest
+
+	otps        map[string]domain.OTP
+	otpAttempts map[string]domain.OTPAttempts
+	otpApps     map[string]domain.OTPApp
+
+	otpDeliveries  map[string]domain.OTPDelivery
+	totpEnrolments map[string]domain.TOTPEnrolment
+
+	emailVerificationLinks map[string]domain.EmailVerificationLink
+
+	bulkSMSJobs       map[string]domain.BulkSMSJob
+	bulkSMSRecipients map[string]domain.BulkSMSRecipient
+
+	smsDeliveryReports map[string]domain.SMSDeliveryReport
+	inboundSMS         map[string]domain.InboundSMS
+	preferences        map[string]domain.ChannelPreference
+	smsConversations   map[string]domain.SMSConversation
+
+	smsRates   map[string]domain.SMSRate
+	smsBudgets map[string]domain.SMSBudget
+
+	emailSuppressions  map[string]domain.EmailSuppression
+	emailConversations map[string]domain.EmailConversation
+	inboundEmails      map[string]domain.InboundEmail
+
+	whatsAppSessions       map[string]domain.WhatsAppSession
+	inboundWhatsApp        map[string]domain.InboundWhatsApp
+	whatsAppConversations  map[string]domain.WhatsAppConversation
+	whatsAppNudges         map[string]domain.WhatsAppNudge
+	videoRooms             map[string]domain.VideoRoom
+	videoParticipants      map[string]domain.VideoParticipant
+	calendarEvents         map[string]domain.StoredCalendarEvent
+	availability           map[string]domain.ClinicianAvailability
+	availabilityExceptions map[string]domain.AvailabilityException
+	slotBookings           map[string]domain.SlotBooking
+}
+
+// NewRepository initializes an empty in-memory repository
+func NewRepository() *Repository {
+	return &Repository{
+		mu:        &sync.Mutex{},
+		templates: map[string]domain.NotificationTemplate{},
+		devices:   map[string]domain.Device{},
+		inbox:     map[string]domain.InboxNotification{},
+		schedules: map[string]domain.ScheduledNotification{},
+		groups:    map[string]domain.RecipientGroup{},
+
+		rateLimits: map[string]domain.RateLimitPolicy{},
+		counters:   map[string]domain.RateLimitCounter{},
+		digests:    map[string]domain.NotificationDigest{},
+
+		otps:        map[string]domain.OTP{},
+		otpAttempts: map[string]domain.OTPAttempts{},
+		otpApps:     map[string]domain.OTPApp{},
+
+		otpDeliveries:  map[string]domain.OTPDelivery{},
+		totpEnrolments: map[string]domain.TOTPEnrolment{},
+
+		emailVerificationLinks: map[string]domain.EmailVerificationLink{},
+
+		bulkSMSJobs:       map[string]domain.BulkSMSJob{},
+		bulkSMSRecipients: map[string]domain.BulkSMSRecipient{},
+
+		smsDeliveryReports: map[string]domain.SMSDeliveryReport{},
+		inboundSMS:         map[string]domain.InboundSMS{},
+		preferences:        map[string]domain.ChannelPreference{},
+		smsConversations:   map[string]domain.SMSConversation{},
+
+		smsRates:   map[string]domain.SMSRate{},
+		smsBudgets: map[string]domain.SMSBudget{},
+
+		emailSuppressions:  map[string]domain.EmailSuppression{},
+		emailConversations: map[string]domain.EmailConversation{},
+		inboundEmails:      map[string]domain.InboundEmail{},
+
+		whatsAppSessions:       map[string]domain.WhatsAppSession{},
+		inboundWhatsApp:        map[string]domain.InboundWhatsApp{},
+		whatsAppConversations:  map[string]domain.WhatsAppConversation{},
+		whatsAppNudges:         map[string]domain.WhatsAppNudge{},
+		videoRooms:             map[string]domain.VideoRoom{},
+		videoParticipants:      map[string]domain.VideoParticipant{},
+		calendarEvents:         map[string]domain.StoredCalendarEvent{},
+		availability:           map[string]domain.ClinicianAvailability{},
+		availabilityExceptions: map[string]domain.AvailabilityException{},
+		slotBookings:           map[string]domain.SlotBooking{},
+	}
+}
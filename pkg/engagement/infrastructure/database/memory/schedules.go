@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveScheduledNotification creates or replaces a scheduled notification
+// keyed on its ID
+func (r *Repository) SaveScheduledNotification(
+	ctx context.Context,
+	notification *domain.ScheduledNotification,
+) (*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.schedules[notification.ID] = *notification
+	saved := *notification
+	return &saved, nil
+}
+
+// GetScheduledNotification retrieves a scheduled notification by ID
+func (r *Repository) GetScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.schedules[id]
+	if !ok {
+		return nil, exceptions.ErrScheduledNotificationNotFound
+	}
+	return &notification, nil
+}
+
+// UpdateScheduledNotification atomically applies `update` to a scheduled
+// notification
+func (r *Repository) UpdateScheduledNotification(
+	ctx context.Context,
+	id string,
+	update func(notification *domain.ScheduledNotification) error,
+) (*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.schedules[id]
+	if !ok {
+		return nil, exceptions.ErrScheduledNotificationNotFound
+	}
+	if err := update(&notification); err != nil {
+		return nil, err
+	}
+	r.schedules[id] = notification
+	return &notification, nil
+}
+
+// ListScheduledNotifications returns the scheduled notifications, oldest first
+func (r *Repository) ListScheduledNotifications(
+	ctx context.Context,
+	status *domain.ScheduleStatus,
+) ([]*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := []*domain.ScheduledNotification{}
+	for _, notification := range r.schedules {
+		if status != nil && notification.Status != *status {
+			continue
+		}
+		n := notification
+		notifications = append(notifications, &n)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+	return notifications, nil
+}
+
+// ListDueScheduledNotifications returns the active scheduled notifications
+// whose next run is not after `now`, earliest first
+func (r *Repository) ListDueScheduledNotifications(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := []*domain.ScheduledNotification{}
+	for _, notification := range r.schedules {
+		if notification.Status != domain.ScheduleStatusActive {
+			continue
+		}
+		if notification.NextRunAt == nil || notification.NextRunAt.After(now) {
+			continue
+		}
+		n := notification
+		notifications = append(notifications, &n)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].NextRunAt.Before(*notifications[j].NextRunAt)
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+// ClaimScheduledNotification leases an active scheduled notification
+func (r *Repository) ClaimScheduledNotification(
+	ctx context.Context,
+	id string,
+	now time.Time,
+	leaseUntil time.Time,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.schedules[id]
+	if !ok {
+		return false, exceptions.ErrScheduledNotificationNotFound
+	}
+	if notification.Status != domain.ScheduleStatusActive {
+		return false, nil
+	}
+	if notification.LeaseExpiresAt != nil && notification.LeaseExpiresAt.After(now) {
+		return false, nil
+	}
+	notification.LeaseExpiresAt = &leaseUntil
+	r.schedules[id] = notification
+	return true, nil
+}
+
+// SaveRecipientGroup creates or replaces a group keyed on its name
+func (r *Repository) SaveRecipientGroup(
+	ctx context.Context,
+	group *domain.RecipientGroup,
+) (*domain.RecipientGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups[group.Name] = *group
+	saved := *group
+	return &saved, nil
+}
+
+// GetRecipientGroup retrieves a group by name
+func (r *Repository) GetRecipientGroup(ctx context.Context, name string) (*domain.RecipientGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, ok := r.groups[name]
+	if !ok {
+		return nil, exceptions.ErrRecipientGroupNotFound
+	}
+	return &group, nil
+}
+
+// ListRecipientGroups returns the groups ordered by name
+func (r *Repository) ListRecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := []*domain.RecipientGroup{}
+	for _, group := range r.groups {
+		g := group
+		groups = append(groups, &g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// DeleteRecipientGroup removes a group
+func (r *Repository) DeleteRecipientGroup(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groups[name]; !ok {
+		return exceptions.ErrRecipientGroupNotFound
+	}
+	delete(r.groups, name)
+	return nil
+}
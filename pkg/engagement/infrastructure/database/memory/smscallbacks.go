@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveSMSDeliveryReport replaces the delivery report of a message
+func (r *Repository) SaveSMSDeliveryReport(ctx context.Context, report *domain.SMSDeliveryReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.smsDeliveryReports[report.MessageID] = *report
+	return nil
+}
+
+// GetSMSDeliveryReport retrieves the delivery report of a message
+func (r *Repository) GetSMSDeliveryReport(ctx context.Context, messageID string) (*domain.SMSDeliveryReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, ok := r.smsDeliveryReports[messageID]
+	if !ok {
+		return nil, exceptions.ErrSMSDeliveryReportNotFound
+	}
+	return &report, nil
+}
+
+// SaveInboundSMS saves a received message keyed on its ID
+func (r *Repository) SaveInboundSMS(ctx context.Context, sms *domain.InboundSMS) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inboundSMS[sms.ID] = *sms
+	return nil
+}
+
+// SaveChannelPreference creates or replaces a preference
+func (r *Repository) SaveChannelPreference(ctx context.Context, preference *domain.ChannelPreference) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.preferences[channelPreferenceKey(preference.Channel, preference.Address)] = *preference
+	return nil
+}
+
+// GetChannelPreference retrieves the preference of an address for a channel
+func (r *Repository) GetChannelPreference(
+	ctx context.Context,
+	channel feedlib.Channel,
+	address string,
+) (*domain.ChannelPreference, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preference, ok := r.preferences[channelPreferenceKey(channel, address)]
+	if !ok {
+		return nil, exceptions.ErrChannelPreferenceNotFound
+	}
+	return &preference, nil
+}
+
+// ListChannelPreferences returns the preferences of the addresses for a
+// channel
+func (r *Repository) ListChannelPreferences(
+	ctx context.Context,
+	channel feedlib.Channel,
+	addresses []string,
+) ([]*domain.ChannelPreference, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preferences := []*domain.ChannelPreference{}
+	seen := map[string]bool{}
+	for _, address := range addresses {
+		key := channelPreferenceKey(channel, address)
+		preference, ok := r.preferences[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		preferences = append(preferences, &preference)
+	}
+	return preferences, nil
+}
+
+// SaveSMSConversation saves a conversation keyed on its ID
+func (r *Repository) SaveSMSConversation(ctx context.Context, conversation *domain.SMSConversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.smsConversations[conversation.ID] = *conversation
+	return nil
+}
+
+// GetActiveSMSConversation returns the latest unexpired conversation of a
+// phone number
+func (r *Repository) GetActiveSMSConversation(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.SMSConversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *domain.SMSConversation
+	for _, conversation := range r.smsConversations {
+		if conversation.Phone != phone || !conversation.ExpiresAt.After(now) {
+			continue
+		}
+		if latest == nil || conversation.CreatedAt.After(latest.CreatedAt) {
+			c := conversation
+			latest = &c
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrSMSConversationNotFound
+	}
+	return latest, nil
+}
+
+func channelPreferenceKey(channel feedlib.Channel, address string) string {
+	return channel.String() + ":" + address
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveSMSRate creates or replaces a rate keyed on its prefix
+func (r *Repository) SaveSMSRate(ctx context.Context, rate *domain.SMSRate) (*domain.SMSRate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.smsRates[rate.Prefix] = *rate
+	saved := *rate
+	return &saved, nil
+}
+
+// ListSMSRates returns the rates ordered by prefix
+func (r *Repository) ListSMSRates(ctx context.Context) ([]*domain.SMSRate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rates := []*domain.SMSRate{}
+	for _, rate := range r.smsRates {
+		rt := rate
+		rates = append(rates, &rt)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Prefix < rates[j].Prefix
+	})
+	return rates, nil
+}
+
+// DeleteSMSRate removes the rate of a prefix
+func (r *Repository) DeleteSMSRate(ctx context.Context, prefix string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.smsRates[prefix]; !ok {
+		return exceptions.ErrSMSRateNotFound
+	}
+	delete(r.smsRates, prefix)
+	return nil
+}
+
+// SaveSMSBudget creates or replaces the budget of a flavour
+func (r *Repository) SaveSMSBudget(ctx context.Context, budget *domain.SMSBudget) (*domain.SMSBudget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.smsBudgets[budget.Flavour.String()] = *budget
+	saved := *budget
+	return &saved, nil
+}
+
+// GetSMSBudget retrieves the budget of a flavour
+func (r *Repository) GetSMSBudget(ctx context.Context, flavour feedlib.Flavour) (*domain.SMSBudget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	budget, ok := r.smsBudgets[flavour.String()]
+	if !ok {
+		return nil, exceptions.ErrSMSBudgetNotFound
+	}
+	return &budget, nil
+}
+
+// ListSMSBudgets returns the budgets ordered by flavour
+func (r *Repository) ListSMSBudgets(ctx context.Context) ([]*domain.SMSBudget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	budgets := []*domain.SMSBudget{}
+	for _, budget := range r.smsBudgets {
+		b := budget
+		budgets = append(budgets, &b)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Flavour < budgets[j].Flavour
+	})
+	return budgets, nil
+}
+
+// DeleteSMSBudget removes the budget of a flavour
+func (r *Repository) DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.smsBudgets[flavour.String()]; !ok {
+		return exceptions.ErrSMSBudgetNotFound
+	}
+	delete(r.smsBudgets, flavour.String())
+	return nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SaveNotificationTemplate creates or replaces a template keyed on its name
+func (r *Repository) SaveNotificationTemplate(
+	ctx context.Context,
+	template *domain.NotificationTemplate,
+) (*domain.NotificationTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.templates[template.Name] = *template
+	saved := r.templates[template.Name]
+	return &saved, nil
+}
+
+// GetNotificationTemplate retrieves a template by name
+func (r *Repository) GetNotificationTemplate(
+	ctx context.Context,
+	name string,
+) (*domain.NotificationTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	template, ok := r.templates[name]
+	if !ok {
+		return nil, exceptions.ErrTemplateNotFound
+	}
+	return &template, nil
+}
+
+// ListNotificationTemplates returns the templates ordered by name
+func (r *Repository) ListNotificationTemplates(
+	ctx context.Context,
+	channel *feedlib.Channel,
+) ([]*domain.NotificationTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	templates := []*domain.NotificationTemplate{}
+	for _, template := range r.templates {
+		if channel != nil && template.Channel != *channel {
+			continue
+		}
+		t := template
+		templates = append(templates, &t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// DeleteNotificationTemplate removes a template
+func (r *Repository) DeleteNotificationTemplate(
+	ctx context.Context,
+	name string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name]; !ok {
+		return exceptions.ErrTemplateNotFound
+	}
+	delete(r.templates, name)
+	return nil
+}
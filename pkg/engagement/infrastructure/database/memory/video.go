@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveVideoRoom creates or replaces a room keyed on its ID
+func (r *Repository) SaveVideoRoom(ctx context.Context, room *domain.VideoRoom) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.videoRooms[room.ID] = *room
+	return nil
+}
+
+// GetVideoRoom retrieves a room by its ID
+func (r *Repository) GetVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room, ok := r.videoRooms[id]
+	if !ok {
+		return nil, exceptions.ErrVideoRoomNotFound
+	}
+	return &room, nil
+}
+
+// ListVideoRooms returns the rooms with a status ordered by their scheduled
+// start
+func (r *Repository) ListVideoRooms(ctx context.Context, status domain.VideoRoomStatus) ([]*domain.VideoRoom, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rooms := []*domain.VideoRoom{}
+	for _, room := range r.videoRooms {
+		if room.Status != status {
+			continue
+		}
+		rm := room
+		rooms = append(rooms, &rm)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ScheduledStart.Before(rooms[j].ScheduledStart)
+	})
+	return rooms, nil
+}
+
+// SaveVideoParticipant creates or replaces a participant's connection keyed
+// on its ID
+func (r *Repository) SaveVideoParticipant(ctx context.Context, participant *domain.VideoParticipant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.videoParticipants[participant.ID] = *participant
+	return nil
+}
+
+// GetVideoParticipant retrieves a participant's connection by its ID
+func (r *Repository) GetVideoParticipant(ctx context.Context, id string) (*domain.VideoParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	participant, ok := r.videoParticipants[id]
+	if !ok {
+		return nil, exceptions.ErrVideoParticipantNotFound
+	}
+	return &participant, nil
+}
+
+// ListVideoParticipants returns the connections to a room in the order that
+// they joined. Connections whose join was not reported are listed last.
+func (r *Repository) ListVideoParticipants(ctx context.Context, roomID string) ([]*domain.VideoParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	participants := []*domain.VideoParticipant{}
+	for _, participant := range r.videoParticipants {
+		if participant.RoomID != roomID {
+			continue
+		}
+		p := participant
+		participants = append(participants, &p)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return joinedBefore(participants[i], participants[j])
+	})
+	return participants, nil
+}
+
+func joinedBefore(a, b *domain.VideoParticipant) bool {
+	if a.JoinedAt == nil || b.JoinedAt == nil {
+		return a.JoinedAt != nil
+	}
+	return a.JoinedAt.Before(*b.JoinedAt)
+}
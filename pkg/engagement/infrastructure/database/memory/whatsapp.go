@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+// SaveWhatsAppSession creates or replaces the session of a phone number
+func (r *Repository) SaveWhatsAppSession(ctx context.Context, session *domain.WhatsAppSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.whatsAppSessions[session.Phone] = *session
+	return nil
+}
+
+// GetWhatsAppSession retrieves the session of a phone number
+func (r *Repository) GetWhatsAppSession(ctx context.Context, phone string) (*domain.WhatsAppSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.whatsAppSessions[phone]
+	if !ok {
+		return nil, exceptions.ErrWhatsAppSessionNotFound
+	}
+	return &session, nil
+}
+
+// SaveInboundWhatsApp saves a received message keyed on its ID
+func (r *Repository) SaveInboundWhatsApp(ctx context.Context, message *domain.InboundWhatsApp) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inboundWhatsApp[message.ID] = *message
+	return nil
+}
+
+// GetInboundWhatsApp retrieves a received message by its ID
+func (r *Repository) GetInboundWhatsApp(ctx context.Context, id string) (*domain.InboundWhatsApp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message, ok := r.inboundWhatsApp[id]
+	if !ok {
+		return nil, exceptions.ErrInboundWhatsAppNotFound
+	}
+	return &message, nil
+}
+
+// ListWhatsAppSupportQueue returns the unresolved support messages, oldest
+// first
+func (r *Repository) ListWhatsAppSupportQueue(ctx context.Context, limit int) ([]*domain.InboundWhatsApp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := []*domain.InboundWhatsApp{}
+	for _, message := range r.inboundWhatsApp {
+		if !message.Support || message.Resolved {
+			continue
+		}
+		m := message
+		messages = append(messages, &m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ReceivedAt.Before(messages[j].ReceivedAt)
+	})
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+// SaveWhatsAppConversation saves a conversation keyed on its ID
+func (r *Repository) SaveWhatsAppConversation(ctx context.Context, conversation *domain.WhatsAppConversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.whatsAppConversations[conversation.ID] = *conversation
+	return nil
+}
+
+// GetActiveWhatsAppConversation returns the latest unexpired conversation of
+// a phone number
+func (r *Repository) GetActiveWhatsAppConversation(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.WhatsAppConversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *domain.WhatsAppConversation
+	for _, conversation := range r.whatsAppConversations {
+		if conversation.Phone != phone || !conversation.ExpiresAt.After(now) {
+			continue
+		}
+		if latest == nil || conversation.CreatedAt.After(latest.CreatedAt) {
+			c := conversation
+			latest = &c
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrWhatsAppConversationNotFound
+	}
+	return latest, nil
+}
+
+// SaveWhatsAppNudge saves a sent nudge keyed on its ID
+func (r *Repository) SaveWhatsAppNudge(ctx context.Context, nudge *domain.WhatsAppNudge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.whatsAppNudges[nudge.ID] = *nudge
+	return nil
+}
+
+// GetActiveWhatsAppNudge returns the latest unanswered and unexpired nudge of
+// a phone number
+func (r *Repository) GetActiveWhatsAppNudge(
+	ctx context.Context,
+	phone string,
+	now time.Time,
+) (*domain.WhatsAppNudge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *domain.WhatsAppNudge
+	for _, nudge := range r.whatsAppNudges {
+		if nudge.Phone != phone || nudge.AnsweredWith != "" || !nudge.ExpiresAt.After(now) {
+			continue
+		}
+		if latest == nil || nudge.CreatedAt.After(latest.CreatedAt) {
+			n := nudge
+			latest = &n
+		}
+	}
+	if latest == nil {
+		return nil, exceptions.ErrWhatsAppNudgeNotFound
+	}
+	return latest, nil
+}
@@ -0,0 +1,40 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+// FakeServiceCalendar simulates the behavior of our calendar implementation
+type FakeServiceCalendar struct {
+	InsertEventFn func(ctx context.Context, event *gcal.Event) (*gcal.Event, error)
+	UpdateEventFn func(ctx context.Context, event *gcal.Event) (*gcal.Event, error)
+	GetEventFn    func(ctx context.Context, id string) (*gcal.Event, error)
+	ListEventsFn  func(ctx context.Context, from, to time.Time, includeCancelled bool) ([]*gcal.Event, error)
+}
+
+// InsertEvent is a mock of the InsertEvent method
+func (f *FakeServiceCalendar) InsertEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	return f.InsertEventFn(ctx, event)
+}
+
+// UpdateEvent is a mock of the UpdateEvent method
+func (f *FakeServiceCalendar) UpdateEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	return f.UpdateEventFn(ctx, event)
+}
+
+// GetEvent is a mock of the GetEvent method
+func (f *FakeServiceCalendar) GetEvent(ctx context.Context, id string) (*gcal.Event, error) {
+	return f.GetEventFn(ctx, id)
+}
+
+// ListEvents is a mock of the ListEvents method
+func (f *FakeServiceCalendar) ListEvents(
+	ctx context.Context,
+	from, to time.Time,
+	includeCancelled bool,
+) ([]*gcal.Event, error) {
+	return f.ListEventsFn(ctx, from, to, includeCancelled)
+}
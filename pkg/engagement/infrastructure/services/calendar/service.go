@@ -0,0 +1,142 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"golang.org/x/oauth2/google"
+	gcal "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// The Google calendar that appointments are kept in, and the user that the
+// service account acts as. Acting as a user of the calendar's Workspace
+// domain needs domain-wide delegation, and is required for events to have
+// attendees.
+const (
+	GoogleCalendarIDEnvVarName      = "GOOGLE_CALENDAR_ID"
+	GoogleCalendarSubjectEnvVarName = "GOOGLE_CALENDAR_SUBJECT"
+
+	// googleSendUpdatesNone stops Google from emailing attendees since
+	// they are notified by the service
+	googleSendUpdatesNone = "none"
+)
+
+// ServiceCalendar defines the interaction with a calendar that holds
+// appointments as Google Calendar API events.
+//
+// Cancelled events are kept with the `cancelled` status.
+type ServiceCalendar interface {
+	// InsertEvent creates an event and returns it with its ID
+	InsertEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error)
+
+	// UpdateEvent replaces the event with the same ID
+	UpdateEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error)
+
+	// GetEvent returns an event, including a cancelled event
+	GetEvent(ctx context.Context, id string) (*gcal.Event, error)
+
+	// ListEvents returns the events that overlap an interval, ordered by
+	// their start
+	ListEvents(ctx context.Context, from, to time.Time, includeCancelled bool) ([]*gcal.Event, error)
+}
+
+// ServiceCalendarImpl keeps events in a Google calendar
+type ServiceCalendarImpl struct {
+	calendarID string
+	events     *gcal.EventsService
+}
+
+// NewService initializes a service that keeps events in a Google calendar
+// with the application default credentials
+func NewService(ctx context.Context) (*ServiceCalendarImpl, error) {
+	params := google.CredentialsParams{
+		Scopes: []string{gcal.CalendarEventsScope},
+	}
+	if subject, err := serverutils.GetEnvVar(GoogleCalendarSubjectEnvVarName); err == nil {
+		params.Subject = subject
+	}
+	credentials, err := google.FindDefaultCredentialsWithParams(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find Google credentials: %w", err)
+	}
+	srv, err := gcal.NewService(ctx, option.WithCredentials(credentials))
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Google Calendar client: %w", err)
+	}
+
+	s := &ServiceCalendarImpl{
+		calendarID: serverutils.MustGetEnvVar(GoogleCalendarIDEnvVarName),
+		events:     srv.Events,
+	}
+	s.checkPreconditions()
+	return s, nil
+}
+
+func (s ServiceCalendarImpl) checkPreconditions() {
+	if s.calendarID == "" {
+		log.Panicf("Google calendar service has no calendar ID")
+	}
+	if s.events == nil {
+		log.Panicf("nil events client in Google calendar service")
+	}
+}
+
+// InsertEvent creates an event in the calendar
+func (s ServiceCalendarImpl) InsertEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	created, err := s.events.Insert(s.calendarID, event).
+		SendUpdates(googleSendUpdatesNone).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Google calendar event: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateEvent replaces an event in the calendar
+func (s ServiceCalendarImpl) UpdateEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	updated, err := s.events.Update(s.calendarID, event.Id, event).
+		SendUpdates(googleSendUpdatesNone).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update Google calendar event: %w", err)
+	}
+	return updated, nil
+}
+
+// GetEvent fetches an event from the calendar
+func (s ServiceCalendarImpl) GetEvent(ctx context.Context, id string) (*gcal.Event, error) {
+	event, err := s.events.Get(s.calendarID, id).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get Google calendar event: %w", err)
+	}
+	return event, nil
+}
+
+// ListEvents reads every page of the events that overlap an interval
+func (s ServiceCalendarImpl) ListEvents(
+	ctx context.Context,
+	from, to time.Time,
+	includeCancelled bool,
+) ([]*gcal.Event, error) {
+	events := []*gcal.Event{}
+	err := s.events.List(s.calendarID).
+		TimeMin(from.Format(time.RFC3339)).
+		TimeMax(to.Format(time.RFC3339)).
+		ShowDeleted(includeCancelled).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(ctx, func(page *gcal.Events) error {
+			events = append(events, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list Google calendar events: %w", err)
+	}
+	return events, nil
+}
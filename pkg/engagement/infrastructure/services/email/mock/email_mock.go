@@ -0,0 +1,17 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+)
+
+// FakeServiceEmail simulates the behavior of our email provider implementation
+type FakeServiceEmail struct {
+	SendFn func(ctx context.Context, message *email.Message) (string, error)
+}
+
+// Send is a mock of the Send method
+func (f *FakeServiceEmail) Send(ctx context.Context, message *email.Message) (string, error) {
+	return f.SendFn(ctx, message)
+}
@@ -0,0 +1,204 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+)
+
+// Mailgun credentials and the address that emails are sent from. These are
+// the same variables that the library's mail service uses.
+const (
+	MailgunAPIKeyEnvVarName     = "MAILGUN_API_KEY"
+	MailgunAPIBaseURLEnvVarName = "MAILGUN_API_BASE_URL"
+	MailgunDomainEnvVarName     = "MAILGUN_DOMAIN"
+	MailgunFromEnvVarName       = "MAILGUN_FROM"
+
+	mailgunEUAPIBaseURL         = "https://api.eu.mailgun.net/v3"
+	mailgunUSAPIBaseURL         = "https://api.mailgun.net/v3"
+	mailgunHTTPTimeoutSeconds   = 30
+	mailgunSandboxDomainKeyword = "sandbox"
+)
+
+// Message is an email to one or more recipients. Text is required; HTML is
+// sent as an alternative to it when it is set.
+type Message struct {
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+
+	// Headers are added to the email's headers e.g `List-Unsubscribe`
+	Headers map[string]string
+}
+
+// Attachment is a file that is attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// mediaType returns the content type of an attachment, falling back to a
+// generic binary type
+func (a Attachment) mediaType() string {
+	if a.ContentType == "" {
+		return "application/octet-stream"
+	}
+	return a.ContentType
+}
+
+// ServiceEmail defines the interaction with an email provider
+type ServiceEmail interface {
+	// Send sends a message and returns the provider's message ID, without
+	// angle brackets
+	Send(ctx context.Context, message *Message) (string, error)
+}
+
+// ServiceEmailImpl sends emails through Mailgun
+type ServiceEmailImpl struct {
+	BaseURL    string
+	domain     string
+	apiKey     string
+	from       string
+	httpClient *http.Client
+}
+
+// NewService initializes a service to send emails through Mailgun. Sandbox
+// domains are sent through the US API and other domains through the EU API
+// unless MAILGUN_API_BASE_URL is set.
+func NewService() *ServiceEmailImpl {
+	domain := serverutils.MustGetEnvVar(MailgunDomainEnvVarName)
+	baseURL, err := serverutils.GetEnvVar(MailgunAPIBaseURLEnvVarName)
+	if err != nil || baseURL == "" {
+		baseURL = mailgunEUAPIBaseURL
+		if strings.Contains(domain, mailgunSandboxDomainKeyword) {
+			baseURL = mailgunUSAPIBaseURL
+		}
+	}
+	return &ServiceEmailImpl{
+		BaseURL: baseURL,
+		domain:  domain,
+		apiKey:  serverutils.MustGetEnvVar(MailgunAPIKeyEnvVarName),
+		from:    serverutils.MustGetEnvVar(MailgunFromEnvVarName),
+		httpClient: &http.Client{
+			Timeout: time.Second * mailgunHTTPTimeoutSeconds,
+		},
+	}
+}
+
+// headerNames returns the names of a message's extra headers in order
+func headerNames(headers map[string]string) []string {
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mailgunResponse is the body of Mailgun's send message response
+type mailgunResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// Send sends a message from the Mailgun domain. Messages are posted as
+// multipart form data so that they can carry attachments.
+func (s ServiceEmailImpl) Send(ctx context.Context, message *Message) (string, error) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	fields := [][2]string{
+		{"from", s.from},
+		{"subject", message.Subject},
+		{"text", message.Text},
+	}
+	for _, to := range message.To {
+		fields = append(fields, [2]string{"to", to})
+	}
+	for _, cc := range message.Cc {
+		fields = append(fields, [2]string{"cc", cc})
+	}
+	for _, bcc := range message.Bcc {
+		fields = append(fields, [2]string{"bcc", bcc})
+	}
+	if message.HTML != "" {
+		fields = append(fields, [2]string{"html", message.HTML})
+	}
+	for _, name := range headerNames(message.Headers) {
+		fields = append(fields, [2]string{"h:" + name, message.Headers[name]})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return "", err
+		}
+	}
+	for _, attachment := range message.Attachments {
+		w, err := form.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{
+				"name":     "attachment",
+				"filename": attachment.Filename,
+			})},
+			"Content-Type": {attachment.mediaType()},
+		})
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write(attachment.Data); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/messages", strings.TrimRight(s.BaseURL, "/"), s.domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth("api", s.apiKey)
+
+	return s.do(req)
+}
+
+// do sends a request to Mailgun and returns the ID of the queued message
+func (s ServiceEmailImpl) do(req *http.Request) (string, error) {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("mailgun API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read Mailgun response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("mailgun API error: %s", strings.TrimSpace(string(body)))
+	}
+
+	sent := mailgunResponse{}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return "", fmt.Errorf("unable to unmarshal Mailgun response: %w", err)
+	}
+	if sent.ID == "" {
+		return "", fmt.Errorf("the email was not sent: %s", sent.Message)
+	}
+	return strings.Trim(sent.ID, "<>"), nil
+}
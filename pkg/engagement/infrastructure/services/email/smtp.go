@@ -0,0 +1,230 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// SMTP server that emails are sent to, e.g a local catcher like MailHog or
+// Mailpit. The username and password are optional.
+const (
+	SMTPHostEnvVarName     = "SMTP_HOST"
+	SMTPPortEnvVarName     = "SMTP_PORT"
+	SMTPUsernameEnvVarName = "SMTP_USERNAME"
+	SMTPPasswordEnvVarName = "SMTP_PASSWORD"
+	SMTPFromEnvVarName     = "SMTP_FROM"
+
+	defaultSMTPHost = "localhost"
+	defaultSMTPPort = "1025"
+	defaultSMTPFrom = "Be.Well <no-reply@localhost>"
+)
+
+// SMTPServiceEmail sends emails to an SMTP server. It is used for tests and
+// local development, where emails are sent to a local catcher instead of
+// Mailgun.
+type SMTPServiceEmail struct {
+	Addr     string
+	From     string
+	username string
+	password string
+}
+
+// NewSMTPService initializes a service to send emails to the SMTP server in
+// the environment. It defaults to a catcher on localhost:1025.
+func NewSMTPService() *SMTPServiceEmail {
+	return &SMTPServiceEmail{
+		Addr: net.JoinHostPort(
+			envOrDefault(SMTPHostEnvVarName, defaultSMTPHost),
+			envOrDefault(SMTPPortEnvVarName, defaultSMTPPort),
+		),
+		From:     envOrDefault(SMTPFromEnvVarName, defaultSMTPFrom),
+		username: os.Getenv(SMTPUsernameEnvVarName),
+		password: os.Getenv(SMTPPasswordEnvVarName),
+	}
+}
+
+// Send sends a message to the SMTP server. The returned ID is the message's
+// Message-ID header.
+func (s SMTPServiceEmail) Send(ctx context.Context, message *Message) (string, error) {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid SMTP from address %s: %w", s.From, err)
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+	messageID := fmt.Sprintf("%s@%s", ksuid.New().String(), domain)
+
+	body, err := buildMIMEMessage(from.String(), messageID, message)
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		host, _, _ := net.SplitHostPort(s.Addr)
+		auth = smtp.PlainAuth("", s.username, s.password, host)
+	}
+	// Bcc recipients are only given to the server, they are not in a header
+	recipients := append(append(append([]string{}, message.To...), message.Cc...), message.Bcc...)
+	if err := smtp.SendMail(s.Addr, auth, from.Address, recipients, body); err != nil {
+		return "", fmt.Errorf("unable to send email over SMTP: %w", err)
+	}
+	return messageID, nil
+}
+
+// buildMIMEMessage writes the headers and body of a message. Messages with
+// attachments are sent as multipart/mixed with the body first.
+func buildMIMEMessage(from string, messageID string, message *Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(message.To, ", "),
+	}
+	if len(message.Cc) > 0 {
+		headers = append(headers, "Cc: "+strings.Join(message.Cc, ", "))
+	}
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"Message-ID: <"+messageID+">",
+		"MIME-Version: 1.0",
+	)
+	for _, name := range headerNames(message.Headers) {
+		headers = append(headers, name+": "+message.Headers[name])
+	}
+
+	bodyHeader, body, err := buildMIMEBody(message)
+	if err != nil {
+		return nil, err
+	}
+	if len(message.Attachments) == 0 {
+		for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			if value := bodyHeader.Get(key); value != "" {
+				headers = append(headers, key+": "+value)
+			}
+		}
+		buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(buf)
+	headers = append(headers, "Content-Type: multipart/mixed; boundary="+parts.Boundary())
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+	w, err := parts.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	for _, attachment := range message.Attachments {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {attachment.mediaType()},
+			"Content-Disposition": {mime.FormatMediaType("attachment", map[string]string{
+				"filename": attachment.Filename,
+			})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(w, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildMIMEBody returns the headers and content of a message's text. Messages
+// with HTML are sent as multipart/alternative with the text first.
+func buildMIMEBody(message *Message) (textproto.MIMEHeader, []byte, error) {
+	buf := &bytes.Buffer{}
+	if message.HTML == "" {
+		if err := writeQuotedPrintable(buf, message.Text); err != nil {
+			return nil, nil, err
+		}
+		return textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}, buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(buf)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, nil, err
+	}
+	return textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + parts.Boundary()},
+	}, buf.Bytes(), nil
+}
+
+// writeBase64Lines writes base64 encoded data in the 76 character lines that
+// MIME requires
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := 76
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,42 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/firebasetools"
+)
+
+// FakeServiceFCM simulates the behavior of our FCM implementation
+type FakeServiceFCM struct {
+	SendNotificationFn func(
+		ctx context.Context,
+		registrationTokens []string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (*dto.FCMSendResult, error)
+}
+
+// SendNotification is a mock of the SendNotification method
+func (f *FakeServiceFCM) SendNotification(
+	ctx context.Context,
+	registrationTokens []string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (*dto.FCMSendResult, error) {
+	return f.SendNotificationFn(
+		ctx,
+		registrationTokens,
+		data,
+		notification,
+		android,
+		ios,
+		web,
+	)
+}
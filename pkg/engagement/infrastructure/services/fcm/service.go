@@ -0,0 +1,168 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/firebasetools"
+)
+
+// MaxMulticastTokens is the most registration tokens that FCM accepts in one
+// multicast message
+const MaxMulticastTokens = 500
+
+// ServiceFCM defines all interactions with Firebase Cloud Messaging
+type ServiceFCM interface {
+	// SendNotification sends a multicast message and reports the outcome
+	// for every registration token
+	SendNotification(
+		ctx context.Context,
+		registrationTokens []string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (*dto.FCMSendResult, error)
+}
+
+// ServiceFCMImpl sends Firebase Cloud Messaging notifications
+type ServiceFCMImpl struct {
+	fcmClient *messaging.Client
+}
+
+// NewService initializes a service to interact with Firebase Cloud Messaging
+func NewService(ctx context.Context) (*ServiceFCMImpl, error) {
+	fc := &firebasetools.FirebaseClient{}
+	app, err := fc.InitFirebase()
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Firebase app: %w", err)
+	}
+
+	fcmClient, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Messaging client: %w", err)
+	}
+
+	srv := &ServiceFCMImpl{
+		fcmClient: fcmClient,
+	}
+	srv.checkPreconditions()
+	return srv, nil
+}
+
+func (s ServiceFCMImpl) checkPreconditions() {
+	if s.fcmClient == nil {
+		log.Panicf("nil messaging client in FCM service")
+	}
+}
+
+// SendNotification sends a message to the specified registration tokens.
+//
+// Unlike a plain multicast, the per token outcome is returned so that callers
+// can prune tokens that FCM reports as no longer registered. The tokens are
+// sent to in batches of MaxMulticastTokens; the tokens of a batch that can't
+// be sent are reported as failures, and an error is only returned when no
+// batch could be sent.
+func (s ServiceFCMImpl) SendNotification(
+	ctx context.Context,
+	registrationTokens []string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (*dto.FCMSendResult, error) {
+	s.checkPreconditions()
+
+	if len(registrationTokens) == 0 {
+		return nil, fmt.Errorf("can't send FCM notifications to empty registration tokens")
+	}
+
+	message := &messaging.MulticastMessage{
+		Data: data,
+	}
+	if notification != nil {
+		message.Notification = &messaging.Notification{
+			Title: notification.Title,
+			Body:  notification.Body,
+		}
+		if notification.ImageURL != nil {
+			message.Notification.ImageURL = *notification.ImageURL
+		}
+	}
+	if android != nil {
+		message.Android = &messaging.AndroidConfig{
+			Priority: android.Priority,
+			Data:     converterandformatter.ConvertInterfaceMap(android.Data),
+		}
+		if android.CollapseKey != nil {
+			message.Android.CollapseKey = *android.CollapseKey
+		}
+		if android.RestrictedPackageName != nil {
+			message.Android.RestrictedPackageName = *android.RestrictedPackageName
+		}
+	}
+	if web != nil {
+		message.Webpush = &messaging.WebpushConfig{
+			Headers: converterandformatter.ConvertInterfaceMap(web.Headers),
+			Data:    converterandformatter.ConvertInterfaceMap(web.Data),
+		}
+	}
+	if ios != nil {
+		message.APNS = &messaging.APNSConfig{
+			Headers: converterandformatter.ConvertInterfaceMap(ios.Headers),
+		}
+	}
+
+	result := &dto.FCMSendResult{
+		MessageIDs: map[string]string{},
+		Failures:   map[string]string{},
+	}
+	var sendErr error
+	sent := false
+	for start := 0; start < len(registrationTokens); start += MaxMulticastTokens {
+		end := start + MaxMulticastTokens
+		if end > len(registrationTokens) {
+			end = len(registrationTokens)
+		}
+		batch := registrationTokens[start:end]
+		batchMessage := *message
+		batchMessage.Tokens = batch
+
+		batchResp, err := s.fcmClient.SendMulticast(ctx, &batchMessage)
+		if err != nil {
+			sendErr = err
+			result.FailureCount += len(batch)
+			for _, token := range batch {
+				result.Failures[token] = err.Error()
+			}
+			continue
+		}
+		sent = true
+
+		result.SuccessCount += batchResp.SuccessCount
+		result.FailureCount += batchResp.FailureCount
+		// The order of responses corresponds to the order of the batch's
+		// registration tokens
+		for idx, resp := range batchResp.Responses {
+			token := batch[idx]
+			if resp.Success {
+				result.MessageIDs[token] = resp.MessageID
+				continue
+			}
+			result.Failures[token] = resp.Error.Error()
+			if messaging.IsRegistrationTokenNotRegistered(resp.Error) {
+				result.UnregisteredTokens = append(result.UnregisteredTokens, token)
+			}
+		}
+	}
+	if !sent {
+		return nil, fmt.Errorf("unable to send FCM messages: %w", sendErr)
+	}
+	return result, nil
+}
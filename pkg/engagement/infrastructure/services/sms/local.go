@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/segmentio/ksuid"
+)
+
+// localCost is what the local service reports that a message cost
+const localCost = "KES 0.0000"
+
+// LocalServiceSMS logs messages instead of sending them. It is used for local
+// development, where there are no Africa's Talking credentials.
+type LocalServiceSMS struct{}
+
+// NewLocalService initializes a service that logs messages
+func NewLocalService() *LocalServiceSMS {
+	return &LocalServiceSMS{}
+}
+
+// SendToMany logs a message to phone numbers
+func (s LocalServiceSMS) SendToMany(
+	ctx context.Context,
+	message string,
+	to []string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	return s.Send(ctx, strings.Join(to, ","), message, from)
+}
+
+// Send logs a message and reports it as sent to every phone number
+func (s LocalServiceSMS) Send(
+	ctx context.Context,
+	to, message string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	if !from.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid sender ID", from)
+	}
+
+	recipients := []dto.Recipient{}
+	for _, number := range strings.Split(to, ",") {
+		number = strings.TrimSpace(number)
+		if number == "" {
+			continue
+		}
+		log.Printf("SMS from %s to %s: %s", from, number, message)
+		recipients = append(recipients, dto.Recipient{
+			Number:    number,
+			Cost:      localCost,
+			Status:    "Success",
+			MessageID: "local-" + ksuid.New().String(),
+		})
+	}
+	return &dto.SendMessageResponse{
+		SMSMessageData: &dto.SMS{Recipients: recipients},
+	}, nil
+}
@@ -0,0 +1,43 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+)
+
+// FakeServiceSMS simulates the behavior of our SMS provider implementation
+type FakeServiceSMS struct {
+	SendFn func(
+		ctx context.Context,
+		to, message string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+
+	SendToManyFn func(
+		ctx context.Context,
+		message string,
+		to []string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+}
+
+// Send is a mock of the Send method
+func (f *FakeServiceSMS) Send(
+	ctx context.Context,
+	to, message string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	return f.SendFn(ctx, to, message, from)
+}
+
+// SendToMany is a mock of the SendToMany method
+func (f *FakeServiceSMS) SendToMany(
+	ctx context.Context,
+	message string,
+	to []string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	return f.SendToManyFn(ctx, message, to, from)
+}
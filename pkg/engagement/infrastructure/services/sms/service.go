@@ -0,0 +1,181 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/serverutils"
+)
+
+// Africa's Talking credentials of each sender ID. These are the same
+// variables that the library's SMS service uses.
+const (
+	AITEnvironmentEnvVarName = "AIT_ENVIRONMENT"
+
+	SLADE360APIKeyEnvVarName   = "AIT_API_KEY"
+	SLADE360UsernameEnvVarName = "AIT_USERNAME"
+	SLADE360SenderIDEnvVarName = "AIT_SENDER_ID"
+
+	BeWellAPIKeyEnvVarName   = "AIT_BEWELL_API_KEY"
+	BeWellUsernameEnvVarName = "AIT_BEWELL_USERNAME"
+	BeWellSenderIDEnvVarName = "AIT_BEWELL_SENDER_ID"
+
+	aitSandboxEnvironment = "sandbox"
+	aitHTTPTimeoutSeconds = 30
+)
+
+// ServiceSMS defines the interaction with an SMS provider
+type ServiceSMS interface {
+	// Send sends a message to a phone number, or to comma separated phone
+	// numbers, from a sender ID
+	Send(
+		ctx context.Context,
+		to, message string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+
+	// SendToMany sends a message to phone numbers from a sender ID
+	SendToMany(
+		ctx context.Context,
+		message string,
+		to []string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+}
+
+// aitCredentials are the Africa's Talking account that a sender ID sends from
+type aitCredentials struct {
+	username string
+	apiKey   string
+	senderID string
+}
+
+// ServiceSMSImpl sends SMS through Africa's Talking
+type ServiceSMSImpl struct {
+	BaseURL     string
+	credentials map[enumutils.SenderID]aitCredentials
+	httpClient  *http.Client
+}
+
+// NewService initializes a service to send SMS through Africa's Talking
+func NewService() *ServiceSMSImpl {
+	return &ServiceSMSImpl{
+		BaseURL: aitBaseURL(serverutils.MustGetEnvVar(AITEnvironmentEnvVarName)),
+		credentials: map[enumutils.SenderID]aitCredentials{
+			enumutils.SenderIDSLADE360: {
+				username: serverutils.MustGetEnvVar(SLADE360UsernameEnvVarName),
+				apiKey:   serverutils.MustGetEnvVar(SLADE360APIKeyEnvVarName),
+				senderID: serverutils.MustGetEnvVar(SLADE360SenderIDEnvVarName),
+			},
+			enumutils.SenderIDBewell: {
+				username: serverutils.MustGetEnvVar(BeWellUsernameEnvVarName),
+				apiKey:   serverutils.MustGetEnvVar(BeWellAPIKeyEnvVarName),
+				senderID: serverutils.MustGetEnvVar(BeWellSenderIDEnvVarName),
+			},
+		},
+		httpClient: &http.Client{
+			Timeout: time.Second * aitHTTPTimeoutSeconds,
+		},
+	}
+}
+
+func aitBaseURL(environment string) string {
+	if environment == aitSandboxEnvironment {
+		return "https://api.sandbox.africastalking.com"
+	}
+	return "https://api.africastalking.com"
+}
+
+// aitResponse is the body of Africa's Talking's send message response
+type aitResponse struct {
+	SMSMessageData struct {
+		Message    string `json:"Message"`
+		Recipients []struct {
+			StatusCode int    `json:"statusCode"`
+			Number     string `json:"number"`
+			Status     string `json:"status"`
+			Cost       string `json:"cost"`
+			MessageID  string `json:"messageId"`
+		} `json:"Recipients"`
+	} `json:"SMSMessageData"`
+}
+
+// SendToMany sends a message to phone numbers in one request
+func (s ServiceSMSImpl) SendToMany(
+	ctx context.Context,
+	message string,
+	to []string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	return s.Send(ctx, strings.Join(to, ","), message, from)
+}
+
+// Send sends a message with the Africa's Talking account of a sender ID
+func (s ServiceSMSImpl) Send(
+	ctx context.Context,
+	to, message string,
+	from enumutils.SenderID,
+) (*dto.SendMessageResponse, error) {
+	credentials, ok := s.credentials[from]
+	if !ok {
+		return nil, fmt.Errorf("no Africa's Talking account for sender ID %s", from)
+	}
+
+	form := url.Values{}
+	form.Set("username", credentials.username)
+	form.Set("to", to)
+	form.Set("message", message)
+	form.Set("from", credentials.senderID)
+
+	endpoint := strings.TrimRight(s.BaseURL, "/") + "/version1/messaging"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apiKey", credentials.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("africa's talking API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Africa's Talking response: %w", err)
+	}
+	if resp.StatusCode > http.StatusCreated {
+		return nil, fmt.Errorf("africa's talking API error: %s", strings.TrimSpace(string(body)))
+	}
+
+	sent := aitResponse{}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal Africa's Talking response: %w", err)
+	}
+
+	recipients := []dto.Recipient{}
+	for _, recipient := range sent.SMSMessageData.Recipients {
+		recipients = append(recipients, dto.Recipient{
+			Number:    recipient.Number,
+			Cost:      recipient.Cost,
+			Status:    recipient.Status,
+			MessageID: recipient.MessageID,
+		})
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("the SMS was not sent: %s", sent.SMSMessageData.Message)
+	}
+	return &dto.SendMessageResponse{
+		SMSMessageData: &dto.SMS{Recipients: recipients},
+	}, nil
+}
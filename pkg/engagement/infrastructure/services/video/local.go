@@ -0,0 +1,87 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kevinburke/twilio-go/token"
+	"github.com/segmentio/ksuid"
+)
+
+// LocalServiceVideo keeps rooms in memory and signs tokens with a key that
+// Twilio does not know. It is used for local development, where there are no
+// Twilio credentials.
+type LocalServiceVideo struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+// NewLocalService initializes a service that keeps rooms in memory
+func NewLocalService() *LocalServiceVideo {
+	return &LocalServiceVideo{rooms: map[string]*Room{}}
+}
+
+// CreateRoom records an in progress room
+func (s *LocalServiceVideo) CreateRoom(
+	ctx context.Context,
+	uniqueName string,
+	maxParticipants int,
+	statusCallback string,
+) (*Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, room := range s.rooms {
+		if room.UniqueName == uniqueName && room.Status != twilioRoomCompleted {
+			return nil, fmt.Errorf("room %s exists", uniqueName)
+		}
+	}
+	room := &Room{
+		SID:        "RM" + ksuid.New().String(),
+		UniqueName: uniqueName,
+		Status:     "in-progress",
+	}
+	s.rooms[room.SID] = room
+	log.Printf("video room %s (%s) for up to %d participants", room.UniqueName, room.SID, maxParticipants)
+	copied := *room
+	return &copied, nil
+}
+
+// GetRoom returns an in progress room by its SID or unique name
+func (s *LocalServiceVideo) GetRoom(ctx context.Context, sidOrUniqueName string) (*Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, room := range s.rooms {
+		if room.SID == sidOrUniqueName ||
+			(room.UniqueName == sidOrUniqueName && room.Status != twilioRoomCompleted) {
+			copied := *room
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("room %s not found", sidOrUniqueName)
+}
+
+// CompleteRoom marks a room as completed
+func (s *LocalServiceVideo) CompleteRoom(ctx context.Context, sid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[sid]
+	if !ok {
+		return fmt.Errorf("room %s not found", sid)
+	}
+	room.Status = twilioRoomCompleted
+	log.Printf("video room %s (%s) completed", room.UniqueName, room.SID)
+	return nil
+}
+
+// AccessToken signs a token with a local key
+func (s *LocalServiceVideo) AccessToken(identity string, roomName string, ttl time.Duration) (string, error) {
+	accessToken := token.New("local", "local", "local", identity, ttl)
+	accessToken.AddGrant(token.NewVideoGrant(roomName))
+	return accessToken.JWT()
+}
@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/video"
+)
+
+// FakeServiceVideo simulates the behavior of our video provider
+// implementation
+type FakeServiceVideo struct {
+	CreateRoomFn func(
+		ctx context.Context,
+		uniqueName string,
+		maxParticipants int,
+		statusCallback string,
+	) (*video.Room, error)
+	GetRoomFn      func(ctx context.Context, sidOrUniqueName string) (*video.Room, error)
+	CompleteRoomFn func(ctx context.Context, sid string) error
+	AccessTokenFn  func(identity string, roomName string, ttl time.Duration) (string, error)
+}
+
+// CreateRoom is a mock of the CreateRoom method
+func (f *FakeServiceVideo) CreateRoom(
+	ctx context.Context,
+	uniqueName string,
+	maxParticipants int,
+	statusCallback string,
+) (*video.Room, error) {
+	return f.CreateRoomFn(ctx, uniqueName, maxParticipants, statusCallback)
+}
+
+// GetRoom is a mock of the GetRoom method
+func (f *FakeServiceVideo) GetRoom(ctx context.Context, sidOrUniqueName string) (*video.Room, error) {
+	return f.GetRoomFn(ctx, sidOrUniqueName)
+}
+
+// CompleteRoom is a mock of the CompleteRoom method
+func (f *FakeServiceVideo) CompleteRoom(ctx context.Context, sid string) error {
+	return f.CompleteRoomFn(ctx, sid)
+}
+
+// AccessToken is a mock of the AccessToken method
+func (f *FakeServiceVideo) AccessToken(identity string, roomName string, ttl time.Duration) (string, error) {
+	return f.AccessTokenFn(identity, roomName, ttl)
+}
@@ -0,0 +1,206 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/twilio-go/token"
+	"github.com/savannahghi/serverutils"
+)
+
+// Twilio credentials and the video API's URL. These are the same variables
+// that the library's Twilio service uses.
+const (
+	TwilioAccountSIDEnvVarName        = "TWILIO_ACCOUNT_SID"
+	TwilioAccountAuthTokenEnvVarName  = "TWILIO_ACCOUNT_AUTH_TOKEN" /* #nosec */
+	TwilioVideoAPIURLEnvVarName       = "TWILIO_VIDEO_API_URL"
+	TwilioVideoAPIKeySIDEnvVarName    = "TWILIO_VIDEO_SID"
+	TwilioVideoAPIKeySecretEnvVarName = "TWILIO_VIDEO_SECRET" /* #nosec */
+
+	twilioHTTPTimeoutSeconds = 30
+
+	// twilioGroupRoom is the type of the rooms that are created. Group
+	// rooms route media through Twilio so that they can have more than two
+	// participants and report when participants join and leave.
+	twilioGroupRoom = "group"
+
+	// twilioRoomCompleted is the status that ends a room
+	twilioRoomCompleted = "completed"
+)
+
+// Room is a video room at the provider
+type Room struct {
+	SID        string `json:"sid"`
+	UniqueName string `json:"unique_name"`
+	Status     string `json:"status"`
+}
+
+// ServiceVideo defines the interaction with a provider of video rooms.
+//
+// Rooms are created when their first participant is about to join. A room
+// that nobody joins, or that everyone has left, is ended by the provider
+// after a few minutes.
+type ServiceVideo interface {
+	// CreateRoom creates a room with a unique name. The provider posts the
+	// room's events to the status callback URL.
+	CreateRoom(
+		ctx context.Context,
+		uniqueName string,
+		maxParticipants int,
+		statusCallback string,
+	) (*Room, error)
+
+	// GetRoom returns a room that is in progress by its SID or unique name
+	GetRoom(ctx context.Context, sidOrUniqueName string) (*Room, error)
+
+	// CompleteRoom ends a room and disconnects its participants
+	CompleteRoom(ctx context.Context, sid string) error
+
+	// AccessToken returns a token that lets one identity join one room
+	// until the token expires
+	AccessToken(identity string, roomName string, ttl time.Duration) (string, error)
+}
+
+// ServiceVideoImpl manages video rooms through Twilio
+type ServiceVideoImpl struct {
+	BaseURL          string
+	accountSID       string
+	accountAuthToken string
+	apiKeySID        string
+	apiKeySecret     string
+	httpClient       *http.Client
+}
+
+// NewService initializes a service to manage video rooms through Twilio
+func NewService() *ServiceVideoImpl {
+	srv := &ServiceVideoImpl{
+		BaseURL:          strings.TrimSuffix(serverutils.MustGetEnvVar(TwilioVideoAPIURLEnvVarName), "/"),
+		accountSID:       serverutils.MustGetEnvVar(TwilioAccountSIDEnvVarName),
+		accountAuthToken: serverutils.MustGetEnvVar(TwilioAccountAuthTokenEnvVarName),
+		apiKeySID:        serverutils.MustGetEnvVar(TwilioVideoAPIKeySIDEnvVarName),
+		apiKeySecret:     serverutils.MustGetEnvVar(TwilioVideoAPIKeySecretEnvVarName),
+		httpClient: &http.Client{
+			Timeout: time.Second * twilioHTTPTimeoutSeconds,
+		},
+	}
+	srv.checkPreconditions()
+	return srv
+}
+
+func (s ServiceVideoImpl) checkPreconditions() {
+	if s.accountSID == "" || s.accountAuthToken == "" {
+		log.Panicf("Twilio video service has no credentials")
+	}
+	if s.apiKeySID == "" || s.apiKeySecret == "" {
+		log.Panicf("Twilio video service has no API key")
+	}
+	if s.BaseURL == "" {
+		log.Panicf("Twilio video service has no base URL")
+	}
+}
+
+// CreateRoom creates a group room
+func (s ServiceVideoImpl) CreateRoom(
+	ctx context.Context,
+	uniqueName string,
+	maxParticipants int,
+	statusCallback string,
+) (*Room, error) {
+	form := url.Values{}
+	form.Set("UniqueName", uniqueName)
+	form.Set("Type", twilioGroupRoom)
+	form.Set("MaxParticipants", strconv.Itoa(maxParticipants))
+	form.Set("StatusCallback", statusCallback)
+	form.Set("StatusCallbackMethod", http.MethodPost)
+
+	room := Room{}
+	if err := s.request(ctx, http.MethodPost, "/v1/Rooms", form, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// GetRoom fetches a room. Twilio finds rooms by unique name only while they
+// are in progress.
+func (s ServiceVideoImpl) GetRoom(ctx context.Context, sidOrUniqueName string) (*Room, error) {
+	room := Room{}
+	path := "/v1/Rooms/" + url.PathEscape(sidOrUniqueName)
+	if err := s.request(ctx, http.MethodGet, path, nil, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// CompleteRoom sets the status of a room to completed
+func (s ServiceVideoImpl) CompleteRoom(ctx context.Context, sid string) error {
+	form := url.Values{}
+	form.Set("Status", twilioRoomCompleted)
+	return s.request(ctx, http.MethodPost, "/v1/Rooms/"+url.PathEscape(sid), form, &Room{})
+}
+
+// AccessToken signs a token with the video API key. Its video grant names the
+// room, so it can't be used to join any other room.
+func (s ServiceVideoImpl) AccessToken(identity string, roomName string, ttl time.Duration) (string, error) {
+	s.checkPreconditions()
+
+	accessToken := token.New(s.accountSID, s.apiKeySID, s.apiKeySecret, identity, ttl)
+	accessToken.AddGrant(token.NewVideoGrant(roomName))
+	jwt, err := accessToken.JWT()
+	if err != nil {
+		return "", fmt.Errorf("unable to sign video access token: %w", err)
+	}
+	return jwt, nil
+}
+
+// request makes an authenticated request to the video API and unmarshals
+// the response into result
+func (s ServiceVideoImpl) request(
+	ctx context.Context,
+	method string,
+	path string,
+	form url.Values,
+	result interface{},
+) error {
+	s.checkPreconditions()
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.SetBasicAuth(s.accountSID, s.accountAuthToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Twilio response: %w", err)
+	}
+	if resp.StatusCode > http.StatusCreated {
+		return fmt.Errorf("twilio API error: %s", string(respBody))
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("unable to unmarshal Twilio room: %w", err)
+	}
+	return nil
+}
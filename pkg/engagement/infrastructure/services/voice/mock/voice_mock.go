@@ -0,0 +1,15 @@
+package mock
+
+import (
+	"context"
+)
+
+// FakeServiceVoice simulates the behavior of our voice call implementation
+type FakeServiceVoice struct {
+	SayFn func(ctx context.Context, to string, message string) (string, error)
+}
+
+// Say is a mock of the Say method
+func (f *FakeServiceVoice) Say(ctx context.Context, to string, message string) (string, error) {
+	return f.SayFn(ctx, to, message)
+}
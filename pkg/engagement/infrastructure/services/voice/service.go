@@ -0,0 +1,117 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+)
+
+// Twilio credentials and the number that calls are made from. These are the
+// same variables that the Twilio SMS service uses.
+const (
+	TwilioAccountSIDEnvVarName       = "TWILIO_ACCOUNT_SID"
+	TwilioAccountAuthTokenEnvVarName = "TWILIO_ACCOUNT_AUTH_TOKEN"
+	TwilioCallerNumberEnvVarName     = "TWILIO_SMS_NUMBER"
+
+	twilioAPIBaseURL         = "https://api.twilio.com/2010-04-01/Accounts/"
+	twilioHTTPTimeoutSeconds = 30
+)
+
+// ServiceVoice defines the interaction with a provider of outbound voice calls
+type ServiceVoice interface {
+	// Say calls a phone number and reads a message out when the call is
+	// answered. It returns the provider's ID of the call.
+	Say(ctx context.Context, to string, message string) (string, error)
+}
+
+// ServiceVoiceImpl makes voice calls through Twilio
+type ServiceVoiceImpl struct {
+	BaseURL          string
+	accountSID       string
+	accountAuthToken string
+	from             string
+	httpClient       *http.Client
+}
+
+// NewService initializes a service to make voice calls through Twilio
+func NewService() *ServiceVoiceImpl {
+	srv := &ServiceVoiceImpl{
+		BaseURL:          twilioAPIBaseURL,
+		accountSID:       serverutils.MustGetEnvVar(TwilioAccountSIDEnvVarName),
+		accountAuthToken: serverutils.MustGetEnvVar(TwilioAccountAuthTokenEnvVarName),
+		from:             serverutils.MustGetEnvVar(TwilioCallerNumberEnvVarName),
+		httpClient: &http.Client{
+			Timeout: time.Second * twilioHTTPTimeoutSeconds,
+		},
+	}
+	srv.checkPreconditions()
+	return srv
+}
+
+func (s ServiceVoiceImpl) checkPreconditions() {
+	if s.accountSID == "" || s.accountAuthToken == "" {
+		log.Panicf("Twilio voice service has no credentials")
+	}
+	if s.from == "" {
+		log.Panicf("Twilio voice service has no caller number")
+	}
+}
+
+// twilioCall is the part of Twilio's call resource that is used
+type twilioCall struct {
+	SID string `json:"sid"`
+}
+
+// Say creates a Twilio call whose TwiML reads the message out twice
+func (s ServiceVoiceImpl) Say(ctx context.Context, to string, message string) (string, error) {
+	s.checkPreconditions()
+
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(message)); err != nil {
+		return "", fmt.Errorf("unable to escape voice message: %w", err)
+	}
+	twiml := fmt.Sprintf(`<Response><Say loop="2">%s</Say></Response>`, escaped.String())
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", s.from)
+	form.Set("Twiml", twiml)
+
+	endpoint := fmt.Sprintf("%s%s/Calls.json", s.BaseURL, s.accountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.accountSID, s.accountAuthToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("twilio API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read Twilio response: %w", err)
+	}
+	if resp.StatusCode > http.StatusCreated {
+		return "", fmt.Errorf("twilio API error: %s", string(body))
+	}
+
+	call := twilioCall{}
+	if err := json.Unmarshal(body, &call); err != nil {
+		return "", fmt.Errorf("unable to unmarshal Twilio call: %w", err)
+	}
+	return call.SID, nil
+}
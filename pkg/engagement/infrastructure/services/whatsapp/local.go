@@ -0,0 +1,50 @@
+package whatsapp
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+// LocalServiceWhatsApp logs messages instead of sending them. It is used for
+// local development, where there are no Twilio credentials.
+type LocalServiceWhatsApp struct{}
+
+// NewLocalService initializes a service that logs messages
+func NewLocalService() *LocalServiceWhatsApp {
+	return &LocalServiceWhatsApp{}
+}
+
+// SendTemplate logs a template message
+func (s LocalServiceWhatsApp) SendTemplate(
+	ctx context.Context,
+	to string,
+	templateID string,
+	parameters []string,
+) (string, error) {
+	log.Printf("WhatsApp template %s to %s: %s", templateID, to, strings.Join(parameters, " | "))
+	return localMessageID(), nil
+}
+
+// SendText logs a text message
+func (s LocalServiceWhatsApp) SendText(ctx context.Context, to string, text string) (string, error) {
+	log.Printf("WhatsApp text to %s: %s", to, text)
+	return localMessageID(), nil
+}
+
+// SendMedia logs a media message
+func (s LocalServiceWhatsApp) SendMedia(
+	ctx context.Context,
+	to string,
+	mediaURL string,
+	caption string,
+) (string, error) {
+	log.Printf("WhatsApp media %s to %s: %s", mediaURL, to, caption)
+	return localMessageID(), nil
+}
+
+func localMessageID() string {
+	return "local-" + ksuid.New().String()
+}
@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"context"
+)
+
+// FakeServiceWhatsApp simulates the behavior of our WhatsApp provider
+// implementation
+type FakeServiceWhatsApp struct {
+	SendTemplateFn func(ctx context.Context, to string, templateID string, parameters []string) (string, error)
+	SendTextFn     func(ctx context.Context, to string, text string) (string, error)
+	SendMediaFn    func(ctx context.Context, to string, mediaURL string, caption string) (string, error)
+}
+
+// SendTemplate is a mock of the SendTemplate method
+func (f *FakeServiceWhatsApp) SendTemplate(
+	ctx context.Context,
+	to string,
+	templateID string,
+	parameters []string,
+) (string, error) {
+	return f.SendTemplateFn(ctx, to, templateID, parameters)
+}
+
+// SendText is a mock of the SendText method
+func (f *FakeServiceWhatsApp) SendText(ctx context.Context, to string, text string) (string, error) {
+	return f.SendTextFn(ctx, to, text)
+}
+
+// SendMedia is a mock of the SendMedia method
+func (f *FakeServiceWhatsApp) SendMedia(
+	ctx context.Context,
+	to string,
+	mediaURL string,
+	caption string,
+) (string, error) {
+	return f.SendMediaFn(ctx, to, mediaURL, caption)
+}
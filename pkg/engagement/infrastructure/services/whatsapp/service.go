@@ -0,0 +1,168 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+)
+
+// Twilio credentials and the WhatsApp sender number. These are the same
+// variables that the library's Twilio service uses.
+const (
+	TwilioWhatsAppSIDEnvVarName       = "TWILIO_WHATSAPP_SID"
+	TwilioWhatsAppAuthTokenEnvVarName = "TWILIO_WHATSAPP_AUTH_TOKEN" /* #nosec */
+	TwilioWhatsAppSenderEnvVarName    = "TWILIO_WHATSAPP_SENDER"
+
+	twilioAPIBaseURL         = "https://api.twilio.com/2010-04-01/Accounts/"
+	twilioHTTPTimeoutSeconds = 30
+
+	// whatsAppAddressPrefix marks Twilio addresses as WhatsApp numbers
+	whatsAppAddressPrefix = "whatsapp:"
+)
+
+// ServiceWhatsApp defines the interaction with a WhatsApp Business API
+// provider. Each method returns the provider's ID of the message.
+//
+// WhatsApp only delivers text and media messages in a session, which starts
+// when the user messages us and lasts 24 hours. Outside a session only
+// approved template messages are delivered.
+type ServiceWhatsApp interface {
+	// SendTemplate sends an approved template message. The parameters fill
+	// the template's placeholders `{{1}}`, `{{2}}` and so on, in order.
+	SendTemplate(ctx context.Context, to string, templateID string, parameters []string) (string, error)
+
+	// SendText sends a session text message
+	SendText(ctx context.Context, to string, text string) (string, error)
+
+	// SendMedia sends a session message with an image, document, audio or
+	// video that the provider downloads from a public URL
+	SendMedia(ctx context.Context, to string, mediaURL string, caption string) (string, error)
+}
+
+// ServiceWhatsAppImpl sends WhatsApp messages through Twilio
+type ServiceWhatsAppImpl struct {
+	BaseURL          string
+	accountSID       string
+	accountAuthToken string
+	sender           string
+	httpClient       *http.Client
+}
+
+// NewService initializes a service to send WhatsApp messages through Twilio
+func NewService() *ServiceWhatsAppImpl {
+	srv := &ServiceWhatsAppImpl{
+		BaseURL:          twilioAPIBaseURL,
+		accountSID:       serverutils.MustGetEnvVar(TwilioWhatsAppSIDEnvVarName),
+		accountAuthToken: serverutils.MustGetEnvVar(TwilioWhatsAppAuthTokenEnvVarName),
+		sender:           serverutils.MustGetEnvVar(TwilioWhatsAppSenderEnvVarName),
+		httpClient: &http.Client{
+			Timeout: time.Second * twilioHTTPTimeoutSeconds,
+		},
+	}
+	srv.checkPreconditions()
+	return srv
+}
+
+func (s ServiceWhatsAppImpl) checkPreconditions() {
+	if s.accountSID == "" || s.accountAuthToken == "" {
+		log.Panicf("Twilio WhatsApp service has no credentials")
+	}
+	if s.sender == "" {
+		log.Panicf("Twilio WhatsApp service has no sender number")
+	}
+}
+
+// twilioMessage is the part of Twilio's message resource that is used
+type twilioMessage struct {
+	SID string `json:"sid"`
+}
+
+// SendTemplate sends a template with Twilio's content API, where templates
+// are identified by their content SID e.g `HXb5b62575e6e4ff6129ad7c8efe1f983e`
+func (s ServiceWhatsAppImpl) SendTemplate(
+	ctx context.Context,
+	to string,
+	templateID string,
+	parameters []string,
+) (string, error) {
+	variables := map[string]string{}
+	for i, parameter := range parameters {
+		variables[strconv.Itoa(i+1)] = parameter
+	}
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal template parameters: %w", err)
+	}
+	form := url.Values{}
+	form.Set("ContentSid", templateID)
+	form.Set("ContentVariables", string(encoded))
+	return s.send(ctx, to, form)
+}
+
+// SendText sends a session text message
+func (s ServiceWhatsAppImpl) SendText(ctx context.Context, to string, text string) (string, error) {
+	form := url.Values{}
+	form.Set("Body", text)
+	return s.send(ctx, to, form)
+}
+
+// SendMedia sends a session media message with an optional caption
+func (s ServiceWhatsAppImpl) SendMedia(
+	ctx context.Context,
+	to string,
+	mediaURL string,
+	caption string,
+) (string, error) {
+	form := url.Values{}
+	form.Set("MediaUrl", mediaURL)
+	if caption != "" {
+		form.Set("Body", caption)
+	}
+	return s.send(ctx, to, form)
+}
+
+// send creates a Twilio message from the WhatsApp sender to a phone number
+func (s ServiceWhatsAppImpl) send(ctx context.Context, to string, form url.Values) (string, error) {
+	s.checkPreconditions()
+
+	form.Set("To", whatsAppAddressPrefix+to)
+	form.Set("From", whatsAppAddressPrefix+strings.TrimPrefix(s.sender, whatsAppAddressPrefix))
+
+	endpoint := fmt.Sprintf("%s%s/Messages.json", s.BaseURL, s.accountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.accountSID, s.accountAuthToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("twilio API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read Twilio response: %w", err)
+	}
+	if resp.StatusCode > http.StatusCreated {
+		return "", fmt.Errorf("twilio API error: %s", string(body))
+	}
+
+	message := twilioMessage{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		return "", fmt.Errorf("unable to unmarshal Twilio message: %w", err)
+	}
+	return message.SID, nil
+}
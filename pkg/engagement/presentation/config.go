@@ -5,19 +5,35 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/gommon/log"
 	osinfra "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
 	engLibPresentation "github.com/savannahghi/engagementcore/pkg/engagement/presentation"
+	libRest "github.com/savannahghi/engagementcore/pkg/engagement/presentation/rest"
 	osusecases "github.com/savannahghi/engagementcore/pkg/engagement/usecases"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	fb "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/firestore"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/calendar"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/fcm"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/video"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/voice"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/whatsapp"
 	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/graph"
 	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/graph/generated"
 	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/interactor"
+	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/rest"
 	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
 	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/interserviceclient"
+	"github.com/savannahghi/pubsubtools"
 	"github.com/savannahghi/serverutils"
 
 	"net/http"
@@ -29,6 +45,89 @@ import (
 const (
 	mbBytes              = 1048576
 	serverTimeoutSeconds = 120
+
+	// schedulerInterval is how often the in-process scheduler checks for
+	// scheduled notifications that are due
+	schedulerInterval = time.Minute
+
+	// bulkSMSInterval is how often the bulk SMS worker checks for jobs that
+	// are not being sent, including jobs whose instance stopped
+	bulkSMSInterval = 10 * time.Second
+
+	// digestInterval is how often digests of rate limited notifications
+	// are checked for and sent
+	digestInterval = time.Minute
+
+	// optional env vars that configure the generated OTPs
+	otpLengthEnvVarName            = "OTP_LENGTH"
+	otpExpiryMinutesEnvVarName     = "OTP_EXPIRY_MINUTES"
+	otpMaxVerifyAttemptsEnvVarName = "OTP_MAX_VERIFY_ATTEMPTS"
+	otpLockoutMinutesEnvVarName    = "OTP_LOCKOUT_MINUTES"
+	otpRetryLadderEnvVarName       = "OTP_RETRY_LADDER"
+
+	// env vars that configure email verification links
+	emailVerificationSigningKeyEnvVarName    = "EMAIL_VERIFICATION_SIGNING_KEY"
+	emailVerificationExpiryMinutesEnvVarName = "EMAIL_VERIFICATION_EXPIRY_MINUTES"
+	serverPublicDomainEnvVarName             = "SERVER_PUBLIC_DOMAIN"
+
+	// smsProviderEnvVarName selects the SMS provider. Messages are sent
+	// through Africa's Talking unless it is set to `local`, which logs them.
+	smsProviderEnvVarName = "SMS_PROVIDER"
+	localSMSProvider      = "local"
+
+	// emailProviderEnvVarName selects the email provider. Emails are sent
+	// through Mailgun unless it is set to `smtp`, which sends them to the
+	// SMTP server in SMTP_HOST and SMTP_PORT e.g a local catcher.
+	emailProviderEnvVarName = "EMAIL_PROVIDER"
+	smtpEmailProvider       = "smtp"
+
+	// whatsAppProviderEnvVarName selects the WhatsApp provider. Messages are
+	// sent through Twilio unless it is set to `local`, which logs them.
+	whatsAppProviderEnvVarName = "WHATSAPP_PROVIDER"
+	localWhatsAppProvider      = "local"
+
+	// env vars with the Twilio content SIDs of the approved WhatsApp
+	// templates that the service sends
+	whatsAppVerificationTemplateEnvVarName = "WHATSAPP_VERIFICATION_TEMPLATE_SID"
+	whatsAppItemTemplateEnvVarName         = "WHATSAPP_ITEM_TEMPLATE_SID"
+
+	// videoProviderEnvVarName selects the video provider. Rooms are created
+	// through Twilio unless it is set to `local`, which keeps them in memory.
+	videoProviderEnvVarName = "VIDEO_PROVIDER"
+	localVideoProvider      = "local"
+
+	// calendarProviderEnvVarName selects the calendar that appointments are
+	// kept in. It is the Google calendar in GOOGLE_CALENDAR_ID unless it is
+	// set to `local`, which keeps them in the repository.
+	calendarProviderEnvVarName = "CALENDAR_PROVIDER"
+	localCalendarProvider      = "local"
+
+	// optional env vars that control how fast bulk SMS jobs are sent
+	bulkSMSChunkSizeEnvVarName       = "BULK_SMS_CHUNK_SIZE"
+	bulkSMSChunkIntervalMsEnvVarName = "BULK_SMS_CHUNK_INTERVAL_MS"
+
+	// smsDefaultBudgetEnvVarName is the optional env var with the most, in
+	// KES, that a single send without a flavour may cost
+	smsDefaultBudgetEnvVarName = "SMS_DEFAULT_BUDGET"
+
+	// smsCallbackSigningKeyEnvVarName is the key that the SMS delivery
+	// report and inbound SMS callback URLs are signed with
+	smsCallbackSigningKeyEnvVarName = "SMS_CALLBACK_SIGNING_KEY"
+
+	// env vars with the keys that email webhooks and unsubscribe links are
+	// signed with. The webhook key is Mailgun's HTTP webhook signing key.
+	mailgunWebhookSigningKeyEnvVarName   = "MAILGUN_WEBHOOK_SIGNING_KEY"
+	emailUnsubscribeSigningKeyEnvVarName = "EMAIL_UNSUBSCRIBE_SIGNING_KEY"
+
+	// env vars with the key that email reply addresses are signed with and
+	// the domain that Mailgun receives replies for
+	emailReplySigningKeyEnvVarName = "EMAIL_REPLY_SIGNING_KEY"
+	emailInboundDomainEnvVarName   = "EMAIL_INBOUND_DOMAIN"
+
+	// env vars with the key that appointment calendar links are signed
+	// with and the address that appointment invites are from
+	appointmentLinkSigningKeyEnvVarName = "APPOINTMENT_LINK_SIGNING_KEY"
+	appointmentOrganizerEnvVarName      = "APPOINTMENT_ORGANIZER_EMAIL"
 )
 
 // AllowedOrigins is list of CORS origins allowed to interact with
@@ -47,6 +146,14 @@ var allowedHeaders = []string{
 	"Content-Type", " X-Authorization", " Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers",
 }
 
+// AllowedContentTypes are the request bodies that the server accepts. The
+// email provider posts inbound emails as multipart/form-data.
+var AllowedContentTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+}
+
 // Router sets up the ginContext router
 func Router(ctx context.Context) (*mux.Router, error) {
 	fc := &firebasetools.FirebaseClient{}
@@ -60,7 +167,219 @@ func Router(ctx context.Context) (*mux.Router, error) {
 	infrastructure := osinfra.NewInteractor()
 	openSourceUsecases := osusecases.NewUsecasesInteractor(infrastructure)
 
-	var notification usecases.NotificationUsecases
+	repository, err := fb.NewFirebaseRepository(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate firebase repository in resolver: %w", err)
+	}
+
+	fcmService, err := fcm.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate FCM service: %w", err)
+	}
+
+	var smsService sms.ServiceSMS = sms.NewService()
+	if os.Getenv(smsProviderEnvVarName) == localSMSProvider {
+		smsService = sms.NewLocalService()
+	}
+	var emailService email.ServiceEmail
+	if os.Getenv(emailProviderEnvVarName) == smtpEmailProvider {
+		emailService = email.NewSMTPService()
+	} else {
+		emailService = email.NewService()
+	}
+
+	var whatsAppService whatsapp.ServiceWhatsApp
+	if os.Getenv(whatsAppProviderEnvVarName) == localWhatsAppProvider {
+		whatsAppService = whatsapp.NewLocalService()
+	} else {
+		whatsAppService = whatsapp.NewService()
+	}
+	whatsAppConfig, err := whatsAppConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	whatsApp, err := usecases.NewWhatsApp(whatsAppService, repository, whatsAppConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate WhatsApp usecases: %w", err)
+	}
+
+	// notifications are not sent to numbers that have opted out of SMS.
+	// OTPs are still sent since the user asked for them.
+	filteredSMSService := usecases.NewSMSOptOutFilter(repository, smsService)
+
+	emailSuppressionConfig, err := emailSuppressionConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	emailSuppressions, err := usecases.NewEmailSuppressions(repository, emailSuppressionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate email suppression usecases: %w", err)
+	}
+
+	// emails are not sent to addresses that bounced or complained. OTPs and
+	// verification links are still sent to addresses that unsubscribed.
+	filteredEmailService := usecases.NewEmailSuppressionFilter(repository, emailService, emailSuppressions)
+	transactionalMail := usecases.NewTransactionalMailFilter(repository, infrastructure.ServiceMailImpl)
+
+	templates := usecases.NewTemplates(repository)
+	inbox := usecases.NewInbox(repository)
+	devices := usecases.NewDevices(repository, fcmService, inbox)
+	rateLimits := usecases.NewRateLimits(repository)
+	smsCostConfig, err := smsCostConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	smsCosts, err := usecases.NewSMSCosts(repository, smsCostConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate SMS cost usecases: %w", err)
+	}
+	notification := usecases.NewNotification(
+		infrastructure.Repository,
+		openSourceUsecases.NotificationImpl,
+		templates,
+		devices,
+		rateLimits,
+		smsCosts,
+		filteredSMSService,
+		filteredEmailService,
+		whatsApp,
+		infrastructure,
+		inbox,
+	)
+	groups := usecases.NewRecipientGroups(repository)
+	schedules := usecases.NewSchedules(repository, repository, templates, notification)
+	go schedules.StartScheduler(ctx, schedulerInterval)
+	go notification.StartDigestDispatcher(ctx, digestInterval)
+
+	otpConfig, err := otpConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	otp, err := usecases.NewOTP(
+		repository,
+		otpConfig,
+		smsService,
+		transactionalMail,
+		infrastructure.ServiceTwilioImpl,
+		whatsApp,
+		voice.NewService(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate OTP usecases: %w", err)
+	}
+
+	emailVerificationConfig, err := emailVerificationConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	emailVerification, err := usecases.NewEmailVerification(
+		repository,
+		templates,
+		transactionalMail,
+		emailVerificationConfig,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate email verification usecases: %w", err)
+	}
+
+	bulkSMSConfig, err := bulkSMSConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	bulkSMS, err := usecases.NewBulkSMS(repository, filteredSMSService, smsCosts, bulkSMSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate bulk SMS usecases: %w", err)
+	}
+	go bulkSMS.StartBulkSMSWorker(ctx, bulkSMSInterval)
+
+	smsCallbackKey, err := serverutils.GetEnvVar(smsCallbackSigningKeyEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	smsCallbacks, err := usecases.NewSMSCallbacks(
+		repository,
+		smsService,
+		openSourceUsecases.UseCaseImpl,
+		inbox,
+		usecases.SMSCallbackConfig{SigningKey: []byte(smsCallbackKey)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate SMS callback usecases: %w", err)
+	}
+
+	emailConversationConfig, err := emailConversationConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	emailConversations, err := usecases.NewEmailConversations(
+		repository,
+		filteredEmailService,
+		openSourceUsecases.ImpUploads,
+		openSourceUsecases.UseCaseImpl,
+		inbox,
+		emailConversationConfig,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate email conversation usecases: %w", err)
+	}
+
+	whatsAppConversationConfig, err := whatsAppConversationConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	whatsAppConversations, err := usecases.NewWhatsAppConversations(
+		repository,
+		whatsApp,
+		openSourceUsecases.UseCaseImpl,
+		openSourceUsecases.UseCaseImpl,
+		inbox,
+		whatsAppConversationConfig,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate WhatsApp conversation usecases: %w", err)
+	}
+
+	var videoService video.ServiceVideo
+	if os.Getenv(videoProviderEnvVarName) == localVideoProvider {
+		videoService = video.NewLocalService()
+	} else {
+		videoService = video.NewService()
+	}
+	videoRoomConfig, err := videoRoomConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	videoRooms, err := usecases.NewVideoRooms(repository, videoService, videoRoomConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate video room usecases: %w", err)
+	}
+
+	var calendarService calendar.ServiceCalendar
+	if os.Getenv(calendarProviderEnvVarName) == localCalendarProvider {
+		calendarService = usecases.NewCalendarStore(repository)
+	} else {
+		calendarService, err = calendar.NewService(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't instantiate calendar service: %w", err)
+		}
+	}
+	appointmentConfig, err := appointmentConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	appointments, err := usecases.NewAppointments(
+		calendarService,
+		templates,
+		filteredEmailService,
+		filteredSMSService,
+		repository,
+		appointmentConfig,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't instantiate appointment usecases: %w", err)
+	}
+	availability := usecases.NewAvailability(repository, appointments)
+
 	var feed usecases.FeedUsecases
 
 	// Initialize the interactor
@@ -69,12 +388,70 @@ func Router(ctx context.Context) (*mux.Router, error) {
 		openSourceUsecases,
 		notification,
 		feed,
+		templates,
+		devices,
+		inbox,
+		schedules,
+		groups,
+		rateLimits,
+		otp,
+		emailVerification,
+		bulkSMS,
+		smsCallbacks,
+		smsCosts,
+		usecases.NewEmail(filteredEmailService, templates, openSourceUsecases.ImpUploads),
+		emailSuppressions,
+		emailConversations,
+		whatsApp,
+		whatsAppConversations,
+		videoRooms,
+		appointments,
+		availability,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("can't instantiate service : %w", err)
 	}
 
 	r := mux.NewRouter() // gorilla mux
+
+	// item publishes are handled here so that their notifications are rate
+	// limited; the route is registered before the library's so it matches first
+	h := rest.NewPresentationHandlers(
+		notification,
+		emailVerification,
+		smsCallbacks,
+		emailSuppressions,
+		emailConversations,
+		whatsAppConversations,
+		videoRooms,
+		appointments,
+		otp,
+		libRest.NewPresentationHandlers(infrastructure, openSourceUsecases),
+	)
+	r.Path(pubsubtools.PubSubHandlerPath).Methods(
+		http.MethodPost).HandlerFunc(h.GoogleCloudPubSubHandler)
+	r.Path(usecases.EmailVerificationPath).Methods(
+		http.MethodGet, http.MethodPost).HandlerFunc(h.VerifyEmailLink)
+	r.Path(usecases.SMSDeliveryReportsPath).Methods(
+		http.MethodPost).HandlerFunc(h.SMSDeliveryReportHandler)
+	r.Path(usecases.InboundSMSPath).Methods(
+		http.MethodPost).HandlerFunc(h.InboundSMSHandler)
+	r.Path(usecases.EmailBouncesPath).Methods(
+		http.MethodPost).HandlerFunc(h.EmailBounceHandler)
+	r.Path(usecases.EmailComplaintsPath).Methods(
+		http.MethodPost).HandlerFunc(h.EmailComplaintHandler)
+	r.Path(usecases.EmailUnsubscribesPath).Methods(
+		http.MethodPost).HandlerFunc(h.EmailUnsubscribeHandler)
+	r.Path(usecases.EmailUnsubscribePath).Methods(
+		http.MethodGet, http.MethodPost).HandlerFunc(h.UnsubscribeEmailLink)
+	r.Path(usecases.InboundEmailPath).Methods(
+		http.MethodPost).HandlerFunc(h.InboundEmailHandler)
+	r.Path(usecases.InboundWhatsAppPath).Methods(
+		http.MethodPost).HandlerFunc(h.InboundWhatsAppHandler)
+	r.Path(usecases.VideoRoomCallbackPath).Methods(
+		http.MethodPost).HandlerFunc(h.VideoRoomCallbackHandler)
+	r.Path(usecases.AppointmentICSPath).Methods(
+		http.MethodGet).HandlerFunc(h.AppointmentICSHandler)
 	engLibPresentation.SharedUnauthenticatedRoutes(ctx, r)
 
 	// Authenticated routes
@@ -85,10 +462,262 @@ func Router(ctx context.Context) (*mux.Router, error) {
 		http.MethodGet,
 	).HandlerFunc(GQLHandler(ctx, i))
 
+	// OTP apps are managed by other services rather than by users
+	iscR := r.Path(usecases.OTPAppsPath).Subrouter()
+	iscR.Use(interserviceclient.InterServiceAuthenticationMiddleware())
+	iscR.Methods(http.MethodPost).HandlerFunc(h.SaveOTPAppHandler)
+	iscR.Methods(http.MethodDelete).HandlerFunc(h.DeleteOTPAppHandler)
+
 	engLibPresentation.SharedAuthenticatedISCRoutes(ctx, r)
 	return r, nil
 }
 
+// otpConfigFromEnv reads the OTP settings from the environment.
+// Settings that are not set keep their defaults.
+func otpConfigFromEnv() (usecases.OTPConfig, error) {
+	config := usecases.OTPConfig{}
+	if length, ok := os.LookupEnv(otpLengthEnvVarName); ok {
+		n, err := strconv.Atoi(length)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", otpLengthEnvVarName, err)
+		}
+		config.Length = n
+	}
+	if expiry, ok := os.LookupEnv(otpExpiryMinutesEnvVarName); ok {
+		minutes, err := strconv.Atoi(expiry)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", otpExpiryMinutesEnvVarName, err)
+		}
+		config.Expiry = time.Duration(minutes) * time.Minute
+	}
+	if attempts, ok := os.LookupEnv(otpMaxVerifyAttemptsEnvVarName); ok {
+		n, err := strconv.Atoi(attempts)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", otpMaxVerifyAttemptsEnvVarName, err)
+		}
+		config.MaxVerifyAttempts = n
+	}
+	if lockout, ok := os.LookupEnv(otpLockoutMinutesEnvVarName); ok {
+		minutes, err := strconv.Atoi(lockout)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", otpLockoutMinutesEnvVarName, err)
+		}
+		config.Lockout = time.Duration(minutes) * time.Minute
+	}
+	if ladder, ok := os.LookupEnv(otpRetryLadderEnvVarName); ok {
+		for _, channel := range strings.Split(ladder, ",") {
+			config.RetryLadder = append(
+				config.RetryLadder,
+				domain.OTPChannel(strings.ToUpper(strings.TrimSpace(channel))),
+			)
+		}
+	}
+	return config, nil
+}
+
+// publicBaseURLFromEnv returns the URL that the service is reached at from
+// the internet. Domains without a scheme are served over HTTPS.
+func publicBaseURLFromEnv() (string, error) {
+	publicDomain, err := serverutils.GetEnvVar(serverPublicDomainEnvVarName)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(publicDomain, "://") {
+		publicDomain = "https://" + publicDomain
+	}
+	return publicDomain, nil
+}
+
+// emailVerificationConfigFromEnv reads the email verification settings from
+// the environment. Links point to the service's public domain.
+func emailVerificationConfigFromEnv() (usecases.EmailVerificationConfig, error) {
+	config := usecases.EmailVerificationConfig{}
+	key, err := serverutils.GetEnvVar(emailVerificationSigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.SigningKey = []byte(key)
+
+	baseURL, err := publicBaseURLFromEnv()
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+
+	if expiry, ok := os.LookupEnv(emailVerificationExpiryMinutesEnvVarName); ok {
+		minutes, err := strconv.Atoi(expiry)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", emailVerificationExpiryMinutesEnvVarName, err)
+		}
+		config.Expiry = time.Duration(minutes) * time.Minute
+	}
+	return config, nil
+}
+
+// emailSuppressionConfigFromEnv reads the email webhook and unsubscribe
+// link signing keys from the environment. Unsubscribe links point to the
+// service's public domain.
+func emailSuppressionConfigFromEnv() (usecases.EmailSuppressionConfig, error) {
+	config := usecases.EmailSuppressionConfig{}
+	webhookKey, err := serverutils.GetEnvVar(mailgunWebhookSigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.WebhookSigningKey = []byte(webhookKey)
+
+	unsubscribeKey, err := serverutils.GetEnvVar(emailUnsubscribeSigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.UnsubscribeSigningKey = []byte(unsubscribeKey)
+
+	baseURL, err := publicBaseURLFromEnv()
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+	return config, nil
+}
+
+// emailConversationConfigFromEnv reads the keys that received emails and
+// reply addresses are signed with, and the domain of reply addresses, from
+// the environment
+func emailConversationConfigFromEnv() (usecases.EmailConversationConfig, error) {
+	config := usecases.EmailConversationConfig{}
+	webhookKey, err := serverutils.GetEnvVar(mailgunWebhookSigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.WebhookSigningKey = []byte(webhookKey)
+
+	replyKey, err := serverutils.GetEnvVar(emailReplySigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.ReplySigningKey = []byte(replyKey)
+
+	inboundDomain, err := serverutils.GetEnvVar(emailInboundDomainEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.InboundDomain = inboundDomain
+	return config, nil
+}
+
+// whatsAppConfigFromEnv reads the IDs of the WhatsApp templates that the
+// service sends from the environment
+func whatsAppConfigFromEnv() (usecases.WhatsAppConfig, error) {
+	config := usecases.WhatsAppConfig{}
+	verificationTemplate, err := serverutils.GetEnvVar(whatsAppVerificationTemplateEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.VerificationTemplateID = verificationTemplate
+
+	itemTemplate, err := serverutils.GetEnvVar(whatsAppItemTemplateEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.ItemTemplateID = itemTemplate
+	return config, nil
+}
+
+// whatsAppConversationConfigFromEnv reads the Twilio auth token that signs
+// WhatsApp webhooks, and the service's public domain that they are posted
+// to, from the environment
+func whatsAppConversationConfigFromEnv() (usecases.WhatsAppConversationConfig, error) {
+	config := usecases.WhatsAppConversationConfig{}
+	authToken, err := serverutils.GetEnvVar(whatsapp.TwilioWhatsAppAuthTokenEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.AuthToken = []byte(authToken)
+
+	baseURL, err := publicBaseURLFromEnv()
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+	return config, nil
+}
+
+// videoRoomConfigFromEnv reads the Twilio auth token that signs video room
+// status callbacks, and the service's public domain that they are posted
+// to, from the environment
+func videoRoomConfigFromEnv() (usecases.VideoRoomConfig, error) {
+	config := usecases.VideoRoomConfig{}
+	authToken, err := serverutils.GetEnvVar(video.TwilioAccountAuthTokenEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.AuthToken = []byte(authToken)
+
+	baseURL, err := publicBaseURLFromEnv()
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+	return config, nil
+}
+
+// appointmentConfigFromEnv reads the key that appointment calendar links are
+// signed with and the address that invites are from
+func appointmentConfigFromEnv() (usecases.AppointmentConfig, error) {
+	config := usecases.AppointmentConfig{}
+	signingKey, err := serverutils.GetEnvVar(appointmentLinkSigningKeyEnvVarName)
+	if err != nil {
+		return config, err
+	}
+	config.SigningKey = []byte(signingKey)
+
+	config.Organizer, err = serverutils.GetEnvVar(appointmentOrganizerEnvVarName)
+	if err != nil {
+		return config, err
+	}
+
+	baseURL, err := publicBaseURLFromEnv()
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+	return config, nil
+}
+
+// bulkSMSConfigFromEnv reads the bulk SMS settings from the environment.
+// Settings that are not set keep their defaults.
+func bulkSMSConfigFromEnv() (usecases.BulkSMSConfig, error) {
+	config := usecases.BulkSMSConfig{}
+	if size, ok := os.LookupEnv(bulkSMSChunkSizeEnvVarName); ok {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", bulkSMSChunkSizeEnvVarName, err)
+		}
+		config.ChunkSize = n
+	}
+	if interval, ok := os.LookupEnv(bulkSMSChunkIntervalMsEnvVarName); ok {
+		ms, err := strconv.Atoi(interval)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", bulkSMSChunkIntervalMsEnvVarName, err)
+		}
+		config.ChunkInterval = time.Duration(ms) * time.Millisecond
+	}
+	return config, nil
+}
+
+// smsCostConfigFromEnv reads the default SMS budget from the environment.
+// There is no default budget when it is not set.
+func smsCostConfigFromEnv() (usecases.SMSCostConfig, error) {
+	config := usecases.SMSCostConfig{}
+	if budget, ok := os.LookupEnv(smsDefaultBudgetEnvVarName); ok {
+		maxCost, err := strconv.ParseFloat(budget, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", smsDefaultBudgetEnvVarName, err)
+		}
+		config.DefaultBudget = maxCost
+	}
+	return config, nil
+}
+
 // GQLHandler sets up a GraphQL resolver
 func GQLHandler(ctx context.Context,
 	service *interactor.Interactor,
@@ -105,7 +734,9 @@ func GQLHandler(ctx context.Context,
 		),
 	)
 	return func(w http.ResponseWriter, r *http.Request) {
-		srv.ServeHTTP(w, r)
+		// the client's address is used to throttle OTP verification
+		ctx := helpers.ContextWithClientIP(r.Context(), helpers.ClientIP(r))
+		srv.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
@@ -132,11 +763,7 @@ func PrepareServer(
 		handlers.AllowedMethods([]string{"OPTIONS", "GET", "POST"}),
 	)(h)
 	h = handlers.CombinedLoggingHandler(os.Stdout, h)
-	h = handlers.ContentTypeHandler(
-		h,
-		"application/json",
-		"application/x-www-form-urlencoded",
-	)
+	h = handlers.ContentTypeHandler(h, AllowedContentTypes...)
 	srv := &http.Server{
 		Handler:      h,
 		Addr:         addr,
@@ -0,0 +1,65 @@
+package presentation_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/handlers"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/presentation"
+	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/rest"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeEmailConversations struct {
+	usecases.EmailConversationUsecases
+
+	recipient string
+	raw       []byte
+}
+
+func (f *fakeEmailConversations) VerifyInboundEmailSignature(timestamp string, token string, signature string) error {
+	return nil
+}
+
+func (f *fakeEmailConversations) HandleInboundEmail(ctx context.Context, recipient string, raw []byte) (*domain.InboundEmail, error) {
+	f.recipient = recipient
+	f.raw = raw
+	return &domain.InboundEmail{}, nil
+}
+
+func TestAllowedContentTypes_InboundEmail(t *testing.T) {
+	conversations := &fakeEmailConversations{}
+	h := handlers.ContentTypeHandler(
+		http.HandlerFunc(rest.NewPresentationHandlers(
+			nil, nil, nil, nil, conversations, nil, nil, nil, nil, nil,
+		).InboundEmailHandler),
+		presentation.AllowedContentTypes...,
+	)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	assert.Nil(t, form.WriteField("recipient", "reply+abc@mail.example.com"))
+	assert.Nil(t, form.WriteField("body-mime", "Subject: Hello\r\n\r\nThanks"))
+	assert.Nil(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/inbound_email", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "reply+abc@mail.example.com", conversations.recipient)
+	assert.Equal(t, "Subject: Hello\r\n\r\nThanks", string(conversations.raw))
+
+	req = httptest.NewRequest(http.MethodPost, "/inbound_email", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
@@ -0,0 +1,102 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/graph/generated"
+	"github.com/savannahghi/serverutils"
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+func (r *mutationResolver) CreateAppointment(ctx context.Context, input dto.AppointmentInput) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	event, err := r.interactor.Appointments.CreateAppointment(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't create appointment: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "createAppointment", err)
+
+	return event, nil
+}
+
+func (r *mutationResolver) UpdateAppointment(ctx context.Context, id string, input dto.AppointmentInput) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	event, err := r.interactor.Appointments.UpdateAppointment(ctx, id, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't update appointment: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "updateAppointment", err)
+
+	return event, nil
+}
+
+func (r *mutationResolver) CancelAppointment(ctx context.Context, id string) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	event, err := r.interactor.Appointments.CancelAppointment(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't cancel appointment: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "cancelAppointment", err)
+
+	return event, nil
+}
+
+func (r *queryResolver) Appointment(ctx context.Context, id string) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	event, err := r.interactor.Appointments.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get appointment: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "appointment", err)
+
+	return event, nil
+}
+
+func (r *queryResolver) Appointments(ctx context.Context, from time.Time, to time.Time, includeCancelled bool, attendeeEmail *string) ([]*calendar.Event, error) {
+	startTime := time.Now()
+
+	events, err := r.interactor.Appointments.ListAppointments(ctx, from, to, includeCancelled, attendeeEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can't list appointments: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "appointments", err)
+
+	return events, nil
+}
+
+func (r *queryResolver) AppointmentICSLink(ctx context.Context, id string) (string, error) {
+	startTime := time.Now()
+
+	link, err := r.interactor.Appointments.AppointmentICSLink(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("can't get appointment calendar link: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "appointmentICSLink", err)
+
+	return link, nil
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+type mutationResolver struct{ *Resolver }
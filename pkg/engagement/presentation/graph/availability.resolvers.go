@@ -0,0 +1,144 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+func (r *mutationResolver) SetClinicianAvailability(ctx context.Context, input dto.ClinicianAvailabilityInput) (*domain.ClinicianAvailability, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	availability, err := r.interactor.Availability.SetClinicianAvailability(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't set clinician availability: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setClinicianAvailability", err)
+
+	return availability, nil
+}
+
+func (r *mutationResolver) DeleteClinicianAvailability(ctx context.Context, clinicianID string, locationID string) (bool, error) {
+	startTime := time.Now()
+
+	err := r.interactor.Availability.DeleteClinicianAvailability(ctx, clinicianID, locationID)
+	if err != nil {
+		return false, fmt.Errorf("can't delete clinician availability: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteClinicianAvailability", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) AddAvailabilityException(ctx context.Context, input dto.AvailabilityExceptionInput) (*domain.AvailabilityException, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	exception, err := r.interactor.Availability.AddAvailabilityException(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't add availability exception: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "addAvailabilityException", err)
+
+	return exception, nil
+}
+
+func (r *mutationResolver) DeleteAvailabilityException(ctx context.Context, id string) (bool, error) {
+	startTime := time.Now()
+
+	err := r.interactor.Availability.DeleteAvailabilityException(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("can't delete availability exception: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteAvailabilityException", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) BookSlot(ctx context.Context, input dto.BookSlotInput) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	event, err := r.interactor.Availability.BookSlot(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't book slot: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "bookSlot", err)
+
+	return event, nil
+}
+
+func (r *mutationResolver) CancelSlotBooking(ctx context.Context, appointmentID string) (*calendar.Event, error) {
+	startTime := time.Now()
+
+	event, err := r.interactor.Availability.CancelSlotBooking(ctx, appointmentID)
+	if err != nil {
+		return nil, fmt.Errorf("can't cancel slot booking: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "cancelSlotBooking", err)
+
+	return event, nil
+}
+
+func (r *queryResolver) ClinicianAvailability(ctx context.Context, clinicianID string) ([]*domain.ClinicianAvailability, error) {
+	startTime := time.Now()
+
+	availability, err := r.interactor.Availability.ListClinicianAvailability(ctx, clinicianID)
+	if err != nil {
+		return nil, fmt.Errorf("can't list clinician availability: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "clinicianAvailability", err)
+
+	return availability, nil
+}
+
+func (r *queryResolver) AvailabilityExceptions(ctx context.Context, clinicianID string, from time.Time, to time.Time) ([]*domain.AvailabilityException, error) {
+	startTime := time.Now()
+
+	exceptions, err := r.interactor.Availability.ListAvailabilityExceptions(ctx, clinicianID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("can't list availability exceptions: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "availabilityExceptions", err)
+
+	return exceptions, nil
+}
+
+func (r *queryResolver) FreeSlots(ctx context.Context, clinicianID string, from time.Time, to time.Time, locationID *string) ([]*dto.FreeSlot, error) {
+	startTime := time.Now()
+
+	slots, err := r.interactor.Availability.FreeSlots(ctx, clinicianID, from, to, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("can't list free slots: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "freeSlots", err)
+
+	return slots, nil
+}
@@ -0,0 +1,71 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SendBulkSms(ctx context.Context, message string, to []string, sender enumutils.SenderID, flavour *feedlib.Flavour) (*domain.BulkSMSJob, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	job, err := r.interactor.BulkSMS.SendBulkSMS(ctx, uid, message, to, sender, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("can't send bulk SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendBulkSMS", err)
+
+	return job, nil
+}
+
+func (r *mutationResolver) CancelBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	startTime := time.Now()
+
+	job, err := r.interactor.BulkSMS.CancelBulkSMSJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't cancel bulk SMS job: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "cancelBulkSMSJob", err)
+
+	return job, nil
+}
+
+func (r *queryResolver) BulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	startTime := time.Now()
+
+	job, err := r.interactor.BulkSMS.GetBulkSMSJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get bulk SMS job: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "bulkSMSJob", err)
+
+	return job, nil
+}
+
+func (r *queryResolver) BulkSMSRecipients(ctx context.Context, jobID string, status *domain.BulkSMSRecipientStatus, after *int, first *int) ([]*domain.BulkSMSRecipient, error) {
+	startTime := time.Now()
+
+	recipients, err := r.interactor.BulkSMS.ListBulkSMSRecipients(ctx, jobID, status, after, first)
+	if err != nil {
+		return nil, fmt.Errorf("can't list bulk SMS recipients: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "bulkSMSRecipients", err)
+
+	return recipients, nil
+}
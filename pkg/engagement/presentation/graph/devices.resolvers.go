@@ -0,0 +1,65 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) RegisterDevice(ctx context.Context, input dto.DeviceInput) (*domain.Device, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	device, err := r.interactor.Devices.RegisterDevice(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't register device: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "registerDevice", err)
+
+	return device, nil
+}
+
+func (r *mutationResolver) UnregisterDevice(ctx context.Context, deviceID string) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	unregistered, err := r.interactor.Devices.UnregisterDevice(ctx, uid, deviceID)
+	if err != nil {
+		return false, fmt.Errorf("can't unregister device: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "unregisterDevice", err)
+
+	return unregistered, nil
+}
+
+func (r *queryResolver) MyDevices(ctx context.Context) ([]*domain.Device, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	devices, err := r.interactor.Devices.ListDevices(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("can't list devices: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "myDevices", err)
+
+	return devices, nil
+}
@@ -0,0 +1,26 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SendTemplatedEmail(ctx context.Context, input dto.TemplatedEmailInput) (string, error) {
+	startTime := time.Now()
+
+	id, err := r.interactor.Email.SendTemplatedEmail(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("can't send templated email: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendTemplatedEmail", err)
+
+	return id, nil
+}
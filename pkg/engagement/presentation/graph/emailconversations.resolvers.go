@@ -0,0 +1,44 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) StartEmailConversation(ctx context.Context, input dto.EmailConversationInput) (*domain.EmailConversation, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	conversation, err := r.interactor.EmailConversations.StartEmailConversation(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't start email conversation: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "startEmailConversation", err)
+
+	return conversation, nil
+}
+
+func (r *queryResolver) EmailConversation(ctx context.Context, id string) (*domain.EmailConversation, error) {
+	startTime := time.Now()
+
+	conversation, err := r.interactor.EmailConversations.GetEmailConversation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get email conversation: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "emailConversation", err)
+
+	return conversation, nil
+}
@@ -0,0 +1,52 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) DeleteEmailSuppression(ctx context.Context, email string) (bool, error) {
+	startTime := time.Now()
+
+	deleted, err := r.interactor.EmailSuppressions.DeleteEmailSuppression(ctx, email)
+	if err != nil {
+		return false, fmt.Errorf("can't delete email suppression: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteEmailSuppression", err)
+
+	return deleted, nil
+}
+
+func (r *queryResolver) EmailSuppressions(ctx context.Context, reason *domain.EmailSuppressionReason) ([]*domain.EmailSuppression, error) {
+	startTime := time.Now()
+
+	suppressions, err := r.interactor.EmailSuppressions.ListEmailSuppressions(ctx, reason)
+	if err != nil {
+		return nil, fmt.Errorf("can't list email suppressions: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "emailSuppressions", err)
+
+	return suppressions, nil
+}
+
+func (r *queryResolver) EmailSuppression(ctx context.Context, email string) (*domain.EmailSuppression, error) {
+	startTime := time.Now()
+
+	suppression, err := r.interactor.EmailSuppressions.GetEmailSuppression(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("can't get email suppression: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "emailSuppression", err)
+
+	return suppression, nil
+}
@@ -0,0 +1,52 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) RequestEmailVerificationLink(ctx context.Context, email string) (*domain.EmailVerificationLink, error) {
+	startTime := time.Now()
+
+	link, err := r.interactor.EmailVerification.RequestEmailVerificationLink(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("can't request email verification link: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "requestEmailVerificationLink", err)
+
+	return link, nil
+}
+
+func (r *mutationResolver) RevokeEmailVerificationLinks(ctx context.Context, email string) (int, error) {
+	startTime := time.Now()
+
+	revoked, err := r.interactor.EmailVerification.RevokeEmailVerificationLinks(ctx, email)
+	if err != nil {
+		return 0, fmt.Errorf("can't revoke email verification links: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "revokeEmailVerificationLinks", err)
+
+	return revoked, nil
+}
+
+func (r *queryResolver) EmailVerificationLink(ctx context.Context, id string) (*domain.EmailVerificationLink, error) {
+	startTime := time.Now()
+
+	link, err := r.interactor.EmailVerification.GetEmailVerificationLink(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get email verification link: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "emailVerificationLink", err)
+
+	return link, nil
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/graph/generated"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
 	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
 	"github.com/savannahghi/feedlib"
 	"github.com/savannahghi/firebasetools"
 	"github.com/savannahghi/profileutils"
@@ -19,7 +21,24 @@ import (
 )
 
 func (r *mutationResolver) SendNotification(ctx context.Context, registrationTokens []string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput) (bool, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	sent, err := r.interactor.Devices.SendPushNotification(
+		ctx,
+		registrationTokens,
+		converterandformatter.ConvertInterfaceMap(data),
+		&notification,
+		android,
+		ios,
+		web,
+	)
+	if err != nil {
+		return false, fmt.Errorf("can't send notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendNotification", err)
+
+	return sent, nil
 }
 
 func (r *mutationResolver) SendFCMByPhoneOrEmail(ctx context.Context, phoneNumber *string, email *string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput) (bool, error) {
@@ -71,23 +90,68 @@ func (r *mutationResolver) ProcessEvent(ctx context.Context, flavour feedlib.Fla
 }
 
 func (r *mutationResolver) SimpleEmail(ctx context.Context, subject string, text string, to []string) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	id, err := r.interactor.Email.SimpleEmail(ctx, subject, text, to)
+	if err != nil {
+		return "", fmt.Errorf("can't send email: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "simpleEmail", err)
+
+	return id, nil
 }
 
 func (r *mutationResolver) VerifyOtp(ctx context.Context, msisdn string, otp string) (bool, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	verified, err := r.interactor.OTP.VerifyOTP(ctx, msisdn, otp)
+	if err != nil {
+		return false, fmt.Errorf("can't verify OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "verifyOTP", err)
+
+	return verified, nil
 }
 
 func (r *mutationResolver) VerifyEmailOtp(ctx context.Context, email string, otp string) (bool, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	verified, err := r.interactor.OTP.VerifyEmailOTP(ctx, email, otp)
+	if err != nil {
+		return false, fmt.Errorf("can't verify email OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "verifyEmailOTP", err)
+
+	return verified, nil
 }
 
 func (r *mutationResolver) Send(ctx context.Context, to string, message string) (*dto.SendMessageResponse, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	resp, err := r.interactor.UsecaseNotification.SendSMS(ctx, to, message, enumutils.SenderIDBewell, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't send SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "send", err)
+
+	return resp, nil
 }
 
 func (r *mutationResolver) SendToMany(ctx context.Context, message string, to []string) (*dto.SendMessageResponse, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	resp, err := r.interactor.UsecaseNotification.SendSMSToMany(ctx, message, to, enumutils.SenderIDBewell, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't send SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendToMany", err)
+
+	return resp, nil
 }
 
 func (r *mutationResolver) RecordNPSResponse(ctx context.Context, input dto.NPSInput) (bool, error) {
@@ -99,7 +163,16 @@ func (r *mutationResolver) Upload(ctx context.Context, input profileutils.Upload
 }
 
 func (r *mutationResolver) PhoneNumberVerificationCode(ctx context.Context, to string, code string, marketingMessage string) (bool, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	sent, err := r.interactor.WhatsApp.PhoneNumberVerificationCode(ctx, to, code, marketingMessage)
+	if err != nil {
+		return false, fmt.Errorf("can't send phone number verification code: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "phoneNumberVerificationCode", err)
+
+	return sent, nil
 }
 
 func (r *queryResolver) GetLibraryContent(ctx context.Context) ([]*domain.GhostCMSPost, error) {
@@ -151,19 +224,55 @@ func (r *queryResolver) UnreadPersistentItems(ctx context.Context, flavour feedl
 }
 
 func (r *queryResolver) GenerateOtp(ctx context.Context, msisdn string, appID *string) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	code, err := r.interactor.OTP.GenerateAndSendOTP(ctx, msisdn, appID)
+	if err != nil {
+		return "", fmt.Errorf("can't generate OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "generateOTP", err)
+
+	return code, nil
 }
 
 func (r *queryResolver) GenerateAndEmailOtp(ctx context.Context, msisdn string, email *string, appID *string) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	code, err := r.interactor.OTP.GenerateAndEmailOTP(ctx, msisdn, email, appID)
+	if err != nil {
+		return "", fmt.Errorf("can't generate and email OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "generateAndEmailOTP", err)
+
+	return code, nil
 }
 
 func (r *queryResolver) GenerateRetryOtp(ctx context.Context, msisdn string, retryStep int, appID *string) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	code, err := r.interactor.OTP.GenerateRetryOTP(ctx, msisdn, retryStep, appID, nil)
+	if err != nil {
+		return "", fmt.Errorf("can't generate retry OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "generateRetryOTP", err)
+
+	return code, nil
 }
 
 func (r *queryResolver) EmailVerificationOtp(ctx context.Context, email string) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+	startTime := time.Now()
+
+	code, err := r.interactor.OTP.EmailVerificationOTP(ctx, email)
+	if err != nil {
+		return "", fmt.Errorf("can't generate email verification OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "emailVerificationOTP", err)
+
+	return code, nil
 }
 
 func (r *queryResolver) ListNPSResponse(ctx context.Context) ([]*dto.NPSResponse, error) {
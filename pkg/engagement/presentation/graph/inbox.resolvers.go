@@ -0,0 +1,83 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get logged in user UID")
+	}
+	count, err := r.interactor.Inbox.MarkNotificationsRead(ctx, uid, ids)
+	if err != nil {
+		return 0, fmt.Errorf("can't mark notifications as read: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "markNotificationsRead", err)
+
+	return count, nil
+}
+
+func (r *mutationResolver) MarkNotificationsUnread(ctx context.Context, ids []string) (int, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get logged in user UID")
+	}
+	count, err := r.interactor.Inbox.MarkNotificationsUnread(ctx, uid, ids)
+	if err != nil {
+		return 0, fmt.Errorf("can't mark notifications as unread: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "markNotificationsUnread", err)
+
+	return count, nil
+}
+
+func (r *mutationResolver) MarkAllNotificationsRead(ctx context.Context) (int, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get logged in user UID")
+	}
+	count, err := r.interactor.Inbox.MarkAllNotificationsRead(ctx, uid)
+	if err != nil {
+		return 0, fmt.Errorf("can't mark all notifications as read: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "markAllNotificationsRead", err)
+
+	return count, nil
+}
+
+func (r *queryResolver) MyNotifications(ctx context.Context, channel *feedlib.Channel, read *bool, pagination *firebasetools.PaginationInput) (*dto.InboxConnection, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	inbox, err := r.interactor.Inbox.GetInbox(ctx, uid, channel, read, pagination)
+	if err != nil {
+		return nil, fmt.Errorf("can't get notifications: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "myNotifications", err)
+
+	return inbox, nil
+}
@@ -6,15 +6,8 @@ package graph
 import (
 	"context"
 	"fmt"
-
-	"github.com/savannahghi/engagement-service/pkg/engagement/presentation/graph/generated"
 )
 
 func (r *mutationResolver) TestFeature(ctx context.Context) (bool, error) {
 	panic(fmt.Errorf("not implemented"))
 }
-
-// Mutation returns generated.MutationResolver implementation.
-func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
-
-type mutationResolver struct{ *Resolver }
@@ -0,0 +1,26 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *queryResolver) OtpApps(ctx context.Context) ([]*domain.OTPApp, error) {
+	startTime := time.Now()
+
+	apps, err := r.interactor.OTP.ListOTPApps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list OTP apps: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "otpApps", err)
+
+	return apps, nil
+}
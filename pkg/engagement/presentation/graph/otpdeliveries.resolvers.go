@@ -0,0 +1,39 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *queryResolver) OtpDeliveries(ctx context.Context, identifier string) ([]*domain.OTPDelivery, error) {
+	startTime := time.Now()
+
+	deliveries, err := r.interactor.OTP.ListOTPDeliveries(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("can't list OTP deliveries: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "otpDeliveries", err)
+
+	return deliveries, nil
+}
+
+func (r *queryResolver) GenerateEmailRetryOtp(ctx context.Context, msisdn string, retryStep int, email string, appID *string) (string, error) {
+	startTime := time.Now()
+
+	code, err := r.interactor.OTP.GenerateRetryOTP(ctx, msisdn, retryStep, appID, &email)
+	if err != nil {
+		return "", fmt.Errorf("can't generate retry OTP: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "generateEmailRetryOTP", err)
+
+	return code, nil
+}
@@ -0,0 +1,54 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SetRateLimit(ctx context.Context, input dto.RateLimitPolicyInput) (*domain.RateLimitPolicy, error) {
+	startTime := time.Now()
+
+	policy, err := r.interactor.RateLimits.SetRateLimit(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't set rate limit: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setRateLimit", err)
+
+	return policy, nil
+}
+
+func (r *mutationResolver) ResetRateLimit(ctx context.Context, flavour feedlib.Flavour, channel feedlib.Channel) (bool, error) {
+	startTime := time.Now()
+
+	reset, err := r.interactor.RateLimits.ResetRateLimit(ctx, flavour, channel)
+	if err != nil {
+		return false, fmt.Errorf("can't reset rate limit: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "resetRateLimit", err)
+
+	return reset, nil
+}
+
+func (r *queryResolver) RateLimits(ctx context.Context) ([]*domain.RateLimitPolicy, error) {
+	startTime := time.Now()
+
+	policies, err := r.interactor.RateLimits.ListRateLimits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list rate limits: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "rateLimits", err)
+
+	return policies, nil
+}
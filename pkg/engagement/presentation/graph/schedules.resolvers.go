@@ -0,0 +1,135 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) ScheduleNotification(ctx context.Context, input dto.ScheduledNotificationInput) (*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	notification, err := r.interactor.Schedules.ScheduleNotification(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't schedule notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "scheduleNotification", err)
+
+	return notification, nil
+}
+
+func (r *mutationResolver) PauseScheduledNotification(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	notification, err := r.interactor.Schedules.PauseScheduledNotification(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't pause scheduled notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "pauseScheduledNotification", err)
+
+	return notification, nil
+}
+
+func (r *mutationResolver) ResumeScheduledNotification(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	notification, err := r.interactor.Schedules.ResumeScheduledNotification(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't resume scheduled notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "resumeScheduledNotification", err)
+
+	return notification, nil
+}
+
+func (r *mutationResolver) CancelScheduledNotification(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	notification, err := r.interactor.Schedules.CancelScheduledNotification(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't cancel scheduled notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "cancelScheduledNotification", err)
+
+	return notification, nil
+}
+
+func (r *mutationResolver) SaveRecipientGroup(ctx context.Context, input dto.RecipientGroupInput) (*domain.RecipientGroup, error) {
+	startTime := time.Now()
+
+	group, err := r.interactor.RecipientGroups.SaveRecipientGroup(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't save recipient group: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "saveRecipientGroup", err)
+
+	return group, nil
+}
+
+func (r *mutationResolver) DeleteRecipientGroup(ctx context.Context, name string) (bool, error) {
+	startTime := time.Now()
+
+	deleted, err := r.interactor.RecipientGroups.DeleteRecipientGroup(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("can't delete recipient group: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteRecipientGroup", err)
+
+	return deleted, nil
+}
+
+func (r *queryResolver) ScheduledNotifications(ctx context.Context, status *domain.ScheduleStatus) ([]*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	notifications, err := r.interactor.Schedules.ListScheduledNotifications(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("can't list scheduled notifications: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "scheduledNotifications", err)
+
+	return notifications, nil
+}
+
+func (r *queryResolver) ScheduledNotification(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	startTime := time.Now()
+
+	notification, err := r.interactor.Schedules.GetScheduledNotification(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get scheduled notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "scheduledNotification", err)
+
+	return notification, nil
+}
+
+func (r *queryResolver) RecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error) {
+	startTime := time.Now()
+
+	groups, err := r.interactor.RecipientGroups.ListRecipientGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list recipient groups: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "recipientGroups", err)
+
+	return groups, nil
+}
@@ -0,0 +1,41 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SendSms(ctx context.Context, to string, message string, sender enumutils.SenderID, flavour *feedlib.Flavour) (*dto.SendMessageResponse, error) {
+	startTime := time.Now()
+
+	resp, err := r.interactor.UsecaseNotification.SendSMS(ctx, to, message, sender, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("can't send SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendSMS", err)
+
+	return resp, nil
+}
+
+func (r *mutationResolver) SendSMSToMany(ctx context.Context, message string, to []string, sender enumutils.SenderID, flavour *feedlib.Flavour) (*dto.SendMessageResponse, error) {
+	startTime := time.Now()
+
+	resp, err := r.interactor.UsecaseNotification.SendSMSToMany(ctx, message, to, sender, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("can't send SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendSMSToMany", err)
+
+	return resp, nil
+}
@@ -0,0 +1,57 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) StartSMSConversation(ctx context.Context, input dto.SMSConversationInput) (*domain.SMSConversation, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	conversation, err := r.interactor.SMSCallbacks.StartSMSConversation(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't start SMS conversation: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "startSMSConversation", err)
+
+	return conversation, nil
+}
+
+func (r *queryResolver) SmsDeliveryReport(ctx context.Context, messageID string) (*domain.SMSDeliveryReport, error) {
+	startTime := time.Now()
+
+	report, err := r.interactor.SMSCallbacks.GetSMSDeliveryReport(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get SMS delivery report: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "smsDeliveryReport", err)
+
+	return report, nil
+}
+
+func (r *queryResolver) SmsChannelPreference(ctx context.Context, phone string) (*domain.ChannelPreference, error) {
+	startTime := time.Now()
+
+	preference, err := r.interactor.SMSCallbacks.GetSMSChannelPreference(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("can't get SMS channel preference: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "smsChannelPreference", err)
+
+	return preference, nil
+}
@@ -0,0 +1,106 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SetSMSRate(ctx context.Context, input dto.SMSRateInput) (*domain.SMSRate, error) {
+	startTime := time.Now()
+
+	rate, err := r.interactor.SMSCosts.SetSMSRate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't set SMS rate: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setSMSRate", err)
+
+	return rate, nil
+}
+
+func (r *mutationResolver) DeleteSMSRate(ctx context.Context, prefix string) (bool, error) {
+	startTime := time.Now()
+
+	deleted, err := r.interactor.SMSCosts.DeleteSMSRate(ctx, prefix)
+	if err != nil {
+		return false, fmt.Errorf("can't delete SMS rate: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteSMSRate", err)
+
+	return deleted, nil
+}
+
+func (r *mutationResolver) SetSMSBudget(ctx context.Context, input dto.SMSBudgetInput) (*domain.SMSBudget, error) {
+	startTime := time.Now()
+
+	budget, err := r.interactor.SMSCosts.SetSMSBudget(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't set SMS budget: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setSMSBudget", err)
+
+	return budget, nil
+}
+
+func (r *mutationResolver) DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) (bool, error) {
+	startTime := time.Now()
+
+	deleted, err := r.interactor.SMSCosts.DeleteSMSBudget(ctx, flavour)
+	if err != nil {
+		return false, fmt.Errorf("can't delete SMS budget: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteSMSBudget", err)
+
+	return deleted, nil
+}
+
+func (r *queryResolver) EstimateSms(ctx context.Context, message string, to []string, flavour *feedlib.Flavour) (*domain.SMSEstimate, error) {
+	startTime := time.Now()
+
+	estimate, err := r.interactor.SMSCosts.EstimateSMS(ctx, message, to, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("can't estimate SMS: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "estimateSMS", err)
+
+	return estimate, nil
+}
+
+func (r *queryResolver) SmsRates(ctx context.Context) ([]*domain.SMSRate, error) {
+	startTime := time.Now()
+
+	rates, err := r.interactor.SMSCosts.ListSMSRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list SMS rates: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "smsRates", err)
+
+	return rates, nil
+}
+
+func (r *queryResolver) SmsBudgets(ctx context.Context) ([]*domain.SMSBudget, error) {
+	startTime := time.Now()
+
+	budgets, err := r.interactor.SMSCosts.ListSMSBudgets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list SMS budgets: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "smsBudgets", err)
+
+	return budgets, nil
+}
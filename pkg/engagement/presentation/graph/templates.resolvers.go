@@ -0,0 +1,107 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) CreateNotificationTemplate(ctx context.Context, input dto.NotificationTemplateInput) (*domain.NotificationTemplate, error) {
+	startTime := time.Now()
+
+	template, err := r.interactor.Templates.CreateTemplate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't create notification template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "createNotificationTemplate", err)
+
+	return template, nil
+}
+
+func (r *mutationResolver) UpdateNotificationTemplate(ctx context.Context, input dto.NotificationTemplateInput) (*domain.NotificationTemplate, error) {
+	startTime := time.Now()
+
+	template, err := r.interactor.Templates.UpdateTemplate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't update notification template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "updateNotificationTemplate", err)
+
+	return template, nil
+}
+
+func (r *mutationResolver) DeleteNotificationTemplate(ctx context.Context, name string) (bool, error) {
+	startTime := time.Now()
+
+	deleted, err := r.interactor.Templates.DeleteTemplate(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("can't delete notification template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteNotificationTemplate", err)
+
+	return deleted, nil
+}
+
+func (r *mutationResolver) SendTemplatedMessage(ctx context.Context, input dto.TemplatedMessageInput) (bool, error) {
+	startTime := time.Now()
+
+	err := r.interactor.UsecaseNotification.SendTemplatedMessage(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("can't send templated message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendTemplatedMessage", err)
+
+	return true, nil
+}
+
+func (r *queryResolver) NotificationTemplates(ctx context.Context, channel *feedlib.Channel) ([]*domain.NotificationTemplate, error) {
+	startTime := time.Now()
+
+	templates, err := r.interactor.Templates.ListTemplates(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("can't list notification templates: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "notificationTemplates", err)
+
+	return templates, nil
+}
+
+func (r *queryResolver) NotificationTemplate(ctx context.Context, name string) (*domain.NotificationTemplate, error) {
+	startTime := time.Now()
+
+	template, err := r.interactor.Templates.GetTemplate(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get notification template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "notificationTemplate", err)
+
+	return template, nil
+}
+
+func (r *queryResolver) PreviewTemplate(ctx context.Context, name string, language *enumutils.Language, variables map[string]interface{}) (*dto.RenderedTemplate, error) {
+	startTime := time.Now()
+
+	rendered, err := r.interactor.Templates.RenderTemplate(ctx, name, language, variables)
+	if err != nil {
+		return nil, fmt.Errorf("can't preview notification template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "previewTemplate", err)
+
+	return rendered, nil
+}
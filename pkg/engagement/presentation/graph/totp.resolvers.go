@@ -0,0 +1,62 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) EnrollTotp(ctx context.Context, msisdn string, otp string) (*dto.TOTPProvisioning, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+
+	provisioning, err := r.interactor.OTP.EnrollTOTP(ctx, uid, msisdn, otp)
+	if err != nil {
+		return nil, fmt.Errorf("can't enroll authenticator app: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "enrollTOTP", err)
+
+	return provisioning, nil
+}
+
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, msisdn string, otp string) ([]string, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+
+	recoveryCodes, err := r.interactor.OTP.ConfirmTOTP(ctx, uid, msisdn, otp)
+	if err != nil {
+		return nil, fmt.Errorf("can't confirm authenticator app: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "confirmTOTP", err)
+
+	return recoveryCodes, nil
+}
+
+func (r *mutationResolver) DisableTotp(ctx context.Context, msisdn string, otp string) (bool, error) {
+	startTime := time.Now()
+
+	disabled, err := r.interactor.OTP.DisableTOTP(ctx, msisdn, otp)
+	if err != nil {
+		return false, fmt.Errorf("can't disable authenticator app: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "disableTOTP", err)
+
+	return disabled, nil
+}
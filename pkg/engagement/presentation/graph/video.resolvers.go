@@ -0,0 +1,117 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) ScheduleVideoRoom(ctx context.Context, input dto.VideoRoomInput) (*domain.VideoRoom, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	room, err := r.interactor.VideoRooms.ScheduleVideoRoom(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't schedule video room: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "scheduleVideoRoom", err)
+
+	return room, nil
+}
+
+func (r *mutationResolver) UpdateVideoRoom(ctx context.Context, id string, input dto.VideoRoomInput) (*domain.VideoRoom, error) {
+	startTime := time.Now()
+
+	room, err := r.interactor.VideoRooms.UpdateVideoRoom(ctx, id, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't update video room: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "updateVideoRoom", err)
+
+	return room, nil
+}
+
+func (r *mutationResolver) EndVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	room, err := r.interactor.VideoRooms.EndVideoRoom(ctx, uid, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't end video room: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "endVideoRoom", err)
+
+	return room, nil
+}
+
+func (r *mutationResolver) VideoRoomAccessToken(ctx context.Context, roomID string) (*dto.VideoRoomToken, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	token, err := r.interactor.VideoRooms.VideoRoomAccessToken(ctx, uid, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get video room access token: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "videoRoomAccessToken", err)
+
+	return token, nil
+}
+
+func (r *queryResolver) VideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error) {
+	startTime := time.Now()
+
+	room, err := r.interactor.VideoRooms.GetVideoRoom(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't get video room: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "videoRoom", err)
+
+	return room, nil
+}
+
+func (r *queryResolver) ActiveVideoRooms(ctx context.Context) ([]*domain.VideoRoom, error) {
+	startTime := time.Now()
+
+	rooms, err := r.interactor.VideoRooms.ListActiveVideoRooms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list active video rooms: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "activeVideoRooms", err)
+
+	return rooms, nil
+}
+
+func (r *queryResolver) VideoRoomParticipants(ctx context.Context, roomID string) ([]*domain.VideoParticipant, error) {
+	startTime := time.Now()
+
+	participants, err := r.interactor.VideoRooms.ListVideoRoomParticipants(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("can't list video room participants: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "videoRoomParticipants", err)
+
+	return participants, nil
+}
@@ -0,0 +1,52 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SendWhatsAppTemplate(ctx context.Context, input dto.WhatsAppTemplateInput) (string, error) {
+	startTime := time.Now()
+
+	id, err := r.interactor.WhatsApp.SendWhatsAppTemplate(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("can't send WhatsApp template: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendWhatsAppTemplate", err)
+
+	return id, nil
+}
+
+func (r *mutationResolver) SendWhatsAppText(ctx context.Context, to string, text string) (string, error) {
+	startTime := time.Now()
+
+	id, err := r.interactor.WhatsApp.SendWhatsAppText(ctx, to, text)
+	if err != nil {
+		return "", fmt.Errorf("can't send WhatsApp message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendWhatsAppText", err)
+
+	return id, nil
+}
+
+func (r *mutationResolver) SendWhatsAppMedia(ctx context.Context, input dto.WhatsAppMediaInput) (string, error) {
+	startTime := time.Now()
+
+	id, err := r.interactor.WhatsApp.SendWhatsAppMedia(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("can't send WhatsApp media: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendWhatsAppMedia", err)
+
+	return id, nil
+}
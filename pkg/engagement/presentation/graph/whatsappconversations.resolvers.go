@@ -0,0 +1,91 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) StartWhatsAppConversation(ctx context.Context, input dto.WhatsAppConversationInput) (*domain.WhatsAppConversation, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	conversation, err := r.interactor.WhatsAppConversations.StartWhatsAppConversation(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't start WhatsApp conversation: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "startWhatsAppConversation", err)
+
+	return conversation, nil
+}
+
+func (r *mutationResolver) SendWhatsAppNudge(ctx context.Context, input dto.WhatsAppNudgeInput) (*domain.WhatsAppNudge, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	nudge, err := r.interactor.WhatsAppConversations.SendWhatsAppNudge(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't send WhatsApp nudge: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendWhatsAppNudge", err)
+
+	return nudge, nil
+}
+
+func (r *mutationResolver) ResolveWhatsAppSupportMessage(ctx context.Context, id string) (*domain.InboundWhatsApp, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	message, err := r.interactor.WhatsAppConversations.ResolveWhatsAppSupportMessage(ctx, uid, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve WhatsApp support message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "resolveWhatsAppSupportMessage", err)
+
+	return message, nil
+}
+
+func (r *queryResolver) WhatsAppSession(ctx context.Context, phone string) (*domain.WhatsAppSession, error) {
+	startTime := time.Now()
+
+	session, err := r.interactor.WhatsAppConversations.GetWhatsAppSession(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("can't get WhatsApp session: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "whatsAppSession", err)
+
+	return session, nil
+}
+
+func (r *queryResolver) WhatsAppSupportQueue(ctx context.Context, limit int) ([]*domain.InboundWhatsApp, error) {
+	startTime := time.Now()
+
+	messages, err := r.interactor.WhatsAppConversations.WhatsAppSupportQueue(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get WhatsApp support queue: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "whatsAppSupportQueue", err)
+
+	return messages, nil
+}
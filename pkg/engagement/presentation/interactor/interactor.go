@@ -8,10 +8,29 @@ import (
 
 // Interactor represents an assemble of all use cases into a single object that can be instantiated anywhere
 type Interactor struct {
-	OpenSourceInfra     infrastructure.Interactor
-	OpenSourceUsecases  sharelib.Interactor
-	UsecaseNotification usecases.NotificationUsecases
-	Feed                usecases.FeedUsecases
+	OpenSourceInfra       infrastructure.Interactor
+	OpenSourceUsecases    sharelib.Interactor
+	UsecaseNotification   usecases.NotificationUsecases
+	Feed                  usecases.FeedUsecases
+	Templates             usecases.TemplateUsecases
+	Devices               usecases.DeviceUsecases
+	Inbox                 usecases.InboxUsecases
+	Schedules             usecases.ScheduleUsecases
+	RecipientGroups       usecases.RecipientGroupUsecases
+	RateLimits            usecases.RateLimitUsecases
+	OTP                   usecases.OTPUsecases
+	EmailVerification     usecases.EmailVerificationUsecases
+	BulkSMS               usecases.BulkSMSUsecases
+	SMSCallbacks          usecases.SMSCallbackUsecases
+	SMSCosts              usecases.SMSCostUsecases
+	Email                 usecases.EmailUsecases
+	EmailSuppressions     usecases.EmailSuppressionUsecases
+	EmailConversations    usecases.EmailConversationUsecases
+	WhatsApp              usecases.WhatsAppUsecases
+	WhatsAppConversations usecases.WhatsAppConversationUsecases
+	VideoRooms            usecases.VideoRoomUsecases
+	Appointments          usecases.AppointmentUsecases
+	Availability          usecases.AvailabilityUsecases
 }
 
 // NewEngagementInteractor returns a new engagement interactor
@@ -20,12 +39,50 @@ func NewEngagementInteractor(
 	openSourceUsecases sharelib.Interactor,
 	notification usecases.NotificationUsecases,
 	feed usecases.FeedUsecases,
+	templates usecases.TemplateUsecases,
+	devices usecases.DeviceUsecases,
+	inbox usecases.InboxUsecases,
+	schedules usecases.ScheduleUsecases,
+	recipientGroups usecases.RecipientGroupUsecases,
+	rateLimits usecases.RateLimitUsecases,
+	otp usecases.OTPUsecases,
+	emailVerification usecases.EmailVerificationUsecases,
+	bulkSMS usecases.BulkSMSUsecases,
+	smsCallbacks usecases.SMSCallbackUsecases,
+	smsCosts usecases.SMSCostUsecases,
+	email usecases.EmailUsecases,
+	emailSuppressions usecases.EmailSuppressionUsecases,
+	emailConversations usecases.EmailConversationUsecases,
+	whatsApp usecases.WhatsAppUsecases,
+	whatsAppConversations usecases.WhatsAppConversationUsecases,
+	videoRooms usecases.VideoRoomUsecases,
+	appointments usecases.AppointmentUsecases,
+	availability usecases.AvailabilityUsecases,
 
 ) (*Interactor, error) {
 	return &Interactor{
-		OpenSourceInfra:     openSourceInfra,
-		OpenSourceUsecases:  openSourceUsecases,
-		UsecaseNotification: notification,
-		Feed:                feed,
+		OpenSourceInfra:       openSourceInfra,
+		OpenSourceUsecases:    openSourceUsecases,
+		UsecaseNotification:   notification,
+		Feed:                  feed,
+		Templates:             templates,
+		Devices:               devices,
+		Inbox:                 inbox,
+		Schedules:             schedules,
+		RecipientGroups:       recipientGroups,
+		RateLimits:            rateLimits,
+		OTP:                   otp,
+		EmailVerification:     emailVerification,
+		BulkSMS:               bulkSMS,
+		SMSCallbacks:          smsCallbacks,
+		SMSCosts:              smsCosts,
+		Email:                 email,
+		EmailSuppressions:     emailSuppressions,
+		EmailConversations:    emailConversations,
+		WhatsApp:              whatsApp,
+		WhatsAppConversations: whatsAppConversations,
+		VideoRooms:            videoRooms,
+		Appointments:          appointments,
+		Availability:          availability,
 	}, nil
 }
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/serverutils"
+)
+
+// AppointmentICSHandler serves an appointment as an iCalendar document from
+// a signed link, so that it can be added to any calendar app
+func (p PresentationHandlersImpl) AppointmentICSHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	id := query.Get("id")
+	err := p.appointments.VerifyAppointmentICSSignature(id, query.Get("expires"), query.Get("signature"))
+	if errors.Is(err, exceptions.ErrInvalidAppointmentICSSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return
+	}
+
+	document, err := p.appointments.AppointmentICS(r.Context(), id)
+	if err != nil {
+		log.Printf("unable to get the calendar of appointment %s: %v", id, err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="appointment.ics"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document); err != nil {
+		log.Printf("unable to write the calendar of appointment %s: %v", id, err)
+	}
+}
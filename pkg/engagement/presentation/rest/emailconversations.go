@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/serverutils"
+)
+
+const (
+	// maxInboundEmailSize is the largest form that the email provider can
+	// post. It allows for the base64 encoding of the provider's 25MB
+	// message limit.
+	maxInboundEmailSize = 40 << 20
+
+	// maxInboundEmailMemory is how much of the form is kept in memory. The
+	// rest is written to temporary files.
+	maxInboundEmailMemory = 8 << 20
+)
+
+// InboundEmailHandler receives the emails that the email provider posts as a
+// form with the raw MIME email in its `body-mime` field. The form may be URL
+// encoded or multipart.
+func (p PresentationHandlersImpl) InboundEmailHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundEmailSize)
+	err := r.ParseMultipartForm(maxInboundEmailMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	if r.MultipartForm != nil {
+		defer func() {
+			if err := r.MultipartForm.RemoveAll(); err != nil {
+				log.Printf("unable to remove inbound email files: %v", err)
+			}
+		}()
+	}
+
+	err = p.emailConversations.VerifyInboundEmailSignature(
+		r.PostForm.Get("timestamp"),
+		r.PostForm.Get("token"),
+		r.PostForm.Get("signature"),
+	)
+	if errors.Is(err, exceptions.ErrInvalidEmailWebhookSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return
+	}
+
+	_, err = p.emailConversations.HandleInboundEmail(
+		r.Context(),
+		r.PostForm.Get("recipient"),
+		[]byte(r.PostForm.Get("body-mime")),
+	)
+	if err != nil {
+		log.Printf("unable to handle inbound email: %v", err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+// emailWebhook is the body of the requests that Mailgun posts to webhooks
+type emailWebhook struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		ID        string  `json:"id"`
+		Event     string  `json:"event"`
+		Severity  string  `json:"severity"`
+		Recipient string  `json:"recipient"`
+		Reason    string  `json:"reason"`
+		Timestamp float64 `json:"timestamp"`
+		Message   struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+		DeliveryStatus struct {
+			Code        int    `json:"code"`
+			Message     string `json:"message"`
+			Description string `json:"description"`
+		} `json:"delivery-status"`
+	} `json:"event-data"`
+}
+
+// EmailBounceHandler receives the delivery failures that the email provider
+// posts. Permanent failures suppress the recipient.
+func (p PresentationHandlersImpl) EmailBounceHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleEmailWebhook(w, r, domain.EmailSuppressionReasonBounce)
+}
+
+// EmailComplaintHandler receives the spam complaints that the email provider
+// posts
+func (p PresentationHandlersImpl) EmailComplaintHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleEmailWebhook(w, r, domain.EmailSuppressionReasonComplaint)
+}
+
+// EmailUnsubscribeHandler receives the unsubscribes that the email provider
+// posts
+func (p PresentationHandlersImpl) EmailUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleEmailWebhook(w, r, domain.EmailSuppressionReasonUnsubscribe)
+}
+
+func (p PresentationHandlersImpl) handleEmailWebhook(
+	w http.ResponseWriter,
+	r *http.Request,
+	reason domain.EmailSuppressionReason,
+) {
+	webhook := emailWebhook{}
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	err := p.emailSuppressions.VerifyEmailWebhookSignature(
+		webhook.Signature.Timestamp,
+		webhook.Signature.Token,
+		webhook.Signature.Signature,
+	)
+	if errors.Is(err, exceptions.ErrInvalidEmailWebhookSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return
+	}
+
+	data := webhook.EventData
+	description := data.DeliveryStatus.Description
+	if description == "" {
+		description = data.DeliveryStatus.Message
+	}
+	if description == "" {
+		description = data.Reason
+	}
+	seconds, fraction := math.Modf(data.Timestamp)
+	_, err = p.emailSuppressions.HandleEmailEvent(r.Context(), reason, &domain.EmailEvent{
+		ID:          data.ID,
+		Event:       data.Event,
+		Severity:    data.Severity,
+		Recipient:   data.Recipient,
+		MessageID:   data.Message.Headers.MessageID,
+		Description: description,
+		Timestamp:   time.Unix(int64(seconds), int64(fraction*1e9)),
+	})
+	if err != nil {
+		log.Printf("unable to handle email %s webhook: %v", data.Event, err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
+
+// emailUnsubscribePage is shown when an unsubscribe link is opened and after
+// the address has been unsubscribed
+var emailUnsubscribePage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Be.Well email preferences</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Confirm}}<form method="POST">
+<button type="submit">Unsubscribe</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+type emailUnsubscribePageData struct {
+	Title   string
+	Message string
+	Confirm bool
+}
+
+// UnsubscribeEmailLink handles the unsubscribe links of emails.
+//
+// Email clients that support one-click unsubscribe POST to the link. Opening
+// the link shows a page that asks the user to confirm so that email scanners
+// that open links don't unsubscribe the address.
+func (p PresentationHandlersImpl) UnsubscribeEmailLink(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("email")
+	err := p.emailSuppressions.VerifyEmailUnsubscribeSignature(address, r.URL.Query().Get("signature"))
+	if err != nil {
+		writeEmailUnsubscribePage(w, http.StatusBadRequest, emailUnsubscribePageData{
+			Title:   "Invalid link",
+			Message: "This unsubscribe link is not valid.",
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeEmailUnsubscribePage(w, http.StatusOK, emailUnsubscribePageData{
+			Title:   "Unsubscribe",
+			Message: "Stop sending Be.Well notification emails to " + address + "?",
+			Confirm: true,
+		})
+		return
+	}
+
+	if _, err := p.emailSuppressions.UnsubscribeEmail(r.Context(), address); err != nil {
+		log.Printf("unable to unsubscribe %s: %v", address, err)
+		writeEmailUnsubscribePage(w, http.StatusInternalServerError, emailUnsubscribePageData{
+			Title:   "Something went wrong",
+			Message: "We could not unsubscribe you. Please try again.",
+		})
+		return
+	}
+	writeEmailUnsubscribePage(w, http.StatusOK, emailUnsubscribePageData{
+		Title:   "Unsubscribed",
+		Message: address + " will no longer be sent notification emails. You can close this page.",
+	})
+}
+
+func writeEmailUnsubscribePage(w http.ResponseWriter, status int, data emailUnsubscribePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := emailUnsubscribePage.Execute(w, data); err != nil {
+		log.Printf("unable to write email unsubscribe page: %v", err)
+	}
+}
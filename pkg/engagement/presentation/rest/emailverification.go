@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+)
+
+// emailVerificationPage is shown when a verification link is opened and
+// after it has been consumed
+var emailVerificationPage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Be.Well email verification</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Token}}<form method="POST">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Verify my email address</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+type emailVerificationPageData struct {
+	Title   string
+	Message string
+	Token   string
+}
+
+// VerifyEmailLink consumes email verification links.
+//
+// Opening a link shows a page that asks the user to confirm, and only the
+// confirmation consumes the link. This stops email scanners that open links
+// ahead of the user from using them up.
+func (p PresentationHandlersImpl) VerifyEmailLink(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeEmailVerificationPage(w, http.StatusBadRequest, emailVerificationPageData{
+			Title:   "Invalid link",
+			Message: "This verification link is not valid.",
+		})
+		return
+	}
+	token := r.Form.Get("token")
+
+	if r.Method != http.MethodPost {
+		writeEmailVerificationPage(w, http.StatusOK, emailVerificationPageData{
+			Title:   "Verify your email address",
+			Message: "Confirm that you want to verify your email address.",
+			Token:   token,
+		})
+		return
+	}
+
+	link, err := p.emailVerification.VerifyEmailLink(r.Context(), token)
+	if errors.Is(err, exceptions.ErrInvalidEmailVerificationLink) {
+		writeEmailVerificationPage(w, http.StatusBadRequest, emailVerificationPageData{
+			Title:   "Invalid link",
+			Message: "This verification link has expired or has already been used. Please request a new one.",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("unable to verify email link: %v", err)
+		writeEmailVerificationPage(w, http.StatusInternalServerError, emailVerificationPageData{
+			Title:   "Something went wrong",
+			Message: "We could not verify your email address. Please try again.",
+		})
+		return
+	}
+
+	writeEmailVerificationPage(w, http.StatusOK, emailVerificationPageData{
+		Title:   "Email verified",
+		Message: link.Email + " has been verified. You can close this page.",
+	})
+}
+
+func writeEmailVerificationPage(w http.ResponseWriter, status int, data emailVerificationPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := emailVerificationPage.Execute(w, data); err != nil {
+		log.Printf("unable to write email verification page: %v", err)
+	}
+}
@@ -1 +1,160 @@
 package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"firebase.google.com/go/auth"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	libRest "github.com/savannahghi/engagementcore/pkg/engagement/presentation/rest"
+	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/pubsubtools"
+	"github.com/savannahghi/serverutils"
+)
+
+// PresentationHandlers represents the REST handlers owned by this service
+type PresentationHandlers interface {
+	GoogleCloudPubSubHandler(w http.ResponseWriter, r *http.Request)
+
+	VerifyEmailLink(w http.ResponseWriter, r *http.Request)
+
+	SMSDeliveryReportHandler(w http.ResponseWriter, r *http.Request)
+
+	InboundSMSHandler(w http.ResponseWriter, r *http.Request)
+
+	EmailBounceHandler(w http.ResponseWriter, r *http.Request)
+
+	EmailComplaintHandler(w http.ResponseWriter, r *http.Request)
+
+	EmailUnsubscribeHandler(w http.ResponseWriter, r *http.Request)
+
+	UnsubscribeEmailLink(w http.ResponseWriter, r *http.Request)
+
+	InboundEmailHandler(w http.ResponseWriter, r *http.Request)
+
+	InboundWhatsAppHandler(w http.ResponseWriter, r *http.Request)
+
+	VideoRoomCallbackHandler(w http.ResponseWriter, r *http.Request)
+
+	AppointmentICSHandler(w http.ResponseWriter, r *http.Request)
+
+	SaveOTPAppHandler(w http.ResponseWriter, r *http.Request)
+
+	DeleteOTPAppHandler(w http.ResponseWriter, r *http.Request)
+}
+
+// PresentationHandlersImpl represents the REST handlers implementation
+type PresentationHandlersImpl struct {
+	notification          usecases.NotificationUsecases
+	emailVerification     usecases.EmailVerificationUsecases
+	smsCallbacks          usecases.SMSCallbackUsecases
+	emailSuppressions     usecases.EmailSuppressionUsecases
+	emailConversations    usecases.EmailConversationUsecases
+	whatsAppConversations usecases.WhatsAppConversationUsecases
+	videoRooms            usecases.VideoRoomUsecases
+	appointments          usecases.AppointmentUsecases
+	otp                   usecases.OTPUsecases
+	lib                   libRest.PresentationHandlers
+}
+
+// NewPresentationHandlers initializes the REST handlers. Requests that this
+// service does not handle itself are passed on to the library's handlers.
+func NewPresentationHandlers(
+	notification usecases.NotificationUsecases,
+	emailVerification usecases.EmailVerificationUsecases,
+	smsCallbacks usecases.SMSCallbackUsecases,
+	emailSuppressions usecases.EmailSuppressionUsecases,
+	emailConversations usecases.EmailConversationUsecases,
+	whatsAppConversations usecases.WhatsAppConversationUsecases,
+	videoRooms usecases.VideoRoomUsecases,
+	appointments usecases.AppointmentUsecases,
+	otp usecases.OTPUsecases,
+	lib libRest.PresentationHandlers,
+) *PresentationHandlersImpl {
+	return &PresentationHandlersImpl{
+		notification:          notification,
+		emailVerification:     emailVerification,
+		smsCallbacks:          smsCallbacks,
+		emailSuppressions:     emailSuppressions,
+		emailConversations:    emailConversations,
+		whatsAppConversations: whatsAppConversations,
+		videoRooms:            videoRooms,
+		appointments:          appointments,
+		otp:                   otp,
+		lib:                   lib,
+	}
+}
+
+// GoogleCloudPubSubHandler receives push messages from Google Cloud Pub-Sub.
+//
+// Item publish messages are handled by this service so that their
+// notifications are rate limited, and nudge publish and resolve messages so
+// that their notifications are rendered from templates. Other topics are
+// handled by the library.
+func (p PresentationHandlersImpl) GoogleCloudPubSubHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	m, err := pubsubtools.VerifyPubSubJWTAndDecodePayload(w, r)
+	if err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	topicID, err := pubsubtools.GetPubSubTopic(m)
+	if err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	var handle func(ctx context.Context, m *pubsubtools.PubSubPayload) error
+	switch topicID {
+	case helpers.AddPubSubNamespace(common.ItemPublishTopic):
+		handle = p.notification.HandleItemPublish
+	case helpers.AddPubSubNamespace(common.NudgePublishTopic):
+		handle = p.notification.HandleNudgePublish
+	case helpers.AddPubSubNamespace(common.NudgeResolveTopic):
+		handle = p.notification.HandleNudgeResolve
+	default:
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		p.lib.GoogleCloudPubSubHandler(w, r)
+		return
+	}
+
+	var envelope libDto.NotificationEnvelope
+	err = json.Unmarshal(m.Message.Data, &envelope)
+	if err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	err = handle(addUIDToContext(r.Context(), envelope.UID), m)
+	if err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
+
+// addUIDToContext authenticates the context as the user that a pub sub
+// message was published for
+func addUIDToContext(ctx context.Context, uid string) context.Context {
+	return context.WithValue(
+		ctx,
+		firebasetools.AuthTokenContextKey,
+		&auth.Token{UID: uid},
+	)
+}
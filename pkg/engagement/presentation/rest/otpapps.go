@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/serverutils"
+)
+
+// SaveOTPAppHandler registers or updates the OTP settings of an app from the
+// JSON body. It is only served to other services.
+func (p PresentationHandlersImpl) SaveOTPAppHandler(w http.ResponseWriter, r *http.Request) {
+	var input dto.OTPAppInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	app, err := p.otp.SaveOTPApp(r.Context(), input)
+	if err != nil {
+		log.Printf("unable to save OTP app %s: %v", input.AppID, err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, app, http.StatusOK)
+}
+
+// DeleteOTPAppHandler removes the OTP settings of the app in the `appId`
+// query parameter. It is only served to other services.
+func (p PresentationHandlersImpl) DeleteOTPAppHandler(w http.ResponseWriter, r *http.Request) {
+	appID := r.URL.Query().Get("appId")
+	if _, err := p.otp.DeleteOTPApp(r.Context(), appID); err != nil {
+		log.Printf("unable to delete OTP app %s: %v", appID, err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
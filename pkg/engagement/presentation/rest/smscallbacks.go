@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/serverutils"
+)
+
+// SMSDeliveryReportHandler receives the delivery reports that the SMS
+// provider posts as form values
+func (p PresentationHandlersImpl) SMSDeliveryReportHandler(w http.ResponseWriter, r *http.Request) {
+	if !p.verifySMSCallback(w, r) {
+		return
+	}
+
+	retries, _ := strconv.Atoi(r.PostForm.Get("retryCount"))
+	err := p.smsCallbacks.HandleSMSDeliveryReport(r.Context(), &domain.SMSDeliveryReport{
+		MessageID:     r.PostForm.Get("id"),
+		Number:        r.PostForm.Get("phoneNumber"),
+		Status:        r.PostForm.Get("status"),
+		FailureReason: r.PostForm.Get("failureReason"),
+		NetworkCode:   r.PostForm.Get("networkCode"),
+		RetryCount:    retries,
+	})
+	if err != nil {
+		log.Printf("unable to handle SMS delivery report: %v", err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
+
+// InboundSMSHandler receives the messages that the SMS provider posts as form
+// values
+func (p PresentationHandlersImpl) InboundSMSHandler(w http.ResponseWriter, r *http.Request) {
+	if !p.verifySMSCallback(w, r) {
+		return
+	}
+
+	_, err := p.smsCallbacks.HandleInboundSMS(r.Context(), &domain.InboundSMS{
+		ID:   r.PostForm.Get("id"),
+		From: r.PostForm.Get("from"),
+		To:   r.PostForm.Get("to"),
+		Text: r.PostForm.Get("text"),
+	})
+	if err != nil {
+		log.Printf("unable to handle received SMS: %v", err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
+
+// verifySMSCallback parses the form of a callback and checks the signature of
+// its URL. It writes the error response when the callback is rejected.
+func (p PresentationHandlersImpl) verifySMSCallback(w http.ResponseWriter, r *http.Request) bool {
+	err := p.smsCallbacks.VerifySMSCallbackSignature(
+		r.URL.Path,
+		r.URL.Query().Get(usecases.SMSCallbackSignatureParam),
+	)
+	if errors.Is(err, exceptions.ErrInvalidSMSCallbackSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/serverutils"
+)
+
+// VideoRoomCallbackHandler receives the video room status callbacks that
+// Twilio posts as form values. Requests must carry Twilio's signature of the
+// URL and form.
+func (p PresentationHandlersImpl) VideoRoomCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	err := p.videoRooms.VerifyVideoCallbackSignature(
+		r.URL.RequestURI(),
+		r.PostForm,
+		r.Header.Get(twilioSignatureHeader),
+	)
+	if errors.Is(err, exceptions.ErrInvalidVideoCallbackSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return
+	}
+
+	// the timestamp is left out when it can't be parsed so that the time
+	// the callback was received is used instead
+	timestamp, _ := time.Parse(time.RFC3339, r.PostForm.Get("Timestamp"))
+	duration, _ := strconv.Atoi(r.PostForm.Get("ParticipantDuration"))
+	err = p.videoRooms.HandleVideoRoomEvent(r.Context(), dto.VideoRoomEvent{
+		Event:               r.PostForm.Get("StatusCallbackEvent"),
+		RoomSID:             r.PostForm.Get("RoomSid"),
+		RoomName:            r.PostForm.Get("RoomName"),
+		ParticipantSID:      r.PostForm.Get("ParticipantSid"),
+		ParticipantIdentity: r.PostForm.Get("ParticipantIdentity"),
+		ParticipantDuration: duration,
+		Timestamp:           timestamp,
+	})
+	if err != nil {
+		log.Printf("unable to handle video room callback: %v", err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	serverutils.WriteJSONResponse(w, map[string]string{"status": "success"}, http.StatusOK)
+}
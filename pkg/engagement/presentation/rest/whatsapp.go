@@ -0,0 +1,68 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/serverutils"
+)
+
+const (
+	// twilioSignatureHeader carries Twilio's signature of a webhook request
+	twilioSignatureHeader = "X-Twilio-Signature"
+
+	// emptyTwiML tells Twilio that there is nothing to reply with
+	emptyTwiML = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+)
+
+// InboundWhatsAppHandler receives the WhatsApp messages that Twilio posts as
+// form values. Requests must carry Twilio's signature of the URL and form.
+func (p PresentationHandlersImpl) InboundWhatsAppHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+	err := p.whatsAppConversations.VerifyWhatsAppWebhookSignature(
+		r.URL.RequestURI(),
+		r.PostForm,
+		r.Header.Get(twilioSignatureHeader),
+	)
+	if errors.Is(err, exceptions.ErrInvalidWhatsAppWebhookSignature) {
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusUnauthorized)
+		return
+	}
+
+	mediaURLs := []string{}
+	media, _ := strconv.Atoi(r.PostForm.Get("NumMedia"))
+	for i := 0; i < media; i++ {
+		if mediaURL := r.PostForm.Get(fmt.Sprintf("MediaUrl%d", i)); mediaURL != "" {
+			mediaURLs = append(mediaURLs, mediaURL)
+		}
+	}
+	_, err = p.whatsAppConversations.HandleInboundWhatsApp(r.Context(), &domain.InboundWhatsApp{
+		ID:            r.PostForm.Get("MessageSid"),
+		From:          r.PostForm.Get("From"),
+		To:            r.PostForm.Get("To"),
+		ProfileName:   r.PostForm.Get("ProfileName"),
+		Text:          r.PostForm.Get("Body"),
+		MediaURLs:     mediaURLs,
+		ButtonPayload: r.PostForm.Get("ButtonPayload"),
+		ButtonText:    r.PostForm.Get("ButtonText"),
+	})
+	if err != nil {
+		log.Printf("unable to handle received WhatsApp message: %v", err)
+		serverutils.WriteJSONResponse(w, serverutils.ErrorMap(err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(emptyTwiML)); err != nil {
+		log.Printf("unable to write WhatsApp webhook response: %v", err)
+	}
+}
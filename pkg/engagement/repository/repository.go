@@ -1 +1,648 @@
 package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// Repository is the interface to be implemented by the database(s)
+// that hold the state owned by this service.
+// The method signatures should be database independent
+type Repository interface {
+	TemplateRepository
+	DeviceRepository
+	InboxRepository
+	ScheduleRepository
+	RecipientGroupRepository
+	RateLimitRepository
+	OTPRepository
+	EmailVerificationRepository
+	BulkSMSRepository
+	SMSCallbackRepository
+	SMSCostRepository
+	EmailSuppressionRepository
+	EmailConversationRepository
+	WhatsAppRepository
+	VideoRoomRepository
+	CalendarRepository
+	AvailabilityRepository
+}
+
+// TemplateRepository stores notification templates
+type TemplateRepository interface {
+	// SaveNotificationTemplate creates or replaces a template keyed on its name
+	SaveNotificationTemplate(
+		ctx context.Context,
+		template *domain.NotificationTemplate,
+	) (*domain.NotificationTemplate, error)
+
+	// GetNotificationTemplate returns exceptions.ErrTemplateNotFound when
+	// there is no template with the supplied name
+	GetNotificationTemplate(
+		ctx context.Context,
+		name string,
+	) (*domain.NotificationTemplate, error)
+
+	// ListNotificationTemplates optionally filters the templates by channel
+	ListNotificationTemplates(
+		ctx context.Context,
+		channel *feedlib.Channel,
+	) ([]*domain.NotificationTemplate, error)
+
+	DeleteNotificationTemplate(
+		ctx context.Context,
+		name string,
+	) error
+}
+
+// DeviceRepository stores the devices that users have registered for
+// push notifications
+type DeviceRepository interface {
+	// SaveDevice creates or replaces a device keyed on the user and device ID
+	SaveDevice(ctx context.Context, device *domain.Device) (*domain.Device, error)
+
+	ListDevices(ctx context.Context, uid string) ([]*domain.Device, error)
+
+	// ListDevicesByToken returns the devices registered with any of the
+	// supplied tokens
+	ListDevicesByToken(ctx context.Context, tokens []string) ([]*domain.Device, error)
+
+	// DeleteDevice returns exceptions.ErrDeviceNotFound when the user has
+	// not registered a device with the supplied ID
+	DeleteDevice(ctx context.Context, uid string, deviceID string) error
+
+	// DeleteDevicesByToken removes every device registered with any of the
+	// supplied tokens and returns the number of devices removed
+	DeleteDevicesByToken(ctx context.Context, tokens []string) (int, error)
+}
+
+// InboxRepository stores the notifications that have been sent to users
+type InboxRepository interface {
+	SaveInboxNotification(
+		ctx context.Context,
+		notification *domain.InboxNotification,
+	) (*domain.InboxNotification, error)
+
+	// ListInboxNotifications returns up to `limit` of a user's notifications,
+	// newest first, starting after the supplied cursor
+	ListInboxNotifications(
+		ctx context.Context,
+		uid string,
+		filter domain.InboxFilter,
+		after *domain.InboxCursor,
+		limit int,
+	) ([]*domain.InboxNotification, error)
+
+	CountUnreadInboxNotifications(ctx context.Context, uid string) (int, error)
+
+	// SetInboxNotificationsRead marks the supplied notifications as read or
+	// unread and returns the number of notifications that changed.
+	// It returns exceptions.ErrInboxNotificationNotFound when any of the
+	// notifications is not in the user's inbox.
+	SetInboxNotificationsRead(
+		ctx context.Context,
+		uid string,
+		ids []string,
+		read bool,
+		at time.Time,
+	) (int, error)
+
+	// MarkAllInboxNotificationsRead marks every unread notification in a
+	// user's inbox as read and returns the number of notifications marked
+	MarkAllInboxNotificationsRead(
+		ctx context.Context,
+		uid string,
+		at time.Time,
+	) (int, error)
+}
+
+// ScheduleRepository is the persistent job store of the notification scheduler
+type ScheduleRepository interface {
+	// SaveScheduledNotification creates or replaces a scheduled notification
+	// keyed on its ID
+	SaveScheduledNotification(
+		ctx context.Context,
+		notification *domain.ScheduledNotification,
+	) (*domain.ScheduledNotification, error)
+
+	// GetScheduledNotification returns exceptions.ErrScheduledNotificationNotFound
+	// when there is no scheduled notification with the supplied ID
+	GetScheduledNotification(
+		ctx context.Context,
+		id string,
+	) (*domain.ScheduledNotification, error)
+
+	// UpdateScheduledNotification atomically applies `update` to a
+	// scheduled notification and saves the result unless `update` returns
+	// an error. `update` may be called more than once so it should only
+	// depend on its argument.
+	UpdateScheduledNotification(
+		ctx context.Context,
+		id string,
+		update func(notification *domain.ScheduledNotification) error,
+	) (*domain.ScheduledNotification, error)
+
+	// ListScheduledNotifications optionally filters the scheduled
+	// notifications by status
+	ListScheduledNotifications(
+		ctx context.Context,
+		status *domain.ScheduleStatus,
+	) ([]*domain.ScheduledNotification, error)
+
+	// ListDueScheduledNotifications returns up to `limit` active scheduled
+	// notifications whose next run is not after `now`, earliest first
+	ListDueScheduledNotifications(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]*domain.ScheduledNotification, error)
+
+	// ClaimScheduledNotification atomically leases an active scheduled
+	// notification until `leaseUntil`. It returns false when the
+	// notification is no longer active or another instance holds a lease
+	// that has not expired.
+	ClaimScheduledNotification(
+		ctx context.Context,
+		id string,
+		now time.Time,
+		leaseUntil time.Time,
+	) (bool, error)
+}
+
+// RecipientGroupRepository stores named lists of recipients
+type RecipientGroupRepository interface {
+	// SaveRecipientGroup creates or replaces a group keyed on its name
+	SaveRecipientGroup(
+		ctx context.Context,
+		group *domain.RecipientGroup,
+	) (*domain.RecipientGroup, error)
+
+	// GetRecipientGroup returns exceptions.ErrRecipientGroupNotFound when
+	// there is no group with the supplied name
+	GetRecipientGroup(ctx context.Context, name string) (*domain.RecipientGroup, error)
+
+	ListRecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error)
+
+	DeleteRecipientGroup(ctx context.Context, name string) error
+}
+
+// RateLimitRepository stores rate limit policies and the per-recipient state
+// used to enforce them
+type RateLimitRepository interface {
+	// SaveRateLimitPolicy creates or replaces the policy of a flavour and channel
+	SaveRateLimitPolicy(
+		ctx context.Context,
+		policy *domain.RateLimitPolicy,
+	) (*domain.RateLimitPolicy, error)
+
+	// GetRateLimitPolicy returns exceptions.ErrRateLimitPolicyNotFound when
+	// no policy has been saved for the flavour and channel
+	GetRateLimitPolicy(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		channel feedlib.Channel,
+	) (*domain.RateLimitPolicy, error)
+
+	ListRateLimitPolicies(ctx context.Context) ([]*domain.RateLimitPolicy, error)
+
+	DeleteRateLimitPolicy(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		channel feedlib.Channel,
+	) error
+
+	// ReserveNotification atomically counts a notification to a recipient
+	// against the policy's current window and returns true if it may be
+	// sent. When the limit has been reached the notification is added to
+	// the recipient's digest, which falls due when the window ends.
+	ReserveNotification(
+		ctx context.Context,
+		policy domain.RateLimitPolicy,
+		recipient string,
+		now time.Time,
+	) (bool, error)
+
+	// ListDueDigests returns up to `limit` digests that are due at `now`
+	ListDueDigests(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]*domain.NotificationDigest, error)
+
+	// TakeDigest atomically removes and returns a digest. It returns
+	// exceptions.ErrDigestNotFound when the digest has already been taken.
+	TakeDigest(ctx context.Context, id string) (*domain.NotificationDigest, error)
+
+	// RequeueDigest atomically puts back a digest that was taken but could
+	// not be sent, merging it with any notifications that have been held
+	// back from the recipient since it was taken
+	RequeueDigest(ctx context.Context, digest *domain.NotificationDigest) error
+}
+
+// OTPAttemptsRepository counts the attempts at throttled OTP and email
+// verification actions
+type OTPAttemptsRepository interface {
+	// UpdateOTPAttempts atomically applies `update` to the attempts stored
+	// under `id` and saves the result. `update` receives a zero value with
+	// the ID set when nothing has been stored. It may be called more than
+	// once so it should only depend on its argument.
+	UpdateOTPAttempts(
+		ctx context.Context,
+		id string,
+		update func(attempts *domain.OTPAttempts),
+	) (*domain.OTPAttempts, error)
+}
+
+// OTPRepository stores the hashes of one time passwords
+type OTPRepository interface {
+	SaveOTP(ctx context.Context, otp *domain.OTP) (*domain.OTP, error)
+
+	// ListValidOTPs returns the unused OTPs of an identifier that have not
+	// expired at `now`, newest first
+	ListValidOTPs(
+		ctx context.Context,
+		identifier string,
+		now time.Time,
+	) ([]*domain.OTP, error)
+
+	// ConsumeOTP atomically marks a valid OTP as used. It returns false if
+	// the OTP had already been used or had expired.
+	ConsumeOTP(ctx context.Context, id string, now time.Time) (bool, error)
+
+	OTPAttemptsRepository
+
+	// SaveOTPApp creates or replaces the OTP settings of an app
+	SaveOTPApp(ctx context.Context, app *domain.OTPApp) (*domain.OTPApp, error)
+
+	// GetOTPApp returns exceptions.ErrOTPAppNotFound when the app has not
+	// been registered
+	GetOTPApp(ctx context.Context, appID string) (*domain.OTPApp, error)
+
+	ListOTPApps(ctx context.Context) ([]*domain.OTPApp, error)
+
+	DeleteOTPApp(ctx context.Context, appID string) error
+
+	SaveOTPDelivery(ctx context.Context, delivery *domain.OTPDelivery) (*domain.OTPDelivery, error)
+
+	// ListOTPDeliveries returns up to `limit` deliveries of the codes of an
+	// identifier, newest first
+	ListOTPDeliveries(
+		ctx context.Context,
+		identifier string,
+		limit int,
+	) ([]*domain.OTPDelivery, error)
+
+	// SaveTOTPEnrolment creates or replaces the enrolment of a phone number
+	SaveTOTPEnrolment(
+		ctx context.Context,
+		enrolment *domain.TOTPEnrolment,
+	) (*domain.TOTPEnrolment, error)
+
+	// GetTOTPEnrolment returns exceptions.ErrTOTPEnrolmentNotFound when
+	// the phone number has not enrolled
+	GetTOTPEnrolment(ctx context.Context, id string) (*domain.TOTPEnrolment, error)
+
+	DeleteTOTPEnrolment(ctx context.Context, id string) error
+
+	// UpdateTOTPEnrolment atomically applies `update` to an enrolment and
+	// saves the result unless `update` returns an error. It returns
+	// exceptions.ErrTOTPEnrolmentNotFound when the phone number has not
+	// enrolled. `update` may be called more than once so it should only
+	// depend on its argument.
+	UpdateTOTPEnrolment(
+		ctx context.Context,
+		id string,
+		update func(enrolment *domain.TOTPEnrolment) error,
+	) (*domain.TOTPEnrolment, error)
+}
+
+// EmailVerificationRepository stores email verification links
+type EmailVerificationRepository interface {
+	SaveEmailVerificationLink(
+		ctx context.Context,
+		link *domain.EmailVerificationLink,
+	) (*domain.EmailVerificationLink, error)
+
+	// GetEmailVerificationLink returns
+	// exceptions.ErrEmailVerificationLinkNotFound when the link does not exist
+	GetEmailVerificationLink(ctx context.Context, id string) (*domain.EmailVerificationLink, error)
+
+	// ConsumeEmailVerificationLink atomically marks a valid link as verified.
+	// It returns false with the stored link if the link is no longer valid.
+	ConsumeEmailVerificationLink(
+		ctx context.Context,
+		id string,
+		now time.Time,
+	) (*domain.EmailVerificationLink, bool, error)
+
+	// RevokeEmailVerificationLinks revokes the valid links of an email
+	// address and returns how many were revoked
+	RevokeEmailVerificationLinks(ctx context.Context, email string, now time.Time) (int, error)
+
+	// requests for links are throttled like OTP generation
+	OTPAttemptsRepository
+}
+
+// BulkSMSRepository is the persistent job store of bulk SMS jobs and the
+// status of each of their recipients
+type BulkSMSRepository interface {
+	// CreateBulkSMSJob saves a new job together with its recipients
+	CreateBulkSMSJob(
+		ctx context.Context,
+		job *domain.BulkSMSJob,
+		recipients []*domain.BulkSMSRecipient,
+	) (*domain.BulkSMSJob, error)
+
+	// GetBulkSMSJob returns exceptions.ErrBulkSMSJobNotFound when there is
+	// no job with the supplied ID
+	GetBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error)
+
+	// UpdateBulkSMSJob atomically applies `update` to a job and saves the
+	// result unless `update` returns an error. `update` may be called more
+	// than once so it should only depend on its argument.
+	UpdateBulkSMSJob(
+		ctx context.Context,
+		id string,
+		update func(job *domain.BulkSMSJob) error,
+	) (*domain.BulkSMSJob, error)
+
+	// ListActiveBulkSMSJobs returns up to `limit` pending and running jobs,
+	// oldest first
+	ListActiveBulkSMSJobs(ctx context.Context, limit int) ([]*domain.BulkSMSJob, error)
+
+	// ListBulkSMSRecipients returns up to `limit` recipients of a job whose
+	// index is after `afterIndex`, in index order, optionally filtered by
+	// status
+	ListBulkSMSRecipients(
+		ctx context.Context,
+		jobID string,
+		status *domain.BulkSMSRecipientStatus,
+		afterIndex int,
+		limit int,
+	) ([]*domain.BulkSMSRecipient, error)
+
+	// SaveBulkSMSRecipients replaces recipients keyed on their job and index
+	SaveBulkSMSRecipients(ctx context.Context, recipients []*domain.BulkSMSRecipient) error
+
+	// UpdateBulkSMSRecipientDelivery sets the delivery status of the
+	// recipient that was sent a message. It returns false when no recipient
+	// was sent the message.
+	UpdateBulkSMSRecipientDelivery(
+		ctx context.Context,
+		messageID string,
+		status string,
+		at time.Time,
+	) (bool, error)
+}
+
+// SMSCallbackRepository stores what SMS providers report back: delivery
+// reports, inbound messages and the opt outs they carry
+type SMSCallbackRepository interface {
+	// SaveSMSDeliveryReport replaces the delivery report of a message
+	SaveSMSDeliveryReport(ctx context.Context, report *domain.SMSDeliveryReport) error
+
+	// GetSMSDeliveryReport returns exceptions.ErrSMSDeliveryReportNotFound
+	// when no report has been received for the message
+	GetSMSDeliveryReport(ctx context.Context, messageID string) (*domain.SMSDeliveryReport, error)
+
+	SaveInboundSMS(ctx context.Context, sms *domain.InboundSMS) error
+
+	// SaveChannelPreference creates or replaces a preference keyed on its
+	// channel and address
+	SaveChannelPreference(ctx context.Context, preference *domain.ChannelPreference) error
+
+	// GetChannelPreference returns exceptions.ErrChannelPreferenceNotFound
+	// when the address has not set a preference for the channel
+	GetChannelPreference(
+		ctx context.Context,
+		channel feedlib.Channel,
+		address string,
+	) (*domain.ChannelPreference, error)
+
+	// ListChannelPreferences returns the preferences that any of the
+	// addresses have set for a channel. Addresses without a preference are
+	// left out.
+	ListChannelPreferences(
+		ctx context.Context,
+		channel feedlib.Channel,
+		addresses []string,
+	) ([]*domain.ChannelPreference, error)
+
+	SaveSMSConversation(ctx context.Context, conversation *domain.SMSConversation) error
+
+	// GetActiveSMSConversation returns the latest conversation of a phone
+	// number that has not expired at `now`, or
+	// exceptions.ErrSMSConversationNotFound
+	GetActiveSMSConversation(
+		ctx context.Context,
+		phone string,
+		now time.Time,
+	) (*domain.SMSConversation, error)
+}
+
+// SMSCostRepository stores the SMS rate table and the SMS budgets of flavours
+type SMSCostRepository interface {
+	// SaveSMSRate creates or replaces a rate keyed on its prefix
+	SaveSMSRate(ctx context.Context, rate *domain.SMSRate) (*domain.SMSRate, error)
+
+	// ListSMSRates returns the rates ordered by prefix
+	ListSMSRates(ctx context.Context) ([]*domain.SMSRate, error)
+
+	// DeleteSMSRate returns exceptions.ErrSMSRateNotFound when no rate has
+	// been saved for the prefix
+	DeleteSMSRate(ctx context.Context, prefix string) error
+
+	// SaveSMSBudget creates or replaces the budget of a flavour
+	SaveSMSBudget(ctx context.Context, budget *domain.SMSBudget) (*domain.SMSBudget, error)
+
+	// GetSMSBudget returns exceptions.ErrSMSBudgetNotFound when no budget
+	// has been saved for the flavour
+	GetSMSBudget(ctx context.Context, flavour feedlib.Flavour) (*domain.SMSBudget, error)
+
+	// ListSMSBudgets returns the budgets ordered by flavour
+	ListSMSBudgets(ctx context.Context) ([]*domain.SMSBudget, error)
+
+	DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) error
+}
+
+// EmailSuppressionRepository stores the email addresses that are not sent
+// email
+type EmailSuppressionRepository interface {
+	// SaveEmailSuppression creates or replaces a suppression keyed on its
+	// email address
+	SaveEmailSuppression(ctx context.Context, suppression *domain.EmailSuppression) error
+
+	// GetEmailSuppression returns exceptions.ErrEmailSuppressionNotFound
+	// when the address is not suppressed
+	GetEmailSuppression(ctx context.Context, email string) (*domain.EmailSuppression, error)
+
+	// ListEmailSuppressions returns the suppressions, optionally of one
+	// reason, newest first
+	ListEmailSuppressions(
+		ctx context.Context,
+		reason *domain.EmailSuppressionReason,
+	) ([]*domain.EmailSuppression, error)
+
+	// DeleteEmailSuppression returns exceptions.ErrEmailSuppressionNotFound
+	// when the address is not suppressed
+	DeleteEmailSuppression(ctx context.Context, email string) error
+}
+
+// EmailConversationRepository stores the feed items that email addresses are
+// emailed about and the replies that they send
+type EmailConversationRepository interface {
+	SaveEmailConversation(ctx context.Context, conversation *domain.EmailConversation) error
+
+	// GetEmailConversation returns exceptions.ErrEmailConversationNotFound
+	// when there is no conversation with the ID
+	GetEmailConversation(ctx context.Context, id string) (*domain.EmailConversation, error)
+
+	// SaveInboundEmail creates or replaces a received email keyed on its ID
+	SaveInboundEmail(ctx context.Context, email *domain.InboundEmail) error
+
+	// GetInboundEmail returns exceptions.ErrInboundEmailNotFound when no
+	// email has been received with the ID
+	GetInboundEmail(ctx context.Context, id string) (*domain.InboundEmail, error)
+}
+
+// WhatsAppRepository stores the WhatsApp messages that phone numbers send us,
+// their session windows and what their replies are routed to
+type WhatsAppRepository interface {
+	// SaveWhatsAppSession creates or replaces the session of a phone number
+	SaveWhatsAppSession(ctx context.Context, session *domain.WhatsAppSession) error
+
+	// GetWhatsAppSession returns exceptions.ErrWhatsAppSessionNotFound when
+	// the phone number has never messaged us
+	GetWhatsAppSession(ctx context.Context, phone string) (*domain.WhatsAppSession, error)
+
+	// SaveInboundWhatsApp creates or replaces a received message keyed on
+	// its ID
+	SaveInboundWhatsApp(ctx context.Context, message *domain.InboundWhatsApp) error
+
+	// GetInboundWhatsApp returns exceptions.ErrInboundWhatsAppNotFound when
+	// no message has been received with the ID
+	GetInboundWhatsApp(ctx context.Context, id string) (*domain.InboundWhatsApp, error)
+
+	// ListWhatsAppSupportQueue returns the unresolved messages of the
+	// support queue, oldest first
+	ListWhatsAppSupportQueue(ctx context.Context, limit int) ([]*domain.InboundWhatsApp, error)
+
+	SaveWhatsAppConversation(ctx context.Context, conversation *domain.WhatsAppConversation) error
+
+	// GetActiveWhatsAppConversation returns the latest conversation of a
+	// phone number that has not expired at `now`, or
+	// exceptions.ErrWhatsAppConversationNotFound
+	GetActiveWhatsAppConversation(
+		ctx context.Context,
+		phone string,
+		now time.Time,
+	) (*domain.WhatsAppConversation, error)
+
+	SaveWhatsAppNudge(ctx context.Context, nudge *domain.WhatsAppNudge) error
+
+	// GetActiveWhatsAppNudge returns the latest unanswered nudge of a phone
+	// number that has not expired at `now`, or
+	// exceptions.ErrWhatsAppNudgeNotFound
+	GetActiveWhatsAppNudge(ctx context.Context, phone string, now time.Time) (*domain.WhatsAppNudge, error)
+}
+
+// VideoRoomRepository holds video rooms and the connections of their
+// participants
+type VideoRoomRepository interface {
+	// SaveVideoRoom creates or replaces a room keyed on its ID
+	SaveVideoRoom(ctx context.Context, room *domain.VideoRoom) error
+
+	// GetVideoRoom returns exceptions.ErrVideoRoomNotFound when no room has
+	// the ID
+	GetVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error)
+
+	// ListVideoRooms returns the rooms with a status, ordered by their
+	// scheduled start
+	ListVideoRooms(ctx context.Context, status domain.VideoRoomStatus) ([]*domain.VideoRoom, error)
+
+	// SaveVideoParticipant creates or replaces a participant's connection
+	// keyed on its ID
+	SaveVideoParticipant(ctx context.Context, participant *domain.VideoParticipant) error
+
+	// GetVideoParticipant returns exceptions.ErrVideoParticipantNotFound
+	// when no connection has the ID
+	GetVideoParticipant(ctx context.Context, id string) (*domain.VideoParticipant, error)
+
+	// ListVideoParticipants returns the connections to a room in the order
+	// that they joined
+	ListVideoParticipants(ctx context.Context, roomID string) ([]*domain.VideoParticipant, error)
+}
+
+// CalendarRepository holds the events of the service's own calendar
+type CalendarRepository interface {
+	// SaveCalendarEvent creates or replaces an event keyed on its ID
+	SaveCalendarEvent(ctx context.Context, event *domain.StoredCalendarEvent) error
+
+	// GetCalendarEvent returns exceptions.ErrCalendarEventNotFound when no
+	// event has the ID
+	GetCalendarEvent(ctx context.Context, id string) (*domain.StoredCalendarEvent, error)
+
+	// ListCalendarEvents returns the events that start before `to` and end
+	// after `from`, ordered by their start
+	ListCalendarEvents(ctx context.Context, from, to time.Time) ([]*domain.StoredCalendarEvent, error)
+}
+
+// AvailabilityRepository holds when clinicians can be booked and their
+// bookings
+type AvailabilityRepository interface {
+	// SaveClinicianAvailability creates or replaces an availability keyed
+	// on its ID
+	SaveClinicianAvailability(ctx context.Context, availability *domain.ClinicianAvailability) error
+
+	// GetClinicianAvailability returns
+	// exceptions.ErrClinicianAvailabilityNotFound when a clinician has no
+	// availability at a location
+	GetClinicianAvailability(
+		ctx context.Context,
+		clinicianID string,
+		locationID string,
+	) (*domain.ClinicianAvailability, error)
+
+	// ListClinicianAvailability returns a clinician's availability at each
+	// location, ordered by location
+	ListClinicianAvailability(ctx context.Context, clinicianID string) ([]*domain.ClinicianAvailability, error)
+
+	DeleteClinicianAvailability(ctx context.Context, id string) error
+
+	SaveAvailabilityException(ctx context.Context, exception *domain.AvailabilityException) error
+
+	// DeleteAvailabilityException returns
+	// exceptions.ErrAvailabilityExceptionNotFound when no exception has the
+	// ID
+	DeleteAvailabilityException(ctx context.Context, id string) error
+
+	// ListAvailabilityExceptions returns a clinician's exceptions that
+	// overlap an interval, ordered by their start
+	ListAvailabilityExceptions(
+		ctx context.Context,
+		clinicianID string,
+		from, to time.Time,
+	) ([]*domain.AvailabilityException, error)
+
+	// BookSlot atomically saves a booking when the clinician has no other
+	// booking within a buffer of it. It returns exceptions.ErrSlotUnavailable
+	// otherwise, including when a concurrent booking wins. A booking that
+	// is saved again with a new time is moved.
+	BookSlot(ctx context.Context, booking *domain.SlotBooking, buffer time.Duration) error
+
+	// SaveSlotBooking replaces a booking keyed on its ID
+	SaveSlotBooking(ctx context.Context, booking *domain.SlotBooking) error
+
+	DeleteSlotBooking(ctx context.Context, id string) error
+
+	// GetSlotBookingByEvent returns exceptions.ErrSlotBookingNotFound when
+	// no booking has the calendar event
+	GetSlotBookingByEvent(ctx context.Context, eventID string) (*domain.SlotBooking, error)
+
+	// ListSlotBookings returns a clinician's active bookings that overlap an
+	// interval, ordered by their start
+	ListSlotBookings(ctx context.Context, clinicianID string, from, to time.Time) ([]*domain.SlotBooking, error)
+}
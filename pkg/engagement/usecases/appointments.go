@@ -0,0 +1,778 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/calendar"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/enumutils"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+const (
+	// AppointmentICSPath is the path of the unauthenticated endpoint that
+	// serves the iCalendar documents of appointments from signed links
+	AppointmentICSPath = "/appointments/ics"
+
+	// AppointmentICSLinkExpiry is how long an iCalendar link can be used
+	AppointmentICSLinkExpiry = 30 * 24 * time.Hour
+
+	// MaxAppointmentReminders is the most reminders that an appointment can
+	// have, which is Google Calendar's limit
+	MaxAppointmentReminders = 5
+
+	// MaxAppointmentReminderMinutes is the earliest that a reminder can be
+	// before an appointment, which is four weeks
+	MaxAppointmentReminderMinutes = 40320
+
+	// DefaultAppointmentTimeZone is the time zone of appointments that are
+	// scheduled without one
+	DefaultAppointmentTimeZone = "Africa/Nairobi"
+
+	// MaxAppointmentAttendees is the most attendees that an appointment can
+	// have
+	MaxAppointmentAttendees = 50
+
+	// the changes to an appointment that attendees are notified of. The
+	// notification templates are named after the change and the channel
+	// e.g `appointment_scheduled_email` or `appointment_cancelled_sms`, and
+	// are rendered with the `summary`, `start`, `end`, `timeZone`,
+	// `location`, `description` and `calendarLink` variables.
+	appointmentScheduled = "scheduled"
+	appointmentUpdated   = "updated"
+	appointmentCancelled = "cancelled"
+
+	// appointmentPhonePropertyPrefix prefixes the private event properties
+	// that hold the phone numbers of attendees, keyed on their email
+	appointmentPhonePropertyPrefix = "phone:"
+
+	// appointmentCreatedByProperty is the private event property that
+	// holds the UID of the user that scheduled an appointment
+	appointmentCreatedByProperty = "createdBy"
+
+	appointmentTimeLayout = "Monday 2 January 2006, 15:04"
+
+	// appointmentInviteFilename is the name of the iCalendar invite that
+	// is attached to appointment emails
+	appointmentInviteFilename = "invite.ics"
+)
+
+// DefaultAppointmentReminderMinutes are the reminders of appointments that
+// are scheduled without any, a day and an hour before them
+var DefaultAppointmentReminderMinutes = []int{24 * 60, 60}
+
+// the notifications that are sent when their templates have not been
+// created, keyed on the change
+var (
+	defaultAppointmentEmailSubjects = map[string]string{
+		appointmentScheduled: "Appointment scheduled: {{.summary}}",
+		appointmentUpdated:   "Appointment changed: {{.summary}}",
+		appointmentCancelled: "Appointment cancelled: {{.summary}}",
+	}
+	defaultAppointmentEmailBodies = map[string]string{
+		appointmentScheduled: `<p>You have an appointment, <strong>{{.summary}}</strong>.</p>
+<p>It starts on {{.start}} and ends on {{.end}} ({{.timeZone}}).{{if .location}} It is at {{.location}}.{{end}}</p>
+{{if .description}}<p>{{.description}}</p>{{end}}
+<p><a href="{{.calendarLink}}">Add it to your calendar</a></p>`,
+		appointmentUpdated: `<p>Your appointment, <strong>{{.summary}}</strong>, has changed.</p>
+<p>It now starts on {{.start}} and ends on {{.end}} ({{.timeZone}}).{{if .location}} It is at {{.location}}.{{end}}</p>
+{{if .description}}<p>{{.description}}</p>{{end}}
+<p><a href="{{.calendarLink}}">Update your calendar</a></p>`,
+		appointmentCancelled: `<p>Your appointment, <strong>{{.summary}}</strong>, on {{.start}} ({{.timeZone}}) has been cancelled.</p>`,
+	}
+	defaultAppointmentSMS = map[string]string{
+		appointmentScheduled: "You have an appointment, {{.summary}}, on {{.start}} ({{.timeZone}}).{{if .location}} Location: {{.location}}.{{end}}",
+		appointmentUpdated:   "Your appointment, {{.summary}}, has changed. It is now on {{.start}} ({{.timeZone}}).{{if .location}} Location: {{.location}}.{{end}}",
+		appointmentCancelled: "Your appointment, {{.summary}}, on {{.start}} ({{.timeZone}}) has been cancelled.",
+	}
+)
+
+// AppointmentConfig holds what appointment invites and iCalendar links are
+// made with
+type AppointmentConfig struct {
+	// SigningKey signs iCalendar links so that only the attendees that
+	// were sent a link can download an appointment
+	SigningKey []byte
+
+	// BaseURL is the public URL of the service that iCalendar links point
+	// to e.g https://engagement.bewell.co.ke
+	BaseURL string
+
+	// Organizer is the email address that invites are from. Attendees'
+	// calendars send their replies to it.
+	Organizer string
+}
+
+// AppointmentUsecases represent logic required to schedule appointments as
+// calendar events and keep their attendees informed of them
+type AppointmentUsecases interface {
+	// CreateAppointment schedules an appointment and notifies its attendees
+	CreateAppointment(ctx context.Context, createdBy string, input dto.AppointmentInput) (*gcal.Event, error)
+
+	// UpdateAppointment changes an appointment that has not been cancelled.
+	// Attendees that are added are notified that it was scheduled, those
+	// that are removed that it was cancelled and the others that it changed.
+	// An appointment booked in a slot keeps its booking, which is moved to
+	// the new time. It returns exceptions.ErrSlotUnavailable when the
+	// clinician is booked at the new time.
+	UpdateAppointment(ctx context.Context, id string, input dto.AppointmentInput) (*gcal.Event, error)
+
+	// CancelAppointment cancels an appointment and notifies its attendees.
+	// The event is kept with the `cancelled` status and the slot that it was
+	// booked in, if any, is freed.
+	CancelAppointment(ctx context.Context, id string) (*gcal.Event, error)
+
+	GetAppointment(ctx context.Context, id string) (*gcal.Event, error)
+
+	// AppointmentICSLink returns a signed link that downloads an
+	// appointment as an iCalendar document
+	AppointmentICSLink(ctx context.Context, id string) (string, error)
+
+	// VerifyAppointmentICSSignature returns
+	// exceptions.ErrInvalidAppointmentICSSignature when an iCalendar link
+	// was not signed for an appointment or has expired
+	VerifyAppointmentICSSignature(id string, expires string, signature string) error
+
+	// AppointmentICS renders an appointment as an iCalendar document that
+	// can be imported into any calendar
+	AppointmentICS(ctx context.Context, id string) ([]byte, error)
+
+	// ListAppointments returns the appointments that overlap an interval
+	// ordered by their start, optionally only those of an attendee
+	ListAppointments(
+		ctx context.Context,
+		from, to time.Time,
+		includeCancelled bool,
+		attendeeEmail *string,
+	) ([]*gcal.Event, error)
+}
+
+// AppointmentImpl represents the appointment usecase implementation
+type AppointmentImpl struct {
+	Calendar  calendar.ServiceCalendar
+	Templates TemplateUsecases
+	Email     email.ServiceEmail
+	SMS       sms.ServiceSMS
+	Config    AppointmentConfig
+
+	// Availability holds the bookings of appointments that were booked in
+	// clinicians' slots
+	Availability repository.AvailabilityRepository
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewAppointments initializes an appointment usecase
+func NewAppointments(
+	calendarService calendar.ServiceCalendar,
+	templates TemplateUsecases,
+	email email.ServiceEmail,
+	sms sms.ServiceSMS,
+	availability repository.AvailabilityRepository,
+	config AppointmentConfig,
+) (*AppointmentImpl, error) {
+	if len(config.SigningKey) < 32 {
+		return nil, fmt.Errorf("the appointment link signing key must be at least 32 bytes")
+	}
+	base, err := url.Parse(config.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%q is not a valid base URL", config.BaseURL)
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	config.Organizer = normalizeEmail(config.Organizer)
+	if !govalidator.IsEmail(config.Organizer) {
+		return nil, fmt.Errorf("%q is not a valid appointment organizer email", config.Organizer)
+	}
+	return &AppointmentImpl{
+		Calendar:     calendarService,
+		Templates:    templates,
+		Email:        email,
+		SMS:          sms,
+		Config:       config,
+		Availability: availability,
+		Clock:        time.Now,
+	}, nil
+}
+
+// CreateAppointment inserts an appointment into the calendar
+func (a *AppointmentImpl) CreateAppointment(
+	ctx context.Context,
+	createdBy string,
+	input dto.AppointmentInput,
+) (*gcal.Event, error) {
+	event, err := appointmentEvent(input)
+	if err != nil {
+		return nil, err
+	}
+	event.ExtendedProperties.Private[appointmentCreatedByProperty] = createdBy
+
+	created, err := a.Calendar.InsertEvent(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create appointment: %w", err)
+	}
+	a.notify(ctx, created, appointmentScheduled, created.Attendees)
+	return created, nil
+}
+
+// UpdateAppointment replaces the details of an appointment, keeping its
+// identity and increasing its sequence
+func (a *AppointmentImpl) UpdateAppointment(
+	ctx context.Context,
+	id string,
+	input dto.AppointmentInput,
+) (*gcal.Event, error) {
+	existing, err := a.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status == calendarEventCancelled {
+		return nil, fmt.Errorf("appointment %s has been cancelled", id)
+	}
+	event, err := appointmentEvent(input)
+	if err != nil {
+		return nil, err
+	}
+	event.Id = existing.Id
+	event.ICalUID = existing.ICalUID
+	event.Status = existing.Status
+	event.Sequence = existing.Sequence + 1
+	if existing.ExtendedProperties != nil {
+		event.ExtendedProperties.Private[appointmentCreatedByProperty] =
+			existing.ExtendedProperties.Private[appointmentCreatedByProperty]
+	}
+
+	booking, err := a.moveSlotBooking(ctx, id, input.Start, input.End)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := a.Calendar.UpdateEvent(ctx, event)
+	if err != nil {
+		if booking != nil {
+			if err := a.Availability.SaveSlotBooking(ctx, booking); err != nil {
+				log.Printf("unable to restore slot booking %s: %v", booking.ID, err)
+			}
+		}
+		return nil, fmt.Errorf("unable to update appointment: %w", err)
+	}
+
+	previous := map[string]bool{}
+	for _, attendee := range existing.Attendees {
+		previous[attendee.Email] = true
+	}
+	added, kept := []*gcal.EventAttendee{}, []*gcal.EventAttendee{}
+	for _, attendee := range updated.Attendees {
+		if previous[attendee.Email] {
+			kept = append(kept, attendee)
+			delete(previous, attendee.Email)
+			continue
+		}
+		added = append(added, attendee)
+	}
+	removed := []*gcal.EventAttendee{}
+	for _, attendee := range existing.Attendees {
+		if previous[attendee.Email] {
+			removed = append(removed, attendee)
+		}
+	}
+
+	a.notify(ctx, updated, appointmentScheduled, added)
+	a.notify(ctx, updated, appointmentUpdated, kept)
+
+	// the removed attendees are told about the appointment that they were
+	// invited to, not its new details, with the new sequence so that their
+	// calendars drop it
+	withdrawn := *existing
+	withdrawn.Status = calendarEventCancelled
+	withdrawn.Sequence = updated.Sequence
+	withdrawn.Attendees = removed
+	a.notify(ctx, &withdrawn, appointmentCancelled, removed)
+	return updated, nil
+}
+
+// CancelAppointment marks an appointment as cancelled
+func (a *AppointmentImpl) CancelAppointment(ctx context.Context, id string) (*gcal.Event, error) {
+	existing, err := a.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status == calendarEventCancelled {
+		return nil, fmt.Errorf("appointment %s has already been cancelled", id)
+	}
+	cancelled := *existing
+	cancelled.Status = calendarEventCancelled
+	cancelled.Sequence = existing.Sequence + 1
+
+	updated, err := a.Calendar.UpdateEvent(ctx, &cancelled)
+	if err != nil {
+		return nil, fmt.Errorf("unable to cancel appointment: %w", err)
+	}
+	// the appointment has been cancelled, so failing to free its slot is
+	// logged rather than failing a cancellation that can't be repeated
+	if err := a.releaseSlotBooking(ctx, id); err != nil {
+		log.Printf("unable to release the slot booking of appointment %s: %v", id, err)
+	}
+	a.notify(ctx, updated, appointmentCancelled, updated.Attendees)
+	return updated, nil
+}
+
+// moveSlotBooking moves the booking of an appointment that was booked in a
+// slot to the appointment's new time. Like a new booking, the new time must
+// be a free slot of the clinician at the booking's location, so it is checked
+// against their working hours, exceptions and other bookings. It returns the
+// booking as it was before the move, or nil when there is no booking or its
+// time is unchanged.
+func (a *AppointmentImpl) moveSlotBooking(
+	ctx context.Context,
+	id string,
+	start, end time.Time,
+) (*domain.SlotBooking, error) {
+	booking, err := a.Availability.GetSlotBookingByEvent(ctx, id)
+	if errors.Is(err, exceptions.ErrSlotBookingNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get slot booking: %w", err)
+	}
+	if booking.Status != domain.SlotBookingStatusBooked ||
+		(booking.Start.Equal(start) && booking.End.Equal(end)) {
+		return nil, nil
+	}
+
+	// a clinician that no longer has availability at the location has no
+	// slots to move the booking to
+	availability, err := a.Availability.GetClinicianAvailability(ctx, booking.ClinicianID, booking.LocationID)
+	if errors.Is(err, exceptions.ErrClinicianAvailabilityNotFound) {
+		return nil, exceptions.ErrSlotUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !end.Equal(start.Add(availability.SlotDuration())) {
+		return nil, exceptions.ErrSlotUnavailable
+	}
+	free, err := freeSlots(
+		ctx,
+		a.Availability,
+		[]*domain.ClinicianAvailability{availability},
+		booking.ClinicianID,
+		start,
+		end,
+		a.Clock(),
+		booking.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	isFree := false
+	for _, slot := range free {
+		if slot.Start.Equal(start) {
+			isFree = true
+			break
+		}
+	}
+	if !isFree {
+		return nil, exceptions.ErrSlotUnavailable
+	}
+
+	moved := *booking
+	moved.Start = start.UTC()
+	moved.End = end.UTC()
+	moved.UpdatedAt = a.Clock()
+	if err := a.Availability.BookSlot(ctx, &moved, availability.Buffer()); err != nil {
+		if errors.Is(err, exceptions.ErrSlotUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to book slot: %w", err)
+	}
+	return booking, nil
+}
+
+// releaseSlotBooking frees the slot that an appointment was booked in, if
+// any
+func (a *AppointmentImpl) releaseSlotBooking(ctx context.Context, id string) error {
+	booking, err := a.Availability.GetSlotBookingByEvent(ctx, id)
+	if errors.Is(err, exceptions.ErrSlotBookingNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	booking.Status = domain.SlotBookingStatusCancelled
+	booking.UpdatedAt = a.Clock()
+	return a.Availability.SaveSlotBooking(ctx, booking)
+}
+
+// GetAppointment retrieves an appointment by its ID
+func (a *AppointmentImpl) GetAppointment(ctx context.Context, id string) (*gcal.Event, error) {
+	event, err := a.Calendar.GetEvent(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get appointment %s: %w", id, err)
+	}
+	return event, nil
+}
+
+// AppointmentICSLink signs a link to an appointment that expires after
+// AppointmentICSLinkExpiry
+func (a *AppointmentImpl) AppointmentICSLink(ctx context.Context, id string) (string, error) {
+	if _, err := a.GetAppointment(ctx, id); err != nil {
+		return "", err
+	}
+	return a.icsLink(id), nil
+}
+
+func (a *AppointmentImpl) icsLink(id string) string {
+	expires := strconv.FormatInt(a.Clock().Add(AppointmentICSLinkExpiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("id", id)
+	query.Set("expires", expires)
+	query.Set("signature", SignAppointmentICSLink(a.Config.SigningKey, id, expires))
+	return a.Config.BaseURL + AppointmentICSPath + "?" + query.Encode()
+}
+
+// SignAppointmentICSLink returns the hex HMAC-SHA256 of an appointment ID and
+// the unix time that its link expires at
+func SignAppointmentICSLink(key []byte, id string, expires string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAppointmentICSSignature compares signatures in constant time and
+// checks that the link has not expired
+func (a *AppointmentImpl) VerifyAppointmentICSSignature(id string, expires string, signature string) error {
+	expected := SignAppointmentICSLink(a.Config.SigningKey, id, expires)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return exceptions.ErrInvalidAppointmentICSSignature
+	}
+	expiry, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !a.Clock().Before(time.Unix(expiry, 0)) {
+		return exceptions.ErrInvalidAppointmentICSSignature
+	}
+	return nil
+}
+
+// AppointmentICS publishes an appointment, including a cancelled one
+func (a *AppointmentImpl) AppointmentICS(ctx context.Context, id string) ([]byte, error) {
+	event, err := a.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return CalendarEventICS(event, ICSMethodPublish, a.Config.Organizer, a.Clock())
+}
+
+// ListAppointments lists the appointments in the calendar
+func (a *AppointmentImpl) ListAppointments(
+	ctx context.Context,
+	from, to time.Time,
+	includeCancelled bool,
+	attendeeEmail *string,
+) ([]*gcal.Event, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("the end of the interval must be after its start")
+	}
+	events, err := a.Calendar.ListEvents(ctx, from, to, includeCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list appointments: %w", err)
+	}
+	if attendeeEmail == nil {
+		return events, nil
+	}
+
+	address := normalizeEmail(*attendeeEmail)
+	filtered := []*gcal.Event{}
+	for _, event := range events {
+		for _, attendee := range event.Attendees {
+			if attendee.Email == address {
+				filtered = append(filtered, event)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// appointmentEvent validates an appointment input and converts it to a
+// calendar event
+func appointmentEvent(input dto.AppointmentInput) (*gcal.Event, error) {
+	summary := strings.TrimSpace(input.Summary)
+	if summary == "" {
+		return nil, fmt.Errorf("an appointment needs a summary")
+	}
+	if !input.End.After(input.Start) {
+		return nil, fmt.Errorf("an appointment must end after it starts")
+	}
+	if input.End.Sub(input.Start) > domain.MaxCalendarEventDuration {
+		return nil, fmt.Errorf(
+			"an appointment can't be longer than %d hours",
+			int(domain.MaxCalendarEventDuration/time.Hour),
+		)
+	}
+	timeZone := DefaultAppointmentTimeZone
+	if input.TimeZone != nil && strings.TrimSpace(*input.TimeZone) != "" {
+		timeZone = strings.TrimSpace(*input.TimeZone)
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid time zone", timeZone)
+	}
+	if len(input.Attendees) == 0 {
+		return nil, fmt.Errorf("an appointment needs at least one attendee")
+	}
+	if len(input.Attendees) > MaxAppointmentAttendees {
+		return nil, fmt.Errorf("an appointment can't have more than %d attendees", MaxAppointmentAttendees)
+	}
+	reminders := input.ReminderMinutes
+	if reminders == nil {
+		reminders = DefaultAppointmentReminderMinutes
+	}
+	if len(reminders) > MaxAppointmentReminders {
+		return nil, fmt.Errorf("an appointment can't have more than %d reminders", MaxAppointmentReminders)
+	}
+
+	event := &gcal.Event{
+		Summary: summary,
+		Start: &gcal.EventDateTime{
+			DateTime: input.Start.In(loc).Format(time.RFC3339),
+			TimeZone: timeZone,
+		},
+		End: &gcal.EventDateTime{
+			DateTime: input.End.In(loc).Format(time.RFC3339),
+			TimeZone: timeZone,
+		},
+		ExtendedProperties: &gcal.EventExtendedProperties{
+			Private: map[string]string{},
+		},
+		// the calendar's default reminders are not used since they are
+		// not known to other calendars
+		Reminders: &gcal.EventReminders{
+			Overrides:       []*gcal.EventReminder{},
+			ForceSendFields: []string{"UseDefault", "Overrides"},
+		},
+	}
+	for _, minutes := range reminders {
+		if minutes < 0 || minutes > MaxAppointmentReminderMinutes {
+			return nil, fmt.Errorf(
+				"reminders must be up to %d minutes before an appointment",
+				MaxAppointmentReminderMinutes,
+			)
+		}
+		event.Reminders.Overrides = append(event.Reminders.Overrides, &gcal.EventReminder{
+			Method:  "popup",
+			Minutes: int64(minutes),
+		})
+	}
+	if input.Description != nil {
+		event.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Location != nil {
+		event.Location = strings.TrimSpace(*input.Location)
+	}
+
+	seen := map[string]bool{}
+	for _, attendee := range input.Attendees {
+		if attendee == nil {
+			continue
+		}
+		address := normalizeEmail(attendee.Email)
+		if !govalidator.IsEmail(address) {
+			return nil, fmt.Errorf("%s is not a valid email", attendee.Email)
+		}
+		if seen[address] {
+			return nil, fmt.Errorf("%s is an attendee more than once", address)
+		}
+		seen[address] = true
+
+		a := &gcal.EventAttendee{
+			Email:          address,
+			Optional:       attendee.Optional,
+			ResponseStatus: "needsAction",
+		}
+		if attendee.DisplayName != nil {
+			a.DisplayName = strings.TrimSpace(*attendee.DisplayName)
+		}
+		event.Attendees = append(event.Attendees, a)
+
+		if attendee.Phone != nil && strings.TrimSpace(*attendee.Phone) != "" {
+			phone, err := converterandformatter.NormalizeMSISDN(*attendee.Phone)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a valid phone number: %w", *attendee.Phone, err)
+			}
+			event.ExtendedProperties.Private[appointmentPhonePropertyPrefix+address] = *phone
+		}
+	}
+	return event, nil
+}
+
+// notify emails attendees about a change to an appointment with an iCalendar
+// invite that updates their calendars, and sends an SMS to those with a phone
+// number. Failures are logged since the appointment has already changed.
+// Attendees are known by their addresses rather than as users, so the
+// notifications are not recorded in an inbox.
+func (a *AppointmentImpl) notify(
+	ctx context.Context,
+	event *gcal.Event,
+	change string,
+	attendees []*gcal.EventAttendee,
+) {
+	if len(attendees) == 0 {
+		return
+	}
+	variables, err := appointmentVariables(event)
+	if err != nil {
+		log.Printf("unable to notify the attendees of appointment %s: %v", event.Id, err)
+		return
+	}
+	if change != appointmentCancelled {
+		variables["calendarLink"] = a.icsLink(event.Id)
+	}
+
+	to, phones := []string{}, []string{}
+	for _, attendee := range attendees {
+		to = append(to, attendee.Email)
+		if event.ExtendedProperties == nil {
+			continue
+		}
+		if phone, ok := event.ExtendedProperties.Private[appointmentPhonePropertyPrefix+attendee.Email]; ok {
+			phones = append(phones, phone)
+		}
+	}
+
+	method := ICSMethodRequest
+	if change == appointmentCancelled {
+		method = ICSMethodCancel
+	}
+	invite, err := CalendarEventICS(event, method, a.Config.Organizer, a.Clock())
+	if err != nil {
+		log.Printf("unable to render the invite of appointment %s: %v", event.Id, err)
+		return
+	}
+
+	if err := a.sendEmail(ctx, change, variables, to, invite, method); err != nil {
+		log.Printf("unable to email the attendees of appointment %s: %v", event.Id, err)
+	}
+	if len(phones) == 0 {
+		return
+	}
+	if err := a.sendSMS(ctx, change, variables, phones); err != nil {
+		log.Printf("unable to send SMS to the attendees of appointment %s: %v", event.Id, err)
+	}
+}
+
+func (a *AppointmentImpl) sendEmail(
+	ctx context.Context,
+	change string,
+	variables map[string]interface{},
+	to []string,
+	invite []byte,
+	method string,
+) error {
+	name := "appointment_" + change + "_email"
+	variables = escapeHTMLVariables(variables)
+
+	subject, body := defaultAppointmentEmailSubjects[change], defaultAppointmentEmailBodies[change]
+	rendered, err := a.Templates.RenderTemplate(ctx, name, nil, variables)
+	switch {
+	case errors.Is(err, exceptions.ErrTemplateNotFound):
+		if subject, err = renderText(subject, variables); err != nil {
+			return fmt.Errorf("unable to render the default %s subject: %w", name, err)
+		}
+		if body, err = renderText(body, variables); err != nil {
+			return fmt.Errorf("unable to render the default %s body: %w", name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("unable to render the %s template: %w", name, err)
+	default:
+		if subject, err = renderText(subject, variables); err != nil {
+			return fmt.Errorf("unable to render the default %s subject: %w", name, err)
+		}
+		if rendered.Subject != nil {
+			subject = *rendered.Subject
+		}
+		body = rendered.Body
+	}
+
+	body, err = inlineCSS(body)
+	if err != nil {
+		return fmt.Errorf("unable to inline the CSS of the email: %w", err)
+	}
+	text, err := htmlToText(body)
+	if err != nil {
+		return fmt.Errorf("unable to convert the email to text: %w", err)
+	}
+	_, err = a.Email.Send(ctx, &email.Message{
+		To:      to,
+		Subject: html.UnescapeString(subject),
+		Text:    text,
+		HTML:    body,
+		Attachments: []email.Attachment{
+			{
+				Filename:    appointmentInviteFilename,
+				ContentType: "text/calendar; charset=utf-8; method=" + method,
+				Data:        invite,
+			},
+		},
+	})
+	return err
+}
+
+func (a *AppointmentImpl) sendSMS(
+	ctx context.Context,
+	change string,
+	variables map[string]interface{},
+	to []string,
+) error {
+	name := "appointment_" + change + "_sms"
+
+	var message string
+	rendered, err := a.Templates.RenderTemplate(ctx, name, nil, variables)
+	switch {
+	case errors.Is(err, exceptions.ErrTemplateNotFound):
+		if message, err = renderText(defaultAppointmentSMS[change], variables); err != nil {
+			return fmt.Errorf("unable to render the default %s message: %w", name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("unable to render the %s template: %w", name, err)
+	default:
+		message = rendered.Body
+	}
+
+	_, err = a.SMS.SendToMany(ctx, message, to, enumutils.SenderIDBewell)
+	return err
+}
+
+// appointmentVariables are the variables that appointment notifications are
+// rendered with. Times are shown in the appointment's time zone.
+func appointmentVariables(event *gcal.Event) (map[string]interface{}, error) {
+	start, end, err := calendarEventInterval(event)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"summary":      event.Summary,
+		"start":        start.Format(appointmentTimeLayout),
+		"end":          end.Format(appointmentTimeLayout),
+		"timeZone":     start.Location().String(),
+		"location":     event.Location,
+		"description":  event.Description,
+		"calendarLink": "",
+	}, nil
+}
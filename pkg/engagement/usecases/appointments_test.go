@@ -0,0 +1,578 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+var testAppointmentConfig = usecases.AppointmentConfig{
+	SigningKey: []byte("0123456789abcdef0123456789abcdef"),
+	BaseURL:    "https://engagement.example.com/",
+	Organizer:  "appointments@example.com",
+}
+
+func newTestAppointments(f *testFixture) (*usecases.AppointmentImpl, usecases.TemplateUsecases) {
+	templates := usecases.NewTemplates(f.repository)
+	appointments, err := usecases.NewAppointments(
+		usecases.NewCalendarStore(f.repository),
+		templates,
+		f.emailService(),
+		f.smsService(),
+		f.repository,
+		testAppointmentConfig,
+	)
+	assert.Nil(f.t, err)
+	appointments.Clock = f.clock.Now
+	return appointments, templates
+}
+
+func testAppointmentInput(start time.Time, attendees ...*dto.AppointmentAttendeeInput) dto.AppointmentInput {
+	location := "Be.Well Clinic, Nairobi"
+	return dto.AppointmentInput{
+		Summary:   "Consultation",
+		Location:  &location,
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+		Attendees: attendees,
+	}
+}
+
+func TestCalendarStore(t *testing.T) {
+	ctx := context.Background()
+	store := usecases.NewCalendarStore(memory.NewRepository())
+	start := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	event := func(start time.Time, duration time.Duration) *gcal.Event {
+		return &gcal.Event{
+			Summary: "Consultation",
+			Start:   &gcal.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:     &gcal.EventDateTime{DateTime: start.Add(duration).Format(time.RFC3339)},
+		}
+	}
+
+	first, err := store.InsertEvent(ctx, event(start, time.Hour))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, first.Id)
+	assert.Equal(t, first.Id+"@bewell.co.ke", first.ICalUID)
+	assert.Equal(t, "confirmed", first.Status)
+
+	second, err := store.InsertEvent(ctx, event(start.Add(2*time.Hour), time.Hour))
+	assert.Nil(t, err)
+
+	_, err = store.InsertEvent(ctx, event(start, 25*time.Hour))
+	assert.NotNil(t, err)
+	_, err = store.InsertEvent(ctx, event(start, -time.Hour))
+	assert.NotNil(t, err)
+
+	_, err = store.GetEvent(ctx, "unknown")
+	assert.True(t, errors.Is(err, exceptions.ErrCalendarEventNotFound))
+	_, err = store.UpdateEvent(ctx, &gcal.Event{Id: "unknown"})
+	assert.True(t, errors.Is(err, exceptions.ErrCalendarEventNotFound))
+
+	// events that overlap the interval are listed in order of their start
+	events, err := store.ListEvents(ctx, start.Add(30*time.Minute), start.Add(150*time.Minute), false)
+	assert.Nil(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, first.Id, events[0].Id)
+		assert.Equal(t, second.Id, events[1].Id)
+	}
+	events, err = store.ListEvents(ctx, start.Add(time.Hour), start.Add(2*time.Hour), false)
+	assert.Nil(t, err)
+	assert.Len(t, events, 0)
+
+	cancelled := *second
+	cancelled.Status = "cancelled"
+	updated, err := store.UpdateEvent(ctx, &cancelled)
+	assert.Nil(t, err)
+	assert.Equal(t, second.ICalUID, updated.ICalUID)
+	assert.Equal(t, second.Created, updated.Created)
+
+	events, err = store.ListEvents(ctx, start, start.Add(4*time.Hour), false)
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+	events, err = store.ListEvents(ctx, start, start.Add(4*time.Hour), true)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestAppointmentImpl_CreateAppointment(t *testing.T) {
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	phone := "0711223344"
+	invalidPhone := "12"
+	timeZone := "Africa/Lagos"
+	invalidTimeZone := "Mars/Olympus_Mons"
+
+	tests := []struct {
+		name    string
+		input   func() dto.AppointmentInput
+		wantErr bool
+	}{
+		{
+			name: "valid appointment",
+			input: func() dto.AppointmentInput {
+				return testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "Jane@Example.com", Phone: &phone})
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid appointment in a time zone",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.TimeZone = &timeZone
+				return input
+			},
+			wantErr: false,
+		},
+		{
+			name: "no summary",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.Summary = " "
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "ends before it starts",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.End = start.Add(-time.Minute)
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "too long",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.End = start.Add(25 * time.Hour)
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid time zone",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.TimeZone = &invalidTimeZone
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "no attendees",
+			input: func() dto.AppointmentInput {
+				return testAppointmentInput(start)
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid email",
+			input: func() dto.AppointmentInput {
+				return testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate attendee",
+			input: func() dto.AppointmentInput {
+				return testAppointmentInput(
+					start,
+					&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+					&dto.AppointmentAttendeeInput{Email: "JANE@example.com"},
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "no reminders",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.ReminderMinutes = []int{}
+				return input
+			},
+			wantErr: false,
+		},
+		{
+			name: "too many reminders",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.ReminderMinutes = []int{5, 10, 15, 30, 60, 120}
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "reminder too early",
+			input: func() dto.AppointmentInput {
+				input := testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com"})
+				input.ReminderMinutes = []int{usecases.MaxAppointmentReminderMinutes + 1}
+				return input
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid phone",
+			input: func() dto.AppointmentInput {
+				return testAppointmentInput(start, &dto.AppointmentAttendeeInput{Email: "jane@example.com", Phone: &invalidPhone})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFixture(t)
+			appointments, _ := newTestAppointments(f)
+			event, err := appointments.CreateAppointment(context.Background(), "uid", tt.input())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateAppointment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.Len(t, f.emails, 0)
+				return
+			}
+			assert.Equal(t, int64(0), event.Sequence)
+			assert.Equal(t, "confirmed", event.Status)
+			assert.Equal(t, "jane@example.com", event.Attendees[0].Email)
+			assert.Equal(t, []string{"Appointment scheduled: Consultation"}, f.subjectsTo("jane@example.com"))
+		})
+	}
+}
+
+func TestAppointmentImpl_CreateAppointment_Notifications(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+	phone := "+254711223344"
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	event, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com", Phone: &phone},
+		&dto.AppointmentAttendeeInput{Email: "john@example.com"},
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-06-01T09:00:00+03:00", event.Start.DateTime)
+	assert.Equal(t, usecases.DefaultAppointmentTimeZone, event.Start.TimeZone)
+
+	if assert.Len(t, f.emails, 1) {
+		assert.Equal(t, []string{"jane@example.com", "john@example.com"}, f.emails[0].To)
+		assert.Contains(t, f.emails[0].Text, "Tuesday 1 June 2021, 09:00")
+		assert.Contains(t, f.emails[0].Text, "Be.Well Clinic, Nairobi")
+	}
+	if assert.Len(t, f.sms.messagesTo(phone), 1) {
+		assert.Contains(t, f.sms.messagesTo(phone)[0], "Tuesday 1 June 2021, 09:00 (Africa/Nairobi)")
+	}
+	assert.Len(t, f.sms.to, 1)
+}
+
+func TestAppointmentImpl_CreateAppointment_Template(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, templates := newTestAppointments(f)
+
+	subject := "See you at {{.start}}"
+	_, err := templates.CreateTemplate(ctx, dto.NotificationTemplateInput{
+		Name:            "appointment_scheduled_email",
+		Channel:         feedlib.ChannelEmail,
+		DefaultLanguage: enumutils.LanguageEn,
+		Variables:       []string{"summary", "start", "end", "timeZone", "location", "description"},
+		Variants: []*dto.TemplateVariantInput{
+			{
+				Language: enumutils.LanguageEn,
+				Subject:  &subject,
+				Body:     "<p>{{.summary}} at {{.location}}</p>",
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	_, err = appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.Nil(t, err)
+	if assert.Len(t, f.emails, 1) {
+		assert.Equal(t, "See you at Tuesday 1 June 2021, 09:00", f.emails[0].Subject)
+		assert.Equal(t, "Consultation at Be.Well Clinic, Nairobi", strings.TrimSpace(f.emails[0].Text))
+	}
+}
+
+func TestAppointmentImpl_UpdateAppointment(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	created, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+		&dto.AppointmentAttendeeInput{Email: "john@example.com"},
+	))
+	assert.Nil(t, err)
+
+	updated, err := appointments.UpdateAppointment(ctx, created.Id, testAppointmentInput(
+		start.Add(time.Hour),
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+		&dto.AppointmentAttendeeInput{Email: "mary@example.com"},
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, created.Id, updated.Id)
+	assert.Equal(t, created.ICalUID, updated.ICalUID)
+	assert.Equal(t, int64(1), updated.Sequence)
+	assert.Equal(t, "2021-06-01T10:00:00+03:00", updated.Start.DateTime)
+
+	assert.Equal(
+		t,
+		[]string{"Appointment scheduled: Consultation", "Appointment changed: Consultation"},
+		f.subjectsTo("jane@example.com"),
+	)
+	assert.Equal(
+		t,
+		[]string{"Appointment scheduled: Consultation", "Appointment cancelled: Consultation"},
+		f.subjectsTo("john@example.com"),
+	)
+	assert.Equal(t, []string{"Appointment scheduled: Consultation"}, f.subjectsTo("mary@example.com"))
+
+	_, err = appointments.UpdateAppointment(ctx, "unknown", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.True(t, errors.Is(err, exceptions.ErrCalendarEventNotFound))
+}
+
+func TestAppointmentImpl_CancelAppointment(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	created, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.Nil(t, err)
+
+	cancelled, err := appointments.CancelAppointment(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, int64(1), cancelled.Sequence)
+	assert.Equal(
+		t,
+		[]string{"Appointment scheduled: Consultation", "Appointment cancelled: Consultation"},
+		f.subjectsTo("jane@example.com"),
+	)
+
+	_, err = appointments.CancelAppointment(ctx, created.Id)
+	assert.NotNil(t, err)
+	_, err = appointments.UpdateAppointment(ctx, created.Id, testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.NotNil(t, err)
+
+	event, err := appointments.GetAppointment(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "cancelled", event.Status)
+}
+
+func TestAppointmentImpl_ListAppointments(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	first, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.Nil(t, err)
+	second, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start.Add(time.Hour),
+		&dto.AppointmentAttendeeInput{Email: "john@example.com"},
+	))
+	assert.Nil(t, err)
+	_, err = appointments.CancelAppointment(ctx, second.Id)
+	assert.Nil(t, err)
+
+	from, to := start, start.Add(2*time.Hour)
+	events, err := appointments.ListAppointments(ctx, from, to, false, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, first.Id, events[0].Id)
+	}
+
+	events, err = appointments.ListAppointments(ctx, from, to, true, nil)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+
+	attendee := "John@Example.com"
+	events, err = appointments.ListAppointments(ctx, from, to, true, &attendee)
+	assert.Nil(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, second.Id, events[0].Id)
+	}
+
+	_, err = appointments.ListAppointments(ctx, to, from, false, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNewAppointments(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func() usecases.AppointmentConfig
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			config: func() usecases.AppointmentConfig {
+				return testAppointmentConfig
+			},
+			wantErr: false,
+		},
+		{
+			name: "short signing key",
+			config: func() usecases.AppointmentConfig {
+				config := testAppointmentConfig
+				config.SigningKey = []byte("secret")
+				return config
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid base URL",
+			config: func() usecases.AppointmentConfig {
+				config := testAppointmentConfig
+				config.BaseURL = "engagement"
+				return config
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid organizer",
+			config: func() usecases.AppointmentConfig {
+				config := testAppointmentConfig
+				config.Organizer = "appointments"
+				return config
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecases.NewAppointments(nil, nil, nil, nil, nil, tt.config())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAppointments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppointmentImpl_Invites(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	created, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+		&dto.AppointmentAttendeeInput{Email: "john@example.com"},
+	))
+	assert.Nil(t, err)
+	if assert.Len(t, f.emails, 1) && assert.Len(t, f.emails[0].Attachments, 1) {
+		attachment := f.emails[0].Attachments[0]
+		assert.Equal(t, "invite.ics", attachment.Filename)
+		assert.Equal(t, "text/calendar; charset=utf-8; method=REQUEST", attachment.ContentType)
+		assert.Contains(t, f.emails[0].HTML, "https://engagement.example.com/appointments/ics?")
+	}
+
+	_, err = appointments.UpdateAppointment(ctx, created.Id, testAppointmentInput(
+		start.Add(time.Hour),
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.Nil(t, err)
+	_, err = appointments.CancelAppointment(ctx, created.Id)
+	assert.Nil(t, err)
+
+	jane := f.invitesTo("jane@example.com")
+	if assert.Len(t, jane, 3) {
+		assert.Contains(t, jane[0], "METHOD:REQUEST\r\n")
+		assert.Contains(t, jane[0], "SEQUENCE:0\r\n")
+		assert.Contains(t, jane[1], "METHOD:REQUEST\r\n")
+		assert.Contains(t, jane[1], "SEQUENCE:1\r\n")
+		assert.Contains(t, jane[1], "DTSTART;TZID=Africa/Nairobi:20210601T100000\r\n")
+		assert.Contains(t, jane[2], "METHOD:CANCEL\r\n")
+		assert.Contains(t, jane[2], "SEQUENCE:2\r\n")
+		assert.Contains(t, jane[2], "STATUS:CANCELLED\r\n")
+		for _, invite := range jane {
+			assert.Contains(t, invite, "UID:"+created.ICalUID+"\r\n")
+		}
+	}
+
+	// the removed attendee's calendar drops the appointment that they
+	// were invited to
+	john := f.invitesTo("john@example.com")
+	if assert.Len(t, john, 2) {
+		assert.Contains(t, john[1], "METHOD:CANCEL\r\n")
+		assert.Contains(t, john[1], "SEQUENCE:1\r\n")
+		assert.Contains(t, john[1], "DTSTART;TZID=Africa/Nairobi:20210601T090000\r\n")
+		assert.Contains(t, john[1], "mailto:john@example.com\r\n")
+		assert.NotContains(t, john[1], "mailto:jane@example.com")
+	}
+}
+
+func TestAppointmentImpl_AppointmentICSLink(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	appointments, _ := newTestAppointments(f)
+
+	start := time.Date(2021, 6, 1, 6, 0, 0, 0, time.UTC)
+	created, err := appointments.CreateAppointment(ctx, "uid", testAppointmentInput(
+		start,
+		&dto.AppointmentAttendeeInput{Email: "jane@example.com"},
+	))
+	assert.Nil(t, err)
+
+	_, err = appointments.AppointmentICSLink(ctx, "unknown")
+	assert.True(t, errors.Is(err, exceptions.ErrCalendarEventNotFound))
+
+	link, err := appointments.AppointmentICSLink(ctx, created.Id)
+	assert.Nil(t, err)
+	parsed, err := url.Parse(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "engagement.example.com", parsed.Host)
+	assert.Equal(t, usecases.AppointmentICSPath, parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, created.Id, query.Get("id"))
+
+	err = appointments.VerifyAppointmentICSSignature(query.Get("id"), query.Get("expires"), query.Get("signature"))
+	assert.Nil(t, err)
+	err = appointments.VerifyAppointmentICSSignature("other", query.Get("expires"), query.Get("signature"))
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidAppointmentICSSignature))
+	err = appointments.VerifyAppointmentICSSignature(query.Get("id"), "9999999999", query.Get("signature"))
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidAppointmentICSSignature))
+
+	document, err := appointments.AppointmentICS(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Contains(t, string(document), "METHOD:PUBLISH\r\n")
+
+	f.clock.Advance(usecases.AppointmentICSLinkExpiry)
+	err = appointments.VerifyAppointmentICSSignature(query.Get("id"), query.Get("expires"), query.Get("signature"))
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidAppointmentICSSignature))
+}
@@ -0,0 +1,617 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/segmentio/ksuid"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+const (
+	// MinSlotMinutes is the shortest slot that a clinician can be booked for
+	MinSlotMinutes = 5
+
+	// MaxFreeSlotsRange is the longest interval that free slots can be
+	// searched in
+	MaxFreeSlotsRange = 31 * 24 * time.Hour
+
+	// MaxAvailabilityExceptionDuration is the longest that an availability
+	// exception can be
+	MaxAvailabilityExceptionDuration = 366 * 24 * time.Hour
+
+	workingHoursLayout = "15:04"
+)
+
+// AvailabilityUsecases represent logic required to manage when clinicians
+// can be booked and to book appointments in their free slots
+type AvailabilityUsecases interface {
+	// SetClinicianAvailability creates or replaces a clinician's working
+	// hours, slot duration and buffer at a location
+	SetClinicianAvailability(
+		ctx context.Context,
+		updatedBy string,
+		input dto.ClinicianAvailabilityInput,
+	) (*domain.ClinicianAvailability, error)
+
+	ListClinicianAvailability(ctx context.Context, clinicianID string) ([]*domain.ClinicianAvailability, error)
+
+	DeleteClinicianAvailability(ctx context.Context, clinicianID string, locationID string) error
+
+	// AddAvailabilityException adds leave or extra hours to a clinician's
+	// availability
+	AddAvailabilityException(
+		ctx context.Context,
+		createdBy string,
+		input dto.AvailabilityExceptionInput,
+	) (*domain.AvailabilityException, error)
+
+	DeleteAvailabilityException(ctx context.Context, id string) error
+
+	// ListAvailabilityExceptions returns a clinician's exceptions that
+	// overlap an interval ordered by their start
+	ListAvailabilityExceptions(
+		ctx context.Context,
+		clinicianID string,
+		from, to time.Time,
+	) ([]*domain.AvailabilityException, error)
+
+	// FreeSlots returns the slots that start in an interval and that a
+	// clinician can be booked for, optionally only those at a location.
+	// They are ordered by their start then location.
+	FreeSlots(
+		ctx context.Context,
+		clinicianID string,
+		from, to time.Time,
+		locationID *string,
+	) ([]*dto.FreeSlot, error)
+
+	// BookSlot books an appointment in one of a clinician's free slots.
+	// It returns exceptions.ErrSlotUnavailable when the slot is not free,
+	// including when it was booked by a concurrent request.
+	BookSlot(ctx context.Context, bookedBy string, input dto.BookSlotInput) (*gcal.Event, error)
+
+	// CancelSlotBooking cancels a booked appointment and frees its slot
+	CancelSlotBooking(ctx context.Context, appointmentID string) (*gcal.Event, error)
+}
+
+// AvailabilityImpl represents the availability usecase implementation
+type AvailabilityImpl struct {
+	Repository   repository.AvailabilityRepository
+	Appointments AppointmentUsecases
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewAvailability initializes an availability usecase
+func NewAvailability(
+	repository repository.AvailabilityRepository,
+	appointments AppointmentUsecases,
+) *AvailabilityImpl {
+	return &AvailabilityImpl{
+		Repository:   repository,
+		Appointments: appointments,
+		Clock:        time.Now,
+	}
+}
+
+// SetClinicianAvailability validates and saves a clinician's availability,
+// replacing the one at the same location
+func (a *AvailabilityImpl) SetClinicianAvailability(
+	ctx context.Context,
+	updatedBy string,
+	input dto.ClinicianAvailabilityInput,
+) (*domain.ClinicianAvailability, error) {
+	clinicianID, err := validateClinicianID(input.ClinicianID)
+	if err != nil {
+		return nil, err
+	}
+	locationID := strings.TrimSpace(input.LocationID)
+	if locationID == "" {
+		return nil, fmt.Errorf("an availability needs a location ID")
+	}
+	clinicianEmail := normalizeEmail(input.ClinicianEmail)
+	if !govalidator.IsEmail(clinicianEmail) {
+		return nil, fmt.Errorf("%s is not a valid email", input.ClinicianEmail)
+	}
+	timeZone := DefaultAppointmentTimeZone
+	if input.TimeZone != nil && strings.TrimSpace(*input.TimeZone) != "" {
+		timeZone = strings.TrimSpace(*input.TimeZone)
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("%q is not a valid time zone", timeZone)
+	}
+	maxSlotMinutes := int(domain.MaxSlotDuration / time.Minute)
+	if input.SlotMinutes < MinSlotMinutes || input.SlotMinutes > maxSlotMinutes {
+		return nil, fmt.Errorf("slots must be between %d and %d minutes", MinSlotMinutes, maxSlotMinutes)
+	}
+	maxBufferMinutes := int(domain.MaxSlotBuffer / time.Minute)
+	if input.BufferMinutes < 0 || input.BufferMinutes > maxBufferMinutes {
+		return nil, fmt.Errorf("buffers must be between 0 and %d minutes", maxBufferMinutes)
+	}
+	workingHours, err := validateWorkingHours(input.WorkingHours)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &domain.ClinicianAvailability{
+		ClinicianID:    clinicianID,
+		ClinicianEmail: clinicianEmail,
+		LocationID:     locationID,
+		TimeZone:       timeZone,
+		WorkingHours:   workingHours,
+		SlotMinutes:    input.SlotMinutes,
+		BufferMinutes:  input.BufferMinutes,
+		UpdatedBy:      updatedBy,
+		UpdatedAt:      a.Clock(),
+	}
+	if input.Location != nil {
+		availability.Location = strings.TrimSpace(*input.Location)
+	}
+
+	existing, err := a.Repository.GetClinicianAvailability(ctx, clinicianID, locationID)
+	switch {
+	case err == nil:
+		availability.ID = existing.ID
+	case errors.Is(err, exceptions.ErrClinicianAvailabilityNotFound):
+		availability.ID = ksuid.New().String()
+	default:
+		return nil, err
+	}
+	if err := a.Repository.SaveClinicianAvailability(ctx, availability); err != nil {
+		return nil, fmt.Errorf("unable to save clinician availability: %w", err)
+	}
+	return availability, nil
+}
+
+// ListClinicianAvailability returns a clinician's availability at each of
+// their locations
+func (a *AvailabilityImpl) ListClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+) ([]*domain.ClinicianAvailability, error) {
+	return a.Repository.ListClinicianAvailability(ctx, clinicianID)
+}
+
+// DeleteClinicianAvailability removes a clinician's availability at a
+// location. Appointments that have been booked are kept.
+func (a *AvailabilityImpl) DeleteClinicianAvailability(
+	ctx context.Context,
+	clinicianID string,
+	locationID string,
+) error {
+	availability, err := a.Repository.GetClinicianAvailability(ctx, clinicianID, locationID)
+	if err != nil {
+		return err
+	}
+	return a.Repository.DeleteClinicianAvailability(ctx, availability.ID)
+}
+
+// AddAvailabilityException validates and saves an availability exception
+func (a *AvailabilityImpl) AddAvailabilityException(
+	ctx context.Context,
+	createdBy string,
+	input dto.AvailabilityExceptionInput,
+) (*domain.AvailabilityException, error) {
+	clinicianID, err := validateClinicianID(input.ClinicianID)
+	if err != nil {
+		return nil, err
+	}
+	if !input.End.After(input.Start) {
+		return nil, fmt.Errorf("an availability exception must end after it starts")
+	}
+	if input.End.Sub(input.Start) > MaxAvailabilityExceptionDuration {
+		return nil, fmt.Errorf(
+			"an availability exception can't be longer than %d days",
+			int(MaxAvailabilityExceptionDuration/(24*time.Hour)),
+		)
+	}
+
+	exception := &domain.AvailabilityException{
+		ID:          ksuid.New().String(),
+		ClinicianID: clinicianID,
+		Start:       input.Start.UTC(),
+		End:         input.End.UTC(),
+		Available:   input.Available,
+		CreatedBy:   createdBy,
+		CreatedAt:   a.Clock(),
+	}
+	if input.LocationID != nil && strings.TrimSpace(*input.LocationID) != "" {
+		exception.LocationID = strings.TrimSpace(*input.LocationID)
+		// extra hours are laid out in the slots of the location
+		_, err := a.Repository.GetClinicianAvailability(ctx, clinicianID, exception.LocationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.Reason != nil {
+		exception.Reason = strings.TrimSpace(*input.Reason)
+	}
+	if err := a.Repository.SaveAvailabilityException(ctx, exception); err != nil {
+		return nil, fmt.Errorf("unable to save availability exception: %w", err)
+	}
+	return exception, nil
+}
+
+// DeleteAvailabilityException removes an availability exception
+func (a *AvailabilityImpl) DeleteAvailabilityException(ctx context.Context, id string) error {
+	return a.Repository.DeleteAvailabilityException(ctx, id)
+}
+
+// ListAvailabilityExceptions returns a clinician's availability exceptions
+func (a *AvailabilityImpl) ListAvailabilityExceptions(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+) ([]*domain.AvailabilityException, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("the end of the interval must be after its start")
+	}
+	return a.Repository.ListAvailabilityExceptions(ctx, clinicianID, from, to)
+}
+
+// FreeSlots lays out the slots of each of a clinician's working periods and
+// available exceptions, and drops those that are in the past, overlap an
+// unavailable exception or are within a buffer of a booking.
+//
+// Working hours are laid out in the availability's time zone, so a slot
+// keeps its local time across daylight saving time changes.
+func (a *AvailabilityImpl) FreeSlots(
+	ctx context.Context,
+	clinicianID string,
+	from, to time.Time,
+	locationID *string,
+) ([]*dto.FreeSlot, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("the end of the interval must be after its start")
+	}
+	if to.Sub(from) > MaxFreeSlotsRange {
+		return nil, fmt.Errorf(
+			"free slots can only be searched for up to %d days",
+			int(MaxFreeSlotsRange/(24*time.Hour)),
+		)
+	}
+
+	availabilities := []*domain.ClinicianAvailability{}
+	if locationID != nil {
+		availability, err := a.Repository.GetClinicianAvailability(ctx, clinicianID, *locationID)
+		if err != nil {
+			return nil, err
+		}
+		availabilities = append(availabilities, availability)
+	} else {
+		all, err := a.Repository.ListClinicianAvailability(ctx, clinicianID)
+		if err != nil {
+			return nil, err
+		}
+		availabilities = all
+	}
+	return freeSlots(ctx, a.Repository, availabilities, clinicianID, from, to, a.Clock(), "")
+}
+
+// freeSlots lays out the free slots of a clinician's availabilities that
+// start in an interval. The booking with the ID in `moving`, if any, does not
+// hold its time so that it can be moved to an overlapping slot.
+func freeSlots(
+	ctx context.Context,
+	repository repository.AvailabilityRepository,
+	availabilities []*domain.ClinicianAvailability,
+	clinicianID string,
+	from, to time.Time,
+	now time.Time,
+	moving string,
+) ([]*dto.FreeSlot, error) {
+	if len(availabilities) == 0 {
+		return []*dto.FreeSlot{}, nil
+	}
+
+	// available exceptions that start before the interval can have slots
+	// that start in it
+	exceptions, err := repository.ListAvailabilityExceptions(ctx, clinicianID, from, to.Add(domain.MaxSlotDuration))
+	if err != nil {
+		return nil, err
+	}
+	// a clinician's bookings at any location hold their time
+	listed, err := repository.ListSlotBookings(
+		ctx,
+		clinicianID,
+		from.Add(-domain.MaxSlotBuffer),
+		to.Add(domain.MaxSlotDuration+domain.MaxSlotBuffer),
+	)
+	if err != nil {
+		return nil, err
+	}
+	bookings := []*domain.SlotBooking{}
+	for _, booking := range listed {
+		if booking.ID != moving {
+			bookings = append(bookings, booking)
+		}
+	}
+
+	slots := []*dto.FreeSlot{}
+	for _, availability := range availabilities {
+		loc, err := time.LoadLocation(availability.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid time zone", availability.TimeZone)
+		}
+		slot, buffer := availability.SlotDuration(), availability.Buffer()
+
+		seen := map[time.Time]bool{}
+		for _, period := range availabilityPeriods(availability, exceptions, from, to, loc) {
+			for start := period[0]; !start.Add(slot).After(period[1]); start = start.Add(slot + buffer) {
+				end := start.Add(slot)
+				if start.Before(from) || !start.Before(to) || start.Before(now) || seen[start] {
+					continue
+				}
+				if slotUnavailable(availability, exceptions, bookings, start, end, buffer) {
+					continue
+				}
+				seen[start] = true
+				slots = append(slots, &dto.FreeSlot{
+					ClinicianID: availability.ClinicianID,
+					LocationID:  availability.LocationID,
+					Start:       start,
+					End:         end,
+				})
+			}
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if !slots[i].Start.Equal(slots[j].Start) {
+			return slots[i].Start.Before(slots[j].Start)
+		}
+		return slots[i].LocationID < slots[j].LocationID
+	})
+	return slots, nil
+}
+
+// BookSlot holds a free slot for the clinician, then schedules the
+// appointment with the clinician as an attendee. The slot is released when
+// the appointment can't be scheduled.
+func (a *AvailabilityImpl) BookSlot(
+	ctx context.Context,
+	bookedBy string,
+	input dto.BookSlotInput,
+) (*gcal.Event, error) {
+	availability, err := a.Repository.GetClinicianAvailability(ctx, input.ClinicianID, input.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	end := input.Start.Add(availability.SlotDuration())
+
+	free, err := a.FreeSlots(ctx, input.ClinicianID, input.Start, end, &input.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	isFree := false
+	for _, slot := range free {
+		if slot.Start.Equal(input.Start) {
+			isFree = true
+			break
+		}
+	}
+	if !isFree {
+		return nil, exceptions.ErrSlotUnavailable
+	}
+
+	now := a.Clock()
+	booking := &domain.SlotBooking{
+		ID:          ksuid.New().String(),
+		ClinicianID: availability.ClinicianID,
+		LocationID:  availability.LocationID,
+		Start:       input.Start.UTC(),
+		End:         end.UTC(),
+		Status:      domain.SlotBookingStatusBooked,
+		BookedBy:    bookedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := a.Repository.BookSlot(ctx, booking, availability.Buffer()); err != nil {
+		if errors.Is(err, exceptions.ErrSlotUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to book slot: %w", err)
+	}
+
+	attendees := []*dto.AppointmentAttendeeInput{}
+	clinicianIsAttendee := false
+	for _, attendee := range input.Attendees {
+		if attendee == nil {
+			continue
+		}
+		if normalizeEmail(attendee.Email) == availability.ClinicianEmail {
+			clinicianIsAttendee = true
+		}
+		attendees = append(attendees, attendee)
+	}
+	if !clinicianIsAttendee {
+		attendees = append(attendees, &dto.AppointmentAttendeeInput{Email: availability.ClinicianEmail})
+	}
+	appointment := dto.AppointmentInput{
+		Summary:         input.Summary,
+		Description:     input.Description,
+		Start:           booking.Start,
+		End:             booking.End,
+		TimeZone:        &availability.TimeZone,
+		Attendees:       attendees,
+		ReminderMinutes: input.ReminderMinutes,
+	}
+	if availability.Location != "" {
+		appointment.Location = &availability.Location
+	}
+
+	event, err := a.Appointments.CreateAppointment(ctx, bookedBy, appointment)
+	if err != nil {
+		if err := a.Repository.DeleteSlotBooking(ctx, booking.ID); err != nil {
+			log.Printf("unable to release slot booking %s: %v", booking.ID, err)
+		}
+		return nil, err
+	}
+
+	// the appointment has been scheduled and its attendees notified, so
+	// failing to link it to the booking is logged
+	booking.EventID = event.Id
+	booking.UpdatedAt = a.Clock()
+	if err := a.Repository.SaveSlotBooking(ctx, booking); err != nil {
+		log.Printf("unable to link slot booking %s to appointment %s: %v", booking.ID, event.Id, err)
+	}
+	return event, nil
+}
+
+// CancelSlotBooking cancels an appointment that was booked in a slot.
+// Cancelling the appointment marks its booking as cancelled.
+func (a *AvailabilityImpl) CancelSlotBooking(ctx context.Context, appointmentID string) (*gcal.Event, error) {
+	if _, err := a.Repository.GetSlotBookingByEvent(ctx, appointmentID); err != nil {
+		return nil, err
+	}
+	return a.Appointments.CancelAppointment(ctx, appointmentID)
+}
+
+// availabilityPeriods returns the start and end of the working periods and
+// available exceptions of an availability that can have slots in an
+// interval
+func availabilityPeriods(
+	availability *domain.ClinicianAvailability,
+	exceptions []*domain.AvailabilityException,
+	from, to time.Time,
+	loc *time.Location,
+) [][2]time.Time {
+	periods := [][2]time.Time{}
+
+	// the days are walked as dates so that each one starts at its local
+	// midnight, whatever its length
+	first, last := from.In(loc), to.In(loc)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); day.Before(last); {
+		for _, hours := range availability.WorkingHours {
+			if hours.Day.Weekday() != day.Weekday() {
+				continue
+			}
+			start, _ := time.Parse(workingHoursLayout, hours.Start)
+			end, _ := time.Parse(workingHoursLayout, hours.End)
+			periods = append(periods, [2]time.Time{
+				time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc),
+				time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc),
+			})
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	for _, exception := range exceptions {
+		if exception.Available && exception.AppliesTo(availability.LocationID) {
+			periods = append(periods, [2]time.Time{exception.Start.In(loc), exception.End.In(loc)})
+		}
+	}
+	return periods
+}
+
+// slotUnavailable returns true if a slot overlaps an unavailable exception
+// or is within a buffer of a booking
+func slotUnavailable(
+	availability *domain.ClinicianAvailability,
+	exceptions []*domain.AvailabilityException,
+	bookings []*domain.SlotBooking,
+	start, end time.Time,
+	buffer time.Duration,
+) bool {
+	for _, exception := range exceptions {
+		if !exception.Available &&
+			exception.AppliesTo(availability.LocationID) &&
+			exception.Start.Before(end) &&
+			exception.End.After(start) {
+			return true
+		}
+	}
+	for _, booking := range bookings {
+		if booking.Conflicts(start, end, buffer) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWorkingHours checks that working periods are `HH:MM` times that
+// end after they start and don't overlap on a day, and orders them by day
+// then start
+func validateWorkingHours(input []*dto.WorkingHoursInput) ([]*domain.WorkingHours, error) {
+	type period struct {
+		hours      *domain.WorkingHours
+		start, end time.Time
+	}
+
+	periods := []period{}
+	for _, hours := range input {
+		if hours == nil {
+			continue
+		}
+		if !hours.Day.IsValid() {
+			return nil, fmt.Errorf("%s is not a valid day", hours.Day)
+		}
+		start, err := time.Parse(workingHoursLayout, strings.TrimSpace(hours.Start))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid HH:MM time", hours.Start)
+		}
+		end, err := time.Parse(workingHoursLayout, strings.TrimSpace(hours.End))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid HH:MM time", hours.End)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("working hours on %s must end after they start", hours.Day)
+		}
+		periods = append(periods, period{
+			hours: &domain.WorkingHours{
+				Day:   hours.Day,
+				Start: start.Format(workingHoursLayout),
+				End:   end.Format(workingHoursLayout),
+			},
+			start: start,
+			end:   end,
+		})
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("an availability needs at least one period of working hours")
+	}
+
+	// weeks start on Monday
+	dayIndex := func(day domain.Weekday) int {
+		return (int(day.Weekday()) + 6) % 7
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].hours.Day != periods[j].hours.Day {
+			return dayIndex(periods[i].hours.Day) < dayIndex(periods[j].hours.Day)
+		}
+		return periods[i].start.Before(periods[j].start)
+	})
+
+	workingHours := []*domain.WorkingHours{}
+	for i, p := range periods {
+		if i > 0 && periods[i-1].hours.Day == p.hours.Day && p.start.Before(periods[i-1].end) {
+			return nil, fmt.Errorf("working hours on %s overlap", p.hours.Day)
+		}
+		workingHours = append(workingHours, p.hours)
+	}
+	return workingHours, nil
+}
+
+// validateClinicianID trims a clinician ID, which names the documents that
+// serialise the clinician's bookings
+func validateClinicianID(clinicianID string) (string, error) {
+	clinicianID = strings.TrimSpace(clinicianID)
+	if clinicianID == "" {
+		return "", fmt.Errorf("a clinician ID is required")
+	}
+	if strings.Contains(clinicianID, "/") {
+		return "", fmt.Errorf("a clinician ID can't contain a /")
+	}
+	return clinicianID, nil
+}
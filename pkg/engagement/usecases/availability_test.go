@@ -0,0 +1,527 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/stretchr/testify/assert"
+)
+
+// nairobi is the default time zone of availability, three hours ahead of
+// UTC
+var nairobi = time.FixedZone("EAT", 3*60*60)
+
+func newTestAvailability(f *testFixture) *usecases.AvailabilityImpl {
+	f.clock.now = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	appointments, _ := newTestAppointments(f)
+	availability := usecases.NewAvailability(f.repository, appointments)
+	availability.Clock = f.clock.Now
+	return availability
+}
+
+// testAvailabilityInput is 30 minute slots with 10 minute breaks on Monday
+// and Wednesday mornings
+func testAvailabilityInput(locationID string) dto.ClinicianAvailabilityInput {
+	location := "Be.Well Clinic, Nairobi"
+	return dto.ClinicianAvailabilityInput{
+		ClinicianID:    "clinician-1",
+		ClinicianEmail: "Doctor@Example.com",
+		LocationID:     locationID,
+		Location:       &location,
+		WorkingHours: []*dto.WorkingHoursInput{
+			{Day: domain.WeekdayWednesday, Start: "09:00", End: "11:00"},
+			{Day: domain.WeekdayMonday, Start: "09:00", End: "11:00"},
+		},
+		SlotMinutes:   30,
+		BufferMinutes: 10,
+	}
+}
+
+func freeSlotStarts(slots []*dto.FreeSlot) []time.Time {
+	starts := []time.Time{}
+	for _, slot := range slots {
+		starts = append(starts, slot.Start.UTC())
+	}
+	return starts
+}
+
+// mondayAt is a time on Monday 7 June 2021 in Nairobi
+func mondayAt(hour, min int) time.Time {
+	return time.Date(2021, 6, 7, hour, min, 0, 0, nairobi)
+}
+
+func TestAvailabilityImpl_SetClinicianAvailability(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+
+	saved, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+	assert.Equal(t, "doctor@example.com", saved.ClinicianEmail)
+	assert.Equal(t, usecases.DefaultAppointmentTimeZone, saved.TimeZone)
+	assert.Equal(t, []*domain.WorkingHours{
+		{Day: domain.WeekdayMonday, Start: "09:00", End: "11:00"},
+		{Day: domain.WeekdayWednesday, Start: "09:00", End: "11:00"},
+	}, saved.WorkingHours)
+
+	input := testAvailabilityInput("clinic-1")
+	input.SlotMinutes = 20
+	replaced, err := availability.SetClinicianAvailability(ctx, "admin", input)
+	assert.Nil(t, err)
+	assert.Equal(t, saved.ID, replaced.ID)
+
+	_, err = availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-2"))
+	assert.Nil(t, err)
+	all, err := availability.ListClinicianAvailability(ctx, "clinician-1")
+	assert.Nil(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, 20, all[0].SlotMinutes)
+
+	tests := []struct {
+		name   string
+		modify func(input *dto.ClinicianAvailabilityInput)
+	}{
+		{
+			name:   "no clinician",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.ClinicianID = " " },
+		},
+		{
+			name:   "clinician ID with a slash",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.ClinicianID = "a/b" },
+		},
+		{
+			name:   "invalid email",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.ClinicianEmail = "doctor" },
+		},
+		{
+			name: "invalid time zone",
+			modify: func(input *dto.ClinicianAvailabilityInput) {
+				timeZone := "Mars/Olympus"
+				input.TimeZone = &timeZone
+			},
+		},
+		{
+			name:   "short slots",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.SlotMinutes = 1 },
+		},
+		{
+			name:   "long slots",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.SlotMinutes = 5 * 60 },
+		},
+		{
+			name:   "long buffer",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.BufferMinutes = 3 * 60 },
+		},
+		{
+			name:   "no working hours",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.WorkingHours = nil },
+		},
+		{
+			name: "invalid day",
+			modify: func(input *dto.ClinicianAvailabilityInput) {
+				input.WorkingHours[0].Day = domain.Weekday("SOMEDAY")
+			},
+		},
+		{
+			name:   "invalid time",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.WorkingHours[0].End = "25:00" },
+		},
+		{
+			name:   "ends before it starts",
+			modify: func(input *dto.ClinicianAvailabilityInput) { input.WorkingHours[0].End = "08:00" },
+		},
+		{
+			name: "overlapping hours",
+			modify: func(input *dto.ClinicianAvailabilityInput) {
+				input.WorkingHours = append(input.WorkingHours, &dto.WorkingHoursInput{
+					Day:   domain.WeekdayMonday,
+					Start: "10:30",
+					End:   "12:00",
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := testAvailabilityInput("clinic-3")
+			tt.modify(&input)
+			_, err := availability.SetClinicianAvailability(ctx, "admin", input)
+			assert.NotNil(t, err)
+		})
+	}
+
+	assert.Nil(t, availability.DeleteClinicianAvailability(ctx, "clinician-1", "clinic-2"))
+	err = availability.DeleteClinicianAvailability(ctx, "clinician-1", "clinic-2")
+	assert.True(t, errors.Is(err, exceptions.ErrClinicianAvailabilityNotFound))
+}
+
+func TestAvailabilityImpl_FreeSlots(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	_, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+
+	// slots are laid out from the start of the working hours with a break
+	// between them, and the last one has to end in them
+	slots, err := availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(24, 0), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{
+		mondayAt(9, 0).UTC(),
+		mondayAt(9, 40).UTC(),
+		mondayAt(10, 20).UTC(),
+	}, freeSlotStarts(slots))
+	assert.Equal(t, "clinic-1", slots[0].LocationID)
+	assert.Equal(t, mondayAt(9, 30).UTC(), slots[0].End.UTC())
+
+	week, err := availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(0, 0).AddDate(0, 0, 7), nil)
+	assert.Nil(t, err)
+	assert.Len(t, week, 6)
+
+	_, err = availability.AddAvailabilityException(ctx, "admin", dto.AvailabilityExceptionInput{
+		ClinicianID: "clinician-1",
+		Start:       mondayAt(9, 30),
+		End:         mondayAt(10, 0),
+	})
+	assert.Nil(t, err)
+	location := "clinic-1"
+	_, err = availability.AddAvailabilityException(ctx, "admin", dto.AvailabilityExceptionInput{
+		ClinicianID: "clinician-1",
+		LocationID:  &location,
+		Start:       mondayAt(14, 0),
+		End:         mondayAt(15, 0),
+		Available:   true,
+	})
+	assert.Nil(t, err)
+
+	slots, err = availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(24, 0), &location)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{
+		mondayAt(9, 0).UTC(),
+		mondayAt(10, 20).UTC(),
+		mondayAt(14, 0).UTC(),
+	}, freeSlotStarts(slots))
+
+	// slots that have started are not free
+	f.clock.now = mondayAt(9, 10)
+	slots, err = availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(12, 0), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{mondayAt(10, 20).UTC()}, freeSlotStarts(slots))
+
+	_, err = availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(0, 0).AddDate(0, 2, 0), nil)
+	assert.NotNil(t, err)
+	_, err = availability.FreeSlots(ctx, "clinician-1", mondayAt(1, 0), mondayAt(0, 0), nil)
+	assert.NotNil(t, err)
+
+	slots, err = availability.FreeSlots(ctx, "clinician-2", mondayAt(0, 0), mondayAt(24, 0), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, slots)
+}
+
+func TestAvailabilityImpl_FreeSlots_DaylightSaving(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	f.clock.now = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	input := testAvailabilityInput("clinic-1")
+	timeZone := "Europe/London"
+	input.TimeZone = &timeZone
+	input.WorkingHours = []*dto.WorkingHoursInput{{Day: domain.WeekdaySunday, Start: "09:00", End: "10:00"}}
+	input.SlotMinutes = 60
+	_, err := availability.SetClinicianAvailability(ctx, "admin", input)
+	assert.Nil(t, err)
+
+	// the clocks went forward on Sunday 28 March 2021, so the slot is an
+	// hour earlier in UTC
+	slots, err := availability.FreeSlots(
+		ctx,
+		"clinician-1",
+		time.Date(2021, 3, 20, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC),
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2021, 3, 21, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 28, 8, 0, 0, 0, time.UTC),
+	}, freeSlotStarts(slots))
+}
+
+func TestAvailabilityImpl_BookSlot(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	_, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+	_, err = availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-2"))
+	assert.Nil(t, err)
+
+	input := dto.BookSlotInput{
+		ClinicianID: "clinician-1",
+		LocationID:  "clinic-1",
+		Start:       mondayAt(9, 40),
+		Summary:     "Consultation",
+		Attendees:   []*dto.AppointmentAttendeeInput{{Email: "patient@example.com"}},
+	}
+	event, err := availability.BookSlot(ctx, "user-1", input)
+	assert.Nil(t, err)
+	assert.Equal(t, "Be.Well Clinic, Nairobi", event.Location)
+	assert.Equal(t, usecases.DefaultAppointmentTimeZone, event.Start.TimeZone)
+	assert.Len(t, event.Attendees, 2)
+	assert.Len(t, f.subjectsTo("doctor@example.com"), 1)
+	assert.Len(t, f.subjectsTo("patient@example.com"), 1)
+
+	// the clinician can't be booked at another location at the same time,
+	// and the slots within the break of the booking are taken
+	slots, err := availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(24, 0), nil)
+	assert.Nil(t, err)
+	assert.Len(t, slots, 4)
+	for _, slot := range slots {
+		assert.False(t, slot.Start.Equal(mondayAt(9, 40)))
+	}
+
+	tests := []struct {
+		name   string
+		modify func(input *dto.BookSlotInput)
+	}{
+		{
+			name:   "booked slot",
+			modify: func(input *dto.BookSlotInput) {},
+		},
+		{
+			name:   "booked slot at another location",
+			modify: func(input *dto.BookSlotInput) { input.LocationID = "clinic-2" },
+		},
+		{
+			name:   "not the start of a slot",
+			modify: func(input *dto.BookSlotInput) { input.Start = mondayAt(9, 5) },
+		},
+		{
+			name:   "outside working hours",
+			modify: func(input *dto.BookSlotInput) { input.Start = mondayAt(13, 0) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := input
+			tt.modify(&input)
+			_, err := availability.BookSlot(ctx, "user-2", input)
+			assert.True(t, errors.Is(err, exceptions.ErrSlotUnavailable))
+		})
+	}
+
+	// the slot is released when the appointment can't be scheduled
+	invalid := input
+	invalid.Start = mondayAt(9, 0)
+	invalid.Summary = " "
+	_, err = availability.BookSlot(ctx, "user-2", invalid)
+	assert.NotNil(t, err)
+	invalid.Summary = "Consultation"
+	_, err = availability.BookSlot(ctx, "user-2", invalid)
+	assert.Nil(t, err)
+
+	// cancelling frees the slot
+	cancelled, err := availability.CancelSlotBooking(ctx, event.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	input.LocationID = "clinic-2"
+	_, err = availability.BookSlot(ctx, "user-2", input)
+	assert.Nil(t, err)
+
+	_, err = availability.CancelSlotBooking(ctx, "unknown")
+	assert.True(t, errors.Is(err, exceptions.ErrSlotBookingNotFound))
+}
+
+func TestAvailabilityImpl_BookSlot_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	_, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+	_, err = availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-2"))
+	assert.Nil(t, err)
+
+	const requests = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		locationID := "clinic-1"
+		if i%2 == 1 {
+			locationID = "clinic-2"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := availability.BookSlot(ctx, "user-1", dto.BookSlotInput{
+				ClinicianID: "clinician-1",
+				LocationID:  locationID,
+				Start:       mondayAt(9, 0),
+				Summary:     "Consultation",
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	booked := 0
+	for err := range errs {
+		if err == nil {
+			booked++
+			continue
+		}
+		assert.True(t, errors.Is(err, exceptions.ErrSlotUnavailable))
+	}
+	assert.Equal(t, 1, booked)
+}
+
+func TestAvailabilityImpl_BookSlot_UpdateAndCancelAppointment(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	_, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+
+	book := func(start time.Time) string {
+		event, err := availability.BookSlot(ctx, "user-1", dto.BookSlotInput{
+			ClinicianID: "clinician-1",
+			LocationID:  "clinic-1",
+			Start:       start,
+			Summary:     "Consultation",
+		})
+		assert.Nil(t, err)
+		return event.Id
+	}
+	first := book(mondayAt(9, 40))
+	second := book(mondayAt(10, 20))
+	freeStarts := func() []time.Time {
+		slots, err := availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(24, 0), nil)
+		assert.Nil(t, err)
+		return freeSlotStarts(slots)
+	}
+	assert.Equal(t, []time.Time{mondayAt(9, 0).UTC()}, freeStarts())
+	clinician := &dto.AppointmentAttendeeInput{Email: "doctor@example.com"}
+
+	// an appointment can't be moved into the buffer of another booking
+	_, err = availability.Appointments.UpdateAppointment(ctx, first, testAppointmentInput(mondayAt(10, 0), clinician))
+	assert.True(t, errors.Is(err, exceptions.ErrSlotUnavailable))
+	event, err := availability.Appointments.GetAppointment(ctx, first)
+	assert.Nil(t, err)
+	start, err := time.Parse(time.RFC3339, event.Start.DateTime)
+	assert.Nil(t, err)
+	assert.True(t, start.Equal(mondayAt(9, 40)))
+
+	// nor outside the clinician's slots, or when they are away
+	wednesday := mondayAt(9, 0).AddDate(0, 0, 2)
+	_, err = availability.AddAvailabilityException(ctx, "admin", dto.AvailabilityExceptionInput{
+		ClinicianID: "clinician-1",
+		Start:       wednesday,
+		End:         wednesday.Add(time.Hour),
+	})
+	assert.Nil(t, err)
+	for _, start := range []time.Time{
+		mondayAt(14, 0),
+		mondayAt(9, 15),
+		mondayAt(9, 0).AddDate(0, 0, 1),
+		wednesday,
+	} {
+		_, err = availability.Appointments.UpdateAppointment(ctx, first, testAppointmentInput(start, clinician))
+		assert.True(t, errors.Is(err, exceptions.ErrSlotUnavailable), start)
+	}
+	longer := testAppointmentInput(mondayAt(9, 0), clinician)
+	longer.End = mondayAt(10, 0)
+	_, err = availability.Appointments.UpdateAppointment(ctx, first, longer)
+	assert.True(t, errors.Is(err, exceptions.ErrSlotUnavailable))
+
+	// it can be changed at the same time, or moved within its own buffer
+	_, err = availability.Appointments.UpdateAppointment(ctx, first, testAppointmentInput(mondayAt(9, 40), clinician))
+	assert.Nil(t, err)
+	_, err = availability.Appointments.UpdateAppointment(ctx, first, testAppointmentInput(mondayAt(9, 0), clinician))
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{mondayAt(9, 40).UTC()}, freeStarts())
+
+	// cancelling a booked appointment frees its slot
+	_, err = availability.Appointments.CancelAppointment(ctx, second)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{mondayAt(9, 40).UTC(), mondayAt(10, 20).UTC()}, freeStarts())
+	_, err = availability.CancelSlotBooking(ctx, first)
+	assert.Nil(t, err)
+	assert.Len(t, freeStarts(), 3)
+}
+
+func TestAvailabilityImpl_AddAvailabilityException(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	availability := newTestAvailability(f)
+	_, err := availability.SetClinicianAvailability(ctx, "admin", testAvailabilityInput("clinic-1"))
+	assert.Nil(t, err)
+
+	unknownLocation := "clinic-2"
+	tests := []struct {
+		name    string
+		input   dto.AvailabilityExceptionInput
+		wantErr bool
+	}{
+		{
+			name: "leave",
+			input: dto.AvailabilityExceptionInput{
+				ClinicianID: "clinician-1",
+				Start:       mondayAt(0, 0),
+				End:         mondayAt(0, 0).AddDate(0, 0, 7),
+			},
+		},
+		{
+			name: "ends before it starts",
+			input: dto.AvailabilityExceptionInput{
+				ClinicianID: "clinician-1",
+				Start:       mondayAt(10, 0),
+				End:         mondayAt(9, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "too long",
+			input: dto.AvailabilityExceptionInput{
+				ClinicianID: "clinician-1",
+				Start:       mondayAt(0, 0),
+				End:         mondayAt(0, 0).AddDate(2, 0, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "location without availability",
+			input: dto.AvailabilityExceptionInput{
+				ClinicianID: "clinician-1",
+				LocationID:  &unknownLocation,
+				Start:       mondayAt(9, 0),
+				End:         mondayAt(10, 0),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := availability.AddAvailabilityException(ctx, "admin", tt.input)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+
+	listed, err := availability.ListAvailabilityExceptions(ctx, "clinician-1", mondayAt(12, 0), mondayAt(13, 0))
+	assert.Nil(t, err)
+	assert.Len(t, listed, 1)
+	slots, err := availability.FreeSlots(ctx, "clinician-1", mondayAt(0, 0), mondayAt(0, 0).AddDate(0, 0, 7), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, slots)
+
+	assert.Nil(t, availability.DeleteAvailabilityException(ctx, listed[0].ID))
+	err = availability.DeleteAvailabilityException(ctx, listed[0].ID)
+	assert.True(t, errors.Is(err, exceptions.ErrAvailabilityExceptionNotFound))
+}
@@ -0,0 +1,459 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// DefaultBulkSMSChunkSize is the number of recipients in each request
+	// to the SMS provider
+	DefaultBulkSMSChunkSize = 100
+
+	// DefaultBulkSMSChunkInterval is the pause between requests to the SMS
+	// provider, which keeps a job within the provider's rate limits
+	DefaultBulkSMSChunkInterval = time.Second
+
+	// bulkSMSLease is how long an instance has to send a chunk of a job
+	// before another instance may resume the job
+	bulkSMSLease = 2 * time.Minute
+
+	// bulkSMSBatchSize is the maximum number of jobs that are picked up on
+	// each tick of the worker
+	bulkSMSBatchSize = 10
+
+	// bulkSMSRecipientsLimit is the maximum number of recipients that are
+	// listed at a time
+	bulkSMSRecipientsLimit = 500
+
+	// maxBulkSMSAttempts is the number of times a chunk is sent while the
+	// SMS provider can't be reached before its recipients are failed
+	maxBulkSMSAttempts = 5
+
+	// bulkSMSRetryDelay is how long a job waits before its chunk is retried
+	// after the SMS provider could not be reached. The delay doubles with
+	// each attempt.
+	bulkSMSRetryDelay = time.Minute
+
+	// aitSuccessStatus is the status that Africa's Talking reports for a
+	// message that it has accepted
+	aitSuccessStatus = "Success"
+)
+
+// errBulkSMSJobNotClaimable is returned from a job update when the job is
+// finished or leased by another instance
+var errBulkSMSJobNotClaimable = errors.New("bulk SMS job can't be claimed")
+
+// BulkSMSConfig controls how fast bulk SMS jobs are sent
+type BulkSMSConfig struct {
+	ChunkSize     int
+	ChunkInterval time.Duration
+}
+
+// BulkSMSUsecases represent logic required to send an SMS to many recipients
+// in the background
+type BulkSMSUsecases interface {
+	// SendBulkSMS creates a job that sends a message to phone numbers. The
+	// job is refused if it is over the flavour's SMS budget, or the default
+	// budget when no flavour is given.
+	SendBulkSMS(
+		ctx context.Context,
+		createdBy string,
+		message string,
+		to []string,
+		sender enumutils.SenderID,
+		flavour *feedlib.Flavour,
+	) (*domain.BulkSMSJob, error)
+
+	GetBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error)
+
+	// ListBulkSMSRecipients pages through the recipients of a job in the
+	// order that they were added. `after` is the index of the last
+	// recipient of the previous page.
+	ListBulkSMSRecipients(
+		ctx context.Context,
+		jobID string,
+		status *domain.BulkSMSRecipientStatus,
+		after *int,
+		first *int,
+	) ([]*domain.BulkSMSRecipient, error)
+
+	// CancelBulkSMSJob stops a job. Messages that have been sent are not
+	// recalled.
+	CancelBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error)
+
+	// RunBulkSMSJobs sends the pending and running jobs that no other
+	// instance is sending and returns the number that were finished
+	RunBulkSMSJobs(ctx context.Context, now time.Time) (int, error)
+}
+
+// BulkSMSImpl represents the bulk SMS usecase implementation
+type BulkSMSImpl struct {
+	Repository repository.BulkSMSRepository
+	SMS        sms.ServiceSMS
+	SMSCosts   SMSCostUsecases
+	Config     BulkSMSConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewBulkSMS initializes a bulk SMS usecase. Unset config fields are filled
+// with the defaults.
+func NewBulkSMS(
+	repository repository.BulkSMSRepository,
+	sms sms.ServiceSMS,
+	smsCosts SMSCostUsecases,
+	config BulkSMSConfig,
+) (*BulkSMSImpl, error) {
+	if config.ChunkSize == 0 {
+		config.ChunkSize = DefaultBulkSMSChunkSize
+	}
+	if config.ChunkInterval == 0 {
+		config.ChunkInterval = DefaultBulkSMSChunkInterval
+	}
+	if config.ChunkSize < 0 || config.ChunkSize > bulkSMSRecipientsLimit {
+		return nil, fmt.Errorf("the bulk SMS chunk size must be between 1 and %d", bulkSMSRecipientsLimit)
+	}
+	if config.ChunkInterval < 0 {
+		return nil, fmt.Errorf("the bulk SMS chunk interval can't be negative")
+	}
+
+	return &BulkSMSImpl{
+		Repository: repository,
+		SMS:        sms,
+		SMSCosts:   smsCosts,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SendBulkSMS validates the message and saves a job with a pending recipient
+// for each distinct phone number. The job is sent by the worker.
+//
+// The budget of a flavour applies to the whole job rather than to each chunk.
+func (b *BulkSMSImpl) SendBulkSMS(
+	ctx context.Context,
+	createdBy string,
+	message string,
+	to []string,
+	sender enumutils.SenderID,
+	flavour *feedlib.Flavour,
+) (*domain.BulkSMSJob, error) {
+	if strings.TrimSpace(message) == "" {
+		return nil, fmt.Errorf("an SMS message can't be empty")
+	}
+	if !sender.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid sender ID", sender)
+	}
+	phones, err := normalizeSMSRecipients(to)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.SMSCosts.CheckSMSBudget(ctx, flavour, message, phones); err != nil {
+		return nil, err
+	}
+
+	now := b.Clock()
+	job := &domain.BulkSMSJob{
+		ID:        ksuid.New().String(),
+		Message:   message,
+		Sender:    sender,
+		Status:    domain.BulkSMSJobStatusPending,
+		Total:     len(phones),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	recipients := []*domain.BulkSMSRecipient{}
+	for i, phone := range phones {
+		recipients = append(recipients, &domain.BulkSMSRecipient{
+			JobID:  job.ID,
+			Index:  i,
+			Number: phone,
+			Status: domain.BulkSMSRecipientStatusPending,
+		})
+	}
+	return b.Repository.CreateBulkSMSJob(ctx, job, recipients)
+}
+
+// GetBulkSMSJob retrieves a job with its progress
+func (b *BulkSMSImpl) GetBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	return b.Repository.GetBulkSMSJob(ctx, id)
+}
+
+// ListBulkSMSRecipients returns a page of a job's recipients
+func (b *BulkSMSImpl) ListBulkSMSRecipients(
+	ctx context.Context,
+	jobID string,
+	status *domain.BulkSMSRecipientStatus,
+	after *int,
+	first *int,
+) ([]*domain.BulkSMSRecipient, error) {
+	if _, err := b.Repository.GetBulkSMSJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+	afterIndex := -1
+	if after != nil {
+		afterIndex = *after
+	}
+	limit := bulkSMSRecipientsLimit
+	if first != nil {
+		if *first <= 0 || *first > bulkSMSRecipientsLimit {
+			return nil, fmt.Errorf("up to %d recipients can be listed at a time", bulkSMSRecipientsLimit)
+		}
+		limit = *first
+	}
+	return b.Repository.ListBulkSMSRecipients(ctx, jobID, status, afterIndex, limit)
+}
+
+// CancelBulkSMSJob cancels a job that has not finished
+func (b *BulkSMSImpl) CancelBulkSMSJob(ctx context.Context, id string) (*domain.BulkSMSJob, error) {
+	return b.Repository.UpdateBulkSMSJob(ctx, id, func(job *domain.BulkSMSJob) error {
+		if job.Status.IsFinal() {
+			return fmt.Errorf("the bulk SMS job is already %s", job.Status)
+		}
+		now := b.Clock()
+		job.Status = domain.BulkSMSJobStatusCancelled
+		job.UpdatedAt = now
+		job.CompletedAt = &now
+		return nil
+	})
+}
+
+// RunBulkSMSJobs sends the active jobs one at a time.
+//
+// A job is leased before it is sent and the lease is renewed after each
+// chunk, so when several instances run the worker each job is only sent by
+// one of them. A job whose instance stopped is resumed by another instance
+// once its lease expires.
+func (b *BulkSMSImpl) RunBulkSMSJobs(ctx context.Context, now time.Time) (int, error) {
+	jobs, err := b.Repository.ListActiveBulkSMSJobs(ctx, bulkSMSBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list bulk SMS jobs: %w", err)
+	}
+
+	finished := 0
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		job, err := b.claim(ctx, j.ID, now)
+		if errors.Is(err, errBulkSMSJobNotClaimable) {
+			continue
+		}
+		if err != nil {
+			log.Printf("unable to claim bulk SMS job %s: %v", j.ID, err)
+			continue
+		}
+
+		done, err := b.run(ctx, job)
+		if err != nil {
+			log.Printf("unable to send bulk SMS job %s: %v", j.ID, err)
+		}
+		if done {
+			finished++
+		}
+	}
+	return finished, nil
+}
+
+// StartBulkSMSWorker runs the active jobs every `interval` until the context
+// is cancelled. It blocks and should be started in its own goroutine.
+func (b *BulkSMSImpl) StartBulkSMSWorker(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, "bulk SMS worker", interval, b.RunBulkSMSJobs)
+}
+
+// claim leases an active job
+func (b *BulkSMSImpl) claim(ctx context.Context, id string, now time.Time) (*domain.BulkSMSJob, error) {
+	return b.Repository.UpdateBulkSMSJob(ctx, id, func(job *domain.BulkSMSJob) error {
+		if job.Status.IsFinal() {
+			return errBulkSMSJobNotClaimable
+		}
+		if job.LeaseExpiresAt != nil && job.LeaseExpiresAt.After(now) {
+			return errBulkSMSJobNotClaimable
+		}
+		if job.RetryAt != nil && job.RetryAt.After(now) {
+			return errBulkSMSJobNotClaimable
+		}
+		leaseUntil := now.Add(bulkSMSLease)
+		job.LeaseExpiresAt = &leaseUntil
+		job.Status = domain.BulkSMSJobStatusRunning
+		job.UpdatedAt = now
+		return nil
+	})
+}
+
+// run sends a claimed job a chunk at a time until it is finished, cancelled
+// or the context is cancelled. It returns true if the job was finished.
+func (b *BulkSMSImpl) run(ctx context.Context, job *domain.BulkSMSJob) (bool, error) {
+	// recipients that were being sent when the job's previous instance
+	// stopped may have been sent, so they are not sent again
+	if err := b.markInterrupted(ctx, job.ID); err != nil {
+		return false, err
+	}
+
+	pending := domain.BulkSMSRecipientStatusPending
+	for {
+		chunk, err := b.Repository.ListBulkSMSRecipients(ctx, job.ID, &pending, -1, b.Config.ChunkSize)
+		if err != nil {
+			return false, fmt.Errorf("unable to list pending recipients: %w", err)
+		}
+		if len(chunk) == 0 {
+			_, err := b.Repository.UpdateBulkSMSJob(ctx, job.ID, func(job *domain.BulkSMSJob) error {
+				now := b.Clock()
+				if !job.Status.IsFinal() {
+					job.Status = domain.BulkSMSJobStatusCompleted
+					job.CompletedAt = &now
+				}
+				job.LeaseExpiresAt = nil
+				job.UpdatedAt = now
+				return nil
+			})
+			return err == nil, err
+		}
+
+		sendErr, err := b.sendChunk(ctx, job, chunk)
+		if err != nil {
+			return false, err
+		}
+
+		updated, err := b.Repository.UpdateBulkSMSJob(ctx, job.ID, func(job *domain.BulkSMSJob) error {
+			now := b.Clock()
+			for _, recipient := range chunk {
+				switch recipient.Status {
+				case domain.BulkSMSRecipientStatusSent:
+					job.Sent++
+				case domain.BulkSMSRecipientStatusFailed:
+					job.Failed++
+				case domain.BulkSMSRecipientStatusUnknown:
+					job.Unknown++
+				}
+			}
+			leaseUntil := now.Add(bulkSMSLease)
+			job.LeaseExpiresAt = &leaseUntil
+			job.RetryAt = nil
+			if sendErr != nil {
+				// the lease is released so that any instance can retry
+				// the chunk once the delay has passed
+				retryAt := now.Add(bulkSMSRetryDelay << (chunk[0].Attempts - 1))
+				job.RetryAt = &retryAt
+				job.LeaseExpiresAt = nil
+			}
+			job.UpdatedAt = now
+			return nil
+		})
+		if err != nil {
+			return false, fmt.Errorf("unable to update bulk SMS job: %w", err)
+		}
+		if sendErr != nil {
+			return false, fmt.Errorf("unable to reach the SMS provider, retrying after %s: %w", updated.RetryAt, sendErr)
+		}
+		if updated.Status.IsFinal() {
+			return false, nil
+		}
+		job = updated
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(b.Config.ChunkInterval):
+		}
+	}
+}
+
+// sendChunk sends the message to a chunk of recipients and records the
+// status that the provider reported for each of them.
+//
+// When the provider can't be reached the recipients are left pending so that
+// the chunk is retried, until it has been attempted maxBulkSMSAttempts times.
+// The provider's error is returned as `sendErr`.
+func (b *BulkSMSImpl) sendChunk(
+	ctx context.Context,
+	job *domain.BulkSMSJob,
+	chunk []*domain.BulkSMSRecipient,
+) (sendErr error, err error) {
+	numbers := []string{}
+	for _, recipient := range chunk {
+		recipient.Status = domain.BulkSMSRecipientStatusSending
+		numbers = append(numbers, recipient.Number)
+	}
+	if err := b.Repository.SaveBulkSMSRecipients(ctx, chunk); err != nil {
+		return nil, fmt.Errorf("unable to save bulk SMS recipients: %w", err)
+	}
+
+	resp, sendErr := b.SMS.SendToMany(ctx, job.Message, numbers, job.Sender)
+	now := b.Clock()
+	reported := map[string]libDto.Recipient{}
+	if sendErr == nil && resp != nil && resp.SMSMessageData != nil {
+		for _, recipient := range resp.SMSMessageData.Recipients {
+			reported[recipient.Number] = recipient
+		}
+	}
+
+	for _, recipient := range chunk {
+		r, ok := reported[recipient.Number]
+		switch {
+		case sendErr != nil:
+			recipient.Attempts++
+			recipient.Status = domain.BulkSMSRecipientStatusPending
+			if recipient.Attempts >= maxBulkSMSAttempts {
+				recipient.Status = domain.BulkSMSRecipientStatusFailed
+			}
+			recipient.Error = sendErr.Error()
+		case !ok:
+			recipient.Status = domain.BulkSMSRecipientStatusUnknown
+			recipient.Error = "the SMS provider did not report on this recipient"
+		case r.Status != aitSuccessStatus:
+			recipient.Status = domain.BulkSMSRecipientStatusFailed
+			recipient.Error = r.Status
+		default:
+			recipient.Status = domain.BulkSMSRecipientStatusSent
+			recipient.Cost = r.Cost
+			recipient.MessageID = r.MessageID
+			recipient.SentAt = &now
+			recipient.Error = ""
+		}
+	}
+	if err := b.Repository.SaveBulkSMSRecipients(ctx, chunk); err != nil {
+		return nil, fmt.Errorf("unable to save bulk SMS recipients: %w", err)
+	}
+	return sendErr, nil
+}
+
+// markInterrupted marks the recipients that are still being sent as unknown
+func (b *BulkSMSImpl) markInterrupted(ctx context.Context, jobID string) error {
+	sending := domain.BulkSMSRecipientStatusSending
+	recipients, err := b.Repository.ListBulkSMSRecipients(ctx, jobID, &sending, -1, bulkSMSRecipientsLimit)
+	if err != nil {
+		return fmt.Errorf("unable to list interrupted recipients: %w", err)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	for _, recipient := range recipients {
+		recipient.Status = domain.BulkSMSRecipientStatusUnknown
+		recipient.Error = "the service stopped while the message was being sent"
+	}
+	if err := b.Repository.SaveBulkSMSRecipients(ctx, recipients); err != nil {
+		return fmt.Errorf("unable to save interrupted recipients: %w", err)
+	}
+	_, err = b.Repository.UpdateBulkSMSJob(ctx, jobID, func(job *domain.BulkSMSJob) error {
+		job.Unknown += len(recipients)
+		return nil
+	})
+	return err
+}
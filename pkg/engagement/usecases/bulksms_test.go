@@ -0,0 +1,285 @@
+package usecases_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBulkSMS(f *testFixture) *usecases.BulkSMSImpl {
+	bulkSMS, err := usecases.NewBulkSMS(f.repository, f.smsService(), f.smsCosts(), usecases.BulkSMSConfig{
+		ChunkSize:     2,
+		ChunkInterval: time.Nanosecond,
+	})
+	assert.Nil(f.t, err)
+	bulkSMS.Clock = f.clock.Now
+	return bulkSMS
+}
+
+func testPhoneNumbers(n int) []string {
+	numbers := []string{}
+	for i := 0; i < n; i++ {
+		numbers = append(numbers, fmt.Sprintf("+25471122%04d", i))
+	}
+	return numbers
+}
+
+func TestBulkSMSImpl_SendBulkSMS(t *testing.T) {
+	ctx := context.Background()
+	bulkSMS := newTestBulkSMS(newTestFixture(t))
+
+	tests := []struct {
+		name      string
+		message   string
+		to        []string
+		sender    enumutils.SenderID
+		wantTotal int
+		wantErr   bool
+	}{
+		{
+			name:      "recipients are normalized and deduplicated",
+			message:   "The clinic is closed on Monday",
+			to:        []string{"0711223344", "+254711223344", "0722334455"},
+			sender:    enumutils.SenderIDBewell,
+			wantTotal: 2,
+		},
+		{
+			name:    "empty message",
+			message: "",
+			to:      []string{"0711223344"},
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+		{
+			name:    "invalid sender ID",
+			message: "The clinic is closed on Monday",
+			to:      []string{"0711223344"},
+			sender:  enumutils.SenderID("PIGEON"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid phone number",
+			message: "The clinic is closed on Monday",
+			to:      []string{"not a phone"},
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := bulkSMS.SendBulkSMS(ctx, "admin", tt.message, tt.to, tt.sender, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendBulkSMS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, domain.BulkSMSJobStatusPending, job.Status)
+				assert.Equal(t, tt.wantTotal, job.Total)
+				assert.Equal(t, 0.0, job.Progress())
+			}
+		})
+	}
+}
+
+func TestBulkSMSImpl_RunBulkSMSJobs(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	bulkSMS := newTestBulkSMS(f)
+
+	numbers := testPhoneNumbers(5)
+	f.sms.statuses[numbers[1]] = "InvalidPhoneNumber"
+	f.sms.statuses[numbers[3]] = ""
+
+	job, err := bulkSMS.SendBulkSMS(ctx, "admin", "The clinic is closed on Monday", numbers, enumutils.SenderIDSLADE360, nil)
+	assert.Nil(t, err)
+
+	finished, err := bulkSMS.RunBulkSMSJobs(ctx, f.clock.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, finished)
+	assert.Equal(t, [][]string{numbers[0:2], numbers[2:4], numbers[4:5]}, f.sms.requests)
+
+	job, err = bulkSMS.GetBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusCompleted, job.Status)
+	assert.Equal(t, 3, job.Sent)
+	assert.Equal(t, 1, job.Failed)
+	assert.Equal(t, 1, job.Unknown)
+	assert.Equal(t, 1.0, job.Progress())
+	assert.NotNil(t, job.CompletedAt)
+
+	recipients, err := bulkSMS.ListBulkSMSRecipients(ctx, job.ID, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, recipients, 5)
+	assert.Equal(t, domain.BulkSMSRecipientStatusSent, recipients[0].Status)
+	assert.Equal(t, "KES 0.8000", recipients[0].Cost)
+	assert.Equal(t, "ATXid_"+numbers[0], recipients[0].MessageID)
+	assert.Equal(t, domain.BulkSMSRecipientStatusFailed, recipients[1].Status)
+	assert.Equal(t, "InvalidPhoneNumber", recipients[1].Error)
+	// a recipient that the provider did not report on may have been sent
+	assert.Equal(t, domain.BulkSMSRecipientStatusUnknown, recipients[3].Status)
+
+	// recipients are paged by index and can be filtered by status
+	after, first := 1, 2
+	page, err := bulkSMS.ListBulkSMSRecipients(ctx, job.ID, nil, &after, &first)
+	assert.Nil(t, err)
+	assert.Len(t, page, 2)
+	assert.Equal(t, numbers[2], page[0].Number)
+	failed := domain.BulkSMSRecipientStatusFailed
+	page, err = bulkSMS.ListBulkSMSRecipients(ctx, job.ID, &failed, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page, 1)
+
+	// finished jobs are not sent again
+	finished, err = bulkSMS.RunBulkSMSJobs(ctx, f.clock.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, finished)
+	assert.Len(t, f.sms.requests, 3)
+}
+
+func TestBulkSMSImpl_RunBulkSMSJobs_Resume(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	bulkSMS := newTestBulkSMS(f)
+
+	numbers := testPhoneNumbers(4)
+	job, err := bulkSMS.SendBulkSMS(ctx, "admin", "The clinic is closed on Monday", numbers, enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+
+	// an instance stopped while the first chunk was with the provider
+	now := f.clock.Now()
+	leaseUntil := now.Add(time.Minute)
+	_, err = f.repository.UpdateBulkSMSJob(ctx, job.ID, func(job *domain.BulkSMSJob) error {
+		job.Status = domain.BulkSMSJobStatusRunning
+		job.LeaseExpiresAt = &leaseUntil
+		return nil
+	})
+	assert.Nil(t, err)
+	recipients, err := f.repository.ListBulkSMSRecipients(ctx, job.ID, nil, -1, 2)
+	assert.Nil(t, err)
+	for _, recipient := range recipients {
+		recipient.Status = domain.BulkSMSRecipientStatusSending
+	}
+	assert.Nil(t, f.repository.SaveBulkSMSRecipients(ctx, recipients))
+
+	// the job is left alone while its lease is held
+	finished, err := bulkSMS.RunBulkSMSJobs(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, finished)
+	assert.Empty(t, f.sms.requests)
+
+	// and resumed once it expires, without resending the interrupted chunk
+	finished, err = bulkSMS.RunBulkSMSJobs(ctx, now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, finished)
+	assert.Equal(t, [][]string{numbers[2:4]}, f.sms.requests)
+
+	job, err = bulkSMS.GetBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusCompleted, job.Status)
+	assert.Equal(t, 2, job.Sent)
+	assert.Equal(t, 2, job.Unknown)
+	assert.Equal(t, 1.0, job.Progress())
+}
+
+func TestBulkSMSImpl_RunBulkSMSJobs_ProviderUnavailable(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	bulkSMS := newTestBulkSMS(f)
+
+	numbers := testPhoneNumbers(3)
+	job, err := bulkSMS.SendBulkSMS(ctx, "admin", "The clinic is closed on Monday", numbers, enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+
+	f.sms.err = fmt.Errorf("connection reset by peer")
+	now := f.clock.Now()
+	finished, err := bulkSMS.RunBulkSMSJobs(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, finished)
+
+	// the chunk is left pending rather than failed
+	job, err = bulkSMS.GetBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusRunning, job.Status)
+	assert.Equal(t, 0, job.Failed)
+	assert.Equal(t, now.Add(time.Minute), *job.RetryAt)
+	recipients, err := bulkSMS.ListBulkSMSRecipients(ctx, job.ID, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSRecipientStatusPending, recipients[0].Status)
+	assert.Equal(t, 1, recipients[0].Attempts)
+	assert.Equal(t, "connection reset by peer", recipients[0].Error)
+
+	// it is not retried before the backoff has passed
+	finished, err = bulkSMS.RunBulkSMSJobs(ctx, now.Add(30*time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, finished)
+	assert.Len(t, f.sms.requests, 1)
+
+	f.sms.err = nil
+	f.clock.Advance(time.Minute)
+	now = f.clock.Now()
+	finished, err = bulkSMS.RunBulkSMSJobs(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, finished)
+	assert.Equal(t, [][]string{numbers[0:2], numbers[0:2], numbers[2:3]}, f.sms.requests)
+
+	job, err = bulkSMS.GetBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusCompleted, job.Status)
+	assert.Equal(t, 3, job.Sent)
+	assert.Nil(t, job.RetryAt)
+	recipients, err = bulkSMS.ListBulkSMSRecipients(ctx, job.ID, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSRecipientStatusSent, recipients[0].Status)
+	assert.Empty(t, recipients[0].Error)
+}
+
+func TestBulkSMSImpl_RunBulkSMSJobs_ProviderUnavailable_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	bulkSMS := newTestBulkSMS(f)
+
+	job, err := bulkSMS.SendBulkSMS(ctx, "admin", "The clinic is closed on Monday", testPhoneNumbers(2), enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+
+	f.sms.err = fmt.Errorf("connection reset by peer")
+	now := f.clock.Now()
+	for i := 0; i < 10; i++ {
+		_, err = bulkSMS.RunBulkSMSJobs(ctx, now)
+		assert.Nil(t, err)
+		f.clock.Advance(time.Hour)
+		now = f.clock.Now()
+	}
+	assert.Len(t, f.sms.requests, 5)
+
+	job, err = bulkSMS.GetBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusCompleted, job.Status)
+	assert.Equal(t, 2, job.Failed)
+}
+
+func TestBulkSMSImpl_CancelBulkSMSJob(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	bulkSMS := newTestBulkSMS(f)
+
+	job, err := bulkSMS.SendBulkSMS(ctx, "admin", "The clinic is closed on Monday", testPhoneNumbers(3), enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+
+	cancelled, err := bulkSMS.CancelBulkSMSJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.BulkSMSJobStatusCancelled, cancelled.Status)
+
+	_, err = bulkSMS.CancelBulkSMSJob(ctx, job.ID)
+	assert.NotNil(t, err)
+
+	finished, err := bulkSMS.RunBulkSMSJobs(ctx, f.clock.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, finished)
+	assert.Empty(t, f.sms.requests)
+}
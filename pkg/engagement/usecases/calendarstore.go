@@ -0,0 +1,190 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/segmentio/ksuid"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+const (
+	// the statuses of a Google Calendar API event
+	calendarEventConfirmed = "confirmed"
+	calendarEventCancelled = "cancelled"
+
+	// calendarICalUIDDomain makes the iCalendar UIDs of the events in the
+	// service's own calendar globally unique
+	calendarICalUIDDomain = "bewell.co.ke"
+)
+
+// CalendarStore is a calendar service that keeps events in the repository.
+// It is used instead of a Google calendar for local development and tests.
+type CalendarStore struct {
+	Repository repository.CalendarRepository
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewCalendarStore initializes a calendar service that keeps events in the
+// repository
+func NewCalendarStore(repository repository.CalendarRepository) *CalendarStore {
+	return &CalendarStore{Repository: repository, Clock: time.Now}
+}
+
+// InsertEvent gives an event an ID and saves it
+func (c *CalendarStore) InsertEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	created := *event
+	created.Id = ksuid.New().String()
+	created.ICalUID = created.Id + "@" + calendarICalUIDDomain
+	created.Kind = "calendar#event"
+	created.Created = c.Clock().UTC().Format(time.RFC3339)
+	if created.Status == "" {
+		created.Status = calendarEventConfirmed
+	}
+	if err := c.save(ctx, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateEvent replaces a saved event
+func (c *CalendarStore) UpdateEvent(ctx context.Context, event *gcal.Event) (*gcal.Event, error) {
+	existing, err := c.GetEvent(ctx, event.Id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *event
+	updated.ICalUID = existing.ICalUID
+	updated.Kind = existing.Kind
+	updated.Created = existing.Created
+	if updated.Status == "" {
+		updated.Status = existing.Status
+	}
+	if err := c.save(ctx, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// GetEvent retrieves a saved event. It returns
+// exceptions.ErrCalendarEventNotFound when no event has the ID.
+func (c *CalendarStore) GetEvent(ctx context.Context, id string) (*gcal.Event, error) {
+	stored, err := c.Repository.GetCalendarEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStoredCalendarEvent(stored)
+}
+
+// ListEvents returns the saved events that overlap an interval ordered by
+// their start
+func (c *CalendarStore) ListEvents(
+	ctx context.Context,
+	from, to time.Time,
+	includeCancelled bool,
+) ([]*gcal.Event, error) {
+	stored, err := c.Repository.ListCalendarEvents(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list calendar events: %w", err)
+	}
+	events := []*gcal.Event{}
+	for _, s := range stored {
+		if s.Status == calendarEventCancelled && !includeCancelled {
+			continue
+		}
+		event, err := decodeStoredCalendarEvent(s)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// save stamps an event with the time that it was updated and saves it with
+// its start, end and status
+func (c *CalendarStore) save(ctx context.Context, event *gcal.Event) error {
+	start, end, err := calendarEventInterval(event)
+	if err != nil {
+		return err
+	}
+	if end.Sub(start) > domain.MaxCalendarEventDuration {
+		return fmt.Errorf(
+			"events can't be longer than %d hours",
+			int(domain.MaxCalendarEventDuration/time.Hour),
+		)
+	}
+	event.Updated = c.Clock().UTC().Format(time.RFC3339)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to marshal calendar event: %w", err)
+	}
+	stored := &domain.StoredCalendarEvent{
+		ID:     event.Id,
+		Start:  start,
+		End:    end,
+		Status: event.Status,
+		Event:  string(data),
+	}
+	if err := c.Repository.SaveCalendarEvent(ctx, stored); err != nil {
+		return fmt.Errorf("unable to save calendar event: %w", err)
+	}
+	return nil
+}
+
+func decodeStoredCalendarEvent(stored *domain.StoredCalendarEvent) (*gcal.Event, error) {
+	event := &gcal.Event{}
+	if err := json.Unmarshal([]byte(stored.Event), event); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal calendar event %s: %w", stored.ID, err)
+	}
+	return event, nil
+}
+
+// calendarEventInterval returns the start and end of an event. All day
+// events start and end at midnight in their time zone.
+func calendarEventInterval(event *gcal.Event) (time.Time, time.Time, error) {
+	start, err := calendarEventTime(event.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid event start: %w", err)
+	}
+	end, err := calendarEventTime(event.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid event end: %w", err)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("an event must end after it starts")
+	}
+	return start, end, nil
+}
+
+func calendarEventTime(t *gcal.EventDateTime) (time.Time, error) {
+	if t == nil {
+		return time.Time{}, fmt.Errorf("the time is required")
+	}
+	loc := time.UTC
+	if t.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(t.TimeZone); err != nil {
+			return time.Time{}, fmt.Errorf("%q is not a valid time zone", t.TimeZone)
+		}
+	}
+	if t.DateTime != "" {
+		parsed, err := time.Parse(time.RFC3339, t.DateTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time", t.DateTime)
+		}
+		return parsed.In(loc), nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", t.Date, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date", t.Date)
+	}
+	return parsed, nil
+}
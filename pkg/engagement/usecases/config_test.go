@@ -38,7 +38,7 @@ func InitializeTestNewNotification(ctx context.Context) (*usecases.NotificationI
 	var repo libRepository.Repository
 	infra := libInfra.NewInteractor()
 	libUsc := libNotification.NewNotification(infra)
-	lib := usecases.NewNotification(repo, libUsc)
+	lib := usecases.NewNotification(repo, libUsc, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 	return lib, repo, nil
 }
 
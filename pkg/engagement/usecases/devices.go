@@ -0,0 +1,249 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/fcm"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+)
+
+// DeviceUsecases represent logic required to manage the push notification
+// registry and to send push notifications to registered devices
+type DeviceUsecases interface {
+	RegisterDevice(
+		ctx context.Context,
+		uid string,
+		input dto.DeviceInput,
+	) (*domain.Device, error)
+
+	UnregisterDevice(ctx context.Context, uid string, deviceID string) (bool, error)
+
+	ListDevices(ctx context.Context, uid string) ([]*domain.Device, error)
+
+	// GetUserTokens returns the registration tokens of every device that
+	// the supplied users have registered
+	GetUserTokens(ctx context.Context, uids []string) ([]string, error)
+
+	// SendPushNotification sends a push notification, records it in the
+	// inbox of every user that received it and prunes the tokens that FCM
+	// reports as unregistered
+	SendPushNotification(
+		ctx context.Context,
+		registrationTokens []string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (bool, error)
+}
+
+// DeviceImpl represents the device registry usecase implementation
+type DeviceImpl struct {
+	Repository repository.DeviceRepository
+	FCM        fcm.ServiceFCM
+	Inbox      InboxUsecases
+}
+
+// NewDevices initializes a device registry usecase
+func NewDevices(
+	repository repository.DeviceRepository,
+	fcm fcm.ServiceFCM,
+	inbox InboxUsecases,
+) *DeviceImpl {
+	return &DeviceImpl{
+		Repository: repository,
+		FCM:        fcm,
+		Inbox:      inbox,
+	}
+}
+
+// RegisterDevice records the push token of a user's device.
+//
+// Any other registration of the same token is removed first because a token
+// identifies a single app installation.
+func (d *DeviceImpl) RegisterDevice(
+	ctx context.Context,
+	uid string,
+	input dto.DeviceInput,
+) (*domain.Device, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("a user ID is required to register a device")
+	}
+	if strings.TrimSpace(input.DeviceID) == "" {
+		return nil, fmt.Errorf("a device ID is required")
+	}
+	if strings.TrimSpace(input.Token) == "" {
+		return nil, fmt.Errorf("a registration token is required")
+	}
+	if !input.Platform.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid device platform", input.Platform)
+	}
+
+	now := time.Now()
+	device := &domain.Device{
+		ID:         input.DeviceID,
+		UID:        uid,
+		Token:      input.Token,
+		Platform:   input.Platform,
+		AppVersion: input.AppVersion,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	existing, err := d.Repository.ListDevices(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the user's devices: %w", err)
+	}
+	for _, e := range existing {
+		if e.ID == device.ID {
+			device.CreatedAt = e.CreatedAt
+		}
+	}
+
+	if _, err := d.Repository.DeleteDevicesByToken(ctx, []string{input.Token}); err != nil {
+		return nil, fmt.Errorf("unable to remove previous registrations of the token: %w", err)
+	}
+	return d.Repository.SaveDevice(ctx, device)
+}
+
+// UnregisterDevice removes a user's device from the registry
+func (d *DeviceImpl) UnregisterDevice(ctx context.Context, uid string, deviceID string) (bool, error) {
+	if err := d.Repository.DeleteDevice(ctx, uid, deviceID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListDevices returns the devices that a user has registered
+func (d *DeviceImpl) ListDevices(ctx context.Context, uid string) ([]*domain.Device, error) {
+	return d.Repository.ListDevices(ctx, uid)
+}
+
+// GetUserTokens returns the registration tokens of the supplied users' devices
+func (d *DeviceImpl) GetUserTokens(ctx context.Context, uids []string) ([]string, error) {
+	tokens := []string{}
+	seen := map[string]bool{}
+	for _, uid := range uids {
+		devices, err := d.Repository.ListDevices(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list devices for %s: %w", uid, err)
+		}
+		for _, device := range devices {
+			if seen[device.Token] {
+				continue
+			}
+			seen[device.Token] = true
+			tokens = append(tokens, device.Token)
+		}
+	}
+	return tokens, nil
+}
+
+// SendPushNotification sends a push notification to the supplied tokens.
+//
+// The notification is added to the inbox of each user that owns a device it
+// was delivered to. Tokens that FCM reports as unregistered are removed from
+// the registry. An error is only returned when the notification was not delivered to any
+// of the tokens.
+func (d *DeviceImpl) SendPushNotification(
+	ctx context.Context,
+	registrationTokens []string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (bool, error) {
+	result, err := d.FCM.SendNotification(
+		ctx,
+		registrationTokens,
+		data,
+		notification,
+		android,
+		ios,
+		web,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	d.recordInInbox(ctx, result, data, notification)
+
+	if len(result.UnregisteredTokens) > 0 {
+		pruned, err := d.Repository.DeleteDevicesByToken(ctx, result.UnregisteredTokens)
+		if err != nil {
+			log.Printf("unable to prune unregistered push tokens: %v", err)
+		} else {
+			log.Printf("pruned %d devices with unregistered push tokens", pruned)
+		}
+	}
+
+	if result.SuccessCount == 0 {
+		failures := []string{}
+		for token, reason := range result.Failures {
+			failures = append(failures, fmt.Sprintf("%s: %s", token, reason))
+		}
+		return false, fmt.Errorf("fcm: unable to deliver to any token: %s", strings.Join(failures, "; "))
+	}
+	return true, nil
+}
+
+// recordInInbox adds a delivered notification to the inbox of every user
+// that owns a device it was delivered to.
+//
+// Failing to record a notification does not fail the send; the notification
+// has already been delivered.
+func (d *DeviceImpl) recordInInbox(
+	ctx context.Context,
+	result *dto.FCMSendResult,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+) {
+	if d.Inbox == nil || notification == nil || len(result.MessageIDs) == 0 {
+		return
+	}
+
+	delivered := []string{}
+	for token := range result.MessageIDs {
+		delivered = append(delivered, token)
+	}
+	devices, err := d.Repository.ListDevicesByToken(ctx, delivered)
+	if err != nil {
+		log.Printf("unable to look up the owners of delivered push tokens: %v", err)
+		return
+	}
+
+	payload := map[string]interface{}{}
+	for k, v := range data {
+		payload[k] = v
+	}
+
+	recorded := map[string]bool{}
+	for _, device := range devices {
+		if recorded[device.UID] {
+			continue
+		}
+		recorded[device.UID] = true
+
+		_, err := d.Inbox.RecordNotification(ctx, &domain.InboxNotification{
+			UID:       device.UID,
+			Channel:   feedlib.ChannelFcm,
+			MessageID: result.MessageIDs[device.Token],
+			Title:     notification.Title,
+			Body:      notification.Body,
+			Data:      payload,
+		})
+		if err != nil {
+			log.Printf("unable to record push notification in %s's inbox: %v", device.UID, err)
+		}
+	}
+}
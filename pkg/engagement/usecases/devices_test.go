@@ -0,0 +1,192 @@
+package usecases_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	fcmMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/fcm/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/firebasetools"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeFCM returns a fake FCM service that reports the supplied tokens as
+// unregistered and delivers to every other token
+func newFakeFCM(unregistered ...string) *fcmMock.FakeServiceFCM {
+	return &fcmMock.FakeServiceFCM{
+		SendNotificationFn: func(
+			ctx context.Context,
+			registrationTokens []string,
+			data map[string]string,
+			notification *firebasetools.FirebaseSimpleNotificationInput,
+			android *firebasetools.FirebaseAndroidConfigInput,
+			ios *firebasetools.FirebaseAPNSConfigInput,
+			web *firebasetools.FirebaseWebpushConfigInput,
+		) (*dto.FCMSendResult, error) {
+			stale := map[string]bool{}
+			for _, token := range unregistered {
+				stale[token] = true
+			}
+			result := &dto.FCMSendResult{
+				MessageIDs:         map[string]string{},
+				Failures:           map[string]string{},
+				UnregisteredTokens: []string{},
+			}
+			for _, token := range registrationTokens {
+				if stale[token] {
+					result.FailureCount++
+					result.Failures[token] = "registration-token-not-registered"
+					result.UnregisteredTokens = append(result.UnregisteredTokens, token)
+					continue
+				}
+				result.SuccessCount++
+				result.MessageIDs[token] = "message-" + token
+			}
+			return result, nil
+		},
+	}
+}
+
+func TestDeviceImpl_RegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	devices := usecases.NewDevices(memory.NewRepository(), newFakeFCM(), nil)
+
+	tests := []struct {
+		name    string
+		uid     string
+		input   dto.DeviceInput
+		wantErr bool
+	}{
+		{
+			name: "valid device",
+			uid:  "user-1",
+			input: dto.DeviceInput{
+				DeviceID: "phone",
+				Token:    "token-1",
+				Platform: domain.DevicePlatformAndroid,
+			},
+		},
+		{
+			name: "missing token",
+			uid:  "user-1",
+			input: dto.DeviceInput{
+				DeviceID: "phone",
+				Platform: domain.DevicePlatformAndroid,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid platform",
+			uid:  "user-1",
+			input: dto.DeviceInput{
+				DeviceID: "phone",
+				Token:    "token-1",
+				Platform: domain.DevicePlatform("SYMBIAN"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing user",
+			input: dto.DeviceInput{
+				DeviceID: "phone",
+				Token:    "token-1",
+				Platform: domain.DevicePlatformAndroid,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := devices.RegisterDevice(ctx, tt.uid, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RegisterDevice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.input.DeviceID, got.ID)
+				assert.Equal(t, tt.uid, got.UID)
+			}
+		})
+	}
+}
+
+func TestDeviceImpl_TokenMovesBetweenUsers(t *testing.T) {
+	ctx := context.Background()
+	devices := usecases.NewDevices(memory.NewRepository(), newFakeFCM(), nil)
+
+	input := dto.DeviceInput{
+		DeviceID: "shared-tablet",
+		Token:    "token-1",
+		Platform: domain.DevicePlatformAndroid,
+	}
+	_, err := devices.RegisterDevice(ctx, "user-1", input)
+	assert.Nil(t, err)
+	_, err = devices.RegisterDevice(ctx, "user-2", input)
+	assert.Nil(t, err)
+
+	first, err := devices.ListDevices(ctx, "user-1")
+	assert.Nil(t, err)
+	assert.Len(t, first, 0)
+
+	tokens, err := devices.GetUserTokens(ctx, []string{"user-1", "user-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"token-1"}, tokens)
+
+	unregistered, err := devices.UnregisterDevice(ctx, "user-2", "shared-tablet")
+	assert.Nil(t, err)
+	assert.True(t, unregistered)
+
+	_, err = devices.UnregisterDevice(ctx, "user-2", "shared-tablet")
+	assert.NotNil(t, err)
+}
+
+func TestDeviceImpl_SendPushNotification(t *testing.T) {
+	ctx := context.Background()
+	devices := usecases.NewDevices(memory.NewRepository(), newFakeFCM("stale"), nil)
+
+	for id, token := range map[string]string{"phone": "fresh", "old-phone": "stale"} {
+		_, err := devices.RegisterDevice(ctx, "user-1", dto.DeviceInput{
+			DeviceID: id,
+			Token:    token,
+			Platform: domain.DevicePlatformIOS,
+		})
+		assert.Nil(t, err)
+	}
+
+	notification := &firebasetools.FirebaseSimpleNotificationInput{
+		Title: "Test",
+		Body:  "Test notification",
+	}
+
+	sent, err := devices.SendPushNotification(
+		ctx,
+		[]string{"fresh", "stale"},
+		nil,
+		notification,
+		nil,
+		nil,
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.True(t, sent)
+
+	registered, err := devices.ListDevices(ctx, "user-1")
+	assert.Nil(t, err)
+	assert.Len(t, registered, 1)
+	assert.Equal(t, "fresh", registered[0].Token)
+
+	sent, err = devices.SendPushNotification(
+		ctx,
+		[]string{"stale"},
+		nil,
+		notification,
+		nil,
+		nil,
+		nil,
+	)
+	assert.NotNil(t, err)
+	assert.False(t, sent)
+}
@@ -0,0 +1,246 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/uploads"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/profileutils"
+	"golang.org/x/net/html"
+)
+
+// MaxEmailAttachmentsSize is the largest total size of the attachments of an
+// email. It is the limit of the email provider.
+const MaxEmailAttachmentsSize = 25 << 20
+
+// EmailUsecases represent logic required to send emails through the
+// configured email provider
+type EmailUsecases interface {
+	// SimpleEmail sends a plain text email and returns the provider's
+	// message ID
+	SimpleEmail(ctx context.Context, subject string, text string, to []string) (string, error)
+
+	// SendTemplatedEmail renders an email template to HTML and plain text,
+	// attaches uploads and sends it. It returns the provider's message ID.
+	SendTemplatedEmail(ctx context.Context, input dto.TemplatedEmailInput) (string, error)
+}
+
+// EmailImpl represents the email usecase implementation
+type EmailImpl struct {
+	Email     email.ServiceEmail
+	Templates TemplateUsecases
+	Uploads   uploads.UsecaseUploads
+}
+
+// NewEmail initializes an email usecase
+func NewEmail(
+	email email.ServiceEmail,
+	templates TemplateUsecases,
+	uploads uploads.UsecaseUploads,
+) *EmailImpl {
+	return &EmailImpl{
+		Email:     email,
+		Templates: templates,
+		Uploads:   uploads,
+	}
+}
+
+// SimpleEmail sends a plain text email to addresses. The addresses are
+// normalized and each one is only sent the email once.
+func (e *EmailImpl) SimpleEmail(
+	ctx context.Context,
+	subject string,
+	text string,
+	to []string,
+) (string, error) {
+	if strings.TrimSpace(subject) == "" {
+		return "", fmt.Errorf("an email subject can't be empty")
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("an email body can't be empty")
+	}
+	addresses, err := normalizeEmailRecipients(to)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := e.Email.Send(ctx, &email.Message{
+		To:      addresses,
+		Subject: subject,
+		Text:    text,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to send email: %w", err)
+	}
+	return id, nil
+}
+
+// SendTemplatedEmail renders an email template and sends it.
+//
+// The body of an email template is HTML. String variables are escaped before
+// they are rendered into it. The CSS of the template's style elements is
+// inlined for the email clients that ignore style sheets, and the plain text
+// alternative is derived from the HTML.
+//
+// An address is only sent the email once, in the first of to, cc and bcc that
+// it is in.
+func (e *EmailImpl) SendTemplatedEmail(ctx context.Context, input dto.TemplatedEmailInput) (string, error) {
+	seen := map[string]bool{}
+	to, err := normalizeEmailAddresses(input.To, seen)
+	if err != nil {
+		return "", err
+	}
+	if len(to) == 0 {
+		return "", fmt.Errorf("an email needs at least one recipient")
+	}
+	cc, err := normalizeEmailAddresses(input.Cc, seen)
+	if err != nil {
+		return "", err
+	}
+	bcc, err := normalizeEmailAddresses(input.Bcc, seen)
+	if err != nil {
+		return "", err
+	}
+
+	rendered, err := e.Templates.RenderTemplate(ctx, input.TemplateName, input.Language, escapeHTMLVariables(input.Variables))
+	if err != nil {
+		return "", fmt.Errorf("unable to render the %s template: %w", input.TemplateName, err)
+	}
+	if rendered.Channel != feedlib.ChannelEmail {
+		return "", fmt.Errorf("the %s template is a %s template, not an email template", input.TemplateName, rendered.Channel)
+	}
+	if rendered.Subject == nil || strings.TrimSpace(*rendered.Subject) == "" {
+		return "", fmt.Errorf("the %s template has no subject", input.TemplateName)
+	}
+
+	body, err := inlineCSS(rendered.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to inline the CSS of the %s template: %w", input.TemplateName, err)
+	}
+	text, err := htmlToText(body)
+	if err != nil {
+		return "", fmt.Errorf("unable to convert the %s template to text: %w", input.TemplateName, err)
+	}
+
+	attachments, err := e.attachments(ctx, input.Attachments)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := e.Email.Send(ctx, &email.Message{
+		To:          to,
+		Cc:          cc,
+		Bcc:         bcc,
+		Subject:     html.UnescapeString(*rendered.Subject),
+		Text:        text,
+		HTML:        body,
+		Attachments: attachments,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to send email: %w", err)
+	}
+	return id, nil
+}
+
+// attachments loads the uploads that are attached to an email
+func (e *EmailImpl) attachments(ctx context.Context, uploadIDs []string) ([]email.Attachment, error) {
+	attachments := []email.Attachment{}
+	seen := map[string]bool{}
+	size := 0
+	for _, id := range uploadIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		upload, err := e.Uploads.FindUploadByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the attachment %s: %w", id, err)
+		}
+		data, err := base64.StdEncoding.DecodeString(upload.Base64data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the attachment %s: %w", id, err)
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("the attachment %s is empty", id)
+		}
+		size += len(data)
+		if size > MaxEmailAttachmentsSize {
+			return nil, fmt.Errorf("the attachments are larger than the %d MB limit", MaxEmailAttachmentsSize>>20)
+		}
+		attachments = append(attachments, email.Attachment{
+			Filename:    attachmentFilename(upload),
+			ContentType: upload.ContentType,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
+
+// attachmentFilename names an attachment after its upload's title, adding an
+// extension for its content type when the title has none
+func attachmentFilename(upload *profileutils.Upload) string {
+	name := strings.TrimSpace(upload.Title)
+	if name == "" {
+		name = upload.ID
+	}
+	if path.Ext(name) != "" {
+		return name
+	}
+	if extensions, err := mime.ExtensionsByType(upload.ContentType); err == nil && len(extensions) > 0 {
+		return name + extensions[0]
+	}
+	return name
+}
+
+// escapeHTMLVariables escapes the string variables of a template that is
+// rendered to HTML
+func escapeHTMLVariables(variables map[string]interface{}) map[string]interface{} {
+	escaped := map[string]interface{}{}
+	for name, value := range variables {
+		if text, ok := value.(string); ok {
+			value = html.EscapeString(text)
+		}
+		escaped[name] = value
+	}
+	return escaped
+}
+
+// normalizeEmailRecipients returns the distinct normalized addresses of email
+// recipients
+func normalizeEmailRecipients(to []string) ([]string, error) {
+	addresses, err := normalizeEmailAddresses(to, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("an email needs at least one recipient")
+	}
+	return addresses, nil
+}
+
+// normalizeEmailAddresses returns the normalized addresses that are not in
+// seen, and adds them to it
+func normalizeEmailAddresses(addresses []string, seen map[string]bool) ([]string, error) {
+	normalized := []string{}
+	for _, address := range addresses {
+		n := normalizeEmail(address)
+		if !govalidator.IsEmail(n) {
+			return nil, fmt.Errorf("%s is not a valid email", address)
+		}
+		if !seen[n] {
+			seen[n] = true
+			normalized = append(normalized, n)
+		}
+	}
+	return normalized, nil
+}
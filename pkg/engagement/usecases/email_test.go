@@ -0,0 +1,400 @@
+package usecases_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	emailMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	uploadsMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/uploads/mock"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/profileutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailImpl_SimpleEmail(t *testing.T) {
+	ctx := context.Background()
+
+	var sent *email.Message
+	fakeEmail := &emailMock.FakeServiceEmail{
+		SendFn: func(ctx context.Context, message *email.Message) (string, error) {
+			if message.Subject == "fail" {
+				return "", fmt.Errorf("provider error")
+			}
+			sent = message
+			return "20211018.1@mg.bewell.co.ke", nil
+		},
+	}
+	e := usecases.NewEmail(fakeEmail, nil, nil)
+
+	tests := []struct {
+		name    string
+		subject string
+		text    string
+		to      []string
+		wantTo  []string
+		wantErr bool
+	}{
+		{
+			name:    "recipients are normalized and deduplicated",
+			subject: "Your results are ready",
+			text:    "Log in to see them",
+			to:      []string{"Jane@Example.com", " jane@example.com", "john@example.com"},
+			wantTo:  []string{"jane@example.com", "john@example.com"},
+		},
+		{
+			name:    "empty subject",
+			subject: " ",
+			text:    "Log in to see them",
+			to:      []string{"jane@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "empty text",
+			subject: "Your results are ready",
+			to:      []string{"jane@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid email",
+			subject: "Your results are ready",
+			text:    "Log in to see them",
+			to:      []string{"jane@example.com", "not an email"},
+			wantErr: true,
+		},
+		{
+			name:    "no recipients",
+			subject: "Your results are ready",
+			text:    "Log in to see them",
+			wantErr: true,
+		},
+		{
+			name:    "provider error",
+			subject: "fail",
+			text:    "Log in to see them",
+			to:      []string{"jane@example.com"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent = nil
+			id, err := e.SimpleEmail(ctx, tt.subject, tt.text, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SimpleEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, "20211018.1@mg.bewell.co.ke", id)
+			assert.Equal(t, tt.wantTo, sent.To)
+			assert.Equal(t, tt.text, sent.Text)
+		})
+	}
+}
+
+// smtpCatcher is a minimal SMTP server that records the messages it receives
+type smtpCatcher struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPCatcher(t *testing.T) *smtpCatcher {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to start SMTP catcher: %v", err)
+	}
+	c := &smtpCatcher{listener: listener, messages: make(chan string, 1)}
+	go c.serve()
+	t.Cleanup(func() { listener.Close() })
+	return c
+}
+
+func (c *smtpCatcher) serve() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		go c.handle(conn)
+	}
+}
+
+func (c *smtpCatcher) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			reply("354 end with .")
+			data := &strings.Builder{}
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			c.messages <- data.String()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestEmailImpl_SimpleEmail_SMTP(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+	host, port, _ := net.SplitHostPort(catcher.listener.Addr().String())
+	for name, value := range map[string]string{
+		email.SMTPHostEnvVarName: host,
+		email.SMTPPortEnvVarName: port,
+		email.SMTPFromEnvVarName: "Be.Well <no-reply@bewell.co.ke>",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	e := usecases.NewEmail(email.NewSMTPService(), nil, nil)
+	id, err := e.SimpleEmail(context.Background(), "Your results are ready", "Log in to see them", []string{"jane@example.com"})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(id, "@bewell.co.ke"))
+
+	message := <-catcher.messages
+	assert.Contains(t, message, "Message-ID: <"+id+">")
+	assert.Contains(t, message, "To: jane@example.com")
+	assert.Contains(t, message, "Subject: Your results are ready")
+	assert.Contains(t, message, "Log in to see them")
+}
+
+const testEmailTemplate = `<html><head><style>
+p { color: #333333; margin: 0 }
+.button { background: #0057b8; color: #ffffff }
+p.note { color: #999999 }
+a:hover { text-decoration: underline }
+</style></head><body>
+<p>Hello {{.name}},</p>
+<p>Your results are ready. <a class="button" href="{{.link}}" style="padding: 8px">View results</a></p>
+<p class="note">Questions? Contact <a href="mailto:help@bewell.co.ke">help@bewell.co.ke</a></p>
+</body></html>`
+
+func newTestTemplatedEmail(f *testFixture) *usecases.EmailImpl {
+	ctx := context.Background()
+	templates := usecases.NewTemplates(f.repository)
+
+	subject := "Results for {{.name}}"
+	for _, input := range []dto.NotificationTemplateInput{
+		{
+			Name:            "results",
+			Channel:         feedlib.ChannelEmail,
+			DefaultLanguage: enumutils.LanguageEn,
+			Variables:       []string{"name", "link"},
+			Variants: []*dto.TemplateVariantInput{
+				{Language: enumutils.LanguageEn, Subject: &subject, Body: testEmailTemplate},
+			},
+		},
+		{
+			Name:            "reminder",
+			Channel:         feedlib.ChannelSms,
+			DefaultLanguage: enumutils.LanguageEn,
+			Variants: []*dto.TemplateVariantInput{
+				{Language: enumutils.LanguageEn, Body: "Your appointment is tomorrow"},
+			},
+		},
+	} {
+		_, err := templates.CreateTemplate(ctx, input)
+		assert.Nil(f.t, err)
+	}
+
+	uploads := &uploadsMock.FakeServiceUploads{
+		FindUploadByIDFn: func(ctx context.Context, id string) (*profileutils.Upload, error) {
+			switch id {
+			case "report":
+				return &profileutils.Upload{
+					ID:          id,
+					Title:       "Lab report",
+					ContentType: "application/pdf",
+					Base64data:  base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")),
+				}, nil
+			case "empty":
+				return &profileutils.Upload{ID: id, Title: "empty.txt"}, nil
+			}
+			return nil, fmt.Errorf("upload %s not found", id)
+		},
+	}
+
+	return usecases.NewEmail(f.emailService(), templates, uploads)
+}
+
+func TestEmailImpl_SendTemplatedEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	e := newTestTemplatedEmail(f)
+
+	id, err := e.SendTemplatedEmail(ctx, dto.TemplatedEmailInput{
+		TemplateName: "results",
+		Variables: map[string]interface{}{
+			"name": "Jane & <John>",
+			"link": "https://bewell.co.ke/results/1",
+		},
+		To:          []string{"Jane@Example.com"},
+		Cc:          []string{"jane@example.com", "doctor@example.com"},
+		Bcc:         []string{"records@example.com"},
+		Attachments: []string{"report", "report"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "20211018.1@mg.bewell.co.ke", id)
+	assert.Len(t, f.emails, 1)
+
+	message := f.emails[0]
+	assert.Equal(t, []string{"jane@example.com"}, message.To)
+	assert.Equal(t, []string{"doctor@example.com"}, message.Cc)
+	assert.Equal(t, []string{"records@example.com"}, message.Bcc)
+	assert.Equal(t, "Results for Jane & <John>", message.Subject)
+
+	// variables are escaped and CSS is inlined in order of specificity
+	assert.Contains(t, message.HTML, "Hello Jane &amp; &lt;John&gt;")
+	assert.Contains(t, message.HTML, `<p style="color: #333333; margin: 0">Hello`)
+	assert.Contains(t, message.HTML, `<p class="note" style="color: #999999; margin: 0">`)
+	assert.Contains(t, message.HTML, `style="background: #0057b8; color: #ffffff; padding: 8px"`)
+	assert.Contains(t, message.HTML, "a:hover { text-decoration: underline }")
+	assert.NotContains(t, message.HTML, ".button {")
+
+	assert.Equal(
+		t,
+		"Hello Jane & <John>,\n\n"+
+			"Your results are ready. View results (https://bewell.co.ke/results/1)\n\n"+
+			"Questions? Contact help@bewell.co.ke",
+		message.Text,
+	)
+
+	assert.Len(t, message.Attachments, 1)
+	assert.Equal(t, "Lab report.pdf", message.Attachments[0].Filename)
+	assert.Equal(t, "application/pdf", message.Attachments[0].ContentType)
+	assert.Equal(t, []byte("%PDF-1.4"), message.Attachments[0].Data)
+}
+
+func TestEmailImpl_SendTemplatedEmail_Errors(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	e := newTestTemplatedEmail(f)
+	variables := map[string]interface{}{"name": "Jane", "link": "https://bewell.co.ke"}
+
+	tests := []struct {
+		name  string
+		input dto.TemplatedEmailInput
+	}{
+		{
+			name:  "no recipients",
+			input: dto.TemplatedEmailInput{TemplateName: "results", Variables: variables},
+		},
+		{
+			name: "invalid cc",
+			input: dto.TemplatedEmailInput{
+				TemplateName: "results",
+				Variables:    variables,
+				To:           []string{"jane@example.com"},
+				Cc:           []string{"not an email"},
+			},
+		},
+		{
+			name: "missing variable",
+			input: dto.TemplatedEmailInput{
+				TemplateName: "results",
+				Variables:    map[string]interface{}{"name": "Jane"},
+				To:           []string{"jane@example.com"},
+			},
+		},
+		{
+			name: "not an email template",
+			input: dto.TemplatedEmailInput{
+				TemplateName: "reminder",
+				To:           []string{"jane@example.com"},
+			},
+		},
+		{
+			name: "unknown upload",
+			input: dto.TemplatedEmailInput{
+				TemplateName: "results",
+				Variables:    variables,
+				To:           []string{"jane@example.com"},
+				Attachments:  []string{"missing"},
+			},
+		},
+		{
+			name: "empty upload",
+			input: dto.TemplatedEmailInput{
+				TemplateName: "results",
+				Variables:    variables,
+				To:           []string{"jane@example.com"},
+				Attachments:  []string{"empty"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.SendTemplatedEmail(ctx, tt.input)
+			assert.NotNil(t, err)
+		})
+	}
+	assert.Empty(t, f.emails)
+}
+
+func TestEmailImpl_SendTemplatedEmail_SMTP(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+	host, port, _ := net.SplitHostPort(catcher.listener.Addr().String())
+	for name, value := range map[string]string{
+		email.SMTPHostEnvVarName: host,
+		email.SMTPPortEnvVarName: port,
+		email.SMTPFromEnvVarName: "Be.Well <no-reply@bewell.co.ke>",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	templated := newTestTemplatedEmail(newTestFixture(t))
+	e := usecases.NewEmail(email.NewSMTPService(), templated.Templates, templated.Uploads)
+	_, err := e.SendTemplatedEmail(context.Background(), dto.TemplatedEmailInput{
+		TemplateName: "results",
+		Variables:    map[string]interface{}{"name": "Jane", "link": "https://bewell.co.ke"},
+		To:           []string{"jane@example.com"},
+		Cc:           []string{"doctor@example.com"},
+		Bcc:          []string{"records@example.com"},
+		Attachments:  []string{"report"},
+	})
+	assert.Nil(t, err)
+
+	message := <-catcher.messages
+	assert.Contains(t, message, "Cc: doctor@example.com")
+	assert.NotContains(t, message, "records@example.com")
+	assert.Contains(t, message, "Content-Type: multipart/mixed")
+	assert.Contains(t, message, "Content-Type: multipart/alternative")
+	assert.Contains(t, message, `Content-Disposition: attachment; filename="Lab report.pdf"`)
+	assert.Contains(t, message, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")))
+}
@@ -0,0 +1,397 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/uploads"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/profileutils"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// InboundEmailPath is the path of the endpoint that the email provider
+	// posts received emails to. The provider posts the raw MIME email to
+	// routes whose URL ends in `mime`.
+	InboundEmailPath = "/email/inbound/mime"
+
+	// emailReplyPrefix starts the local part of reply addresses e.g
+	// `reply+<conversation ID>.<signature>@reply.bewell.co.ke`
+	emailReplyPrefix = "reply+"
+
+	// emailReplySignatureLength is how many hex characters of the signature
+	// are kept in reply addresses, which have to fit in 64 characters
+	emailReplySignatureLength = 20
+)
+
+// EmailConversationConfig holds the keys and domain of email conversations
+type EmailConversationConfig struct {
+	// WebhookSigningKey is the email provider's webhook signing key, which
+	// also signs the received emails that it posts
+	WebhookSigningKey []byte
+
+	// ReplySigningKey signs reply addresses so that a reply can only be
+	// posted to the conversation that its address was made for
+	ReplySigningKey []byte
+
+	// InboundDomain is the domain that the email provider receives email
+	// for e.g reply.bewell.co.ke
+	InboundDomain string
+}
+
+// EmailConversationUsecases represent logic required to email addresses
+// about feed items and to post the replies to the items' conversations
+type EmailConversationUsecases interface {
+	// VerifyInboundEmailSignature checks the signature of a received email.
+	// It returns exceptions.ErrInvalidEmailWebhookSignature when the
+	// signature does not match or is too old.
+	VerifyInboundEmailSignature(timestamp string, token string, signature string) error
+
+	// HandleInboundEmail parses a raw MIME email and posts it to the
+	// conversation of the reply address that it was sent to. `recipient` is
+	// the address that the provider received the email for.
+	HandleInboundEmail(ctx context.Context, recipient string, raw []byte) (*domain.InboundEmail, error)
+
+	// StartEmailConversation emails an address about a feed item
+	StartEmailConversation(
+		ctx context.Context,
+		createdBy string,
+		input dto.EmailConversationInput,
+	) (*domain.EmailConversation, error)
+
+	GetEmailConversation(ctx context.Context, id string) (*domain.EmailConversation, error)
+}
+
+// EmailConversationImpl represents the email conversation usecase
+// implementation
+type EmailConversationImpl struct {
+	Repository repository.EmailConversationRepository
+	Email      email.ServiceEmail
+	Uploads    uploads.UsecaseUploads
+	Feed       FeedMessages
+	Inbox      InboxUsecases
+	Config     EmailConversationConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewEmailConversations initializes an email conversation usecase
+func NewEmailConversations(
+	repository repository.EmailConversationRepository,
+	email email.ServiceEmail,
+	uploads uploads.UsecaseUploads,
+	feed FeedMessages,
+	inbox InboxUsecases,
+	config EmailConversationConfig,
+) (*EmailConversationImpl, error) {
+	if len(config.WebhookSigningKey) == 0 {
+		return nil, fmt.Errorf("the email webhook signing key is required")
+	}
+	if len(config.ReplySigningKey) < 32 {
+		return nil, fmt.Errorf("the email reply signing key must be at least 32 bytes")
+	}
+	if !govalidator.IsDNSName(config.InboundDomain) {
+		return nil, fmt.Errorf("%q is not a valid inbound email domain", config.InboundDomain)
+	}
+	return &EmailConversationImpl{
+		Repository: repository,
+		Email:      email,
+		Uploads:    uploads,
+		Feed:       feed,
+		Inbox:      inbox,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SignEmailReplyAddress returns the signature of a conversation that is part
+// of its reply address
+func SignEmailReplyAddress(key []byte, conversationID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(emailReplyPrefix + conversationID))
+	return hex.EncodeToString(mac.Sum(nil))[:emailReplySignatureLength]
+}
+
+// EmailReplyAddress returns the address that replies to the emails of a
+// conversation are sent to
+func (e *EmailConversationImpl) EmailReplyAddress(conversationID string) string {
+	signature := SignEmailReplyAddress(e.Config.ReplySigningKey, conversationID)
+	return emailReplyPrefix + conversationID + "." + signature + "@" + e.Config.InboundDomain
+}
+
+// replyConversationID returns the conversation ID of a reply address, and
+// false when the address is not a reply address or its signature does not
+// match
+func (e *EmailConversationImpl) replyConversationID(address string) (string, bool) {
+	at := strings.LastIndex(address, "@")
+	if at < 0 || !strings.EqualFold(address[at+1:], e.Config.InboundDomain) {
+		return "", false
+	}
+	local := address[:at]
+	if !strings.HasPrefix(strings.ToLower(local), emailReplyPrefix) {
+		return "", false
+	}
+	token := local[len(emailReplyPrefix):]
+	dot := strings.LastIndex(token, ".")
+	if dot < 0 {
+		return "", false
+	}
+	id, signature := token[:dot], strings.ToLower(token[dot+1:])
+	expected := SignEmailReplyAddress(e.Config.ReplySigningKey, id)
+	if id == "" || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", false
+	}
+	return id, true
+}
+
+// VerifyInboundEmailSignature checks the email provider's signature of a
+// received email
+func (e *EmailConversationImpl) VerifyInboundEmailSignature(timestamp string, token string, signature string) error {
+	return verifyEmailWebhookSignature(e.Config.WebhookSigningKey, e.Clock(), timestamp, token, signature)
+}
+
+// HandleInboundEmail saves a received email after posting it to the feed item
+// of its conversation.
+//
+// Quoted history is stripped from the reply and its attachments are saved as
+// uploads. Emails that were not sent to a valid reply address, or that were
+// not sent by the address of the conversation, are only saved.
+//
+// The email's ID is derived from its Message-ID so that an email that is
+// posted again is not uploaded or posted twice.
+func (e *EmailConversationImpl) HandleInboundEmail(
+	ctx context.Context,
+	recipient string,
+	raw []byte,
+) (*domain.InboundEmail, error) {
+	parsed, err := parseMIMEEmail(raw)
+	if err != nil {
+		return nil, err
+	}
+	id := inboundEmailID(parsed.messageID, raw)
+	existing, err := e.Repository.GetInboundEmail(ctx, id)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, exceptions.ErrInboundEmailNotFound) {
+		return nil, fmt.Errorf("unable to get inbound email: %w", err)
+	}
+
+	text, err := parsed.replyText()
+	if err != nil {
+		return nil, err
+	}
+	recipients := []string{}
+	for _, address := range strings.Split(recipient, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			recipients = append(recipients, address)
+		}
+	}
+	recipients = append(recipients, parsed.recipients...)
+
+	inbound := &domain.InboundEmail{
+		ID:          id,
+		MessageID:   parsed.messageID,
+		From:        parsed.from,
+		Subject:     parsed.subject,
+		Text:        text,
+		Attachments: []string{},
+		ReceivedAt:  e.Clock(),
+	}
+	if len(recipients) > 0 {
+		inbound.Recipient = recipients[0]
+	}
+
+	conversation, err := e.replyConversation(ctx, recipients)
+	if err != nil {
+		return nil, err
+	}
+	if conversation != nil && conversation.Email != parsed.from {
+		log.Printf("email from %s to conversation %s of %s was not posted", parsed.from, conversation.ID, conversation.Email)
+		conversation = nil
+	}
+	if conversation != nil {
+		if err := e.postReply(ctx, conversation, inbound, parsed.attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.Repository.SaveInboundEmail(ctx, inbound); err != nil {
+		return nil, fmt.Errorf("unable to save inbound email: %w", err)
+	}
+	return inbound, nil
+}
+
+// replyConversation returns the conversation of the first valid reply
+// address, or nil when the email was not sent to one
+func (e *EmailConversationImpl) replyConversation(
+	ctx context.Context,
+	recipients []string,
+) (*domain.EmailConversation, error) {
+	for _, address := range recipients {
+		id, ok := e.replyConversationID(address)
+		if !ok {
+			continue
+		}
+		conversation, err := e.Repository.GetEmailConversation(ctx, id)
+		if errors.Is(err, exceptions.ErrEmailConversationNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get email conversation: %w", err)
+		}
+		return conversation, nil
+	}
+	return nil, nil
+}
+
+// postReply uploads the attachments of a reply and posts it to the feed item
+// of its conversation. The attachments are listed after the reply's text.
+func (e *EmailConversationImpl) postReply(
+	ctx context.Context,
+	conversation *domain.EmailConversation,
+	inbound *domain.InboundEmail,
+	attachments []email.Attachment,
+) error {
+	text := inbound.Text
+	links := []string{}
+	for i, attachment := range attachments {
+		filename := attachment.Filename
+		if filename == "" {
+			filename = fmt.Sprintf("attachment-%d", i+1)
+		}
+		upload, err := e.Uploads.Upload(ctx, profileutils.UploadInput{
+			Title:       filename,
+			ContentType: attachment.ContentType,
+			Language:    enumutils.LanguageEn.String(),
+			Base64data:  base64.StdEncoding.EncodeToString(attachment.Data),
+			Filename:    filename,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to upload email attachment %s: %w", filename, err)
+		}
+		inbound.Attachments = append(inbound.Attachments, upload.ID)
+		links = append(links, "- "+filename+": "+upload.URL)
+	}
+	if len(links) > 0 {
+		text = strings.TrimSpace(text + "\n\nAttachments:\n" + strings.Join(links, "\n"))
+	}
+	if text == "" {
+		return nil
+	}
+
+	_, err := e.Feed.PostMessage(ctx, conversation.UID, conversation.Flavour, conversation.ItemID, &feedlib.Message{
+		ID:             "email-" + inbound.ID,
+		SequenceNumber: int(inbound.ReceivedAt.Unix()),
+		Text:           text,
+		PostedByUID:    conversation.UID,
+		PostedByName:   inbound.From,
+		Timestamp:      inbound.ReceivedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to post email reply: %w", err)
+	}
+	inbound.ConversationID = conversation.ID
+	return nil
+}
+
+// inboundEmailID is the hash of an email's Message-ID, or of the whole email
+// when it has none. Message-IDs contain characters that can't be in a
+// document ID.
+func inboundEmailID(messageID string, raw []byte) string {
+	content := []byte(messageID)
+	if messageID == "" {
+		content = raw
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:16])
+}
+
+// StartEmailConversation records the feed item that replies to an email
+// belong to and sends the email with the conversation's reply address.
+//
+// The conversation is saved before the email is sent so that a reply can't
+// arrive before it exists.
+func (e *EmailConversationImpl) StartEmailConversation(
+	ctx context.Context,
+	createdBy string,
+	input dto.EmailConversationInput,
+) (*domain.EmailConversation, error) {
+	if strings.TrimSpace(input.Subject) == "" || strings.TrimSpace(input.Message) == "" {
+		return nil, fmt.Errorf("an email conversation needs a subject and a message")
+	}
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid flavour", input.Flavour)
+	}
+	if input.UID == "" || input.ItemID == "" {
+		return nil, fmt.Errorf("an email conversation needs a UID and an item ID")
+	}
+	address := normalizeEmail(input.Email)
+	if !govalidator.IsEmail(address) {
+		return nil, fmt.Errorf("%s is not a valid email", input.Email)
+	}
+
+	conversation := &domain.EmailConversation{
+		ID:        ksuid.New().String(),
+		Email:     address,
+		UID:       input.UID,
+		Flavour:   input.Flavour,
+		ItemID:    input.ItemID,
+		Subject:   input.Subject,
+		CreatedBy: createdBy,
+		CreatedAt: e.Clock(),
+	}
+	if err := e.Repository.SaveEmailConversation(ctx, conversation); err != nil {
+		return nil, fmt.Errorf("unable to save email conversation: %w", err)
+	}
+
+	messageID, err := e.Email.Send(ctx, &email.Message{
+		To:      []string{address},
+		Subject: input.Subject,
+		Text:    input.Message,
+		Headers: map[string]string{"Reply-To": e.EmailReplyAddress(conversation.ID)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to send email: %w", err)
+	}
+
+	recordDispatch(ctx, e.Inbox, &domain.InboxNotification{
+		UID:       conversation.UID,
+		Channel:   feedlib.ChannelEmail,
+		MessageID: messageID,
+		Title:     input.Subject,
+		Body:      input.Message,
+		Data: map[string]interface{}{
+			"flavour":        string(conversation.Flavour),
+			"itemID":         conversation.ItemID,
+			"conversationID": conversation.ID,
+		},
+		CreatedAt: conversation.CreatedAt,
+	})
+	return conversation, nil
+}
+
+// GetEmailConversation retrieves an email conversation by its ID
+func (e *EmailConversationImpl) GetEmailConversation(
+	ctx context.Context,
+	id string,
+) (*domain.EmailConversation, error) {
+	return e.Repository.GetEmailConversation(ctx, id)
+}
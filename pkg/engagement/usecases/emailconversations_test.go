@@ -0,0 +1,332 @@
+package usecases_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	uploadsMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/uploads/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/profileutils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEmailReplyKey = []byte("abcdef0123456789abcdef0123456789")
+
+const testInboundDomain = "reply.example.com"
+
+func newTestEmailConversations(f *testFixture) (*usecases.EmailConversationImpl, *[]profileutils.UploadInput) {
+	uploaded := []profileutils.UploadInput{}
+	uploads := &uploadsMock.FakeServiceUploads{
+		UploadFn: func(ctx context.Context, input profileutils.UploadInput) (*profileutils.Upload, error) {
+			uploaded = append(uploaded, input)
+			id := fmt.Sprintf("upload-%d", len(uploaded))
+			return &profileutils.Upload{ID: id, URL: "https://storage.example.com/" + id}, nil
+		},
+	}
+	e, err := usecases.NewEmailConversations(
+		f.repository,
+		f.emailService(),
+		uploads,
+		f.feed,
+		usecases.NewInbox(f.repository),
+		usecases.EmailConversationConfig{
+			WebhookSigningKey: testEmailWebhookKey,
+			ReplySigningKey:   testEmailReplyKey,
+			InboundDomain:     testInboundDomain,
+		},
+	)
+	if err != nil {
+		f.t.Fatalf("unable to initialize email conversations: %v", err)
+	}
+	e.Clock = f.clock.Now
+	return e, &uploaded
+}
+
+func startTestEmailConversation(t *testing.T, e *usecases.EmailConversationImpl) *domain.EmailConversation {
+	conversation, err := e.StartEmailConversation(context.Background(), "clinician", dto.EmailConversationInput{
+		Email:   "Patient@Example.com",
+		UID:     "patient",
+		Flavour: feedlib.FlavourConsumer,
+		ItemID:  "item",
+		Subject: "Your results",
+		Message: "Your results are ready. Reply with any questions.",
+	})
+	if err != nil {
+		t.Fatalf("unable to start email conversation: %v", err)
+	}
+	return conversation
+}
+
+// testReply builds a raw email from the patient to an address
+func testReply(to string, contentType string, body string) []byte {
+	return []byte(strings.Join([]string{
+		"From: Patient <patient@example.com>",
+		"To: " + to,
+		"Subject: Re: Your results",
+		"Message-ID: <CAB123@mail.example.com>",
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+		"",
+		body,
+	}, "\r\n"))
+}
+
+func TestNewEmailConversations(t *testing.T) {
+	_, err := usecases.NewEmailConversations(nil, nil, nil, nil, nil, usecases.EmailConversationConfig{
+		WebhookSigningKey: testEmailWebhookKey,
+		ReplySigningKey:   []byte("short"),
+		InboundDomain:     testInboundDomain,
+	})
+	assert.Error(t, err)
+
+	_, err = usecases.NewEmailConversations(nil, nil, nil, nil, nil, usecases.EmailConversationConfig{
+		WebhookSigningKey: testEmailWebhookKey,
+		ReplySigningKey:   testEmailReplyKey,
+		InboundDomain:     "not a domain",
+	})
+	assert.Error(t, err)
+}
+
+func TestEmailConversationImpl_VerifyInboundEmailSignature(t *testing.T) {
+	e, _ := newTestEmailConversations(newTestFixture(t))
+	now := strconv.FormatInt(e.Clock().Unix(), 10)
+
+	err := e.VerifyInboundEmailSignature(now, "token", usecases.SignEmailWebhook(testEmailWebhookKey, now, "token"))
+	assert.Nil(t, err)
+
+	err = e.VerifyInboundEmailSignature(now, "token", usecases.SignEmailWebhook(testEmailReplyKey, now, "token"))
+	assert.Error(t, err)
+}
+
+func TestEmailConversationImpl_StartEmailConversation(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	e, _ := newTestEmailConversations(f)
+
+	conversation := startTestEmailConversation(t, e)
+	assert.Equal(t, "patient@example.com", conversation.Email)
+	assert.Equal(t, "clinician", conversation.CreatedBy)
+
+	assert.Len(t, f.emails, 1)
+	message := f.emails[0]
+	assert.Equal(t, []string{"patient@example.com"}, message.To)
+	assert.Equal(t, e.EmailReplyAddress(conversation.ID), message.Headers["Reply-To"])
+	assert.True(t, strings.HasPrefix(message.Headers["Reply-To"], "reply+"+conversation.ID+"."))
+	assert.True(t, strings.HasSuffix(message.Headers["Reply-To"], "@"+testInboundDomain))
+
+	saved, err := e.GetEmailConversation(ctx, conversation.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, conversation.ItemID, saved.ItemID)
+
+	invalid := []dto.EmailConversationInput{
+		{Email: "not an email", UID: "patient", Flavour: feedlib.FlavourConsumer, ItemID: "item", Subject: "s", Message: "m"},
+		{Email: "patient@example.com", UID: "patient", Flavour: feedlib.FlavourConsumer, ItemID: "item", Message: "m"},
+		{Email: "patient@example.com", Flavour: feedlib.FlavourConsumer, ItemID: "item", Subject: "s", Message: "m"},
+		{Email: "patient@example.com", UID: "patient", Flavour: "invalid", ItemID: "item", Subject: "s", Message: "m"},
+	}
+	for _, input := range invalid {
+		_, err := e.StartEmailConversation(ctx, "clinician", input)
+		assert.Error(t, err)
+	}
+	assert.Len(t, f.emails, 1)
+}
+
+func TestEmailConversationImpl_HandleInboundEmail_Replies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantText    string
+	}{
+		{
+			name:        "gmail reply with a wrapped quote header",
+			contentType: "text/plain; charset=UTF-8",
+			body: "When should I come in?\r\n\r\nOn Mon, Oct 18, 2021 at 10:00 AM Be.Well <\r\n" +
+				"reply@reply.example.com> wrote:\r\n\r\n> Your results are ready.\r\n",
+			wantText: "When should I come in?",
+		},
+		{
+			name:        "outlook reply",
+			contentType: "text/plain; charset=UTF-8",
+			body: "Thanks, see you then.\r\n\r\n________________________________\r\n" +
+				"From: Be.Well <reply@reply.example.com>\r\nSent: Monday, October 18, 2021\r\n" +
+				"Subject: Your results\r\n\r\nYour results are ready.\r\n",
+			wantText: "Thanks, see you then.",
+		},
+		{
+			name:        "outlook header block without a separator",
+			contentType: "text/plain; charset=UTF-8",
+			body: "Thanks.\r\n\r\nFrom: Be.Well <reply@reply.example.com>\r\n" +
+				"Date: Monday, October 18, 2021\r\n\r\nYour results are ready.\r\n",
+			wantText: "Thanks.",
+		},
+		{
+			name:        "inline reply with a signature",
+			contentType: "text/plain; charset=UTF-8",
+			body:        "> Your results are ready.\r\nCan I get a copy?\r\n\r\n-- \r\nJane Doe\r\n",
+			wantText:    "Can I get a copy?",
+		},
+		{
+			name:        "quoted printable latin-1",
+			contentType: "text/plain; charset=ISO-8859-1\r\nContent-Transfer-Encoding: quoted-printable",
+			body:        "Merci, =E0 bient=F4t.\r\n",
+			wantText:    "Merci, à bientôt.",
+		},
+		{
+			name:        "html only reply",
+			contentType: "text/html; charset=UTF-8",
+			body: `<div dir="ltr">I feel <b>better</b> today.</div><br>` +
+				`<div class="gmail_quote"><div class="gmail_attr">On Mon, Oct 18, 2021 Be.Well wrote:</div>` +
+				`<blockquote class="gmail_quote">Your results are ready.</blockquote></div>`,
+			wantText: "I feel better today.",
+		},
+		{
+			name:        "only a quote",
+			contentType: "text/plain; charset=UTF-8",
+			body:        "> Your results are ready.\r\n",
+			wantText:    "> Your results are ready.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newTestFixture(t)
+			e, _ := newTestEmailConversations(f)
+			conversation := startTestEmailConversation(t, e)
+			address := e.EmailReplyAddress(conversation.ID)
+
+			inbound, err := e.HandleInboundEmail(ctx, address, testReply(address, tt.contentType, tt.body))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantText, inbound.Text)
+			assert.Equal(t, conversation.ID, inbound.ConversationID)
+			assert.Equal(t, "patient@example.com", inbound.From)
+			assert.Equal(t, "Re: Your results", inbound.Subject)
+
+			assert.Len(t, f.feed.posted, 1)
+			assert.Equal(t, tt.wantText, f.feed.posted[0].Text)
+			assert.Equal(t, "patient", f.feed.posted[0].PostedByUID)
+			assert.Equal(t, []string{"patient/CONSUMER/item"}, f.feed.items)
+		})
+	}
+}
+
+func TestEmailConversationImpl_HandleInboundEmail_Attachments(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	e, uploaded := newTestEmailConversations(f)
+	conversation := startTestEmailConversation(t, e)
+	address := e.EmailReplyAddress(conversation.ID)
+
+	body := strings.Join([]string{
+		"--mixed",
+		"Content-Type: multipart/alternative; boundary=alt",
+		"",
+		"--alt",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		"Here is my prescription.",
+		"--alt",
+		"Content-Type: text/html; charset=UTF-8",
+		"",
+		"<p>Here is my <i>prescription</i>.</p>",
+		"--alt--",
+		"--mixed",
+		"Content-Type: image/png",
+		"Content-Disposition: inline",
+		"Content-ID: <logo>",
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString([]byte("logo")),
+		"--mixed",
+		`Content-Type: application/pdf; name="prescription.pdf"`,
+		`Content-Disposition: attachment; filename="prescription.pdf"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")),
+		"--mixed--",
+	}, "\r\n")
+	raw := testReply(address, "multipart/mixed; boundary=mixed", body)
+
+	inbound, err := e.HandleInboundEmail(ctx, address, raw)
+	assert.Nil(t, err)
+	assert.Equal(t, "Here is my prescription.", inbound.Text)
+	assert.Equal(t, []string{"upload-1"}, inbound.Attachments)
+
+	assert.Len(t, *uploaded, 1)
+	upload := (*uploaded)[0]
+	assert.Equal(t, "prescription.pdf", upload.Filename)
+	assert.Equal(t, "application/pdf", upload.ContentType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), upload.Base64data)
+
+	assert.Len(t, f.feed.posted, 1)
+	assert.Equal(
+		t,
+		"Here is my prescription.\n\nAttachments:\n- prescription.pdf: https://storage.example.com/upload-1",
+		f.feed.posted[0].Text,
+	)
+
+	// an email that is posted again is not uploaded or posted twice
+	again, err := e.HandleInboundEmail(ctx, address, raw)
+	assert.Nil(t, err)
+	assert.Equal(t, inbound.ID, again.ID)
+	assert.Len(t, *uploaded, 1)
+	assert.Len(t, f.feed.posted, 1)
+}
+
+func TestEmailConversationImpl_HandleInboundEmail_NotPosted(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	e, _ := newTestEmailConversations(f)
+	conversation := startTestEmailConversation(t, e)
+	address := e.EmailReplyAddress(conversation.ID)
+	forged := "reply+" + conversation.ID + ".0123456789abcdef0123@" + testInboundDomain
+
+	tests := []struct {
+		name string
+		to   string
+		raw  []byte
+	}{
+		{
+			name: "reply address with a forged signature",
+			to:   forged,
+			raw:  testReply(forged, "text/plain", "Hello"),
+		},
+		{
+			name: "email that is not to a reply address",
+			to:   "support@" + testInboundDomain,
+			raw:  testReply("support@"+testInboundDomain, "text/plain", "Hello"),
+		},
+		{
+			name: "reply from another address",
+			to:   address,
+			raw: []byte(strings.Replace(
+				string(testReply(address, "text/plain", "Hello")),
+				"patient@example.com",
+				"someone@example.com",
+				1,
+			)),
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// each email has its own Message-ID so that it is not treated
+			// as a retry of the previous one
+			raw := strings.Replace(string(tt.raw), "CAB123", fmt.Sprintf("CAB%d", i), 1)
+			inbound, err := e.HandleInboundEmail(ctx, tt.to, []byte(raw))
+			assert.Nil(t, err)
+			assert.Equal(t, "", inbound.ConversationID)
+			assert.Equal(t, "Hello", inbound.Text)
+			assert.Len(t, f.feed.posted, 0)
+		})
+	}
+
+	_, err := e.HandleInboundEmail(ctx, address, []byte("not an email"))
+	assert.Error(t, err)
+}
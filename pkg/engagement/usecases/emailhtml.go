@@ -0,0 +1,400 @@
+package usecases
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+	// cssCompoundSelectorPattern matches the selectors that can be inlined
+	// e.g `p`, `.button`, `#footer` or `td.total`
+	cssCompoundSelectorPattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*)?((?:[.#][-_a-zA-Z0-9]+)*)$`)
+	cssSimpleSelectorPattern   = regexp.MustCompile(`[.#][-_a-zA-Z0-9]+`)
+)
+
+// cssRule is a style sheet rule with a single selector that can be inlined.
+//
+// A selector is a list of compound selectors that are separated by the
+// descendant combinator e.g `table td.total`.
+type cssRule struct {
+	selector     []cssCompoundSelector
+	declarations [][2]string
+	specificity  [3]int
+	order        int
+}
+
+type cssCompoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// matches reports whether an element is matched by a compound selector
+func (c cssCompoundSelector) matches(n *html.Node) bool {
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && htmlAttr(n, "id") != c.id {
+		return false
+	}
+	classes := strings.Fields(htmlAttr(n, "class"))
+	for _, class := range c.classes {
+		found := false
+		for _, c := range classes {
+			if c == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether an element is matched by a rule's selector. The
+// last compound selector must match the element and the others its
+// ancestors, in order.
+func (r cssRule) matches(n *html.Node) bool {
+	last := len(r.selector) - 1
+	if !r.selector[last].matches(n) {
+		return false
+	}
+	i := last - 1
+	for p := n.Parent; p != nil && i >= 0; p = p.Parent {
+		if p.Type == html.ElementNode && r.selector[i].matches(p) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+// parseCSS splits a style sheet into the rules that can be inlined and the
+// CSS that can't e.g media queries and pseudo classes, which is left in a
+// style element for the email clients that support it.
+func parseCSS(css string, order int) ([]cssRule, string) {
+	css = cssCommentPattern.ReplaceAllString(css, "")
+	rules := []cssRule{}
+	remaining := []string{}
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+		open := strings.Index(css, "{")
+		if open < 0 {
+			break
+		}
+
+		if strings.HasPrefix(css, "@") {
+			end := matchingBrace(css, open)
+			remaining = append(remaining, css[:end])
+			css = css[end:]
+			continue
+		}
+
+		close := strings.Index(css[open:], "}")
+		if close < 0 {
+			break
+		}
+		close += open
+		selectors := css[:open]
+		declarations := parseCSSDeclarations(css[open+1 : close])
+		css = css[close+1:]
+
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+			rule, ok := parseCSSSelector(selector)
+			if !ok {
+				remaining = append(remaining, fmt.Sprintf("%s { %s }", selector, formatCSSDeclarations(declarations)))
+				continue
+			}
+			rule.declarations = declarations
+			rule.order = order
+			order++
+			rules = append(rules, rule)
+		}
+	}
+	return rules, strings.Join(remaining, "\n")
+}
+
+// matchingBrace returns the index after the brace that closes the block that
+// opens at an index
+func matchingBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseCSSSelector(selector string) (cssRule, bool) {
+	rule := cssRule{}
+	for _, part := range strings.Fields(selector) {
+		match := cssCompoundSelectorPattern.FindStringSubmatch(part)
+		if match == nil {
+			return rule, false
+		}
+		compound := cssCompoundSelector{tag: strings.ToLower(match[1])}
+		if compound.tag != "" {
+			rule.specificity[2]++
+		}
+		for _, simple := range cssSimpleSelectorPattern.FindAllString(match[2], -1) {
+			if simple[0] == '#' {
+				compound.id = simple[1:]
+				rule.specificity[0]++
+			} else {
+				compound.classes = append(compound.classes, simple[1:])
+				rule.specificity[1]++
+			}
+		}
+		rule.selector = append(rule.selector, compound)
+	}
+	return rule, len(rule.selector) > 0
+}
+
+func parseCSSDeclarations(block string) [][2]string {
+	declarations := [][2]string{}
+	for _, declaration := range strings.Split(block, ";") {
+		colon := strings.Index(declaration, ":")
+		if colon < 0 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(declaration[:colon]))
+		value := strings.TrimSpace(declaration[colon+1:])
+		if property == "" || value == "" {
+			continue
+		}
+		declarations = append(declarations, [2]string{property, value})
+	}
+	return declarations
+}
+
+func formatCSSDeclarations(declarations [][2]string) string {
+	formatted := []string{}
+	for _, declaration := range declarations {
+		formatted = append(formatted, declaration[0]+": "+declaration[1])
+	}
+	return strings.Join(formatted, "; ")
+}
+
+// inlineCSS moves the rules of an HTML document's style elements into the
+// style attributes of the elements that they match, since many email clients
+// ignore style sheets.
+//
+// Rules are applied in order of specificity and then of appearance, and an
+// element's own style attribute wins over them.
+func inlineCSS(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse HTML: %w", err)
+	}
+
+	styles := []*html.Node{}
+	rules := []cssRule{}
+	remaining := []string{}
+	walkHTML(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Style {
+			return
+		}
+		styles = append(styles, n)
+		css := &strings.Builder{}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			css.WriteString(c.Data)
+		}
+		parsed, rest := parseCSS(css.String(), len(rules))
+		rules = append(rules, parsed...)
+		if rest != "" {
+			remaining = append(remaining, rest)
+		}
+	})
+
+	// styles that can't be inlined are kept in the first style element
+	for i, style := range styles {
+		if i == 0 && len(remaining) > 0 {
+			for c := style.FirstChild; c != nil; c = style.FirstChild {
+				style.RemoveChild(c)
+			}
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: strings.Join(remaining, "\n")})
+			continue
+		}
+		style.Parent.RemoveChild(style)
+	}
+
+	walkHTML(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		matched := []cssRule{}
+		for _, rule := range rules {
+			if rule.matches(n) {
+				matched = append(matched, rule)
+			}
+		}
+		if len(matched) == 0 {
+			return
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i].specificity, matched[j].specificity
+			for k := range a {
+				if a[k] != b[k] {
+					return a[k] < b[k]
+				}
+			}
+			return matched[i].order < matched[j].order
+		})
+
+		properties := []string{}
+		values := map[string]string{}
+		apply := func(declarations [][2]string) {
+			for _, declaration := range declarations {
+				if _, ok := values[declaration[0]]; !ok {
+					properties = append(properties, declaration[0])
+				}
+				values[declaration[0]] = declaration[1]
+			}
+		}
+		for _, rule := range matched {
+			apply(rule.declarations)
+		}
+		apply(parseCSSDeclarations(htmlAttr(n, "style")))
+
+		declarations := [][2]string{}
+		for _, property := range properties {
+			declarations = append(declarations, [2]string{property, values[property]})
+		}
+		setHTMLAttr(n, "style", formatCSSDeclarations(declarations))
+	})
+
+	buf := &bytes.Buffer{}
+	if err := html.Render(buf, doc); err != nil {
+		return "", fmt.Errorf("unable to render HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// htmlToText converts an HTML document to the plain text alternative of an
+// email. Block elements start new lines and links are followed by their URL.
+func htmlToText(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse HTML: %w", err)
+	}
+	buf := &strings.Builder{}
+	writeHTMLText(buf, doc)
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text := blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text), nil
+}
+
+func writeHTMLText(buf *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(whitespacePattern.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Style, atom.Script, atom.Title:
+		return
+	case atom.Br:
+		buf.WriteString("\n")
+		return
+	case atom.Hr:
+		buf.WriteString("\n\n")
+		return
+	case atom.Img:
+		buf.WriteString(htmlAttr(n, "alt"))
+		return
+	case atom.A:
+		link := &strings.Builder{}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeHTMLText(link, c)
+		}
+		text := strings.Join(strings.Fields(link.String()), " ")
+		href := strings.TrimSpace(htmlAttr(n, "href"))
+		buf.WriteString(text)
+		if href != "" && !strings.HasPrefix(href, "#") && href != text && href != "mailto:"+text {
+			buf.WriteString(" (" + href + ")")
+		}
+		return
+	}
+
+	before, after := "", ""
+	switch n.DataAtom {
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Table, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre:
+		before, after = "\n\n", "\n\n"
+	case atom.Div, atom.Tr, atom.Section, atom.Header, atom.Footer, atom.Article:
+		before, after = "\n", "\n"
+	case atom.Li:
+		before, after = "\n- ", "\n"
+	case atom.Td, atom.Th:
+		after = " "
+	}
+	buf.WriteString(before)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeHTMLText(buf, c)
+	}
+	buf.WriteString(after)
+}
+
+// walkHTML calls a function on every node of a document, parents first. The
+// children of a node are collected before the function is called so that it
+// can remove the node.
+func walkHTML(n *html.Node, fn func(*html.Node)) {
+	children := []*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, c)
+	}
+	fn(n)
+	for _, c := range children {
+		walkHTML(c, fn)
+	}
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setHTMLAttr(n *html.Node, key string, value string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
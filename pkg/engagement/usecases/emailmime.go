@@ -0,0 +1,300 @@
+package usecases
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// maxMIMEDepth is how deeply multipart bodies can be nested
+const maxMIMEDepth = 10
+
+var (
+	// quoteHeaderPattern matches the line that email clients put above the
+	// email that is being replied to e.g
+	// `On Mon, 18 Oct 2021 at 10:00, Be.Well <reply@bewell.co.ke> wrote:`
+	quoteHeaderPattern = regexp.MustCompile(`(?i)^on\s.{1,300}\swrote:$`)
+
+	// quoteSeparatorPattern matches the lines that Outlook and other
+	// clients put above a quoted or forwarded email
+	quoteSeparatorPattern = regexp.MustCompile(`(?i)^(-{2,}\s*(original|forwarded) message\s*-{2,}|_{10,})$`)
+
+	// quoteFieldPattern matches the fields of the header block that
+	// Outlook puts above a quoted email
+	quoteFromPattern  = regexp.MustCompile(`(?i)^\*?from:\*?\s`)
+	quoteFieldPattern = regexp.MustCompile(`(?i)^\*?(sent|date|to|subject):\*?\s`)
+)
+
+// mimeWordDecoder decodes the encoded words of headers in any charset
+var mimeWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// parsedEmail is the content of a received email
+type parsedEmail struct {
+	messageID   string
+	from        string
+	recipients  []string
+	subject     string
+	text        string
+	html        string
+	attachments []email.Attachment
+}
+
+// parseMIMEEmail reads the headers, text, HTML and attachments of a raw
+// email. The first text/plain and text/html parts that are not attachments
+// are the email's body.
+func parseMIMEEmail(raw []byte) (*parsedEmail, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read email: %w", err)
+	}
+	addresses := &mail.AddressParser{WordDecoder: mimeWordDecoder}
+	from, err := addresses.Parse(message.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email sender: %w", err)
+	}
+
+	parsed := &parsedEmail{
+		messageID:  strings.Trim(strings.TrimSpace(message.Header.Get("Message-Id")), "<>"),
+		from:       normalizeEmail(from.Address),
+		subject:    decodeMIMEHeader(message.Header.Get("Subject")),
+		recipients: []string{},
+	}
+	for _, field := range []string{"To", "Cc"} {
+		if message.Header.Get(field) == "" {
+			continue
+		}
+		// the recipients only help to find the reply address so a header
+		// that can't be parsed is ignored
+		list, err := addresses.ParseList(message.Header.Get(field))
+		if err != nil {
+			continue
+		}
+		for _, address := range list {
+			parsed.recipients = append(parsed.recipients, address.Address)
+		}
+	}
+
+	header := textproto.MIMEHeader(message.Header)
+	if err := parsed.readPart(header, message.Body, 0); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// readPart reads a part of an email, and the parts of a multipart part
+func (p *parsedEmail) readPart(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return fmt.Errorf("the email's parts are nested more than %d deep", maxMIMEDepth)
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("unable to read email part: %w", err)
+			}
+			if err := p.readPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		disposition, dispositionParams = "", map[string]string{}
+	}
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeMIMEHeader(filename)
+
+	switch {
+	case disposition != "attachment" && mediaType == "text/plain" && p.text == "":
+		p.text = decodeCharset(params["charset"], content)
+
+	case disposition != "attachment" && mediaType == "text/html" && p.html == "":
+		p.html = decodeCharset(params["charset"], content)
+
+	// images that are shown in the body e.g the logo of a signature are
+	// not attachments
+	case disposition == "inline" && header.Get("Content-Id") != "":
+
+	case disposition == "attachment" || filename != "":
+		p.attachments = append(p.attachments, email.Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Data:        content,
+		})
+	}
+	return nil
+}
+
+// decodeTransferEncoding decodes the base64 or quoted-printable content of a
+// part
+func decodeTransferEncoding(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s email part: %w", encoding, err)
+	}
+	return content, nil
+}
+
+// decodeCharset converts text to UTF-8. Text in an unknown charset is kept as
+// it is.
+func decodeCharset(label string, content []byte) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || label == "utf-8" || label == "us-ascii" {
+		return string(content)
+	}
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(content))
+	if err != nil {
+		return string(content)
+	}
+	decoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return string(content)
+	}
+	return string(decoded)
+}
+
+// decodeMIMEHeader decodes the encoded words of a header e.g
+// `=?UTF-8?Q?R=C3=A9sultats?=`. Headers that can't be decoded are kept as
+// they are.
+func decodeMIMEHeader(value string) string {
+	decoded, err := mimeWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// replyText returns the text of a reply without the email that it quotes.
+// HTML is only used when the email has no plain text body.
+func (p *parsedEmail) replyText() (string, error) {
+	text := p.text
+	if strings.TrimSpace(text) == "" && p.html != "" {
+		document, err := stripHTMLQuotes(p.html)
+		if err != nil {
+			return "", err
+		}
+		text, err = htmlToText(document)
+		if err != nil {
+			return "", err
+		}
+	}
+	return stripQuotedReply(text), nil
+}
+
+// stripHTMLQuotes removes the quoted emails that Gmail and Apple Mail mark up
+// in the HTML of a reply
+func stripHTMLQuotes(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse HTML: %w", err)
+	}
+	walkHTML(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.Parent == nil {
+			return
+		}
+		classes := " " + htmlAttr(n, "class") + " "
+		quoted := strings.Contains(classes, " gmail_quote ") ||
+			(n.DataAtom == atom.Blockquote && htmlAttr(n, "type") == "cite")
+		if quoted {
+			n.Parent.RemoveChild(n)
+		}
+	})
+	buf := &bytes.Buffer{}
+	if err := html.Render(buf, doc); err != nil {
+		return "", fmt.Errorf("unable to render HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// stripQuotedReply removes the quoted history of a plain text reply.
+//
+// The text is cut at the first line that introduces a quoted email or a
+// signature, and lines that are quoted with `>` are dropped. If nothing is
+// left the text is returned as it was, since showing a quote is better than
+// losing a reply.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	kept := []string{}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		next := ""
+		if i+1 < len(lines) {
+			next = strings.TrimSpace(lines[i+1])
+		}
+
+		// Gmail wraps long quote headers onto a second line
+		if quoteHeaderPattern.MatchString(trimmed) || quoteHeaderPattern.MatchString(trimmed+" "+next) {
+			break
+		}
+		if quoteSeparatorPattern.MatchString(trimmed) || isQuoteFieldBlock(lines[i:]) {
+			break
+		}
+		if strings.TrimRight(line, " \t") == "--" {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+
+	stripped := strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(kept, "\n"), "\n\n"))
+	if stripped == "" {
+		return strings.TrimSpace(text)
+	}
+	return stripped
+}
+
+// isQuoteFieldBlock reports whether lines start with the `From:` line of a
+// header block that is followed by a `Sent:`, `Date:`, `To:` or `Subject:`
+// line
+func isQuoteFieldBlock(lines []string) bool {
+	if !quoteFromPattern.MatchString(strings.TrimSpace(lines[0])) {
+		return false
+	}
+	for i := 1; i < len(lines) && i <= 4; i++ {
+		if quoteFieldPattern.MatchString(strings.TrimSpace(lines[i])) {
+			return true
+		}
+	}
+	return false
+}
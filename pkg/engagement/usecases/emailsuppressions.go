@@ -0,0 +1,465 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/mail"
+)
+
+const (
+	// EmailBouncesPath, EmailComplaintsPath and EmailUnsubscribesPath are
+	// the paths of the webhooks that the email provider posts events to
+	EmailBouncesPath      = "/email/bounces"
+	EmailComplaintsPath   = "/email/complaints"
+	EmailUnsubscribesPath = "/email/unsubscribes"
+
+	// EmailUnsubscribePath is the path of the unsubscribe links in the
+	// List-Unsubscribe header of emails
+	EmailUnsubscribePath = "/email/unsubscribe"
+
+	// EmailWebhookMaxAge is how old the timestamp of a webhook signature
+	// can be. Older requests are rejected so that they can't be replayed.
+	EmailWebhookMaxAge = 15 * time.Minute
+
+	// the events and severity that the email provider reports
+	emailEventFailed       = "failed"
+	emailEventBounced      = "bounced"
+	emailEventComplained   = "complained"
+	emailEventUnsubscribed = "unsubscribed"
+	emailSeverityTemporary = "temporary"
+)
+
+// EmailSuppressionConfig holds the keys that email webhooks and unsubscribe
+// links are signed with
+type EmailSuppressionConfig struct {
+	// WebhookSigningKey is the email provider's webhook signing key
+	WebhookSigningKey []byte
+
+	// UnsubscribeSigningKey signs the unsubscribe links of emails so that
+	// an address can only be unsubscribed from a link that was sent to it
+	UnsubscribeSigningKey []byte
+
+	// BaseURL is the public URL of the service that unsubscribe links
+	// point to e.g https://engagement.bewell.co.ke
+	BaseURL string
+}
+
+// EmailSuppressionUsecases represent logic required to maintain the list of
+// email addresses that are not sent email
+type EmailSuppressionUsecases interface {
+	// VerifyEmailWebhookSignature checks the signature of a webhook request.
+	// It returns exceptions.ErrInvalidEmailWebhookSignature when the
+	// signature does not match or is too old.
+	VerifyEmailWebhookSignature(timestamp string, token string, signature string) error
+
+	// HandleEmailEvent suppresses the recipient of a bounce, complaint or
+	// unsubscribe event. It returns nil when the event does not suppress the
+	// recipient e.g a temporary delivery failure.
+	HandleEmailEvent(
+		ctx context.Context,
+		reason domain.EmailSuppressionReason,
+		event *domain.EmailEvent,
+	) (*domain.EmailSuppression, error)
+
+	// EmailUnsubscribeLink returns the signed unsubscribe link of an address
+	EmailUnsubscribeLink(address string) string
+
+	// VerifyEmailUnsubscribeSignature returns
+	// exceptions.ErrInvalidEmailUnsubscribeSignature when an unsubscribe
+	// link was not signed for the address
+	VerifyEmailUnsubscribeSignature(address string, signature string) error
+
+	// UnsubscribeEmail stops notification emails to an address
+	UnsubscribeEmail(ctx context.Context, address string) (*domain.EmailSuppression, error)
+
+	GetEmailSuppression(ctx context.Context, address string) (*domain.EmailSuppression, error)
+
+	ListEmailSuppressions(
+		ctx context.Context,
+		reason *domain.EmailSuppressionReason,
+	) ([]*domain.EmailSuppression, error)
+
+	// DeleteEmailSuppression allows an address to be sent email again e.g
+	// after a mailbox that bounced has been fixed
+	DeleteEmailSuppression(ctx context.Context, address string) (bool, error)
+}
+
+// EmailSuppressionImpl represents the email suppression usecase
+// implementation
+type EmailSuppressionImpl struct {
+	Repository repository.EmailSuppressionRepository
+	Config     EmailSuppressionConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewEmailSuppressions initializes an email suppression usecase
+func NewEmailSuppressions(
+	repository repository.EmailSuppressionRepository,
+	config EmailSuppressionConfig,
+) (*EmailSuppressionImpl, error) {
+	if len(config.WebhookSigningKey) == 0 {
+		return nil, fmt.Errorf("the email webhook signing key is required")
+	}
+	if len(config.UnsubscribeSigningKey) < 32 {
+		return nil, fmt.Errorf("the email unsubscribe signing key must be at least 32 bytes")
+	}
+	if _, err := url.ParseRequestURI(config.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid email unsubscribe base URL: %w", err)
+	}
+	return &EmailSuppressionImpl{
+		Repository: repository,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SignEmailWebhook returns the hex HMAC-SHA256 of a webhook's timestamp and
+// token, which is how the email provider signs its requests
+func SignEmailWebhook(key []byte, timestamp string, token string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignEmailUnsubscribe returns the hex HMAC-SHA256 of an address that signs
+// its unsubscribe link
+func SignEmailUnsubscribe(key []byte, address string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(EmailUnsubscribePath + ":" + address))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyEmailWebhookSignature checks the email provider's signature of a
+// webhook request
+func (e *EmailSuppressionImpl) VerifyEmailWebhookSignature(timestamp string, token string, signature string) error {
+	return verifyEmailWebhookSignature(e.Config.WebhookSigningKey, e.Clock(), timestamp, token, signature)
+}
+
+// verifyEmailWebhookSignature compares signatures in constant time and
+// rejects timestamps that are more than EmailWebhookMaxAge from now
+func verifyEmailWebhookSignature(
+	key []byte,
+	now time.Time,
+	timestamp string,
+	token string,
+	signature string,
+) error {
+	expected := SignEmailWebhook(key, timestamp, token)
+	if token == "" || !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return exceptions.ErrInvalidEmailWebhookSignature
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return exceptions.ErrInvalidEmailWebhookSignature
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > EmailWebhookMaxAge || age < -EmailWebhookMaxAge {
+		return fmt.Errorf("%w: the signature is %s old", exceptions.ErrInvalidEmailWebhookSignature, age.Round(time.Second))
+	}
+	return nil
+}
+
+// HandleEmailEvent checks that an event was posted to the webhook of its
+// kind and suppresses its recipient
+func (e *EmailSuppressionImpl) HandleEmailEvent(
+	ctx context.Context,
+	reason domain.EmailSuppressionReason,
+	event *domain.EmailEvent,
+) (*domain.EmailSuppression, error) {
+	expected := map[domain.EmailSuppressionReason][]string{
+		domain.EmailSuppressionReasonBounce:      {emailEventFailed, emailEventBounced},
+		domain.EmailSuppressionReasonComplaint:   {emailEventComplained},
+		domain.EmailSuppressionReasonUnsubscribe: {emailEventUnsubscribed},
+	}
+	events, ok := expected[reason]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid suppression reason", reason)
+	}
+	matched := false
+	for _, name := range events {
+		if event.Event == name {
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("a %q event is not a %s", event.Event, strings.ToLower(reason.String()))
+	}
+
+	// the provider retries temporary failures so they don't suppress the
+	// recipient
+	if event.Event == emailEventFailed && event.Severity == emailSeverityTemporary {
+		return nil, nil
+	}
+
+	return e.suppress(ctx, &domain.EmailSuppression{
+		Email:       event.Recipient,
+		Reason:      reason,
+		Description: event.Description,
+		MessageID:   strings.Trim(event.MessageID, "<>"),
+	})
+}
+
+// EmailUnsubscribeLink returns the link that unsubscribes an address
+func (e *EmailSuppressionImpl) EmailUnsubscribeLink(address string) string {
+	query := url.Values{}
+	query.Set("email", address)
+	query.Set("signature", SignEmailUnsubscribe(e.Config.UnsubscribeSigningKey, address))
+	return strings.TrimRight(e.Config.BaseURL, "/") + EmailUnsubscribePath + "?" + query.Encode()
+}
+
+// VerifyEmailUnsubscribeSignature compares signatures in constant time
+func (e *EmailSuppressionImpl) VerifyEmailUnsubscribeSignature(address string, signature string) error {
+	expected := SignEmailUnsubscribe(e.Config.UnsubscribeSigningKey, address)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return exceptions.ErrInvalidEmailUnsubscribeSignature
+	}
+	return nil
+}
+
+// UnsubscribeEmail suppresses notification emails to an address
+func (e *EmailSuppressionImpl) UnsubscribeEmail(ctx context.Context, address string) (*domain.EmailSuppression, error) {
+	return e.suppress(ctx, &domain.EmailSuppression{
+		Email:       address,
+		Reason:      domain.EmailSuppressionReasonUnsubscribe,
+		Description: "unsubscribed from a link",
+	})
+}
+
+// GetEmailSuppression returns the suppression of an address
+func (e *EmailSuppressionImpl) GetEmailSuppression(ctx context.Context, address string) (*domain.EmailSuppression, error) {
+	return e.Repository.GetEmailSuppression(ctx, normalizeEmail(address))
+}
+
+// ListEmailSuppressions returns the suppressions, optionally of one reason,
+// newest first
+func (e *EmailSuppressionImpl) ListEmailSuppressions(
+	ctx context.Context,
+	reason *domain.EmailSuppressionReason,
+) ([]*domain.EmailSuppression, error) {
+	return e.Repository.ListEmailSuppressions(ctx, reason)
+}
+
+// DeleteEmailSuppression removes an address from the suppression list
+func (e *EmailSuppressionImpl) DeleteEmailSuppression(ctx context.Context, address string) (bool, error) {
+	err := e.Repository.DeleteEmailSuppression(ctx, normalizeEmail(address))
+	if err != nil && !errors.Is(err, exceptions.ErrEmailSuppressionNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// suppress saves a suppression. An unsubscribe does not replace a bounce or
+// a complaint since those also stop transactional emails.
+func (e *EmailSuppressionImpl) suppress(
+	ctx context.Context,
+	suppression *domain.EmailSuppression,
+) (*domain.EmailSuppression, error) {
+	suppression.Email = normalizeEmail(suppression.Email)
+	if !govalidator.IsEmail(suppression.Email) {
+		return nil, fmt.Errorf("%s is not a valid email", suppression.Email)
+	}
+
+	existing, err := e.Repository.GetEmailSuppression(ctx, suppression.Email)
+	if err != nil && !errors.Is(err, exceptions.ErrEmailSuppressionNotFound) {
+		return nil, fmt.Errorf("unable to get the suppression of %s: %w", suppression.Email, err)
+	}
+	if existing != nil && existing.SuppressesTransactional() && !suppression.SuppressesTransactional() {
+		return existing, nil
+	}
+
+	suppression.CreatedAt = e.Clock()
+	if err := e.Repository.SaveEmailSuppression(ctx, suppression); err != nil {
+		return nil, fmt.Errorf("unable to save the suppression of %s: %w", suppression.Email, err)
+	}
+	return suppression, nil
+}
+
+// emailSuppressed reports whether an address is not sent email. Transactional
+// emails are only suppressed by bounces and complaints.
+func emailSuppressed(
+	ctx context.Context,
+	repository repository.EmailSuppressionRepository,
+	address string,
+	transactional bool,
+) (bool, error) {
+	suppression, err := repository.GetEmailSuppression(ctx, normalizeEmail(address))
+	if errors.Is(err, exceptions.ErrEmailSuppressionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get the suppression of %s: %w", address, err)
+	}
+	return !transactional || suppression.SuppressesTransactional(), nil
+}
+
+// unsuppressedEmails returns the addresses that are not suppressed
+func unsuppressedEmails(
+	ctx context.Context,
+	repository repository.EmailSuppressionRepository,
+	addresses []string,
+	transactional bool,
+) ([]string, error) {
+	allowed := []string{}
+	for _, address := range addresses {
+		suppressed, err := emailSuppressed(ctx, repository, address, transactional)
+		if err != nil {
+			return nil, err
+		}
+		if !suppressed {
+			allowed = append(allowed, address)
+		}
+	}
+	return allowed, nil
+}
+
+// EmailSuppressionFilter is an email service that does not send email to
+// suppressed addresses. Emails to a single recipient get a one-click
+// List-Unsubscribe header.
+type EmailSuppressionFilter struct {
+	Repository   repository.EmailSuppressionRepository
+	Email        email.ServiceEmail
+	Suppressions EmailSuppressionUsecases
+}
+
+// NewEmailSuppressionFilter wraps an email service so that suppressed
+// addresses are skipped
+func NewEmailSuppressionFilter(
+	repository repository.EmailSuppressionRepository,
+	email email.ServiceEmail,
+	suppressions EmailSuppressionUsecases,
+) *EmailSuppressionFilter {
+	return &EmailSuppressionFilter{Repository: repository, Email: email, Suppressions: suppressions}
+}
+
+// Send removes the suppressed recipients of a message and sends it to the
+// others. It returns an error wrapping exceptions.ErrEmailRecipientsSuppressed
+// when every `To` recipient is suppressed.
+func (f *EmailSuppressionFilter) Send(ctx context.Context, message *email.Message) (string, error) {
+	filtered := *message
+	var err error
+	if filtered.To, err = unsuppressedEmails(ctx, f.Repository, message.To, false); err != nil {
+		return "", err
+	}
+	if len(filtered.To) == 0 {
+		return "", fmt.Errorf("%w: %s", exceptions.ErrEmailRecipientsSuppressed, strings.Join(message.To, ", "))
+	}
+	if filtered.Cc, err = unsuppressedEmails(ctx, f.Repository, message.Cc, false); err != nil {
+		return "", err
+	}
+	if filtered.Bcc, err = unsuppressedEmails(ctx, f.Repository, message.Bcc, false); err != nil {
+		return "", err
+	}
+
+	// an unsubscribe link is only added when it can only be used by the
+	// address that it unsubscribes
+	if len(filtered.To) == 1 && len(filtered.Cc) == 0 && len(filtered.Bcc) == 0 {
+		headers := map[string]string{}
+		for name, value := range message.Headers {
+			headers[name] = value
+		}
+		headers["List-Unsubscribe"] = "<" + f.Suppressions.EmailUnsubscribeLink(normalizeEmail(filtered.To[0])) + ">"
+		headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+		filtered.Headers = headers
+	}
+	return f.Email.Send(ctx, &filtered)
+}
+
+// TransactionalMailFilter is a mail service that does not send email to
+// addresses that bounced or complained. It is used for emails that the
+// recipient asked for, e.g one time passwords, so unsubscribed addresses are
+// still sent them.
+type TransactionalMailFilter struct {
+	mail.ServiceMail
+	Repository repository.EmailSuppressionRepository
+}
+
+// NewTransactionalMailFilter wraps a mail service so that addresses that
+// bounced or complained are skipped
+func NewTransactionalMailFilter(
+	repository repository.EmailSuppressionRepository,
+	mail mail.ServiceMail,
+) *TransactionalMailFilter {
+	return &TransactionalMailFilter{ServiceMail: mail, Repository: repository}
+}
+
+func (f *TransactionalMailFilter) allowed(ctx context.Context, to []string) ([]string, error) {
+	allowed, err := unsuppressedEmails(ctx, f.Repository, to, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("%w: %s", exceptions.ErrEmailRecipientsSuppressed, strings.Join(to, ", "))
+	}
+	return allowed, nil
+}
+
+// SendEmail sends an email to the addresses that are not suppressed
+func (f *TransactionalMailFilter) SendEmail(
+	ctx context.Context,
+	subject, text string,
+	body *string,
+	to ...string,
+) (string, string, error) {
+	allowed, err := f.allowed(ctx, to)
+	if err != nil {
+		return "", "", err
+	}
+	return f.ServiceMail.SendEmail(ctx, subject, text, body, allowed...)
+}
+
+// SendMailgun sends an email to the addresses that are not suppressed
+func (f *TransactionalMailFilter) SendMailgun(
+	ctx context.Context,
+	subject, text string,
+	body *string,
+	to ...string,
+) (string, string, error) {
+	allowed, err := f.allowed(ctx, to)
+	if err != nil {
+		return "", "", err
+	}
+	return f.ServiceMail.SendMailgun(ctx, subject, text, body, allowed...)
+}
+
+// SendInBlue sends an email to the addresses that are not suppressed
+func (f *TransactionalMailFilter) SendInBlue(
+	ctx context.Context,
+	subject, text string,
+	to ...string,
+) (string, string, error) {
+	allowed, err := f.allowed(ctx, to)
+	if err != nil {
+		return "", "", err
+	}
+	return f.ServiceMail.SendInBlue(ctx, subject, text, allowed...)
+}
+
+// SimpleEmail sends an email to the addresses that are not suppressed
+func (f *TransactionalMailFilter) SimpleEmail(
+	ctx context.Context,
+	subject, text string,
+	body *string,
+	to ...string,
+) (string, error) {
+	allowed, err := f.allowed(ctx, to)
+	if err != nil {
+		return "", err
+	}
+	return f.ServiceMail.SimpleEmail(ctx, subject, text, body, allowed...)
+}
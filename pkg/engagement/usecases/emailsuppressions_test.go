@@ -0,0 +1,287 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testEmailWebhookKey     = []byte("key-0123456789abcdef")
+	testEmailUnsubscribeKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func newTestEmailSuppressions(f *testFixture) *usecases.EmailSuppressionImpl {
+	s, err := usecases.NewEmailSuppressions(f.repository, usecases.EmailSuppressionConfig{
+		WebhookSigningKey:     testEmailWebhookKey,
+		UnsubscribeSigningKey: testEmailUnsubscribeKey,
+		BaseURL:               "https://engagement.example.com/",
+	})
+	if err != nil {
+		f.t.Fatalf("unable to initialize email suppressions: %v", err)
+	}
+	s.Clock = f.clock.Now
+	return s
+}
+
+func TestNewEmailSuppressions(t *testing.T) {
+	_, err := usecases.NewEmailSuppressions(nil, usecases.EmailSuppressionConfig{
+		WebhookSigningKey:     testEmailWebhookKey,
+		UnsubscribeSigningKey: []byte("short"),
+		BaseURL:               "https://engagement.example.com",
+	})
+	assert.Error(t, err)
+
+	_, err = usecases.NewEmailSuppressions(nil, usecases.EmailSuppressionConfig{
+		UnsubscribeSigningKey: testEmailUnsubscribeKey,
+		BaseURL:               "https://engagement.example.com",
+	})
+	assert.Error(t, err)
+}
+
+func TestEmailSuppressionImpl_VerifyEmailWebhookSignature(t *testing.T) {
+	f := newTestFixture(t)
+	s := newTestEmailSuppressions(f)
+	now := strconv.FormatInt(f.clock.Now().Unix(), 10)
+	old := strconv.FormatInt(f.clock.Now().Add(-usecases.EmailWebhookMaxAge-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		token     string
+		signature string
+		wantErr   bool
+	}{
+		{
+			name:      "signed request",
+			timestamp: now,
+			token:     "token",
+			signature: usecases.SignEmailWebhook(testEmailWebhookKey, now, "token"),
+		},
+		{
+			name:      "signature of another token",
+			timestamp: now,
+			token:     "token",
+			signature: usecases.SignEmailWebhook(testEmailWebhookKey, now, "other"),
+			wantErr:   true,
+		},
+		{
+			name:      "signed with another key",
+			timestamp: now,
+			token:     "token",
+			signature: usecases.SignEmailWebhook([]byte("another key"), now, "token"),
+			wantErr:   true,
+		},
+		{
+			name:      "old signature",
+			timestamp: old,
+			token:     "token",
+			signature: usecases.SignEmailWebhook(testEmailWebhookKey, old, "token"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.VerifyEmailWebhookSignature(tt.timestamp, tt.token, tt.signature)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailWebhookSignature))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestEmailSuppressionImpl_HandleEmailEvent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestEmailSuppressions(newTestFixture(t))
+
+	tests := []struct {
+		name       string
+		reason     domain.EmailSuppressionReason
+		event      domain.EmailEvent
+		wantReason domain.EmailSuppressionReason
+		wantNil    bool
+		wantErr    bool
+	}{
+		{
+			name:   "permanent failures are bounces",
+			reason: domain.EmailSuppressionReasonBounce,
+			event: domain.EmailEvent{
+				Event:       "failed",
+				Severity:    "permanent",
+				Recipient:   "Jane@Example.com",
+				MessageID:   "<20211018.1@mg.bewell.co.ke>",
+				Description: "No such user",
+			},
+			wantReason: domain.EmailSuppressionReasonBounce,
+		},
+		{
+			name:   "temporary failures are retried",
+			reason: domain.EmailSuppressionReasonBounce,
+			event: domain.EmailEvent{
+				Event:     "failed",
+				Severity:  "temporary",
+				Recipient: "john@example.com",
+			},
+			wantNil: true,
+		},
+		{
+			name:       "complaints",
+			reason:     domain.EmailSuppressionReasonComplaint,
+			event:      domain.EmailEvent{Event: "complained", Recipient: "john@example.com"},
+			wantReason: domain.EmailSuppressionReasonComplaint,
+		},
+		{
+			name:       "unsubscribes don't replace bounces",
+			reason:     domain.EmailSuppressionReasonUnsubscribe,
+			event:      domain.EmailEvent{Event: "unsubscribed", Recipient: "jane@example.com"},
+			wantReason: domain.EmailSuppressionReasonBounce,
+		},
+		{
+			name:    "events posted to the wrong webhook",
+			reason:  domain.EmailSuppressionReasonComplaint,
+			event:   domain.EmailEvent{Event: "unsubscribed", Recipient: "jane@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid recipient",
+			reason:  domain.EmailSuppressionReasonComplaint,
+			event:   domain.EmailEvent{Event: "complained", Recipient: "not an email"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := tt.event
+			suppression, err := s.HandleEmailEvent(ctx, tt.reason, &event)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, suppression)
+				return
+			}
+			assert.Equal(t, tt.wantReason, suppression.Reason)
+		})
+	}
+
+	suppression, err := s.GetEmailSuppression(ctx, "JANE@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "20211018.1@mg.bewell.co.ke", suppression.MessageID)
+	assert.Equal(t, "No such user", suppression.Description)
+
+	_, err = s.GetEmailSuppression(ctx, "unknown@example.com")
+	assert.True(t, errors.Is(err, exceptions.ErrEmailSuppressionNotFound))
+
+	reason := domain.EmailSuppressionReasonComplaint
+	complaints, err := s.ListEmailSuppressions(ctx, &reason)
+	assert.NoError(t, err)
+	assert.Len(t, complaints, 1)
+
+	deleted, err := s.DeleteEmailSuppression(ctx, "jane@example.com")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	all, err := s.ListEmailSuppressions(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestEmailSuppressionImpl_UnsubscribeEmail(t *testing.T) {
+	ctx := context.Background()
+	s := newTestEmailSuppressions(newTestFixture(t))
+
+	link, err := url.Parse(s.EmailUnsubscribeLink("jane@example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, "engagement.example.com", link.Host)
+	assert.Equal(t, usecases.EmailUnsubscribePath, link.Path)
+	assert.Equal(t, "jane@example.com", link.Query().Get("email"))
+
+	signature := link.Query().Get("signature")
+	assert.NoError(t, s.VerifyEmailUnsubscribeSignature("jane@example.com", signature))
+	err = s.VerifyEmailUnsubscribeSignature("john@example.com", signature)
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailUnsubscribeSignature))
+
+	suppression, err := s.UnsubscribeEmail(ctx, "jane@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.EmailSuppressionReasonUnsubscribe, suppression.Reason)
+	assert.False(t, suppression.SuppressesTransactional())
+}
+
+func TestEmailSuppressionFilter_Send(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	s := newTestEmailSuppressions(f)
+	_, err := s.UnsubscribeEmail(ctx, "unsubscribed@example.com")
+	assert.NoError(t, err)
+
+	filter := usecases.NewEmailSuppressionFilter(f.repository, f.emailService(), s)
+
+	_, err = filter.Send(ctx, &email.Message{
+		To:      []string{"jane@example.com", "unsubscribed@example.com"},
+		Cc:      []string{"unsubscribed@example.com"},
+		Subject: "The clinic is closed",
+		Text:    "The clinic is closed today",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, f.emails, 1)
+	assert.Equal(t, []string{"jane@example.com"}, f.emails[0].To)
+	assert.Empty(t, f.emails[0].Cc)
+
+	// a single recipient gets a one-click unsubscribe link
+	assert.Equal(t, "<"+s.EmailUnsubscribeLink("jane@example.com")+">", f.emails[0].Headers["List-Unsubscribe"])
+	assert.Equal(t, "List-Unsubscribe=One-Click", f.emails[0].Headers["List-Unsubscribe-Post"])
+
+	_, err = filter.Send(ctx, &email.Message{
+		To:      []string{"jane@example.com", "john@example.com"},
+		Subject: "The clinic is closed",
+		Text:    "The clinic is closed today",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, f.emails, 2)
+	assert.Empty(t, f.emails[1].Headers)
+
+	_, err = filter.Send(ctx, &email.Message{
+		To:      []string{"unsubscribed@example.com"},
+		Cc:      []string{"jane@example.com"},
+		Subject: "The clinic is closed",
+		Text:    "The clinic is closed today",
+	})
+	assert.True(t, errors.Is(err, exceptions.ErrEmailRecipientsSuppressed))
+	assert.Len(t, f.emails, 2)
+}
+
+func TestTransactionalMailFilter_SendEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	s := newTestEmailSuppressions(f)
+	_, err := s.UnsubscribeEmail(ctx, "unsubscribed@example.com")
+	assert.NoError(t, err)
+	_, err = s.HandleEmailEvent(ctx, domain.EmailSuppressionReasonBounce, &domain.EmailEvent{
+		Event:     "failed",
+		Severity:  "permanent",
+		Recipient: "bounced@example.com",
+	})
+	assert.NoError(t, err)
+
+	filter := usecases.NewTransactionalMailFilter(f.repository, f.mailService())
+
+	// addresses that unsubscribed are still sent transactional emails
+	_, _, err = filter.SendEmail(ctx, "Your code", "123456", nil, "unsubscribed@example.com", "bounced@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"unsubscribed@example.com"}, f.mail.to)
+
+	_, _, err = filter.SendEmail(ctx, "Your code", "123456", nil, "Bounced@example.com")
+	assert.True(t, errors.Is(err, exceptions.ErrEmailRecipientsSuppressed))
+}
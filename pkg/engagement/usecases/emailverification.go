@@ -0,0 +1,259 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/mail"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// EmailVerificationPath is the path of the unauthenticated endpoint that
+	// consumes email verification links
+	EmailVerificationPath = "/verify_email"
+
+	// EmailVerificationTemplateName is the notification template that the
+	// verification email is rendered from. It is rendered with the `link`
+	// and `expiryMinutes` variables.
+	EmailVerificationTemplateName = "email_verification_link"
+
+	// DefaultEmailVerificationExpiry is how long a link can be used when no
+	// expiry is configured
+	DefaultEmailVerificationExpiry = 30 * time.Minute
+
+	// the email that is sent when the template has not been created
+	defaultEmailVerificationSubject = "Verify your Be.Well email address"
+	defaultEmailVerificationBody    = `<p>Please confirm that this is your email address by opening the link below.</p>
+<p><a href="{{.link}}">Verify my email address</a></p>
+<p>The link can be used once and expires in {{.expiryMinutes}} minutes. If you did not ask to verify this address you can ignore this email.</p>`
+)
+
+// EmailVerificationConfig controls how email verification links are signed
+// and where they point to
+type EmailVerificationConfig struct {
+	// SigningKey signs the links so that forged links are rejected
+	// before they are looked up
+	SigningKey []byte
+
+	// BaseURL is the public URL of this service that links point to
+	BaseURL string
+
+	Expiry time.Duration
+}
+
+// EmailVerificationUsecases represent logic required to verify email addresses
+// with signed, single use links
+type EmailVerificationUsecases interface {
+	// RequestEmailVerificationLink emails a verification link to an email
+	// address. Requests are throttled per address like OTP generation.
+	RequestEmailVerificationLink(ctx context.Context, email string) (*domain.EmailVerificationLink, error)
+
+	// GetEmailVerificationLink returns a link with its current status
+	GetEmailVerificationLink(ctx context.Context, id string) (*domain.EmailVerificationLink, error)
+
+	// RevokeEmailVerificationLinks revokes the outstanding links of an
+	// email address and returns how many were revoked
+	RevokeEmailVerificationLinks(ctx context.Context, email string) (int, error)
+
+	// VerifyEmailLink consumes the token of a link. It returns an error
+	// wrapping exceptions.ErrInvalidEmailVerificationLink when the link
+	// can't be used.
+	VerifyEmailLink(ctx context.Context, token string) (*domain.EmailVerificationLink, error)
+}
+
+// EmailVerificationImpl represents the email verification usecase implementation
+type EmailVerificationImpl struct {
+	Repository repository.EmailVerificationRepository
+	Templates  TemplateUsecases
+	Mail       mail.ServiceMail
+	Config     EmailVerificationConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewEmailVerification initializes an email verification usecase
+func NewEmailVerification(
+	repository repository.EmailVerificationRepository,
+	templates TemplateUsecases,
+	mail mail.ServiceMail,
+	config EmailVerificationConfig,
+) (*EmailVerificationImpl, error) {
+	if len(config.SigningKey) < 32 {
+		return nil, fmt.Errorf("the email verification signing key must be at least 32 bytes")
+	}
+	if _, err := url.ParseRequestURI(config.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid email verification base URL: %w", err)
+	}
+	if config.Expiry == 0 {
+		config.Expiry = DefaultEmailVerificationExpiry
+	}
+	if config.Expiry < 0 {
+		return nil, fmt.Errorf("the email verification expiry can't be negative")
+	}
+
+	return &EmailVerificationImpl{
+		Repository: repository,
+		Templates:  templates,
+		Mail:       mail,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// RequestEmailVerificationLink saves a link and emails it
+func (e *EmailVerificationImpl) RequestEmailVerificationLink(
+	ctx context.Context,
+	email string,
+) (*domain.EmailVerificationLink, error) {
+	address := normalizeEmail(email)
+	if !govalidator.IsEmail(address) {
+		return nil, fmt.Errorf("%s is not a valid email", email)
+	}
+
+	now := e.Clock()
+	err := countOTPAttempt(ctx, e.Repository, otpAttemptsKey(emailLinkAttempts, address), otpGeneratePolicy, now)
+	if err != nil {
+		return nil, err
+	}
+	link, err := e.Repository.SaveEmailVerificationLink(ctx, &domain.EmailVerificationLink{
+		ID:        ksuid.New().String(),
+		Email:     address,
+		CreatedAt: now,
+		ExpiresAt: now.Add(e.Config.Expiry),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save email verification link: %w", err)
+	}
+
+	subject, body, err := e.renderEmail(ctx, e.linkURL(link.ID))
+	if err != nil {
+		return nil, err
+	}
+	_, _, err = e.Mail.SendEmail(ctx, subject, body, nil, address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send email verification link: %w", err)
+	}
+
+	link.Status = link.StatusAt(now)
+	return link, nil
+}
+
+// GetEmailVerificationLink retrieves a link with its status
+func (e *EmailVerificationImpl) GetEmailVerificationLink(
+	ctx context.Context,
+	id string,
+) (*domain.EmailVerificationLink, error) {
+	link, err := e.Repository.GetEmailVerificationLink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	link.Status = link.StatusAt(e.Clock())
+	return link, nil
+}
+
+// RevokeEmailVerificationLinks revokes the valid links of an email address
+func (e *EmailVerificationImpl) RevokeEmailVerificationLinks(ctx context.Context, email string) (int, error) {
+	return e.Repository.RevokeEmailVerificationLinks(ctx, normalizeEmail(email), e.Clock())
+}
+
+// VerifyEmailLink checks the signature of a token and consumes its link
+func (e *EmailVerificationImpl) VerifyEmailLink(
+	ctx context.Context,
+	token string,
+) (*domain.EmailVerificationLink, error) {
+	id, ok := e.verifyToken(token)
+	if !ok {
+		return nil, fmt.Errorf("%w: bad signature", exceptions.ErrInvalidEmailVerificationLink)
+	}
+
+	now := e.Clock()
+	link, consumed, err := e.Repository.ConsumeEmailVerificationLink(ctx, id, now)
+	if errors.Is(err, exceptions.ErrEmailVerificationLinkNotFound) {
+		return nil, fmt.Errorf("%w: not found", exceptions.ErrInvalidEmailVerificationLink)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify email link: %w", err)
+	}
+	if !consumed {
+		return nil, fmt.Errorf(
+			"%w: %s",
+			exceptions.ErrInvalidEmailVerificationLink,
+			strings.ToLower(link.StatusAt(now).String()),
+		)
+	}
+
+	resetOTPAttempts(ctx, e.Repository, otpAttemptsKey(emailLinkAttempts, link.Email))
+	link.Status = link.StatusAt(now)
+	return link, nil
+}
+
+// renderEmail renders the verification email from its template, or from the
+// built in email when the template has not been created
+func (e *EmailVerificationImpl) renderEmail(ctx context.Context, link string) (string, string, error) {
+	variables := map[string]interface{}{
+		"link":          link,
+		"expiryMinutes": int(e.Config.Expiry / time.Minute),
+	}
+
+	rendered, err := e.Templates.RenderTemplate(ctx, EmailVerificationTemplateName, nil, variables)
+	if errors.Is(err, exceptions.ErrTemplateNotFound) {
+		body, err := renderText(defaultEmailVerificationBody, variables)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to render email verification email: %w", err)
+		}
+		return defaultEmailVerificationSubject, body, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("unable to render email verification email: %w", err)
+	}
+
+	subject := defaultEmailVerificationSubject
+	if rendered.Subject != nil {
+		subject = *rendered.Subject
+	}
+	return subject, rendered.Body, nil
+}
+
+// linkURL returns the link that consumes a token
+func (e *EmailVerificationImpl) linkURL(id string) string {
+	query := url.Values{}
+	query.Set("token", e.token(id))
+	return strings.TrimRight(e.Config.BaseURL, "/") + EmailVerificationPath + "?" + query.Encode()
+}
+
+// token signs a link ID. The token is the ID and its signature.
+func (e *EmailVerificationImpl) token(id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(e.signature(id))
+}
+
+// verifyToken returns the link ID of a token with a valid signature
+func (e *EmailVerificationImpl) verifyToken(token string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	return parts[0], hmac.Equal(signature, e.signature(parts[0]))
+}
+
+func (e *EmailVerificationImpl) signature(id string) []byte {
+	mac := hmac.New(sha256.New, e.Config.SigningKey)
+	mac.Write([]byte("email-verification:" + id))
+	return mac.Sum(nil)
+}
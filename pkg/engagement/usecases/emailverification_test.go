@@ -0,0 +1,252 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEmailVerificationKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestEmailVerification(f *testFixture) (*usecases.EmailVerificationImpl, usecases.TemplateUsecases) {
+	templates := usecases.NewTemplates(f.repository)
+	verification, err := usecases.NewEmailVerification(
+		f.repository,
+		templates,
+		f.mailService(),
+		usecases.EmailVerificationConfig{
+			SigningKey: testEmailVerificationKey,
+			BaseURL:    "https://engagement.example.com/",
+		},
+	)
+	assert.Nil(f.t, err)
+	verification.Clock = f.clock.Now
+	return verification, templates
+}
+
+// emailedToken extracts the token of the link in an email
+func emailedToken(t *testing.T, body string) string {
+	match := regexp.MustCompile(`href="([^"]+)"`).FindStringSubmatch(body)
+	if !assert.Len(t, match, 2) {
+		return ""
+	}
+	link, err := url.Parse(strings.ReplaceAll(match[1], "&amp;", "&"))
+	assert.Nil(t, err)
+	assert.Equal(t, "engagement.example.com", link.Host)
+	assert.Equal(t, usecases.EmailVerificationPath, link.Path)
+	return link.Query().Get("token")
+}
+
+func TestNewEmailVerification(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  usecases.EmailVerificationConfig
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			config: usecases.EmailVerificationConfig{
+				SigningKey: testEmailVerificationKey,
+				BaseURL:    "https://engagement.example.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "short signing key",
+			config: usecases.EmailVerificationConfig{
+				SigningKey: []byte("secret"),
+				BaseURL:    "https://engagement.example.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid base URL",
+			config: usecases.EmailVerificationConfig{
+				SigningKey: testEmailVerificationKey,
+				BaseURL:    "engagement",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative expiry",
+			config: usecases.EmailVerificationConfig{
+				SigningKey: testEmailVerificationKey,
+				BaseURL:    "https://engagement.example.com",
+				Expiry:     -time.Minute,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verification, err := usecases.NewEmailVerification(
+				memory.NewRepository(),
+				nil,
+				nil,
+				tt.config,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewEmailVerification() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, usecases.DefaultEmailVerificationExpiry, verification.Config.Expiry)
+			}
+		})
+	}
+}
+
+func TestEmailVerificationImpl_VerifyEmailLink(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	verification, _ := newTestEmailVerification(f)
+
+	_, err := verification.RequestEmailVerificationLink(ctx, "not an email")
+	assert.NotNil(t, err)
+
+	link, err := verification.RequestEmailVerificationLink(ctx, " Jane@Example.com ")
+	assert.Nil(t, err)
+	assert.Equal(t, "jane@example.com", link.Email)
+	assert.Equal(t, domain.EmailVerificationStatusPending, link.Status)
+	assert.Equal(t, []string{"jane@example.com"}, f.mail.to)
+	assert.Contains(t, f.mail.bodies[0], "30 minutes")
+
+	token := emailedToken(t, f.mail.bodies[0])
+	assert.True(t, strings.HasPrefix(token, link.ID+"."))
+
+	// tokens that are not signed with the key are rejected
+	for _, forged := range []string{"", link.ID, link.ID + ".c2lnbmF0dXJl", token + "x"} {
+		_, err = verification.VerifyEmailLink(ctx, forged)
+		assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailVerificationLink), forged)
+	}
+
+	verified, err := verification.VerifyEmailLink(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.EmailVerificationStatusVerified, verified.Status)
+
+	// links can only be used once
+	_, err = verification.VerifyEmailLink(ctx, token)
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailVerificationLink))
+
+	status, err := verification.GetEmailVerificationLink(ctx, link.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.EmailVerificationStatusVerified, status.Status)
+
+	// expired links are rejected
+	expiring, err := verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+	f.clock.Advance(usecases.DefaultEmailVerificationExpiry + time.Second)
+	_, err = verification.VerifyEmailLink(ctx, emailedToken(t, f.mail.bodies[1]))
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailVerificationLink))
+	status, err = verification.GetEmailVerificationLink(ctx, expiring.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.EmailVerificationStatusExpired, status.Status)
+}
+
+func TestEmailVerificationImpl_RevokeEmailVerificationLinks(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	verification, _ := newTestEmailVerification(f)
+
+	first, err := verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+	f.clock.Advance(time.Minute)
+	_, err = verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+	_, err = verification.RequestEmailVerificationLink(ctx, "john@example.com")
+	assert.Nil(t, err)
+
+	revoked, err := verification.RevokeEmailVerificationLinks(ctx, "JANE@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, revoked)
+
+	_, err = verification.VerifyEmailLink(ctx, emailedToken(t, f.mail.bodies[0]))
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidEmailVerificationLink))
+	status, err := verification.GetEmailVerificationLink(ctx, first.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.EmailVerificationStatusRevoked, status.Status)
+
+	// other addresses keep their links
+	_, err = verification.VerifyEmailLink(ctx, emailedToken(t, f.mail.bodies[2]))
+	assert.Nil(t, err)
+
+	revoked, err = verification.RevokeEmailVerificationLinks(ctx, "jane@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, revoked)
+}
+
+func TestEmailVerificationImpl_RequestEmailVerificationLink_Template(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	verification, templates := newTestEmailVerification(f)
+
+	subject := "Confirm your email"
+	_, err := templates.CreateTemplate(ctx, dto.NotificationTemplateInput{
+		Name:            usecases.EmailVerificationTemplateName,
+		Channel:         feedlib.ChannelEmail,
+		DefaultLanguage: enumutils.LanguageEn,
+		Variables:       []string{"link", "expiryMinutes"},
+		Variants: []*dto.TemplateVariantInput{
+			{
+				Language: enumutils.LanguageEn,
+				Subject:  &subject,
+				Body:     `<a href="{{.link}}">Confirm</a> within {{.expiryMinutes}} minutes`,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	_, err = verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{subject}, f.mail.subjects)
+	assert.Contains(t, f.mail.bodies[0], "within 30 minutes")
+
+	_, err = verification.VerifyEmailLink(ctx, emailedToken(t, f.mail.bodies[0]))
+	assert.Nil(t, err)
+}
+
+func TestEmailVerificationImpl_RequestEmailVerificationLink_Throttle(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	verification, _ := newTestEmailVerification(f)
+
+	_, err := verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+
+	// a second request has to wait for the backoff
+	_, err = verification.RequestEmailVerificationLink(ctx, "Jane@example.com")
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+	_, err = verification.RequestEmailVerificationLink(ctx, "john@example.com")
+	assert.Nil(t, err)
+
+	// the address is locked after too many requests
+	for i := 0; i < 4; i++ {
+		f.clock.Advance(10 * time.Minute)
+		_, err = verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+		assert.Nil(t, err)
+	}
+	f.clock.Advance(10 * time.Minute)
+	_, err = verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+	assert.Len(t, f.mail.to, 6)
+
+	// verifying the address lifts the limit
+	f.clock.Advance(time.Minute)
+	_, err = verification.VerifyEmailLink(ctx, emailedToken(t, f.mail.bodies[len(f.mail.bodies)-1]))
+	assert.Nil(t, err)
+	_, err = verification.RequestEmailVerificationLink(ctx, "jane@example.com")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,307 @@
+package usecases_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	emailMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email/mock"
+	smsMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms/mock"
+	whatsAppMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/whatsapp/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	mailMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/mail/mock"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/pubsubtools"
+	"github.com/stretchr/testify/assert"
+)
+
+// testClock is a clock that only moves when it is advanced
+type testClock struct {
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Now()}
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// sentSMS records the messages given to the fake SMS service
+type sentSMS struct {
+	to        []string
+	messages  []string
+	senderIDs []enumutils.SenderID
+
+	// requests are the recipients of each request
+	requests [][]string
+
+	// statuses that the fake provider reports instead of Success. A number
+	// with an empty status is left out of the response.
+	statuses map[string]string
+
+	// err is returned instead of a response e.g when the provider can't be
+	// reached
+	err error
+
+	// sending is called before each request is answered e.g to change what
+	// is being sent while it is sending
+	sending func()
+}
+
+// messagesTo returns the messages that were sent to a phone number
+func (s *sentSMS) messagesTo(phone string) []string {
+	messages := []string{}
+	for i, to := range s.to {
+		if to == phone {
+			messages = append(messages, s.messages[i])
+		}
+	}
+	return messages
+}
+
+// sentEmails records the emails given to the fake library mail service
+type sentEmails struct {
+	subjects []string
+	bodies   []string
+	to       []string
+}
+
+// sentWhatsApp is a message that was given to the fake WhatsApp service
+type sentWhatsApp struct {
+	to         string
+	templateID string
+	parameters []string
+	text       string
+	mediaURL   string
+}
+
+// fakeFeed records the messages that are posted to feed items
+type fakeFeed struct {
+	posted []*feedlib.Message
+	items  []string
+}
+
+func (f *fakeFeed) PostMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	message *feedlib.Message,
+) (*feedlib.Message, error) {
+	f.posted = append(f.posted, message)
+	f.items = append(f.items, uid+"/"+flavour.String()+"/"+itemID)
+	return message, nil
+}
+
+// testFixture is the memory repository, clock and fake providers that the
+// usecases under test are built on. The fakes record what they are asked to
+// send.
+type testFixture struct {
+	t          *testing.T
+	repository *memory.Repository
+	clock      *testClock
+
+	sms      *sentSMS
+	emails   []*email.Message
+	mail     *sentEmails
+	whatsApp []sentWhatsApp
+	feed     *fakeFeed
+}
+
+func newTestFixture(t *testing.T) *testFixture {
+	return &testFixture{
+		t:          t,
+		repository: memory.NewRepository(),
+		clock:      newTestClock(),
+		sms:        &sentSMS{statuses: map[string]string{}},
+		mail:       &sentEmails{},
+		feed:       &fakeFeed{},
+	}
+}
+
+// subjectsTo returns the subjects of the emails sent to an address
+func (f *testFixture) subjectsTo(address string) []string {
+	subjects := []string{}
+	for _, message := range f.emails {
+		for _, to := range message.To {
+			if to == address {
+				subjects = append(subjects, message.Subject)
+			}
+		}
+	}
+	return subjects
+}
+
+// invitesTo returns the iCalendar invites attached to the emails sent to an
+// address
+func (f *testFixture) invitesTo(address string) []string {
+	invites := []string{}
+	for _, message := range f.emails {
+		for _, to := range message.To {
+			if to != address {
+				continue
+			}
+			for _, attachment := range message.Attachments {
+				invites = append(invites, string(attachment.Data))
+			}
+		}
+	}
+	return invites
+}
+
+// smsService is a fake SMS provider that accepts every message
+func (f *testFixture) smsService() *smsMock.FakeServiceSMS {
+	sendToMany := func(
+		ctx context.Context,
+		message string,
+		to []string,
+		from enumutils.SenderID,
+	) (*libDto.SendMessageResponse, error) {
+		f.sms.requests = append(f.sms.requests, to)
+		if f.sms.sending != nil {
+			f.sms.sending()
+		}
+		if f.sms.err != nil {
+			return nil, f.sms.err
+		}
+		recipients := []libDto.Recipient{}
+		for _, number := range to {
+			f.sms.to = append(f.sms.to, number)
+			f.sms.messages = append(f.sms.messages, message)
+			f.sms.senderIDs = append(f.sms.senderIDs, from)
+
+			status, ok := f.sms.statuses[number]
+			if !ok {
+				status = "Success"
+			}
+			if status == "" {
+				continue
+			}
+			recipients = append(recipients, libDto.Recipient{
+				Number:    number,
+				Cost:      "KES 0.8000",
+				Status:    status,
+				MessageID: "ATXid_" + number,
+			})
+		}
+		return &libDto.SendMessageResponse{
+			SMSMessageData: &libDto.SMS{Recipients: recipients},
+		}, nil
+	}
+	return &smsMock.FakeServiceSMS{
+		SendFn: func(
+			ctx context.Context,
+			to, message string,
+			from enumutils.SenderID,
+		) (*libDto.SendMessageResponse, error) {
+			return sendToMany(ctx, message, []string{to}, from)
+		},
+		SendToManyFn: sendToMany,
+	}
+}
+
+// emailService is a fake email provider. The ID of each message is its
+// position among the sent messages.
+func (f *testFixture) emailService() *emailMock.FakeServiceEmail {
+	return &emailMock.FakeServiceEmail{
+		SendFn: func(ctx context.Context, message *email.Message) (string, error) {
+			f.emails = append(f.emails, message)
+			return fmt.Sprintf("20211018.%d@mg.bewell.co.ke", len(f.emails)), nil
+		},
+	}
+}
+
+// mailService is a fake of the library's mail service
+func (f *testFixture) mailService() *mailMock.FakeServiceMail {
+	return &mailMock.FakeServiceMail{
+		SendEmailFn: func(
+			ctx context.Context,
+			subject, text string,
+			body *string,
+			to ...string,
+		) (string, string, error) {
+			f.mail.subjects = append(f.mail.subjects, subject)
+			f.mail.bodies = append(f.mail.bodies, text)
+			f.mail.to = append(f.mail.to, to...)
+			return "", "", nil
+		},
+	}
+}
+
+// whatsAppService is a fake WhatsApp provider. The ID of each message is
+// its position among the sent messages.
+func (f *testFixture) whatsAppService() *whatsAppMock.FakeServiceWhatsApp {
+	return &whatsAppMock.FakeServiceWhatsApp{
+		SendTemplateFn: func(ctx context.Context, to string, templateID string, parameters []string) (string, error) {
+			f.whatsApp = append(f.whatsApp, sentWhatsApp{to: to, templateID: templateID, parameters: parameters})
+			return fmt.Sprintf("SM%d", len(f.whatsApp)), nil
+		},
+		SendTextFn: func(ctx context.Context, to string, text string) (string, error) {
+			f.whatsApp = append(f.whatsApp, sentWhatsApp{to: to, text: text})
+			return fmt.Sprintf("SM%d", len(f.whatsApp)), nil
+		},
+		SendMediaFn: func(ctx context.Context, to string, mediaURL string, caption string) (string, error) {
+			f.whatsApp = append(f.whatsApp, sentWhatsApp{to: to, mediaURL: mediaURL, text: caption})
+			return fmt.Sprintf("SM%d", len(f.whatsApp)), nil
+		},
+	}
+}
+
+// smsCosts prices SMS with the rates and budgets in the fixture's repository.
+// There is no default budget.
+func (f *testFixture) smsCosts() *usecases.SMSCostImpl {
+	costs, err := usecases.NewSMSCosts(f.repository, usecases.SMSCostConfig{})
+	assert.Nil(f.t, err)
+	return costs
+}
+
+// whatsAppUsecases sends through the fake WhatsApp provider with the
+// `HXverification` and `HXitem` templates
+func (f *testFixture) whatsAppUsecases() *usecases.WhatsAppImpl {
+	w, err := usecases.NewWhatsApp(f.whatsAppService(), f.repository, usecases.WhatsAppConfig{
+		VerificationTemplateID: "HXverification",
+		ItemTemplateID:         "HXitem",
+	})
+	assert.Nil(f.t, err)
+	w.Clock = f.clock.Now
+	return w
+}
+
+// pubSubPayload is the pub sub message that publishes a feed element e.g an
+// item or a nudge
+func (f *testFixture) pubSubPayload(element interface{}) *pubsubtools.PubSubPayload {
+	payload, err := json.Marshal(element)
+	assert.Nil(f.t, err)
+	data, err := json.Marshal(libDto.NotificationEnvelope{
+		Flavour: feedlib.FlavourConsumer,
+		Payload: payload,
+	})
+	assert.Nil(f.t, err)
+	return &pubsubtools.PubSubPayload{
+		Message: pubsubtools.PubSubMessage{Data: data},
+	}
+}
+
+// openWhatsAppSession starts the session of a phone number as if it had
+// just messaged us
+func (f *testFixture) openWhatsAppSession(phone string) {
+	err := f.repository.SaveWhatsAppSession(context.Background(), &domain.WhatsAppSession{
+		Phone:         phone,
+		LastMessageAt: f.clock.Now(),
+		ExpiresAt:     f.clock.Now().Add(usecases.WhatsAppSessionWindow),
+	})
+	assert.Nil(f.t, err)
+}
@@ -0,0 +1,98 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+)
+
+// RecipientGroupUsecases represent logic required to manage the named
+// recipient lists that scheduled notifications can target
+type RecipientGroupUsecases interface {
+	// SaveRecipientGroup creates a group or replaces the members of an
+	// existing group with the same name
+	SaveRecipientGroup(
+		ctx context.Context,
+		input dto.RecipientGroupInput,
+	) (*domain.RecipientGroup, error)
+
+	ListRecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error)
+
+	DeleteRecipientGroup(ctx context.Context, name string) (bool, error)
+}
+
+// RecipientGroupImpl represents the recipient group usecase implementation
+type RecipientGroupImpl struct {
+	Repository repository.RecipientGroupRepository
+}
+
+// NewRecipientGroups initializes a recipient group usecase
+func NewRecipientGroups(
+	repository repository.RecipientGroupRepository,
+) *RecipientGroupImpl {
+	return &RecipientGroupImpl{
+		Repository: repository,
+	}
+}
+
+// SaveRecipientGroup creates or replaces a recipient group
+func (g *RecipientGroupImpl) SaveRecipientGroup(
+	ctx context.Context,
+	input dto.RecipientGroupInput,
+) (*domain.RecipientGroup, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("a recipient group name is required")
+	}
+
+	members := []string{}
+	seen := map[string]bool{}
+	for _, member := range input.Members {
+		member = strings.TrimSpace(member)
+		if member == "" || seen[member] {
+			continue
+		}
+		seen[member] = true
+		members = append(members, member)
+	}
+
+	now := time.Now()
+	group := &domain.RecipientGroup{
+		Name:        name,
+		Description: input.Description,
+		Members:     members,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	existing, err := g.Repository.GetRecipientGroup(ctx, name)
+	switch {
+	case err == nil:
+		group.CreatedAt = existing.CreatedAt
+	case !errors.Is(err, exceptions.ErrRecipientGroupNotFound):
+		return nil, fmt.Errorf("unable to check for an existing recipient group: %w", err)
+	}
+	return g.Repository.SaveRecipientGroup(ctx, group)
+}
+
+// ListRecipientGroups lists the recipient groups ordered by name
+func (g *RecipientGroupImpl) ListRecipientGroups(ctx context.Context) ([]*domain.RecipientGroup, error) {
+	return g.Repository.ListRecipientGroups(ctx)
+}
+
+// DeleteRecipientGroup removes a recipient group.
+//
+// Scheduled notifications that target the group skip it from then on.
+func (g *RecipientGroupImpl) DeleteRecipientGroup(ctx context.Context, name string) (bool, error) {
+	if err := g.Repository.DeleteRecipientGroup(ctx, name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,267 @@
+package usecases
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+// The iTIP methods of iCalendar documents. Published documents are
+// downloaded into a calendar, while requests and cancellations are invites
+// that update the attendee's copy of an event.
+const (
+	ICSMethodPublish = "PUBLISH"
+	ICSMethodRequest = "REQUEST"
+	ICSMethodCancel  = "CANCEL"
+
+	icsProductID     = "-//Be.Well//Engagement Service//EN"
+	icsUTCLayout     = "20060102T150405Z"
+	icsLocalLayout   = "20060102T150405"
+	icsMaxLineOctets = 75
+
+	// icsTimeZoneWindow is how far before and after an event the offset
+	// changes of its time zone are described
+	icsTimeZoneWindow = 366 * 24 * time.Hour
+)
+
+// icsTextEscaper escapes the characters of TEXT values
+var icsTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// CalendarEventICS renders an event as an RFC 5545 iCalendar document with
+// one event.
+//
+// Times are in the event's time zone, which is described in the document,
+// and reminders are display alarms. The organizer, an email address, is
+// required by requests and cancellations.
+func CalendarEventICS(
+	event *gcal.Event,
+	method string,
+	organizer string,
+	stamp time.Time,
+) ([]byte, error) {
+	start, end, err := calendarEventInterval(event)
+	if err != nil {
+		return nil, err
+	}
+	if event.ICalUID == "" {
+		return nil, fmt.Errorf("the event has no iCalendar UID")
+	}
+	if organizer == "" && method != ICSMethodPublish {
+		return nil, fmt.Errorf("a %s needs an organizer", strings.ToLower(method))
+	}
+
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", icsProductID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", method)
+
+	loc := start.Location()
+	allDay := event.Start.DateTime == ""
+	if loc != time.UTC && !allDay {
+		w.timeZone(loc, start.Add(-icsTimeZoneWindow), end.Add(icsTimeZoneWindow))
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", event.ICalUID)
+	w.line("DTSTAMP", stamp.UTC().Format(icsUTCLayout))
+	switch {
+	case allDay:
+		w.line("DTSTART;VALUE=DATE", start.Format("20060102"))
+		w.line("DTEND;VALUE=DATE", end.Format("20060102"))
+	case loc == time.UTC:
+		w.line("DTSTART", start.Format(icsUTCLayout))
+		w.line("DTEND", end.Format(icsUTCLayout))
+	default:
+		w.line("DTSTART;TZID="+loc.String(), start.Format(icsLocalLayout))
+		w.line("DTEND;TZID="+loc.String(), end.Format(icsLocalLayout))
+	}
+	w.line("SEQUENCE", fmt.Sprint(event.Sequence))
+	if event.Status == calendarEventCancelled || method == ICSMethodCancel {
+		w.line("STATUS", "CANCELLED")
+	} else {
+		w.line("STATUS", "CONFIRMED")
+	}
+	w.line("SUMMARY", icsText(event.Summary))
+	if event.Description != "" {
+		w.line("DESCRIPTION", icsText(event.Description))
+	}
+	if event.Location != "" {
+		w.line("LOCATION", icsText(event.Location))
+	}
+	if created, err := time.Parse(time.RFC3339, event.Created); err == nil {
+		w.line("CREATED", created.UTC().Format(icsUTCLayout))
+	}
+	if updated, err := time.Parse(time.RFC3339, event.Updated); err == nil {
+		w.line("LAST-MODIFIED", updated.UTC().Format(icsUTCLayout))
+	}
+	if organizer != "" {
+		w.line("ORGANIZER", "mailto:"+organizer)
+	}
+	for _, attendee := range event.Attendees {
+		name := "ATTENDEE"
+		if attendee.DisplayName != "" {
+			name += ";CN=" + icsParam(attendee.DisplayName)
+		}
+		if attendee.Optional {
+			name += ";ROLE=OPT-PARTICIPANT"
+		} else {
+			name += ";ROLE=REQ-PARTICIPANT"
+		}
+		name += ";PARTSTAT=" + icsParticipationStatus(attendee.ResponseStatus)
+		if method == ICSMethodRequest {
+			name += ";RSVP=TRUE"
+		}
+		w.line(name, "mailto:"+attendee.Email)
+	}
+	if event.Reminders != nil && event.Status != calendarEventCancelled && method != ICSMethodCancel {
+		for _, reminder := range event.Reminders.Overrides {
+			w.line("BEGIN", "VALARM")
+			w.line("ACTION", "DISPLAY")
+			w.line("DESCRIPTION", icsText(event.Summary))
+			w.line("TRIGGER", fmt.Sprintf("-PT%dM", reminder.Minutes))
+			w.line("END", "VALARM")
+		}
+	}
+	w.line("END", "VEVENT")
+	w.line("END", "VCALENDAR")
+	return w.Bytes(), nil
+}
+
+// icsWriter writes content lines, folding those longer than 75 octets
+type icsWriter struct {
+	bytes.Buffer
+}
+
+func (w *icsWriter) line(name string, value string) {
+	line := name + ":" + value
+	limit := icsMaxLineOctets
+	for len(line) > limit {
+		// folds must not split a UTF-8 sequence
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+
+		// continuation lines start with a space, which counts to their
+		// length
+		limit = icsMaxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// timeZone describes a time zone with its offset changes in an interval.
+// Each change is its own observance. Those with the larger offset are
+// daylight saving time.
+func (w *icsWriter) timeZone(loc *time.Location, from, to time.Time) {
+	type observance struct {
+		onset      time.Time
+		name       string
+		offsetFrom int
+		offsetTo   int
+	}
+
+	name, offset := from.In(loc).Zone()
+	observances := []observance{{onset: from, name: name, offsetFrom: offset, offsetTo: offset}}
+	for t := from; t.Before(to); {
+		next := t.Add(24 * time.Hour)
+		if _, o := next.In(loc).Zone(); o != offset {
+			// the change is within the day; find its second
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(loc).Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			name, o := hi.In(loc).Zone()
+			observances = append(observances, observance{onset: hi, name: name, offsetFrom: offset, offsetTo: o})
+			offset = o
+			t = hi
+			continue
+		}
+		t = next
+	}
+
+	offsets := []int{}
+	for _, o := range observances {
+		offsets = append(offsets, o.offsetTo)
+	}
+	sort.Ints(offsets)
+
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", loc.String())
+	for _, o := range observances {
+		kind := "STANDARD"
+		if o.offsetTo > offsets[0] {
+			kind = "DAYLIGHT"
+		}
+		w.line("BEGIN", kind)
+		// the onset is in the local time before it
+		w.line("DTSTART", o.onset.Add(time.Duration(o.offsetFrom)*time.Second).UTC().Format(icsLocalLayout))
+		w.line("TZOFFSETFROM", icsOffset(o.offsetFrom))
+		w.line("TZOFFSETTO", icsOffset(o.offsetTo))
+		if o.name != "" {
+			w.line("TZNAME", icsText(o.name))
+		}
+		w.line("END", kind)
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+func icsText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
+
+// icsParam quotes a parameter value. Quoted values can't contain quotes.
+func icsParam(value string) string {
+	value = strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ").Replace(value)
+	return `"` + value + `"`
+}
+
+// icsParticipationStatus converts the response status of a Google Calendar
+// API attendee
+func icsParticipationStatus(status string) string {
+	switch status {
+	case "accepted":
+		return "ACCEPTED"
+	case "declined":
+		return "DECLINED"
+	case "tentative":
+		return "TENTATIVE"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
@@ -0,0 +1,140 @@
+package usecases_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/stretchr/testify/assert"
+	gcal "google.golang.org/api/calendar/v3"
+)
+
+func testICSEvent(timeZone string, start, end string) *gcal.Event {
+	return &gcal.Event{
+		ICalUID:     "abc@bewell.co.ke",
+		Summary:     "Consultation; follow up, review",
+		Description: "Bring your test results\nand prescription",
+		Location:    "Be.Well Clinic, Nairobi",
+		Start:       &gcal.EventDateTime{DateTime: start, TimeZone: timeZone},
+		End:         &gcal.EventDateTime{DateTime: end, TimeZone: timeZone},
+		Sequence:    2,
+		Status:      "confirmed",
+		Attendees: []*gcal.EventAttendee{
+			{Email: "jane@example.com", DisplayName: `Jane "JD" Doe`, ResponseStatus: "needsAction"},
+			{Email: "john@example.com", Optional: true, ResponseStatus: "accepted"},
+		},
+		Reminders: &gcal.EventReminders{
+			Overrides: []*gcal.EventReminder{{Method: "popup", Minutes: 60}},
+		},
+	}
+}
+
+// icsLines unfolds an iCalendar document into its content lines
+func icsLines(t *testing.T, document []byte) []string {
+	text := string(document)
+	assert.True(t, strings.HasSuffix(text, "\r\n"))
+	for _, line := range strings.Split(text, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestCalendarEventICS(t *testing.T) {
+	stamp := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := testICSEvent("Africa/Nairobi", "2021-06-01T09:00:00+03:00", "2021-06-01T09:30:00+03:00")
+
+	document, err := usecases.CalendarEventICS(event, usecases.ICSMethodRequest, "appointments@example.com", stamp)
+	assert.Nil(t, err)
+	lines := icsLines(t, document)
+
+	assert.Equal(t, "BEGIN:VCALENDAR", lines[0])
+	assert.Equal(t, "END:VCALENDAR", lines[len(lines)-1])
+	for _, line := range []string{
+		"VERSION:2.0",
+		"METHOD:REQUEST",
+		"BEGIN:VTIMEZONE",
+		"TZID:Africa/Nairobi",
+		"TZOFFSETFROM:+0300",
+		"TZOFFSETTO:+0300",
+		"TZNAME:EAT",
+		"UID:abc@bewell.co.ke",
+		"DTSTAMP:20210501T120000Z",
+		"DTSTART;TZID=Africa/Nairobi:20210601T090000",
+		"DTEND;TZID=Africa/Nairobi:20210601T093000",
+		"SEQUENCE:2",
+		"STATUS:CONFIRMED",
+		`SUMMARY:Consultation\; follow up\, review`,
+		`DESCRIPTION:Bring your test results\nand prescription`,
+		`LOCATION:Be.Well Clinic\, Nairobi`,
+		"ORGANIZER:mailto:appointments@example.com",
+		`ATTENDEE;CN="Jane 'JD' Doe";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:jane@example.com`,
+		"ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=TRUE:mailto:john@example.com",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT60M",
+	} {
+		assert.Contains(t, lines, line)
+	}
+	assert.Equal(t, 1, strings.Count(string(document), "BEGIN:STANDARD"))
+	assert.NotContains(t, string(document), "BEGIN:DAYLIGHT")
+
+	_, err = usecases.CalendarEventICS(event, usecases.ICSMethodCancel, "", stamp)
+	assert.NotNil(t, err)
+}
+
+func TestCalendarEventICS_Cancel(t *testing.T) {
+	stamp := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := testICSEvent("", "2021-06-01T06:00:00Z", "2021-06-01T06:30:00Z")
+
+	document, err := usecases.CalendarEventICS(event, usecases.ICSMethodCancel, "appointments@example.com", stamp)
+	assert.Nil(t, err)
+	lines := icsLines(t, document)
+	assert.Contains(t, lines, "METHOD:CANCEL")
+	assert.Contains(t, lines, "STATUS:CANCELLED")
+	assert.Contains(t, lines, "DTSTART:20210601T060000Z")
+	assert.Contains(t, lines, "ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=ACCEPTED:mailto:john@example.com")
+	assert.NotContains(t, lines, "BEGIN:VTIMEZONE")
+	assert.NotContains(t, lines, "BEGIN:VALARM")
+}
+
+func TestCalendarEventICS_DaylightSaving(t *testing.T) {
+	stamp := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := testICSEvent("Europe/London", "2021-06-01T09:00:00+01:00", "2021-06-01T09:30:00+01:00")
+
+	document, err := usecases.CalendarEventICS(event, usecases.ICSMethodPublish, "", stamp)
+	assert.Nil(t, err)
+	lines := icsLines(t, document)
+	assert.Contains(t, lines, "DTSTART;TZID=Europe/London:20210601T090000")
+	assert.NotContains(t, strings.Join(lines, "\n"), "ORGANIZER")
+
+	// British summer time starts at 01:00 GMT on the last Sunday of March
+	// and ends at 01:00 GMT, 02:00 BST, on the last Sunday of October
+	for _, line := range []string{
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20210328T010000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0100",
+		"TZNAME:BST",
+		"BEGIN:STANDARD",
+		"DTSTART:20211031T020000",
+		"TZNAME:GMT",
+	} {
+		assert.Contains(t, lines, line)
+	}
+}
+
+func TestCalendarEventICS_Folding(t *testing.T) {
+	stamp := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := testICSEvent("Africa/Nairobi", "2021-06-01T09:00:00+03:00", "2021-06-01T09:30:00+03:00")
+	event.Description = strings.Repeat("Karibu sana, ūsijali ", 20)
+
+	document, err := usecases.CalendarEventICS(event, usecases.ICSMethodPublish, "", stamp)
+	assert.Nil(t, err)
+	lines := icsLines(t, document)
+	assert.Contains(t, lines, "DESCRIPTION:"+strings.ReplaceAll(event.Description, ",", `\,`))
+	for _, line := range strings.Split(string(document), "\r\n") {
+		assert.True(t, utf8.ValidString(line))
+	}
+}
@@ -0,0 +1,234 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	defaultInboxPageSize = 20
+	maxInboxPageSize     = 100
+)
+
+// InboxUsecases represent logic required to keep and page through the
+// notifications that have been sent to a user
+type InboxUsecases interface {
+	// RecordNotification adds a notification to a user's inbox
+	RecordNotification(
+		ctx context.Context,
+		notification *domain.InboxNotification,
+	) (*domain.InboxNotification, error)
+
+	// GetInbox returns a page of a user's notifications, newest first.
+	// Only forward pagination i.e `first` and `after` is supported.
+	GetInbox(
+		ctx context.Context,
+		uid string,
+		channel *feedlib.Channel,
+		read *bool,
+		pagination *firebasetools.PaginationInput,
+	) (*dto.InboxConnection, error)
+
+	MarkNotificationsRead(ctx context.Context, uid string, ids []string) (int, error)
+
+	MarkNotificationsUnread(ctx context.Context, uid string, ids []string) (int, error)
+
+	MarkAllNotificationsRead(ctx context.Context, uid string) (int, error)
+}
+
+// InboxImpl represents the notification inbox usecase implementation
+type InboxImpl struct {
+	Repository repository.InboxRepository
+}
+
+// NewInbox initializes a notification inbox usecase
+func NewInbox(
+	repository repository.InboxRepository,
+) *InboxImpl {
+	return &InboxImpl{
+		Repository: repository,
+	}
+}
+
+// RecordNotification adds a notification to a user's inbox
+func (i *InboxImpl) RecordNotification(
+	ctx context.Context,
+	notification *domain.InboxNotification,
+) (*domain.InboxNotification, error) {
+	if notification == nil || notification.UID == "" {
+		return nil, fmt.Errorf("an inbox notification needs a user ID")
+	}
+	if !notification.Channel.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid channel", notification.Channel)
+	}
+
+	n := *notification
+	n.ID = ksuid.New().String()
+	n.Read = false
+	n.ReadAt = nil
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if n.Data == nil {
+		n.Data = map[string]interface{}{}
+	}
+	return i.Repository.SaveInboxNotification(ctx, &n)
+}
+
+// GetInbox returns a page of a user's notifications, newest first
+func (i *InboxImpl) GetInbox(
+	ctx context.Context,
+	uid string,
+	channel *feedlib.Channel,
+	read *bool,
+	pagination *firebasetools.PaginationInput,
+) (*dto.InboxConnection, error) {
+	if channel != nil && !channel.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid channel", channel)
+	}
+
+	limit := defaultInboxPageSize
+	var after *domain.InboxCursor
+	if pagination != nil {
+		if pagination.Last != 0 || pagination.Before != "" {
+			return nil, fmt.Errorf("the inbox only supports forward pagination")
+		}
+		if pagination.First < 0 {
+			return nil, fmt.Errorf("first must not be negative")
+		}
+		if pagination.First > 0 {
+			limit = pagination.First
+		}
+		if pagination.After != "" {
+			cursor, err := decodeInboxCursor(pagination.After)
+			if err != nil {
+				return nil, err
+			}
+			after = cursor
+		}
+	}
+	if limit > maxInboxPageSize {
+		limit = maxInboxPageSize
+	}
+
+	// one extra notification is fetched to tell whether there is a next page
+	notifications, err := i.Repository.ListInboxNotifications(
+		ctx,
+		uid,
+		domain.InboxFilter{Channel: channel, Read: read},
+		after,
+		limit+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list inbox notifications: %w", err)
+	}
+	hasNextPage := len(notifications) > limit
+	if hasNextPage {
+		notifications = notifications[:limit]
+	}
+
+	unread, err := i.Repository.CountUnreadInboxNotifications(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to count unread inbox notifications: %w", err)
+	}
+
+	connection := &dto.InboxConnection{
+		Edges: []*dto.InboxEdge{},
+		PageInfo: &firebasetools.PageInfo{
+			HasNextPage:     hasNextPage,
+			HasPreviousPage: after != nil,
+		},
+		UnreadCount: unread,
+	}
+	for _, notification := range notifications {
+		connection.Edges = append(connection.Edges, &dto.InboxEdge{
+			Cursor: encodeInboxCursor(notification),
+			Node:   notification,
+		})
+	}
+	if len(connection.Edges) > 0 {
+		start := connection.Edges[0].Cursor
+		end := connection.Edges[len(connection.Edges)-1].Cursor
+		connection.PageInfo.StartCursor = &start
+		connection.PageInfo.EndCursor = &end
+	}
+	return connection, nil
+}
+
+// MarkNotificationsRead marks notifications in a user's inbox as read
+func (i *InboxImpl) MarkNotificationsRead(ctx context.Context, uid string, ids []string) (int, error) {
+	return i.setRead(ctx, uid, ids, true)
+}
+
+// MarkNotificationsUnread marks notifications in a user's inbox as unread
+func (i *InboxImpl) MarkNotificationsUnread(ctx context.Context, uid string, ids []string) (int, error) {
+	return i.setRead(ctx, uid, ids, false)
+}
+
+// MarkAllNotificationsRead marks every notification in a user's inbox as read
+func (i *InboxImpl) MarkAllNotificationsRead(ctx context.Context, uid string) (int, error) {
+	return i.Repository.MarkAllInboxNotificationsRead(ctx, uid, time.Now())
+}
+
+func (i *InboxImpl) setRead(ctx context.Context, uid string, ids []string, read bool) (int, error) {
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("at least one notification ID is required")
+	}
+	if len(ids) > maxInboxPageSize {
+		return 0, fmt.Errorf("at most %d notifications can be updated at once", maxInboxPageSize)
+	}
+	return i.Repository.SetInboxNotificationsRead(ctx, uid, ids, read, time.Now())
+}
+
+// encodeInboxCursor returns an opaque cursor for a notification's position
+// in the inbox
+func encodeInboxCursor(notification *domain.InboxNotification) string {
+	raw := fmt.Sprintf("%d:%s", notification.CreatedAt.UnixNano(), notification.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeInboxCursor(cursor string) (*domain.InboxCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, exceptions.ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, exceptions.ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, exceptions.ErrInvalidCursor
+	}
+	return &domain.InboxCursor{
+		CreatedAt: time.Unix(0, nanos),
+		ID:        parts[1],
+	}, nil
+}
+
+// recordDispatch adds a notification that was sent to a user over SMS, email
+// or WhatsApp to their inbox.
+//
+// Failing to record a notification does not fail the send; the notification
+// has already been sent.
+func recordDispatch(ctx context.Context, inbox InboxUsecases, notification *domain.InboxNotification) {
+	if inbox == nil {
+		return
+	}
+	if _, err := inbox.RecordNotification(ctx, notification); err != nil {
+		log.Printf("unable to record %s notification in %s's inbox: %v", notification.Channel, notification.UID, err)
+	}
+}
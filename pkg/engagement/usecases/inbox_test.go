@@ -0,0 +1,302 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedInbox records `count` notifications for a user, one minute apart,
+// alternating between the FCM and SMS channels
+func seedInbox(t *testing.T, inbox *usecases.InboxImpl, uid string, count int) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < count; i++ {
+		channel := feedlib.ChannelFcm
+		if i%2 == 1 {
+			channel = feedlib.ChannelSms
+		}
+		_, err := inbox.RecordNotification(ctx, &domain.InboxNotification{
+			UID:       uid,
+			Channel:   channel,
+			Title:     fmt.Sprintf("Notification %d", i),
+			Body:      "Test notification",
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		assert.Nil(t, err)
+	}
+}
+
+func TestInboxImpl_GetInbox(t *testing.T) {
+	ctx := context.Background()
+	inbox := usecases.NewInbox(memory.NewRepository())
+	seedInbox(t, inbox, "user-1", 5)
+	seedInbox(t, inbox, "user-2", 2)
+
+	fcm := feedlib.ChannelFcm
+	invalid := feedlib.Channel("PIGEON")
+
+	tests := []struct {
+		name       string
+		channel    *feedlib.Channel
+		pagination *firebasetools.PaginationInput
+		wantCount  int
+		wantNext   bool
+		wantErr    bool
+	}{
+		{
+			name:      "default page",
+			wantCount: 5,
+		},
+		{
+			name:       "first page",
+			pagination: &firebasetools.PaginationInput{First: 2},
+			wantCount:  2,
+			wantNext:   true,
+		},
+		{
+			name:      "channel filter",
+			channel:   &fcm,
+			wantCount: 3,
+		},
+		{
+			name:    "invalid channel",
+			channel: &invalid,
+			wantErr: true,
+		},
+		{
+			name:       "backward pagination",
+			pagination: &firebasetools.PaginationInput{Last: 2},
+			wantErr:    true,
+		},
+		{
+			name:       "invalid cursor",
+			pagination: &firebasetools.PaginationInput{After: "not a cursor"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inbox.GetInbox(ctx, "user-1", tt.channel, nil, tt.pagination)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetInbox() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Len(t, got.Edges, tt.wantCount)
+				assert.Equal(t, tt.wantNext, got.PageInfo.HasNextPage)
+				assert.Equal(t, 5, got.UnreadCount)
+			}
+		})
+	}
+}
+
+func TestInboxImpl_Pagination(t *testing.T) {
+	ctx := context.Background()
+	inbox := usecases.NewInbox(memory.NewRepository())
+	seedInbox(t, inbox, "user-1", 5)
+
+	titles := []string{}
+	pagination := &firebasetools.PaginationInput{First: 2}
+	for {
+		page, err := inbox.GetInbox(ctx, "user-1", nil, nil, pagination)
+		assert.Nil(t, err)
+		for _, edge := range page.Edges {
+			titles = append(titles, edge.Node.Title)
+		}
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		pagination = &firebasetools.PaginationInput{First: 2, After: *page.PageInfo.EndCursor}
+	}
+
+	assert.Equal(t, []string{
+		"Notification 4",
+		"Notification 3",
+		"Notification 2",
+		"Notification 1",
+		"Notification 0",
+	}, titles)
+}
+
+func TestInboxImpl_MarkNotificationsRead(t *testing.T) {
+	ctx := context.Background()
+	inbox := usecases.NewInbox(memory.NewRepository())
+	seedInbox(t, inbox, "user-1", 3)
+	seedInbox(t, inbox, "user-2", 1)
+
+	page, err := inbox.GetInbox(ctx, "user-1", nil, nil, nil)
+	assert.Nil(t, err)
+	ids := []string{page.Edges[0].Node.ID, page.Edges[1].Node.ID}
+
+	marked, err := inbox.MarkNotificationsRead(ctx, "user-1", ids)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, marked)
+
+	marked, err = inbox.MarkNotificationsRead(ctx, "user-1", ids)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, marked)
+
+	read := true
+	page, err = inbox.GetInbox(ctx, "user-1", nil, &read, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Edges, 2)
+	assert.NotNil(t, page.Edges[0].Node.ReadAt)
+	assert.Equal(t, 1, page.UnreadCount)
+
+	marked, err = inbox.MarkNotificationsUnread(ctx, "user-1", ids[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, 1, marked)
+
+	other, err := inbox.GetInbox(ctx, "user-2", nil, nil, nil)
+	assert.Nil(t, err)
+	_, err = inbox.MarkNotificationsRead(ctx, "user-1", []string{other.Edges[0].Node.ID})
+	assert.True(t, errors.Is(err, exceptions.ErrInboxNotificationNotFound))
+
+	_, err = inbox.MarkNotificationsRead(ctx, "user-1", nil)
+	assert.NotNil(t, err)
+
+	marked, err = inbox.MarkAllNotificationsRead(ctx, "user-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, marked)
+
+	page, err = inbox.GetInbox(ctx, "user-1", nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, page.UnreadCount)
+
+	other, err = inbox.GetInbox(ctx, "user-2", nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, other.UnreadCount)
+}
+
+func TestDeviceImpl_SendPushNotificationRecordsInbox(t *testing.T) {
+	ctx := context.Background()
+	repository := memory.NewRepository()
+	inbox := usecases.NewInbox(repository)
+	devices := usecases.NewDevices(repository, newFakeFCM(), inbox)
+
+	for uid, tokens := range map[string][]string{
+		"user-1": {"phone-1", "tablet-1"},
+		"user-2": {"phone-2"},
+	} {
+		for _, token := range tokens {
+			_, err := devices.RegisterDevice(ctx, uid, dto.DeviceInput{
+				DeviceID: token,
+				Token:    token,
+				Platform: domain.DevicePlatformAndroid,
+			})
+			assert.Nil(t, err)
+		}
+	}
+
+	sent, err := devices.SendPushNotification(
+		ctx,
+		[]string{"phone-1", "tablet-1", "phone-2"},
+		map[string]string{"appointmentID": "123"},
+		&firebasetools.FirebaseSimpleNotificationInput{
+			Title: "Appointment",
+			Body:  "Your appointment has been confirmed",
+		},
+		nil,
+		nil,
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.True(t, sent)
+
+	for _, uid := range []string{"user-1", "user-2"} {
+		page, err := inbox.GetInbox(ctx, uid, nil, nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Edges, 1)
+		assert.Equal(t, "Appointment", page.Edges[0].Node.Title)
+		assert.Equal(t, "123", page.Edges[0].Node.Data["appointmentID"])
+	}
+}
+
+func TestInboxImpl_RecordsDispatches(t *testing.T) {
+	ctx := context.Background()
+
+	s := newTestSMSCallbacks(newTestFixture(t))
+	_, err := s.StartSMSConversation(ctx, "nurse", dto.SMSConversationInput{
+		Phone:   "0711223344",
+		UID:     "patient",
+		Flavour: feedlib.FlavourConsumer,
+		ItemID:  "item",
+		Message: "How are you feeling today?",
+		Sender:  enumutils.SenderIDBewell,
+	})
+	assert.Nil(t, err)
+
+	e, _ := newTestEmailConversations(newTestFixture(t))
+	startTestEmailConversation(t, e)
+
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+	_, err = tw.conversations.StartWhatsAppConversation(ctx, "clinician", dto.WhatsAppConversationInput{
+		Phone:      "0722000000",
+		UID:        "patient",
+		Flavour:    feedlib.FlavourConsumer,
+		ItemID:     "item-1",
+		TemplateID: "HXresults",
+		Parameters: []string{"Jane"},
+	})
+	assert.Nil(t, err)
+	_, err = tw.conversations.SendWhatsAppNudge(ctx, "clinician", dto.WhatsAppNudgeInput{
+		Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer,
+		NudgeID: "nudge-1", TemplateID: "HXnudge",
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		inbox   usecases.InboxUsecases
+		channel feedlib.Channel
+		want    []string
+	}{
+		{
+			name:    "sms",
+			inbox:   s.Inbox,
+			channel: feedlib.ChannelSms,
+			want:    []string{"How are you feeling today?"},
+		},
+		{
+			name:    "email",
+			inbox:   e.Inbox,
+			channel: feedlib.ChannelEmail,
+			want:    []string{"Your results are ready. Reply with any questions."},
+		},
+		{
+			name:    "whatsapp",
+			inbox:   tw.conversations.Inbox,
+			channel: feedlib.ChannelWhatsapp,
+			want:    []string{"Verify your email to get your lab results by email", "Jane"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.inbox.GetInbox(ctx, "patient", &tt.channel, nil, nil)
+			assert.Nil(t, err)
+			bodies := []string{}
+			for _, edge := range page.Edges {
+				bodies = append(bodies, edge.Node.Body)
+			}
+			assert.ElementsMatch(t, tt.want, bodies)
+
+			other := feedlib.ChannelFcm
+			page, err = tt.inbox.GetInbox(ctx, "patient", &other, nil, nil)
+			assert.Nil(t, err)
+			assert.Empty(t, page.Edges)
+		})
+	}
+}
@@ -1,28 +1,625 @@
 package usecases
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/email"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
 	libRepository "github.com/savannahghi/engagementcore/pkg/engagement/repository"
 	libNotification "github.com/savannahghi/engagementcore/pkg/engagement/usecases/feed"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/pubsubtools"
+)
+
+// the senders that identify item and nudge notifications in the FCM data
+// payload. They match the senders used by the feed library.
+const (
+	itemPublishSender  = "ITEM_PUBLISHED"
+	nudgePublishSender = "NUDGE_PUBLISHED"
+	nudgeResolveSender = "NUDGE_RESOLVED"
+)
+
+const (
+	// ItemPublishTemplateName is the notification template that the push
+	// notifications and WhatsApp messages of published items are rendered
+	// from. It is rendered with the item's `tagline` and `summary`. Its
+	// subject is the title and its body the text of the notification.
+	ItemPublishTemplateName = "item_publish"
+
+	// NudgePublishTemplateName is the notification template that the push
+	// notifications of published nudges are rendered from. It is rendered
+	// with the nudge's `title`, `text` and `message`, the nudge's publish
+	// message.
+	NudgePublishTemplateName = "nudge_publish"
+
+	// NudgeResolveTemplateName is the notification template that the push
+	// notifications of resolved nudges are rendered from. It is rendered
+	// with the nudge's `title`, `text` and `message`, the nudge's resolve
+	// message.
+	NudgeResolveTemplateName = "nudge_resolve"
 )
 
+// PhoneNumbers looks up the phone numbers of users
+type PhoneNumbers interface {
+	// GetPhoneNumbers returns the phone numbers of each user, primary
+	// number first
+	GetPhoneNumbers(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error)
+}
+
 // NotificationUsecases represent logic required to make notification
 type NotificationUsecases interface {
 	libNotification.NotificationUsecases
+
+	// SendTemplatedMessage renders a named template and sends it over
+	// the template's channel
+	SendTemplatedMessage(
+		ctx context.Context,
+		input dto.TemplatedMessageInput,
+	) error
+
+	// SendDueDigests sends the digests of rate limited notifications that
+	// are due at `now` and returns the number that were sent
+	SendDueDigests(ctx context.Context, now time.Time) (int, error)
+
+	// SendSMS sends a message to a phone number from a sender ID. The
+	// message is refused if it is over the flavour's SMS budget, or the
+	// default budget when no flavour is given.
+	SendSMS(
+		ctx context.Context,
+		to string,
+		message string,
+		sender enumutils.SenderID,
+		flavour *feedlib.Flavour,
+	) (*libDto.SendMessageResponse, error)
+
+	// SendSMSToMany sends a message to phone numbers from a sender ID. The
+	// message is refused if it is over the flavour's SMS budget, or the
+	// default budget when no flavour is given.
+	SendSMSToMany(
+		ctx context.Context,
+		message string,
+		to []string,
+		sender enumutils.SenderID,
+		flavour *feedlib.Flavour,
+	) (*libDto.SendMessageResponse, error)
 }
 
 // NotificationImpl represents the notification usecase implementation
 type NotificationImpl struct {
+	libNotification.NotificationUsecases
+
 	LibRepository libRepository.Repository
 	LibUsecases   libNotification.NotificationUsecases
+	Templates     TemplateUsecases
+	Devices       DeviceUsecases
+	RateLimits    RateLimitUsecases
+	SMSCosts      SMSCostUsecases
+	SMS           sms.ServiceSMS
+	Email         email.ServiceEmail
+	WhatsApp      WhatsAppUsecases
+	PhoneNumbers  PhoneNumbers
+	Inbox         InboxUsecases
 }
 
 // NewNotification initializes a notification usecase
 func NewNotification(
 	libRepository libRepository.Repository,
 	libUsecases libNotification.NotificationUsecases,
+	templates TemplateUsecases,
+	devices DeviceUsecases,
+	rateLimits RateLimitUsecases,
+	smsCosts SMSCostUsecases,
+	sms sms.ServiceSMS,
+	email email.ServiceEmail,
+	whatsApp WhatsAppUsecases,
+	phoneNumbers PhoneNumbers,
+	inbox InboxUsecases,
 ) *NotificationImpl {
 	return &NotificationImpl{
-		LibRepository: libRepository,
-		LibUsecases:   libUsecases,
+		NotificationUsecases: libUsecases,
+		LibRepository:        libRepository,
+		LibUsecases:          libUsecases,
+		Templates:            templates,
+		Devices:              devices,
+		RateLimits:           rateLimits,
+		SMSCosts:             smsCosts,
+		SMS:                  sms,
+		Email:                email,
+		WhatsApp:             whatsApp,
+		PhoneNumbers:         phoneNumbers,
+		Inbox:                inbox,
+	}
+}
+
+// SendTemplatedMessage renders a named template and sends it over
+// the template's channel.
+//
+// When the message has a flavour, recipients who have reached the flavour's
+// rate limit are skipped and the message is added to their digests instead.
+// SMS that are over the flavour's budget, or the default budget, are not
+// sent.
+//
+// Push notifications are recorded in the inbox of the users they are
+// delivered to. SMS, emails and WhatsApp messages are not, since they are
+// sent to phone numbers and addresses rather than to users.
+func (n *NotificationImpl) SendTemplatedMessage(
+	ctx context.Context,
+	input dto.TemplatedMessageInput,
+) error {
+	if len(input.To) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	rendered, err := n.Templates.RenderTemplate(
+		ctx,
+		input.TemplateName,
+		input.Language,
+		input.Variables,
+	)
+	if err != nil {
+		return err
+	}
+
+	to := input.To
+	if rendered.Channel == feedlib.ChannelSms {
+		err := n.SMSCosts.CheckSMSBudget(ctx, input.Flavour, rendered.Body, to)
+		if err != nil {
+			return err
+		}
+	}
+	if input.Flavour != nil {
+		to = n.allowed(ctx, *input.Flavour, rendered.Channel, to)
+		if len(to) == 0 {
+			return nil
+		}
+	}
+	return n.dispatch(ctx, rendered, to)
+}
+
+// HandleItemPublish responds to item publish messages.
+//
+// It replaces the feed library's handler so that the push notifications of
+// persistent items are rate limited per user, and so that items with the
+// WhatsApp notification channel are sent over WhatsApp. The library still
+// saves the item's label and updates the unread count.
+func (n *NotificationImpl) HandleItemPublish(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	var envelope libDto.NotificationEnvelope
+	if err := json.Unmarshal(m.Message.Data, &envelope); err != nil {
+		return fmt.Errorf("can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+	var item feedlib.Item
+	if err := json.Unmarshal(envelope.Payload, &item); err != nil {
+		return fmt.Errorf("can't unmarshal item from pubsub data: %w", err)
+	}
+
+	title, body, err := n.renderNotification(
+		ctx,
+		ItemPublishTemplateName,
+		map[string]interface{}{"tagline": item.Tagline, "summary": item.Summary},
+		item.Tagline,
+		item.Summary,
+	)
+	if err != nil {
+		return err
+	}
+
+	if item.Persistent {
+		users := n.allowed(ctx, envelope.Flavour, feedlib.ChannelFcm, item.Users)
+		iconURL := common.DefaultIconPath
+		err := n.push(ctx, users, itemPublishSender, envelope, &firebasetools.FirebaseSimpleNotificationInput{
+			Title:    title,
+			Body:     body,
+			ImageURL: &iconURL,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to notify item: %w", err)
+		}
+	}
+	if hasNotificationChannel(item.NotificationChannels, feedlib.ChannelWhatsapp) {
+		n.whatsAppItem(ctx, envelope.Flavour, item.Users, &item, title, body)
+	}
+
+	return n.LibUsecases.NotifyItemUpdate(ctx, itemPublishSender, false, m)
+}
+
+// HandleNudgePublish responds to nudge publish messages.
+//
+// It replaces the feed library's handler so that the push notification is
+// rendered from the NudgePublishTemplateName template.
+func (n *NotificationImpl) HandleNudgePublish(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	return n.pushNudge(ctx, nudgePublishSender, m)
+}
+
+// HandleNudgeResolve responds to nudge resolve messages.
+//
+// It replaces the feed library's handler so that the push notification is
+// rendered from the NudgeResolveTemplateName template.
+func (n *NotificationImpl) HandleNudgeResolve(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	return n.pushNudge(ctx, nudgeResolveSender, m)
+}
+
+// pushNudge sends the tray notification of a published or resolved nudge to
+// its users' devices
+func (n *NotificationImpl) pushNudge(
+	ctx context.Context,
+	sender string,
+	m *pubsubtools.PubSubPayload,
+) error {
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	var envelope libDto.NotificationEnvelope
+	if err := json.Unmarshal(m.Message.Data, &envelope); err != nil {
+		return fmt.Errorf("can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+	var nudge feedlib.Nudge
+	if err := json.Unmarshal(envelope.Payload, &nudge); err != nil {
+		return fmt.Errorf("can't unmarshal nudge from pubsub data: %w", err)
+	}
+
+	name, message := NudgePublishTemplateName, nudge.NotificationBody.PublishMessage
+	if sender == nudgeResolveSender {
+		name, message = NudgeResolveTemplateName, nudge.NotificationBody.ResolveMessage
+	}
+	title, body, err := n.renderNotification(
+		ctx,
+		name,
+		map[string]interface{}{"title": nudge.Title, "text": nudge.Text, "message": message},
+		nudge.Title,
+		message,
+	)
+	if err != nil {
+		return err
+	}
+
+	var imageURL string
+	for _, link := range nudge.Links {
+		imageURL = link.Thumbnail
+	}
+	err = n.push(ctx, nudge.Users, sender, envelope, &firebasetools.FirebaseSimpleNotificationInput{
+		Title:    title,
+		Body:     body,
+		ImageURL: &imageURL,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to notify nudge: %w", err)
+	}
+	return nil
+}
+
+// push sends a tray notification to users' devices. The envelope is sent in
+// the data payload under the sender.
+func (n *NotificationImpl) push(
+	ctx context.Context,
+	users []string,
+	sender string,
+	envelope libDto.NotificationEnvelope,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+) error {
+	if len(users) == 0 {
+		return nil
+	}
+	tokens, err := n.Devices.GetUserTokens(ctx, users)
+	if err != nil {
+		return fmt.Errorf("can't get user tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	marshalled, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("can't marshal notification envelope: %w", err)
+	}
+	_, err = n.Devices.SendPushNotification(
+		ctx,
+		tokens,
+		map[string]string{sender: string(marshalled)},
+		notification,
+		nil,
+		nil,
+		nil,
+	)
+	return err
+}
+
+// renderNotification renders the title and body of a notification from a
+// template, or returns the given title and body when the template has not
+// been created
+func (n *NotificationImpl) renderNotification(
+	ctx context.Context,
+	name string,
+	variables map[string]interface{},
+	title string,
+	body string,
+) (string, string, error) {
+	rendered, err := n.Templates.RenderTemplate(ctx, name, nil, variables)
+	if errors.Is(err, exceptions.ErrTemplateNotFound) {
+		return title, body, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("unable to render the %s template: %w", name, err)
+	}
+	if rendered.Subject != nil {
+		title = *rendered.Subject
+	}
+	return title, rendered.Body, nil
+}
+
+// whatsAppItem sends the notification of a published item to the primary
+// phone number of each user, and records it in the user's inbox. Failures are
+// logged since the item has already been published and its other
+// notifications sent.
+//
+// The rate limit is applied to the phone numbers rather than the users so
+// that the digests of held back items can be sent to them.
+func (n *NotificationImpl) whatsAppItem(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	users []string,
+	item *feedlib.Item,
+	title string,
+	body string,
+) {
+	if len(users) == 0 {
+		return
+	}
+	numbers, err := n.PhoneNumbers.GetPhoneNumbers(ctx, onboarding.UserUIDs{UIDs: users})
+	if err != nil {
+		log.Printf("unable to get the phone numbers of item %s users: %v", item.ID, err)
+		return
+	}
+	phones := []string{}
+	owners := map[string][]string{}
+	for _, uid := range users {
+		if len(numbers[uid]) > 0 {
+			phone := numbers[uid][0]
+			if len(owners[phone]) == 0 {
+				phones = append(phones, phone)
+			}
+			owners[phone] = append(owners[phone], uid)
+		}
+	}
+	for _, phone := range n.allowed(ctx, flavour, feedlib.ChannelWhatsapp, phones) {
+		messageID, err := n.WhatsApp.SendWhatsAppNotification(ctx, phone, title, body)
+		if err != nil {
+			log.Printf("unable to send item %s to %s over WhatsApp: %v", item.ID, phone, err)
+			continue
+		}
+		for _, uid := range owners[phone] {
+			recordDispatch(ctx, n.Inbox, &domain.InboxNotification{
+				UID:       uid,
+				Channel:   feedlib.ChannelWhatsapp,
+				MessageID: messageID,
+				Title:     title,
+				Body:      body,
+				Data: map[string]interface{}{
+					"flavour": string(flavour),
+					"itemID":  item.ID,
+				},
+			})
+		}
+	}
+}
+
+func hasNotificationChannel(channels []feedlib.Channel, channel feedlib.Channel) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// SendDueDigests sends a summary e.g "You have 5 new updates" to each
+// recipient whose held back notifications are due. A digest that can't be
+// sent is requeued and retried later, except SMS digests that are over the
+// SMS budget of their flavour, which are dropped.
+func (n *NotificationImpl) SendDueDigests(ctx context.Context, now time.Time) (int, error) {
+	digests, err := n.RateLimits.TakeDueDigests(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, digest := range digests {
+		err := n.sendDigest(ctx, digest)
+		if errors.Is(err, exceptions.ErrSMSBudgetExceeded) {
+			log.Printf("dropping digest %s: %v", digest.ID, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("unable to send digest %s: %v", digest.ID, err)
+			if err := n.RateLimits.RetryDigest(ctx, digest, now); err != nil {
+				log.Printf("unable to retry digest %s: %v", digest.ID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendDigest sends a digest to its recipient. SMS digests are checked against
+// the SMS budget of their flavour.
+func (n *NotificationImpl) sendDigest(ctx context.Context, digest *domain.NotificationDigest) error {
+	message := digestMessage(digest)
+	if digest.Channel == feedlib.ChannelSms {
+		flavour := digest.Flavour
+		err := n.SMSCosts.CheckSMSBudget(ctx, &flavour, message.Body, []string{digest.Recipient})
+		if err != nil {
+			return err
+		}
+	}
+	return n.dispatch(ctx, message, []string{digest.Recipient})
+}
+
+// StartDigestDispatcher sends due digests every `interval` until the context
+// is cancelled. It blocks and should be started in its own goroutine.
+func (n *NotificationImpl) StartDigestDispatcher(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, "digest dispatcher", interval, n.SendDueDigests)
+}
+
+// allowed returns the recipients who may be sent a notification now.
+//
+// Rate limiting fails open: a recipient whose limit can't be checked is
+// still notified.
+func (n *NotificationImpl) allowed(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+	recipients []string,
+) []string {
+	allowed := []string{}
+	for _, recipient := range recipients {
+		ok, err := n.RateLimits.Allow(ctx, flavour, channel, recipient)
+		if err != nil {
+			log.Printf("unable to check the rate limit of %s: %v", recipient, err)
+			ok = true
+		}
+		if ok {
+			allowed = append(allowed, recipient)
+		}
+	}
+	return allowed
+}
+
+// digestMessage summarises the notifications that were held back from a
+// recipient
+func digestMessage(digest *domain.NotificationDigest) *dto.RenderedTemplate {
+	subject := "New updates"
+	body := fmt.Sprintf("You have %d new updates", digest.Count)
+	if digest.Count == 1 {
+		body = "You have 1 new update"
+	}
+	return &dto.RenderedTemplate{
+		TemplateName: "digest",
+		Channel:      digest.Channel,
+		Language:     enumutils.LanguageEn,
+		Subject:      &subject,
+		Body:         body,
+	}
+}
+
+// dispatch sends an already rendered message over its channel
+func (n *NotificationImpl) dispatch(
+	ctx context.Context,
+	message *dto.RenderedTemplate,
+	to []string,
+) error {
+	subject := message.TemplateName
+	if message.Subject != nil {
+		subject = *message.Subject
+	}
+
+	switch message.Channel {
+	case feedlib.ChannelFcm:
+		tokens, err := n.Devices.GetUserTokens(ctx, to)
+		if err != nil {
+			return fmt.Errorf("unable to look up push tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			return fmt.Errorf("none of the recipients has a registered device")
+		}
+		_, err = n.Devices.SendPushNotification(
+			ctx,
+			tokens,
+			map[string]string{"template": message.TemplateName},
+			&firebasetools.FirebaseSimpleNotificationInput{
+				Title: subject,
+				Body:  message.Body,
+			},
+			nil,
+			nil,
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to send FCM notification: %w", err)
+		}
+
+	case feedlib.ChannelSms:
+		_, err := n.SMS.SendToMany(ctx, message.Body, to, enumutils.SenderIDBewell)
+		if err != nil {
+			return fmt.Errorf("unable to send SMS: %w", err)
+		}
+
+	case feedlib.ChannelEmail:
+		body, err := inlineCSS(message.Body)
+		if err != nil {
+			return fmt.Errorf("unable to inline the CSS of the email: %w", err)
+		}
+		text, err := htmlToText(body)
+		if err != nil {
+			return fmt.Errorf("unable to convert the email to text: %w", err)
+		}
+		// each recipient is sent their own email so that they don't see
+		// each other's addresses
+		return eachRecipient(to, "email", func(recipient string) error {
+			_, err := n.Email.Send(ctx, &email.Message{
+				To:      []string{recipient},
+				Subject: subject,
+				Text:    text,
+				HTML:    body,
+			})
+			return err
+		})
+
+	// templated messages are sent with the item template so that they reach
+	// recipients who are not in a WhatsApp session
+	case feedlib.ChannelWhatsapp:
+		return eachRecipient(to, "WhatsApp message", func(recipient string) error {
+			_, err := n.WhatsApp.SendWhatsAppNotification(ctx, recipient, subject, message.Body)
+			return err
+		})
+
+	default:
+		return fmt.Errorf("unsupported notification channel %s", message.Channel)
+	}
+	return nil
+}
+
+// eachRecipient sends a message to each recipient. A recipient that can't be
+// sent the message is logged and skipped, and an error is only returned when
+// none of them could be sent it.
+func eachRecipient(to []string, kind string, send func(recipient string) error) error {
+	var err error
+	sent := 0
+	for _, recipient := range to {
+		if err = send(recipient); err != nil {
+			log.Printf("unable to send %s to %s: %v", kind, recipient, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 && err != nil {
+		return fmt.Errorf("unable to send %s: %w", kind, err)
 	}
+	return nil
 }
@@ -0,0 +1,598 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/voice"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/mail"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/otp"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/twilio"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/interserviceclient"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// DefaultOTPLength is the number of digits in a generated code when
+	// no length is configured
+	DefaultOTPLength = 6
+
+	// DefaultOTPExpiry is how long a generated code can be verified when
+	// no expiry is configured
+	DefaultOTPExpiry = 10 * time.Minute
+
+	// the shortest and longest codes that can be configured
+	minOTPLength = 4
+	maxOTPLength = 10
+
+	otpEmailSubject = "Be.Well Verification Code"
+	otpVoiceMessage = "Your Be.Well verification code is %s."
+)
+
+// DefaultOTPRetryLadder is the channel used at each step of GenerateRetryOTP
+// when no ladder is configured.
+//
+// The email step needs the user's email address. It is only taken by the
+// generateEmailRetryOTP query, so generateRetryOTP fails at that step and
+// clients that reach it call generateEmailRetryOTP instead.
+var DefaultOTPRetryLadder = []domain.OTPChannel{
+	domain.OTPChannelSMS,
+	domain.OTPChannelWhatsApp,
+	domain.OTPChannelEmail,
+	domain.OTPChannelVoice,
+}
+
+// OTPConfig controls the one time passwords that are generated and how
+// many wrong codes can be tried
+type OTPConfig struct {
+	Length int
+	Expiry time.Duration
+
+	// MaxVerifyAttempts is the number of codes that can be tried for a
+	// phone number or email address before it is locked for `Lockout`
+	MaxVerifyAttempts int
+	Lockout           time.Duration
+
+	// RetryLadder is the channel that GenerateRetryOTP sends a code over at
+	// each retry step, starting with step 1
+	RetryLadder []domain.OTPChannel
+
+	// TOTPDriftSteps is the number of 30 second steps either side of the
+	// current one whose authenticator app codes are accepted
+	TOTPDriftSteps int
+}
+
+// OTPUsecases represent logic required to send and verify one time passwords.
+//
+// Codes requested with an appId are generated and sent with the settings
+// registered for that app.
+type OTPUsecases interface {
+	// GenerateAndSendOTP sends a code to a phone number by SMS
+	GenerateAndSendOTP(ctx context.Context, msisdn string, appID *string) (string, error)
+
+	// GenerateAndEmailOTP sends a code to a phone number by SMS and, when
+	// an email address is supplied, by email as well
+	GenerateAndEmailOTP(
+		ctx context.Context,
+		msisdn string,
+		email *string,
+		appID *string,
+	) (string, error)
+
+	// GenerateRetryOTP sends a code to a phone number over the channel of a
+	// step of the retry ladder when the first SMS did not arrive. An email
+	// address is required for a step that sends the code by email.
+	GenerateRetryOTP(
+		ctx context.Context,
+		msisdn string,
+		retryStep int,
+		appID *string,
+		email *string,
+	) (string, error)
+
+	// EmailVerificationOTP sends a code to an email address
+	EmailVerificationOTP(ctx context.Context, email string) (string, error)
+
+	// VerifyOTP checks a code that was sent to a phone number, or a code
+	// from the phone number's authenticator app. A code can only be
+	// verified once.
+	VerifyOTP(ctx context.Context, msisdn string, code string) (bool, error)
+
+	// VerifyEmailOTP checks a code that was sent to an email address. A
+	// code can only be verified once.
+	VerifyEmailOTP(ctx context.Context, email string, code string) (bool, error)
+
+	// SaveOTPApp registers or updates the OTP settings of an app
+	SaveOTPApp(ctx context.Context, input dto.OTPAppInput) (*domain.OTPApp, error)
+
+	// ListOTPApps returns the registered apps
+	ListOTPApps(ctx context.Context) ([]*domain.OTPApp, error)
+
+	// DeleteOTPApp removes the OTP settings of an app
+	DeleteOTPApp(ctx context.Context, appID string) (bool, error)
+
+	// ListOTPDeliveries returns how the latest codes of a phone number or
+	// email address were sent
+	ListOTPDeliveries(ctx context.Context, identifier string) ([]*domain.OTPDelivery, error)
+
+	// EnrollTOTP creates the secret of an authenticator app for a phone
+	// number on behalf of a user. `otp` must be a code that was sent to the
+	// phone number by SMS.
+	EnrollTOTP(ctx context.Context, uid string, msisdn string, otp string) (*dto.TOTPProvisioning, error)
+
+	// ConfirmTOTP activates an authenticator app with a code from it and
+	// returns single use recovery codes. Only the user who enrolled the app
+	// can confirm it.
+	ConfirmTOTP(ctx context.Context, uid string, msisdn string, code string) ([]string, error)
+
+	// DisableTOTP removes the authenticator app of a phone number. `otp`
+	// must be a code that was sent to the phone number by SMS.
+	DisableTOTP(ctx context.Context, msisdn string, otp string) (bool, error)
+}
+
+// OTPImpl represents the OTP usecase implementation
+type OTPImpl struct {
+	Repository repository.OTPRepository
+	Config     OTPConfig
+	SMS        sms.ServiceSMS
+	Mail       mail.ServiceMail
+	Twilio     twilio.ServiceTwilio
+	WhatsApp   WhatsAppUsecases
+	Voice      voice.ServiceVoice
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewOTP initializes an OTP usecase.
+// Zero values in the config are replaced with the defaults.
+func NewOTP(
+	repository repository.OTPRepository,
+	config OTPConfig,
+	sms sms.ServiceSMS,
+	mail mail.ServiceMail,
+	twilio twilio.ServiceTwilio,
+	whatsApp WhatsAppUsecases,
+	voice voice.ServiceVoice,
+) (*OTPImpl, error) {
+	if config.Length == 0 {
+		config.Length = DefaultOTPLength
+	}
+	if config.Expiry == 0 {
+		config.Expiry = DefaultOTPExpiry
+	}
+	if config.MaxVerifyAttempts == 0 {
+		config.MaxVerifyAttempts = DefaultMaxOTPVerifyAttempts
+	}
+	if config.Lockout == 0 {
+		config.Lockout = DefaultOTPLockout
+	}
+	if len(config.RetryLadder) == 0 {
+		config.RetryLadder = DefaultOTPRetryLadder
+	}
+	if config.TOTPDriftSteps == 0 {
+		config.TOTPDriftSteps = DefaultTOTPDriftSteps
+	}
+	if config.Length < minOTPLength || config.Length > maxOTPLength {
+		return nil, fmt.Errorf("OTP length must be between %d and %d digits", minOTPLength, maxOTPLength)
+	}
+	if config.Expiry < 0 || config.Lockout < 0 || config.MaxVerifyAttempts < 0 || config.TOTPDriftSteps < 0 {
+		return nil, fmt.Errorf("OTP expiry, lockout, attempts and drift can't be negative")
+	}
+	for _, channel := range config.RetryLadder {
+		if !channel.IsValid() {
+			return nil, fmt.Errorf("%s is not a valid OTP retry channel", channel)
+		}
+	}
+
+	return &OTPImpl{
+		Repository: repository,
+		Config:     config,
+		SMS:        sms,
+		Mail:       mail,
+		Twilio:     twilio,
+		WhatsApp:   whatsApp,
+		Voice:      voice,
+		Clock:      time.Now,
+	}, nil
+}
+
+// GenerateAndSendOTP creates a code and sends it to a phone number by SMS
+func (o *OTPImpl) GenerateAndSendOTP(ctx context.Context, msisdn string, appID *string) (string, error) {
+	app, err := o.app(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	return o.generateAndSendOTP(ctx, msisdn, app)
+}
+
+// GenerateAndEmailOTP creates a code, sends it to a phone number by SMS and
+// emails it when an email address is supplied
+func (o *OTPImpl) GenerateAndEmailOTP(
+	ctx context.Context,
+	msisdn string,
+	email *string,
+	appID *string,
+) (string, error) {
+	app, err := o.app(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	var address string
+	if email != nil {
+		address = normalizeEmail(*email)
+		if !govalidator.IsEmail(address) {
+			return "", fmt.Errorf("%s is not a valid email", *email)
+		}
+		if err := checkOTPChannel(app, domain.OTPChannelEmail); err != nil {
+			return "", err
+		}
+	}
+
+	code, err := o.generateAndSendOTP(ctx, msisdn, app)
+	if err != nil {
+		return "", err
+	}
+	if address == "" {
+		return code, nil
+	}
+
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return "", err
+	}
+	err = o.sendEmail(ctx, domain.OTPDelivery{
+		Identifier:  *phone,
+		AppID:       app.AppID,
+		Channel:     domain.OTPChannelEmail,
+		Destination: address,
+	}, code)
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// GenerateRetryOTP creates a code and sends it to a phone number over the
+// channel of a retry step.
+//
+// SMS retries go through Twilio so that they take a different route from the
+// first SMS. A code sent by email or voice call is still verified against the
+// phone number.
+func (o *OTPImpl) GenerateRetryOTP(
+	ctx context.Context,
+	msisdn string,
+	retryStep int,
+	appID *string,
+	email *string,
+) (string, error) {
+	if retryStep < 1 || retryStep > len(o.Config.RetryLadder) {
+		return "", fmt.Errorf("invalid retry step %d", retryStep)
+	}
+	channel := o.Config.RetryLadder[retryStep-1]
+
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return "", err
+	}
+	app, err := o.app(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	if err := checkOTPChannel(app, channel); err != nil {
+		return "", err
+	}
+	var address string
+	if channel == domain.OTPChannelEmail {
+		if email == nil {
+			return "", fmt.Errorf("an email address is required for retry step %d", retryStep)
+		}
+		address = normalizeEmail(*email)
+		if !govalidator.IsEmail(address) {
+			return "", fmt.Errorf("%s is not a valid email", *email)
+		}
+	}
+
+	code, err := o.issue(ctx, *phone, app)
+	if err != nil {
+		return "", err
+	}
+	text, err := renderOTPText(app, code)
+	if err != nil {
+		return "", fmt.Errorf("unable to render OTP message: %w", err)
+	}
+
+	delivery := domain.OTPDelivery{
+		Identifier:  *phone,
+		AppID:       app.AppID,
+		RetryStep:   retryStep,
+		Channel:     channel,
+		Destination: *phone,
+	}
+	switch channel {
+	case domain.OTPChannelSMS:
+		err = o.deliver(ctx, delivery, func() (string, error) {
+			return otpProviderTwilio, o.Twilio.SendSMS(ctx, *phone, text)
+		})
+	case domain.OTPChannelWhatsApp:
+		err = o.deliver(ctx, delivery, func() (string, error) {
+			sent, err := o.WhatsApp.PhoneNumberVerificationCode(ctx, *phone, code, text)
+			if err == nil && !sent {
+				err = fmt.Errorf("the message was not accepted")
+			}
+			return otpProviderTwilio, err
+		})
+	case domain.OTPChannelEmail:
+		delivery.Destination = address
+		return code, o.sendEmail(ctx, delivery, code)
+	case domain.OTPChannelVoice:
+		err = o.deliver(ctx, delivery, func() (string, error) {
+			_, err := o.Voice.Say(ctx, *phone, otpVoiceText(code))
+			return otpProviderTwilio, err
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to send OTP over %s: %w", channel, err)
+	}
+	return code, nil
+}
+
+// EmailVerificationOTP creates a code and sends it to an email address
+func (o *OTPImpl) EmailVerificationOTP(ctx context.Context, email string) (string, error) {
+	address := normalizeEmail(email)
+	if !govalidator.IsEmail(address) {
+		return "", fmt.Errorf("%s is not a valid email", email)
+	}
+
+	code, err := o.issue(ctx, address, o.defaultApp())
+	if err != nil {
+		return "", err
+	}
+
+	err = o.sendEmail(ctx, domain.OTPDelivery{
+		Identifier:  address,
+		Channel:     domain.OTPChannelEmail,
+		Destination: address,
+	}, code)
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// VerifyOTP checks and consumes a code that was sent to a phone number
+func (o *OTPImpl) VerifyOTP(ctx context.Context, msisdn string, code string) (bool, error) {
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return false, err
+	}
+	return o.verify(ctx, *phone, code, true)
+}
+
+// VerifyEmailOTP checks and consumes a code that was sent to an email address
+func (o *OTPImpl) VerifyEmailOTP(ctx context.Context, email string, code string) (bool, error) {
+	return o.verify(ctx, normalizeEmail(email), code, true)
+}
+
+// generateAndSendOTP creates a code with an app's settings and sends it to a
+// phone number by SMS
+func (o *OTPImpl) generateAndSendOTP(ctx context.Context, msisdn string, app domain.OTPApp) (string, error) {
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return "", err
+	}
+	if err := checkOTPChannel(app, domain.OTPChannelSMS); err != nil {
+		return "", err
+	}
+
+	code, err := o.issue(ctx, *phone, app)
+	if err != nil {
+		return "", err
+	}
+	text, err := renderOTPText(app, code)
+	if err != nil {
+		return "", fmt.Errorf("unable to render OTP message: %w", err)
+	}
+
+	delivery := domain.OTPDelivery{
+		Identifier:  *phone,
+		AppID:       app.AppID,
+		Channel:     domain.OTPChannelSMS,
+		Destination: *phone,
+	}
+	err = o.deliver(ctx, delivery, func() (string, error) {
+		return o.sendSMS(ctx, *phone, text, app.SenderID)
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to send OTP by SMS: %w", err)
+	}
+	return code, nil
+}
+
+// issue generates a code for an identifier with an app's length and expiry
+// and saves its hash. Generation is throttled per identifier.
+func (o *OTPImpl) issue(ctx context.Context, identifier string, app domain.OTPApp) (string, error) {
+	now := o.Clock()
+	err := o.attempt(ctx, otpAttemptsKey(otpGenerateAttempts, identifier), otpGeneratePolicy, now)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := generateOTPCode(app.Length)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate OTP: %w", err)
+	}
+	salt, err := generateSalt()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate OTP: %w", err)
+	}
+
+	_, err = o.Repository.SaveOTP(ctx, &domain.OTP{
+		ID:         ksuid.New().String(),
+		Identifier: identifier,
+		CodeHash:   hashOTP(salt, code),
+		Salt:       salt,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(app.Expiry()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to save OTP: %w", err)
+	}
+	return code, nil
+}
+
+// verify consumes the valid OTP of an identifier that matches the code, or
+// when `acceptTOTP` is set accepts a code from the identifier's authenticator
+// app. It returns false without an error when neither matches.
+//
+// Attempts are limited per identifier and per client IP. A successful
+// verification resets the identifier's attempts.
+func (o *OTPImpl) verify(ctx context.Context, identifier string, code string, acceptTOTP bool) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, fmt.Errorf("an OTP is required")
+	}
+
+	// the client is checked first so that a blocked client can't use up the
+	// attempts of the phone numbers it is guessing codes for
+	now := o.Clock()
+	ipKey := ""
+	if ip := helpers.ClientIPFromContext(ctx); ip != "" {
+		ipKey = otpAttemptsKey(otpVerifyIPAttempts, ip)
+		if err := o.attempt(ctx, ipKey, otpVerifyIPPolicy, now); err != nil {
+			return false, err
+		}
+	}
+	identifierKey := otpAttemptsKey(otpVerifyAttempts, identifier)
+	if err := o.attempt(ctx, identifierKey, o.verifyPolicy(), now); err != nil {
+		return false, err
+	}
+
+	verified, err := o.consume(ctx, identifier, code, now)
+	if err != nil {
+		return false, err
+	}
+	if !verified && acceptTOTP {
+		verified, err = o.verifyTOTP(ctx, identifier, code, now)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !verified {
+		return false, nil
+	}
+
+	o.resetAttempts(ctx, identifierKey)
+	o.resetAttempts(ctx, otpAttemptsKey(otpGenerateAttempts, identifier))
+	if ipKey != "" {
+		o.forgiveAttempt(ctx, ipKey)
+	}
+	return true, nil
+}
+
+// consume marks the valid OTP of an identifier that matches the code as used
+func (o *OTPImpl) consume(ctx context.Context, identifier string, code string, now time.Time) (bool, error) {
+	otps, err := o.Repository.ListValidOTPs(ctx, identifier, now)
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve OTPs: %w", err)
+	}
+	for _, stored := range otps {
+		if !otpMatches(stored, code) {
+			continue
+		}
+		consumed, err := o.Repository.ConsumeOTP(ctx, stored.ID, now)
+		if err != nil {
+			return false, fmt.Errorf("unable to consume OTP: %w", err)
+		}
+		// a code that was consumed concurrently is not valid
+		return consumed, nil
+	}
+	return false, nil
+}
+
+// sendSMS sends a text message to a normalized phone number and returns the
+// provider it was sent through. Kenyan numbers are sent through Africa's
+// Talking with the sender ID and other numbers through Twilio.
+func (o *OTPImpl) sendSMS(
+	ctx context.Context,
+	phone string,
+	text string,
+	senderID enumutils.SenderID,
+) (string, error) {
+	if interserviceclient.IsKenyanNumber(phone) {
+		_, err := o.SMS.Send(ctx, phone, text, senderID)
+		return otpProviderAfricasTalking, err
+	}
+	return otpProviderTwilio, o.Twilio.SendSMS(ctx, phone, text)
+}
+
+// sendEmail emails a code and records the delivery
+func (o *OTPImpl) sendEmail(ctx context.Context, delivery domain.OTPDelivery, code string) error {
+	err := o.deliver(ctx, delivery, func() (string, error) {
+		_, _, err := o.Mail.SendEmail(
+			ctx,
+			otpEmailSubject,
+			otp.GenerateEmailFunc(code),
+			nil,
+			delivery.Destination,
+		)
+		return otpProviderMailgun, err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to send OTP to email: %w", err)
+	}
+	return nil
+}
+
+// otpVoiceText returns the message that reads a code out one digit at a time
+func otpVoiceText(code string) string {
+	return fmt.Sprintf(otpVoiceMessage, strings.Join(strings.Split(code, ""), ", "))
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// generateOTPCode returns a random numeric code of `length` digits,
+// including leading zeros
+func generateOTPCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func generateSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+func hashOTP(salt string, code string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func otpMatches(stored *domain.OTP, code string) bool {
+	hash := hashOTP(stored.Salt, code)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(stored.CodeHash)) == 1
+}
@@ -0,0 +1,189 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// defaultOTPMessageTemplate renders the message that carries a code when an
+// app has not configured its own
+const defaultOTPMessageTemplate = "{{.code}} is your Be.Well verification code {{.hash}}"
+
+// OTPAppsPath is where other services register, update and delete the OTP
+// settings of apps. Users can only list them.
+const OTPAppsPath = "/internal/otp_apps"
+
+// otpChannels are the channels that codes can be sent over
+var otpChannels = []feedlib.Channel{
+	feedlib.ChannelSms,
+	feedlib.ChannelEmail,
+	feedlib.ChannelWhatsapp,
+}
+
+// SaveOTPApp validates and saves the OTP settings of an app. Unset fields are
+// filled with the service's defaults when the app is saved.
+func (o *OTPImpl) SaveOTPApp(ctx context.Context, input dto.OTPAppInput) (*domain.OTPApp, error) {
+	appID := strings.TrimSpace(input.AppID)
+	if appID == "" {
+		return nil, fmt.Errorf("an appId is required")
+	}
+
+	app := o.defaultApp()
+	app.AppID = appID
+	if input.SenderID != nil {
+		if !input.SenderID.IsValid() {
+			return nil, fmt.Errorf("%s is not a valid sender ID", *input.SenderID)
+		}
+		app.SenderID = *input.SenderID
+	}
+	if input.MessageTemplate != nil {
+		app.MessageTemplate = strings.TrimSpace(*input.MessageTemplate)
+	}
+	if input.Length != nil {
+		if *input.Length < minOTPLength || *input.Length > maxOTPLength {
+			return nil, fmt.Errorf("OTP length must be between %d and %d digits", minOTPLength, maxOTPLength)
+		}
+		app.Length = *input.Length
+	}
+	if input.ExpirySeconds != nil {
+		if *input.ExpirySeconds <= 0 {
+			return nil, fmt.Errorf("the OTP expiry must be at least one second")
+		}
+		app.ExpirySeconds = *input.ExpirySeconds
+	}
+	if input.Channels != nil {
+		channels, err := otpAppChannels(input.Channels)
+		if err != nil {
+			return nil, err
+		}
+		app.Channels = channels
+	}
+	if input.SMSRetrieverHash != nil {
+		app.SMSRetrieverHash = strings.TrimSpace(*input.SMSRetrieverHash)
+	}
+
+	// the template is rendered with a placeholder code so that a template
+	// that can't carry the code is rejected when saved rather than when a
+	// code is sent
+	const placeholder = "CODE"
+	text, err := renderOTPText(app, placeholder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTP message template: %w", err)
+	}
+	if !strings.Contains(text, placeholder) {
+		return nil, fmt.Errorf("the OTP message template must include {{.code}}")
+	}
+
+	app.UpdatedAt = time.Now()
+	return o.Repository.SaveOTPApp(ctx, &app)
+}
+
+// ListOTPApps returns the registered apps
+func (o *OTPImpl) ListOTPApps(ctx context.Context) ([]*domain.OTPApp, error) {
+	return o.Repository.ListOTPApps(ctx)
+}
+
+// DeleteOTPApp removes the OTP settings of an app. Its codes are then sent
+// with the defaults.
+func (o *OTPImpl) DeleteOTPApp(ctx context.Context, appID string) (bool, error) {
+	err := o.Repository.DeleteOTPApp(ctx, appID)
+	if err != nil && !errors.Is(err, exceptions.ErrOTPAppNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// app returns the settings that codes requested with an appId are generated
+// and sent with.
+//
+// Apps that have not been registered use the defaults. Their appId is used as
+// the SMS Retriever hash since that is what clients sent before the registry
+// existed.
+func (o *OTPImpl) app(ctx context.Context, appID *string) (domain.OTPApp, error) {
+	app := o.defaultApp()
+	if appID == nil || strings.TrimSpace(*appID) == "" {
+		return app, nil
+	}
+
+	id := strings.TrimSpace(*appID)
+	registered, err := o.Repository.GetOTPApp(ctx, id)
+	if errors.Is(err, exceptions.ErrOTPAppNotFound) {
+		app.AppID = id
+		app.SMSRetrieverHash = id
+		return app, nil
+	}
+	if err != nil {
+		return app, fmt.Errorf("unable to retrieve OTP app: %w", err)
+	}
+	return *registered, nil
+}
+
+// defaultApp returns the settings of codes that are not requested by a
+// registered app
+func (o *OTPImpl) defaultApp() domain.OTPApp {
+	return domain.OTPApp{
+		SenderID:        enumutils.SenderIDBewell,
+		MessageTemplate: defaultOTPMessageTemplate,
+		Length:          o.Config.Length,
+		ExpirySeconds:   int(o.Config.Expiry / time.Second),
+		Channels:        otpChannels,
+	}
+}
+
+// checkOTPChannel returns an error wrapping exceptions.ErrOTPChannelNotAllowed
+// when an app's codes can't be sent over a channel.
+//
+// Voice calls reach the same phone number as SMS so they are allowed
+// whenever SMS is.
+func checkOTPChannel(app domain.OTPApp, channel domain.OTPChannel) error {
+	allowed := map[domain.OTPChannel]feedlib.Channel{
+		domain.OTPChannelSMS:      feedlib.ChannelSms,
+		domain.OTPChannelWhatsApp: feedlib.ChannelWhatsapp,
+		domain.OTPChannelEmail:    feedlib.ChannelEmail,
+		domain.OTPChannelVoice:    feedlib.ChannelSms,
+	}[channel]
+	if app.AllowsChannel(allowed) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s can't be used by %s", exceptions.ErrOTPChannelNotAllowed, channel, app.AppID)
+}
+
+// renderOTPText renders the message that carries an app's code
+func renderOTPText(app domain.OTPApp, code string) (string, error) {
+	text, err := renderText(app.MessageTemplate, map[string]interface{}{
+		"code":          code,
+		"hash":          app.SMSRetrieverHash,
+		"expiryMinutes": app.ExpirySeconds / 60,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func otpAppChannels(channels []feedlib.Channel) ([]feedlib.Channel, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("an app needs at least one OTP channel")
+	}
+	seen := map[feedlib.Channel]bool{}
+	allowed := []feedlib.Channel{}
+	for _, channel := range channels {
+		if !(domain.OTPApp{Channels: otpChannels}).AllowsChannel(channel) {
+			return nil, fmt.Errorf("codes can't be sent over %s", channel)
+		}
+		if !seen[channel] {
+			seen[channel] = true
+			allowed = append(allowed, channel)
+		}
+	}
+	return allowed, nil
+}
@@ -0,0 +1,182 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+)
+
+const (
+	// DefaultMaxOTPVerifyAttempts is the number of codes that can be tried
+	// for a phone number or email address before it is locked
+	DefaultMaxOTPVerifyAttempts = 5
+
+	// DefaultOTPLockout is how long a phone number or email address is
+	// locked after too many wrong codes
+	DefaultOTPLockout = 15 * time.Minute
+
+	// prefixes of the keys that attempts are counted under
+	otpVerifyAttempts   = "verify"
+	otpVerifyIPAttempts = "verify-ip"
+	otpGenerateAttempts = "generate"
+	emailLinkAttempts   = "email-link"
+)
+
+// otpAttemptPolicy limits how often an OTP action can be attempted.
+//
+// After each attempt the caller waits `backoff`, doubling with every further
+// attempt up to `maxBackoff`. After `maxAttempts` attempts the key is locked
+// for `lockout`. Attempts are forgotten when there has been none for `window`.
+type otpAttemptPolicy struct {
+	maxAttempts int
+	lockout     time.Duration
+	window      time.Duration
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+var (
+	// a client IP is allowed more attempts than a single phone number since
+	// several users may share it e.g behind a clinic's NAT. It does not back
+	// off for the same reason.
+	otpVerifyIPPolicy = otpAttemptPolicy{
+		maxAttempts: 20,
+		lockout:     time.Hour,
+		window:      time.Hour,
+	}
+
+	// generation is throttled so that retries can't be used to flood a
+	// phone number or inbox. Email verification links are throttled the
+	// same way.
+	otpGeneratePolicy = otpAttemptPolicy{
+		maxAttempts: 5,
+		lockout:     time.Hour,
+		window:      time.Hour,
+		backoff:     30 * time.Second,
+		maxBackoff:  5 * time.Minute,
+	}
+)
+
+// verifyPolicy returns the policy that limits the codes tried for a phone
+// number or email address
+func (o *OTPImpl) verifyPolicy() otpAttemptPolicy {
+	return otpAttemptPolicy{
+		maxAttempts: o.Config.MaxVerifyAttempts,
+		lockout:     o.Config.Lockout,
+		window:      time.Hour,
+		backoff:     time.Second,
+		maxBackoff:  30 * time.Second,
+	}
+}
+
+// attempt records an attempt against a policy. It returns an error wrapping
+// exceptions.ErrTooManyOTPAttempts when the attempt is not allowed yet.
+//
+// Every attempt is counted as a failure until it succeeds and the count is
+// reset, so that concurrent guesses can't get around the limit.
+func (o *OTPImpl) attempt(
+	ctx context.Context,
+	key string,
+	policy otpAttemptPolicy,
+	now time.Time,
+) error {
+	return countOTPAttempt(ctx, o.Repository, key, policy, now)
+}
+
+// resetAttempts forgets the attempts stored under a key
+func (o *OTPImpl) resetAttempts(ctx context.Context, key string) {
+	resetOTPAttempts(ctx, o.Repository, key)
+}
+
+// countOTPAttempt records an attempt in a repository, see attempt
+func countOTPAttempt(
+	ctx context.Context,
+	repository repository.OTPAttemptsRepository,
+	key string,
+	policy otpAttemptPolicy,
+	now time.Time,
+) error {
+	var denied error
+	_, err := repository.UpdateOTPAttempts(ctx, key, func(attempts *domain.OTPAttempts) {
+		denied = applyOTPAttempt(attempts, policy, now)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to count OTP attempts: %w", err)
+	}
+	return denied
+}
+
+// resetOTPAttempts forgets the attempts stored under a key in a repository
+func resetOTPAttempts(ctx context.Context, repository repository.OTPAttemptsRepository, key string) {
+	_, err := repository.UpdateOTPAttempts(ctx, key, func(attempts *domain.OTPAttempts) {
+		*attempts = domain.OTPAttempts{ID: attempts.ID}
+	})
+	if err != nil {
+		log.Printf("unable to reset OTP attempts %s: %v", key, err)
+	}
+}
+
+// forgiveAttempt uncounts a single successful attempt
+func (o *OTPImpl) forgiveAttempt(ctx context.Context, key string) {
+	_, err := o.Repository.UpdateOTPAttempts(ctx, key, func(attempts *domain.OTPAttempts) {
+		if attempts.Count > 0 {
+			attempts.Count--
+		}
+	})
+	if err != nil {
+		log.Printf("unable to update OTP attempts %s: %v", key, err)
+	}
+}
+
+func applyOTPAttempt(attempts *domain.OTPAttempts, policy otpAttemptPolicy, now time.Time) error {
+	if attempts.LockedUntil != nil {
+		if now.Before(*attempts.LockedUntil) {
+			return tooManyOTPAttempts(*attempts.LockedUntil)
+		}
+		*attempts = domain.OTPAttempts{ID: attempts.ID}
+	}
+	if attempts.Count > 0 && now.Sub(attempts.LastAttemptAt) >= policy.window {
+		attempts.Count = 0
+	}
+
+	if attempts.Count >= policy.maxAttempts {
+		until := now.Add(policy.lockout)
+		attempts.LockedUntil = &until
+		return tooManyOTPAttempts(until)
+	}
+	if attempts.Count > 0 && policy.backoff > 0 {
+		next := attempts.LastAttemptAt.Add(policy.wait(attempts.Count))
+		if now.Before(next) {
+			return tooManyOTPAttempts(next)
+		}
+	}
+
+	attempts.Count++
+	attempts.LastAttemptAt = now
+	return nil
+}
+
+// wait returns how long to wait after `count` attempts
+func (p otpAttemptPolicy) wait(count int) time.Duration {
+	wait := p.backoff
+	for i := 1; i < count && wait < p.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.maxBackoff {
+		wait = p.maxBackoff
+	}
+	return wait
+}
+
+func tooManyOTPAttempts(until time.Time) error {
+	return fmt.Errorf("%w: try again after %s", exceptions.ErrTooManyOTPAttempts, until.Format(time.RFC3339))
+}
+
+func otpAttemptsKey(action string, subject string) string {
+	return fmt.Sprintf("%s_%s", action, subject)
+}
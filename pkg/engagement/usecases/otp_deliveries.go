@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"context"
+	"log"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// otpDeliveriesLimit is the number of deliveries that are returned for
+	// a phone number or email address
+	otpDeliveriesLimit = 50
+
+	// the providers that codes are sent through
+	otpProviderAfricasTalking = "AFRICAS_TALKING"
+	otpProviderTwilio         = "TWILIO"
+	otpProviderMailgun        = "MAILGUN"
+)
+
+// ListOTPDeliveries returns the latest deliveries of the codes of a phone
+// number or email address
+func (o *OTPImpl) ListOTPDeliveries(ctx context.Context, identifier string) ([]*domain.OTPDelivery, error) {
+	if phone, err := converterandformatter.NormalizeMSISDN(identifier); err == nil {
+		identifier = *phone
+	} else {
+		identifier = normalizeEmail(identifier)
+	}
+	return o.Repository.ListOTPDeliveries(ctx, identifier, otpDeliveriesLimit)
+}
+
+// deliver sends a code with `send` and records the outcome. `send` returns
+// the provider that it used.
+//
+// A delivery that can't be recorded is logged rather than failing a code
+// that has already been sent.
+func (o *OTPImpl) deliver(
+	ctx context.Context,
+	delivery domain.OTPDelivery,
+	send func() (string, error),
+) error {
+	provider, err := send()
+
+	delivery.ID = ksuid.New().String()
+	delivery.Provider = provider
+	delivery.Status = domain.OTPDeliveryStatusSent
+	if err != nil {
+		delivery.Status = domain.OTPDeliveryStatusFailed
+		delivery.Error = err.Error()
+	}
+	delivery.CreatedAt = o.Clock()
+	if _, saveErr := o.Repository.SaveOTPDelivery(ctx, &delivery); saveErr != nil {
+		log.Printf("unable to record OTP delivery to %s: %v", delivery.Destination, saveErr)
+	}
+	return err
+}
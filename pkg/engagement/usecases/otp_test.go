@@ -0,0 +1,524 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	voiceMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/voice/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	twilioMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/twilio/mock"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+// sentOTPs records the recipients of the messages sent over the channels
+// that are not in the test fixture
+type sentOTPs struct {
+	twilio []string
+	voice  []string
+
+	// voiceErr is returned by the fake voice call provider
+	voiceErr error
+}
+
+func newTestOTP(f *testFixture, config usecases.OTPConfig) (*usecases.OTPImpl, *sentOTPs) {
+	sent := &sentOTPs{}
+	fakeTwilio := &twilioMock.FakeServiceTwilio{
+		SendSMSFn: func(ctx context.Context, to string, msg string) error {
+			sent.twilio = append(sent.twilio, to)
+			return nil
+		},
+	}
+	fakeVoice := &voiceMock.FakeServiceVoice{
+		SayFn: func(ctx context.Context, to string, message string) (string, error) {
+			if sent.voiceErr != nil {
+				return "", sent.voiceErr
+			}
+			sent.voice = append(sent.voice, to)
+			return "CA123", nil
+		},
+	}
+
+	otp, err := usecases.NewOTP(f.repository, config, f.smsService(), f.mailService(), fakeTwilio, f.whatsAppUsecases(), fakeVoice)
+	assert.Nil(f.t, err)
+	otp.Clock = f.clock.Now
+	return otp, sent
+}
+
+func TestNewOTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  usecases.OTPConfig
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: usecases.OTPConfig{},
+		},
+		{
+			name:   "configured",
+			config: usecases.OTPConfig{Length: 8, Expiry: time.Minute},
+		},
+		{
+			name:    "too short",
+			config:  usecases.OTPConfig{Length: 3},
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			config:  usecases.OTPConfig{Length: 11},
+			wantErr: true,
+		},
+		{
+			name: "retry ladder",
+			config: usecases.OTPConfig{
+				RetryLadder: []domain.OTPChannel{domain.OTPChannelWhatsApp, domain.OTPChannelVoice},
+			},
+		},
+		{
+			name: "invalid retry channel",
+			config: usecases.OTPConfig{
+				RetryLadder: []domain.OTPChannel{"PIGEON"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecases.NewOTP(memory.NewRepository(), tt.config, nil, nil, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOTPImpl_VerifyOTP(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{Length: 8})
+
+	code, err := otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+	assert.Len(t, code, 8)
+	assert.Equal(t, []string{"+254711223344"}, f.sms.to)
+
+	// only a hash of the code is stored
+	stored, err := f.repository.ListValidOTPs(ctx, "+254711223344", f.clock.Now())
+	assert.Nil(t, err)
+	assert.Len(t, stored, 1)
+	assert.NotContains(t, stored[0].CodeHash, code)
+
+	verified, err := otp.VerifyOTP(ctx, "+254711223344", "00000000")
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	f.clock.Advance(time.Second)
+	verified, err = otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	// a code can only be used once
+	verified, err = otp.VerifyOTP(ctx, "0711223344", code)
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	_, err = otp.VerifyOTP(ctx, "not a phone number", code)
+	assert.NotNil(t, err)
+}
+
+func TestOTPImpl_VerifyOTP_Expired(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{Expiry: time.Minute})
+
+	code, err := otp.GenerateAndSendOTP(ctx, "+254711223344", nil)
+	assert.Nil(t, err)
+	f.clock.Advance(time.Minute)
+
+	verified, err := otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.Nil(t, err)
+	assert.False(t, verified)
+}
+
+func TestOTPImpl_GenerateRetryOTP(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, sent := newTestOTP(f, usecases.OTPConfig{})
+	email := "achieng@example.com"
+
+	tests := []struct {
+		name      string
+		msisdn    string
+		retryStep int
+		email     *string
+		wantErr   bool
+	}{
+		{
+			name:      "twilio sms",
+			msisdn:    "+254711223344",
+			retryStep: 1,
+		},
+		{
+			name:      "whatsapp",
+			msisdn:    "+254711223344",
+			retryStep: 2,
+		},
+		{
+			name:      "email",
+			msisdn:    "+254711223344",
+			retryStep: 3,
+			email:     &email,
+		},
+		{
+			name:      "email without an address",
+			msisdn:    "+254711223344",
+			retryStep: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "voice",
+			msisdn:    "+254711223344",
+			retryStep: 4,
+		},
+		{
+			name:      "invalid retry step",
+			msisdn:    "+254711223344",
+			retryStep: 5,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid phone number",
+			msisdn:    "12",
+			retryStep: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := otp.GenerateRetryOTP(ctx, tt.msisdn, tt.retryStep, nil, tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateRetryOTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				verified, err := otp.VerifyOTP(ctx, tt.msisdn, code)
+				assert.Nil(t, err)
+				assert.True(t, verified)
+			}
+		})
+	}
+	assert.Len(t, sent.twilio, 1)
+	if assert.Len(t, f.whatsApp, 1) {
+		assert.Equal(t, "HXverification", f.whatsApp[0].templateID)
+	}
+	assert.Equal(t, []string{email}, f.mail.to)
+	assert.Len(t, sent.voice, 1)
+}
+
+func TestOTPImpl_ListOTPDeliveries(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, sent := newTestOTP(f, usecases.OTPConfig{
+		RetryLadder: []domain.OTPChannel{domain.OTPChannelVoice},
+	})
+
+	_, err := otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+
+	f.clock.Advance(time.Minute)
+	sent.voiceErr = fmt.Errorf("the number is unreachable")
+	_, err = otp.GenerateRetryOTP(ctx, "0711223344", 1, nil, nil)
+	assert.NotNil(t, err)
+
+	deliveries, err := otp.ListOTPDeliveries(ctx, "0711223344")
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 2)
+
+	assert.Equal(t, domain.OTPChannelVoice, deliveries[0].Channel)
+	assert.Equal(t, 1, deliveries[0].RetryStep)
+	assert.Equal(t, domain.OTPDeliveryStatusFailed, deliveries[0].Status)
+	assert.Equal(t, "the number is unreachable", deliveries[0].Error)
+
+	assert.Equal(t, domain.OTPChannelSMS, deliveries[1].Channel)
+	assert.Equal(t, 0, deliveries[1].RetryStep)
+	assert.Equal(t, "AFRICAS_TALKING", deliveries[1].Provider)
+	assert.Equal(t, domain.OTPDeliveryStatusSent, deliveries[1].Status)
+	assert.Equal(t, "+254711223344", deliveries[1].Destination)
+
+	_, err = otp.EmailVerificationOTP(ctx, "achieng@example.com")
+	assert.Nil(t, err)
+	deliveries, err = otp.ListOTPDeliveries(ctx, "Achieng@Example.com")
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, domain.OTPChannelEmail, deliveries[0].Channel)
+}
+
+func TestOTPImpl_VerifyEmailOTP(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	_, err := otp.EmailVerificationOTP(ctx, "not an email")
+	assert.NotNil(t, err)
+
+	code, err := otp.EmailVerificationOTP(ctx, "Achieng@Example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"achieng@example.com"}, f.mail.to)
+
+	verified, err := otp.VerifyEmailOTP(ctx, "achieng@example.com", code)
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	email := "achieng@example.com"
+	code, err = otp.GenerateAndEmailOTP(ctx, "+254711223344", &email, nil)
+	assert.Nil(t, err)
+	assert.Len(t, f.mail.to, 2)
+	assert.Len(t, f.sms.to, 1)
+
+	verified, err = otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.Nil(t, err)
+	assert.True(t, verified)
+}
+
+func TestOTPImpl_VerifyOTP_Lockout(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{MaxVerifyAttempts: 3, Lockout: 15 * time.Minute})
+
+	code, err := otp.GenerateAndSendOTP(ctx, "+254711223344", nil)
+	assert.Nil(t, err)
+
+	verified, err := otp.VerifyOTP(ctx, "+254711223344", "000000")
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	// wrong codes back off
+	_, err = otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+
+	for i := 0; i < 2; i++ {
+		f.clock.Advance(time.Minute)
+		verified, err = otp.VerifyOTP(ctx, "+254711223344", "000000")
+		assert.Nil(t, err)
+		assert.False(t, verified)
+	}
+
+	// even the right code is refused while locked
+	f.clock.Advance(time.Minute)
+	_, err = otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+
+	f.clock.Advance(15 * time.Minute)
+	code, err = otp.GenerateAndSendOTP(ctx, "+254711223344", nil)
+	assert.Nil(t, err)
+	verified, err = otp.VerifyOTP(ctx, "+254711223344", code)
+	assert.Nil(t, err)
+	assert.True(t, verified)
+}
+
+func TestOTPImpl_VerifyOTP_ClientIPLockout(t *testing.T) {
+	ctx := helpers.ContextWithClientIP(context.Background(), "197.248.0.1")
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	// guessing across many phone numbers is limited by the client's address
+	for i := 0; i < 20; i++ {
+		verified, err := otp.VerifyOTP(ctx, fmt.Sprintf("+2547110000%02d", i), "123456")
+		assert.Nil(t, err)
+		assert.False(t, verified)
+	}
+	_, err := otp.VerifyOTP(ctx, "+254711000099", "123456")
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+
+	// other clients are not affected
+	other := helpers.ContextWithClientIP(context.Background(), "197.248.0.2")
+	_, err = otp.VerifyOTP(other, "+254711000099", "123456")
+	assert.Nil(t, err)
+}
+
+func TestOTPImpl_GenerateOTP_Throttled(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, sent := newTestOTP(f, usecases.OTPConfig{})
+
+	_, err := otp.GenerateAndSendOTP(ctx, "+254711223344", nil)
+	assert.Nil(t, err)
+
+	_, err = otp.GenerateRetryOTP(ctx, "+254711223344", 2, nil, nil)
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+
+	f.clock.Advance(30 * time.Second)
+	_, err = otp.GenerateRetryOTP(ctx, "+254711223344", 2, nil, nil)
+	assert.Nil(t, err)
+
+	// the wait doubles with each code
+	f.clock.Advance(30 * time.Second)
+	_, err = otp.GenerateRetryOTP(ctx, "+254711223344", 1, nil, nil)
+	assert.True(t, errors.Is(err, exceptions.ErrTooManyOTPAttempts))
+
+	f.clock.Advance(30 * time.Second)
+	_, err = otp.GenerateRetryOTP(ctx, "+254711223344", 1, nil, nil)
+	assert.Nil(t, err)
+
+	assert.Len(t, f.sms.to, 1)
+	assert.Len(t, sent.twilio, 1)
+	assert.Len(t, f.whatsApp, 1)
+}
+
+func TestOTPImpl_SaveOTPApp(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	senderID := enumutils.SenderIDSLADE360
+	invalidSenderID := enumutils.SenderID("ACME")
+	template := "Your code is {{.code}}"
+	noCode := "Welcome to Be.Well"
+	badTemplate := "{{.code"
+	length := 4
+	tooLong := 12
+	expiry := 300
+	hash := "FA+9qCX9VSu"
+
+	tests := []struct {
+		name    string
+		input   dto.OTPAppInput
+		wantErr bool
+	}{
+		{
+			name: "configured",
+			input: dto.OTPAppInput{
+				AppID:            "com.savannah.bewell",
+				SenderID:         &senderID,
+				MessageTemplate:  &template,
+				Length:           &length,
+				ExpirySeconds:    &expiry,
+				Channels:         []feedlib.Channel{feedlib.ChannelSms},
+				SMSRetrieverHash: &hash,
+			},
+		},
+		{
+			name:  "defaults",
+			input: dto.OTPAppInput{AppID: "com.savannah.pro"},
+		},
+		{
+			name:    "no appId",
+			input:   dto.OTPAppInput{AppID: " "},
+			wantErr: true,
+		},
+		{
+			name:    "invalid sender ID",
+			input:   dto.OTPAppInput{AppID: "app", SenderID: &invalidSenderID},
+			wantErr: true,
+		},
+		{
+			name:    "template without the code",
+			input:   dto.OTPAppInput{AppID: "app", MessageTemplate: &noCode},
+			wantErr: true,
+		},
+		{
+			name:    "unparseable template",
+			input:   dto.OTPAppInput{AppID: "app", MessageTemplate: &badTemplate},
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			input:   dto.OTPAppInput{AppID: "app", Length: &tooLong},
+			wantErr: true,
+		},
+		{
+			name:    "no channels",
+			input:   dto.OTPAppInput{AppID: "app", Channels: []feedlib.Channel{}},
+			wantErr: true,
+		},
+		{
+			name:    "push channel",
+			input:   dto.OTPAppInput{AppID: "app", Channels: []feedlib.Channel{feedlib.ChannelFcm}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := otp.SaveOTPApp(ctx, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SaveOTPApp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	apps, err := otp.ListOTPApps(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, "com.savannah.bewell", apps[0].AppID)
+	assert.Equal(t, usecases.DefaultOTPLength, apps[1].Length)
+	assert.Len(t, apps[1].Channels, 3)
+
+	deleted, err := otp.DeleteOTPApp(ctx, "com.savannah.pro")
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	apps, err = otp.ListOTPApps(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, apps, 1)
+}
+
+func TestOTPImpl_GenerateOTP_App(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	senderID := enumutils.SenderIDSLADE360
+	template := "<#> {{.code}} is your code. It expires in {{.expiryMinutes}} minutes. {{.hash}}"
+	length := 4
+	expiry := 300
+	hash := "FA+9qCX9VSu"
+	_, err := otp.SaveOTPApp(ctx, dto.OTPAppInput{
+		AppID:            "com.savannah.bewell",
+		SenderID:         &senderID,
+		MessageTemplate:  &template,
+		Length:           &length,
+		ExpirySeconds:    &expiry,
+		Channels:         []feedlib.Channel{feedlib.ChannelSms},
+		SMSRetrieverHash: &hash,
+	})
+	assert.Nil(t, err)
+
+	appID := "com.savannah.bewell"
+	code, err := otp.GenerateAndSendOTP(ctx, "+254711223344", &appID)
+	assert.Nil(t, err)
+	assert.Len(t, code, 4)
+	assert.Equal(t, []enumutils.SenderID{enumutils.SenderIDSLADE360}, f.sms.senderIDs)
+	assert.Equal(t, "<#> "+code+" is your code. It expires in 5 minutes. FA+9qCX9VSu", f.sms.messages[0])
+
+	stored, err := f.repository.ListValidOTPs(ctx, "+254711223344", f.clock.Now())
+	assert.Nil(t, err)
+	assert.Len(t, stored, 1)
+	assert.Equal(t, 5*time.Minute, stored[0].ExpiresAt.Sub(stored[0].CreatedAt))
+
+	// the app's codes can only be sent by SMS
+	f.clock.Advance(time.Minute)
+	_, err = otp.GenerateRetryOTP(ctx, "+254711223344", 2, &appID, nil)
+	assert.True(t, errors.Is(err, exceptions.ErrOTPChannelNotAllowed))
+	email := "achieng@example.com"
+	_, err = otp.GenerateAndEmailOTP(ctx, "+254711223344", &email, &appID)
+	assert.True(t, errors.Is(err, exceptions.ErrOTPChannelNotAllowed))
+	assert.Empty(t, f.mail.to)
+
+	// an app that has not been registered uses the defaults with its appId
+	// as the SMS Retriever hash, without surrounding spaces
+	unregistered := " kL8x2pQ4mRt "
+	code, err = otp.GenerateAndSendOTP(ctx, "+254722334455", &unregistered)
+	assert.Nil(t, err)
+	assert.Len(t, code, usecases.DefaultOTPLength)
+	assert.Equal(t, enumutils.SenderIDBewell, f.sms.senderIDs[1])
+	assert.Equal(t, code+" is your Be.Well verification code kL8x2pQ4mRt", f.sms.messages[1])
+}
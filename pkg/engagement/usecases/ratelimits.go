@@ -0,0 +1,231 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/feedlib"
+)
+
+// digestBatchSize is the maximum number of digests taken per run of the
+// digest dispatcher
+const digestBatchSize = 100
+
+const (
+	// maxDigestAttempts is the number of times sending a digest may fail
+	// before it is dropped
+	maxDigestAttempts = 5
+
+	// digestRetryDelay is how long the digest dispatcher waits before
+	// retrying a digest after its first failure. The delay doubles after
+	// each failure.
+	digestRetryDelay = 5 * time.Minute
+)
+
+// defaultRateLimits are the limits of each channel when no policy has been
+// configured for a flavour. They are deliberately lenient for push and
+// strict for the channels that cost money per message.
+var defaultRateLimits = map[feedlib.Channel]domain.RateLimitPolicy{
+	feedlib.ChannelFcm:      {Limit: 5, WindowSeconds: 15 * 60},
+	feedlib.ChannelSms:      {Limit: 3, WindowSeconds: 60 * 60},
+	feedlib.ChannelEmail:    {Limit: 5, WindowSeconds: 60 * 60},
+	feedlib.ChannelWhatsapp: {Limit: 3, WindowSeconds: 60 * 60},
+}
+
+// RateLimitUsecases represent logic required to limit how many notifications
+// a recipient receives and to collapse the rest into digests
+type RateLimitUsecases interface {
+	// SetRateLimit configures the limit of a flavour and channel
+	SetRateLimit(
+		ctx context.Context,
+		input dto.RateLimitPolicyInput,
+	) (*domain.RateLimitPolicy, error)
+
+	// ListRateLimits returns the limit in force for every flavour and
+	// channel, whether configured or the built-in default
+	ListRateLimits(ctx context.Context) ([]*domain.RateLimitPolicy, error)
+
+	// ResetRateLimit restores the built-in default of a flavour and channel
+	ResetRateLimit(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		channel feedlib.Channel,
+	) (bool, error)
+
+	// Allow returns true if a notification may be sent to the recipient
+	// now. When it returns false the notification has been added to the
+	// recipient's digest.
+	Allow(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		channel feedlib.Channel,
+		recipient string,
+	) (bool, error)
+
+	// TakeDueDigests removes and returns the digests that are due at `now`
+	TakeDueDigests(ctx context.Context, now time.Time) ([]*domain.NotificationDigest, error)
+
+	// RetryDigest puts back a taken digest that could not be sent so that
+	// it is retried later. It returns an error when the digest has failed
+	// too many times and has been dropped.
+	RetryDigest(ctx context.Context, digest *domain.NotificationDigest, now time.Time) error
+}
+
+// RateLimitImpl represents the rate limit usecase implementation
+type RateLimitImpl struct {
+	Repository repository.RateLimitRepository
+}
+
+// NewRateLimits initializes a rate limit usecase
+func NewRateLimits(
+	repository repository.RateLimitRepository,
+) *RateLimitImpl {
+	return &RateLimitImpl{
+		Repository: repository,
+	}
+}
+
+// SetRateLimit validates and saves the limit of a flavour and channel.
+// A limit of 0 turns rate limiting off.
+func (rl *RateLimitImpl) SetRateLimit(
+	ctx context.Context,
+	input dto.RateLimitPolicyInput,
+) (*domain.RateLimitPolicy, error) {
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("invalid flavour %s", input.Flavour)
+	}
+	if !input.Channel.IsValid() {
+		return nil, fmt.Errorf("invalid channel %s", input.Channel)
+	}
+	if input.Limit < 0 {
+		return nil, fmt.Errorf("the limit can't be negative")
+	}
+	if input.Limit > 0 && input.WindowSeconds <= 0 {
+		return nil, fmt.Errorf("the window must be at least one second")
+	}
+
+	return rl.Repository.SaveRateLimitPolicy(ctx, &domain.RateLimitPolicy{
+		Flavour:       input.Flavour,
+		Channel:       input.Channel,
+		Limit:         input.Limit,
+		WindowSeconds: input.WindowSeconds,
+		UpdatedAt:     time.Now(),
+	})
+}
+
+// ListRateLimits returns the policy of every flavour and channel
+func (rl *RateLimitImpl) ListRateLimits(ctx context.Context) ([]*domain.RateLimitPolicy, error) {
+	policies := []*domain.RateLimitPolicy{}
+	for _, flavour := range feedlib.AllFlavour {
+		for _, channel := range feedlib.AllChannel {
+			policy, err := rl.policy(ctx, flavour, channel)
+			if err != nil {
+				return nil, err
+			}
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+// ResetRateLimit removes the configured limit of a flavour and channel
+func (rl *RateLimitImpl) ResetRateLimit(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) (bool, error) {
+	err := rl.Repository.DeleteRateLimitPolicy(ctx, flavour, channel)
+	if err != nil && !errors.Is(err, exceptions.ErrRateLimitPolicyNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// Allow reserves a notification to a recipient against the limit of the
+// flavour and channel
+func (rl *RateLimitImpl) Allow(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+	recipient string,
+) (bool, error) {
+	policy, err := rl.policy(ctx, flavour, channel)
+	if err != nil {
+		return false, err
+	}
+	if policy.Limit == 0 {
+		return true, nil
+	}
+	return rl.Repository.ReserveNotification(ctx, *policy, recipient, time.Now())
+}
+
+// TakeDueDigests removes and returns the digests that are due.
+//
+// A digest is removed before it is returned so that when several instances
+// of the service dispatch digests, each digest is only sent once.
+func (rl *RateLimitImpl) TakeDueDigests(
+	ctx context.Context,
+	now time.Time,
+) ([]*domain.NotificationDigest, error) {
+	due, err := rl.Repository.ListDueDigests(ctx, now, digestBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list due digests: %w", err)
+	}
+
+	digests := []*domain.NotificationDigest{}
+	for _, d := range due {
+		digest, err := rl.Repository.TakeDigest(ctx, d.ID)
+		if errors.Is(err, exceptions.ErrDigestNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to take digest %s: %w", d.ID, err)
+		}
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
+
+// RetryDigest requeues a digest that could not be sent with an exponential
+// backoff
+func (rl *RateLimitImpl) RetryDigest(
+	ctx context.Context,
+	digest *domain.NotificationDigest,
+	now time.Time,
+) error {
+	retry := *digest
+	retry.Attempts++
+	if retry.Attempts >= maxDigestAttempts {
+		return fmt.Errorf("digest %s failed %d times and has been dropped", digest.ID, retry.Attempts)
+	}
+	retry.DueAt = now.Add(digestRetryDelay << (retry.Attempts - 1))
+	return rl.Repository.RequeueDigest(ctx, &retry)
+}
+
+// policy returns the configured policy of a flavour and channel or the
+// channel's default
+func (rl *RateLimitImpl) policy(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	channel feedlib.Channel,
+) (*domain.RateLimitPolicy, error) {
+	policy, err := rl.Repository.GetRateLimitPolicy(ctx, flavour, channel)
+	if err == nil {
+		return policy, nil
+	}
+	if !errors.Is(err, exceptions.ErrRateLimitPolicyNotFound) {
+		return nil, fmt.Errorf("unable to get the rate limit of %s %s: %w", flavour, channel, err)
+	}
+
+	defaultPolicy := defaultRateLimits[channel]
+	defaultPolicy.Flavour = flavour
+	defaultPolicy.Channel = channel
+	defaultPolicy.IsDefault = true
+	return &defaultPolicy, nil
+}
@@ -0,0 +1,267 @@
+package usecases_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	smsMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/sms/mock"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitImpl_SetRateLimit(t *testing.T) {
+	ctx := context.Background()
+	rateLimits := usecases.NewRateLimits(memory.NewRepository())
+
+	tests := []struct {
+		name    string
+		input   dto.RateLimitPolicyInput
+		wantErr bool
+	}{
+		{
+			name: "happy case",
+			input: dto.RateLimitPolicyInput{
+				Flavour:       feedlib.FlavourConsumer,
+				Channel:       feedlib.ChannelSms,
+				Limit:         2,
+				WindowSeconds: 3600,
+			},
+		},
+		{
+			name: "disabled",
+			input: dto.RateLimitPolicyInput{
+				Flavour: feedlib.FlavourPro,
+				Channel: feedlib.ChannelFcm,
+			},
+		},
+		{
+			name: "invalid flavour",
+			input: dto.RateLimitPolicyInput{
+				Flavour:       "VISITOR",
+				Channel:       feedlib.ChannelSms,
+				Limit:         2,
+				WindowSeconds: 3600,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative limit",
+			input: dto.RateLimitPolicyInput{
+				Flavour:       feedlib.FlavourConsumer,
+				Channel:       feedlib.ChannelSms,
+				Limit:         -1,
+				WindowSeconds: 3600,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no window",
+			input: dto.RateLimitPolicyInput{
+				Flavour: feedlib.FlavourConsumer,
+				Channel: feedlib.ChannelSms,
+				Limit:   2,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rateLimits.SetRateLimit(ctx, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimitImpl_ListRateLimits(t *testing.T) {
+	ctx := context.Background()
+	rateLimits := usecases.NewRateLimits(memory.NewRepository())
+
+	policies, err := rateLimits.ListRateLimits(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, policies, len(feedlib.AllFlavour)*len(feedlib.AllChannel))
+	for _, policy := range policies {
+		assert.True(t, policy.IsDefault)
+		assert.Greater(t, policy.Limit, 0)
+	}
+
+	_, err = rateLimits.SetRateLimit(ctx, dto.RateLimitPolicyInput{
+		Flavour:       feedlib.FlavourConsumer,
+		Channel:       feedlib.ChannelSms,
+		Limit:         10,
+		WindowSeconds: 60,
+	})
+	assert.Nil(t, err)
+
+	policies, err = rateLimits.ListRateLimits(ctx)
+	assert.Nil(t, err)
+	configured := 0
+	for _, policy := range policies {
+		if !policy.IsDefault {
+			configured++
+			assert.Equal(t, 10, policy.Limit)
+		}
+	}
+	assert.Equal(t, 1, configured)
+
+	reset, err := rateLimits.ResetRateLimit(ctx, feedlib.FlavourConsumer, feedlib.ChannelSms)
+	assert.Nil(t, err)
+	assert.True(t, reset)
+
+	policies, err = rateLimits.ListRateLimits(ctx)
+	assert.Nil(t, err)
+	for _, policy := range policies {
+		assert.True(t, policy.IsDefault)
+	}
+}
+
+func TestNotificationImpl_RateLimitedMessages(t *testing.T) {
+	ctx := context.Background()
+	repository := memory.NewRepository()
+	templates := usecases.NewTemplates(repository)
+	_, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+
+	rateLimits := usecases.NewRateLimits(repository)
+	_, err = rateLimits.SetRateLimit(ctx, dto.RateLimitPolicyInput{
+		Flavour:       feedlib.FlavourConsumer,
+		Channel:       feedlib.ChannelSms,
+		Limit:         2,
+		WindowSeconds: 3600,
+	})
+	assert.Nil(t, err)
+
+	var sent []string
+	fakeSMS := &smsMock.FakeServiceSMS{
+		SendToManyFn: func(
+			ctx context.Context,
+			message string,
+			to []string,
+			from enumutils.SenderID,
+		) (*libDto.SendMessageResponse, error) {
+			sent = append(sent, message)
+			return &libDto.SendMessageResponse{}, nil
+		},
+	}
+	n := usecases.NewNotification(nil, nil, templates, nil, rateLimits, newTestFixture(t).smsCosts(), fakeSMS, nil, nil, nil, nil)
+
+	flavour := feedlib.FlavourConsumer
+	input := dto.TemplatedMessageInput{
+		TemplateName: "appointment_reminder",
+		Variables:    map[string]interface{}{"firstName": "Wanjiru", "time": "10:00"},
+		To:           []string{"+254711223344"},
+		Flavour:      &flavour,
+	}
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, n.SendTemplatedMessage(ctx, input))
+	}
+	assert.Len(t, sent, 2)
+
+	// messages without a flavour are not rate limited
+	input.Flavour = nil
+	assert.Nil(t, n.SendTemplatedMessage(ctx, input))
+	assert.Len(t, sent, 3)
+
+	// the digest is held until the window ends
+	count, err := n.SendDueDigests(ctx, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = n.SendDueDigests(ctx, time.Now().Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "You have 3 new updates", sent[len(sent)-1])
+
+	// a digest is only sent once
+	count, err = n.SendDueDigests(ctx, time.Now().Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestNotificationImpl_SendDueDigests_Retry(t *testing.T) {
+	ctx := context.Background()
+	repository := memory.NewRepository()
+	templates := usecases.NewTemplates(repository)
+	_, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+
+	rateLimits := usecases.NewRateLimits(repository)
+	_, err = rateLimits.SetRateLimit(ctx, dto.RateLimitPolicyInput{
+		Flavour:       feedlib.FlavourConsumer,
+		Channel:       feedlib.ChannelSms,
+		Limit:         1,
+		WindowSeconds: 3600,
+	})
+	assert.Nil(t, err)
+
+	var sent []string
+	failing := false
+	fakeSMS := &smsMock.FakeServiceSMS{
+		SendToManyFn: func(
+			ctx context.Context,
+			message string,
+			to []string,
+			from enumutils.SenderID,
+		) (*libDto.SendMessageResponse, error) {
+			if failing {
+				return nil, fmt.Errorf("the SMS gateway is unavailable")
+			}
+			sent = append(sent, message)
+			return &libDto.SendMessageResponse{}, nil
+		},
+	}
+	n := usecases.NewNotification(nil, nil, templates, nil, rateLimits, newTestFixture(t).smsCosts(), fakeSMS, nil, nil, nil, nil)
+
+	flavour := feedlib.FlavourConsumer
+	input := dto.TemplatedMessageInput{
+		TemplateName: "appointment_reminder",
+		Variables:    map[string]interface{}{"firstName": "Wanjiru", "time": "10:00"},
+		To:           []string{"+254711223344"},
+		Flavour:      &flavour,
+	}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, n.SendTemplatedMessage(ctx, input))
+	}
+	assert.Len(t, sent, 1)
+
+	now := time.Now().Add(2 * time.Hour)
+	failing = true
+	count, err := n.SendDueDigests(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// the failed digest is retried after a backoff rather than lost
+	failing = false
+	count, err = n.SendDueDigests(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = n.SendDueDigests(ctx, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "You have 2 new updates", sent[len(sent)-1])
+
+	// a digest that keeps failing is eventually dropped
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, n.SendTemplatedMessage(ctx, input))
+	}
+	failing = true
+	for i := 0; i < 10; i++ {
+		now = now.Add(24 * time.Hour)
+		count, err = n.SendDueDigests(ctx, now)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, count)
+	}
+	failing = false
+	count, err = n.SendDueDigests(ctx, now.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,492 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/segmentio/ksuid"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	// defaultScheduleTimezone is used when a scheduled notification does
+	// not specify a timezone
+	defaultScheduleTimezone = "Africa/Nairobi"
+
+	// schedulerBatchSize is the maximum number of due notifications that
+	// are sent on each tick of the scheduler
+	schedulerBatchSize = 50
+
+	// schedulerLease is how long a scheduler instance has to send a due
+	// notification before another instance may retry it
+	schedulerLease = 5 * time.Minute
+)
+
+// ScheduleUsecases represent logic required to schedule one-off and
+// recurring notifications and to send them when they fall due
+type ScheduleUsecases interface {
+	ScheduleNotification(
+		ctx context.Context,
+		createdBy string,
+		input dto.ScheduledNotificationInput,
+	) (*domain.ScheduledNotification, error)
+
+	GetScheduledNotification(
+		ctx context.Context,
+		id string,
+	) (*domain.ScheduledNotification, error)
+
+	ListScheduledNotifications(
+		ctx context.Context,
+		status *domain.ScheduleStatus,
+	) ([]*domain.ScheduledNotification, error)
+
+	PauseScheduledNotification(
+		ctx context.Context,
+		id string,
+	) (*domain.ScheduledNotification, error)
+
+	// ResumeScheduledNotification reactivates a paused notification.
+	// Runs that were missed while it was paused are skipped.
+	ResumeScheduledNotification(
+		ctx context.Context,
+		id string,
+	) (*domain.ScheduledNotification, error)
+
+	CancelScheduledNotification(
+		ctx context.Context,
+		id string,
+	) (*domain.ScheduledNotification, error)
+
+	// RunDueNotifications sends the notifications that are due at `now` and
+	// returns the number that were sent successfully
+	RunDueNotifications(ctx context.Context, now time.Time) (int, error)
+}
+
+// ScheduleImpl represents the notification scheduler usecase implementation
+type ScheduleImpl struct {
+	Repository   repository.ScheduleRepository
+	Groups       repository.RecipientGroupRepository
+	Templates    TemplateUsecases
+	Notification NotificationUsecases
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewSchedules initializes a notification scheduler usecase
+func NewSchedules(
+	repository repository.ScheduleRepository,
+	groups repository.RecipientGroupRepository,
+	templates TemplateUsecases,
+	notification NotificationUsecases,
+) *ScheduleImpl {
+	return &ScheduleImpl{
+		Repository:   repository,
+		Groups:       groups,
+		Templates:    templates,
+		Notification: notification,
+		Clock:        time.Now,
+	}
+}
+
+// ScheduleNotification validates and saves a scheduled notification
+func (s *ScheduleImpl) ScheduleNotification(
+	ctx context.Context,
+	createdBy string,
+	input dto.ScheduledNotificationInput,
+) (*domain.ScheduledNotification, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("a scheduled notification name is required")
+	}
+	if !input.Recurrence.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid recurrence", input.Recurrence)
+	}
+	if len(input.To) == 0 && len(input.Groups) == 0 {
+		return nil, fmt.Errorf("at least one recipient or recipient group is required")
+	}
+	if input.Flavour != nil && !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid flavour", *input.Flavour)
+	}
+	for _, group := range input.Groups {
+		if _, err := s.Groups.GetRecipientGroup(ctx, group); err != nil {
+			return nil, fmt.Errorf("unable to get recipient group %s: %w", group, err)
+		}
+	}
+
+	// rendering the template up front rejects unknown templates and
+	// missing variables when the notification is scheduled rather than
+	// when it is due
+	_, err := s.Templates.RenderTemplate(ctx, input.TemplateName, input.Language, input.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	now := s.Clock()
+	notification := &domain.ScheduledNotification{
+		ID:           ksuid.New().String(),
+		Name:         name,
+		TemplateName: input.TemplateName,
+		Language:     input.Language,
+		Variables:    input.Variables,
+		To:           input.To,
+		Groups:       input.Groups,
+		Flavour:      input.Flavour,
+		Recurrence:   input.Recurrence,
+		Timezone:     defaultScheduleTimezone,
+		StartAt:      input.StartAt,
+		EndAt:        input.EndAt,
+		Status:       domain.ScheduleStatusActive,
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if notification.Variables == nil {
+		notification.Variables = map[string]interface{}{}
+	}
+	if notification.To == nil {
+		notification.To = []string{}
+	}
+	if notification.Groups == nil {
+		notification.Groups = []string{}
+	}
+	if input.Timezone != nil && *input.Timezone != "" {
+		notification.Timezone = *input.Timezone
+	}
+	if input.Recurrence != domain.ScheduleRecurrenceOnce {
+		if input.Schedule == nil || strings.TrimSpace(*input.Schedule) == "" {
+			return nil, fmt.Errorf("a %s schedule is required", input.Recurrence)
+		}
+		notification.Schedule = strings.TrimSpace(*input.Schedule)
+	}
+	if input.Recurrence == domain.ScheduleRecurrenceOnce && !input.StartAt.After(now) {
+		return nil, fmt.Errorf("a one-off notification must be scheduled in the future")
+	}
+	if input.EndAt != nil && !input.EndAt.After(input.StartAt) {
+		return nil, fmt.Errorf("endAt must be after startAt")
+	}
+
+	next, err := nextRun(notification, now)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, fmt.Errorf("the schedule has no runs after %s", now.Format(time.RFC3339))
+	}
+	notification.NextRunAt = next
+
+	return s.Repository.SaveScheduledNotification(ctx, notification)
+}
+
+// GetScheduledNotification retrieves a scheduled notification by ID
+func (s *ScheduleImpl) GetScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	return s.Repository.GetScheduledNotification(ctx, id)
+}
+
+// ListScheduledNotifications lists scheduled notifications, optionally
+// filtered by status
+func (s *ScheduleImpl) ListScheduledNotifications(
+	ctx context.Context,
+	status *domain.ScheduleStatus,
+) ([]*domain.ScheduledNotification, error) {
+	if status != nil && !status.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid schedule status", status)
+	}
+	return s.Repository.ListScheduledNotifications(ctx, status)
+}
+
+// PauseScheduledNotification stops an active notification from being sent
+// until it is resumed
+func (s *ScheduleImpl) PauseScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	now := s.Clock()
+	return s.Repository.UpdateScheduledNotification(ctx, id, func(notification *domain.ScheduledNotification) error {
+		if notification.Status != domain.ScheduleStatusActive {
+			return fmt.Errorf("only an active notification can be paused, this one is %s", notification.Status)
+		}
+		notification.Status = domain.ScheduleStatusPaused
+		notification.UpdatedAt = now
+		return nil
+	})
+}
+
+// ResumeScheduledNotification reactivates a paused notification
+func (s *ScheduleImpl) ResumeScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	now := s.Clock()
+	return s.Repository.UpdateScheduledNotification(ctx, id, func(notification *domain.ScheduledNotification) error {
+		if notification.Status != domain.ScheduleStatusPaused {
+			return fmt.Errorf("only a paused notification can be resumed, this one is %s", notification.Status)
+		}
+		next, err := nextRun(notification, now)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return fmt.Errorf("the schedule has no runs after %s", now.Format(time.RFC3339))
+		}
+		notification.Status = domain.ScheduleStatusActive
+		notification.NextRunAt = next
+		notification.UpdatedAt = now
+		return nil
+	})
+}
+
+// CancelScheduledNotification permanently stops a notification from being sent
+func (s *ScheduleImpl) CancelScheduledNotification(
+	ctx context.Context,
+	id string,
+) (*domain.ScheduledNotification, error) {
+	now := s.Clock()
+	return s.Repository.UpdateScheduledNotification(ctx, id, func(notification *domain.ScheduledNotification) error {
+		if notification.Status.IsFinal() {
+			return fmt.Errorf("the notification is already %s", notification.Status)
+		}
+		notification.Status = domain.ScheduleStatusCancelled
+		notification.NextRunAt = nil
+		notification.UpdatedAt = now
+		return nil
+	})
+}
+
+// RunDueNotifications sends the notifications that are due at `now`.
+//
+// Each notification is leased before it is sent so that when several
+// instances of the service run the scheduler, a notification is only sent
+// once. Runs that were missed e.g while the service was down are collapsed
+// into a single run.
+func (s *ScheduleImpl) RunDueNotifications(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.Repository.ListDueScheduledNotifications(ctx, now, schedulerBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list due notifications: %w", err)
+	}
+
+	sent := 0
+	for _, d := range due {
+		claimed, err := s.Repository.ClaimScheduledNotification(ctx, d.ID, now, now.Add(schedulerLease))
+		if err != nil {
+			log.Printf("unable to claim scheduled notification %s: %v", d.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		// the notification is read again because it may have changed
+		// between listing and claiming it
+		notification, err := s.Repository.GetScheduledNotification(ctx, d.ID)
+		if err != nil {
+			log.Printf("unable to get scheduled notification %s: %v", d.ID, err)
+			continue
+		}
+
+		ok, err := s.run(ctx, notification, now)
+		if err != nil {
+			log.Printf("unable to update scheduled notification %s: %v", d.ID, err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// run sends a claimed notification and schedules its next run.
+// It returns true if the notification was sent.
+//
+// The run is recorded on the stored notification rather than the claimed
+// copy so that a pause or cancellation made while it was being sent is
+// kept.
+func (s *ScheduleImpl) run(
+	ctx context.Context,
+	notification *domain.ScheduledNotification,
+	now time.Time,
+) (bool, error) {
+	sendErr := s.send(ctx, notification)
+	if sendErr != nil {
+		log.Printf("unable to send scheduled notification %s: %v", notification.ID, sendErr)
+	}
+
+	_, err := s.Repository.UpdateScheduledNotification(ctx, notification.ID, func(notification *domain.ScheduledNotification) error {
+		notification.LastRunAt = &now
+		notification.RunCount++
+		notification.LastError = ""
+		if sendErr != nil {
+			notification.LastError = sendErr.Error()
+		}
+		notification.LeaseExpiresAt = nil
+		notification.UpdatedAt = now
+		if notification.Status != domain.ScheduleStatusActive {
+			return nil
+		}
+
+		next, err := nextRun(notification, now)
+		if err != nil {
+			// the schedule was valid when it was saved so this should not
+			// happen; stop the notification rather than retrying forever
+			next = nil
+			notification.LastError = err.Error()
+		}
+		notification.NextRunAt = next
+		if next == nil {
+			notification.Status = domain.ScheduleStatusCompleted
+			if notification.Recurrence == domain.ScheduleRecurrenceOnce && sendErr != nil {
+				notification.Status = domain.ScheduleStatusFailed
+			}
+		}
+		return nil
+	})
+	return sendErr == nil, err
+}
+
+// send renders the notification's template and sends it to its recipients
+func (s *ScheduleImpl) send(ctx context.Context, notification *domain.ScheduledNotification) error {
+	recipients, err := s.recipients(ctx, notification)
+	if err != nil {
+		return err
+	}
+	return s.Notification.SendTemplatedMessage(ctx, dto.TemplatedMessageInput{
+		TemplateName: notification.TemplateName,
+		Language:     notification.Language,
+		Variables:    notification.Variables,
+		To:           recipients,
+		Flavour:      notification.Flavour,
+	})
+}
+
+// recipients returns the notification's recipients and the current members
+// of its recipient groups, without duplicates.
+//
+// A group that has been deleted since the notification was scheduled is
+// skipped.
+func (s *ScheduleImpl) recipients(
+	ctx context.Context,
+	notification *domain.ScheduledNotification,
+) ([]string, error) {
+	recipients := []string{}
+	seen := map[string]bool{}
+	add := func(recipient string) {
+		if seen[recipient] {
+			return
+		}
+		seen[recipient] = true
+		recipients = append(recipients, recipient)
+	}
+
+	for _, recipient := range notification.To {
+		add(recipient)
+	}
+	for _, name := range notification.Groups {
+		group, err := s.Groups.GetRecipientGroup(ctx, name)
+		if errors.Is(err, exceptions.ErrRecipientGroupNotFound) {
+			log.Printf("scheduled notification %s: recipient group %s no longer exists", notification.ID, name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get recipient group %s: %w", name, err)
+		}
+		for _, member := range group.Members {
+			add(member)
+		}
+	}
+	return recipients, nil
+}
+
+// StartScheduler runs due notifications every `interval` until the context
+// is cancelled. It blocks and should be started in its own goroutine.
+func (s *ScheduleImpl) StartScheduler(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, "notification scheduler", interval, s.RunDueNotifications)
+}
+
+// runPeriodically calls `run` every `interval` until the context is
+// cancelled, logging any errors under `name`
+func runPeriodically(
+	ctx context.Context,
+	name string,
+	interval time.Duration,
+	run func(ctx context.Context, now time.Time) (int, error),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := run(ctx, now); err != nil {
+				log.Printf("%s: %v", name, err)
+			}
+		}
+	}
+}
+
+// nextRun returns the first run of a notification that is strictly after
+// `after` and not before its start time. It returns nil when there are no
+// more runs.
+func nextRun(notification *domain.ScheduledNotification, after time.Time) (*time.Time, error) {
+	location, err := time.LoadLocation(notification.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid timezone: %w", notification.Timezone, err)
+	}
+
+	// the start time itself is a valid run
+	if after.Before(notification.StartAt) {
+		after = notification.StartAt.Add(-time.Nanosecond)
+	}
+
+	var next time.Time
+	switch notification.Recurrence {
+	case domain.ScheduleRecurrenceOnce:
+		if after.Before(notification.StartAt) {
+			next = notification.StartAt
+		}
+
+	case domain.ScheduleRecurrenceCron:
+		schedule, err := cron.ParseStandard(notification.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", notification.Schedule, err)
+		}
+		next = schedule.Next(after.In(location))
+
+	case domain.ScheduleRecurrenceRrule:
+		option, err := rrule.StrToROptionInLocation(notification.Schedule, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE %q: %w", notification.Schedule, err)
+		}
+		option.Dtstart = notification.StartAt.In(location)
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE %q: %w", notification.Schedule, err)
+		}
+		next = rule.After(after, false)
+
+	default:
+		return nil, fmt.Errorf("%s is not a valid recurrence", notification.Recurrence)
+	}
+
+	if next.IsZero() {
+		return nil, nil
+	}
+	if notification.EndAt != nil && next.After(*notification.EndAt) {
+		return nil, nil
+	}
+	return &next, nil
+}
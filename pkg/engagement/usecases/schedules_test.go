@@ -0,0 +1,324 @@
+package usecases_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSchedules returns a scheduler that sends SMS reminders through the
+// fixture's fake SMS service
+func newTestSchedules(f *testFixture) (*usecases.ScheduleImpl, *usecases.RecipientGroupImpl) {
+	templates := usecases.NewTemplates(f.repository)
+	_, err := templates.CreateTemplate(context.Background(), getTestTemplateInput())
+	assert.Nil(f.t, err)
+
+	notification := usecases.NewNotification(nil, nil, templates, nil, nil, f.smsCosts(), f.smsService(), nil, nil, nil, nil)
+	schedules := usecases.NewSchedules(f.repository, f.repository, templates, notification)
+	schedules.Clock = f.clock.Now
+	return schedules, usecases.NewRecipientGroups(f.repository)
+}
+
+func getTestScheduleInput(recurrence domain.ScheduleRecurrence, schedule string) dto.ScheduledNotificationInput {
+	input := dto.ScheduledNotificationInput{
+		Name:         "morning reminder",
+		TemplateName: "appointment_reminder",
+		Variables:    map[string]interface{}{"firstName": "Akinyi", "time": "08:00"},
+		To:           []string{"+254711223344"},
+		Recurrence:   recurrence,
+		StartAt:      time.Now().Add(time.Hour),
+	}
+	if schedule != "" {
+		input.Schedule = &schedule
+	}
+	return input
+}
+
+func TestScheduleImpl_ScheduleNotification(t *testing.T) {
+	ctx := context.Background()
+	schedules, _ := newTestSchedules(newTestFixture(t))
+
+	missingVariables := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	missingVariables.Variables = nil
+
+	past := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	past.StartAt = time.Now().Add(-time.Hour)
+
+	noRecipients := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	noRecipients.To = nil
+
+	unknownGroup := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	unknownGroup.Groups = []string{"unknown"}
+
+	badTimezone := getTestScheduleInput(domain.ScheduleRecurrenceCron, "0 8 * * *")
+	timezone := "Mars/Olympus_Mons"
+	badTimezone.Timezone = &timezone
+
+	tests := []struct {
+		name    string
+		input   dto.ScheduledNotificationInput
+		wantErr bool
+	}{
+		{
+			name:  "one-off",
+			input: getTestScheduleInput(domain.ScheduleRecurrenceOnce, ""),
+		},
+		{
+			name:  "cron",
+			input: getTestScheduleInput(domain.ScheduleRecurrenceCron, "0 8 * * *"),
+		},
+		{
+			name:  "rrule",
+			input: getTestScheduleInput(domain.ScheduleRecurrenceRrule, "FREQ=DAILY;BYHOUR=8;BYMINUTE=0;BYSECOND=0"),
+		},
+		{
+			name:    "invalid cron expression",
+			input:   getTestScheduleInput(domain.ScheduleRecurrenceCron, "every morning"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid rrule",
+			input:   getTestScheduleInput(domain.ScheduleRecurrenceRrule, "FREQ=SOMETIMES"),
+			wantErr: true,
+		},
+		{
+			name:    "recurring without a schedule",
+			input:   getTestScheduleInput(domain.ScheduleRecurrenceCron, ""),
+			wantErr: true,
+		},
+		{
+			name:    "missing template variables",
+			input:   missingVariables,
+			wantErr: true,
+		},
+		{
+			name:    "one-off in the past",
+			input:   past,
+			wantErr: true,
+		},
+		{
+			name:    "no recipients",
+			input:   noRecipients,
+			wantErr: true,
+		},
+		{
+			name:    "unknown recipient group",
+			input:   unknownGroup,
+			wantErr: true,
+		},
+		{
+			name:    "invalid timezone",
+			input:   badTimezone,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schedules.ScheduleNotification(ctx, "admin", tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ScheduleNotification() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, domain.ScheduleStatusActive, got.Status)
+				assert.NotNil(t, got.NextRunAt)
+				assert.False(t, got.NextRunAt.Before(tt.input.StartAt))
+			}
+		})
+	}
+}
+
+func TestScheduleImpl_NextRun(t *testing.T) {
+	ctx := context.Background()
+	schedules, _ := newTestSchedules(newTestFixture(t))
+
+	nairobi, err := time.LoadLocation("Africa/Nairobi")
+	assert.Nil(t, err)
+	tomorrow := time.Now().In(nairobi).AddDate(0, 0, 1)
+	start := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 6, 0, 0, 0, nairobi)
+	want := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 8, 0, 0, 0, nairobi)
+
+	for _, input := range []dto.ScheduledNotificationInput{
+		getTestScheduleInput(domain.ScheduleRecurrenceCron, "0 8 * * *"),
+		getTestScheduleInput(domain.ScheduleRecurrenceRrule, "FREQ=DAILY;BYHOUR=8;BYMINUTE=0;BYSECOND=0"),
+	} {
+		input.StartAt = start
+		got, err := schedules.ScheduleNotification(ctx, "admin", input)
+		assert.Nil(t, err)
+		assert.True(t, want.Equal(*got.NextRunAt), "%s: got %s, want %s", input.Recurrence, got.NextRunAt, want)
+	}
+}
+
+func TestScheduleImpl_RunDueNotifications(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	schedules, groups := newTestSchedules(f)
+
+	_, err := groups.SaveRecipientGroup(ctx, dto.RecipientGroupInput{
+		Name:    "diabetes clinic",
+		Members: []string{"+254722000001", "+254711223344"},
+	})
+	assert.Nil(t, err)
+
+	once, err := schedules.ScheduleNotification(ctx, "admin", getTestScheduleInput(domain.ScheduleRecurrenceOnce, ""))
+	assert.Nil(t, err)
+
+	daily := getTestScheduleInput(domain.ScheduleRecurrenceCron, "0 8 * * *")
+	daily.Groups = []string{"diabetes clinic"}
+	recurring, err := schedules.ScheduleNotification(ctx, "admin", daily)
+	assert.Nil(t, err)
+
+	paused, err := schedules.ScheduleNotification(ctx, "admin", getTestScheduleInput(domain.ScheduleRecurrenceOnce, ""))
+	assert.Nil(t, err)
+	_, err = schedules.PauseScheduledNotification(ctx, paused.ID)
+	assert.Nil(t, err)
+
+	// nothing is due yet
+	count, err := schedules.RunDueNotifications(ctx, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	later := recurring.NextRunAt.Add(time.Minute)
+	if once.NextRunAt.After(later) {
+		later = once.NextRunAt.Add(time.Minute)
+	}
+	count, err = schedules.RunDueNotifications(ctx, later)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, f.sms.requests, 2)
+	assert.Contains(t, f.sms.requests, []string{"+254711223344", "+254722000001"})
+
+	got, err := schedules.GetScheduledNotification(ctx, once.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ScheduleStatusCompleted, got.Status)
+	assert.Nil(t, got.NextRunAt)
+	assert.Equal(t, 1, got.RunCount)
+
+	got, err = schedules.GetScheduledNotification(ctx, recurring.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ScheduleStatusActive, got.Status)
+	assert.True(t, got.NextRunAt.After(later))
+
+	// running again at the same instant sends nothing
+	count, err = schedules.RunDueNotifications(ctx, later)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = schedules.CancelScheduledNotification(ctx, recurring.ID)
+	assert.Nil(t, err)
+	_, err = schedules.ResumeScheduledNotification(ctx, recurring.ID)
+	assert.NotNil(t, err)
+
+	count, err = schedules.RunDueNotifications(ctx, later.AddDate(0, 0, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = schedules.ResumeScheduledNotification(ctx, paused.ID)
+	assert.Nil(t, err)
+}
+
+func TestScheduleImpl_RunDueNotifications_Flavour(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	schedules, _ := newTestSchedules(f)
+	costs := newTestSMSCosts(f)
+	_, err := costs.SetSMSBudget(ctx, dto.SMSBudgetInput{Flavour: feedlib.FlavourConsumer, MaxCost: 0.1})
+	assert.Nil(t, err)
+
+	invalid := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	flavour := feedlib.Flavour("unknown")
+	invalid.Flavour = &flavour
+	_, err = schedules.ScheduleNotification(ctx, "admin", invalid)
+	assert.NotNil(t, err)
+
+	// the consumer budget is too small for the reminder, which has no
+	// budget without its flavour
+	consumer := getTestScheduleInput(domain.ScheduleRecurrenceOnce, "")
+	flavour = feedlib.FlavourConsumer
+	consumer.Flavour = &flavour
+	budgeted, err := schedules.ScheduleNotification(ctx, "admin", consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, &flavour, budgeted.Flavour)
+	unbudgeted, err := schedules.ScheduleNotification(ctx, "admin", getTestScheduleInput(domain.ScheduleRecurrenceOnce, ""))
+	assert.Nil(t, err)
+
+	count, err := schedules.RunDueNotifications(ctx, unbudgeted.NextRunAt.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Len(t, f.sms.requests, 1)
+
+	got, err := schedules.GetScheduledNotification(ctx, budgeted.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ScheduleStatusFailed, got.Status)
+	assert.Contains(t, got.LastError, "budget")
+
+	got, err = schedules.GetScheduledNotification(ctx, unbudgeted.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ScheduleStatusCompleted, got.Status)
+}
+
+func TestScheduleImpl_RunDueNotifications_ChangedWhileSending(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(s *usecases.ScheduleImpl, ctx context.Context, id string) (*domain.ScheduledNotification, error)
+		wantStatus domain.ScheduleStatus
+	}{
+		{
+			name:       "paused while sending",
+			change:     (*usecases.ScheduleImpl).PauseScheduledNotification,
+			wantStatus: domain.ScheduleStatusPaused,
+		},
+		{
+			name:       "cancelled while sending",
+			change:     (*usecases.ScheduleImpl).CancelScheduledNotification,
+			wantStatus: domain.ScheduleStatusCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newTestFixture(t)
+			schedules, _ := newTestSchedules(f)
+			var id string
+			f.sms.sending = func() {
+				_, err := tt.change(schedules, ctx, id)
+				assert.Nil(t, err)
+			}
+
+			scheduled, err := schedules.ScheduleNotification(
+				ctx,
+				"admin",
+				getTestScheduleInput(domain.ScheduleRecurrenceCron, "0 8 * * *"),
+			)
+			assert.Nil(t, err)
+			id = scheduled.ID
+
+			now := scheduled.NextRunAt.Add(time.Minute)
+			count, err := schedules.RunDueNotifications(ctx, now)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, count)
+
+			got, err := schedules.GetScheduledNotification(ctx, id)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, 1, got.RunCount)
+			assert.Equal(t, now, *got.LastRunAt)
+			if tt.wantStatus == domain.ScheduleStatusCancelled {
+				assert.Nil(t, got.NextRunAt)
+			} else {
+				assert.Equal(t, *scheduled.NextRunAt, *got.NextRunAt)
+			}
+
+			// a notification that is no longer active is not sent again
+			count, err = schedules.RunDueNotifications(ctx, now.AddDate(0, 0, 2))
+			assert.Nil(t, err)
+			assert.Equal(t, 0, count)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/savannahghi/converterandformatter"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+)
+
+// maxSMSRecipients is the most recipients that an SMS is sent to while the
+// caller waits. Larger lists are sent as bulk SMS jobs.
+const maxSMSRecipients = DefaultBulkSMSChunkSize
+
+// SendSMS sends a message to a phone number from a sender ID
+func (n *NotificationImpl) SendSMS(
+	ctx context.Context,
+	to string,
+	message string,
+	sender enumutils.SenderID,
+	flavour *feedlib.Flavour,
+) (*libDto.SendMessageResponse, error) {
+	return n.SendSMSToMany(ctx, message, []string{to}, sender, flavour)
+}
+
+// SendSMSToMany sends a message to phone numbers from a sender ID. The
+// numbers are normalized and each one is only sent the message once.
+// Messages are checked against the SMS budget of their flavour, or the
+// default budget.
+func (n *NotificationImpl) SendSMSToMany(
+	ctx context.Context,
+	message string,
+	to []string,
+	sender enumutils.SenderID,
+	flavour *feedlib.Flavour,
+) (*libDto.SendMessageResponse, error) {
+	if strings.TrimSpace(message) == "" {
+		return nil, fmt.Errorf("an SMS message can't be empty")
+	}
+	if !sender.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid sender ID", sender)
+	}
+	phones, err := normalizeSMSRecipients(to)
+	if err != nil {
+		return nil, err
+	}
+	if len(phones) > maxSMSRecipients {
+		return nil, fmt.Errorf(
+			"an SMS can be sent to up to %d recipients at a time, use sendBulkSMS for more",
+			maxSMSRecipients,
+		)
+	}
+	if err := n.SMSCosts.CheckSMSBudget(ctx, flavour, message, phones); err != nil {
+		return nil, err
+	}
+
+	resp, err := n.SMS.SendToMany(ctx, message, phones, sender)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send SMS: %w", err)
+	}
+	if resp.SMSMessageData == nil {
+		resp.SMSMessageData = &libDto.SMS{Recipients: []libDto.Recipient{}}
+	}
+	return resp, nil
+}
+
+// normalizeSMSRecipients returns the distinct normalized phone numbers of
+// SMS recipients
+func normalizeSMSRecipients(to []string) ([]string, error) {
+	seen := map[string]bool{}
+	phones := []string{}
+	for _, number := range to {
+		phone, err := converterandformatter.NormalizeMSISDN(number)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid phone number: %w", number, err)
+		}
+		if !seen[*phone] {
+			seen[*phone] = true
+			phones = append(phones, *phone)
+		}
+	}
+	if len(phones) == 0 {
+		return nil, fmt.Errorf("an SMS needs at least one recipient")
+	}
+	return phones, nil
+}
@@ -0,0 +1,128 @@
+package usecases_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	smsMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationImpl_SendSMSToMany(t *testing.T) {
+	ctx := context.Background()
+
+	var sentTo []string
+	var sentFrom enumutils.SenderID
+	fakeSMS := &smsMock.FakeServiceSMS{
+		SendToManyFn: func(
+			ctx context.Context,
+			message string,
+			to []string,
+			from enumutils.SenderID,
+		) (*libDto.SendMessageResponse, error) {
+			sentTo = to
+			sentFrom = from
+			recipients := []libDto.Recipient{}
+			for i, number := range to {
+				recipients = append(recipients, libDto.Recipient{
+					Number:    number,
+					Cost:      "KES 0.8000",
+					Status:    "Success",
+					MessageID: fmt.Sprintf("ATXid_%d", i),
+				})
+			}
+			return &libDto.SendMessageResponse{
+				SMSMessageData: &libDto.SMS{Recipients: recipients},
+			}, nil
+		},
+	}
+	n := usecases.NewNotification(nil, nil, nil, nil, nil, newTestFixture(t).smsCosts(), fakeSMS, nil, nil, nil, nil)
+
+	tests := []struct {
+		name           string
+		message        string
+		to             []string
+		sender         enumutils.SenderID
+		wantRecipients []string
+		wantErr        bool
+	}{
+		{
+			name:           "recipients are normalized and deduplicated",
+			message:        "Your results are ready",
+			to:             []string{"0711223344", "+254711223344", "0722334455"},
+			sender:         enumutils.SenderIDSLADE360,
+			wantRecipients: []string{"+254711223344", "+254722334455"},
+		},
+		{
+			name:    "empty message",
+			message: " ",
+			to:      []string{"0711223344"},
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+		{
+			name:    "invalid sender ID",
+			message: "Your results are ready",
+			to:      []string{"0711223344"},
+			sender:  enumutils.SenderID("PIGEON"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid phone number",
+			message: "Your results are ready",
+			to:      []string{"0711223344", "not a phone"},
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+		{
+			name:    "too many recipients",
+			message: "Your results are ready",
+			to:      testPhoneNumbers(101),
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+		{
+			name:    "no recipients",
+			message: "Your results are ready",
+			to:      []string{},
+			sender:  enumutils.SenderIDBewell,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentTo = nil
+			resp, err := n.SendSMSToMany(ctx, tt.message, tt.to, tt.sender, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendSMSToMany() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.Nil(t, sentTo)
+				return
+			}
+			assert.Equal(t, tt.wantRecipients, sentTo)
+			assert.Equal(t, tt.sender, sentFrom)
+			assert.Len(t, resp.SMSMessageData.Recipients, len(tt.wantRecipients))
+			assert.Equal(t, "ATXid_0", resp.SMSMessageData.Recipients[0].MessageID)
+		})
+	}
+}
+
+func TestNotificationImpl_SendSMS_Local(t *testing.T) {
+	n := usecases.NewNotification(nil, nil, nil, nil, nil, newTestFixture(t).smsCosts(), sms.NewLocalService(), nil, nil, nil, nil)
+
+	resp, err := n.SendSMS(context.Background(), "0711223344", "Your results are ready", enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+	assert.Len(t, resp.SMSMessageData.Recipients, 1)
+	recipient := resp.SMSMessageData.Recipients[0]
+	assert.Equal(t, "+254711223344", recipient.Number)
+	assert.Equal(t, "Success", recipient.Status)
+	assert.NotEmpty(t, recipient.MessageID)
+	assert.NotEmpty(t, recipient.Cost)
+}
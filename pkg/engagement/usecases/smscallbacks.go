@@ -0,0 +1,469 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/sms"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// SMSDeliveryReportsPath is the path of the endpoint that the SMS
+	// provider posts delivery reports to
+	SMSDeliveryReportsPath = "/sms/delivery_reports"
+
+	// InboundSMSPath is the path of the endpoint that the SMS provider posts
+	// received messages to
+	InboundSMSPath = "/sms/inbound"
+
+	// SMSCallbackSignatureParam is the query parameter that carries the
+	// signature of a callback URL
+	SMSCallbackSignatureParam = "signature"
+
+	// SMSConversationExpiry is how long replies from a phone number are
+	// posted to the feed item that it was texted about
+	SMSConversationExpiry = 7 * 24 * time.Hour
+
+	// smsOptedOutStatus is reported for recipients that were not sent a
+	// message because they opted out of SMS
+	smsOptedOutStatus = "OptedOut"
+
+	smsOptOutConfirmation = "You have been unsubscribed from Be.Well SMS. Reply START to subscribe again."
+	smsOptInConfirmation  = "You have been subscribed to Be.Well SMS. Reply STOP to unsubscribe."
+)
+
+// smsOptOutKeywords and smsOptInKeywords are the messages that change whether
+// a phone number is sent SMS. Matching ignores case and surrounding
+// punctuation.
+var (
+	smsOptOutKeywords = map[string]bool{
+		"STOP": true, "STOPALL": true, "UNSUBSCRIBE": true,
+		"CANCEL": true, "END": true, "QUIT": true,
+	}
+	smsOptInKeywords = map[string]bool{
+		"START": true, "UNSTOP": true, "SUBSCRIBE": true, "YES": true,
+	}
+)
+
+// SMSCallbackConfig holds the key that SMS provider callback URLs are signed
+// with
+type SMSCallbackConfig struct {
+	// SigningKey signs the callback URLs that are registered with the SMS
+	// provider. The provider can't sign its requests so the signature is
+	// part of the URL and is kept secret like a password.
+	SigningKey []byte
+}
+
+// FeedMessages posts messages to the conversation of a feed item
+type FeedMessages interface {
+	PostMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		message *feedlib.Message,
+	) (*feedlib.Message, error)
+}
+
+// SMSCallbackUsecases represent logic required to handle the delivery reports
+// and received messages that the SMS provider posts to this service
+type SMSCallbackUsecases interface {
+	// VerifySMSCallbackSignature checks that a callback was posted to a
+	// signed URL. It returns exceptions.ErrInvalidSMSCallbackSignature when
+	// the signature does not match.
+	VerifySMSCallbackSignature(path string, signature string) error
+
+	// HandleSMSDeliveryReport saves the latest delivery status of a message
+	HandleSMSDeliveryReport(ctx context.Context, report *domain.SMSDeliveryReport) error
+
+	// HandleInboundSMS applies opt out and opt in keywords and posts other
+	// messages to the conversation that the sender is in
+	HandleInboundSMS(ctx context.Context, message *domain.InboundSMS) (*domain.InboundSMS, error)
+
+	// StartSMSConversation texts a phone number about a feed item
+	StartSMSConversation(
+		ctx context.Context,
+		createdBy string,
+		input dto.SMSConversationInput,
+	) (*domain.SMSConversation, error)
+
+	GetSMSDeliveryReport(ctx context.Context, messageID string) (*domain.SMSDeliveryReport, error)
+
+	// GetSMSChannelPreference returns whether a phone number has opted out
+	// of SMS. Numbers that have not sent a keyword are opted in.
+	GetSMSChannelPreference(ctx context.Context, phone string) (*domain.ChannelPreference, error)
+}
+
+// SMSCallbackImpl represents the SMS callback usecase implementation
+type SMSCallbackImpl struct {
+	Repository repository.Repository
+	SMS        sms.ServiceSMS
+	Feed       FeedMessages
+	Inbox      InboxUsecases
+	Config     SMSCallbackConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewSMSCallbacks initializes an SMS callback usecase. Opt out confirmations
+// are sent with the SMS service so it should not filter opted out numbers.
+func NewSMSCallbacks(
+	repository repository.Repository,
+	sms sms.ServiceSMS,
+	feed FeedMessages,
+	inbox InboxUsecases,
+	config SMSCallbackConfig,
+) (*SMSCallbackImpl, error) {
+	if len(config.SigningKey) < 32 {
+		return nil, fmt.Errorf("the SMS callback signing key must be at least 32 bytes")
+	}
+	return &SMSCallbackImpl{
+		Repository: repository,
+		SMS:        sms,
+		Feed:       feed,
+		Inbox:      inbox,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SignSMSCallbackPath returns the signature of a callback path. The URL that
+// is registered with the SMS provider is the path with the signature in its
+// `signature` query parameter.
+func SignSMSCallbackPath(key []byte, path string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySMSCallbackSignature compares a signature with the signature of a path
+// in constant time
+func (s *SMSCallbackImpl) VerifySMSCallbackSignature(path string, signature string) error {
+	want := SignSMSCallbackPath(s.Config.SigningKey, path)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(signature))) {
+		return exceptions.ErrInvalidSMSCallbackSignature
+	}
+	return nil
+}
+
+// HandleSMSDeliveryReport saves a delivery report and updates the bulk SMS
+// recipient that was sent the message, if any
+func (s *SMSCallbackImpl) HandleSMSDeliveryReport(ctx context.Context, report *domain.SMSDeliveryReport) error {
+	if report.MessageID == "" {
+		return fmt.Errorf("a delivery report needs a message ID")
+	}
+	if report.Status == "" {
+		return fmt.Errorf("a delivery report needs a status")
+	}
+	if phone, err := converterandformatter.NormalizeMSISDN(report.Number); err == nil {
+		report.Number = *phone
+	}
+	report.ReceivedAt = s.Clock()
+
+	if err := s.Repository.SaveSMSDeliveryReport(ctx, report); err != nil {
+		return fmt.Errorf("unable to save SMS delivery report: %w", err)
+	}
+	_, err := s.Repository.UpdateBulkSMSRecipientDelivery(ctx, report.MessageID, report.Status, report.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("unable to update bulk SMS recipient: %w", err)
+	}
+	return nil
+}
+
+// HandleInboundSMS saves a received message after acting on it.
+//
+// Opt out and opt in keywords update the sender's SMS preference and are
+// confirmed by SMS. Other messages are posted to the feed item that the sender
+// was last texted about, while that conversation has not expired.
+func (s *SMSCallbackImpl) HandleInboundSMS(
+	ctx context.Context,
+	message *domain.InboundSMS,
+) (*domain.InboundSMS, error) {
+	if message.ID == "" {
+		return nil, fmt.Errorf("a received SMS needs an ID")
+	}
+	phone, err := converterandformatter.NormalizeMSISDN(message.From)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid phone number: %w", message.From, err)
+	}
+	message.From = *phone
+	message.ReceivedAt = s.Clock()
+
+	keyword := strings.ToUpper(strings.Trim(message.Text, " \t\r\n.!"))
+	switch {
+	case smsOptOutKeywords[keyword], smsOptInKeywords[keyword]:
+		message.Keyword = keyword
+		if err := s.applyKeyword(ctx, message.From, keyword); err != nil {
+			return nil, err
+		}
+
+	default:
+		conversationID, err := s.postReply(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		message.ConversationID = conversationID
+	}
+
+	if err := s.Repository.SaveInboundSMS(ctx, message); err != nil {
+		return nil, fmt.Errorf("unable to save received SMS: %w", err)
+	}
+	return message, nil
+}
+
+// applyKeyword saves the SMS preference that a keyword sets and confirms it
+func (s *SMSCallbackImpl) applyKeyword(ctx context.Context, phone string, keyword string) error {
+	optedOut := smsOptOutKeywords[keyword]
+	err := s.Repository.SaveChannelPreference(ctx, &domain.ChannelPreference{
+		Channel:   feedlib.ChannelSms,
+		Address:   phone,
+		OptedOut:  optedOut,
+		Source:    "SMS keyword " + keyword,
+		UpdatedAt: s.Clock(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save SMS preference: %w", err)
+	}
+
+	confirmation := smsOptInConfirmation
+	if optedOut {
+		confirmation = smsOptOutConfirmation
+	}
+	// the preference is saved so a failed confirmation is not retried
+	if _, err := s.SMS.Send(ctx, phone, confirmation, enumutils.SenderIDBewell); err != nil {
+		log.Printf("unable to confirm SMS keyword %s: %v", keyword, err)
+	}
+	return nil
+}
+
+// postReply posts a message to the sender's active conversation and returns
+// the conversation's ID. Messages from senders that are not in a
+// conversation are only saved.
+func (s *SMSCallbackImpl) postReply(ctx context.Context, message *domain.InboundSMS) (string, error) {
+	conversation, err := s.Repository.GetActiveSMSConversation(ctx, message.From, message.ReceivedAt)
+	if errors.Is(err, exceptions.ErrSMSConversationNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get SMS conversation: %w", err)
+	}
+
+	// the message ID is derived from the provider's ID so that a callback
+	// that is retried replaces the message instead of posting it again
+	_, err = s.Feed.PostMessage(ctx, conversation.UID, conversation.Flavour, conversation.ItemID, &feedlib.Message{
+		ID:             "sms-" + message.ID,
+		SequenceNumber: int(message.ReceivedAt.Unix()),
+		Text:           message.Text,
+		PostedByUID:    conversation.UID,
+		PostedByName:   message.From,
+		Timestamp:      message.ReceivedAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to post SMS reply: %w", err)
+	}
+	return conversation.ID, nil
+}
+
+// StartSMSConversation sends a message to a phone number and records the feed
+// item that its replies belong to
+func (s *SMSCallbackImpl) StartSMSConversation(
+	ctx context.Context,
+	createdBy string,
+	input dto.SMSConversationInput,
+) (*domain.SMSConversation, error) {
+	if strings.TrimSpace(input.Message) == "" {
+		return nil, fmt.Errorf("an SMS message can't be empty")
+	}
+	if !input.Sender.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid sender ID", input.Sender)
+	}
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid flavour", input.Flavour)
+	}
+	if input.UID == "" || input.ItemID == "" {
+		return nil, fmt.Errorf("an SMS conversation needs a UID and an item ID")
+	}
+	phone, err := converterandformatter.NormalizeMSISDN(input.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid phone number: %w", input.Phone, err)
+	}
+
+	preference, err := s.GetSMSChannelPreference(ctx, *phone)
+	if err != nil {
+		return nil, err
+	}
+	if preference.OptedOut {
+		return nil, fmt.Errorf("%s has opted out of SMS", *phone)
+	}
+
+	resp, err := s.SMS.Send(ctx, *phone, input.Message, input.Sender)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send SMS: %w", err)
+	}
+
+	now := s.Clock()
+	conversation := &domain.SMSConversation{
+		ID:        ksuid.New().String(),
+		Phone:     *phone,
+		UID:       input.UID,
+		Flavour:   input.Flavour,
+		ItemID:    input.ItemID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(SMSConversationExpiry),
+	}
+	if err := s.Repository.SaveSMSConversation(ctx, conversation); err != nil {
+		return nil, fmt.Errorf("unable to save SMS conversation: %w", err)
+	}
+
+	messageID := ""
+	if resp != nil && resp.SMSMessageData != nil && len(resp.SMSMessageData.Recipients) > 0 {
+		messageID = resp.SMSMessageData.Recipients[0].MessageID
+	}
+	recordDispatch(ctx, s.Inbox, &domain.InboxNotification{
+		UID:       conversation.UID,
+		Channel:   feedlib.ChannelSms,
+		MessageID: messageID,
+		Body:      input.Message,
+		Data: map[string]interface{}{
+			"flavour":        string(conversation.Flavour),
+			"itemID":         conversation.ItemID,
+			"conversationID": conversation.ID,
+		},
+		CreatedAt: now,
+	})
+	return conversation, nil
+}
+
+// GetSMSDeliveryReport retrieves the latest delivery report of a message
+func (s *SMSCallbackImpl) GetSMSDeliveryReport(
+	ctx context.Context,
+	messageID string,
+) (*domain.SMSDeliveryReport, error) {
+	return s.Repository.GetSMSDeliveryReport(ctx, messageID)
+}
+
+// GetSMSChannelPreference retrieves the SMS preference of a phone number
+func (s *SMSCallbackImpl) GetSMSChannelPreference(
+	ctx context.Context,
+	phone string,
+) (*domain.ChannelPreference, error) {
+	number, err := converterandformatter.NormalizeMSISDN(phone)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid phone number: %w", phone, err)
+	}
+	return smsChannelPreference(ctx, s.Repository, *number)
+}
+
+// smsChannelPreference returns the saved preference of a normalized phone
+// number, or an opted in preference when none is saved
+func smsChannelPreference(
+	ctx context.Context,
+	repository repository.SMSCallbackRepository,
+	phone string,
+) (*domain.ChannelPreference, error) {
+	preference, err := repository.GetChannelPreference(ctx, feedlib.ChannelSms, phone)
+	if errors.Is(err, exceptions.ErrChannelPreferenceNotFound) {
+		return &domain.ChannelPreference{Channel: feedlib.ChannelSms, Address: phone}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get SMS preference: %w", err)
+	}
+	return preference, nil
+}
+
+// SMSOptOutFilter is an SMS service that does not send messages to phone
+// numbers that have opted out of SMS. Opted out numbers are reported as
+// recipients with the `OptedOut` status.
+type SMSOptOutFilter struct {
+	Repository repository.SMSCallbackRepository
+	SMS        sms.ServiceSMS
+}
+
+// NewSMSOptOutFilter wraps an SMS service so that opted out phone numbers are
+// skipped
+func NewSMSOptOutFilter(repository repository.SMSCallbackRepository, sms sms.ServiceSMS) *SMSOptOutFilter {
+	return &SMSOptOutFilter{Repository: repository, SMS: sms}
+}
+
+// Send sends a message to a phone number unless it has opted out. Like the
+// SMS service, it accepts several comma separated numbers, each of which is
+// checked.
+func (f *SMSOptOutFilter) Send(
+	ctx context.Context,
+	to string,
+	message string,
+	from enumutils.SenderID,
+) (*libDto.SendMessageResponse, error) {
+	numbers := []string{}
+	for _, number := range strings.Split(to, ",") {
+		if number = strings.TrimSpace(number); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return f.SendToMany(ctx, message, numbers, from)
+}
+
+// SendToMany sends a message to the phone numbers that have not opted out
+func (f *SMSOptOutFilter) SendToMany(
+	ctx context.Context,
+	message string,
+	to []string,
+	from enumutils.SenderID,
+) (*libDto.SendMessageResponse, error) {
+	phones := make([]string, len(to))
+	for i, number := range to {
+		phones[i] = number
+		if normalized, err := converterandformatter.NormalizeMSISDN(number); err == nil {
+			phones[i] = *normalized
+		}
+	}
+	preferences, err := f.Repository.ListChannelPreferences(ctx, feedlib.ChannelSms, phones)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get SMS preferences: %w", err)
+	}
+	optedOut := map[string]bool{}
+	for _, preference := range preferences {
+		optedOut[preference.Address] = preference.OptedOut
+	}
+
+	allowed := []string{}
+	skipped := []libDto.Recipient{}
+	for i, number := range to {
+		if optedOut[phones[i]] {
+			skipped = append(skipped, libDto.Recipient{Number: number, Status: smsOptedOutStatus})
+			continue
+		}
+		allowed = append(allowed, number)
+	}
+
+	resp := &libDto.SendMessageResponse{SMSMessageData: &libDto.SMS{Recipients: []libDto.Recipient{}}}
+	if len(allowed) > 0 {
+		sent, err := f.SMS.SendToMany(ctx, message, allowed, from)
+		if err != nil {
+			return nil, err
+		}
+		if sent.SMSMessageData != nil {
+			resp = sent
+		}
+	}
+	resp.SMSMessageData.Recipients = append(resp.SMSMessageData.Recipients, skipped...)
+	return resp, nil
+}
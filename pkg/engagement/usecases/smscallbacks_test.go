@@ -0,0 +1,262 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSMSCallbackKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestSMSCallbacks(f *testFixture) *usecases.SMSCallbackImpl {
+	s, err := usecases.NewSMSCallbacks(
+		f.repository,
+		f.smsService(),
+		f.feed,
+		usecases.NewInbox(f.repository),
+		usecases.SMSCallbackConfig{SigningKey: testSMSCallbackKey},
+	)
+	if err != nil {
+		f.t.Fatalf("unable to initialize SMS callbacks: %v", err)
+	}
+	s.Clock = f.clock.Now
+	return s
+}
+
+func TestNewSMSCallbacks(t *testing.T) {
+	_, err := usecases.NewSMSCallbacks(nil, nil, nil, nil, usecases.SMSCallbackConfig{SigningKey: []byte("short")})
+	assert.Error(t, err)
+}
+
+func TestSMSCallbackImpl_VerifySMSCallbackSignature(t *testing.T) {
+	s := newTestSMSCallbacks(newTestFixture(t))
+	signature := usecases.SignSMSCallbackPath(testSMSCallbackKey, usecases.InboundSMSPath)
+
+	tests := []struct {
+		name      string
+		path      string
+		signature string
+		wantErr   bool
+	}{
+		{
+			name:      "signed path",
+			path:      usecases.InboundSMSPath,
+			signature: signature,
+		},
+		{
+			name:      "signature of another path",
+			path:      usecases.SMSDeliveryReportsPath,
+			signature: signature,
+			wantErr:   true,
+		},
+		{
+			name:    "missing signature",
+			path:    usecases.InboundSMSPath,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.VerifySMSCallbackSignature(tt.path, tt.signature)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, exceptions.ErrInvalidSMSCallbackSignature))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSMSCallbackImpl_HandleSMSDeliveryReport(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	s := newTestSMSCallbacks(f)
+
+	err := f.repository.SaveBulkSMSRecipients(ctx, []*domain.BulkSMSRecipient{{
+		JobID:     "job",
+		Number:    "+254711223344",
+		Status:    domain.BulkSMSRecipientStatusSent,
+		MessageID: "ATXid_1",
+	}})
+	assert.NoError(t, err)
+
+	err = s.HandleSMSDeliveryReport(ctx, &domain.SMSDeliveryReport{
+		MessageID:     "ATXid_1",
+		Number:        "0711223344",
+		Status:        "Failed",
+		FailureReason: "AbsentSubscriber",
+	})
+	assert.NoError(t, err)
+
+	report, err := s.GetSMSDeliveryReport(ctx, "ATXid_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "+254711223344", report.Number)
+	assert.Equal(t, "AbsentSubscriber", report.FailureReason)
+
+	recipients, err := f.repository.ListBulkSMSRecipients(ctx, "job", nil, -1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, recipients, 1)
+	assert.Equal(t, "Failed", recipients[0].DeliveryStatus)
+	assert.NotNil(t, recipients[0].DeliveryUpdatedAt)
+
+	// reports of messages that were not sent in bulk are still saved
+	err = s.HandleSMSDeliveryReport(ctx, &domain.SMSDeliveryReport{MessageID: "ATXid_2", Status: "Success"})
+	assert.NoError(t, err)
+
+	err = s.HandleSMSDeliveryReport(ctx, &domain.SMSDeliveryReport{Status: "Success"})
+	assert.Error(t, err)
+}
+
+func TestSMSCallbackImpl_HandleInboundSMS_Keywords(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	s := newTestSMSCallbacks(f)
+
+	tests := []struct {
+		name         string
+		text         string
+		wantKeyword  string
+		wantOptedOut bool
+	}{
+		{
+			name:         "stop opts out",
+			text:         " stop. ",
+			wantKeyword:  "STOP",
+			wantOptedOut: true,
+		},
+		{
+			name:         "start opts back in",
+			text:         "START",
+			wantKeyword:  "START",
+			wantOptedOut: false,
+		},
+		{
+			name:         "unsubscribe opts out",
+			text:         "Unsubscribe",
+			wantKeyword:  "UNSUBSCRIBE",
+			wantOptedOut: true,
+		},
+		{
+			name:         "other messages leave the preference",
+			text:         "please stop the reminders",
+			wantOptedOut: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmations := len(f.sms.to)
+			message, err := s.HandleInboundSMS(ctx, &domain.InboundSMS{
+				ID:   "ATXid_in_" + string(rune('a'+i)),
+				From: "0711223344",
+				To:   "40000",
+				Text: tt.text,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantKeyword, message.Keyword)
+
+			preference, err := s.GetSMSChannelPreference(ctx, "+254711223344")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOptedOut, preference.OptedOut)
+
+			if tt.wantKeyword != "" {
+				assert.Len(t, f.sms.to, confirmations+1)
+			} else {
+				assert.Len(t, f.sms.to, confirmations)
+			}
+		})
+	}
+	assert.Empty(t, f.feed.posted)
+}
+
+func TestSMSCallbackImpl_HandleInboundSMS_Replies(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	s := newTestSMSCallbacks(f)
+
+	conversation, err := s.StartSMSConversation(ctx, "nurse", dto.SMSConversationInput{
+		Phone:   "0711223344",
+		UID:     "patient",
+		Flavour: feedlib.FlavourConsumer,
+		ItemID:  "item",
+		Message: "How are you feeling today?",
+		Sender:  enumutils.SenderIDBewell,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+254711223344"}, f.sms.to)
+
+	message, err := s.HandleInboundSMS(ctx, &domain.InboundSMS{ID: "ATXid_in_1", From: "+254711223344", Text: "Much better"})
+	assert.NoError(t, err)
+	assert.Equal(t, conversation.ID, message.ConversationID)
+	assert.Len(t, f.feed.posted, 1)
+	assert.Equal(t, "Much better", f.feed.posted[0].Text)
+	assert.Equal(t, "patient/CONSUMER/item", f.feed.items[0])
+
+	// replies from other numbers are only saved
+	message, err = s.HandleInboundSMS(ctx, &domain.InboundSMS{ID: "ATXid_in_2", From: "0722334455", Text: "Hello"})
+	assert.NoError(t, err)
+	assert.Empty(t, message.ConversationID)
+
+	// replies after the conversation expires are only saved
+	f.clock.Advance(usecases.SMSConversationExpiry + time.Minute)
+	message, err = s.HandleInboundSMS(ctx, &domain.InboundSMS{ID: "ATXid_in_3", From: "0711223344", Text: "Thanks"})
+	assert.NoError(t, err)
+	assert.Empty(t, message.ConversationID)
+	assert.Len(t, f.feed.posted, 1)
+
+	// opted out numbers can't be texted
+	_, err = s.HandleInboundSMS(ctx, &domain.InboundSMS{ID: "ATXid_in_4", From: "0711223344", Text: "STOP"})
+	assert.NoError(t, err)
+	_, err = s.StartSMSConversation(ctx, "nurse", dto.SMSConversationInput{
+		Phone:   "0711223344",
+		UID:     "patient",
+		Flavour: feedlib.FlavourConsumer,
+		ItemID:  "item",
+		Message: "Are you there?",
+		Sender:  enumutils.SenderIDBewell,
+	})
+	assert.Error(t, err)
+}
+
+func TestSMSOptOutFilter_SendToMany(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	filter := usecases.NewSMSOptOutFilter(f.repository, f.smsService())
+
+	err := f.repository.SaveChannelPreference(ctx, &domain.ChannelPreference{
+		Channel:  feedlib.ChannelSms,
+		Address:  "+254711223344",
+		OptedOut: true,
+	})
+	assert.NoError(t, err)
+
+	resp, err := filter.SendToMany(ctx, "The clinic is closed", []string{"0711223344", "+254722334455"}, enumutils.SenderIDBewell)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+254722334455"}, f.sms.to)
+
+	statuses := map[string]string{}
+	for _, recipient := range resp.SMSMessageData.Recipients {
+		statuses[recipient.Number] = recipient.Status
+	}
+	assert.Equal(t, map[string]string{"0711223344": "OptedOut", "+254722334455": "Success"}, statuses)
+
+	// nothing is sent when every number has opted out
+	resp, err = filter.Send(ctx, "+254711223344", "The clinic is closed", enumutils.SenderIDBewell)
+	assert.NoError(t, err)
+	assert.Len(t, f.sms.to, 1)
+	assert.Len(t, resp.SMSMessageData.Recipients, 1)
+
+	// each of several comma separated numbers is checked
+	resp, err = filter.Send(ctx, "+254733000000, 0711223344", "The clinic is closed", enumutils.SenderIDBewell)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+254733000000"}, f.sms.requests[len(f.sms.requests)-1])
+	assert.Len(t, resp.SMSMessageData.Recipients, 2)
+}
@@ -0,0 +1,362 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/feedlib"
+)
+
+const (
+	// SMSCostCurrency is the currency that the SMS provider bills in. Rates,
+	// budgets and estimates are in this currency.
+	SMSCostCurrency = "KES"
+
+	// the number of characters that fit in a single segment and in each
+	// segment of a longer message, for each encoding
+	gsm7SingleSegment = 160
+	gsm7MultiSegment  = 153
+	ucs2SingleSegment = 70
+	ucs2MultiSegment  = 67
+)
+
+// smsRatePrefixPattern matches the international prefixes that rates are set
+// for e.g `+254` or `+2547`
+var smsRatePrefixPattern = regexp.MustCompile(`^\+[0-9]{1,7}$`)
+
+// gsm7Basic and gsm7Extension are the characters of the GSM 03.38 alphabet.
+// Characters in the extension table are sent as an escape and the character
+// so they take two septets.
+var (
+	gsm7Basic     = map[rune]bool{}
+	gsm7Extension = map[rune]bool{}
+)
+
+func init() {
+	basic := "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	for _, r := range basic {
+		gsm7Basic[r] = true
+	}
+	for _, r := range "\f^{}\\[~]|€" {
+		gsm7Extension[r] = true
+	}
+}
+
+// SMSCostUsecases represent logic required to estimate what an SMS will cost
+// before it is sent, and to refuse sends that are over a flavour's budget
+type SMSCostUsecases interface {
+	// EstimateSMS works out the encoding, segments and cost of a message to
+	// phone numbers. The estimate is compared with the flavour's budget, or
+	// with the default budget when no flavour is given.
+	EstimateSMS(
+		ctx context.Context,
+		message string,
+		to []string,
+		flavour *feedlib.Flavour,
+	) (*domain.SMSEstimate, error)
+
+	// CheckSMSBudget returns an error wrapping
+	// exceptions.ErrSMSBudgetExceeded when a message to phone numbers is
+	// over the budget of a flavour, or over the default budget when no
+	// flavour is given
+	CheckSMSBudget(
+		ctx context.Context,
+		flavour *feedlib.Flavour,
+		message string,
+		to []string,
+	) error
+
+	SetSMSRate(ctx context.Context, input dto.SMSRateInput) (*domain.SMSRate, error)
+
+	ListSMSRates(ctx context.Context) ([]*domain.SMSRate, error)
+
+	DeleteSMSRate(ctx context.Context, prefix string) (bool, error)
+
+	SetSMSBudget(ctx context.Context, input dto.SMSBudgetInput) (*domain.SMSBudget, error)
+
+	ListSMSBudgets(ctx context.Context) ([]*domain.SMSBudget, error)
+
+	DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) (bool, error)
+}
+
+// SMSCostConfig holds the budget of sends that are not made for a flavour
+type SMSCostConfig struct {
+	// DefaultBudget is the most that a single send without a flavour, or
+	// of a flavour that has no budget, may cost. Zero leaves them
+	// unbudgeted.
+	DefaultBudget float64
+}
+
+// SMSCostImpl represents the SMS cost usecase implementation
+type SMSCostImpl struct {
+	Repository repository.SMSCostRepository
+	Config     SMSCostConfig
+}
+
+// NewSMSCosts initializes an SMS cost usecase
+func NewSMSCosts(repository repository.SMSCostRepository, config SMSCostConfig) (*SMSCostImpl, error) {
+	if config.DefaultBudget < 0 {
+		return nil, fmt.Errorf("the default SMS budget can't be negative")
+	}
+	return &SMSCostImpl{
+		Repository: repository,
+		Config:     config,
+	}, nil
+}
+
+// EstimateSMS prices a message to each phone number with the rate of the
+// longest matching prefix.
+//
+// Numbers that no rate matches are not priced. An estimate with unpriced
+// numbers is never within a budget since its cost is not known.
+func (c *SMSCostImpl) EstimateSMS(
+	ctx context.Context,
+	message string,
+	to []string,
+	flavour *feedlib.Flavour,
+) (*domain.SMSEstimate, error) {
+	if message == "" {
+		return nil, fmt.Errorf("an SMS message can't be empty")
+	}
+	phones, err := normalizeSMSRecipients(to)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := c.Repository.ListSMSRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list SMS rates: %w", err)
+	}
+
+	encoding, length, segments := smsSegments(message)
+	estimate := &domain.SMSEstimate{
+		Encoding:     encoding,
+		Length:       length,
+		Segments:     segments,
+		Recipients:   []*domain.SMSRecipientEstimate{},
+		Currency:     SMSCostCurrency,
+		WithinBudget: true,
+	}
+	priced := true
+	for _, phone := range phones {
+		recipient := &domain.SMSRecipientEstimate{Number: phone}
+		if rate := matchSMSRate(rates, phone); rate != nil {
+			recipient.Prefix = rate.Prefix
+			recipient.Country = rate.Country
+			recipient.Cost = roundSMSCost(rate.CostPerSegment * float64(segments))
+			recipient.Priced = true
+			estimate.TotalCost += recipient.Cost
+		} else {
+			priced = false
+		}
+		estimate.Recipients = append(estimate.Recipients, recipient)
+	}
+	estimate.TotalCost = roundSMSCost(estimate.TotalCost)
+
+	budget, err := c.budget(ctx, flavour)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return estimate, nil
+	}
+	estimate.Budget = budget
+	estimate.WithinBudget = priced && estimate.TotalCost <= *budget
+	return estimate, nil
+}
+
+// budget returns the budget of a flavour, or the default budget when no
+// flavour is given or the flavour has no budget. It returns nil when there
+// is no budget.
+func (c *SMSCostImpl) budget(ctx context.Context, flavour *feedlib.Flavour) (*float64, error) {
+	if flavour != nil {
+		budget, err := c.Repository.GetSMSBudget(ctx, *flavour)
+		if err == nil {
+			return &budget.MaxCost, nil
+		}
+		if !errors.Is(err, exceptions.ErrSMSBudgetNotFound) {
+			return nil, fmt.Errorf("unable to get the SMS budget of %s: %w", *flavour, err)
+		}
+	}
+	if c.Config.DefaultBudget == 0 {
+		return nil, nil
+	}
+	budget := c.Config.DefaultBudget
+	return &budget, nil
+}
+
+// CheckSMSBudget estimates a message against the budget of a flavour, or the
+// default budget
+func (c *SMSCostImpl) CheckSMSBudget(
+	ctx context.Context,
+	flavour *feedlib.Flavour,
+	message string,
+	to []string,
+) error {
+	estimate, err := c.EstimateSMS(ctx, message, to, flavour)
+	if err != nil {
+		return err
+	}
+	if estimate.WithinBudget {
+		return nil
+	}
+	for _, recipient := range estimate.Recipients {
+		if !recipient.Priced {
+			return fmt.Errorf(
+				"%w: there is no SMS rate for %s so the cost can't be checked",
+				exceptions.ErrSMSBudgetExceeded,
+				recipient.Number,
+			)
+		}
+	}
+	name := "default"
+	if flavour != nil {
+		name = flavour.String()
+	}
+	return fmt.Errorf(
+		"%w: the estimated cost of %s %.4f is over the %s budget of %s %.4f",
+		exceptions.ErrSMSBudgetExceeded,
+		estimate.Currency,
+		estimate.TotalCost,
+		name,
+		estimate.Currency,
+		*estimate.Budget,
+	)
+}
+
+// SetSMSRate validates and saves the rate of a prefix
+func (c *SMSCostImpl) SetSMSRate(ctx context.Context, input dto.SMSRateInput) (*domain.SMSRate, error) {
+	if !smsRatePrefixPattern.MatchString(input.Prefix) {
+		return nil, fmt.Errorf("%s is not a valid prefix, e.g +254", input.Prefix)
+	}
+	if input.CostPerSegment < 0 {
+		return nil, fmt.Errorf("the cost per segment can't be negative")
+	}
+	return c.Repository.SaveSMSRate(ctx, &domain.SMSRate{
+		Prefix:         input.Prefix,
+		Country:        strings.TrimSpace(input.Country),
+		CostPerSegment: input.CostPerSegment,
+		UpdatedAt:      time.Now(),
+	})
+}
+
+// ListSMSRates returns the rate table ordered by prefix
+func (c *SMSCostImpl) ListSMSRates(ctx context.Context) ([]*domain.SMSRate, error) {
+	return c.Repository.ListSMSRates(ctx)
+}
+
+// DeleteSMSRate removes the rate of a prefix
+func (c *SMSCostImpl) DeleteSMSRate(ctx context.Context, prefix string) (bool, error) {
+	err := c.Repository.DeleteSMSRate(ctx, prefix)
+	if err != nil && !errors.Is(err, exceptions.ErrSMSRateNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetSMSBudget validates and saves the budget of a flavour
+func (c *SMSCostImpl) SetSMSBudget(ctx context.Context, input dto.SMSBudgetInput) (*domain.SMSBudget, error) {
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("invalid flavour %s", input.Flavour)
+	}
+	if input.MaxCost < 0 {
+		return nil, fmt.Errorf("the budget can't be negative")
+	}
+	return c.Repository.SaveSMSBudget(ctx, &domain.SMSBudget{
+		Flavour:   input.Flavour,
+		MaxCost:   input.MaxCost,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// ListSMSBudgets returns the budgets that have been set
+func (c *SMSCostImpl) ListSMSBudgets(ctx context.Context) ([]*domain.SMSBudget, error) {
+	return c.Repository.ListSMSBudgets(ctx)
+}
+
+// DeleteSMSBudget removes the budget of a flavour so that its sends are only
+// limited by the default budget
+func (c *SMSCostImpl) DeleteSMSBudget(ctx context.Context, flavour feedlib.Flavour) (bool, error) {
+	err := c.Repository.DeleteSMSBudget(ctx, flavour)
+	if err != nil && !errors.Is(err, exceptions.ErrSMSBudgetNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// matchSMSRate returns the rate with the longest prefix of a phone number
+func matchSMSRate(rates []*domain.SMSRate, phone string) *domain.SMSRate {
+	var match *domain.SMSRate
+	for _, rate := range rates {
+		if !strings.HasPrefix(phone, rate.Prefix) {
+			continue
+		}
+		if match == nil || len(rate.Prefix) > len(match.Prefix) {
+			match = rate
+		}
+	}
+	return match
+}
+
+// smsSegments returns the encoding of a message, its length in the units of
+// the encoding and the number of segments that it is sent in.
+//
+// A character is never split across segments: GSM-7 escapes stay with their
+// character and UCS-2 surrogate pairs stay together.
+func smsSegments(message string) (domain.SMSEncoding, int, int) {
+	units := []int{}
+	encoding := domain.SMSEncodingGSM7
+	for _, r := range message {
+		if gsm7Basic[r] {
+			units = append(units, 1)
+		} else if gsm7Extension[r] {
+			units = append(units, 2)
+		} else {
+			encoding = domain.SMSEncodingUCS2
+			break
+		}
+	}
+
+	single, multi := gsm7SingleSegment, gsm7MultiSegment
+	if encoding == domain.SMSEncodingUCS2 {
+		single, multi = ucs2SingleSegment, ucs2MultiSegment
+		units = []int{}
+		for _, r := range message {
+			units = append(units, len(utf16.Encode([]rune{r})))
+		}
+	}
+
+	length := 0
+	for _, u := range units {
+		length += u
+	}
+	if length <= single {
+		return encoding, length, 1
+	}
+
+	segments, used := 1, 0
+	for _, u := range units {
+		if used+u > multi {
+			segments++
+			used = 0
+		}
+		used += u
+	}
+	return encoding, length, segments
+}
+
+// roundSMSCost rounds a cost to the 4 decimal places that the SMS provider
+// reports costs in
+func roundSMSCost(cost float64) float64 {
+	return math.Round(cost*10000) / 10000
+}
@@ -0,0 +1,262 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSMSCosts(f *testFixture) *usecases.SMSCostImpl {
+	ctx := context.Background()
+	costs := f.smsCosts()
+	for _, rate := range []dto.SMSRateInput{
+		{Prefix: "+254", Country: "Kenya", CostPerSegment: 0.8},
+		{Prefix: "+2547", Country: "Kenya (mobile)", CostPerSegment: 0.5},
+		{Prefix: "+256", Country: "Uganda", CostPerSegment: 2.25},
+	} {
+		_, err := costs.SetSMSRate(ctx, rate)
+		assert.Nil(f.t, err)
+	}
+	return costs
+}
+
+func TestSMSCostImpl_EstimateSMS_Segments(t *testing.T) {
+	ctx := context.Background()
+	costs := newTestSMSCosts(newTestFixture(t))
+
+	tests := []struct {
+		name         string
+		message      string
+		wantEncoding domain.SMSEncoding
+		wantLength   int
+		wantSegments int
+	}{
+		{
+			name:         "single GSM-7 segment",
+			message:      strings.Repeat("a", 160),
+			wantEncoding: domain.SMSEncodingGSM7,
+			wantLength:   160,
+			wantSegments: 1,
+		},
+		{
+			name:         "long GSM-7 message",
+			message:      strings.Repeat("a", 161),
+			wantEncoding: domain.SMSEncodingGSM7,
+			wantLength:   161,
+			wantSegments: 2,
+		},
+		{
+			name:         "extension characters take two septets",
+			message:      strings.Repeat("€", 80),
+			wantEncoding: domain.SMSEncodingGSM7,
+			wantLength:   160,
+			wantSegments: 1,
+		},
+		{
+			name:         "escapes are not split across segments",
+			message:      strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10),
+			wantEncoding: domain.SMSEncodingGSM7,
+			wantLength:   164,
+			wantSegments: 2,
+		},
+		{
+			name:         "single UCS-2 segment",
+			message:      strings.Repeat("ä", 69) + "ł",
+			wantEncoding: domain.SMSEncodingUCS2,
+			wantLength:   70,
+			wantSegments: 1,
+		},
+		{
+			name:         "emoji are two code units",
+			message:      "Habari 😀" + strings.Repeat(" ", 62),
+			wantEncoding: domain.SMSEncodingUCS2,
+			wantLength:   71,
+			wantSegments: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate, err := costs.EstimateSMS(ctx, tt.message, []string{"0711223344"}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantEncoding, estimate.Encoding)
+			assert.Equal(t, tt.wantLength, estimate.Length)
+			assert.Equal(t, tt.wantSegments, estimate.Segments)
+		})
+	}
+}
+
+func TestSMSCostImpl_EstimateSMS_Cost(t *testing.T) {
+	ctx := context.Background()
+	costs := newTestSMSCosts(newTestFixture(t))
+
+	message := strings.Repeat("a", 200)
+	estimate, err := costs.EstimateSMS(ctx, message, []string{"0711223344", "+254202223344", "+256772123456", "+14155552671"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, estimate.Segments)
+	assert.Equal(t, usecases.SMSCostCurrency, estimate.Currency)
+	assert.Len(t, estimate.Recipients, 4)
+
+	// the longest prefix wins
+	assert.Equal(t, "+2547", estimate.Recipients[0].Prefix)
+	assert.Equal(t, 1.0, estimate.Recipients[0].Cost)
+	assert.Equal(t, "+254", estimate.Recipients[1].Prefix)
+	assert.Equal(t, 1.6, estimate.Recipients[1].Cost)
+	assert.Equal(t, 4.5, estimate.Recipients[2].Cost)
+	assert.False(t, estimate.Recipients[3].Priced)
+	assert.Equal(t, 7.1, estimate.TotalCost)
+
+	// there is no budget to be over without a flavour or a default budget
+	assert.True(t, estimate.WithinBudget)
+	assert.Nil(t, estimate.Budget)
+
+	_, err = costs.EstimateSMS(ctx, "", []string{"0711223344"}, nil)
+	assert.NotNil(t, err)
+	_, err = costs.EstimateSMS(ctx, message, []string{"not a phone"}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestSMSCostImpl_CheckSMSBudget(t *testing.T) {
+	ctx := context.Background()
+	costs := newTestSMSCosts(newTestFixture(t))
+	_, err := costs.SetSMSBudget(ctx, dto.SMSBudgetInput{Flavour: feedlib.FlavourConsumer, MaxCost: 2})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		flavour feedlib.Flavour
+		to      []string
+		wantErr bool
+	}{
+		{
+			name:    "within budget",
+			flavour: feedlib.FlavourConsumer,
+			to:      []string{"0711223344", "0722334455"},
+		},
+		{
+			name:    "over budget",
+			flavour: feedlib.FlavourConsumer,
+			to:      []string{"0711223344", "0722334455", "0733445566", "0744556677", "0755667788"},
+			wantErr: true,
+		},
+		{
+			name:    "unpriced recipient",
+			flavour: feedlib.FlavourConsumer,
+			to:      []string{"+14155552671"},
+			wantErr: true,
+		},
+		{
+			name:    "flavour without a budget",
+			flavour: feedlib.FlavourPro,
+			to:      []string{"0711223344", "0722334455", "0733445566", "0744556677", "0755667788"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := costs.CheckSMSBudget(ctx, &tt.flavour, "Your results are ready", tt.to)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, exceptions.ErrSMSBudgetExceeded))
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+
+	deleted, err := costs.DeleteSMSBudget(ctx, feedlib.FlavourConsumer)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	flavour := feedlib.FlavourConsumer
+	err = costs.CheckSMSBudget(ctx, &flavour, "Your results are ready", []string{"+14155552671"})
+	assert.Nil(t, err)
+}
+
+func TestSMSCostImpl_DefaultBudget(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	_, err := usecases.NewSMSCosts(f.repository, usecases.SMSCostConfig{DefaultBudget: -1})
+	assert.NotNil(t, err)
+
+	costs := newTestSMSCosts(f)
+	costs.Config.DefaultBudget = 1
+	_, err = costs.SetSMSBudget(ctx, dto.SMSBudgetInput{Flavour: feedlib.FlavourConsumer, MaxCost: 5})
+	assert.Nil(t, err)
+	consumer, pro := feedlib.FlavourConsumer, feedlib.FlavourPro
+	to := []string{"0711223344", "0722334455", "0733445566"}
+
+	estimate, err := costs.EstimateSMS(ctx, "Your results are ready", to, nil)
+	assert.Nil(t, err)
+	if assert.NotNil(t, estimate.Budget) {
+		assert.Equal(t, 1.0, *estimate.Budget)
+	}
+	assert.False(t, estimate.WithinBudget)
+
+	// sends without a flavour, and of flavours without a budget, are checked
+	// against the default budget
+	err = costs.CheckSMSBudget(ctx, nil, "Your results are ready", to)
+	assert.True(t, errors.Is(err, exceptions.ErrSMSBudgetExceeded))
+	err = costs.CheckSMSBudget(ctx, &pro, "Your results are ready", to)
+	assert.True(t, errors.Is(err, exceptions.ErrSMSBudgetExceeded))
+	err = costs.CheckSMSBudget(ctx, &consumer, "Your results are ready", to)
+	assert.Nil(t, err)
+
+	n := usecases.NewNotification(nil, nil, nil, nil, nil, costs, f.smsService(), nil, nil, nil, nil)
+	_, err = n.SendSMSToMany(ctx, "Your results are ready", to, enumutils.SenderIDBewell, nil)
+	assert.True(t, errors.Is(err, exceptions.ErrSMSBudgetExceeded))
+	_, err = n.SendSMS(ctx, "0711223344", "Your results are ready", enumutils.SenderIDBewell, nil)
+	assert.Nil(t, err)
+	assert.Len(t, f.sms.to, 1)
+}
+
+func TestSMSCostImpl_BudgetDropsDigests(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	costs := newTestSMSCosts(f)
+	templates := usecases.NewTemplates(f.repository)
+	_, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+	rateLimits := usecases.NewRateLimits(f.repository)
+	_, err = rateLimits.SetRateLimit(ctx, dto.RateLimitPolicyInput{
+		Flavour:       feedlib.FlavourConsumer,
+		Channel:       feedlib.ChannelSms,
+		Limit:         1,
+		WindowSeconds: 3600,
+	})
+	assert.Nil(t, err)
+	n := usecases.NewNotification(nil, nil, templates, nil, rateLimits, costs, f.smsService(), nil, nil, nil, nil)
+
+	flavour := feedlib.FlavourConsumer
+	input := dto.TemplatedMessageInput{
+		TemplateName: "appointment_reminder",
+		Variables:    map[string]interface{}{"firstName": "Wanjiru", "time": "10:00"},
+		To:           []string{"+254711223344"},
+		Flavour:      &flavour,
+	}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, n.SendTemplatedMessage(ctx, input))
+	}
+	assert.Len(t, f.sms.to, 1)
+
+	// the digest is over the budget that was set since, so it is dropped
+	// rather than retried
+	_, err = costs.SetSMSBudget(ctx, dto.SMSBudgetInput{Flavour: feedlib.FlavourConsumer, MaxCost: 0.1})
+	assert.Nil(t, err)
+	count, err := n.SendDueDigests(ctx, time.Now().Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = costs.DeleteSMSBudget(ctx, feedlib.FlavourConsumer)
+	assert.Nil(t, err)
+	count, err = n.SendDueDigests(ctx, time.Now().Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+	assert.Len(t, f.sms.to, 1)
+}
@@ -0,0 +1,273 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/segmentio/ksuid"
+)
+
+// TemplateUsecases represent logic required to manage and render
+// notification templates
+type TemplateUsecases interface {
+	CreateTemplate(
+		ctx context.Context,
+		input dto.NotificationTemplateInput,
+	) (*domain.NotificationTemplate, error)
+
+	UpdateTemplate(
+		ctx context.Context,
+		input dto.NotificationTemplateInput,
+	) (*domain.NotificationTemplate, error)
+
+	DeleteTemplate(ctx context.Context, name string) (bool, error)
+
+	GetTemplate(ctx context.Context, name string) (*domain.NotificationTemplate, error)
+
+	ListTemplates(
+		ctx context.Context,
+		channel *feedlib.Channel,
+	) ([]*domain.NotificationTemplate, error)
+
+	// RenderTemplate renders a template in the requested language.
+	// When no language is supplied the template's default language is used.
+	RenderTemplate(
+		ctx context.Context,
+		name string,
+		language *enumutils.Language,
+		variables map[string]interface{},
+	) (*dto.RenderedTemplate, error)
+}
+
+// TemplateImpl represents the notification template usecase implementation
+type TemplateImpl struct {
+	Repository repository.TemplateRepository
+}
+
+// NewTemplates initializes a notification template usecase
+func NewTemplates(
+	repository repository.TemplateRepository,
+) *TemplateImpl {
+	return &TemplateImpl{
+		Repository: repository,
+	}
+}
+
+// CreateTemplate validates and saves a new notification template
+func (t *TemplateImpl) CreateTemplate(
+	ctx context.Context,
+	input dto.NotificationTemplateInput,
+) (*domain.NotificationTemplate, error) {
+	template, err := templateFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = t.Repository.GetNotificationTemplate(ctx, template.Name)
+	if err == nil {
+		return nil, exceptions.ErrTemplateExists
+	}
+	if !errors.Is(err, exceptions.ErrTemplateNotFound) {
+		return nil, fmt.Errorf("unable to check for an existing template: %w", err)
+	}
+
+	now := time.Now()
+	template.ID = ksuid.New().String()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	return t.Repository.SaveNotificationTemplate(ctx, template)
+}
+
+// UpdateTemplate replaces the content of an existing notification template
+func (t *TemplateImpl) UpdateTemplate(
+	ctx context.Context,
+	input dto.NotificationTemplateInput,
+) (*domain.NotificationTemplate, error) {
+	template, err := templateFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := t.Repository.GetNotificationTemplate(ctx, template.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	template.ID = existing.ID
+	template.CreatedAt = existing.CreatedAt
+	template.UpdatedAt = time.Now()
+	return t.Repository.SaveNotificationTemplate(ctx, template)
+}
+
+// DeleteTemplate removes a notification template
+func (t *TemplateImpl) DeleteTemplate(ctx context.Context, name string) (bool, error) {
+	if err := t.Repository.DeleteNotificationTemplate(ctx, name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetTemplate retrieves a notification template by name
+func (t *TemplateImpl) GetTemplate(
+	ctx context.Context,
+	name string,
+) (*domain.NotificationTemplate, error) {
+	return t.Repository.GetNotificationTemplate(ctx, name)
+}
+
+// ListTemplates lists notification templates, optionally filtered by channel
+func (t *TemplateImpl) ListTemplates(
+	ctx context.Context,
+	channel *feedlib.Channel,
+) ([]*domain.NotificationTemplate, error) {
+	if channel != nil && !channel.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid channel", channel)
+	}
+	return t.Repository.ListNotificationTemplates(ctx, channel)
+}
+
+// RenderTemplate renders a template's subject and body with the supplied variables.
+//
+// Rendering fails when a variable that the template uses is not supplied.
+func (t *TemplateImpl) RenderTemplate(
+	ctx context.Context,
+	name string,
+	language *enumutils.Language,
+	variables map[string]interface{},
+) (*dto.RenderedTemplate, error) {
+	template, err := t.Repository.GetNotificationTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := template.DefaultLanguage
+	if language != nil {
+		requested = *language
+	}
+	variant, ok := template.Variant(requested)
+	if !ok {
+		return nil, fmt.Errorf("template %s has no %s or default language variant", name, requested)
+	}
+
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	rendered := &dto.RenderedTemplate{
+		TemplateName: template.Name,
+		Channel:      template.Channel,
+		Language:     variant.Language,
+	}
+	body, err := renderText(variant.Body, variables)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render %s template body: %w", name, err)
+	}
+	rendered.Body = body
+
+	if variant.Subject != nil {
+		subject, err := renderText(*variant.Subject, variables)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render %s template subject: %w", name, err)
+		}
+		rendered.Subject = &subject
+	}
+	return rendered, nil
+}
+
+func renderText(text string, variables map[string]interface{}) (string, error) {
+	tmpl, err := texttemplate.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateFromInput validates a template input.
+//
+// Every variant is rendered with placeholder values for the declared variables
+// so that a template that uses an undeclared variable is rejected when saved
+// rather than when a message is sent.
+func templateFromInput(input dto.NotificationTemplateInput) (*domain.NotificationTemplate, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("a template name is required")
+	}
+	if !input.Channel.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid channel", input.Channel)
+	}
+	if !input.DefaultLanguage.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid language", input.DefaultLanguage)
+	}
+	if len(input.Variants) == 0 {
+		return nil, fmt.Errorf("a template needs at least one language variant")
+	}
+
+	placeholders := map[string]interface{}{}
+	for _, variable := range input.Variables {
+		placeholders[variable] = variable
+	}
+
+	seen := map[enumutils.Language]bool{}
+	variants := []domain.TemplateVariant{}
+	for _, v := range input.Variants {
+		if v == nil {
+			continue
+		}
+		if !v.Language.IsValid() {
+			return nil, fmt.Errorf("%s is not a valid language", v.Language)
+		}
+		if seen[v.Language] {
+			return nil, fmt.Errorf("duplicate %s template variant", v.Language)
+		}
+		seen[v.Language] = true
+
+		if strings.TrimSpace(v.Body) == "" {
+			return nil, fmt.Errorf("the %s template variant has an empty body", v.Language)
+		}
+		if _, err := renderText(v.Body, placeholders); err != nil {
+			return nil, fmt.Errorf("invalid %s template body: %w", v.Language, err)
+		}
+		if v.Subject != nil {
+			if _, err := renderText(*v.Subject, placeholders); err != nil {
+				return nil, fmt.Errorf("invalid %s template subject: %w", v.Language, err)
+			}
+		}
+		variants = append(variants, domain.TemplateVariant{
+			Language: v.Language,
+			Subject:  v.Subject,
+			Body:     v.Body,
+		})
+	}
+	if !seen[input.DefaultLanguage] {
+		return nil, fmt.Errorf("the default language %s has no template variant", input.DefaultLanguage)
+	}
+
+	variables := input.Variables
+	if variables == nil {
+		variables = []string{}
+	}
+
+	return &domain.NotificationTemplate{
+		Name:            name,
+		Description:     input.Description,
+		Channel:         input.Channel,
+		DefaultLanguage: input.DefaultLanguage,
+		Variables:       variables,
+		Variants:        variants,
+	}, nil
+}
@@ -0,0 +1,404 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	libDto "github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	smsMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/sms/mock"
+	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestTemplateInput() dto.NotificationTemplateInput {
+	subject := "Appointment reminder"
+	return dto.NotificationTemplateInput{
+		Name:            "appointment_reminder",
+		Description:     "Reminds a patient of an upcoming appointment",
+		Channel:         feedlib.ChannelSms,
+		DefaultLanguage: enumutils.LanguageEn,
+		Variables:       []string{"firstName", "time"},
+		Variants: []*dto.TemplateVariantInput{
+			{
+				Language: enumutils.LanguageEn,
+				Subject:  &subject,
+				Body:     "Hello {{.firstName}}, your appointment is at {{.time}}",
+			},
+			{
+				Language: enumutils.LanguageSw,
+				Body:     "Habari {{.firstName}}, miadi yako ni saa {{.time}}",
+			},
+		},
+	}
+}
+
+func TestTemplateImpl_CreateTemplate(t *testing.T) {
+	ctx := context.Background()
+	templates := usecases.NewTemplates(memory.NewRepository())
+
+	undeclared := getTestTemplateInput()
+	undeclared.Variants[0].Body = "Hello {{.lastName}}"
+
+	noDefault := getTestTemplateInput()
+	noDefault.DefaultLanguage = enumutils.LanguageSw
+	noDefault.Variants = noDefault.Variants[:1]
+
+	invalidChannel := getTestTemplateInput()
+	invalidChannel.Channel = feedlib.Channel("PIGEON")
+
+	tests := []struct {
+		name    string
+		input   dto.NotificationTemplateInput
+		wantErr bool
+	}{
+		{
+			name:    "valid template",
+			input:   getTestTemplateInput(),
+			wantErr: false,
+		},
+		{
+			name:    "duplicate template name",
+			input:   getTestTemplateInput(),
+			wantErr: true,
+		},
+		{
+			name:    "template uses an undeclared variable",
+			input:   undeclared,
+			wantErr: true,
+		},
+		{
+			name:    "default language without a variant",
+			input:   noDefault,
+			wantErr: true,
+		},
+		{
+			name:    "invalid channel",
+			input:   invalidChannel,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templates.CreateTemplate(ctx, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.NotEmpty(t, got.ID)
+				assert.Equal(t, tt.input.Name, got.Name)
+				assert.Len(t, got.Variants, 2)
+			}
+		})
+	}
+}
+
+func TestTemplateImpl_RenderTemplate(t *testing.T) {
+	ctx := context.Background()
+	templates := usecases.NewTemplates(memory.NewRepository())
+	_, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+
+	variables := map[string]interface{}{"firstName": "Wanjiku", "time": "10:00"}
+	swahili := enumutils.LanguageSw
+
+	type args struct {
+		name      string
+		language  *enumutils.Language
+		variables map[string]interface{}
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantBody    string
+		wantSubject bool
+		wantErr     bool
+	}{
+		{
+			name:        "default language",
+			args:        args{name: "appointment_reminder", variables: variables},
+			wantBody:    "Hello Wanjiku, your appointment is at 10:00",
+			wantSubject: true,
+		},
+		{
+			name:     "swahili variant",
+			args:     args{name: "appointment_reminder", language: &swahili, variables: variables},
+			wantBody: "Habari Wanjiku, miadi yako ni saa 10:00",
+		},
+		{
+			name:    "missing variable",
+			args:    args{name: "appointment_reminder", variables: map[string]interface{}{"firstName": "Wanjiku"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown template",
+			args:    args{name: "unknown", variables: variables},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templates.RenderTemplate(ctx, tt.args.name, tt.args.language, tt.args.variables)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.wantBody, got.Body)
+				assert.Equal(t, tt.wantSubject, got.Subject != nil)
+			}
+		})
+	}
+}
+
+func TestTemplateImpl_UpdateAndDeleteTemplate(t *testing.T) {
+	ctx := context.Background()
+	templates := usecases.NewTemplates(memory.NewRepository())
+
+	_, err := templates.UpdateTemplate(ctx, getTestTemplateInput())
+	assert.True(t, errors.Is(err, exceptions.ErrTemplateNotFound))
+
+	created, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+
+	input := getTestTemplateInput()
+	input.Variants = input.Variants[:1]
+	updated, err := templates.UpdateTemplate(ctx, input)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Len(t, updated.Variants, 1)
+
+	sms := feedlib.ChannelSms
+	listed, err := templates.ListTemplates(ctx, &sms)
+	assert.Nil(t, err)
+	assert.Len(t, listed, 1)
+
+	deleted, err := templates.DeleteTemplate(ctx, input.Name)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+
+	_, err = templates.GetTemplate(ctx, input.Name)
+	assert.True(t, errors.Is(err, exceptions.ErrTemplateNotFound))
+}
+
+func TestNotificationImpl_SendTemplatedMessage(t *testing.T) {
+	ctx := context.Background()
+	templates := usecases.NewTemplates(memory.NewRepository())
+	_, err := templates.CreateTemplate(ctx, getTestTemplateInput())
+	assert.Nil(t, err)
+
+	var sent []string
+	fakeSMS := &smsMock.FakeServiceSMS{
+		SendToManyFn: func(
+			ctx context.Context,
+			message string,
+			to []string,
+			from enumutils.SenderID,
+		) (*libDto.SendMessageResponse, error) {
+			sent = append(sent, message)
+			return &libDto.SendMessageResponse{}, nil
+		},
+	}
+	n := usecases.NewNotification(nil, nil, templates, nil, nil, newTestFixture(t).smsCosts(), fakeSMS, nil, nil, nil, nil)
+
+	tests := []struct {
+		name    string
+		input   dto.TemplatedMessageInput
+		wantErr bool
+	}{
+		{
+			name: "happy case",
+			input: dto.TemplatedMessageInput{
+				TemplateName: "appointment_reminder",
+				Variables:    map[string]interface{}{"firstName": "Otieno", "time": "09:30"},
+				To:           []string{"+254711223344"},
+			},
+		},
+		{
+			name: "no recipients",
+			input: dto.TemplatedMessageInput{
+				TemplateName: "appointment_reminder",
+				Variables:    map[string]interface{}{"firstName": "Otieno", "time": "09:30"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing variables",
+			input: dto.TemplatedMessageInput{
+				TemplateName: "appointment_reminder",
+				To:           []string{"+254711223344"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := n.SendTemplatedMessage(ctx, tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("SendTemplatedMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	assert.Equal(t, []string{"Hello Otieno, your appointment is at 09:30"}, sent)
+}
+
+func TestNotificationImpl_SendTemplatedMessage_EachRecipient(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	templates := usecases.NewTemplates(f.repository)
+	subject := "Results"
+	for name, channel := range map[string]feedlib.Channel{
+		"results_email":    feedlib.ChannelEmail,
+		"results_whatsapp": feedlib.ChannelWhatsapp,
+	} {
+		_, err := templates.CreateTemplate(ctx, dto.NotificationTemplateInput{
+			Name:            name,
+			Channel:         channel,
+			DefaultLanguage: enumutils.LanguageEn,
+			Variants: []*dto.TemplateVariantInput{
+				{Language: enumutils.LanguageEn, Subject: &subject, Body: "Your results are ready"},
+			},
+		})
+		assert.Nil(t, err)
+	}
+	n := usecases.NewNotification(nil, nil, templates, nil, nil, nil, nil, f.emailService(), f.whatsAppUsecases(), nil, nil)
+
+	// recipients don't see each other's addresses
+	err := n.SendTemplatedMessage(ctx, dto.TemplatedMessageInput{
+		TemplateName: "results_email",
+		To:           []string{"jane@example.com", "john@example.com"},
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, f.emails, 2) {
+		assert.Equal(t, []string{"jane@example.com"}, f.emails[0].To)
+		assert.Equal(t, []string{"john@example.com"}, f.emails[1].To)
+	}
+
+	// WhatsApp messages reach recipients who are not in a session, and a
+	// recipient that can't be sent the message doesn't stop the others
+	err = n.SendTemplatedMessage(ctx, dto.TemplatedMessageInput{
+		TemplateName: "results_whatsapp",
+		To:           []string{"+254722000000", "not a number", "+254733000000"},
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, f.whatsApp, 2) {
+		assert.Equal(t, "+254722000000", f.whatsApp[0].to)
+		assert.Equal(t, "+254733000000", f.whatsApp[1].to)
+		assert.Equal(t, []string{"Results", "Your results are ready"}, f.whatsApp[1].parameters)
+	}
+
+	err = n.SendTemplatedMessage(ctx, dto.TemplatedMessageInput{
+		TemplateName: "results_whatsapp",
+		To:           []string{"not a number"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestNotificationImpl_FeedNotificationTemplates(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	templates := usecases.NewTemplates(f.repository)
+
+	var pushed []*firebasetools.FirebaseSimpleNotificationInput
+	fcm := newFakeFCM()
+	send := fcm.SendNotificationFn
+	fcm.SendNotificationFn = func(
+		ctx context.Context,
+		registrationTokens []string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (*dto.FCMSendResult, error) {
+		pushed = append(pushed, notification)
+		return send(ctx, registrationTokens, data, notification, android, ios, web)
+	}
+	devices := usecases.NewDevices(f.repository, fcm, nil)
+	_, err := devices.RegisterDevice(ctx, "uid-1", dto.DeviceInput{
+		DeviceID: "phone",
+		Token:    "token-1",
+		Platform: domain.DevicePlatformAndroid,
+	})
+	assert.Nil(t, err)
+
+	n := usecases.NewNotification(
+		nil,
+		fakeItemNotifications{},
+		templates,
+		devices,
+		usecases.NewRateLimits(f.repository),
+		nil,
+		nil,
+		nil,
+		f.whatsAppUsecases(),
+		fakePhoneNumbers{"uid-1": {"+254722000000"}},
+		nil,
+	)
+	item := feedlib.Item{
+		ID:                   "item-1",
+		Tagline:              "Lab results",
+		Summary:              "Your lab results are ready",
+		Persistent:           true,
+		Users:                []string{"uid-1"},
+		NotificationChannels: []feedlib.Channel{feedlib.ChannelWhatsapp},
+	}
+	nudge := feedlib.Nudge{
+		ID:    "nudge-1",
+		Title: "Verify your email",
+		Text:  "Verify your email to get your results",
+		Users: []string{"uid-1"},
+		NotificationBody: feedlib.NotificationBody{
+			PublishMessage: "Please verify your email",
+			ResolveMessage: "Your email has been verified",
+		},
+	}
+
+	// the feed's own strings are sent when there are no templates
+	assert.Nil(t, n.HandleItemPublish(ctx, f.pubSubPayload(item)))
+	assert.Nil(t, n.HandleNudgePublish(ctx, f.pubSubPayload(nudge)))
+	if assert.Len(t, pushed, 2) {
+		assert.Equal(t, "Lab results", pushed[0].Title)
+		assert.Equal(t, "Your lab results are ready", pushed[0].Body)
+		assert.Equal(t, "Verify your email", pushed[1].Title)
+		assert.Equal(t, "Please verify your email", pushed[1].Body)
+	}
+	if assert.Len(t, f.whatsApp, 1) {
+		assert.Equal(t, []string{"Lab results", "Your lab results are ready"}, f.whatsApp[0].parameters)
+	}
+
+	for name, body := range map[string]string{
+		usecases.ItemPublishTemplateName:  "{{.summary}}. Open Be.Well to read it.",
+		usecases.NudgeResolveTemplateName: "{{.message}}. Thank you!",
+	} {
+		subject := "Be.Well"
+		_, err := templates.CreateTemplate(ctx, dto.NotificationTemplateInput{
+			Name:            name,
+			Channel:         feedlib.ChannelFcm,
+			DefaultLanguage: enumutils.LanguageEn,
+			Variables:       []string{"summary", "message"},
+			Variants: []*dto.TemplateVariantInput{
+				{Language: enumutils.LanguageEn, Subject: &subject, Body: body},
+			},
+		})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, n.HandleItemPublish(ctx, f.pubSubPayload(item)))
+	assert.Nil(t, n.HandleNudgeResolve(ctx, f.pubSubPayload(nudge)))
+	if assert.Len(t, pushed, 4) {
+		assert.Equal(t, "Be.Well", pushed[2].Title)
+		assert.Equal(t, "Your lab results are ready. Open Be.Well to read it.", pushed[2].Body)
+		assert.Equal(t, "Be.Well", pushed[3].Title)
+		assert.Equal(t, "Your email has been verified. Thank you!", pushed[3].Body)
+	}
+	if assert.Len(t, f.whatsApp, 2) {
+		assert.Equal(t, []string{"Be.Well", "Your lab results are ready. Open Be.Well to read it."}, f.whatsApp[1].parameters)
+	}
+}
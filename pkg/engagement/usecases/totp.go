@@ -0,0 +1,296 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 RFC 6238 authenticator apps use HMAC-SHA1
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+)
+
+const (
+	// DefaultTOTPDriftSteps is the number of 30 second steps either side of
+	// the current one whose codes are accepted, to allow for clock drift on
+	// the phone
+	DefaultTOTPDriftSteps = 1
+
+	// the settings that authenticator apps assume when a provisioning URI
+	// leaves them out
+	totpPeriod      = 30 * time.Second
+	totpDigits      = 6
+	totpSecretBytes = 20
+	totpIssuer      = "Be.Well"
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+// errTOTPRejected is returned from an enrolment update when a code is not
+// accepted so that nothing is saved
+var errTOTPRejected = errors.New("TOTP code rejected")
+
+// EnrollTOTP creates a secret for an authenticator app. The enrolment is
+// only used once it has been confirmed with a code from the app.
+//
+// An SMS code proves that the user holds the phone number, so that an
+// authenticator app can't be added to someone else's number.
+func (o *OTPImpl) EnrollTOTP(
+	ctx context.Context,
+	uid string,
+	msisdn string,
+	otp string,
+) (*dto.TOTPProvisioning, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("a user is required to enroll an authenticator app")
+	}
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := o.Repository.GetTOTPEnrolment(ctx, *phone)
+	if err != nil && !errors.Is(err, exceptions.ErrTOTPEnrolmentNotFound) {
+		return nil, fmt.Errorf("unable to retrieve TOTP enrolment: %w", err)
+	}
+	if existing != nil && existing.Confirmed {
+		return nil, fmt.Errorf("an authenticator app is already enrolled for %s, disable it first", *phone)
+	}
+	if err := o.verifySMSOTP(ctx, *phone, otp); err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to generate TOTP secret: %w", err)
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+
+	_, err = o.Repository.SaveTOTPEnrolment(ctx, &domain.TOTPEnrolment{
+		ID:         *phone,
+		Secret:     secret,
+		EnrolledBy: uid,
+		CreatedAt:  o.Clock(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save TOTP enrolment: %w", err)
+	}
+
+	return &dto.TOTPProvisioning{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(*phone, secret),
+	}, nil
+}
+
+// ConfirmTOTP confirms an enrolment with a code from the authenticator app
+// and returns its recovery codes. The recovery codes are only shown once.
+func (o *OTPImpl) ConfirmTOTP(ctx context.Context, uid string, msisdn string, code string) ([]string, error) {
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return nil, err
+	}
+	now := o.Clock()
+	identifierKey := otpAttemptsKey(otpVerifyAttempts, *phone)
+	if err := o.attempt(ctx, identifierKey, o.verifyPolicy(), now); err != nil {
+		return nil, err
+	}
+
+	codes, recoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate recovery codes: %w", err)
+	}
+
+	_, err = o.Repository.UpdateTOTPEnrolment(ctx, *phone, func(enrolment *domain.TOTPEnrolment) error {
+		if enrolment.Confirmed {
+			return fmt.Errorf("the authenticator app has already been confirmed")
+		}
+		if uid == "" || enrolment.EnrolledBy != uid {
+			return errTOTPRejected
+		}
+		step, ok := o.matchTOTP(*enrolment, strings.TrimSpace(code), now)
+		if !ok {
+			return errTOTPRejected
+		}
+		enrolment.Confirmed = true
+		enrolment.ConfirmedAt = &now
+		enrolment.LastUsedStep = step
+		enrolment.RecoveryCodes = recoveryCodes
+		return nil
+	})
+	if errors.Is(err, errTOTPRejected) {
+		return nil, fmt.Errorf("the code from the authenticator app is not valid")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to confirm TOTP enrolment: %w", err)
+	}
+
+	o.resetAttempts(ctx, identifierKey)
+	return codes, nil
+}
+
+// DisableTOTP removes the authenticator app of a phone number once an SMS
+// code sent to it has been verified
+func (o *OTPImpl) DisableTOTP(ctx context.Context, msisdn string, otp string) (bool, error) {
+	phone, err := converterandformatter.NormalizeMSISDN(msisdn)
+	if err != nil {
+		return false, err
+	}
+	if err := o.verifySMSOTP(ctx, *phone, otp); err != nil {
+		return false, err
+	}
+	err = o.Repository.DeleteTOTPEnrolment(ctx, *phone)
+	if err != nil && !errors.Is(err, exceptions.ErrTOTPEnrolmentNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifySMSOTP consumes a code that was sent to a phone number. Codes from
+// the phone number's authenticator app are not accepted so that the app
+// can't be used to replace itself.
+func (o *OTPImpl) verifySMSOTP(ctx context.Context, phone string, code string) error {
+	verified, err := o.verify(ctx, phone, code, false)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return fmt.Errorf("the OTP sent to %s is not valid", phone)
+	}
+	return nil
+}
+
+// verifyTOTP accepts a code from a confirmed authenticator app, or one of its
+// unused recovery codes. Accepted codes can't be used again.
+func (o *OTPImpl) verifyTOTP(ctx context.Context, identifier string, code string, now time.Time) (bool, error) {
+	_, err := o.Repository.UpdateTOTPEnrolment(ctx, identifier, func(enrolment *domain.TOTPEnrolment) error {
+		if !enrolment.Confirmed {
+			return errTOTPRejected
+		}
+		if step, ok := o.matchTOTP(*enrolment, code, now); ok {
+			enrolment.LastUsedStep = step
+			return nil
+		}
+		normalized := normalizeRecoveryCode(code)
+		for i, recoveryCode := range enrolment.RecoveryCodes {
+			if recoveryCode.UsedAt != nil {
+				continue
+			}
+			hash := hashOTP(recoveryCode.Salt, normalized)
+			if subtle.ConstantTimeCompare([]byte(hash), []byte(recoveryCode.CodeHash)) == 1 {
+				enrolment.RecoveryCodes[i].UsedAt = &now
+				return nil
+			}
+		}
+		return errTOTPRejected
+	})
+	if errors.Is(err, errTOTPRejected) || errors.Is(err, exceptions.ErrTOTPEnrolmentNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to verify TOTP: %w", err)
+	}
+	return true, nil
+}
+
+// matchTOTP returns the time step of the code if it is valid within the drift
+// window and newer than the last accepted code
+func (o *OTPImpl) matchTOTP(enrolment domain.TOTPEnrolment, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrolment.Secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod/time.Second)
+	drift := int64(o.Config.TOTPDriftSteps)
+	for step := current - drift; step <= current+drift; step++ {
+		if step <= enrolment.LastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the RFC 6238 code of a time step
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// totpProvisioningURI returns the otpauth:// URI that authenticator apps
+// scan to add an account
+func totpProvisioningURI(phone string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(int(totpPeriod/time.Second)))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + phone,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// generateRecoveryCodes returns recovery codes formatted as `abcd-efgh` and
+// their salted hashes
+func generateRecoveryCodes() ([]string, []domain.RecoveryCode, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := []string{}
+	hashed := []domain.RecoveryCode{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))
+		salt, err := generateSalt()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashed = append(hashed, domain.RecoveryCode{
+			CodeHash: hashOTP(salt, code),
+			Salt:     salt,
+		})
+	}
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,186 @@
+package usecases_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 RFC 6238 authenticator apps use HMAC-SHA1
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/stretchr/testify/assert"
+)
+
+// authenticatorCode computes the code that an authenticator app shows at a
+// time for a base32 secret
+func authenticatorCode(t *testing.T, secret string, at time.Time) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	assert.Nil(t, err)
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestAuthenticatorCode(t *testing.T) {
+	// the SHA1 test vector of RFC 6238, truncated to six digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	assert.Equal(t, "287082", authenticatorCode(t, secret, time.Unix(59, 0)))
+	assert.Equal(t, "005924", authenticatorCode(t, secret, time.Unix(1234567890, 0)))
+}
+
+func TestOTPImpl_EnrollTOTP(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	smsCode, err := otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+	provisioning, err := otp.EnrollTOTP(ctx, "user-1", "0711223344", smsCode)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, provisioning.Secret)
+	assert.True(t, strings.HasPrefix(provisioning.ProvisioningURI, "otpauth://totp/Be.Well:+254711223344?"))
+	assert.Contains(t, provisioning.ProvisioningURI, "secret="+provisioning.Secret)
+	assert.Contains(t, provisioning.ProvisioningURI, "issuer=Be.Well")
+
+	// codes are not accepted until the app is confirmed
+	code := authenticatorCode(t, provisioning.Secret, f.clock.Now())
+	verified, err := otp.VerifyOTP(ctx, "0711223344", code)
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	f.clock.Advance(time.Second)
+	_, err = otp.ConfirmTOTP(ctx, "user-1", "0711223344", "000000")
+	assert.NotNil(t, err)
+
+	f.clock.Advance(time.Minute)
+	code = authenticatorCode(t, provisioning.Secret, f.clock.Now())
+	recoveryCodes, err := otp.ConfirmTOTP(ctx, "user-1", "0711223344", code)
+	assert.Nil(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	smsCode, err = otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+	_, err = otp.EnrollTOTP(ctx, "user-1", "0711223344", smsCode)
+	assert.NotNil(t, err)
+
+	// the code used to confirm can't be replayed
+	verified, err = otp.VerifyOTP(ctx, "0711223344", code)
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	f.clock.Advance(time.Minute)
+	verified, err = otp.VerifyOTP(ctx, "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	// a phone that is a step behind is within the drift window
+	f.clock.Advance(time.Minute)
+	verified, err = otp.VerifyOTP(
+		ctx,
+		"0711223344",
+		authenticatorCode(t, provisioning.Secret, f.clock.Now().Add(-30*time.Second)),
+	)
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	// but not one that is several steps behind
+	f.clock.Advance(5 * time.Minute)
+	verified, err = otp.VerifyOTP(
+		ctx,
+		"0711223344",
+		authenticatorCode(t, provisioning.Secret, f.clock.Now().Add(-2*time.Minute)),
+	)
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	// recovery codes are accepted once
+	f.clock.Advance(time.Minute)
+	verified, err = otp.VerifyOTP(ctx, "0711223344", strings.ToUpper(recoveryCodes[0]))
+	assert.Nil(t, err)
+	assert.True(t, verified)
+	verified, err = otp.VerifyOTP(ctx, "0711223344", recoveryCodes[0])
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	// the authenticator app can't be used to disable itself
+	f.clock.Advance(time.Minute)
+	_, err = otp.DisableTOTP(ctx, "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.NotNil(t, err)
+
+	f.clock.Advance(time.Minute)
+	smsCode, err = otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+	disabled, err := otp.DisableTOTP(ctx, "0711223344", smsCode)
+	assert.Nil(t, err)
+	assert.True(t, disabled)
+
+	f.clock.Advance(time.Minute)
+	verified, err = otp.VerifyOTP(ctx, "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.Nil(t, err)
+	assert.False(t, verified)
+}
+
+func TestOTPImpl_TOTP_OtherUsers(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	otp, _ := newTestOTP(f, usecases.OTPConfig{})
+
+	// an attacker can't enroll an app for a number they don't hold, with or
+	// without a code sent to their own number
+	_, err := otp.EnrollTOTP(ctx, "attacker", "0711223344", "")
+	assert.NotNil(t, err)
+	_, err = otp.EnrollTOTP(ctx, "attacker", "0711223344", "123456")
+	assert.NotNil(t, err)
+	attackerCode, err := otp.GenerateAndSendOTP(ctx, "0722000111", nil)
+	assert.Nil(t, err)
+	_, err = otp.EnrollTOTP(ctx, "attacker", "0711223344", attackerCode)
+	assert.NotNil(t, err)
+
+	// failed codes back off before the next attempt
+	f.clock.Advance(time.Minute)
+	victimCode, err := otp.GenerateAndSendOTP(ctx, "0711223344", nil)
+	assert.Nil(t, err)
+	_, err = otp.EnrollTOTP(ctx, "", "0711223344", victimCode)
+	assert.NotNil(t, err)
+	provisioning, err := otp.EnrollTOTP(ctx, "victim", "0711223344", victimCode)
+	assert.Nil(t, err)
+
+	// the SMS code is used up by the enrolment
+	f.clock.Advance(time.Minute)
+	_, err = otp.EnrollTOTP(ctx, "attacker", "0711223344", victimCode)
+	assert.NotNil(t, err)
+
+	// only the user who enrolled the app can confirm it, even with a valid
+	// code from it
+	f.clock.Advance(time.Minute)
+	_, err = otp.ConfirmTOTP(ctx, "attacker", "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.NotNil(t, err)
+	f.clock.Advance(time.Minute)
+	recoveryCodes, err := otp.ConfirmTOTP(ctx, "victim", "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.Nil(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	// an attacker can't disable the app without a code sent to the number
+	f.clock.Advance(time.Minute)
+	_, err = otp.DisableTOTP(ctx, "0711223344", "")
+	assert.NotNil(t, err)
+	attackerCode, err = otp.GenerateAndSendOTP(ctx, "0722000111", nil)
+	assert.Nil(t, err)
+	_, err = otp.DisableTOTP(ctx, "0711223344", attackerCode)
+	assert.NotNil(t, err)
+
+	f.clock.Advance(time.Minute)
+	verified, err := otp.VerifyOTP(ctx, "0711223344", authenticatorCode(t, provisioning.Secret, f.clock.Now()))
+	assert.Nil(t, err)
+	assert.True(t, verified)
+}
@@ -0,0 +1,455 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/video"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// VideoRoomCallbackPath is the path of the endpoint that the video
+	// provider posts room status callbacks to
+	VideoRoomCallbackPath = "/video/callbacks"
+
+	// VideoRoomEarlyJoin is how long before its scheduled start a room can
+	// be joined
+	VideoRoomEarlyJoin = 15 * time.Minute
+
+	// VideoRoomGracePeriod is how long after its scheduled end a room can
+	// be joined, for consults that overrun
+	VideoRoomGracePeriod = 30 * time.Minute
+
+	// MaxVideoTokenTTL is the longest that a video access token is valid.
+	// Tokens for rooms that end sooner expire at the end of the grace period.
+	MaxVideoTokenTTL = 4 * time.Hour
+
+	// MaxVideoRoomParticipants is the most participants that a group room
+	// can have
+	MaxVideoRoomParticipants = 50
+
+	// MaxVideoRoomDuration is the longest that a room can be scheduled for
+	MaxVideoRoomDuration = 24 * time.Hour
+
+	// the status callback events that are recorded
+	videoRoomEnded               = "room-ended"
+	videoParticipantConnected    = "participant-connected"
+	videoParticipantDisconnected = "participant-disconnected"
+)
+
+// VideoRoomConfig holds what the video provider's status callbacks are
+// verified with
+type VideoRoomConfig struct {
+	// AuthToken is the Twilio auth token that signs status callbacks
+	AuthToken []byte
+
+	// BaseURL is the public URL of the service e.g `https://example.com`.
+	// Rooms are created with a status callback to this URL.
+	BaseURL string
+}
+
+// VideoRoomUsecases represent logic required to schedule video consults and
+// let their participants join them
+type VideoRoomUsecases interface {
+	// VerifyVideoCallbackSignature checks the signature of a status
+	// callback to a path and query with the posted form values. It returns
+	// exceptions.ErrInvalidVideoCallbackSignature when the signature does
+	// not match.
+	VerifyVideoCallbackSignature(requestURI string, form url.Values, signature string) error
+
+	// ScheduleVideoRoom schedules a room for an appointment or feed item
+	ScheduleVideoRoom(ctx context.Context, createdBy string, input dto.VideoRoomInput) (*domain.VideoRoom, error)
+
+	// UpdateVideoRoom changes a room that has not ended. Rooms in progress
+	// can only change their participants and scheduled end.
+	UpdateVideoRoom(ctx context.Context, id string, input dto.VideoRoomInput) (*domain.VideoRoom, error)
+
+	GetVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error)
+
+	// ListActiveVideoRooms returns the rooms in progress, ordered by their
+	// scheduled start
+	ListActiveVideoRooms(ctx context.Context) ([]*domain.VideoRoom, error)
+
+	// EndVideoRoom disconnects the participants of a room in progress, or
+	// cancels a room that has not started
+	EndVideoRoom(ctx context.Context, endedBy string, id string) (*domain.VideoRoom, error)
+
+	// VideoRoomAccessToken returns a token for a participant to join a room,
+	// starting the room if it is the first. It returns
+	// exceptions.ErrNotVideoRoomParticipant for other users.
+	VideoRoomAccessToken(ctx context.Context, uid string, id string) (*dto.VideoRoomToken, error)
+
+	// HandleVideoRoomEvent records when participants join and leave a
+	// room, and when the provider ends it. Events of rooms that were not
+	// scheduled here are ignored.
+	HandleVideoRoomEvent(ctx context.Context, event dto.VideoRoomEvent) error
+
+	// ListVideoRoomParticipants returns the connections to a room in the
+	// order that they joined
+	ListVideoRoomParticipants(ctx context.Context, id string) ([]*domain.VideoParticipant, error)
+}
+
+// VideoRoomImpl represents the video room usecase implementation
+type VideoRoomImpl struct {
+	Repository repository.VideoRoomRepository
+	Video      video.ServiceVideo
+	Config     VideoRoomConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewVideoRooms initializes a video room usecase
+func NewVideoRooms(
+	repository repository.VideoRoomRepository,
+	videoService video.ServiceVideo,
+	config VideoRoomConfig,
+) (*VideoRoomImpl, error) {
+	if len(config.AuthToken) == 0 {
+		return nil, fmt.Errorf("the video callback auth token is required")
+	}
+	base, err := url.Parse(config.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%q is not a valid base URL", config.BaseURL)
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &VideoRoomImpl{
+		Repository: repository,
+		Video:      videoService,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// VerifyVideoCallbackSignature compares a signature with the signature of
+// the request in constant time
+func (v *VideoRoomImpl) VerifyVideoCallbackSignature(
+	requestURI string,
+	form url.Values,
+	signature string,
+) error {
+	want := SignTwilioWebhook(v.Config.AuthToken, v.Config.BaseURL+requestURI, form)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return exceptions.ErrInvalidVideoCallbackSignature
+	}
+	return nil
+}
+
+// ScheduleVideoRoom validates and saves a room. The provider's room is not
+// created until a participant asks to join.
+func (v *VideoRoomImpl) ScheduleVideoRoom(
+	ctx context.Context,
+	createdBy string,
+	input dto.VideoRoomInput,
+) (*domain.VideoRoom, error) {
+	now := v.Clock()
+	room := &domain.VideoRoom{
+		ID:        ksuid.New().String(),
+		Status:    domain.VideoRoomStatusScheduled,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := v.applyVideoRoomInput(room, input); err != nil {
+		return nil, err
+	}
+	if err := v.Repository.SaveVideoRoom(ctx, room); err != nil {
+		return nil, fmt.Errorf("unable to save video room: %w", err)
+	}
+	return room, nil
+}
+
+// UpdateVideoRoom replaces the appointment or item, participants and
+// schedule of a room. Participants that are removed from a room in progress
+// stay connected until their tokens expire or the room ends.
+func (v *VideoRoomImpl) UpdateVideoRoom(
+	ctx context.Context,
+	id string,
+	input dto.VideoRoomInput,
+) (*domain.VideoRoom, error) {
+	room, err := v.Repository.GetVideoRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if room.Status.IsEnded() {
+		return nil, fmt.Errorf("video room %s has ended", id)
+	}
+
+	updated := *room
+	if err := v.applyVideoRoomInput(&updated, input); err != nil {
+		return nil, err
+	}
+	if room.Status == domain.VideoRoomStatusInProgress {
+		if updated.AppointmentID != room.AppointmentID ||
+			updated.ItemID != room.ItemID ||
+			!updated.ScheduledStart.Equal(room.ScheduledStart) {
+			return nil, fmt.Errorf("video room %s is in progress; only its participants and end can change", id)
+		}
+	}
+	updated.UpdatedAt = v.Clock()
+	if err := v.Repository.SaveVideoRoom(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("unable to save video room: %w", err)
+	}
+	return &updated, nil
+}
+
+// GetVideoRoom returns a room by its ID
+func (v *VideoRoomImpl) GetVideoRoom(ctx context.Context, id string) (*domain.VideoRoom, error) {
+	return v.Repository.GetVideoRoom(ctx, id)
+}
+
+// ListActiveVideoRooms returns the rooms in progress
+func (v *VideoRoomImpl) ListActiveVideoRooms(ctx context.Context) ([]*domain.VideoRoom, error) {
+	return v.Repository.ListVideoRooms(ctx, domain.VideoRoomStatusInProgress)
+}
+
+// EndVideoRoom completes a room at the provider when it is in progress.
+// Rooms that were never started are cancelled.
+func (v *VideoRoomImpl) EndVideoRoom(ctx context.Context, endedBy string, id string) (*domain.VideoRoom, error) {
+	room, err := v.Repository.GetVideoRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if room.Status.IsEnded() {
+		return nil, fmt.Errorf("video room %s has already ended", id)
+	}
+	if room.Status == domain.VideoRoomStatusInProgress {
+		if err := v.Video.CompleteRoom(ctx, room.RoomSID); err != nil {
+			return nil, fmt.Errorf("unable to end video room: %w", err)
+		}
+	}
+
+	now := v.Clock()
+	room.Status = domain.VideoRoomStatusCompleted
+	if room.StartedAt == nil {
+		room.Status = domain.VideoRoomStatusCancelled
+	}
+	room.EndedAt = &now
+	room.EndedBy = endedBy
+	room.UpdatedAt = now
+	if err := v.Repository.SaveVideoRoom(ctx, room); err != nil {
+		return nil, fmt.Errorf("unable to save video room: %w", err)
+	}
+	return room, nil
+}
+
+// VideoRoomAccessToken returns a token whose identity is the participant's
+// UID and whose grant is for the room alone. Rooms can be joined from
+// VideoRoomEarlyJoin before their start until VideoRoomGracePeriod after
+// their end, and tokens expire at the end of that window.
+func (v *VideoRoomImpl) VideoRoomAccessToken(ctx context.Context, uid string, id string) (*dto.VideoRoomToken, error) {
+	room, err := v.Repository.GetVideoRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !room.HasParticipant(uid) {
+		return nil, exceptions.ErrNotVideoRoomParticipant
+	}
+	if room.Status.IsEnded() {
+		return nil, fmt.Errorf("video room %s has ended", id)
+	}
+	now := v.Clock()
+	opensAt := room.ScheduledStart.Add(-VideoRoomEarlyJoin)
+	closesAt := room.ScheduledEnd.Add(VideoRoomGracePeriod)
+	if now.Before(opensAt) {
+		return nil, fmt.Errorf("video room %s can't be joined before %s", id, opensAt.Format(time.RFC3339))
+	}
+	if !now.Before(closesAt) {
+		return nil, fmt.Errorf("video room %s closed at %s", id, closesAt.Format(time.RFC3339))
+	}
+
+	if room.Status == domain.VideoRoomStatusScheduled {
+		if err := v.startVideoRoom(ctx, room); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := closesAt.Sub(now)
+	if ttl > MaxVideoTokenTTL {
+		ttl = MaxVideoTokenTTL
+	}
+	jwt, err := v.Video.AccessToken(uid, room.ID, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create video access token: %w", err)
+	}
+	return &dto.VideoRoomToken{
+		JWT:       jwt,
+		RoomID:    room.ID,
+		RoomSID:   room.RoomSID,
+		Identity:  uid,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// startVideoRoom creates the provider's room, named after the room's ID.
+//
+// Participants that ask to join at the same time each try to create the
+// room. The provider refuses to create a second room with the name, so the
+// room that was created first is used.
+func (v *VideoRoomImpl) startVideoRoom(ctx context.Context, room *domain.VideoRoom) error {
+	providerRoom, err := v.Video.CreateRoom(
+		ctx,
+		room.ID,
+		len(room.Participants),
+		v.Config.BaseURL+VideoRoomCallbackPath,
+	)
+	if err != nil {
+		existing, getErr := v.Video.GetRoom(ctx, room.ID)
+		if getErr != nil {
+			return fmt.Errorf("unable to create video room: %w", err)
+		}
+		providerRoom = existing
+	}
+
+	now := v.Clock()
+	room.RoomSID = providerRoom.SID
+	room.Status = domain.VideoRoomStatusInProgress
+	if room.StartedAt == nil {
+		room.StartedAt = &now
+	}
+	room.UpdatedAt = now
+	if err := v.Repository.SaveVideoRoom(ctx, room); err != nil {
+		return fmt.Errorf("unable to save video room: %w", err)
+	}
+	return nil
+}
+
+// HandleVideoRoomEvent records participant connections and room ends.
+//
+// The provider ends rooms a few minutes after everyone leaves. A room that
+// ends before its scheduled end is scheduled again so that participants who
+// dropped off can rejoin; otherwise it is completed.
+func (v *VideoRoomImpl) HandleVideoRoomEvent(ctx context.Context, event dto.VideoRoomEvent) error {
+	room, err := v.Repository.GetVideoRoom(ctx, event.RoomName)
+	if errors.Is(err, exceptions.ErrVideoRoomNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	at := event.Timestamp
+	if at.IsZero() {
+		at = v.Clock()
+	}
+
+	switch event.Event {
+	case videoParticipantConnected, videoParticipantDisconnected:
+		if event.ParticipantSID == "" {
+			return fmt.Errorf("a %s event has no participant", event.Event)
+		}
+		participant, err := v.Repository.GetVideoParticipant(ctx, event.ParticipantSID)
+		if errors.Is(err, exceptions.ErrVideoParticipantNotFound) {
+			// the disconnect of a participant can arrive before its connect
+			participant = &domain.VideoParticipant{
+				ID:       event.ParticipantSID,
+				RoomID:   room.ID,
+				RoomSID:  event.RoomSID,
+				Identity: event.ParticipantIdentity,
+			}
+		} else if err != nil {
+			return err
+		}
+		if event.Event == videoParticipantConnected {
+			participant.JoinedAt = &at
+		} else {
+			participant.LeftAt = &at
+			participant.DurationSeconds = event.ParticipantDuration
+		}
+		if err := v.Repository.SaveVideoParticipant(ctx, participant); err != nil {
+			return fmt.Errorf("unable to save video participant: %w", err)
+		}
+
+	case videoRoomEnded:
+		// rooms that were ended here, or started again, have moved on
+		if room.Status != domain.VideoRoomStatusInProgress || room.RoomSID != event.RoomSID {
+			return nil
+		}
+		if at.Before(room.ScheduledEnd) {
+			room.Status = domain.VideoRoomStatusScheduled
+			room.RoomSID = ""
+		} else {
+			room.Status = domain.VideoRoomStatusCompleted
+			room.EndedAt = &at
+		}
+		room.UpdatedAt = v.Clock()
+		if err := v.Repository.SaveVideoRoom(ctx, room); err != nil {
+			return fmt.Errorf("unable to save video room: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListVideoRoomParticipants returns the connections to a room
+func (v *VideoRoomImpl) ListVideoRoomParticipants(ctx context.Context, id string) ([]*domain.VideoParticipant, error) {
+	if _, err := v.Repository.GetVideoRoom(ctx, id); err != nil {
+		return nil, err
+	}
+	return v.Repository.ListVideoParticipants(ctx, id)
+}
+
+// applyVideoRoomInput validates an input and sets it on a room. Duplicate
+// participants are dropped.
+func (v *VideoRoomImpl) applyVideoRoomInput(room *domain.VideoRoom, input dto.VideoRoomInput) error {
+	appointmentID, itemID := "", ""
+	if input.AppointmentID != nil {
+		appointmentID = strings.TrimSpace(*input.AppointmentID)
+	}
+	if input.ItemID != nil {
+		itemID = strings.TrimSpace(*input.ItemID)
+	}
+	if (appointmentID == "") == (itemID == "") {
+		return fmt.Errorf("a video room is for either an appointment or a feed item")
+	}
+	if itemID != "" && (input.Flavour == nil || !input.Flavour.IsValid()) {
+		return fmt.Errorf("a valid flavour is required for a feed item's video room")
+	}
+
+	participants := []string{}
+	seen := map[string]bool{}
+	for _, participant := range input.Participants {
+		participant = strings.TrimSpace(participant)
+		if participant == "" || seen[participant] {
+			continue
+		}
+		seen[participant] = true
+		participants = append(participants, participant)
+	}
+	if len(participants) == 0 {
+		return fmt.Errorf("a video room needs at least one participant")
+	}
+	if len(participants) > MaxVideoRoomParticipants {
+		return fmt.Errorf("a video room can't have more than %d participants", MaxVideoRoomParticipants)
+	}
+
+	if !input.ScheduledEnd.After(input.ScheduledStart) {
+		return fmt.Errorf("a video room must end after it starts")
+	}
+	if input.ScheduledEnd.Sub(input.ScheduledStart) > MaxVideoRoomDuration {
+		return fmt.Errorf("a video room can't be longer than %s", MaxVideoRoomDuration)
+	}
+	if !input.ScheduledEnd.After(v.Clock()) {
+		return fmt.Errorf("a video room can't end in the past")
+	}
+
+	room.AppointmentID = appointmentID
+	room.ItemID = itemID
+	room.Flavour = nil
+	if itemID != "" {
+		flavour := *input.Flavour
+		room.Flavour = &flavour
+	}
+	room.Participants = participants
+	room.ScheduledStart = input.ScheduledStart
+	room.ScheduledEnd = input.ScheduledEnd
+	return nil
+}
@@ -0,0 +1,531 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/video"
+	videoMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/video/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+var testVideoAuthToken = []byte("video-auth-token")
+
+// testVideoRooms is a video room usecase with a fake provider that records
+// the rooms that were created and completed
+type testVideoRooms struct {
+	*testFixture
+	rooms *usecases.VideoRoomImpl
+
+	created   []string
+	completed []string
+	tokenTTLs []time.Duration
+
+	// createErr is returned by the fake provider when a room is created
+	createErr error
+}
+
+func newTestVideoRooms(f *testFixture) *testVideoRooms {
+	tv := &testVideoRooms{testFixture: f}
+	fake := &videoMock.FakeServiceVideo{
+		CreateRoomFn: func(
+			ctx context.Context,
+			uniqueName string,
+			maxParticipants int,
+			statusCallback string,
+		) (*video.Room, error) {
+			if tv.createErr != nil {
+				return nil, tv.createErr
+			}
+			tv.created = append(tv.created, uniqueName)
+			return &video.Room{
+				SID:        fmt.Sprintf("RM%d", len(tv.created)),
+				UniqueName: uniqueName,
+				Status:     "in-progress",
+			}, nil
+		},
+		GetRoomFn: func(ctx context.Context, sidOrUniqueName string) (*video.Room, error) {
+			if len(tv.created) == 0 {
+				return nil, fmt.Errorf("room not found")
+			}
+			return &video.Room{SID: "RM1", UniqueName: sidOrUniqueName, Status: "in-progress"}, nil
+		},
+		CompleteRoomFn: func(ctx context.Context, sid string) error {
+			tv.completed = append(tv.completed, sid)
+			return nil
+		},
+		AccessTokenFn: func(identity string, roomName string, ttl time.Duration) (string, error) {
+			tv.tokenTTLs = append(tv.tokenTTLs, ttl)
+			return identity + "@" + roomName, nil
+		},
+	}
+	rooms, err := usecases.NewVideoRooms(tv.repository, fake, usecases.VideoRoomConfig{
+		AuthToken: testVideoAuthToken,
+		BaseURL:   "https://engagement.example.com/",
+	})
+	assert.Nil(f.t, err)
+	rooms.Clock = tv.clock.Now
+	tv.rooms = rooms
+	return tv
+}
+
+// schedule schedules an appointment room for a clinician and a patient that
+// starts in an hour and lasts half an hour
+func (tv *testVideoRooms) schedule(t *testing.T) *domain.VideoRoom {
+	appointmentID := "appointment-1"
+	room, err := tv.rooms.ScheduleVideoRoom(context.Background(), "clinician", dto.VideoRoomInput{
+		AppointmentID:  &appointmentID,
+		Participants:   []string{"clinician", "patient"},
+		ScheduledStart: tv.clock.Now().Add(time.Hour),
+		ScheduledEnd:   tv.clock.Now().Add(90 * time.Minute),
+	})
+	assert.Nil(t, err)
+	return room
+}
+
+func TestNewVideoRooms(t *testing.T) {
+	repository := memory.NewRepository()
+
+	_, err := usecases.NewVideoRooms(repository, nil, usecases.VideoRoomConfig{
+		BaseURL: "https://engagement.example.com",
+	})
+	assert.NotNil(t, err)
+
+	_, err = usecases.NewVideoRooms(repository, nil, usecases.VideoRoomConfig{
+		AuthToken: testVideoAuthToken,
+		BaseURL:   "engagement.example.com",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestVideoRoomImpl_VerifyVideoCallbackSignature(t *testing.T) {
+	tv := newTestVideoRooms(newTestFixture(t))
+	form := url.Values{
+		"RoomName":            {"room-1"},
+		"StatusCallbackEvent": {"participant-connected"},
+	}
+	signature := usecases.SignTwilioWebhook(
+		testVideoAuthToken,
+		"https://engagement.example.com/video/callbacks",
+		form,
+	)
+
+	err := tv.rooms.VerifyVideoCallbackSignature(usecases.VideoRoomCallbackPath, form, signature)
+	assert.Nil(t, err)
+
+	form.Set("StatusCallbackEvent", "room-ended")
+	err = tv.rooms.VerifyVideoCallbackSignature(usecases.VideoRoomCallbackPath, form, signature)
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidVideoCallbackSignature))
+}
+
+func TestVideoRoomImpl_ScheduleVideoRoom(t *testing.T) {
+	ctx := context.Background()
+	appointmentID := "appointment-1"
+	itemID := "item-1"
+	consumer := feedlib.FlavourConsumer
+	start := time.Now().Add(time.Hour)
+
+	tooMany := []string{}
+	for i := 0; i <= usecases.MaxVideoRoomParticipants; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("user-%d", i))
+	}
+
+	tests := []struct {
+		name             string
+		input            dto.VideoRoomInput
+		wantErr          bool
+		wantParticipants []string
+	}{
+		{
+			name: "appointment room",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   []string{"clinician", "patient", "patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantParticipants: []string{"clinician", "patient"},
+		},
+		{
+			name: "feed item room",
+			input: dto.VideoRoomInput{
+				ItemID:         &itemID,
+				Flavour:        &consumer,
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantParticipants: []string{"patient"},
+		},
+		{
+			name: "feed item room without a flavour",
+			input: dto.VideoRoomInput{
+				ItemID:         &itemID,
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "both an appointment and a feed item",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				ItemID:         &itemID,
+				Flavour:        &consumer,
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "neither an appointment nor a feed item",
+			input: dto.VideoRoomInput{
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "no participants",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   []string{" "},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many participants",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   tooMany,
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "ends before it starts",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(-time.Minute),
+			},
+			wantErr: true,
+		},
+		{
+			name: "ended in the past",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   []string{"patient"},
+				ScheduledStart: start.Add(-3 * time.Hour),
+				ScheduledEnd:   start.Add(-2 * time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "too long",
+			input: dto.VideoRoomInput{
+				AppointmentID:  &appointmentID,
+				Participants:   []string{"patient"},
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(usecases.MaxVideoRoomDuration + time.Minute),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tv := newTestVideoRooms(newTestFixture(t))
+
+			room, err := tv.rooms.ScheduleVideoRoom(ctx, "clinician", tt.input)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, domain.VideoRoomStatusScheduled, room.Status)
+			assert.Equal(t, tt.wantParticipants, room.Participants)
+			assert.Empty(t, tv.created)
+
+			saved, err := tv.rooms.GetVideoRoom(ctx, room.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, room.ID, saved.ID)
+		})
+	}
+}
+
+func TestVideoRoomImpl_VideoRoomAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	room := tv.schedule(t)
+
+	_, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.NotNil(t, err, "rooms can't be joined long before they start")
+
+	tv.clock.Advance(time.Hour - usecases.VideoRoomEarlyJoin)
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "stranger", room.ID)
+	assert.True(t, errors.Is(err, exceptions.ErrNotVideoRoomParticipant))
+
+	token, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "patient@"+room.ID, token.JWT)
+	assert.Equal(t, "RM1", token.RoomSID)
+	assert.Equal(t, "patient", token.Identity)
+
+	// the token lasts until the end of the grace period
+	wantTTL := usecases.VideoRoomEarlyJoin + 30*time.Minute + usecases.VideoRoomGracePeriod
+	assert.Equal(t, wantTTL, tv.tokenTTLs[0])
+	assert.Equal(t, tv.clock.Now().Add(wantTTL), token.ExpiresAt)
+
+	// the room is only created once
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "clinician", room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{room.ID}, tv.created)
+
+	started, err := tv.rooms.GetVideoRoom(ctx, room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.VideoRoomStatusInProgress, started.Status)
+	assert.NotNil(t, started.StartedAt)
+
+	active, err := tv.rooms.ListActiveVideoRooms(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, active, 1)
+
+	tv.clock.Advance(usecases.VideoRoomEarlyJoin + 30*time.Minute + usecases.VideoRoomGracePeriod)
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.NotNil(t, err, "rooms can't be joined after the grace period")
+}
+
+func TestVideoRoomImpl_VideoRoomAccessToken_TTL(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	appointmentID := "appointment-1"
+	room, err := tv.rooms.ScheduleVideoRoom(ctx, "clinician", dto.VideoRoomInput{
+		AppointmentID:  &appointmentID,
+		Participants:   []string{"patient"},
+		ScheduledStart: tv.clock.Now(),
+		ScheduledEnd:   tv.clock.Now().Add(8 * time.Hour),
+	})
+	assert.Nil(t, err)
+
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, usecases.MaxVideoTokenTTL, tv.tokenTTLs[0])
+}
+
+func TestVideoRoomImpl_VideoRoomAccessToken_AlreadyCreated(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	room := tv.schedule(t)
+	tv.clock.Advance(time.Hour)
+
+	// another participant created the room at the same time
+	tv.created = []string{room.ID}
+	tv.createErr = fmt.Errorf("room exists")
+
+	token, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "RM1", token.RoomSID)
+}
+
+func TestVideoRoomImpl_UpdateVideoRoom(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	room := tv.schedule(t)
+	appointmentID := room.AppointmentID
+
+	later := dto.VideoRoomInput{
+		AppointmentID:  &appointmentID,
+		Participants:   []string{"clinician", "patient"},
+		ScheduledStart: room.ScheduledStart.Add(time.Hour),
+		ScheduledEnd:   room.ScheduledEnd.Add(time.Hour),
+	}
+	updated, err := tv.rooms.UpdateVideoRoom(ctx, room.ID, later)
+	assert.Nil(t, err)
+	assert.Equal(t, later.ScheduledStart, updated.ScheduledStart)
+
+	tv.clock.Advance(2 * time.Hour)
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+
+	// participants can be added to a room in progress
+	later.Participants = []string{"clinician", "patient", "interpreter"}
+	updated, err = tv.rooms.UpdateVideoRoom(ctx, room.ID, later)
+	assert.Nil(t, err)
+	assert.True(t, updated.HasParticipant("interpreter"))
+
+	// but it can't be moved
+	later.ScheduledStart = later.ScheduledStart.Add(time.Hour)
+	later.ScheduledEnd = later.ScheduledEnd.Add(time.Hour)
+	_, err = tv.rooms.UpdateVideoRoom(ctx, room.ID, later)
+	assert.NotNil(t, err)
+
+	_, err = tv.rooms.UpdateVideoRoom(ctx, "unknown", later)
+	assert.True(t, errors.Is(err, exceptions.ErrVideoRoomNotFound))
+}
+
+func TestVideoRoomImpl_EndVideoRoom(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+
+	scheduled := tv.schedule(t)
+	cancelled, err := tv.rooms.EndVideoRoom(ctx, "clinician", scheduled.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.VideoRoomStatusCancelled, cancelled.Status)
+	assert.Empty(t, tv.completed)
+
+	started := tv.schedule(t)
+	tv.clock.Advance(time.Hour)
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "patient", started.ID)
+	assert.Nil(t, err)
+
+	completed, err := tv.rooms.EndVideoRoom(ctx, "clinician", started.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.VideoRoomStatusCompleted, completed.Status)
+	assert.Equal(t, "clinician", completed.EndedBy)
+	assert.Equal(t, []string{"RM1"}, tv.completed)
+
+	_, err = tv.rooms.EndVideoRoom(ctx, "clinician", started.ID)
+	assert.NotNil(t, err)
+	_, err = tv.rooms.VideoRoomAccessToken(ctx, "patient", started.ID)
+	assert.NotNil(t, err)
+
+	active, err := tv.rooms.ListActiveVideoRooms(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, active)
+}
+
+func TestVideoRoomImpl_HandleVideoRoomEvent(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	room := tv.schedule(t)
+	tv.clock.Advance(time.Hour)
+	_, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+
+	joined := tv.clock.Now().Add(time.Minute)
+	left := joined.Add(10 * time.Minute)
+	events := []dto.VideoRoomEvent{
+		{
+			Event:               "participant-connected",
+			RoomSID:             "RM1",
+			RoomName:            room.ID,
+			ParticipantSID:      "PA1",
+			ParticipantIdentity: "patient",
+			Timestamp:           joined,
+		},
+		{
+			Event:               "track-added",
+			RoomSID:             "RM1",
+			RoomName:            room.ID,
+			ParticipantSID:      "PA1",
+			ParticipantIdentity: "patient",
+			Timestamp:           joined,
+		},
+		{
+			Event:               "participant-disconnected",
+			RoomSID:             "RM1",
+			RoomName:            room.ID,
+			ParticipantSID:      "PA1",
+			ParticipantIdentity: "patient",
+			ParticipantDuration: 600,
+			Timestamp:           left,
+		},
+		{
+			// the disconnect arrived before the connect
+			Event:               "participant-disconnected",
+			RoomSID:             "RM1",
+			RoomName:            room.ID,
+			ParticipantSID:      "PA2",
+			ParticipantIdentity: "clinician",
+			ParticipantDuration: 300,
+			Timestamp:           left,
+		},
+		{
+			Event:    "participant-connected",
+			RoomSID:  "RM9",
+			RoomName: "not-scheduled-here",
+		},
+	}
+	for _, event := range events {
+		assert.Nil(t, tv.rooms.HandleVideoRoomEvent(ctx, event))
+	}
+
+	participants, err := tv.rooms.ListVideoRoomParticipants(ctx, room.ID)
+	assert.Nil(t, err)
+	assert.Len(t, participants, 2)
+	assert.Equal(t, "patient", participants[0].Identity)
+	assert.Equal(t, joined, *participants[0].JoinedAt)
+	assert.Equal(t, left, *participants[0].LeftAt)
+	assert.Equal(t, 600, participants[0].DurationSeconds)
+	assert.Equal(t, "clinician", participants[1].Identity)
+	assert.Nil(t, participants[1].JoinedAt)
+
+	_, err = tv.rooms.ListVideoRoomParticipants(ctx, "unknown")
+	assert.True(t, errors.Is(err, exceptions.ErrVideoRoomNotFound))
+}
+
+func TestVideoRoomImpl_HandleVideoRoomEvent_RoomEnded(t *testing.T) {
+	ctx := context.Background()
+	tv := newTestVideoRooms(newTestFixture(t))
+	room := tv.schedule(t)
+	tv.clock.Advance(time.Hour)
+	_, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+
+	// the provider ended the room before its scheduled end, so it can be
+	// started again
+	err = tv.rooms.HandleVideoRoomEvent(ctx, dto.VideoRoomEvent{
+		Event:     "room-ended",
+		RoomSID:   "RM1",
+		RoomName:  room.ID,
+		Timestamp: tv.clock.Now().Add(10 * time.Minute),
+	})
+	assert.Nil(t, err)
+	rescheduled, err := tv.rooms.GetVideoRoom(ctx, room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.VideoRoomStatusScheduled, rescheduled.Status)
+	assert.Empty(t, rescheduled.RoomSID)
+
+	tv.clock.Advance(15 * time.Minute)
+	token, err := tv.rooms.VideoRoomAccessToken(ctx, "patient", room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "RM2", token.RoomSID)
+
+	// a late event of the first room is ignored
+	err = tv.rooms.HandleVideoRoomEvent(ctx, dto.VideoRoomEvent{
+		Event:     "room-ended",
+		RoomSID:   "RM1",
+		RoomName:  room.ID,
+		Timestamp: tv.clock.Now(),
+	})
+	assert.Nil(t, err)
+
+	err = tv.rooms.HandleVideoRoomEvent(ctx, dto.VideoRoomEvent{
+		Event:     "room-ended",
+		RoomSID:   "RM2",
+		RoomName:  room.ID,
+		Timestamp: room.ScheduledEnd.Add(5 * time.Minute),
+	})
+	assert.Nil(t, err)
+	ended, err := tv.rooms.GetVideoRoom(ctx, room.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.VideoRoomStatusCompleted, ended.Status)
+	assert.NotNil(t, ended.EndedAt)
+}
@@ -0,0 +1,240 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/whatsapp"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+)
+
+const (
+	// MaxWhatsAppTextLength and MaxWhatsAppCaptionLength are the longest
+	// text and media caption, in characters, that WhatsApp accepts
+	MaxWhatsAppTextLength    = 4096
+	MaxWhatsAppCaptionLength = 1024
+)
+
+// WhatsAppConfig holds the IDs of the approved templates that the service
+// sends on its own
+type WhatsAppConfig struct {
+	// VerificationTemplateID is the template
+	// `Your phone number verification code is {{1}}`
+	VerificationTemplateID string
+
+	// ItemTemplateID is the template that notifies a user of a feed item or
+	// a notification. Its placeholders are a title and a body e.g the item's
+	// tagline and summary.
+	ItemTemplateID string
+}
+
+// WhatsAppUsecases represent logic required to send WhatsApp messages. Each
+// method returns the provider's ID of the message.
+type WhatsAppUsecases interface {
+	// SendWhatsAppTemplate sends an approved template message, which is
+	// delivered outside a session
+	SendWhatsAppTemplate(ctx context.Context, input dto.WhatsAppTemplateInput) (string, error)
+
+	// SendWhatsAppText sends a text message in a session. It returns
+	// exceptions.ErrWhatsAppSessionExpired when the recipient is not in a
+	// session.
+	SendWhatsAppText(ctx context.Context, to string, text string) (string, error)
+
+	// SendWhatsAppMedia sends a media message in a session. It returns
+	// exceptions.ErrWhatsAppSessionExpired when the recipient is not in a
+	// session.
+	SendWhatsAppMedia(ctx context.Context, input dto.WhatsAppMediaInput) (string, error)
+
+	// SendWhatsAppNotification sends a notification's title and body with
+	// the item template, which is delivered outside a session
+	SendWhatsAppNotification(ctx context.Context, to string, title string, body string) (string, error)
+
+	// PhoneNumberVerificationCode sends a verification code with the
+	// verification template
+	PhoneNumberVerificationCode(ctx context.Context, to string, code string, marketingMessage string) (bool, error)
+}
+
+// WhatsAppImpl represents the WhatsApp usecase implementation
+type WhatsAppImpl struct {
+	WhatsApp   whatsapp.ServiceWhatsApp
+	Repository repository.WhatsAppRepository
+	Config     WhatsAppConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewWhatsApp initializes a WhatsApp usecase. The repository holds the
+// sessions that the inbound WhatsApp webhook opens.
+func NewWhatsApp(
+	whatsApp whatsapp.ServiceWhatsApp,
+	repository repository.WhatsAppRepository,
+	config WhatsAppConfig,
+) (*WhatsAppImpl, error) {
+	if config.VerificationTemplateID == "" || config.ItemTemplateID == "" {
+		return nil, fmt.Errorf("the WhatsApp verification and item template IDs are required")
+	}
+	return &WhatsAppImpl{
+		WhatsApp:   whatsApp,
+		Repository: repository,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SendWhatsAppTemplate sends an approved template to a phone number
+func (w *WhatsAppImpl) SendWhatsAppTemplate(ctx context.Context, input dto.WhatsAppTemplateInput) (string, error) {
+	phone, err := normalizeWhatsAppNumber(input.To)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(input.TemplateID) == "" {
+		return "", fmt.Errorf("a WhatsApp template ID is required")
+	}
+	parameters := []string{}
+	for i, parameter := range input.Parameters {
+		parameter = whatsAppTemplateParameter(parameter)
+		if parameter == "" {
+			return "", fmt.Errorf("WhatsApp template parameter %d is empty", i+1)
+		}
+		parameters = append(parameters, parameter)
+	}
+	id, err := w.WhatsApp.SendTemplate(ctx, phone, input.TemplateID, parameters)
+	if err != nil {
+		return "", fmt.Errorf("unable to send WhatsApp template: %w", err)
+	}
+	return id, nil
+}
+
+// SendWhatsAppText sends a text message to a phone number
+func (w *WhatsAppImpl) SendWhatsAppText(ctx context.Context, to string, text string) (string, error) {
+	phone, err := normalizeWhatsAppNumber(to)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("a WhatsApp message can't be empty")
+	}
+	if utf8.RuneCountInString(text) > MaxWhatsAppTextLength {
+		return "", fmt.Errorf("a WhatsApp message can't be longer than %d characters", MaxWhatsAppTextLength)
+	}
+	if err := w.checkSession(ctx, phone); err != nil {
+		return "", err
+	}
+	id, err := w.WhatsApp.SendText(ctx, phone, text)
+	if err != nil {
+		return "", fmt.Errorf("unable to send WhatsApp message: %w", err)
+	}
+	return id, nil
+}
+
+// SendWhatsAppMedia sends media from a public HTTPS URL to a phone number
+func (w *WhatsAppImpl) SendWhatsAppMedia(ctx context.Context, input dto.WhatsAppMediaInput) (string, error) {
+	phone, err := normalizeWhatsAppNumber(input.To)
+	if err != nil {
+		return "", err
+	}
+	mediaURL, err := url.Parse(input.MediaURL)
+	if err != nil || mediaURL.Scheme != "https" || mediaURL.Host == "" {
+		return "", fmt.Errorf("%q is not a valid HTTPS media URL", input.MediaURL)
+	}
+	caption := ""
+	if input.Caption != nil {
+		caption = *input.Caption
+	}
+	if utf8.RuneCountInString(caption) > MaxWhatsAppCaptionLength {
+		return "", fmt.Errorf("a WhatsApp caption can't be longer than %d characters", MaxWhatsAppCaptionLength)
+	}
+	if err := w.checkSession(ctx, phone); err != nil {
+		return "", err
+	}
+	id, err := w.WhatsApp.SendMedia(ctx, phone, mediaURL.String(), caption)
+	if err != nil {
+		return "", fmt.Errorf("unable to send WhatsApp media: %w", err)
+	}
+	return id, nil
+}
+
+// SendWhatsAppNotification sends the item template with a notification's
+// title and body. Notifications without a body repeat their title since
+// template parameters can't be empty.
+func (w *WhatsAppImpl) SendWhatsAppNotification(
+	ctx context.Context,
+	to string,
+	title string,
+	body string,
+) (string, error) {
+	title = whatsAppTemplateParameter(title)
+	body = whatsAppTemplateParameter(body)
+	if title == "" {
+		return "", fmt.Errorf("a WhatsApp notification needs a title")
+	}
+	if body == "" {
+		body = title
+	}
+	return w.SendWhatsAppTemplate(ctx, dto.WhatsAppTemplateInput{
+		To:         to,
+		TemplateID: w.Config.ItemTemplateID,
+		Parameters: []string{title, body},
+	})
+}
+
+// PhoneNumberVerificationCode sends a code with the verification template.
+//
+// The marketing message is not sent, since WhatsApp only delivers messages
+// that are not templates in a session that the user started.
+func (w *WhatsAppImpl) PhoneNumberVerificationCode(
+	ctx context.Context,
+	to string,
+	code string,
+	marketingMessage string,
+) (bool, error) {
+	_, err := w.SendWhatsAppTemplate(ctx, dto.WhatsAppTemplateInput{
+		To:         to,
+		TemplateID: w.Config.VerificationTemplateID,
+		Parameters: []string{code},
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkSession returns exceptions.ErrWhatsAppSessionExpired unless a phone
+// number has messaged us in the last 24 hours. WhatsApp drops messages that
+// are not templates outside a session, so they are refused here instead.
+func (w *WhatsAppImpl) checkSession(ctx context.Context, phone string) error {
+	session, err := w.Repository.GetWhatsAppSession(ctx, phone)
+	if errors.Is(err, exceptions.ErrWhatsAppSessionNotFound) {
+		return exceptions.ErrWhatsAppSessionExpired
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get WhatsApp session: %w", err)
+	}
+	if !session.ExpiresAt.After(w.Clock()) {
+		return exceptions.ErrWhatsAppSessionExpired
+	}
+	return nil
+}
+
+func normalizeWhatsAppNumber(to string) (string, error) {
+	phone, err := converterandformatter.NormalizeMSISDN(to)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid phone number: %w", to, err)
+	}
+	return *phone, nil
+}
+
+// whatsAppTemplateParameter removes what WhatsApp rejects in template
+// parameters: new lines, tabs and runs of spaces
+func whatsAppTemplateParameter(parameter string) string {
+	return strings.Join(strings.Fields(parameter), " ")
+}
@@ -0,0 +1,355 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	whatsAppMock "github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/services/whatsapp/mock"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	libNotification "github.com/savannahghi/engagementcore/pkg/engagement/usecases/feed"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/pubsubtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWhatsApp(t *testing.T) {
+	fake := &whatsAppMock.FakeServiceWhatsApp{}
+	repository := memory.NewRepository()
+
+	_, err := usecases.NewWhatsApp(fake, repository, usecases.WhatsAppConfig{ItemTemplateID: "HXitem"})
+	assert.NotNil(t, err)
+
+	_, err = usecases.NewWhatsApp(fake, repository, usecases.WhatsAppConfig{VerificationTemplateID: "HXverification"})
+	assert.NotNil(t, err)
+}
+
+func TestWhatsAppImpl_SendWhatsAppTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		input          dto.WhatsAppTemplateInput
+		wantErr        bool
+		wantTo         string
+		wantParameters []string
+	}{
+		{
+			name: "valid template",
+			input: dto.WhatsAppTemplateInput{
+				To:         "0722000000",
+				TemplateID: "HXappointment",
+				Parameters: []string{"Dr. Otieno", "Monday\n10am"},
+			},
+			wantTo:         "+254722000000",
+			wantParameters: []string{"Dr. Otieno", "Monday 10am"},
+		},
+		{
+			name: "invalid phone number",
+			input: dto.WhatsAppTemplateInput{
+				To:         "not a number",
+				TemplateID: "HXappointment",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing template",
+			input: dto.WhatsAppTemplateInput{
+				To: "+254722000000",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty parameter",
+			input: dto.WhatsAppTemplateInput{
+				To:         "+254722000000",
+				TemplateID: "HXappointment",
+				Parameters: []string{"Dr. Otieno", " \n "},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFixture(t)
+			w := f.whatsAppUsecases()
+
+			id, err := w.SendWhatsAppTemplate(ctx, tt.input)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Empty(t, f.whatsApp)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "SM1", id)
+			assert.Equal(t, tt.wantTo, f.whatsApp[0].to)
+			assert.Equal(t, tt.input.TemplateID, f.whatsApp[0].templateID)
+			assert.Equal(t, tt.wantParameters, f.whatsApp[0].parameters)
+		})
+	}
+}
+
+func TestWhatsAppImpl_SendWhatsAppText(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		to      string
+		text    string
+		wantErr bool
+	}{
+		{
+			name: "valid text",
+			to:   "+254722000000",
+			text: "Your results are ready",
+		},
+		{
+			name:    "empty text",
+			to:      "+254722000000",
+			text:    "  ",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			to:      "+254722000000",
+			text:    strings.Repeat("a", usecases.MaxWhatsAppTextLength+1),
+			wantErr: true,
+		},
+		{
+			name:    "invalid phone number",
+			to:      "12",
+			text:    "Your results are ready",
+			wantErr: true,
+		},
+		{
+			name:    "not in a session",
+			to:      "+254733000000",
+			text:    "Your results are ready",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFixture(t)
+			f.openWhatsAppSession("+254722000000")
+			w := f.whatsAppUsecases()
+
+			_, err := w.SendWhatsAppText(ctx, tt.to, tt.text)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Empty(t, f.whatsApp)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.text, f.whatsApp[0].text)
+		})
+	}
+}
+
+func TestWhatsAppImpl_SendWhatsAppMedia(t *testing.T) {
+	ctx := context.Background()
+	caption := "Your prescription"
+	longCaption := strings.Repeat("a", usecases.MaxWhatsAppCaptionLength+1)
+
+	tests := []struct {
+		name    string
+		input   dto.WhatsAppMediaInput
+		wantErr bool
+	}{
+		{
+			name: "valid media",
+			input: dto.WhatsAppMediaInput{
+				To:       "+254722000000",
+				MediaURL: "https://assets.bewell.co.ke/prescription.pdf",
+				Caption:  &caption,
+			},
+		},
+		{
+			name: "media without a caption",
+			input: dto.WhatsAppMediaInput{
+				To:       "+254722000000",
+				MediaURL: "https://assets.bewell.co.ke/prescription.pdf",
+			},
+		},
+		{
+			name: "media that is not served over HTTPS",
+			input: dto.WhatsAppMediaInput{
+				To:       "+254722000000",
+				MediaURL: "http://assets.bewell.co.ke/prescription.pdf",
+			},
+			wantErr: true,
+		},
+		{
+			name: "caption too long",
+			input: dto.WhatsAppMediaInput{
+				To:       "+254722000000",
+				MediaURL: "https://assets.bewell.co.ke/prescription.pdf",
+				Caption:  &longCaption,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFixture(t)
+			f.openWhatsAppSession("+254722000000")
+			w := f.whatsAppUsecases()
+
+			_, err := w.SendWhatsAppMedia(ctx, tt.input)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Empty(t, f.whatsApp)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.input.MediaURL, f.whatsApp[0].mediaURL)
+		})
+	}
+}
+
+func TestWhatsAppImpl_SessionExpiry(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	f.openWhatsAppSession("+254722000000")
+	w := f.whatsAppUsecases()
+
+	_, err := w.SendWhatsAppText(ctx, "+254722000000", "How are you feeling today?")
+	assert.Nil(t, err)
+
+	f.clock.Advance(usecases.WhatsAppSessionWindow)
+	_, err = w.SendWhatsAppText(ctx, "+254722000000", "How are you feeling today?")
+	assert.True(t, errors.Is(err, exceptions.ErrWhatsAppSessionExpired))
+	assert.Len(t, f.whatsApp, 1)
+
+	// templates are delivered outside a session
+	_, err = w.SendWhatsAppTemplate(ctx, dto.WhatsAppTemplateInput{
+		To:         "+254722000000",
+		TemplateID: "HXcheckin",
+	})
+	assert.Nil(t, err)
+}
+
+func TestWhatsAppImpl_SendWhatsAppNotification(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	w := f.whatsAppUsecases()
+
+	_, err := w.SendWhatsAppNotification(ctx, "+254722000000", "Lab results", "Your lab results\nare ready")
+	assert.Nil(t, err)
+	_, err = w.SendWhatsAppNotification(ctx, "+254722000000", "Lab results", "")
+	assert.Nil(t, err)
+	_, err = w.SendWhatsAppNotification(ctx, "+254722000000", " ", "Your lab results are ready")
+	assert.NotNil(t, err)
+
+	assert.Len(t, f.whatsApp, 2)
+	assert.Equal(t, "HXitem", f.whatsApp[0].templateID)
+	assert.Equal(t, []string{"Lab results", "Your lab results are ready"}, f.whatsApp[0].parameters)
+	assert.Equal(t, []string{"Lab results", "Lab results"}, f.whatsApp[1].parameters)
+}
+
+// fakePhoneNumbers are the phone numbers of users
+type fakePhoneNumbers map[string][]string
+
+func (f fakePhoneNumbers) GetPhoneNumbers(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error) {
+	numbers := map[string][]string{}
+	for _, uid := range uids.UIDs {
+		numbers[uid] = f[uid]
+	}
+	return numbers, nil
+}
+
+// fakeItemNotifications stands in for the feed library's notifications of
+// item updates
+type fakeItemNotifications struct {
+	libNotification.NotificationUsecases
+}
+
+func (f fakeItemNotifications) NotifyItemUpdate(
+	ctx context.Context,
+	sender string,
+	includeNotification bool,
+	m *pubsubtools.PubSubPayload,
+) error {
+	return nil
+}
+
+func TestNotificationImpl_HandleItemPublish_WhatsAppDigest(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	rateLimits := usecases.NewRateLimits(f.repository)
+	_, err := rateLimits.SetRateLimit(ctx, dto.RateLimitPolicyInput{
+		Flavour:       feedlib.FlavourConsumer,
+		Channel:       feedlib.ChannelWhatsapp,
+		Limit:         1,
+		WindowSeconds: 3600,
+	})
+	assert.Nil(t, err)
+	n := usecases.NewNotification(
+		nil,
+		fakeItemNotifications{},
+		usecases.NewTemplates(f.repository),
+		nil,
+		rateLimits,
+		nil,
+		nil,
+		nil,
+		f.whatsAppUsecases(),
+		fakePhoneNumbers{"uid-1": {"+254722000000", "+254733000000"}},
+		usecases.NewInbox(f.repository),
+	)
+
+	for _, id := range []string{"item-1", "item-2", "item-3"} {
+		err := n.HandleItemPublish(ctx, f.pubSubPayload(feedlib.Item{
+			ID:                   id,
+			Tagline:              "Lab results",
+			Summary:              "Your lab results are ready",
+			Users:                []string{"uid-1"},
+			NotificationChannels: []feedlib.Channel{feedlib.ChannelWhatsapp},
+		}))
+		assert.Nil(t, err)
+	}
+	if assert.Len(t, f.whatsApp, 1) {
+		assert.Equal(t, "HXitem", f.whatsApp[0].templateID)
+		assert.Equal(t, []string{"Lab results", "Your lab results are ready"}, f.whatsApp[0].parameters)
+	}
+
+	// only the item that was sent is in the user's inbox
+	channel := feedlib.ChannelWhatsapp
+	inbox, err := usecases.NewInbox(f.repository).GetInbox(ctx, "uid-1", &channel, nil, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, inbox.Edges, 1) {
+		assert.Equal(t, "SM1", inbox.Edges[0].Node.MessageID)
+		assert.Equal(t, "item-1", inbox.Edges[0].Node.Data["itemID"])
+	}
+
+	// the held back items are summarised to the user's phone number
+	count, err := n.SendDueDigests(ctx, time.Now().Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	if assert.Len(t, f.whatsApp, 2) {
+		assert.Equal(t, "+254722000000", f.whatsApp[1].to)
+		assert.Equal(t, []string{"New updates", "You have 2 new updates"}, f.whatsApp[1].parameters)
+	}
+}
+
+func TestWhatsAppImpl_PhoneNumberVerificationCode(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFixture(t)
+	w := f.whatsAppUsecases()
+
+	ok, err := w.PhoneNumberVerificationCode(ctx, "+254722000000", "123456", "Welcome to Be.Well")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "HXverification", f.whatsApp[0].templateID)
+	assert.Equal(t, []string{"123456"}, f.whatsApp[0].parameters)
+
+	ok, err = w.PhoneNumberVerificationCode(ctx, "", "123456", "")
+	assert.NotNil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,561 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 Twilio signs webhooks with HMAC-SHA1
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/repository"
+	"github.com/savannahghi/feedlib"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// InboundWhatsAppPath is the path of the endpoint that the WhatsApp
+	// provider posts received messages to
+	InboundWhatsAppPath = "/whatsapp/inbound"
+
+	// WhatsAppSessionWindow is how long after a phone number messages us
+	// that it can be sent messages that are not templates
+	WhatsAppSessionWindow = 24 * time.Hour
+
+	// WhatsAppConversationExpiry is how long replies from a phone number are
+	// posted to the feed item that it was messaged about
+	WhatsAppConversationExpiry = 7 * 24 * time.Hour
+
+	// WhatsAppNudgeExpiry is how long the quick reply buttons of a nudge can
+	// be used, unless the nudge expires sooner
+	WhatsAppNudgeExpiry = 7 * 24 * time.Hour
+
+	defaultWhatsAppSupportQueueSize = 50
+	maxWhatsAppSupportQueueSize     = 100
+)
+
+// nonEventNameCharacters matches what can't be in an event name. Event names
+// are upper case words separated by underscores.
+var nonEventNameCharacters = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// WhatsAppConversationConfig holds what the WhatsApp provider's webhook
+// requests are verified with
+type WhatsAppConversationConfig struct {
+	// AuthToken is the Twilio auth token that signs webhook requests
+	AuthToken []byte
+
+	// BaseURL is the public URL of the service e.g `https://example.com`.
+	// Signatures cover the full URL that the provider posted to.
+	BaseURL string
+}
+
+// FeedNudges reads nudges and processes the events that their actions
+// trigger
+type FeedNudges interface {
+	GetNudge(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		nudgeID string,
+	) (*feedlib.Nudge, error)
+
+	ProcessEvent(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		event *feedlib.Event,
+	) error
+}
+
+// WhatsAppConversationUsecases represent logic required to receive WhatsApp
+// messages and route them to feed items, nudges or the support queue
+type WhatsAppConversationUsecases interface {
+	// VerifyWhatsAppWebhookSignature checks the signature of a webhook
+	// request to a path and query with the posted form values. It returns
+	// exceptions.ErrInvalidWhatsAppWebhookSignature when the signature does
+	// not match.
+	VerifyWhatsAppWebhookSignature(requestURI string, form url.Values, signature string) error
+
+	// HandleInboundWhatsApp opens the sender's session and routes the
+	// message
+	HandleInboundWhatsApp(ctx context.Context, message *domain.InboundWhatsApp) (*domain.InboundWhatsApp, error)
+
+	// StartWhatsAppConversation messages a phone number about a feed item
+	StartWhatsAppConversation(
+		ctx context.Context,
+		createdBy string,
+		input dto.WhatsAppConversationInput,
+	) (*domain.WhatsAppConversation, error)
+
+	// SendWhatsAppNudge sends a nudge with quick reply buttons
+	SendWhatsAppNudge(
+		ctx context.Context,
+		createdBy string,
+		input dto.WhatsAppNudgeInput,
+	) (*domain.WhatsAppNudge, error)
+
+	// GetWhatsAppSession returns the session of a phone number. Numbers that
+	// have never messaged us have a session that expired at the zero time.
+	GetWhatsAppSession(ctx context.Context, phone string) (*domain.WhatsAppSession, error)
+
+	// WhatsAppSupportQueue returns the unresolved messages that were not
+	// routed to a feed item or nudge, oldest first
+	WhatsAppSupportQueue(ctx context.Context, limit int) ([]*domain.InboundWhatsApp, error)
+
+	// ResolveWhatsAppSupportMessage removes a message from the support queue
+	ResolveWhatsAppSupportMessage(
+		ctx context.Context,
+		resolvedBy string,
+		id string,
+	) (*domain.InboundWhatsApp, error)
+}
+
+// WhatsAppConversationImpl represents the WhatsApp conversation usecase
+// implementation
+type WhatsAppConversationImpl struct {
+	Repository repository.WhatsAppRepository
+	WhatsApp   WhatsAppUsecases
+	Feed       FeedMessages
+	Nudges     FeedNudges
+	Inbox      InboxUsecases
+	Config     WhatsAppConversationConfig
+
+	// Clock returns the current time. It can be replaced in tests.
+	Clock func() time.Time
+}
+
+// NewWhatsAppConversations initializes a WhatsApp conversation usecase
+func NewWhatsAppConversations(
+	repository repository.WhatsAppRepository,
+	whatsApp WhatsAppUsecases,
+	feed FeedMessages,
+	nudges FeedNudges,
+	inbox InboxUsecases,
+	config WhatsAppConversationConfig,
+) (*WhatsAppConversationImpl, error) {
+	if len(config.AuthToken) == 0 {
+		return nil, fmt.Errorf("the WhatsApp webhook auth token is required")
+	}
+	base, err := url.Parse(config.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%q is not a valid base URL", config.BaseURL)
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &WhatsAppConversationImpl{
+		Repository: repository,
+		WhatsApp:   whatsApp,
+		Feed:       feed,
+		Nudges:     nudges,
+		Inbox:      inbox,
+		Config:     config,
+		Clock:      time.Now,
+	}, nil
+}
+
+// SignTwilioWebhook returns Twilio's signature of a webhook request: the
+// base64 HMAC-SHA1 of the URL followed by each form field's name and value,
+// in name order
+func SignTwilioWebhook(authToken []byte, webhookURL string, form url.Values) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	content := webhookURL
+	for _, name := range names {
+		for _, value := range form[name] {
+			content += name + value
+		}
+	}
+	mac := hmac.New(sha1.New, authToken)
+	mac.Write([]byte(content))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWhatsAppWebhookSignature compares a signature with the signature of
+// the request in constant time
+func (w *WhatsAppConversationImpl) VerifyWhatsAppWebhookSignature(
+	requestURI string,
+	form url.Values,
+	signature string,
+) error {
+	want := SignTwilioWebhook(w.Config.AuthToken, w.Config.BaseURL+requestURI, form)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return exceptions.ErrInvalidWhatsAppWebhookSignature
+	}
+	return nil
+}
+
+// HandleInboundWhatsApp saves a received message after routing it.
+//
+// Every message opens or extends the sender's 24 hour session. A tap on a
+// quick reply button of the sender's latest nudge is processed as the event
+// of the nudge action with the same name. Other messages are posted to the
+// feed item that the sender was last messaged about, or added to the support
+// queue when the sender is not in a conversation. A message that was already
+// received is returned as it was saved.
+func (w *WhatsAppConversationImpl) HandleInboundWhatsApp(
+	ctx context.Context,
+	message *domain.InboundWhatsApp,
+) (*domain.InboundWhatsApp, error) {
+	if message.ID == "" {
+		return nil, fmt.Errorf("a received WhatsApp message needs an ID")
+	}
+	existing, err := w.Repository.GetInboundWhatsApp(ctx, message.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, exceptions.ErrInboundWhatsAppNotFound) {
+		return nil, fmt.Errorf("unable to get received WhatsApp message: %w", err)
+	}
+
+	phone, err := normalizeWhatsAppNumber(strings.TrimPrefix(message.From, "whatsapp:"))
+	if err != nil {
+		return nil, err
+	}
+	message.From = phone
+	message.To = strings.TrimPrefix(message.To, "whatsapp:")
+	message.ReceivedAt = w.Clock()
+
+	err = w.Repository.SaveWhatsAppSession(ctx, &domain.WhatsAppSession{
+		Phone:         phone,
+		LastMessageAt: message.ReceivedAt,
+		ExpiresAt:     message.ReceivedAt.Add(WhatsAppSessionWindow),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save WhatsApp session: %w", err)
+	}
+
+	answered := false
+	if message.ButtonPayload != "" || message.ButtonText != "" {
+		answered, err = w.answerNudge(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !answered {
+		if err := w.postReply(ctx, message); err != nil {
+			return nil, err
+		}
+		message.Support = message.ConversationID == ""
+	}
+
+	if err := w.Repository.SaveInboundWhatsApp(ctx, message); err != nil {
+		return nil, fmt.Errorf("unable to save received WhatsApp message: %w", err)
+	}
+	return message, nil
+}
+
+// answerNudge processes a quick reply as the event of the action of the
+// sender's latest nudge that it names. It reports false when the sender has
+// no unanswered nudge or the nudge has no such action.
+func (w *WhatsAppConversationImpl) answerNudge(ctx context.Context, message *domain.InboundWhatsApp) (bool, error) {
+	sent, err := w.Repository.GetActiveWhatsAppNudge(ctx, message.From, message.ReceivedAt)
+	if errors.Is(err, exceptions.ErrWhatsAppNudgeNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get WhatsApp nudge: %w", err)
+	}
+	nudge, err := w.Nudges.GetNudge(ctx, sent.UID, sent.Flavour, sent.NudgeID)
+	if err != nil {
+		return false, fmt.Errorf("unable to get nudge %s: %w", sent.NudgeID, err)
+	}
+
+	var action *feedlib.Action
+	for i, a := range nudge.Actions {
+		if strings.EqualFold(a.Name, strings.TrimSpace(message.ButtonPayload)) ||
+			strings.EqualFold(a.Name, strings.TrimSpace(message.ButtonText)) {
+			action = &nudge.Actions[i]
+			break
+		}
+	}
+	if action == nil {
+		return false, nil
+	}
+
+	// the event ID is derived from the provider's ID so that the event is
+	// not processed again when the message is received again
+	event := &feedlib.Event{
+		ID:   "whatsapp-" + message.ID,
+		Name: nudgeEventName(action.Name),
+		Context: feedlib.Context{
+			UserID:    sent.UID,
+			Flavour:   sent.Flavour,
+			Timestamp: message.ReceivedAt,
+		},
+		Payload: feedlib.Payload{Data: map[string]interface{}{
+			"nudgeID":    sent.NudgeID,
+			"actionID":   action.ID,
+			"actionName": action.Name,
+			"channel":    feedlib.ChannelWhatsapp.String(),
+			"messageID":  message.ID,
+		}},
+	}
+	if err := w.Nudges.ProcessEvent(ctx, sent.UID, sent.Flavour, event); err != nil {
+		return false, fmt.Errorf("unable to process nudge event: %w", err)
+	}
+
+	sent.AnsweredWith = action.Name
+	sent.AnsweredAt = message.ReceivedAt
+	if err := w.Repository.SaveWhatsAppNudge(ctx, sent); err != nil {
+		return false, fmt.Errorf("unable to save WhatsApp nudge: %w", err)
+	}
+	message.NudgeID = sent.NudgeID
+	message.EventID = event.ID
+	return true, nil
+}
+
+// postReply posts a message, with links to its media, to the sender's
+// active conversation. Messages from senders that are not in a conversation
+// are left for the support queue.
+func (w *WhatsAppConversationImpl) postReply(ctx context.Context, message *domain.InboundWhatsApp) error {
+	conversation, err := w.Repository.GetActiveWhatsAppConversation(ctx, message.From, message.ReceivedAt)
+	if errors.Is(err, exceptions.ErrWhatsAppConversationNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get WhatsApp conversation: %w", err)
+	}
+
+	text := message.Text
+	if len(message.MediaURLs) > 0 {
+		links := []string{}
+		for _, mediaURL := range message.MediaURLs {
+			links = append(links, "- "+mediaURL)
+		}
+		text = strings.TrimSpace(text + "\n\nAttachments:\n" + strings.Join(links, "\n"))
+	}
+	name := message.ProfileName
+	if name == "" {
+		name = message.From
+	}
+
+	// the message ID is derived from the provider's ID so that a webhook
+	// that is retried replaces the message instead of posting it again
+	_, err = w.Feed.PostMessage(ctx, conversation.UID, conversation.Flavour, conversation.ItemID, &feedlib.Message{
+		ID:             "whatsapp-" + message.ID,
+		SequenceNumber: int(message.ReceivedAt.Unix()),
+		Text:           text,
+		PostedByUID:    conversation.UID,
+		PostedByName:   name,
+		Timestamp:      message.ReceivedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to post WhatsApp reply: %w", err)
+	}
+	message.ConversationID = conversation.ID
+	return nil
+}
+
+// StartWhatsAppConversation sends a template to a phone number and records
+// the feed item that its replies belong to
+func (w *WhatsAppConversationImpl) StartWhatsAppConversation(
+	ctx context.Context,
+	createdBy string,
+	input dto.WhatsAppConversationInput,
+) (*domain.WhatsAppConversation, error) {
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid flavour", input.Flavour)
+	}
+	if input.UID == "" || input.ItemID == "" {
+		return nil, fmt.Errorf("a WhatsApp conversation needs a UID and an item ID")
+	}
+	phone, err := normalizeWhatsAppNumber(input.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	messageID, err := w.WhatsApp.SendWhatsAppTemplate(ctx, dto.WhatsAppTemplateInput{
+		To:         phone,
+		TemplateID: input.TemplateID,
+		Parameters: input.Parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := w.Clock()
+	conversation := &domain.WhatsAppConversation{
+		ID:        ksuid.New().String(),
+		Phone:     phone,
+		UID:       input.UID,
+		Flavour:   input.Flavour,
+		ItemID:    input.ItemID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(WhatsAppConversationExpiry),
+	}
+	if err := w.Repository.SaveWhatsAppConversation(ctx, conversation); err != nil {
+		return nil, fmt.Errorf("unable to save WhatsApp conversation: %w", err)
+	}
+
+	// the template's text is kept by WhatsApp, so the inbox shows its
+	// parameters
+	recordDispatch(ctx, w.Inbox, &domain.InboxNotification{
+		UID:       conversation.UID,
+		Channel:   feedlib.ChannelWhatsapp,
+		MessageID: messageID,
+		Body:      strings.Join(input.Parameters, "\n"),
+		Data: map[string]interface{}{
+			"flavour":        string(conversation.Flavour),
+			"itemID":         conversation.ItemID,
+			"conversationID": conversation.ID,
+			"templateID":     input.TemplateID,
+		},
+		CreatedAt: now,
+	})
+	return conversation, nil
+}
+
+// SendWhatsAppNudge sends a nudge's title and text with a quick reply
+// template and records the nudge so that taps on its buttons trigger the
+// nudge's actions
+func (w *WhatsAppConversationImpl) SendWhatsAppNudge(
+	ctx context.Context,
+	createdBy string,
+	input dto.WhatsAppNudgeInput,
+) (*domain.WhatsAppNudge, error) {
+	if !input.Flavour.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid flavour", input.Flavour)
+	}
+	if input.UID == "" || input.NudgeID == "" {
+		return nil, fmt.Errorf("a WhatsApp nudge needs a UID and a nudge ID")
+	}
+	phone, err := normalizeWhatsAppNumber(input.Phone)
+	if err != nil {
+		return nil, err
+	}
+	nudge, err := w.Nudges.GetNudge(ctx, input.UID, input.Flavour, input.NudgeID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get nudge %s: %w", input.NudgeID, err)
+	}
+	if len(nudge.Actions) == 0 {
+		return nil, fmt.Errorf("nudge %s has no actions to reply with", input.NudgeID)
+	}
+
+	now := w.Clock()
+	expiresAt := now.Add(WhatsAppNudgeExpiry)
+	if !nudge.Expiry.IsZero() && nudge.Expiry.Before(expiresAt) {
+		expiresAt = nudge.Expiry
+	}
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("nudge %s has expired", input.NudgeID)
+	}
+
+	text := nudge.Text
+	if strings.TrimSpace(text) == "" {
+		text = nudge.Title
+	}
+	messageID, err := w.WhatsApp.SendWhatsAppTemplate(ctx, dto.WhatsAppTemplateInput{
+		To:         phone,
+		TemplateID: input.TemplateID,
+		Parameters: []string{nudge.Title, text},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sent := &domain.WhatsAppNudge{
+		ID:        ksuid.New().String(),
+		Phone:     phone,
+		UID:       input.UID,
+		Flavour:   input.Flavour,
+		NudgeID:   input.NudgeID,
+		MessageID: messageID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := w.Repository.SaveWhatsAppNudge(ctx, sent); err != nil {
+		return nil, fmt.Errorf("unable to save WhatsApp nudge: %w", err)
+	}
+
+	recordDispatch(ctx, w.Inbox, &domain.InboxNotification{
+		UID:       sent.UID,
+		Channel:   feedlib.ChannelWhatsapp,
+		MessageID: messageID,
+		Title:     nudge.Title,
+		Body:      text,
+		Data: map[string]interface{}{
+			"flavour":    string(sent.Flavour),
+			"nudgeID":    sent.NudgeID,
+			"templateID": input.TemplateID,
+		},
+		CreatedAt: now,
+	})
+	return sent, nil
+}
+
+// GetWhatsAppSession retrieves the session of a phone number
+func (w *WhatsAppConversationImpl) GetWhatsAppSession(
+	ctx context.Context,
+	phone string,
+) (*domain.WhatsAppSession, error) {
+	number, err := normalizeWhatsAppNumber(phone)
+	if err != nil {
+		return nil, err
+	}
+	session, err := w.Repository.GetWhatsAppSession(ctx, number)
+	if errors.Is(err, exceptions.ErrWhatsAppSessionNotFound) {
+		return &domain.WhatsAppSession{Phone: number}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get WhatsApp session: %w", err)
+	}
+	return session, nil
+}
+
+// WhatsAppSupportQueue lists the unresolved support messages
+func (w *WhatsAppConversationImpl) WhatsAppSupportQueue(
+	ctx context.Context,
+	limit int,
+) ([]*domain.InboundWhatsApp, error) {
+	if limit <= 0 {
+		limit = defaultWhatsAppSupportQueueSize
+	}
+	if limit > maxWhatsAppSupportQueueSize {
+		limit = maxWhatsAppSupportQueueSize
+	}
+	return w.Repository.ListWhatsAppSupportQueue(ctx, limit)
+}
+
+// ResolveWhatsAppSupportMessage marks a support message as dealt with
+func (w *WhatsAppConversationImpl) ResolveWhatsAppSupportMessage(
+	ctx context.Context,
+	resolvedBy string,
+	id string,
+) (*domain.InboundWhatsApp, error) {
+	message, err := w.Repository.GetInboundWhatsApp(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !message.Support {
+		return nil, fmt.Errorf("WhatsApp message %s is not in the support queue", id)
+	}
+	if message.Resolved {
+		return message, nil
+	}
+	message.Resolved = true
+	message.ResolvedBy = resolvedBy
+	message.ResolvedAt = w.Clock()
+	if err := w.Repository.SaveInboundWhatsApp(ctx, message); err != nil {
+		return nil, fmt.Errorf("unable to save WhatsApp message: %w", err)
+	}
+	return message, nil
+}
+
+// nudgeEventName converts the name of a nudge action to an event name e.g
+// `Verify email` to `VERIFY_EMAIL`
+func nudgeEventName(action string) string {
+	return strings.Trim(nonEventNameCharacters.ReplaceAllString(strings.ToUpper(action), "_"), "_")
+}
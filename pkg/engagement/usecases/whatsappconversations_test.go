@@ -0,0 +1,374 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagement-service/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagement-service/pkg/engagement/domain"
+	"github.com/savannahghi/engagement-service/pkg/engagement/infrastructure/database/memory"
+	"github.com/savannahghi/engagement-service/pkg/engagement/usecases"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+var testWhatsAppAuthToken = []byte("twilio-auth-token")
+
+// fakeNudges serves nudges and records the events that are processed
+type fakeNudges struct {
+	nudges map[string]*feedlib.Nudge
+	events []*feedlib.Event
+}
+
+func (f *fakeNudges) GetNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudgeID string,
+) (*feedlib.Nudge, error) {
+	nudge, ok := f.nudges[nudgeID]
+	if !ok {
+		return nil, errors.New("nudge not found")
+	}
+	return nudge, nil
+}
+
+func (f *fakeNudges) ProcessEvent(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	event *feedlib.Event,
+) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+type testWhatsAppConversations struct {
+	*testFixture
+	conversations *usecases.WhatsAppConversationImpl
+	nudges        *fakeNudges
+}
+
+func newTestWhatsAppConversations(f *testFixture) *testWhatsAppConversations {
+	nudges := &fakeNudges{nudges: map[string]*feedlib.Nudge{
+		"nudge-1": {
+			ID:    "nudge-1",
+			Title: "Verify your email",
+			Text:  "Verify your email to get your lab results by email",
+			Actions: []feedlib.Action{
+				{ID: "action-1", Name: "Verify email"},
+				{ID: "action-2", Name: "Later"},
+			},
+		},
+		"nudge-2": {ID: "nudge-2", Title: "Welcome to Be.Well"},
+	}}
+	w, err := usecases.NewWhatsAppConversations(
+		f.repository,
+		f.whatsAppUsecases(),
+		f.feed,
+		nudges,
+		usecases.NewInbox(f.repository),
+		usecases.WhatsAppConversationConfig{
+			AuthToken: testWhatsAppAuthToken,
+			BaseURL:   "https://engagement.example.com/",
+		},
+	)
+	if err != nil {
+		f.t.Fatalf("unable to initialize WhatsApp conversations: %v", err)
+	}
+	w.Clock = f.clock.Now
+	return &testWhatsAppConversations{
+		testFixture:   f,
+		conversations: w,
+		nudges:        nudges,
+	}
+}
+
+func TestNewWhatsAppConversations(t *testing.T) {
+	repository := memory.NewRepository()
+
+	_, err := usecases.NewWhatsAppConversations(repository, nil, nil, nil, nil, usecases.WhatsAppConversationConfig{
+		BaseURL: "https://engagement.example.com",
+	})
+	assert.NotNil(t, err)
+
+	_, err = usecases.NewWhatsAppConversations(repository, nil, nil, nil, nil, usecases.WhatsAppConversationConfig{
+		AuthToken: testWhatsAppAuthToken,
+		BaseURL:   "engagement.example.com",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestWhatsAppConversationImpl_VerifyWhatsAppWebhookSignature(t *testing.T) {
+	w := newTestWhatsAppConversations(newTestFixture(t)).conversations
+	form := url.Values{
+		"MessageSid": {"SM123"},
+		"From":       {"whatsapp:+254722000000"},
+		"Body":       {"Hello"},
+	}
+	signature := usecases.SignTwilioWebhook(
+		testWhatsAppAuthToken,
+		"https://engagement.example.com/whatsapp/inbound?source=twilio",
+		form,
+	)
+
+	err := w.VerifyWhatsAppWebhookSignature("/whatsapp/inbound?source=twilio", form, signature)
+	assert.Nil(t, err)
+
+	err = w.VerifyWhatsAppWebhookSignature("/whatsapp/inbound", form, signature)
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidWhatsAppWebhookSignature))
+
+	tampered := url.Values{
+		"MessageSid": {"SM123"},
+		"From":       {"whatsapp:+254722000000"},
+		"Body":       {"Goodbye"},
+	}
+	err = w.VerifyWhatsAppWebhookSignature("/whatsapp/inbound?source=twilio", tampered, signature)
+	assert.True(t, errors.Is(err, exceptions.ErrInvalidWhatsAppWebhookSignature))
+}
+
+func TestWhatsAppConversationImpl_HandleInboundWhatsApp_Session(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+
+	_, err := tw.conversations.WhatsApp.SendWhatsAppText(ctx, "+254722000000", "How are you feeling?")
+	assert.True(t, errors.Is(err, exceptions.ErrWhatsAppSessionExpired))
+
+	message, err := tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:   "SM1",
+		From: "whatsapp:+254722000000",
+		To:   "whatsapp:+14155238886",
+		Text: "Hi",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "+254722000000", message.From)
+	assert.Equal(t, "+14155238886", message.To)
+
+	session, err := tw.conversations.GetWhatsAppSession(ctx, "0722000000")
+	assert.Nil(t, err)
+	assert.Equal(t, tw.clock.Now().Add(usecases.WhatsAppSessionWindow), session.ExpiresAt)
+
+	_, err = tw.conversations.WhatsApp.SendWhatsAppText(ctx, "+254722000000", "How are you feeling?")
+	assert.Nil(t, err)
+
+	tw.clock.Advance(usecases.WhatsAppSessionWindow)
+	_, err = tw.conversations.WhatsApp.SendWhatsAppText(ctx, "+254722000000", "How are you feeling?")
+	assert.True(t, errors.Is(err, exceptions.ErrWhatsAppSessionExpired))
+
+	session, err = tw.conversations.GetWhatsAppSession(ctx, "+254733000000")
+	assert.Nil(t, err)
+	assert.True(t, session.ExpiresAt.IsZero())
+}
+
+func TestWhatsAppConversationImpl_HandleInboundWhatsApp_Conversation(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+
+	conversation, err := tw.conversations.StartWhatsAppConversation(ctx, "clinician", dto.WhatsAppConversationInput{
+		Phone:      "0722000000",
+		UID:        "patient",
+		Flavour:    feedlib.FlavourConsumer,
+		ItemID:     "item-1",
+		TemplateID: "HXresults",
+		Parameters: []string{"Jane"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "+254722000000", conversation.Phone)
+	assert.Equal(t, "HXresults", tw.whatsApp[0].templateID)
+
+	message, err := tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:          "SM1",
+		From:        "whatsapp:+254722000000",
+		ProfileName: "Jane",
+		Text:        "Thank you",
+		MediaURLs:   []string{"https://api.twilio.com/media/ME1"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, conversation.ID, message.ConversationID)
+	assert.False(t, message.Support)
+	assert.Len(t, tw.feed.posted, 1)
+	assert.Equal(t, "whatsapp-SM1", tw.feed.posted[0].ID)
+	assert.Equal(t, "Jane", tw.feed.posted[0].PostedByName)
+	assert.Equal(t, "Thank you\n\nAttachments:\n- https://api.twilio.com/media/ME1", tw.feed.posted[0].Text)
+	assert.Equal(t, "patient/CONSUMER/item-1", tw.feed.items[0])
+
+	// a retried webhook is not posted again
+	_, err = tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:   "SM1",
+		From: "whatsapp:+254722000000",
+		Text: "Thank you",
+	})
+	assert.Nil(t, err)
+	assert.Len(t, tw.feed.posted, 1)
+
+	tw.clock.Advance(usecases.WhatsAppConversationExpiry)
+	message, err = tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:   "SM2",
+		From: "whatsapp:+254722000000",
+		Text: "Are you there?",
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, message.ConversationID)
+	assert.True(t, message.Support)
+	assert.Len(t, tw.feed.posted, 1)
+}
+
+func TestWhatsAppConversationImpl_StartWhatsAppConversation_Invalid(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+
+	tests := []struct {
+		name  string
+		input dto.WhatsAppConversationInput
+	}{
+		{
+			name: "invalid flavour",
+			input: dto.WhatsAppConversationInput{
+				Phone: "+254722000000", UID: "patient", Flavour: "INVALID", ItemID: "item-1", TemplateID: "HXresults",
+			},
+		},
+		{
+			name: "missing item",
+			input: dto.WhatsAppConversationInput{
+				Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer, TemplateID: "HXresults",
+			},
+		},
+		{
+			name: "invalid phone number",
+			input: dto.WhatsAppConversationInput{
+				Phone: "12", UID: "patient", Flavour: feedlib.FlavourConsumer, ItemID: "item-1", TemplateID: "HXresults",
+			},
+		},
+		{
+			name: "missing template",
+			input: dto.WhatsAppConversationInput{
+				Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer, ItemID: "item-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tw.conversations.StartWhatsAppConversation(ctx, "clinician", tt.input)
+			assert.NotNil(t, err)
+			assert.Empty(t, tw.whatsApp)
+		})
+	}
+}
+
+func TestWhatsAppConversationImpl_Nudges(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+
+	_, err := tw.conversations.SendWhatsAppNudge(ctx, "clinician", dto.WhatsAppNudgeInput{
+		Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer,
+		NudgeID: "nudge-2", TemplateID: "HXnudge",
+	})
+	assert.NotNil(t, err, "a nudge without actions can't be answered")
+
+	sent, err := tw.conversations.SendWhatsAppNudge(ctx, "clinician", dto.WhatsAppNudgeInput{
+		Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer,
+		NudgeID: "nudge-1", TemplateID: "HXnudge",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SM1", sent.MessageID)
+	assert.Equal(t, []string{"Verify your email", "Verify your email to get your lab results by email"}, tw.whatsApp[0].parameters)
+
+	// a button that is not one of the nudge's actions goes to support
+	message, err := tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:            "SM2",
+		From:          "whatsapp:+254722000000",
+		Text:          "Stop",
+		ButtonPayload: "STOP",
+		ButtonText:    "Stop",
+	})
+	assert.Nil(t, err)
+	assert.True(t, message.Support)
+	assert.Empty(t, tw.nudges.events)
+
+	message, err = tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:            "SM3",
+		From:          "whatsapp:+254722000000",
+		Text:          "Verify email",
+		ButtonPayload: "verify email",
+		ButtonText:    "Verify email",
+	})
+	assert.Nil(t, err)
+	assert.False(t, message.Support)
+	assert.Equal(t, "nudge-1", message.NudgeID)
+	assert.Equal(t, "whatsapp-SM3", message.EventID)
+
+	assert.Len(t, tw.nudges.events, 1)
+	event := tw.nudges.events[0]
+	assert.Equal(t, "VERIFY_EMAIL", event.Name)
+	assert.Equal(t, "patient", event.Context.UserID)
+	assert.Equal(t, feedlib.FlavourConsumer, event.Context.Flavour)
+	assert.Equal(t, "action-1", event.Payload.Data["actionID"])
+	assert.Equal(t, "nudge-1", event.Payload.Data["nudgeID"])
+
+	// a nudge is only answered once
+	message, err = tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+		ID:            "SM4",
+		From:          "whatsapp:+254722000000",
+		Text:          "Later",
+		ButtonPayload: "Later",
+		ButtonText:    "Later",
+	})
+	assert.Nil(t, err)
+	assert.True(t, message.Support)
+	assert.Len(t, tw.nudges.events, 1)
+}
+
+func TestWhatsAppConversationImpl_SendWhatsAppNudge_Expiry(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+	input := dto.WhatsAppNudgeInput{
+		Phone: "+254722000000", UID: "patient", Flavour: feedlib.FlavourConsumer,
+		NudgeID: "nudge-1", TemplateID: "HXnudge",
+	}
+
+	tw.nudges.nudges["nudge-1"].Expiry = tw.clock.Now().Add(time.Hour)
+	sent, err := tw.conversations.SendWhatsAppNudge(ctx, "clinician", input)
+	assert.Nil(t, err)
+	assert.Equal(t, tw.clock.Now().Add(time.Hour), sent.ExpiresAt)
+
+	tw.nudges.nudges["nudge-1"].Expiry = tw.clock.Now().Add(-time.Hour)
+	_, err = tw.conversations.SendWhatsAppNudge(ctx, "clinician", input)
+	assert.NotNil(t, err)
+}
+
+func TestWhatsAppConversationImpl_SupportQueue(t *testing.T) {
+	ctx := context.Background()
+	tw := newTestWhatsAppConversations(newTestFixture(t))
+
+	for _, id := range []string{"SM1", "SM2"} {
+		_, err := tw.conversations.HandleInboundWhatsApp(ctx, &domain.InboundWhatsApp{
+			ID:   id,
+			From: "whatsapp:+254722000000",
+			Text: "I need help with my appointment",
+		})
+		assert.Nil(t, err)
+		tw.clock.Advance(time.Minute)
+	}
+
+	queue, err := tw.conversations.WhatsAppSupportQueue(ctx, 0)
+	assert.Nil(t, err)
+	assert.Len(t, queue, 2)
+	assert.Equal(t, "SM1", queue[0].ID)
+
+	resolved, err := tw.conversations.ResolveWhatsAppSupportMessage(ctx, "agent", "SM1")
+	assert.Nil(t, err)
+	assert.True(t, resolved.Resolved)
+	assert.Equal(t, "agent", resolved.ResolvedBy)
+
+	queue, err = tw.conversations.WhatsAppSupportQueue(ctx, 10)
+	assert.Nil(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, "SM2", queue[0].ID)
+
+	_, err = tw.conversations.ResolveWhatsAppSupportMessage(ctx, "agent", "SM9")
+	assert.True(t, errors.Is(err, exceptions.ErrInboundWhatsAppNotFound))
+}